@@ -0,0 +1,296 @@
+// Package main is a synthetic downstream receiver: it subscribes to the
+// consumer's publish topic and answers every batch on the ACK topic with a
+// configurable latency and failure rate, reporting end-to-end delivery
+// latency derived from the Redis entry IDs. Pair it with cmd/loadgen for
+// reproducible soak and throughput benchmarks.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
+)
+
+type options struct {
+	broker       string
+	clientID     string
+	publishTopic string
+	ackTopic     string
+	nackReason   string
+	hmacKey      string
+	latency      time.Duration
+	jitter       time.Duration
+	report       time.Duration
+	failureRate  float64
+	seed         uint64
+	qos          int
+}
+
+func parseFlags(args []string) (*options, error) {
+	fs := flag.NewFlagSet("ackbot", flag.ContinueOnError)
+	o := &options{}
+	fs.StringVar(&o.broker, "mqtt-broker", "tcp://localhost:1883", "MQTT broker URL")
+	fs.StringVar(&o.clientID, "mqtt-client-id", "syslog-ackbot", "MQTT client ID")
+	fs.StringVar(&o.publishTopic, "mqtt-publish-topic", "syslog/remote", "Topic the consumer publishes to")
+	fs.StringVar(&o.ackTopic, "mqtt-ack-topic", "syslog/remote/acknowledgement", "Topic ACKs are sent on")
+	fs.IntVar(&o.qos, "mqtt-qos", 0, "MQTT QoS for subscribe and ACK publish (0, 1, or 2)")
+	fs.DurationVar(&o.latency, "latency", 0, "Simulated processing latency before each ACK")
+	fs.DurationVar(&o.jitter, "jitter", 0, "Uniform random extra latency in [0, jitter)")
+	fs.Float64Var(&o.failureRate, "failure-rate", 0, "Fraction of messages answered with ack:false (0..1)")
+	fs.StringVar(&o.nackReason, "nack-reason", "", "Reason sent with each ack:false (empty sends none)")
+	fs.StringVar(&o.hmacKey, "ack-hmac-key", "", "Key ACKs are signed with, as the consumer's MQTT_ACK_HMAC_KEY")
+	fs.DurationVar(&o.report, "report-interval", 5*time.Second, "Statistics report interval")
+	fs.Uint64Var(&o.seed, "seed", 1, "PRNG seed for reproducible failure injection")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *options) validate() error {
+	if o.qos < 0 || o.qos > 2 {
+		return errors.New("ackbot: mqtt-qos must be 0, 1, or 2")
+	}
+	if o.failureRate < 0 || o.failureRate > 1 {
+		return errors.New("ackbot: failure-rate must be between 0 and 1")
+	}
+	if o.latency < 0 || o.jitter < 0 {
+		return errors.New("ackbot: latency and jitter must not be negative")
+	}
+	if o.report <= 0 {
+		return errors.New("ackbot: report-interval must be positive")
+	}
+	if o.publishTopic == "" || o.ackTopic == "" {
+		return errors.New("ackbot: publish and ack topics must not be empty")
+	}
+	return nil
+}
+
+// entryAge derives the time since XADD from the millisecond part of a
+// Redis stream ID ("<ms>-<seq>").
+func entryAge(id string, now time.Time) (time.Duration, bool) {
+	msPart, _, _ := bytes.Cut([]byte(id), []byte{'-'})
+	ms, err := strconv.ParseInt(string(msPart), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return now.Sub(time.UnixMilli(ms)), true
+}
+
+// stats accumulates counters and latency samples between reports.
+type stats struct {
+	samples   []time.Duration
+	mu        sync.Mutex
+	batches   int
+	received  int
+	acked     int
+	nacked    int
+	malformed int
+}
+
+func (s *stats) record(received, acked, nacked, malformed int, ages []time.Duration) {
+	s.mu.Lock()
+	s.batches++
+	s.received += received
+	s.acked += acked
+	s.nacked += nacked
+	s.malformed += malformed
+	s.samples = append(s.samples, ages...)
+	s.mu.Unlock()
+}
+
+// snapshot returns the interval's counters and resets them.
+func (s *stats) snapshot() (batches, received, acked, nacked, malformed int, samples []time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches, received, acked, nacked, malformed = s.batches, s.received, s.acked, s.nacked, s.malformed
+	samples = s.samples
+	s.batches, s.received, s.acked, s.nacked, s.malformed = 0, 0, 0, 0, 0
+	s.samples = nil
+	return batches, received, acked, nacked, malformed, samples
+}
+
+// percentile expects sorted input and returns 0 for an empty slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+type bot struct {
+	client paho.Client
+	opts   *options
+	log    *log.Logger
+	stats  *stats
+	rng    *rand.Rand
+	rngMu  sync.Mutex
+}
+
+func newBot(o *options, logger *log.Logger) *bot {
+	return &bot{
+		opts:  o,
+		log:   logger,
+		stats: &stats{},
+		rng:   rand.New(rand.NewPCG(o.seed, o.seed^0x9e3779b97f4a7c15)), //nolint:gosec // fault injection, not crypto
+	}
+}
+
+// fail draws whether a single message is answered negatively.
+func (b *bot) fail() bool {
+	if b.opts.failureRate == 0 {
+		return false
+	}
+	b.rngMu.Lock()
+	defer b.rngMu.Unlock()
+	return b.rng.Float64() < b.opts.failureRate
+}
+
+func (b *bot) delay() time.Duration {
+	if b.opts.jitter == 0 {
+		return b.opts.latency
+	}
+	b.rngMu.Lock()
+	defer b.rngMu.Unlock()
+	return b.opts.latency + time.Duration(b.rng.Int64N(int64(b.opts.jitter)))
+}
+
+// verdict answers an entry with an ACK, or with a NACK at the failure rate.
+func (b *bot) verdict(ackclient.Entry) ackclient.Ack {
+	if b.fail() {
+		return ackclient.Ack{Reason: b.opts.nackReason}
+	}
+	return ackclient.Ack{Ack: true}
+}
+
+// handle may run concurrently (OrderMatters=false), so shared state is
+// guarded.
+func (b *bot) handle(ctx context.Context, payload []byte) {
+	batch, err := ackclient.Decode(payload)
+	if err != nil {
+		b.log.Warnf(ctx, "Dropping batch: %v: %s", err, b.log.Preview(payload))
+		return
+	}
+
+	now := time.Now()
+	ages := make([]time.Duration, 0, len(batch.Entries))
+	for _, e := range batch.Entries {
+		if age, ok := entryAge(e.ID, now); ok {
+			ages = append(ages, age)
+		}
+	}
+	acks := ackclient.Answer(batch.Entries, b.verdict)
+	acked, nacked := 0, 0
+	for _, a := range acks {
+		if a.Ack {
+			acked += len(a.IDs)
+		} else {
+			nacked += len(a.IDs)
+		}
+	}
+	b.stats.record(len(batch.Entries), acked, nacked, batch.Malformed, ages)
+
+	sender := ackclient.Sender{Publish: b.publish, Topic: b.opts.ackTopic, Key: []byte(b.opts.hmacKey)}
+	send := func() {
+		if err := sender.Send(acks...); err != nil {
+			b.log.Warnf(ctx, "Failed to send ACKs: %v", err)
+		}
+	}
+	if d := b.delay(); d > 0 {
+		time.AfterFunc(d, send)
+		return
+	}
+	send()
+}
+
+// publish hands an ACK to the client without waiting for the broker.
+func (b *bot) publish(topic string, payload []byte) error {
+	b.client.Publish(topic, byte(b.opts.qos), false, payload) //nolint:gosec // qos validated 0..2
+	return nil
+}
+
+func (b *bot) reportLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.report)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			batches, received, acked, nacked, malformed, samples := b.stats.snapshot()
+			slices.Sort(samples)
+			secs := b.opts.report.Seconds()
+			b.log.Infof(ctx,
+				"batches=%d received=%d (%.0f msg/s) acked=%d nacked=%d malformed=%d latency p50=%s p99=%s max=%s",
+				batches, received, float64(received)/secs, acked, nacked, malformed,
+				percentile(samples, 0.50), percentile(samples, 0.99), percentile(samples, 1))
+		}
+	}
+}
+
+func run(ctx context.Context, args []string) int {
+	logger := log.New()
+
+	o, err := parseFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		logger.Errorf(ctx, "Invalid flags: %v", err)
+		return 2
+	}
+
+	b := newBot(o, logger)
+	opts := paho.NewClientOptions().
+		AddBroker(o.broker).
+		SetClientID(o.clientID).
+		SetAutoReconnect(true).
+		SetOrderMatters(false)
+	opts.SetOnConnectHandler(func(c paho.Client) {
+		tok := c.Subscribe(o.publishTopic, byte(o.qos), func(_ paho.Client, m paho.Message) { //nolint:gosec // validated
+			b.handle(ctx, m.Payload())
+		})
+		if tok.WaitTimeout(10*time.Second) && tok.Error() == nil {
+			logger.Infof(ctx, "Subscribed to %s, answering on %s", o.publishTopic, o.ackTopic)
+			return
+		}
+		logger.Errorf(ctx, "Failed to subscribe to %s: %v", o.publishTopic, tok.Error())
+	})
+	b.client = paho.NewClient(opts)
+
+	tok := b.client.Connect()
+	if !tok.WaitTimeout(10*time.Second) || tok.Error() != nil {
+		logger.Errorf(ctx, "Failed to connect to MQTT broker %s: %v", o.broker, tok.Error())
+		return 1
+	}
+	defer b.client.Disconnect(1000)
+
+	logger.Infof(ctx, "ackbot running: latency=%s jitter=%s failure-rate=%.3f", o.latency, o.jitter, o.failureRate)
+	b.reportLoop(ctx)
+	return 0
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	cancel()
+	os.Exit(code)
+}
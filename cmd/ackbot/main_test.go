@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
+)
+
+func TestParseFlags_Invalid(t *testing.T) {
+	cases := map[string][]string{
+		"qos":          {"-mqtt-qos", "3"},
+		"failure rate": {"-failure-rate", "1.5"},
+		"latency":      {"-latency", "-1s"},
+		"report":       {"-report-interval", "0s"},
+		"empty topic":  {"-mqtt-ack-topic", ""},
+	}
+	for name, args := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseFlags(args); err == nil {
+				t.Errorf("parseFlags(%v) error = nil; want error", args)
+			}
+		})
+	}
+}
+
+func TestEntryAge(t *testing.T) {
+	now := time.UnixMilli(1700000001500)
+	age, ok := entryAge("1700000001000-3", now)
+	if !ok || age != 500*time.Millisecond {
+		t.Errorf("entryAge = %v, %v; want 500ms, true", age, ok)
+	}
+	if _, ok := entryAge("not-an-id", now); ok {
+		t.Error("entryAge(invalid) ok = true; want false")
+	}
+}
+
+func TestBotVerdict(t *testing.T) {
+	b := newBot(&options{failureRate: 1, nackReason: "overloaded", seed: 1}, nil)
+	if v := b.verdict(ackclient.Entry{}); v.Ack || v.Reason != "overloaded" {
+		t.Errorf("verdict at failure rate 1 = %+v; want a NACK with reason overloaded", v)
+	}
+	b.opts.failureRate = 0
+	if v := b.verdict(ackclient.Entry{}); !v.Ack {
+		t.Errorf("verdict at failure rate 0 = %+v; want an ACK", v)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile(nil) = %v; want 0", got)
+	}
+	sorted := []time.Duration{1, 2, 3, 4, 5}
+	if got := percentile(sorted, 0.5); got != 3 {
+		t.Errorf("p50 = %v; want 3", got)
+	}
+	if got := percentile(sorted, 1); got != 5 {
+		t.Errorf("p100 = %v; want 5", got)
+	}
+}
+
+func TestStatsSnapshotResets(t *testing.T) {
+	var s stats
+	s.record(3, 2, 1, 0, []time.Duration{time.Millisecond})
+	batches, received, acked, nacked, _, samples := s.snapshot()
+	if batches != 1 || received != 3 || acked != 2 || nacked != 1 || len(samples) != 1 {
+		t.Errorf("snapshot = %d/%d/%d/%d/%d; want 1/3/2/1/1", batches, received, acked, nacked, len(samples))
+	}
+	if batches, _, _, _, _, _ = s.snapshot(); batches != 0 {
+		t.Errorf("second snapshot batches = %d; want 0", batches)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// captureSignal returns nil: there is no SIGUSR1 outside Unix, and the
+// capture is only served on the health server.
+func captureSignal() <-chan os.Signal {
+	return nil
+}
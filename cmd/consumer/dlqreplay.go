@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// dlqReplayCommand is the first argument that moves dead letter entries
+// back to their source streams instead of running the consumer.
+const dlqReplayCommand = "dlq-replay"
+
+// runDLQReplay replays the Redis dead letter stream once with the
+// -dlq-replay-* options and exits; a running consumer picks the entries up
+// from their streams like any other. It returns the process exit code.
+func runDLQReplay(ctx context.Context) int {
+	var opts redis.ReplayOptions
+	flag.BoolVar(&opts.DryRun, "dlq-replay-dry-run", false, "Count the entries that would be replayed, change nothing")
+	flag.StringVar(&opts.Reason, "dlq-replay-reason", "", "Replay only entries dead-lettered for this reason")
+	flag.StringVar(&opts.Target, "dlq-replay-target", "", "Stream to replay into instead of each entry's source stream")
+	flag.IntVar(&opts.Limit, "dlq-replay-limit", 0, "Stop after this many entries (0 for all)")
+	flag.IntVar(&opts.Rate, "dlq-replay-rate", 0, "Entries replayed per second (0 for no limit)")
+
+	logger := log.New()
+	logger.Infof(ctx, "Starting syslog consumer DLQ replay")
+
+	cfg, err := loadAndLogConfig(ctx, logger)
+	if err != nil {
+		return 1
+	}
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := dlqReplay(sigCtx, cfg, logger, &opts); err != nil {
+		logger.Errorf(ctx, "DLQ replay failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func dlqReplay(ctx context.Context, cfg *config.Config, logger *log.Logger, opts *redis.ReplayOptions) error {
+	if opts.Limit < 0 || opts.Rate < 0 {
+		return errors.New("-dlq-replay-limit and -dlq-replay-rate must not be negative")
+	}
+	if cfg.Redis.DeadLetterStream == "" {
+		return errors.New("no dead letter stream configured; set REDIS_DEAD_LETTER_STREAM")
+	}
+	redisClient, err := redis.NewClient(ctx, &cfg.Redis, logger)
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	defer func() {
+		if err := redisClient.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", err)
+		}
+	}()
+
+	result, err := redisClient.ReplayDeadLetters(ctx, opts)
+	verb := "replayed"
+	if opts.DryRun {
+		verb = "would replay"
+	}
+	logger.Infof(ctx, "DLQ replay from %s: %s %d entries, skipped %d",
+		cfg.Redis.DeadLetterStream, verb, result.Replayed, result.Skipped)
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+func TestDLQReplay_NeedsDeadLetterStream(t *testing.T) {
+	if err := dlqReplay(t.Context(), testCfg(), log.New(), &redis.ReplayOptions{}); err == nil {
+		t.Error("dlqReplay() error = nil; want error without a dead letter stream")
+	}
+}
+
+func TestDLQReplay_MovesEntriesBack(t *testing.T) {
+	s := miniredis.RunT(t)
+	cfg := testCfg()
+	cfg.Redis.Address = s.Addr()
+	cfg.Redis.GroupName = "test-group"
+	cfg.Redis.Consumer = "test-consumer"
+	cfg.Redis.PingTimeout = time.Second
+	cfg.Redis.DeadLetterStream = "dlq"
+	if _, err := s.XAdd("dlq", "*", []string{"stream", testStreamName, "id", "1-0", "raw", "a"}); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+
+	if err := dlqReplay(t.Context(), cfg, log.New(), &redis.ReplayOptions{DryRun: true}); err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	if n := streamLen(t, s, "dlq"); n != 1 {
+		t.Fatalf("dlq length after dry run = %d; want 1", n)
+	}
+	if err := dlqReplay(t.Context(), cfg, log.New(), &redis.ReplayOptions{}); err != nil {
+		t.Fatalf("dlqReplay() error = %v", err)
+	}
+	if n := streamLen(t, s, "dlq"); n != 0 {
+		t.Errorf("dlq length = %d; want 0", n)
+	}
+	if n := streamLen(t, s, testStreamName); n != 1 {
+		t.Errorf("%s length = %d; want 1", testStreamName, n)
+	}
+}
+
+func streamLen(t *testing.T, s *miniredis.Miniredis, key string) int {
+	t.Helper()
+	entries, err := s.Stream(key)
+	if err != nil {
+		t.Fatalf("Stream(%s) error = %v", key, err)
+	}
+	return len(entries)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// groupResetCommand is the first argument that moves the consumer group
+// of the streams to another entry instead of running the consumer.
+const groupResetCommand = "group-reset"
+
+// groupResetOptions are the -group-reset-* flags.
+type groupResetOptions struct {
+	id                string // position of every stream the consumer reads
+	streams           string // comma-separated stream=ID pairs, over id
+	confirm           string // must name the consumer group for anything to change
+	deadLetterPending bool
+}
+
+// groupResetTarget is a stream and the position its group is moved to.
+type groupResetTarget struct {
+	stream string
+	id     string
+}
+
+// runGroupReset moves the consumer group of the streams to the
+// -group-reset-* positions once and exits, so the consumers read the
+// streams again from there. Without -group-reset-confirm naming the group
+// it only reports what it would do. It returns the process exit code.
+func runGroupReset(ctx context.Context) int {
+	var opts groupResetOptions
+	flag.StringVar(&opts.id, "group-reset-id", "", `Position of every stream read: 0, $ or an entry ID`)
+	flag.StringVar(&opts.streams, "group-reset-streams", "", "Comma-separated stream=ID positions, over -group-reset-id")
+	flag.StringVar(&opts.confirm, "group-reset-confirm", "", "Name of the consumer group; without it nothing changes")
+	flag.BoolVar(&opts.deadLetterPending, "group-reset-dlq-pending", false,
+		"Dead-letter the group's pending entries before the reset")
+
+	logger := log.New()
+	logger.Infof(ctx, "Starting syslog consumer group reset")
+
+	cfg, err := loadAndLogConfig(ctx, logger)
+	if err != nil {
+		return 1
+	}
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := groupReset(sigCtx, cfg, logger, &opts); err != nil {
+		logger.Errorf(ctx, "Group reset failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func groupReset(ctx context.Context, cfg *config.Config, logger *log.Logger, opts *groupResetOptions) error {
+	explicit, err := checkGroupReset(cfg, opts)
+	if err != nil {
+		return err
+	}
+	redisClient, err := redis.NewClient(ctx, &cfg.Redis, logger)
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	defer func() {
+		if err := redisClient.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", err)
+		}
+	}()
+
+	resetOpts := redis.GroupResetOptions{DeadLetterPending: opts.deadLetterPending, DryRun: opts.confirm == ""}
+	verb := "reset"
+	if resetOpts.DryRun {
+		verb = "would reset"
+		logger.Warnf(ctx, "Dry run: pass -group-reset-confirm=%s to reset the group", cfg.Redis.GroupName)
+	}
+	for _, target := range groupResetTargets(redisClient.Streams(), opts.id, explicit) {
+		reset, err := redisClient.ResetGroup(ctx, target.stream, target.id, &resetOpts)
+		if err != nil {
+			return err
+		}
+		logger.Infof(ctx, "Group %s on %s: %s to %s, %d pending, %d dead-lettered",
+			redis.Key(&cfg.Redis, cfg.Redis.GroupName), reset.Stream, verb, reset.ID, reset.Pending, reset.DeadLettered)
+	}
+	return nil
+}
+
+// checkGroupReset checks opts against cfg before anything connects, and
+// returns the -group-reset-streams targets.
+func checkGroupReset(cfg *config.Config, opts *groupResetOptions) ([]groupResetTarget, error) {
+	explicit, err := parseGroupResetStreams(opts.streams)
+	if err != nil {
+		return nil, err
+	}
+	if opts.id == "" && len(explicit) == 0 {
+		return nil, errors.New("nothing to reset; set -group-reset-id or -group-reset-streams")
+	}
+	if opts.id != "" && !redis.ValidGroupID(opts.id) {
+		return nil, fmt.Errorf("-group-reset-id %q must be 0, $ or an entry ID", opts.id)
+	}
+	if opts.confirm != "" && opts.confirm != cfg.Redis.GroupName {
+		return nil, fmt.Errorf("-group-reset-confirm %q does not name the consumer group %q",
+			opts.confirm, cfg.Redis.GroupName)
+	}
+	if opts.deadLetterPending && cfg.Redis.DeadLetterStream == "" {
+		return nil, errors.New("no dead letter stream configured for -group-reset-dlq-pending; set REDIS_DEAD_LETTER_STREAM")
+	}
+	return explicit, nil
+}
+
+// parseGroupResetStreams parses the stream=ID pairs of
+// -group-reset-streams, in order. A stream name may hold '=': the ID
+// follows the last one.
+func parseGroupResetStreams(value string) ([]groupResetTarget, error) {
+	var targets []groupResetTarget
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndexByte(item, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("-group-reset-streams entry %q must be stream=ID", item)
+		}
+		target := groupResetTarget{stream: item[:i], id: item[i+1:]}
+		if !redis.ValidGroupID(target.id) {
+			return nil, fmt.Errorf("-group-reset-streams entry %q: ID must be 0, $ or an entry ID", item)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// groupResetTargets returns the explicit targets, then every stream read
+// that is not one of them at id, when id is set.
+func groupResetTargets(streams []string, id string, explicit []groupResetTarget) []groupResetTarget {
+	targets := explicit
+	if id == "" {
+		return targets
+	}
+	for _, stream := range streams {
+		listed := slices.ContainsFunc(explicit, func(t groupResetTarget) bool { return t.stream == stream })
+		if !listed {
+			targets = append(targets, groupResetTarget{stream: stream, id: id})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func TestParseGroupResetStreams(t *testing.T) {
+	got, err := parseGroupResetStreams(" syslog:a=0, syslog:b=$,app=x=1700000000000-1,")
+	if err != nil {
+		t.Fatalf("parseGroupResetStreams() error = %v", err)
+	}
+	want := []groupResetTarget{{"syslog:a", "0"}, {"syslog:b", "$"}, {"app=x", "1700000000000-1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGroupResetStreams() = %v; want %v", got, want)
+	}
+	for _, bad := range []string{"syslog:a", "=0", "syslog:a=yesterday"} {
+		if _, err := parseGroupResetStreams(bad); err == nil {
+			t.Errorf("parseGroupResetStreams(%q) error = nil; want error", bad)
+		}
+	}
+}
+
+func TestGroupResetTargets(t *testing.T) {
+	explicit := []groupResetTarget{{"b", "5-0"}}
+	got := groupResetTargets([]string{"a", "b", "c"}, "$", explicit)
+	want := []groupResetTarget{{"b", "5-0"}, {"a", "$"}, {"c", "$"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupResetTargets() = %v; want %v", got, want)
+	}
+	if got := groupResetTargets([]string{"a", "b"}, "", explicit); !reflect.DeepEqual(got, explicit) {
+		t.Errorf("groupResetTargets() without id = %v; want only %v", got, explicit)
+	}
+}
+
+func TestCheckGroupReset(t *testing.T) {
+	cfg := testCfg()
+	cfg.Redis.GroupName = "consumers"
+	for _, tt := range []struct {
+		name string
+		opts groupResetOptions
+	}{
+		{"nothing", groupResetOptions{}},
+		{"bad id", groupResetOptions{id: "latest"}},
+		{"wrong group", groupResetOptions{id: "0", confirm: "other"}},
+		{"no dead letter stream", groupResetOptions{id: "0", deadLetterPending: true}},
+	} {
+		if _, err := checkGroupReset(cfg, &tt.opts); err == nil {
+			t.Errorf("%s: checkGroupReset() error = nil; want error", tt.name)
+		}
+	}
+	if _, err := checkGroupReset(cfg, &groupResetOptions{id: "0", confirm: "consumers"}); err != nil {
+		t.Errorf("checkGroupReset() error = %v; want nil", err)
+	}
+}
+
+func TestGroupReset_DryRunWithoutConfirm(t *testing.T) {
+	s := miniredis.RunT(t)
+	cfg := testCfg()
+	cfg.Redis.Address = s.Addr()
+	cfg.Redis.GroupName = "test-group"
+	cfg.Redis.Consumer = "test-consumer"
+	cfg.Redis.PingTimeout = time.Second
+	cfg.Redis.DeadLetterStream = "dlq"
+	if _, err := s.XAdd(testStreamName, "*", []string{"raw", "a"}); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+
+	// miniredis has no XGROUP SETID; a dry run never sends it.
+	opts := groupResetOptions{id: "0", deadLetterPending: true}
+	if err := groupReset(t.Context(), cfg, log.New(), &opts); err != nil {
+		t.Fatalf("groupReset() error = %v", err)
+	}
+	if n := streamLen(t, s, testStreamName); n != 1 {
+		t.Errorf("%s length = %d; want 1", testStreamName, n)
+	}
+	if s.Exists("dlq") {
+		t.Error("dlq exists after a dry run; want nothing dead-lettered")
+	}
+}
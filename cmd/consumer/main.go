@@ -6,67 +6,82 @@ import (
 	"errors"
 	"os"
 	"os/signal"
-	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
-	"time"
 
-	"github.com/ibs-source/syslog-consumer/internal/compress"
 	"github.com/ibs-source/syslog-consumer/internal/config"
-	"github.com/ibs-source/syslog-consumer/internal/health"
-	"github.com/ibs-source/syslog-consumer/internal/hotpath"
 	"github.com/ibs-source/syslog-consumer/internal/log"
-	"github.com/ibs-source/syslog-consumer/internal/mqtt"
-	"github.com/ibs-source/syslog-consumer/internal/redis"
+	"github.com/ibs-source/syslog-consumer/internal/tuning"
+	"github.com/ibs-source/syslog-consumer/pkg/consumer"
 )
 
-func run(ctx context.Context) int {
-	if os.Getenv("GOGC") == "" {
-		debug.SetGCPercent(200)
+// Exit codes of the consumer. An orchestrator restarts it with backoff on
+// exitRedis and exitMQTT, which clear once the dependency is back, and
+// alerts and halts on exitConfig and exitTLS, which need an operator;
+// exitRuntime is a failure after the pipeline started. The consumer does
+// not retry a failed start itself beyond MQTT_CONNECT_ATTEMPTS.
+const (
+	exitOK      = 0
+	exitRuntime = 1
+	exitConfig  = 2
+	exitRedis   = 3
+	exitMQTT    = 4
+	exitTLS     = 5
+)
+
+// exitCode maps an error of consumer.Start to the exit code.
+func exitCode(err error) int {
+	var startupErr *consumer.StartupError
+	if err == nil {
+		return exitOK
 	}
-	if os.Getenv("GOMEMLIMIT") == "" {
-		debug.SetMemoryLimit(2 << 30)
+	if !errors.As(err, &startupErr) {
+		return exitRuntime
 	}
+	switch startupErr.Failure {
+	case consumer.StartupRedis:
+		return exitRedis
+	case consumer.StartupMQTT:
+		return exitMQTT
+	case consumer.StartupTLS:
+		return exitTLS
+	default:
+		return exitConfig
+	}
+}
 
+func run(ctx context.Context) int {
 	logger := log.New()
 	logger.Infof(ctx, "Starting syslog consumer")
 
 	cfg, err := loadAndLogConfig(ctx, logger)
 	if err != nil {
-		return 1
+		return exitConfig
 	}
-
-	compress.Init(&cfg.Compress)
-
-	initCtx, initCancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
-	redisClient, mqttPool, hp, err := initializeServices(initCtx, cfg, logger)
-	initCancel()
-	if err != nil {
-		return 1
-	}
-	defer closeServices(ctx, redisClient, mqttPool, hp, logger)
-
-	healthSrv := health.NewServer(
-		cfg.Pipeline.HealthAddr,
-		redisClient,
-		mqttPool,
-		cfg.Pipeline.HealthPingTimeout,
-		cfg.Pipeline.HealthReadHeaderTimeout,
-	)
-	go func() {
-		if err := healthSrv.ListenAndServe(ctx); err != nil {
-			logger.Infof(ctx, "Health server stopped: %v", err)
+	logRuntime(ctx, logger, tuning.Apply(&cfg.Runtime))
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *preflightEnabled {
+		if code := runPreflight(sigCtx, cfg, logger, os.Stderr); code != exitOK {
+			logger.Errorf(ctx, "Preflight checks failed; fix the items marked FAIL or start with -preflight=false")
+			return code
 		}
-	}()
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Pipeline.ShutdownTimeout)
-		defer cancel()
-		if err := healthSrv.Shutdown(shutdownCtx); err != nil {
-			logger.Errorf(ctx, "Health server shutdown error: %v", err)
+	}
+	if *statsInterval > 0 {
+		if isTerminal(os.Stderr) {
+			defer startStats(sigCtx, os.Stderr, *statsInterval, cfg.Redis.LagInterval > 0)()
+		} else {
+			logger.Infof(ctx, "Not printing -stats-interval reports: stderr is not a terminal")
 		}
-	}()
-	logger.Infof(ctx, "Health server listening on %s", cfg.Pipeline.HealthAddr)
-
-	return runMainLoop(ctx, hp, cfg, logger)
+	}
+	var hooks consumer.Hooks
+	if cfg.Pipeline.CaptureSize > 0 {
+		hooks.CaptureDump = captureSignal()
+	}
+	return exitCode(consumer.Start(sigCtx, cfg, hooks))
 }
 
 func loadAndLogConfig(ctx context.Context, logger *log.Logger) (*config.Config, error) {
@@ -78,104 +93,56 @@ func loadAndLogConfig(ctx context.Context, logger *log.Logger) (*config.Config,
 
 	logger.SetLevel(cfg.Log.Level)
 	logger.Infof(ctx, "Configuration loaded successfully")
+	if cfg.Profile != "" {
+		overrides := "none"
+		if len(cfg.ProfileOverrides) > 0 {
+			overrides = strings.Join(cfg.ProfileOverrides, ", ")
+		}
+		logger.Infof(ctx, "Profile: %s, overridden by environment or flags: %s", cfg.Profile, overrides)
+	}
 	logger.Infof(ctx, "Redis: %s, Stream: %s", cfg.Redis.Address, cfg.Redis.Stream)
-	logger.Infof(ctx, "MQTT: %s, Publish: %s, ACK: %s", cfg.MQTT.Broker, cfg.MQTT.PublishTopic, cfg.MQTT.AckTopic)
+	if cfg.Redis.KeyPrefix != "" {
+		logger.Infof(ctx, "Redis key prefix: %s", cfg.Redis.KeyPrefix)
+	}
+	if cfg.Redis.ReplicaAddress != "" {
+		logger.Infof(ctx, "Redis read replica: %s", cfg.Redis.ReplicaAddress)
+	}
+	logger.Infof(ctx, "MQTT: %s, Publish: %s, ACK: %s",
+		config.RedactURL(cfg.MQTT.Broker), cfg.MQTT.PublishTopic, cfg.MQTT.AckTopic)
 	logger.Infof(ctx, "Pipeline: Buffer=%d", cfg.Pipeline.BufferCapacity)
 	return cfg, nil
 }
 
-func initializeServices(
-	ctx context.Context, cfg *config.Config, logger *log.Logger,
-) (*redis.Client, *mqtt.Pool, *hotpath.HotPath, error) {
-	redisClient, err := redis.NewClient(ctx, &cfg.Redis, logger)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to create Redis client: %v", err)
-		return nil, nil, nil, err
+// logRuntime logs the runtime settings tuning.Apply left in effect.
+func logRuntime(ctx context.Context, logger *log.Logger, s tuning.Settings) {
+	cpuLimit, memoryLimit := "none", "none"
+	if s.CPULimit > 0 {
+		cpuLimit = strconv.FormatFloat(s.CPULimit, 'f', -1, 64)
 	}
-	logger.Infof(ctx, "Connected to Redis")
-
-	mqttPool, err := mqtt.NewPool(ctx, &cfg.MQTT, cfg.MQTT.PoolSize, logger)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to create MQTT pool: %v", err)
-		if cerr := redisClient.Close(); cerr != nil {
-			logger.Errorf(ctx, "Error closing Redis client: %v", cerr)
-		}
-		return nil, nil, nil, err
-	}
-	logger.Infof(ctx, "Connected to MQTT broker with %d connections", cfg.MQTT.PoolSize)
-
-	hp, err := hotpath.New(redisClient, mqttPool, cfg, logger)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to create hot path: %v", err)
-		if cerr := mqttPool.Close(); cerr != nil {
-			logger.Errorf(ctx, "Error closing MQTT pool: %v", cerr)
-		}
-		if cerr := redisClient.Close(); cerr != nil {
-			logger.Errorf(ctx, "Error closing Redis client: %v", cerr)
-		}
-		return nil, nil, nil, err
+	if s.CgroupMemory > 0 {
+		memoryLimit = strconv.FormatInt(s.CgroupMemory>>20, 10) + " MiB"
 	}
-	return redisClient, mqttPool, hp, nil
+	logger.Infof(ctx, "Runtime: GOMAXPROCS=%d (%s), GOGC=%d (%s), memory limit %d MiB (%s); cgroup CPUs %s, memory %s",
+		s.MaxProcs, s.MaxProcsSource, s.GCPercent, s.GCPercentSource, s.MemoryLimit>>20, s.MemoryLimitSource,
+		cpuLimit, memoryLimit)
 }
 
-func closeServices(
-	ctx context.Context, redisClient *redis.Client, mqttPool *mqtt.Pool, hp *hotpath.HotPath, logger *log.Logger,
-) {
-	if err := hp.Close(); err != nil {
-		logger.Errorf(ctx, "Error closing hot path: %v", err)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == selftestCommand {
+		os.Args = slices.Delete(os.Args, 1, 2) // the config flags follow the command
+		os.Exit(runSelftest(context.Background()))
 	}
-	if err := mqttPool.Close(); err != nil {
-		logger.Errorf(ctx, "Error closing MQTT pool: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == dlqReplayCommand {
+		os.Args = slices.Delete(os.Args, 1, 2)
+		os.Exit(runDLQReplay(context.Background()))
 	}
-	if err := redisClient.Close(); err != nil {
-		logger.Errorf(ctx, "Error closing Redis client: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == groupResetCommand {
+		os.Args = slices.Delete(os.Args, 1, 2)
+		os.Exit(runGroupReset(context.Background()))
 	}
-}
-
-func runMainLoop(ctx context.Context, hp *hotpath.HotPath, cfg *config.Config, logger *log.Logger) int {
-	runCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	doneCh := make(chan error, 1)
-	go func() {
-		doneCh <- hp.Run(runCtx)
-	}()
-
-	logger.Infof(ctx, "Hot path orchestrator started")
-
-	select {
-	case sig := <-sigChan:
-		logger.Infof(ctx, "Received signal %v, initiating graceful shutdown", sig)
-		cancel()
-
-		timer := time.NewTimer(cfg.Pipeline.ShutdownTimeout)
-		defer timer.Stop()
-
-		select {
-		case err := <-doneCh:
-			if err != nil && !errors.Is(err, context.Canceled) {
-				logger.Errorf(ctx, "Hot path shutdown error: %v", err)
-				return 1
-			}
-			logger.Infof(ctx, "Graceful shutdown completed")
-			return 0
-		case <-timer.C:
-			logger.Errorf(ctx, "Shutdown timeout exceeded")
-			return 1
-		}
-
-	case err := <-doneCh:
-		if err != nil && !errors.Is(err, context.Canceled) {
-			logger.Errorf(ctx, "Hot path error: %v", err)
-			return 1
-		}
-		return 0
+	if len(os.Args) > 1 && os.Args[1] == verifyCommand {
+		os.Args = slices.Delete(os.Args, 1, 2)
+		os.Exit(runVerify(context.Background()))
 	}
-}
-
-func main() {
 	os.Exit(run(context.Background()))
 }
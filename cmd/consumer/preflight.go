@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/certs"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+var preflightEnabled = flag.Bool("preflight", true,
+	"Check certificates, Redis and the MQTT publish and ACK ACLs before starting")
+
+// certExpiryWarning is how close to expiry a certificate is reported.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// preflightStatus orders the outcomes of a check from best to worst.
+type preflightStatus int
+
+const (
+	preflightOK preflightStatus = iota
+	preflightSkip
+	preflightWarn
+	preflightFail
+)
+
+func (s preflightStatus) String() string {
+	return [...]string{"ok", "skip", "warn", "FAIL"}[s]
+}
+
+// preflightResult is the outcome of one check; the details say what was
+// found and, for warnings and failures, what to do about it.
+type preflightResult struct {
+	details []string
+	status  preflightStatus
+}
+
+func (r *preflightResult) add(status preflightStatus, detail string) {
+	r.status = max(r.status, status)
+	r.details = append(r.details, detail)
+}
+
+type preflightCheck struct {
+	run  func(ctx context.Context) preflightResult
+	name string
+	exit int // the exit code when the check fails
+}
+
+// runPreflight checks, in order, that the MQTT and health certificates load
+// and are in date, that Redis supports and permits every command cfg needs,
+// that the broker accepts a publish below the publish topic and a
+// subscription to the ACK topic, unless the archive is the sole sink. It
+// writes a checklist to w and returns exitOK when the consumer may start,
+// or the exit code of the failed check; a failed check skips the ones
+// after it.
+func runPreflight(ctx context.Context, cfg *config.Config, logger *log.Logger, w io.Writer) int {
+	checks := []preflightCheck{
+		{name: "certificates", exit: exitTLS, run: func(context.Context) preflightResult {
+			return checkCertificates(&cfg.MQTT, time.Now())
+		}},
+		{name: "health certificates", exit: exitTLS, run: func(context.Context) preflightResult {
+			return checkHealthCertificates(&cfg.Pipeline, time.Now())
+		}},
+		{name: "redis", exit: exitRedis, run: func(ctx context.Context) preflightResult {
+			return checkRedis(ctx, &cfg.Redis)
+		}},
+		{name: "mqtt publish", exit: exitMQTT, run: withMQTT(cfg, func(ctx context.Context) preflightResult {
+			return checkMQTTPublish(ctx, &cfg.MQTT, logger)
+		})},
+		{name: "mqtt ack subscribe", exit: exitMQTT, run: withMQTT(cfg, func(ctx context.Context) preflightResult {
+			return checkMQTTAck(ctx, &cfg.MQTT, logger)
+		})},
+	}
+
+	_, _ = fmt.Fprintln(w, "Preflight checks:")
+	code := exitOK
+	for _, check := range checks {
+		result := preflightResult{status: preflightSkip, details: []string{"skipped after a failed check"}}
+		if code == exitOK {
+			result = check.run(ctx)
+		}
+		writePreflightResult(w, check.name, &result)
+		if code == exitOK && result.status == preflightFail {
+			code = check.exit
+		}
+	}
+	return code
+}
+
+// withMQTT returns run, or a skipped check under ARCHIVE_MODE=only, where
+// the consumer connects to no broker.
+func withMQTT(cfg *config.Config, run func(context.Context) preflightResult) func(context.Context) preflightResult {
+	if cfg.Archive.Mode != config.ArchiveOnly {
+		return run
+	}
+	return func(context.Context) preflightResult {
+		return preflightResult{status: preflightSkip, details: []string{"ARCHIVE_MODE=only publishes nothing to MQTT"}}
+	}
+}
+
+func writePreflightResult(w io.Writer, name string, result *preflightResult) {
+	_, _ = fmt.Fprintf(w, "  [%-4s] %s\n", result.status, name)
+	for _, detail := range result.details {
+		_, _ = fmt.Fprintf(w, "         - %s\n", detail)
+	}
+}
+
+// checkCertificates parses MQTT_CA_CERT and the MQTT_CLIENT_CERT /
+// MQTT_CLIENT_KEY pair and checks their validity period against now.
+func checkCertificates(cfg *config.MQTTConfig, now time.Time) preflightResult {
+	var result preflightResult
+	if !cfg.TLSEnabled {
+		result.add(preflightOK, "TLS disabled")
+		return result
+	}
+	if cfg.CACert != "" {
+		checkCACerts(&result, cfg.CACert, "MQTT_CA_CERT", now)
+	}
+	switch {
+	case cfg.ClientCert != "" && cfg.ClientKey != "":
+		pair, err := certs.LoadPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			result.add(preflightFail, fmt.Sprintf("client certificate: %v; check MQTT_CLIENT_CERT and MQTT_CLIENT_KEY", err))
+			break
+		}
+		checkValidity(&result, "client certificate "+certs.Name(cfg.ClientCert), pair.Leaf, now)
+	case cfg.ClientCert != "" || cfg.ClientKey != "":
+		result.add(preflightWarn, "only one of MQTT_CLIENT_CERT and MQTT_CLIENT_KEY is set; no client certificate is sent")
+	}
+	if len(result.details) == 0 {
+		result.add(preflightOK, "no certificate files configured")
+	}
+	return result
+}
+
+// checkHealthCertificates is checkCertificates for the health listener:
+// PIPELINE_HEALTH_TLS_CERT / PIPELINE_HEALTH_TLS_KEY and
+// PIPELINE_HEALTH_CLIENT_CA.
+func checkHealthCertificates(cfg *config.PipelineConfig, now time.Time) preflightResult {
+	var result preflightResult
+	if cfg.HealthTLSCert == "" {
+		result.add(preflightOK, "TLS disabled")
+		return result
+	}
+	pair, err := certs.LoadPair(cfg.HealthTLSCert, cfg.HealthTLSKey)
+	if err != nil {
+		result.add(preflightFail,
+			fmt.Sprintf("server certificate: %v; check PIPELINE_HEALTH_TLS_CERT and PIPELINE_HEALTH_TLS_KEY", err))
+	} else {
+		checkValidity(&result, "server certificate "+certs.Name(cfg.HealthTLSCert), pair.Leaf, now)
+	}
+	if cfg.HealthClientCA != "" {
+		checkCACerts(&result, cfg.HealthClientCA, "PIPELINE_HEALTH_CLIENT_CA", now)
+	}
+	return result
+}
+
+// checkCACerts checks the certificates in path, a file or inline PEM,
+// which the env variable names.
+func checkCACerts(result *preflightResult, path, env string, now time.Time) {
+	data, err := certs.Read(path)
+	if err != nil {
+		result.add(preflightFail, fmt.Sprintf("CA certificate: %v; check %s", err, env))
+		return
+	}
+	found := 0
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			result.add(preflightFail, fmt.Sprintf("CA certificate %s: %v", certs.Name(path), err))
+			return
+		}
+		checkValidity(result, fmt.Sprintf("CA certificate %q in %s", cert.Subject.CommonName, certs.Name(path)), cert, now)
+		found++
+	}
+	if found == 0 {
+		result.add(preflightFail, fmt.Sprintf("CA certificate %s holds no PEM certificate; check %s", certs.Name(path), env))
+	}
+}
+
+func checkValidity(result *preflightResult, label string, cert *x509.Certificate, now time.Time) {
+	const layout = "2006-01-02"
+	switch left := cert.NotAfter.Sub(now); {
+	case now.Before(cert.NotBefore):
+		result.add(preflightFail, fmt.Sprintf("%s is not valid before %s; check the host clock",
+			label, cert.NotBefore.Format(layout)))
+	case left <= 0:
+		result.add(preflightFail, fmt.Sprintf("%s expired on %s; renew it", label, cert.NotAfter.Format(layout)))
+	case left < certExpiryWarning:
+		result.add(preflightWarn, fmt.Sprintf("%s expires on %s, in %d days; renew it soon",
+			label, cert.NotAfter.Format(layout), int(left.Hours()/24)))
+	default:
+		result.add(preflightOK, fmt.Sprintf("%s valid until %s", label, cert.NotAfter.Format(layout)))
+	}
+}
+
+func checkRedis(ctx context.Context, cfg *config.RedisConfig) preflightResult {
+	var result preflightResult
+	check, err := redis.CheckServer(ctx, cfg)
+	if err != nil {
+		result.add(preflightFail, fmt.Sprintf("%v; check REDIS_ADDRESS and the credentials", err))
+		return result
+	}
+	version := "Redis " + check.Version
+	if check.Version == "" {
+		version = "Redis version not reported (INFO denied or trimmed)"
+	}
+	result.add(preflightOK, version)
+	for _, problem := range check.Problems {
+		result.add(preflightFail, problem)
+	}
+	return result
+}
+
+// checkMQTTPublish connects with its own client ID and no persistent
+// session and publishes an empty QoS 1 message to <publish topic>/preflight.
+// Brokers that answer a denied publish with an error or by dropping the
+// connection are caught; MQTT 3.1.1 brokers that acknowledge it and
+// discard the message are not.
+func checkMQTTPublish(ctx context.Context, cfg *config.MQTTConfig, logger *log.Logger) preflightResult {
+	var result preflightResult
+	topic := cfg.PublishTopic + "/preflight"
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout+cfg.WriteTimeout)
+	defer cancel()
+	pool, ok := connectProbe(ctx, cfg, logger, &result)
+	if !ok {
+		return result
+	}
+	defer func() { _ = pool.Close() }()
+
+	if err := pool.PublishTo(ctx, nil, topic, 1, false); err != nil {
+		result.add(preflightFail, fmt.Sprintf("publish to %s failed: %v; check the broker ACL", topic, err))
+		return result
+	}
+	if !pool.IsConnected() {
+		result.add(preflightFail, fmt.Sprintf("broker closed the connection after a publish to %s; check the broker ACL",
+			topic))
+		return result
+	}
+	result.add(preflightOK, fmt.Sprintf("published to %s on %s", topic, config.RedactURL(cfg.Broker)))
+	return result
+}
+
+// checkMQTTAck subscribes to the ACK topic with the probe client and
+// unsubscribes again. paho reports a SUBACK failure code as success, so a
+// broker whose ACL denies the topic is caught here rather than by ACKs that
+// never arrive.
+func checkMQTTAck(ctx context.Context, cfg *config.MQTTConfig, logger *log.Logger) preflightResult {
+	var result preflightResult
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout+cfg.SubscribeTimeout)
+	defer cancel()
+	pool, ok := connectProbe(ctx, cfg, logger, &result)
+	if !ok {
+		return result
+	}
+	defer func() { _ = pool.Close() }()
+
+	if err := pool.SubscribeAck(ctx, func(message.AckMessage) {}); err != nil {
+		result.add(preflightFail, fmt.Sprintf("subscribe to %s failed: %v; check the broker ACL", cfg.AckTopic, err))
+		return result
+	}
+	_ = pool.UnsubscribeAck(ctx)
+	result.add(preflightOK, fmt.Sprintf("subscribed to %s on %s", cfg.AckTopic, config.RedactURL(cfg.Broker)))
+	return result
+}
+
+// connectProbe connects a single client with its own client ID and no
+// persistent session, so the checks leave the consumer's session alone.
+func connectProbe(ctx context.Context, cfg *config.MQTTConfig, logger *log.Logger,
+	result *preflightResult,
+) (*mqtt.Pool, bool) {
+	probe := *cfg
+	probe.ClientID += "-preflight"
+	probe.PersistentSession = false
+	probe.StoreDir = ""
+	pool, err := mqtt.NewPool(ctx, &probe, 1, logger)
+	if err != nil {
+		result.add(preflightFail, fmt.Sprintf("cannot connect to %s: %v; check MQTT_BROKER and the TLS settings",
+			config.RedactURL(cfg.Broker), err))
+		return nil, false
+	}
+	return pool, true
+}
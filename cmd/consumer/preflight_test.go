@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+// writeCert writes a self-signed certificate valid from notBefore to
+// notAfter, and its key, to dir and returns both paths.
+func writeCert(t *testing.T, dir, name string, notBefore, notAfter time.Time) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	certPath, keyPath = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+	return certPath, keyPath
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile(%s) error = %v", path, err)
+	}
+}
+
+func TestCheckCertificates_TLSDisabled(t *testing.T) {
+	result := checkCertificates(&config.MQTTConfig{CACert: "/does/not/exist"}, time.Now())
+	if result.status != preflightOK {
+		t.Errorf("status = %v, details = %q; want ok without TLS", result.status, result.details)
+	}
+}
+
+func TestCheckCertificates_Validity(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	ca, _ := writeCert(t, dir, "ca", now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	expiring, expiringKey := writeCert(t, dir, "expiring", now.Add(-time.Hour), now.AddDate(0, 0, 10))
+	expired, _ := writeCert(t, dir, "expired", now.AddDate(-1, 0, 0), now.Add(-time.Hour))
+	future, _ := writeCert(t, dir, "future", now.Add(time.Hour), now.AddDate(1, 0, 0))
+
+	tests := []struct {
+		name string
+		cfg  config.MQTTConfig
+		want preflightStatus
+	}{
+		{"valid CA", config.MQTTConfig{CACert: ca}, preflightOK},
+		{"expiring client", config.MQTTConfig{CACert: ca, ClientCert: expiring, ClientKey: expiringKey}, preflightWarn},
+		{"expired CA", config.MQTTConfig{CACert: expired}, preflightFail},
+		{"not yet valid CA", config.MQTTConfig{CACert: future}, preflightFail},
+		{"key only", config.MQTTConfig{ClientKey: expiringKey}, preflightWarn},
+		{"missing CA", config.MQTTConfig{CACert: filepath.Join(dir, "missing.crt")}, preflightFail},
+		{"CA is a key", config.MQTTConfig{CACert: expiringKey}, preflightFail},
+	}
+	for _, tt := range tests {
+		tt.cfg.TLSEnabled = true
+		if result := checkCertificates(&tt.cfg, now); result.status != tt.want {
+			t.Errorf("%s: status = %v, details = %q; want %v", tt.name, result.status, result.details, tt.want)
+		}
+	}
+}
+
+func TestCheckCertificates_MismatchedKey(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	cert, _ := writeCert(t, dir, "client", now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	_, otherKey := writeCert(t, dir, "other", now.Add(-time.Hour), now.AddDate(1, 0, 0))
+
+	result := checkCertificates(&config.MQTTConfig{TLSEnabled: true, ClientCert: cert, ClientKey: otherKey}, now)
+	if result.status != preflightFail || !strings.Contains(result.details[0], "MQTT_CLIENT_KEY") {
+		t.Errorf("status = %v, details = %q; want a failure naming MQTT_CLIENT_KEY", result.status, result.details)
+	}
+}
+
+func TestCheckCertificates_InlineKey(t *testing.T) {
+	now := time.Now()
+	cert, key := writeCert(t, t.TempDir(), "client", now.Add(-time.Hour), now.AddDate(0, 0, 10))
+	keyPEM, err := os.ReadFile(key)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	result := checkCertificates(&config.MQTTConfig{TLSEnabled: true, ClientCert: cert, ClientKey: string(keyPEM)}, now)
+	if result.status != preflightWarn {
+		t.Errorf("status = %v, details = %q; want the expiry warning", result.status, result.details)
+	}
+	result = checkCertificates(&config.MQTTConfig{TLSEnabled: true, CACert: string(keyPEM)}, now)
+	for _, detail := range result.details {
+		if strings.Contains(detail, "PRIVATE KEY") {
+			t.Errorf("detail %q quotes the inline PEM", detail)
+		}
+	}
+}
+
+func TestCheckHealthCertificates(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	cert, key := writeCert(t, dir, "health", now.Add(-time.Hour), now.AddDate(1, 0, 0))
+	expired, expiredKey := writeCert(t, dir, "expired", now.AddDate(-1, 0, 0), now.Add(-time.Hour))
+
+	tests := []struct {
+		name string
+		cfg  config.PipelineConfig
+		want preflightStatus
+	}{
+		{"TLS disabled", config.PipelineConfig{HealthClientCA: "/does/not/exist"}, preflightOK},
+		{"valid", config.PipelineConfig{HealthTLSCert: cert, HealthTLSKey: key, HealthClientCA: cert}, preflightOK},
+		{"expired", config.PipelineConfig{HealthTLSCert: expired, HealthTLSKey: expiredKey}, preflightFail},
+		{"mismatched key", config.PipelineConfig{HealthTLSCert: cert, HealthTLSKey: expiredKey}, preflightFail},
+		{"client CA is a key", config.PipelineConfig{HealthTLSCert: cert, HealthTLSKey: key, HealthClientCA: key},
+			preflightFail},
+	}
+	for _, tt := range tests {
+		if result := checkHealthCertificates(&tt.cfg, now); result.status != tt.want {
+			t.Errorf("%s: status = %v, details = %q; want %v", tt.name, result.status, result.details, tt.want)
+		}
+	}
+}
+
+func TestRunPreflight_SkipsAfterFailure(t *testing.T) {
+	cfg := testCfg()
+	cfg.Redis.Address = "localhost:1"
+	cfg.Redis.PingTimeout = 100 * time.Millisecond
+	var out strings.Builder
+
+	if code := runPreflight(t.Context(), cfg, log.New(), &out); code != exitRedis {
+		t.Errorf("runPreflight() = %d; want exitRedis when Redis is unreachable", code)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"[ok  ] certificates", "[FAIL] redis", "[skip] mqtt publish", "[skip] mqtt ack subscribe",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
+)
+
+// selftestCommand is the first argument that selects the selftest instead
+// of the consumer.
+const selftestCommand = "selftest"
+
+const selftestProbeRaw = "syslog-consumer selftest probe"
+
+// selftestPublisher is the MQTT surface the selftest drives; *mqtt.Pool
+// implements it.
+type selftestPublisher interface {
+	mqtt.Publisher
+	UnsubscribeAck(ctx context.Context) error
+	PublishAck(ctx context.Context, payload []byte) error
+}
+
+// runSelftest pushes one probe entry through the real pipeline — Redis,
+// hot path, MQTT publish, ACK subscription, XACK + XDEL — on a temporary
+// stream and reports the first stage that does not complete. It returns
+// the process exit code.
+func runSelftest(ctx context.Context) int {
+	timeout := flag.Duration("selftest-timeout", 30*time.Second, "Deadline for the whole selftest")
+	selfAck := flag.Bool("selftest-self-ack", false, "Publish the probe ACK ourselves instead of waiting for a receiver")
+
+	logger := log.New()
+	logger.Infof(ctx, "Starting syslog consumer selftest")
+
+	cfg, err := loadAndLogConfig(ctx, logger)
+	if err != nil {
+		return 1
+	}
+	compress.Init(&cfg.Compress)
+	prepareSelftestConfig(cfg)
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	testCtx, cancel := context.WithTimeout(sigCtx, *timeout)
+	defer cancel()
+
+	if err := selftest(testCtx, cfg, logger, *selfAck); err != nil {
+		logger.Errorf(ctx, "Selftest failed at %v", err)
+		return 1
+	}
+	logger.Infof(ctx, "Selftest passed")
+	return 0
+}
+
+// prepareSelftestConfig points the pipeline at a fresh temporary stream with
+// its own consumer group and gives the MQTT connection a distinct,
+// non-persistent session, so a consumer running against the same Redis and
+// broker is left undisturbed. Receipts are disabled so the probe leaves no
+// compliance record.
+func prepareSelftestConfig(cfg *config.Config) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	cfg.Redis.Stream = fmt.Sprintf("syslog-consumer-selftest-%s-%d-%d", hostname, os.Getpid(), time.Now().UnixMilli())
+	cfg.Redis.GroupName += "-selftest"
+	cfg.MQTT.ClientID += "-selftest"
+	cfg.MQTT.PersistentSession = false
+	cfg.MQTT.StoreDir = ""
+	cfg.Redis.ReceiptsStream = ""
+}
+
+func selftest(ctx context.Context, cfg *config.Config, logger *log.Logger, selfAck bool) error {
+	redisClient, err := redis.NewClient(ctx, &cfg.Redis, logger)
+	if err != nil {
+		return fmt.Errorf("stage redis: %w", err)
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Redis.WriteTimeout)
+		defer cancel()
+		if err := redisClient.DeleteStream(cleanupCtx, redis.Key(&cfg.Redis, cfg.Redis.Stream)); err != nil {
+			logger.Warnf(ctx, "Failed to remove temporary stream: %v", err)
+		}
+		if err := redisClient.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", err)
+		}
+	}()
+	logger.Infof(ctx, "Selftest stage redis: ok (temporary stream %s)", redis.Key(&cfg.Redis, cfg.Redis.Stream))
+
+	pool, err := mqtt.NewPool(ctx, &cfg.MQTT, 1, logger)
+	if err != nil {
+		return fmt.Errorf("stage mqtt: %w", err)
+	}
+	defer func() {
+		if err := pool.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing MQTT pool: %v", err)
+		}
+	}()
+	logger.Infof(ctx, "Selftest stage mqtt: ok (broker %s)", config.RedactURL(cfg.MQTT.Broker))
+
+	return runProbe(ctx, cfg, redisClient, pool, logger, selfAck)
+}
+
+// runProbe adds the probe entry and runs the hot path until the probe has
+// been acknowledged and deleted, or ctx ends.
+func runProbe(
+	ctx context.Context, cfg *config.Config, redisClient *redis.Client,
+	pub selftestPublisher, logger *log.Logger, selfAck bool,
+) error {
+	id, err := redisClient.AddEntry(ctx, redis.Key(&cfg.Redis, cfg.Redis.Stream), "raw", selftestProbeRaw)
+	if err != nil {
+		return fmt.Errorf("stage probe: %w", err)
+	}
+	logger.Infof(ctx, "Selftest stage probe: ok (entry %s)", id)
+
+	probe := newSelftestProbe(cfg, id)
+	hp, err := hotpath.New(&probeRedis{Client: redisClient, probe: probe},
+		&probePublisher{selftestPublisher: pub, probe: probe}, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("stage pipeline: %w", err)
+	}
+	defer func() {
+		if err := hp.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing hot path: %v", err)
+		}
+	}()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var runErr error
+	stopped := make(chan struct{})
+	go func() {
+		runErr = hp.Run(runCtx)
+		close(stopped)
+	}()
+
+	err = probe.wait(ctx, stopped, &runErr, pub, selfAck, logger)
+	cancel()
+	<-stopped
+	return err
+}
+
+// selftestStage is one step the probe entry has to pass; done is closed
+// once it has.
+type selftestStage struct {
+	done chan struct{}
+	name string
+	hint string
+	once sync.Once
+}
+
+func (s *selftestStage) pass() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// selftestProbe follows the probe entry through the pipeline. probeRedis
+// and probePublisher report to it as the hot path calls through them.
+type selftestProbe struct {
+	stream     string
+	id         string
+	ackKey     []byte
+	subscribed selftestStage
+	published  selftestStage
+	acked      selftestStage
+	committed  selftestStage
+	nacked     atomic.Bool
+}
+
+func newSelftestProbe(cfg *config.Config, id string) *selftestProbe {
+	p := &selftestProbe{stream: redis.Key(&cfg.Redis, cfg.Redis.Stream), id: id, ackKey: []byte(cfg.MQTT.AckHMACKey)}
+	p.subscribed = selftestStage{name: "subscribe",
+		hint: fmt.Sprintf("could not subscribe to ACK topic %s", cfg.MQTT.AckTopic)}
+	p.published = selftestStage{name: "publish",
+		hint: fmt.Sprintf("probe was not published to %s; check the broker ACL for the topic", cfg.MQTT.PublishTopic)}
+	p.acked = selftestStage{name: "ack",
+		hint: fmt.Sprintf("no ACK for probe %s arrived on %s; is a receiver consuming %s? "+
+			"Use -selftest-self-ack to loop the ACK back without one", id, cfg.MQTT.AckTopic, cfg.MQTT.PublishTopic)}
+	p.committed = selftestStage{name: "commit",
+		hint: fmt.Sprintf("probe %s was acknowledged but XACK + XDEL did not complete", id)}
+	for _, s := range p.stages() {
+		s.done = make(chan struct{})
+	}
+	return p
+}
+
+func (p *selftestProbe) stages() []*selftestStage {
+	return []*selftestStage{&p.subscribed, &p.published, &p.acked, &p.committed}
+}
+
+func (p *selftestProbe) isProbe(stream string, ids []string) bool {
+	return stream == p.stream && slices.Contains(ids, p.id)
+}
+
+func (p *selftestProbe) diagnose(s *selftestStage) string {
+	if s == &p.acked && p.nacked.Load() {
+		return fmt.Sprintf("the receiver answered ack:false for probe %s", p.id)
+	}
+	return s.hint
+}
+
+// wait blocks until every stage has passed, the hot path stops, or ctx
+// ends. With selfAck it answers the probe on the ACK topic as soon as it
+// has been published, so the ACK still makes the round trip via the broker.
+func (p *selftestProbe) wait(
+	ctx context.Context, stopped <-chan struct{}, runErr *error,
+	pub selftestPublisher, selfAck bool, logger *log.Logger,
+) error {
+	for _, s := range p.stages() {
+		select {
+		case <-s.done:
+			logger.Infof(ctx, "Selftest stage %s: ok", s.name)
+		case <-stopped:
+			return fmt.Errorf("stage %s: pipeline stopped: %v", s.name, *runErr)
+		case <-ctx.Done():
+			return fmt.Errorf("stage %s: %s: %w", s.name, p.diagnose(s), ctx.Err())
+		}
+		if s == &p.published && selfAck {
+			if err := p.selfAck(ctx, pub); err != nil {
+				return fmt.Errorf("stage %s: self-ack failed: %w", p.acked.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// selfAck sends the ACK a receiver would send for the probe, signed with
+// MQTT_ACK_HMAC_KEY when it is set.
+func (p *selftestProbe) selfAck(ctx context.Context, pub selftestPublisher) error {
+	ack := ackclient.Accept(p.stream, p.id)
+	payload, err := ackclient.Marshal(&ack, p.ackKey)
+	if err != nil {
+		return err
+	}
+	return pub.PublishAck(ctx, payload)
+}
+
+// probeRedis marks the commit stage once the probe's XACK + XDEL succeeds.
+type probeRedis struct {
+	*redis.Client
+	probe *selftestProbe
+}
+
+func (r *probeRedis) AckAndDeleteBatch(ctx context.Context, ids []string, stream string) error {
+	if err := r.Client.AckAndDeleteBatch(ctx, ids, stream); err != nil {
+		return err
+	}
+	if r.probe.isProbe(stream, ids) {
+		r.probe.committed.pass()
+	}
+	return nil
+}
+
+// probePublisher marks the subscribe, publish and ack stages. The
+// temporary stream holds only the probe, so any successful publish is it.
+type probePublisher struct {
+	selftestPublisher
+	probe *selftestProbe
+}
+
+func (p *probePublisher) Publish(ctx context.Context, payload message.Payload) error {
+	if err := p.selftestPublisher.Publish(ctx, payload); err != nil {
+		return err
+	}
+	p.probe.published.pass()
+	return nil
+}
+
+func (p *probePublisher) SubscribeAck(ctx context.Context, handler func(message.AckMessage)) error {
+	err := p.selftestPublisher.SubscribeAck(ctx, func(ack message.AckMessage) {
+		if p.probe.isProbe(ack.Stream, ack.IDs) {
+			if ack.Ack {
+				p.probe.acked.pass()
+			} else {
+				p.probe.nacked.Store(true)
+			}
+		}
+		handler(ack)
+	})
+	if err != nil {
+		return err
+	}
+	p.probe.subscribed.pass()
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// loopbackPublisher hands ACKs sent with PublishAck straight to the ACK
+// handler, standing in for the broker round trip.
+type loopbackPublisher struct {
+	handler   func(message.AckMessage)
+	published [][]byte
+	mu        sync.Mutex
+}
+
+func (p *loopbackPublisher) Publish(_ context.Context, payload message.Payload) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, payload)
+	return nil
+}
+
+func (p *loopbackPublisher) SubscribeAck(_ context.Context, handler func(message.AckMessage)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+	return nil
+}
+
+func (p *loopbackPublisher) UnsubscribeAck(_ context.Context) error { return nil }
+
+func (p *loopbackPublisher) PublishAck(_ context.Context, payload []byte) error {
+	var ack message.AckMessage
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	handler := p.handler
+	p.mu.Unlock()
+	handler(ack)
+	return nil
+}
+
+func (p *loopbackPublisher) Close() error { return nil }
+
+func selftestCfg(t *testing.T, s *miniredis.Miniredis) *config.Config {
+	t.Helper()
+	cfg := testCfg()
+	cfg.Redis.Address = s.Addr()
+	cfg.Redis.GroupName = "test-group"
+	cfg.Redis.Consumer = "test-consumer"
+	cfg.Redis.BatchSize = 10
+	cfg.Redis.BlockTimeout = 20 * time.Millisecond
+	cfg.Redis.PingTimeout = time.Second
+	cfg.Redis.WriteTimeout = time.Second
+	cfg.Pipeline.AckBatchSize = 1
+	cfg.Pipeline.AckFlushInterval = 10 * time.Millisecond
+	cfg.Pipeline.DrainTimeout = time.Second
+	cfg.MQTT.PublishTopic = "test/pub"
+	cfg.MQTT.AckTopic = "test/ack"
+	prepareSelftestConfig(cfg)
+	return cfg
+}
+
+func selftestRedis(t *testing.T, cfg *config.Config) *redis.Client {
+	t.Helper()
+	client, err := redis.NewClient(t.Context(), &cfg.Redis, log.New())
+	if err != nil {
+		t.Fatalf("redis.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return client
+}
+
+func TestRunProbe_SelfAckCompletes(t *testing.T) {
+	s := miniredis.RunT(t)
+	cfg := selftestCfg(t, s)
+	pub := &loopbackPublisher{}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := runProbe(ctx, cfg, selftestRedis(t, cfg), pub, log.New(), true); err != nil {
+		t.Fatalf("runProbe() error = %v", err)
+	}
+
+	if len(pub.published) != 1 {
+		t.Errorf("published %d payloads; want the probe only", len(pub.published))
+	}
+	if entries, err := s.Stream(cfg.Redis.Stream); err != nil || len(entries) != 0 {
+		t.Errorf("stream entries = %v (err %v); want the probe deleted", entries, err)
+	}
+}
+
+func TestRunProbe_ReportsMissingAck(t *testing.T) {
+	s := miniredis.RunT(t)
+	cfg := selftestCfg(t, s)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 300*time.Millisecond)
+	defer cancel()
+	err := runProbe(ctx, cfg, selftestRedis(t, cfg), &loopbackPublisher{}, log.New(), false)
+	if err == nil {
+		t.Fatal("runProbe() error = nil; want the ack stage to time out")
+	}
+	if !strings.HasPrefix(err.Error(), "stage ack:") || !strings.Contains(err.Error(), cfg.MQTT.AckTopic) {
+		t.Errorf("error = %q; want an ack stage diagnostic naming the ACK topic", err)
+	}
+}
+
+func TestPrepareSelftestConfig_IsolatesFromConsumer(t *testing.T) {
+	cfg := testCfg()
+	cfg.Redis.GroupName = "group"
+	cfg.MQTT.ClientID = "client"
+	cfg.MQTT.PersistentSession = true
+	cfg.MQTT.StoreDir = "/var/lib/store"
+	cfg.Redis.ReceiptsStream = "receipts"
+
+	prepareSelftestConfig(cfg)
+
+	if cfg.Redis.Stream == testStreamName || !strings.HasPrefix(cfg.Redis.Stream, "syslog-consumer-selftest-") {
+		t.Errorf("stream = %q; want a temporary selftest stream", cfg.Redis.Stream)
+	}
+	if cfg.Redis.GroupName != "group-selftest" || cfg.MQTT.ClientID != "client-selftest" {
+		t.Errorf("group/client = %q/%q; want selftest suffixes", cfg.Redis.GroupName, cfg.MQTT.ClientID)
+	}
+	if cfg.MQTT.PersistentSession || cfg.MQTT.StoreDir != "" {
+		t.Error("selftest must not use a persistent MQTT session or inflight store")
+	}
+	if cfg.Redis.ReceiptsStream != "" {
+		t.Error("selftest must not write receipts")
+	}
+}
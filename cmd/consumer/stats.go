@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+var statsInterval = flag.Duration("stats-interval", 0,
+	"Print a one-line throughput report to stderr at this interval when it is a terminal (0 disables)")
+
+// statsSample is a reading of the counters the report shows.
+type statsSample struct {
+	at        time.Time
+	fetched   int64
+	published int64
+	acked     int64
+	errors    int64
+	pending   int64
+	lag       int64
+}
+
+func sampleStats(at time.Time) statsSample {
+	return statsSample{
+		at:        at,
+		fetched:   metrics.MessagesFetched.Value(),
+		published: metrics.MessagesPublished.Value(),
+		acked:     metrics.MessagesAcked.Value(),
+		errors:    metrics.FetchErrors.Value() + metrics.PublishErrors.Value() + metrics.AckErrors.Value(),
+		pending:   metrics.GroupPending.Value(),
+		lag:       metrics.GroupLag.Value(),
+	}
+}
+
+// formatStats renders the rates between prev and cur. Pending and lag are
+// only read with REDIS_LAG_INTERVAL set; without it they show as "-".
+func formatStats(prev, cur *statsSample, lagKnown bool) string {
+	secs := cur.at.Sub(prev.at).Seconds()
+	rate := func(from, to int64) string {
+		if secs <= 0 {
+			return "0/s"
+		}
+		return strconv.FormatFloat(float64(to-from)/secs, 'f', 0, 64) + "/s"
+	}
+	pending, lag := "-", "-"
+	if lagKnown {
+		pending, lag = strconv.FormatInt(cur.pending, 10), strconv.FormatInt(cur.lag, 10)
+	}
+	return fmt.Sprintf("fetched %s  published %s  acked %s  pending %s  lag %s  errors %d",
+		rate(prev.fetched, cur.fetched), rate(prev.published, cur.published), rate(prev.acked, cur.acked),
+		pending, lag, cur.errors)
+}
+
+// isTerminal reports whether f is a character device, as a terminal is.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// startStats redraws the report line on w every interval until ctx is done
+// or the returned function is called, which waits for the last line to be
+// ended.
+func startStats(ctx context.Context, w io.Writer, interval time.Duration, lagKnown bool) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		prev := sampleStats(time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				_, _ = fmt.Fprintln(w)
+				return
+			case now := <-ticker.C:
+				cur := sampleStats(now)
+				_, _ = fmt.Fprintf(w, "\r\033[K%s", formatStats(&prev, &cur, lagKnown))
+				prev = cur
+			}
+		}
+	})
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func TestFormatStats(t *testing.T) {
+	start := time.Now()
+	prev := statsSample{at: start, fetched: 100, published: 90, acked: 80}
+	cur := statsSample{
+		at: start.Add(2 * time.Second), fetched: 300, published: 290, acked: 200,
+		errors: 3, pending: 12, lag: 40,
+	}
+
+	want := "fetched 100/s  published 100/s  acked 60/s  pending 12  lag 40  errors 3"
+	if got := formatStats(&prev, &cur, true); got != want {
+		t.Errorf("formatStats() = %q; want %q", got, want)
+	}
+	if got := formatStats(&prev, &cur, false); !strings.Contains(got, "pending -  lag -") {
+		t.Errorf("formatStats() = %q; want pending and lag unknown without REDIS_LAG_INTERVAL", got)
+	}
+	if got := formatStats(&prev, &prev, false); !strings.HasPrefix(got, "fetched 0/s") {
+		t.Errorf("formatStats() = %q; want no rate over no time", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the reporter goroutine.
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStartStats(t *testing.T) {
+	var out syncBuffer
+	stop := startStats(t.Context(), &out, 10*time.Millisecond, false)
+	metrics.MessagesFetched.Add(1)
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "fetched") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	got := out.String()
+	if !strings.HasPrefix(got, "\r\033[Kfetched ") {
+		t.Errorf("report = %q; want a redrawn line", got)
+	}
+	if !strings.HasSuffix(got, "\n") {
+		t.Errorf("report = %q; want the line ended once stopped", got)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stats")
+	if err != nil {
+		t.Fatalf("CreateTemp() error = %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if isTerminal(f) {
+		t.Error("isTerminal(regular file) = true; want false")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// verifyCommand is the first argument that checks how the consumer would
+// handle the entries of the streams without publishing, acknowledging or
+// deleting any of them.
+const verifyCommand = "verify"
+
+// verifyOptions are the -verify-* flags.
+type verifyOptions struct {
+	after string // read each stream from the entry after this ID; empty for the oldest
+	limit int    // entries read per stream; 0 for all
+}
+
+// runVerify reads the configured streams with XRANGE, without a consumer
+// group and without connecting to MQTT, runs their entries through the
+// publish path and reports what it would have published. It returns the
+// process exit code.
+func runVerify(ctx context.Context) int {
+	var opts verifyOptions
+	flag.StringVar(&opts.after, "verify-after", "", "Read each stream from the entry after this ID (default: oldest)")
+	flag.IntVar(&opts.limit, "verify-limit", 1000, "Entries read per stream (0 for all)")
+
+	logger := log.New()
+	logger.Infof(ctx, "Starting syslog consumer verify")
+
+	cfg, err := loadAndLogConfig(ctx, logger)
+	if err != nil {
+		return 1
+	}
+	compress.Init(&cfg.Compress)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := verify(sigCtx, cfg, logger, &opts, os.Stderr); err != nil {
+		logger.Errorf(ctx, "Verify failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func verify(ctx context.Context, cfg *config.Config, logger *log.Logger, opts *verifyOptions, w io.Writer) error {
+	if opts.limit < 0 {
+		return errors.New("-verify-limit must not be negative")
+	}
+	reader, err := redis.NewReader(ctx, &cfg.Redis, logger)
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", err)
+		}
+	}()
+	verifier, err := hotpath.NewVerifier(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = verifier.Close() }()
+
+	streams := reader.Streams()
+	for _, stream := range streams {
+		if err := verifyStream(ctx, reader, verifier, stream, cfg.Redis.BatchSize, opts); err != nil {
+			return err
+		}
+	}
+	writeVerifyReport(w, len(streams), verifier.Report())
+	return nil
+}
+
+// verifyStream hands the verifier up to opts.limit entries of stream,
+// batchSize at a time.
+func verifyStream(
+	ctx context.Context, reader *redis.Client, verifier *hotpath.Verifier, stream string, batchSize int,
+	opts *verifyOptions,
+) error {
+	after, read := opts.after, 0
+	for opts.limit == 0 || read < opts.limit {
+		count := batchSize
+		if opts.limit > 0 {
+			count = min(count, opts.limit-read)
+		}
+		batch, err := reader.RangeBatch(ctx, stream, after, count)
+		if err != nil {
+			return err
+		}
+		if len(batch.Items) == 0 {
+			return nil
+		}
+		after = batch.Items[len(batch.Items)-1].ID
+		read += len(batch.Items)
+		verifier.Check(ctx, batch)
+	}
+	return nil
+}
+
+func writeVerifyReport(w io.Writer, streams int, report hotpath.VerifyReport) {
+	_, _ = fmt.Fprintf(w, "Verified %d entries from %d streams; nothing was published, acknowledged or deleted.\n",
+		report.Entries, streams)
+	for _, topic := range slices.Sorted(maps.Keys(report.Topics)) {
+		count := report.Topics[topic]
+		_, _ = fmt.Fprintf(w, "  would publish %d payloads (%d bytes) to %s\n", count.Payloads, count.Bytes, topic)
+	}
+	if report.DeadLettered > 0 {
+		_, _ = fmt.Fprintf(w, "  would dead-letter %d entries\n", report.DeadLettered)
+	}
+	if report.Deleted > 0 {
+		_, _ = fmt.Fprintf(w, "  would delete %d entries unpublished\n", report.Deleted)
+	}
+	_, _ = fmt.Fprintln(w, "Metrics:")
+	expvar.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok && v.Value() != 0 && strings.HasPrefix(kv.Key, "consumer.") {
+			_, _ = fmt.Fprintf(w, "  %s = %d\n", kv.Key, v.Value())
+		}
+	})
+}
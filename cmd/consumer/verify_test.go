@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func TestVerify_ReadsWithoutSideEffects(t *testing.T) {
+	s := miniredis.RunT(t)
+	cfg := testCfg()
+	cfg.Redis.Address = s.Addr()
+	cfg.Redis.GroupName = "test-group"
+	cfg.Redis.PingTimeout = time.Second
+	cfg.Redis.BatchSize = 2
+	cfg.MQTT.PublishTopic = "logs"
+	for _, raw := range []string{"a", "b", "c"} {
+		if _, err := s.XAdd(testStreamName, "*", []string{"raw", raw}); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+
+	var out strings.Builder
+	if err := verify(t.Context(), cfg, log.New(), &verifyOptions{limit: 3}, &out); err != nil {
+		t.Fatalf("verify() error = %v", err)
+	}
+	report := out.String()
+	for _, want := range []string{"Verified 3 entries from 1 streams", "would publish 2 payloads", "to logs"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+	if n := streamLen(t, s, testStreamName); n != 3 {
+		t.Errorf("%s length = %d; want 3", testStreamName, n)
+	}
+	if keys := s.Keys(); len(keys) != 1 {
+		t.Errorf("keys = %v; want only the stream", keys)
+	}
+}
+
+func TestVerify_NegativeLimit(t *testing.T) {
+	if err := verify(t.Context(), testCfg(), log.New(), &verifyOptions{limit: -1}, &strings.Builder{}); err == nil {
+		t.Error("verify() error = nil; want error for a negative limit")
+	}
+}
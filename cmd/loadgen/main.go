@@ -0,0 +1,254 @@
+// Package main is a synthetic load generator: it XADDs syslog-shaped
+// entries into one or more Redis streams at a fixed rate so the consumer can
+// be benchmarked end to end together with cmd/ackbot.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+// tick is the pacing granularity: every tick the generator tops up to the
+// number of messages that should have been sent by now.
+const tick = 10 * time.Millisecond
+
+type options struct {
+	address  string
+	password string
+	streams  []string
+	rate     int
+	total    int
+	duration time.Duration
+	report   time.Duration
+	sizeMin  int
+	sizeMax  int
+	batch    int
+	seed     uint64
+}
+
+func parseFlags(args []string) (*options, error) {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	o := &options{}
+	var streams string
+	fs.StringVar(&o.address, "redis-address", "localhost:6379", "Redis address")
+	fs.StringVar(&o.password, "redis-password", "", "Redis password")
+	fs.StringVar(&streams, "streams", "syslog-stream", "Comma-separated target streams (round-robin)")
+	fs.IntVar(&o.rate, "rate", 10000, "Messages per second (0 = unthrottled)")
+	fs.IntVar(&o.total, "count", 0, "Stop after this many messages (0 = unlimited)")
+	fs.DurationVar(&o.duration, "duration", 0, "Stop after this long (0 = until interrupted)")
+	fs.DurationVar(&o.report, "report-interval", 5*time.Second, "Progress report interval")
+	fs.IntVar(&o.sizeMin, "size-min", 200, "Minimum raw line size in bytes")
+	fs.IntVar(&o.sizeMax, "size-max", 1200, "Maximum raw line size in bytes")
+	fs.IntVar(&o.batch, "batch", 500, "XADDs per pipeline round-trip")
+	fs.Uint64Var(&o.seed, "seed", 1, "PRNG seed for reproducible runs")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	for s := range strings.SplitSeq(streams, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			o.streams = append(o.streams, s)
+		}
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *options) validate() error {
+	if len(o.streams) == 0 {
+		return errors.New("loadgen: at least one stream is required")
+	}
+	if o.rate < 0 {
+		return errors.New("loadgen: rate must not be negative")
+	}
+	if o.sizeMin < 1 || o.sizeMax < o.sizeMin {
+		return errors.New("loadgen: size-min must be positive and not exceed size-max")
+	}
+	if o.batch < 1 {
+		return errors.New("loadgen: batch must be positive")
+	}
+	if o.report <= 0 {
+		return errors.New("loadgen: report-interval must be positive")
+	}
+	return nil
+}
+
+// generator builds entries deterministically from the seed so two runs with
+// the same flags produce the same size distribution.
+type generator struct {
+	rng     *rand.Rand
+	builder *jsonfast.Builder
+	padding string
+	sizeMin int
+	sizeMax int
+	seq     uint64
+}
+
+func newGenerator(o *options) *generator {
+	return &generator{
+		rng:     rand.New(rand.NewPCG(o.seed, o.seed^0x9e3779b97f4a7c15)), //nolint:gosec // benchmark data, not crypto
+		builder: jsonfast.New(512),
+		padding: strings.Repeat("x", o.sizeMax),
+		sizeMin: o.sizeMin,
+		sizeMax: o.sizeMax,
+	}
+}
+
+// next returns the "object" and "raw" field values for one entry, mirroring
+// the shape produced by the upstream syslog ingester.
+func (g *generator) next(now time.Time) (object, raw string) {
+	g.seq++
+	severity := g.rng.IntN(8)
+	size := g.sizeMin + g.rng.IntN(g.sizeMax-g.sizeMin+1)
+
+	g.builder.Reset()
+	g.builder.BeginObject()
+	g.builder.AddTimeRFC3339Field("timestamp", now)
+	g.builder.AddIntField("severity", severity)
+	g.builder.AddStringField("facility", "local0")
+	g.builder.AddStringField("hostname", "loadgen")
+	g.builder.BeginObjectField("structured_data")
+	g.builder.AddStringField("seq", strconv.FormatUint(g.seq, 10))
+	g.builder.EndObjectField()
+	g.builder.EndObject()
+	object = string(g.builder.Bytes())
+
+	head := "<" + strconv.Itoa(128+severity) + ">1 " + now.Format(time.RFC3339Nano) + " loadgen bench - - - "
+	if pad := size - len(head); pad > 0 {
+		raw = head + g.padding[:min(pad, len(g.padding))]
+	} else {
+		raw = head
+	}
+	return object, raw
+}
+
+func run(ctx context.Context, args []string) int {
+	logger := log.New()
+
+	o, err := parseFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		logger.Errorf(ctx, "Invalid flags: %v", err)
+		return 2
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: o.address, Password: o.password})
+	defer func() {
+		if cerr := rdb.Close(); cerr != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", cerr)
+		}
+	}()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		logger.Errorf(ctx, "Failed to connect to Redis: %v", err)
+		return 1
+	}
+
+	if o.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.duration)
+		defer cancel()
+	}
+
+	logger.Infof(ctx, "Generating load: rate=%d/s streams=%v size=%d-%d batch=%d",
+		o.rate, o.streams, o.sizeMin, o.sizeMax, o.batch)
+
+	sent, err := generate(ctx, rdb, o, logger)
+	logger.Infof(ctx, "Load generation finished: %d messages sent", sent)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		logger.Errorf(ctx, "Load generation failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// generate paces XADDs against wall-clock time so a slow round-trip is
+// caught up on the next tick instead of silently lowering the rate.
+func generate(ctx context.Context, rdb *redis.Client, o *options, logger *log.Logger) (int, error) {
+	gen := newGenerator(o)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	lastReport, lastSent := start, 0
+	sent := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sent, ctx.Err()
+		case now := <-ticker.C:
+			due := dueMessages(o, now.Sub(start), sent)
+			for due > 0 {
+				n := min(due, o.batch)
+				if err := sendBatch(ctx, rdb, gen, o.streams, sent, n); err != nil {
+					return sent, err
+				}
+				sent += n
+				due -= n
+			}
+			if o.total > 0 && sent >= o.total {
+				return sent, nil
+			}
+			if elapsed := now.Sub(lastReport); elapsed >= o.report {
+				logger.Infof(ctx, "Sent %d messages (%.0f msg/s)", sent,
+					float64(sent-lastSent)/elapsed.Seconds())
+				lastReport, lastSent = now, sent
+			}
+		}
+	}
+}
+
+// dueMessages returns how many messages must be sent now to stay on the
+// configured rate, capped by the remaining total.
+func dueMessages(o *options, elapsed time.Duration, sent int) int {
+	var due int
+	if o.rate == 0 {
+		due = o.batch
+	} else {
+		due = int(elapsed.Seconds()*float64(o.rate)) - sent
+	}
+	if o.total > 0 {
+		due = min(due, o.total-sent)
+	}
+	return max(due, 0)
+}
+
+func sendBatch(ctx context.Context, rdb *redis.Client, gen *generator, streams []string, offset, n int) error {
+	now := time.Now()
+	pipe := rdb.Pipeline()
+	for i := range n {
+		object, raw := gen.next(now)
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: streams[(offset+i)%len(streams)],
+			Values: []any{"object", object, "raw", raw},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("xadd pipeline failed: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	cancel()
+	os.Exit(code)
+}
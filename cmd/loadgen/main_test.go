@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseFlags_Defaults(t *testing.T) {
+	o, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags(nil) error = %v", err)
+	}
+	if len(o.streams) != 1 || o.streams[0] != "syslog-stream" {
+		t.Errorf("streams = %v; want [syslog-stream]", o.streams)
+	}
+	if o.rate != 10000 || o.batch != 500 {
+		t.Errorf("rate/batch = %d/%d; want 10000/500", o.rate, o.batch)
+	}
+}
+
+func TestParseFlags_Streams(t *testing.T) {
+	o, err := parseFlags([]string{"-streams", "a, b,,c"})
+	if err != nil {
+		t.Fatalf("parseFlags error = %v", err)
+	}
+	if strings.Join(o.streams, ",") != "a,b,c" {
+		t.Errorf("streams = %v; want [a b c]", o.streams)
+	}
+}
+
+func TestParseFlags_Invalid(t *testing.T) {
+	cases := map[string][]string{
+		"no streams":   {"-streams", " , "},
+		"negative":     {"-rate", "-1"},
+		"size order":   {"-size-min", "100", "-size-max", "10"},
+		"zero batch":   {"-batch", "0"},
+		"zero report":  {"-report-interval", "0s"},
+		"unknown flag": {"-bogus"},
+	}
+	for name, args := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseFlags(args); err == nil {
+				t.Errorf("parseFlags(%v) error = nil; want error", args)
+			}
+		})
+	}
+}
+
+func TestDueMessages(t *testing.T) {
+	o := &options{rate: 1000, batch: 50}
+	if got := dueMessages(o, 100*time.Millisecond, 0); got != 100 {
+		t.Errorf("dueMessages(100ms, 0) = %d; want 100", got)
+	}
+	if got := dueMessages(o, 100*time.Millisecond, 150); got != 0 {
+		t.Errorf("dueMessages ahead of schedule = %d; want 0", got)
+	}
+	o.total = 120
+	if got := dueMessages(o, time.Second, 100); got != 20 {
+		t.Errorf("dueMessages capped by total = %d; want 20", got)
+	}
+	o.rate, o.total = 0, 0
+	if got := dueMessages(o, 0, 0); got != 50 {
+		t.Errorf("dueMessages unthrottled = %d; want batch size 50", got)
+	}
+}
+
+func TestGenerator_SizeAndShape(t *testing.T) {
+	o := &options{sizeMin: 100, sizeMax: 300, seed: 42}
+	g := newGenerator(o)
+	now := time.Now()
+	for range 200 {
+		object, raw := g.next(now)
+		if len(raw) < o.sizeMin || len(raw) > o.sizeMax {
+			t.Fatalf("len(raw) = %d; want within [%d, %d]", len(raw), o.sizeMin, o.sizeMax)
+		}
+		var v map[string]any
+		if err := json.Unmarshal([]byte(object), &v); err != nil {
+			t.Fatalf("object is not valid JSON: %v (%s)", err, object)
+		}
+		if _, ok := v["severity"]; !ok {
+			t.Fatalf("object missing severity: %s", object)
+		}
+	}
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	o := &options{sizeMin: 10, sizeMax: 5000, seed: 7}
+	now := time.Unix(1700000000, 0)
+	a, b := newGenerator(o), newGenerator(o)
+	for range 50 {
+		_, ra := a.next(now)
+		_, rb := b.next(now)
+		if ra != rb {
+			t.Fatal("generators with the same seed diverged")
+		}
+	}
+}
+
+func TestSendBatch_RoundRobinStreams(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	o := &options{sizeMin: 50, sizeMax: 60, seed: 1, streams: []string{"s1", "s2"}}
+	if err := sendBatch(t.Context(), rdb, newGenerator(o), o.streams, 0, 5); err != nil {
+		t.Fatalf("sendBatch error = %v", err)
+	}
+	for stream, want := range map[string]int64{"s1": 3, "s2": 2} {
+		n, err := rdb.XLen(t.Context(), stream).Result()
+		if err != nil {
+			t.Fatalf("XLen(%s) error = %v", stream, err)
+		}
+		if n != want {
+			t.Errorf("XLen(%s) = %d; want %d", stream, n, want)
+		}
+	}
+}
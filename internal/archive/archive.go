@@ -0,0 +1,351 @@
+// Package archive writes the consumed records to NDJSON files in a local
+// directory, rotated by size and age and optionally gzipped, each closed
+// with a manifest, and uploads the closed files to an S3-compatible bucket.
+//
+// Its Sink is a mqtt.Publisher, so it runs beside the broker
+// (ARCHIVE_MODE=copy) or in its place (ARCHIVE_MODE=only), where it
+// acknowledges the entries it wrote as the downstream would.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// record is the entry an archive line holds.
+type record struct {
+	id     string
+	stream string
+}
+
+// Sink writes published payloads to archive files. A Sink is safe for
+// concurrent use.
+type Sink struct {
+	log      *log.Logger
+	seg      *segment // nil until the first write after a rotation
+	handler  func(message.AckMessage)
+	uploader *uploader // nil without ARCHIVE_S3_BUCKET
+	stop     chan struct{}
+	done     chan struct{}
+	now      func() time.Time
+	dir      string
+	host     string
+	plain    []byte
+	lines    []byte
+	recs     []record
+	maxAge   time.Duration
+	maxBytes int64
+	seq      int
+	mu       sync.Mutex
+	once     sync.Once
+	gzip     bool
+}
+
+var _ mqtt.Publisher = (*Sink)(nil)
+
+// New opens the archive in cfg.Dir. Files a crash left open are rewritten
+// from their complete lines and closed, and closed files not yet uploaded
+// are queued for upload.
+func New(cfg *config.ArchiveConfig, logger *log.Logger) (*Sink, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	s := &Sink{
+		log:      logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		now:      time.Now,
+		dir:      cfg.Dir,
+		host:     host,
+		maxAge:   cfg.MaxAge,
+		maxBytes: int64(cfg.MaxBytes),
+		gzip:     cfg.Gzip,
+	}
+	if cfg.S3Bucket != "" {
+		client, err := newS3Client(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.uploader = newUploader(client, cfg, logger)
+	}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+	go s.run()
+	s.uploader.start()
+	return s, nil
+}
+
+// Publish writes the records of payload, made durable before it returns,
+// and acknowledges them to the handler SubscribeAck set.
+func (s *Sink) Publish(_ context.Context, payload message.Payload) error {
+	s.mu.Lock()
+	acks, err := s.write(payload)
+	handler := s.handler
+	s.mu.Unlock()
+	if err != nil {
+		metrics.ArchiveErrors.Add(1)
+		return err
+	}
+	for i := range acks {
+		handler(acks[i])
+	}
+	return nil
+}
+
+// SubscribeAck makes Publish acknowledge every entry it wrote. The sink
+// only takes a handler as the sole sink; beside the broker the broker's
+// acknowledgements stand.
+func (s *Sink) SubscribeAck(_ context.Context, handler func(message.AckMessage)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+	return nil
+}
+
+// Close closes the file being written and stops the uploader; an upload
+// it interrupts resumes on the next start.
+func (s *Sink) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.mu.Lock()
+		err = s.rotate()
+		s.mu.Unlock()
+		s.uploader.close()
+	})
+	return err
+}
+
+// write appends the records of payload to the open file, rotating it first
+// when it is full, and returns the acknowledgements to send. s.mu is held.
+func (s *Sink) write(payload message.Payload) ([]message.AckMessage, error) {
+	plain := payload
+	if compress.IsCompressed(payload) {
+		var err error
+		if s.plain, err = compress.Decompress(s.plain, payload); err != nil {
+			return nil, fmt.Errorf("archive: %w", err)
+		}
+		plain = s.plain
+	}
+	s.lines, s.recs = appendLines(s.lines[:0], s.recs[:0], plain)
+	if len(s.recs) == 0 {
+		return []message.AckMessage{}, nil
+	}
+	if err := s.append(s.lines, s.recs); err != nil {
+		return nil, err
+	}
+	metrics.ArchiveRecords.Add(int64(len(s.recs)))
+	metrics.ArchiveBytes.Add(int64(len(s.lines)))
+	if s.handler == nil {
+		return []message.AckMessage{}, nil
+	}
+	return groupAcks(s.recs), nil
+}
+
+// append writes lines to the open file, made durable, opening a new one
+// when there is none or the open one is full.
+func (s *Sink) append(lines []byte, recs []record) error {
+	if s.seg != nil && (s.seg.bytes >= s.maxBytes || s.now().Sub(s.seg.opened) >= s.maxAge) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	for s.seg == nil {
+		seg, err := openSegment(s.dir, s.nextName(), s.gzip, s.now())
+		if err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		s.seg = seg // nil when the name is taken: the next one is tried
+	}
+	if err := s.seg.write(lines, recs); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if err := s.seg.sync(); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	return nil
+}
+
+// nextName names a new file after the host and the time, with a sequence
+// number for files opened within the same second.
+func (s *Sink) nextName() string {
+	s.seq++
+	return s.host + "-" + s.now().UTC().Format("20060102T150405Z") + "-" + strconv.Itoa(s.seq)
+}
+
+// rotate closes the open file, if any, and hands it to the uploader.
+// s.mu is held.
+func (s *Sink) rotate() error {
+	if s.seg == nil {
+		return nil
+	}
+	seg := s.seg
+	s.seg = nil
+	if _, err := seg.close(s.now()); err != nil {
+		metrics.ArchiveErrors.Add(1)
+		return err
+	}
+	metrics.ArchiveFiles.Add(1)
+	s.uploader.kick()
+	return nil
+}
+
+// run closes the open file once it reaches ARCHIVE_MAX_AGE, even when no
+// more records come.
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(min(s.maxAge, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			var err error
+			if s.seg != nil && s.now().Sub(s.seg.opened) >= s.maxAge {
+				err = s.rotate()
+			}
+			s.mu.Unlock()
+			if err != nil {
+				s.log.Warnf(context.Background(), "Archive rotation failed: %v", err)
+			}
+		}
+	}
+}
+
+// appendLines appends the archive line of every "<id>\t<stream>\t<json>"
+// record in data to lines, and its entry to recs.
+func appendLines(lines []byte, recs []record, data []byte) ([]byte, []record) {
+	for line := range bytes.Lines(data) {
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		id, rest, ok := bytes.Cut(line, []byte{'\t'})
+		if !ok {
+			continue
+		}
+		stream, body, ok := bytes.Cut(rest, []byte{'\t'})
+		if !ok {
+			continue
+		}
+		rec := record{id: string(id), stream: string(stream)}
+		lines = append(lines, `{"id":"`...)
+		lines = append(lines, jsonfast.EscapeString(rec.id)...)
+		lines = append(lines, `","stream":"`...)
+		lines = append(lines, jsonfast.EscapeString(rec.stream)...)
+		lines = append(lines, `","record":`...)
+		if jsonfast.IsStructuralJSON(string(body)) {
+			lines = append(lines, body...)
+		} else {
+			lines = append(lines, '"')
+			lines = append(lines, jsonfast.EscapeString(string(body))...)
+			lines = append(lines, '"')
+		}
+		lines = append(lines, "}\n"...)
+		recs = append(recs, rec)
+	}
+	return lines, recs
+}
+
+// groupAcks returns one acknowledgement per stream of recs.
+func groupAcks(recs []record) []message.AckMessage {
+	var acks []message.AckMessage
+	index := make(map[string]int)
+	for _, r := range recs {
+		i, ok := index[r.stream]
+		if !ok {
+			i = len(acks)
+			index[r.stream] = i
+			acks = append(acks, message.AckMessage{Stream: r.stream, Ack: true})
+		}
+		acks[i].IDs = append(acks[i].IDs, r.id)
+	}
+	return acks
+}
+
+// recover rewrites the files a crash left open, and the closed files it
+// left without a manifest, to new files. Their complete lines are kept.
+func (s *Sink) recover() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(s.dir, name)
+		switch {
+		case strings.HasSuffix(name, manifestSuffix+openSuffix), strings.HasSuffix(name, stateSuffix+openSuffix):
+			err = os.Remove(path)
+		case strings.HasSuffix(name, openSuffix), isDataFile(name) && !exists(path+manifestSuffix):
+			err = s.recoverFile(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverFile writes the complete lines of path to a new file and removes
+// path.
+func (s *Sink) recoverFile(path string) error {
+	lines, err := recoverLines(path)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	recs := make([]record, 0, bytes.Count(lines, []byte{'\n'}))
+	for line := range bytes.Lines(lines) {
+		var r struct {
+			ID     string `json:"id"`
+			Stream string `json:"stream"`
+		}
+		_ = json.Unmarshal(line, &r) // the lines are kept all the same
+		recs = append(recs, record{id: r.ID, stream: r.Stream})
+	}
+	if len(recs) > 0 {
+		if err := s.append(lines, recs); err != nil {
+			return err
+		}
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		s.log.Infof(context.Background(), "Recovered %d archive records from %s", len(recs), filepath.Base(path))
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("archive: %w", err)
+	}
+	return nil
+}
+
+// isDataFile reports whether name is a closed archive file.
+func isDataFile(name string) bool {
+	return strings.HasSuffix(name, ndjsonSuffix) || strings.HasSuffix(name, ndjsonSuffix+gzipSuffix)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,220 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestMain(m *testing.M) {
+	compress.Init(&config.CompressConfig{FreelistSize: 4, MaxDecompressBytes: 64 << 20, WarmupCount: 1})
+	os.Exit(m.Run())
+}
+
+func testArchiveConfig(t *testing.T) *config.ArchiveConfig {
+	t.Helper()
+	return &config.ArchiveConfig{Mode: config.ArchiveOnly, Dir: t.TempDir(), MaxAge: time.Hour, MaxBytes: 1 << 20}
+}
+
+// closedFiles returns the manifests of the closed files in dir, by file
+// name, and checks each against its file.
+func closedFiles(t *testing.T, dir string) map[string]Manifest {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+manifestSuffix))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	manifests := make(map[string]Manifest, len(paths))
+	for _, path := range paths {
+		var m Manifest
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", path, err)
+		}
+		file, err := os.ReadFile(filepath.Join(dir, m.File))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if sum := sha256.Sum256(file); hex.EncodeToString(sum[:]) != m.SHA256 || int64(len(file)) != m.Size {
+			t.Errorf("%s: manifest sha256/size = %s/%d; file has %x/%d", m.File, m.SHA256, m.Size, sum, len(file))
+		}
+		manifests[m.File] = m
+	}
+	return manifests
+}
+
+// readLines returns the NDJSON lines of the archive file at path.
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	var r io.Reader = file
+	if strings.HasSuffix(path, gzipSuffix) {
+		if r, err = gzip.NewReader(file); err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestSink_PublishWritesAndAcks(t *testing.T) {
+	cfg := testArchiveConfig(t)
+	cfg.Gzip = true
+	sink, err := New(cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var acks []message.AckMessage
+	if err := sink.SubscribeAck(t.Context(), func(ack message.AckMessage) { acks = append(acks, ack) }); err != nil {
+		t.Fatalf("SubscribeAck() error = %v", err)
+	}
+
+	plain := []byte("1-0\tsyslog:a\t{\"msg\":\"one\"}\n2-0\tsyslog:b\t{\"msg\":\"two\"}\n3-0\tsyslog:a\tnot json\n")
+	marked := compress.EncodeMarked(compress.NewEncoder(), nil, []byte("4-0\tsyslog:b\t{\"msg\":\"four\"}\n"),
+		[]byte("origin"))
+	for _, payload := range [][]byte{plain, marked} {
+		if err := sink.Publish(t.Context(), payload); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	wantAcks := []message.AckMessage{
+		{Stream: "syslog:a", IDs: []string{"1-0", "3-0"}, Ack: true},
+		{Stream: "syslog:b", IDs: []string{"2-0"}, Ack: true},
+		{Stream: "syslog:b", IDs: []string{"4-0"}, Ack: true},
+	}
+	if !reflect.DeepEqual(acks, wantAcks) {
+		t.Errorf("acks = %+v; want %+v", acks, wantAcks)
+	}
+
+	manifests := closedFiles(t, cfg.Dir)
+	if len(manifests) != 1 {
+		t.Fatalf("closed files = %d; want 1", len(manifests))
+	}
+	for name, m := range manifests {
+		if !strings.HasSuffix(name, ndjsonSuffix+gzipSuffix) || !m.Gzip || m.Records != 4 {
+			t.Errorf("manifest = %+v; want 4 gzipped records", m)
+		}
+		wantStreams := map[string]StreamRange{
+			"syslog:a": {First: "1-0", Last: "3-0", Records: 2},
+			"syslog:b": {First: "2-0", Last: "4-0", Records: 2},
+		}
+		if !reflect.DeepEqual(m.Streams, wantStreams) {
+			t.Errorf("Streams = %+v; want %+v", m.Streams, wantStreams)
+		}
+		want := []string{
+			`{"id":"1-0","stream":"syslog:a","record":{"msg":"one"}}`,
+			`{"id":"2-0","stream":"syslog:b","record":{"msg":"two"}}`,
+			`{"id":"3-0","stream":"syslog:a","record":"not json"}`,
+			`{"id":"4-0","stream":"syslog:b","record":{"msg":"four"}}`,
+		}
+		if got := readLines(t, filepath.Join(cfg.Dir, name)); !reflect.DeepEqual(got, want) {
+			t.Errorf("lines = %q; want %q", got, want)
+		}
+	}
+}
+
+func TestSink_Rotates(t *testing.T) {
+	cfg := testArchiveConfig(t)
+	cfg.MaxBytes = 1
+	sink, err := New(cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, payload := range []string{"1-0\ts\t{}\n", "2-0\ts\t{}\n", "3-0\ts\t{}\n"} {
+		if err := sink.Publish(t.Context(), []byte(payload)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if got := len(closedFiles(t, cfg.Dir)); got != 2 {
+		t.Errorf("closed files before Close = %d; want 2", got)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := len(closedFiles(t, cfg.Dir)); got != 3 {
+		t.Errorf("closed files after Close = %d; want 3", got)
+	}
+
+	cfg = testArchiveConfig(t)
+	cfg.MaxAge = 10 * time.Millisecond
+	if sink, err = New(cfg, log.New()); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = sink.Close() }()
+	if err := sink.Publish(t.Context(), []byte("1-0\ts\t{}\n")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(closedFiles(t, cfg.Dir)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(closedFiles(t, cfg.Dir)); got != 1 {
+		t.Errorf("closed files after max age = %d; want 1", got)
+	}
+}
+
+func TestNew_RecoversOpenFiles(t *testing.T) {
+	cfg := testArchiveConfig(t)
+	lines := `{"id":"1-0","stream":"s","record":{}}` + "\n" + `{"id":"2-0","stream":"s","record":{}}` + "\n"
+	torn := lines + `{"id":"3-0","str`
+	if err := os.WriteFile(filepath.Join(cfg.Dir, "host-1"+ndjsonSuffix+openSuffix), []byte(torn), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte(lines))
+	_ = w.Flush() // a crash leaves the stream without its trailer
+	if err := os.WriteFile(filepath.Join(cfg.Dir, "host-2"+ndjsonSuffix+gzipSuffix+openSuffix), gz.Bytes(),
+		0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	sink, err := New(cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if open, _ := filepath.Glob(filepath.Join(cfg.Dir, "*"+openSuffix)); len(open) != 0 {
+		t.Errorf("open files left = %v", open)
+	}
+	manifests := closedFiles(t, cfg.Dir)
+	if len(manifests) != 2 {
+		t.Fatalf("closed files = %d; want 2", len(manifests))
+	}
+	for _, m := range manifests {
+		if want := (StreamRange{First: "1-0", Last: "2-0", Records: 2}); m.Records != 2 || m.Streams["s"] != want {
+			t.Errorf("manifest = %+v; want the 2 complete records", m)
+		}
+	}
+}
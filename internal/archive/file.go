@@ -0,0 +1,211 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File name suffixes. A file being written ends in openSuffix; it is
+// renamed without it once closed, and its manifest is written next to it.
+const (
+	ndjsonSuffix   = ".ndjson"
+	gzipSuffix     = ".gz"
+	openSuffix     = ".open"
+	manifestSuffix = ".manifest.json"
+)
+
+// Manifest describes one closed archive file.
+type Manifest struct {
+	Opened  time.Time              `json:"opened"`
+	Closed  time.Time              `json:"closed"`
+	Streams map[string]StreamRange `json:"streams"`
+	File    string                 `json:"file"`
+	SHA256  string                 `json:"sha256"`
+	// Records and Bytes count the NDJSON lines and their size before
+	// compression; Size is the size of the file.
+	Records int   `json:"records"`
+	Bytes   int64 `json:"bytes"`
+	Size    int64 `json:"size"`
+	Gzip    bool  `json:"gzip"`
+}
+
+// StreamRange is the entries of one stream in an archive file.
+type StreamRange struct {
+	First   string `json:"first"`
+	Last    string `json:"last"`
+	Records int    `json:"records"`
+}
+
+// segment is the archive file being written.
+type segment struct {
+	opened   time.Time
+	file     *os.File
+	gz       *gzip.Writer
+	hash     io.Writer // sha256 of what reaches the file
+	sum      func() []byte
+	out      io.Writer // gz, or the hashed file
+	streams  map[string]StreamRange
+	path     string // final path, without openSuffix
+	records  int
+	bytes    int64
+	size     int64
+	compress bool
+}
+
+// openSegment creates name in dir, with openSuffix until it is closed. It
+// fails with os.ErrExist when the name is taken.
+func openSegment(dir, name string, compress bool, now time.Time) (*segment, error) {
+	path := filepath.Join(dir, name+ndjsonSuffix)
+	if compress {
+		path += gzipSuffix
+	}
+	if exists(path) { // left by an earlier run within the same second
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrExist}
+	}
+	file, err := os.OpenFile(filepath.Clean(path+openSuffix), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	h := sha256.New()
+	s := &segment{
+		opened:   now,
+		file:     file,
+		streams:  make(map[string]StreamRange),
+		path:     path,
+		compress: compress,
+		sum:      func() []byte { return h.Sum(nil) },
+	}
+	s.hash = io.MultiWriter(file, h, (*countWriter)(&s.size))
+	s.out = s.hash
+	if compress {
+		s.gz = gzip.NewWriter(s.hash)
+		s.out = s.gz
+	}
+	return s, nil
+}
+
+type countWriter int64
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
+
+// write appends lines, the records of ids in stream order, and counts them.
+func (s *segment) write(lines []byte, recs []record) error {
+	if _, err := s.out.Write(lines); err != nil {
+		return err
+	}
+	s.bytes += int64(len(lines))
+	s.records += len(recs)
+	for i := range recs {
+		r := s.streams[recs[i].stream]
+		if r.Records == 0 {
+			r.First = recs[i].id
+		}
+		r.Last = recs[i].id
+		r.Records++
+		s.streams[recs[i].stream] = r
+	}
+	return nil
+}
+
+// sync makes what was written so far durable.
+func (s *segment) sync() error {
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return s.file.Sync()
+}
+
+// close finishes the file, renames it to its final name and writes its
+// manifest.
+func (s *segment) close(now time.Time) (*Manifest, error) {
+	var errs []error
+	if s.gz != nil {
+		errs = append(errs, s.gz.Close())
+	}
+	errs = append(errs, s.file.Sync(), s.file.Close(), os.Rename(s.path+openSuffix, s.path))
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("archive: closing %s: %w", filepath.Base(s.path), err)
+	}
+	m := &Manifest{
+		Opened:  s.opened.UTC(),
+		Closed:  now.UTC(),
+		Streams: s.streams,
+		File:    filepath.Base(s.path),
+		SHA256:  hex.EncodeToString(s.sum()),
+		Records: s.records,
+		Bytes:   s.bytes,
+		Size:    s.size,
+		Gzip:    s.compress,
+	}
+	return m, writeManifest(s.path, m)
+}
+
+// writeManifest writes m next to the file at path, durably.
+func writeManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path+manifestSuffix, append(data, '\n')); err != nil {
+		return fmt.Errorf("archive: writing the manifest of %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeFile replaces the file at path with data, durably: data is written
+// to a temporary file first and renamed over path.
+func writeFile(path string, data []byte) error {
+	tmp := path + openSuffix
+	file, err := os.OpenFile(filepath.Clean(tmp), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err = errors.Join(err, file.Sync(), file.Close()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recoverLines returns the complete lines of a file left open by a crash,
+// read through gzip when it is compressed, up to the first line cut short
+// or the first unreadable byte.
+func recoverLines(path string) ([]byte, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	var lines []byte
+	var r io.Reader = file
+	if strings.HasSuffix(strings.TrimSuffix(path, openSuffix), gzipSuffix) {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return lines, nil //nolint:nilerr // an empty or torn header holds no lines
+		}
+		r = gz
+	}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			return lines, nil //nolint:nilerr // a torn tail ends the recoverable lines
+		}
+		lines = append(lines, line...)
+	}
+}
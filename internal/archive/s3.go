@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// errNoSuchUpload is returned for a multipart upload the bucket no longer
+// knows, which has to start over.
+var errNoSuchUpload = errors.New("s3: no such upload")
+
+// s3Client speaks the subset of the S3 API the uploader needs, to AWS or
+// any S3-compatible server, with path-style URLs and Signature Version 4.
+type s3Client struct {
+	http      *http.Client
+	endpoint  *url.URL
+	now       func() time.Time
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func newS3Client(cfg *config.ArchiveConfig) (*s3Client, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.S3Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	return &s3Client{
+		http:      &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  endpoint,
+		bucket:    cfg.S3Bucket,
+		region:    cfg.S3Region,
+		accessKey: cfg.S3AccessKeyID,
+		secretKey: cfg.S3SecretAccessKey,
+		now:       time.Now,
+	}, nil
+}
+
+// completedPart is one part of a multipart upload, as CompleteMultipartUpload
+// lists it.
+type completedPart struct {
+	ETag       string `xml:"ETag" json:"etag"`
+	PartNumber int    `xml:"PartNumber" json:"part"`
+}
+
+// putObject uploads body as key in one request.
+func (c *s3Client) putObject(ctx context.Context, key string, body []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// createUpload starts a multipart upload of key and returns its ID.
+func (c *s3Client) createUpload(ctx context.Context, key string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("s3: no upload ID in the CreateMultipartUpload reply (%v)", err)
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads part number n of an upload and returns its ETag.
+func (c *s3Client) uploadPart(ctx context.Context, key, uploadID string, n int, body []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}
+	resp, err := c.do(ctx, http.MethodPut, key, query, body)
+	if err != nil {
+		return "", err
+	}
+	if err := resp.Body.Close(); err != nil {
+		return "", err
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", errors.New("s3: no ETag in the UploadPart reply")
+	}
+	return etag, nil
+}
+
+// completeUpload assembles the parts of an upload into key.
+func (c *s3Client) completeUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// S3 may report a failure in a 200 reply once it has started sending it.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return fmt.Errorf("s3: CompleteMultipartUpload: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// do sends a signed request for key and returns the reply when its status
+// is 2xx; the caller closes its body.
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path += "/" + c.bucket + "/" + key
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, body)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound && bytes.Contains(reply, []byte("NoSuchUpload")) {
+		return nil, errNoSuchUpload
+	}
+	return nil, fmt.Errorf("s3: %s %s: %s: %s", method, key, resp.Status, bytes.TrimSpace(reply))
+}
+
+// sign adds the Signature Version 4 Authorization header to req, covering
+// the host and every header req already has.
+func (c *s3Client) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	stamp, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name, v := range req.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	slices.Sort(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + values[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers.String(), signed,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), day)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.accessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// stateSuffix ends the file that records the progress of a multipart
+// upload, next to the file uploaded.
+const stateSuffix = ".upload.json"
+
+// uploadState is the progress of a multipart upload, saved after every
+// part so an interrupted upload resumes where it stopped.
+type uploadState struct {
+	UploadID  string          `json:"upload_id"`
+	Parts     []completedPart `json:"parts"`
+	PartBytes int             `json:"part_bytes"`
+}
+
+// uploader uploads the closed archive files, those with a manifest, to the
+// bucket: the file first, then its manifest, so a manifest in the bucket
+// always describes a complete file. Both are removed locally once
+// uploaded. A failed pass is tried again after ARCHIVE_UPLOAD_RETRY.
+type uploader struct {
+	client    *s3Client
+	log       *log.Logger
+	cancel    context.CancelFunc // set by start
+	kicks     chan struct{}
+	done      chan struct{}
+	dir       string
+	prefix    string
+	retry     time.Duration
+	partBytes int
+}
+
+func newUploader(client *s3Client, cfg *config.ArchiveConfig, logger *log.Logger) *uploader {
+	return &uploader{
+		client:    client,
+		log:       logger,
+		kicks:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		dir:       cfg.Dir,
+		prefix:    cfg.S3Prefix,
+		retry:     cfg.UploadRetry,
+		partBytes: cfg.S3PartBytes,
+	}
+}
+
+// start runs the uploader in the background. A nil uploader does nothing,
+// as do its other methods.
+func (u *uploader) start() {
+	if u == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancel = cancel
+	go u.run(ctx)
+}
+
+// kick starts a pass for a newly closed file.
+func (u *uploader) kick() {
+	if u == nil {
+		return
+	}
+	select {
+	case u.kicks <- struct{}{}:
+	default:
+	}
+}
+
+// close interrupts the pass in progress and waits for it to end.
+func (u *uploader) close() {
+	if u == nil {
+		return
+	}
+	u.cancel()
+	<-u.done
+}
+
+func (u *uploader) run(ctx context.Context) {
+	defer close(u.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.kicks:
+		case <-timer.C:
+		}
+		if err := u.uploadAll(ctx); err != nil && ctx.Err() == nil {
+			metrics.ArchiveUploadErrors.Add(1)
+			u.log.Warnf(ctx, "Archive upload failed, retrying in %v: %v", u.retry, err)
+		}
+		timer.Reset(u.retry)
+	}
+}
+
+// uploadAll uploads every closed file, oldest first, and stops at the
+// first failure.
+func (u *uploader) uploadAll(ctx context.Context) error {
+	entries, err := os.ReadDir(u.dir)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasSuffix(name, manifestSuffix) {
+			pending = append(pending, strings.TrimSuffix(name, manifestSuffix))
+		}
+	}
+	metrics.ArchivePendingUploads.Set(int64(len(pending)))
+	for i, name := range pending {
+		if err := u.upload(ctx, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		metrics.ArchiveUploads.Add(1)
+		metrics.ArchivePendingUploads.Set(int64(len(pending) - i - 1))
+	}
+	return nil
+}
+
+// upload uploads the file name and its manifest, then removes them. The
+// file is gone already when an earlier pass stopped after removing it.
+func (u *uploader) upload(ctx context.Context, name string) error {
+	path := filepath.Join(u.dir, name)
+	if exists(path) {
+		if err := u.uploadFile(ctx, path, u.prefix+name); err != nil {
+			return err
+		}
+	}
+	manifest, err := os.ReadFile(filepath.Clean(path + manifestSuffix))
+	if err != nil {
+		return err
+	}
+	if err := u.client.putObject(ctx, u.prefix+name+manifestSuffix, manifest); err != nil {
+		return err
+	}
+	for _, p := range []string{path, path + stateSuffix, path + manifestSuffix} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadFile uploads the file at path as key: in one request up to
+// ARCHIVE_S3_PART_BYTES, in parts beyond.
+func (u *uploader) uploadFile(ctx context.Context, path, key string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > int64(u.partBytes) {
+		return u.uploadParts(ctx, path, key, info.Size())
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	return u.client.putObject(ctx, key, data)
+}
+
+// uploadParts uploads the file at path as key in a multipart upload,
+// resuming the one its state file records. An upload the bucket no longer
+// knows, aborted or expired, starts over on the next pass.
+func (u *uploader) uploadParts(ctx context.Context, path, key string, size int64) error {
+	statePath := path + stateSuffix
+	state, err := u.resume(ctx, statePath, key)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	buf := make([]byte, u.partBytes)
+	for n := len(state.Parts) + 1; int64(n-1)*int64(u.partBytes) < size; n++ {
+		read, err := file.ReadAt(buf, int64(n-1)*int64(u.partBytes))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		etag, err := u.client.uploadPart(ctx, key, state.UploadID, n, buf[:read])
+		if err != nil {
+			return u.restartOn(ctx, err, statePath)
+		}
+		state.Parts = append(state.Parts, completedPart{ETag: etag, PartNumber: n})
+		if err := saveState(statePath, state); err != nil {
+			return err
+		}
+	}
+	return u.restartOn(ctx, u.client.completeUpload(ctx, key, state.UploadID, state.Parts), statePath)
+}
+
+// resume returns the state of the upload of key saved at statePath, or of
+// a new upload when there is none or it was made with another part size.
+func (u *uploader) resume(ctx context.Context, statePath, key string) (*uploadState, error) {
+	state := &uploadState{}
+	if data, err := os.ReadFile(filepath.Clean(statePath)); err == nil {
+		if json.Unmarshal(data, state) == nil && state.UploadID != "" && state.PartBytes == u.partBytes {
+			return state, nil
+		}
+	}
+	id, err := u.client.createUpload(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	state = &uploadState{UploadID: id, Parts: []completedPart{}, PartBytes: u.partBytes}
+	return state, saveState(statePath, state)
+}
+
+// restartOn drops the saved state when err says the upload is gone, and
+// returns err.
+func (u *uploader) restartOn(ctx context.Context, err error, statePath string) error {
+	if errors.Is(err, errNoSuchUpload) {
+		u.log.Warnf(ctx, "Archive upload of %s is gone, starting over", filepath.Base(statePath))
+		_ = os.Remove(statePath)
+	}
+	return err
+}
+
+func saveState(path string, state *uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
@@ -0,0 +1,100 @@
+// Package certs loads the PEM certificates named in the configuration, for
+// the MQTT connections and the health listener alike. Each setting holds
+// either the path of a PEM file or, to pass a key through the environment
+// without a file, the PEM data itself.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pemPrefix starts every PEM block, and no sensible path.
+const pemPrefix = "-----BEGIN "
+
+// Inline reports whether value holds PEM data rather than a path.
+func Inline(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), pemPrefix)
+}
+
+// Read returns the PEM data of value: value itself when inline, or else
+// the content of the file it names.
+func Read(value string) ([]byte, error) {
+	if Inline(value) {
+		return []byte(value), nil
+	}
+	return os.ReadFile(filepath.Clean(value))
+}
+
+// Name describes value in messages: its path, or "inline PEM", so a key
+// passed inline never reaches a log.
+func Name(value string) string {
+	if Inline(value) {
+		return "inline PEM"
+	}
+	return value
+}
+
+// Pool reads the PEM certificates of value into a pool, to verify the
+// peer's certificate against.
+func Pool(value string) (*x509.CertPool, error) {
+	pem, err := Read(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA cert: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("failed to parse CA cert")
+	}
+	return pool, nil
+}
+
+// LoadPair is tls.LoadX509KeyPair for a certificate and key each given as
+// a path or inline.
+func LoadPair(certValue, keyValue string) (tls.Certificate, error) {
+	certPEM, err := Read(certValue)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := Read(keyValue)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// KeyPair loads a certificate and its private key; what names the pair in
+// the error, as "client" or "server".
+func KeyPair(certValue, keyValue, what string) (tls.Certificate, error) {
+	cert, err := LoadPair(certValue, keyValue)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load %s cert/key: %w", what, err)
+	}
+	return cert, nil
+}
+
+// Server returns the TLS configuration of a listener presenting the
+// certPath/keyPath pair. With clientCA set, clients must present a
+// certificate it signed.
+func Server(certPath, keyPath, clientCA string) (*tls.Config, error) {
+	cert, err := KeyPair(certPath, keyPath, "server")
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCA != "" {
+		if cfg.ClientCAs, err = Pool(clientCA); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
@@ -0,0 +1,175 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issuer signs test certificates.
+type issuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// writeCert writes a certificate for name and its key to dir, signed by
+// parent or self-signed as a CA when parent is nil, and returns it with
+// both paths.
+func writeCert(t *testing.T, dir, name string, parent *issuer) (ca *issuer, certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer := &issuer{cert: tmpl, key: key}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer = parent
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer.cert, &key.PublicKey, signer.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	certPath, keyPath = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+	return &issuer{cert: cert, key: key}, certPath, keyPath
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile(%s) error = %v", path, err)
+	}
+}
+
+func TestServer_RequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca, caPath, _ := writeCert(t, dir, "ca", nil)
+	_, serverCert, serverKey := writeCert(t, dir, "server", ca)
+	_, clientCert, clientKey := writeCert(t, dir, "client", ca)
+
+	cfg, err := Server(serverCert, serverKey, caPath)
+	if err != nil {
+		t.Fatalf("Server() error = %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	ts.TLS = cfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots, err := Pool(caPath)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12},
+		}}
+		defer client.CloseIdleConnections()
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL, http.NoBody)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	pair, err := KeyPair(clientCert, clientKey, "client")
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+	if err := get(pair); err != nil {
+		t.Errorf("GET with a client certificate: %v; want it served", err)
+	}
+	if err := get(); err == nil {
+		t.Error("GET without a client certificate succeeded; want the handshake refused")
+	}
+}
+
+func TestServer_WithoutClientCA(t *testing.T) {
+	dir := t.TempDir()
+	_, cert, key := writeCert(t, dir, "server", nil)
+	cfg, err := Server(cert, key, "")
+	if err != nil {
+		t.Fatalf("Server() error = %v", err)
+	}
+	if cfg.ClientAuth != tls.NoClientCert || len(cfg.Certificates) != 1 {
+		t.Errorf("ClientAuth = %v, %d certificates; want no client auth and the server pair",
+			cfg.ClientAuth, len(cfg.Certificates))
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, cert, key := writeCert(t, dir, "server", nil)
+	if _, err := Server(cert, filepath.Join(dir, "missing.key"), ""); err == nil {
+		t.Error("Server() with a missing key: nil error")
+	}
+	if _, err := Server(cert, key, filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("Server() with a missing client CA: nil error")
+	}
+	if _, err := Server(cert, key, key); err == nil {
+		t.Error("Server() with a key as client CA: nil error")
+	}
+}
+
+func TestKeyPair_Inline(t *testing.T) {
+	dir := t.TempDir()
+	_, certPath, keyPath := writeCert(t, dir, "client", nil)
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if _, err := KeyPair(certPath, "\n"+string(keyPEM), "client"); err != nil {
+		t.Errorf("KeyPair() with an inline key: %v", err)
+	}
+	if _, err := Pool(string(certPEM)); err != nil {
+		t.Errorf("Pool() with an inline CA: %v", err)
+	}
+	if got := Name(string(keyPEM)); got != "inline PEM" {
+		t.Errorf("Name(inline key) = %q; want inline PEM", got)
+	}
+	if got := Name(keyPath); got != keyPath {
+		t.Errorf("Name(path) = %q; want the path", got)
+	}
+}
@@ -5,6 +5,8 @@
 package compress
 
 import (
+	"encoding/binary"
+
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/klauspost/compress/zstd"
 )
@@ -75,6 +77,45 @@ func EncodeWith(enc *zstd.Encoder, dst, src []byte) []byte {
 	return enc.EncodeAll(src, dst[:0])
 }
 
+// EncodeMarked is EncodeWith with an origin frame (AppendOrigin) ahead of
+// the compressed one; an empty origin adds nothing.
+func EncodeMarked(enc *zstd.Encoder, dst, src, origin []byte) []byte {
+	return enc.EncodeAll(src, AppendOrigin(dst[:0], origin))
+}
+
+// originMagic is the magic of origin frames, one of the sixteen skippable
+// frame magics 0x184D2A50-0x184D2A5F (RFC 8878 §3.1.2).
+const originMagic = 0x184D2A5C
+
+// maxOriginLen bounds the origin an origin frame carries.
+const maxOriginLen = 1024
+
+// AppendOrigin appends to dst a skippable frame carrying origin, which
+// names the publisher of the payload that follows. Zstandard decoders skip
+// the frame, so a marked payload decompresses to the same bytes. An empty
+// origin appends nothing; one over maxOriginLen bytes is cut short.
+func AppendOrigin(dst, origin []byte) []byte {
+	if len(origin) == 0 {
+		return dst
+	}
+	origin = origin[:min(len(origin), maxOriginLen)]
+	dst = binary.LittleEndian.AppendUint32(dst, originMagic)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(min(len(origin), maxOriginLen))) // min elides gosec G115
+	return append(dst, origin...)
+}
+
+// Origin returns the origin of a payload that starts with an origin frame.
+func Origin(data []byte) ([]byte, bool) {
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != originMagic {
+		return nil, false
+	}
+	size := uint64(binary.LittleEndian.Uint32(data[4:]))
+	if size > uint64(len(data)-8) {
+		return nil, false
+	}
+	return data[8 : 8+size], true
+}
+
 // Decompress is thread-safe and zero-alloc in steady state.
 func Decompress(dst, src []byte) ([]byte, error) {
 	dec := getDecoder()
@@ -83,8 +124,12 @@ func Decompress(dst, src []byte) ([]byte, error) {
 	return out, err
 }
 
-// IsCompressed checks for the Zstandard magic 0xFD2FB528 (RFC 8878 §3.1.1).
+// IsCompressed checks for the Zstandard magic 0xFD2FB528 (RFC 8878 §3.1.1),
+// or an origin frame ahead of it.
 func IsCompressed(data []byte) bool {
+	if _, ok := Origin(data); ok {
+		return true
+	}
 	return len(data) >= 4 &&
 		data[0] == 0x28 && data[1] == 0xB5 &&
 		data[2] == 0x2F && data[3] == 0xFD
@@ -278,3 +278,32 @@ func BenchmarkEncodeWith(b *testing.B) {
 		dst = EncodeWith(enc, dst, src)
 	}
 }
+
+func TestEncodeMarked_Origin(t *testing.T) {
+	src := []byte(`{"host":"srv1","msg":"marked"}` + "\n")
+	enc := NewEncoder()
+	marked := EncodeMarked(enc, nil, src, []byte("syslog-consumer:edge-1"))
+
+	origin, ok := Origin(marked)
+	if !ok || string(origin) != "syslog-consumer:edge-1" {
+		t.Fatalf("Origin() = %q, %v; want syslog-consumer:edge-1", origin, ok)
+	}
+	if !IsCompressed(marked) {
+		t.Error("IsCompressed() = false; want true for a marked payload")
+	}
+	got, err := Decompress(nil, marked)
+	if err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Fatalf("Decompress() = %q; want %q: decoders must skip the origin frame", got, src)
+	}
+
+	plain := EncodeMarked(enc, nil, src, nil)
+	if _, ok := Origin(plain); ok {
+		t.Error("Origin() ok = true; want false without an origin")
+	}
+	if _, ok := Origin(marked[:10]); ok {
+		t.Error("Origin() ok = true; want false for a truncated frame")
+	}
+}
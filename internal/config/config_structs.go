@@ -2,15 +2,69 @@
 // environment variables and command line flags.
 package config
 
-import "time"
+import (
+	"path"
+	"time"
+)
 
 // Config aggregates every subsystem's configuration.
 type Config struct {
-	Log      LogConfig
-	MQTT     MQTTConfig
-	Pipeline PipelineConfig
-	Redis    RedisConfig
-	Compress CompressConfig
+	// Profile is the APP_PROFILE that tuned the defaults, empty for none,
+	// and ProfileOverrides the environment variables of the settings it
+	// tunes that the environment or flags changed.
+	Profile          string
+	ProfileOverrides []string
+	Log              LogConfig
+	MQTT             MQTTConfig
+	Archive          ArchiveConfig
+	Redis            RedisConfig
+	Pipeline         PipelineConfig
+	Compress         CompressConfig
+	Runtime          RuntimeConfig
+}
+
+// Archive modes for ArchiveConfig.Mode; an empty Mode disables the archive.
+const (
+	// ArchiveCopy archives what is published to MQTT_PUBLISH_TOPIC once
+	// the publish succeeded; the entries are still acknowledged by the
+	// MQTT receivers.
+	ArchiveCopy = "copy"
+	// ArchiveOnly makes the archive the sole sink: nothing is published to
+	// MQTT, and entries are acknowledged once written to disk.
+	ArchiveOnly = "only"
+)
+
+// ArchiveConfig writes the published records as NDJSON files to Dir,
+// rotated at MaxBytes of records or MaxAge, gzipped with Gzip, each with a
+// manifest. With S3Bucket set, closed files are uploaded to it, by
+// multipart upload of S3PartBytes parts that resumes after a restart, and
+// removed from Dir; failed uploads are retried every UploadRetry.
+type ArchiveConfig struct {
+	Mode              string
+	Dir               string
+	S3Endpoint        string
+	S3Bucket          string
+	S3Region          string
+	S3Prefix          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	MaxAge            time.Duration
+	UploadRetry       time.Duration
+	MaxBytes          int
+	S3PartBytes       int
+	Gzip              bool
+}
+
+// RuntimeConfig tunes the garbage collector of the consumer binary. GOGC
+// and GOMEMLIMIT in the environment take precedence over both settings.
+type RuntimeConfig struct {
+	// GCPercent is the GOGC the collector runs with.
+	GCPercent int
+	// MemoryLimit is the soft memory limit of the Go runtime in bytes. Zero
+	// derives it as MemoryLimitPercent of the cgroup memory limit, or uses
+	// 2 GiB when the process has none.
+	MemoryLimit        int
+	MemoryLimitPercent int
 }
 
 // CompressConfig tunes the zstd encoder/decoder freelists.
@@ -23,24 +77,84 @@ type CompressConfig struct {
 // LogConfig is a placeholder for future logging knobs; currently only Level.
 type LogConfig struct {
 	Level string
+	// QueueSize bounds the records waiting to be written under Async;
+	// records that find it full are dropped and counted.
+	QueueSize int
+	// PayloadPreviewBytes is how many bytes of a payload a log record
+	// shows, cut on a UTF-8 boundary; 0 shows only its size.
+	PayloadPreviewBytes int
+	// Async writes records from a background goroutine, so a slow stdout
+	// never stalls the pipeline.
+	Async bool
 }
 
 // RedisConfig drives the Redis stream consumer and its connection pool.
 type RedisConfig struct {
-	Address             string
-	Stream              string
-	Consumer            string
-	GroupName           string
-	BatchSize           int
-	DiscoveryScanCount  int
-	BlockTimeout        time.Duration
-	ClaimIdle           time.Duration
+	Address          string
+	Stream           string
+	Consumer         string
+	GroupName        string
+	Username         string
+	Password         string
+	PasswordFile     string // holds Password, e.g. a mounted secret; read at startup
+	DeadLetterStream string
+	ReceiptsStream   string
+	Dedup            string
+	// MemoryPressureKey is kept set while the server is under memory
+	// pressure (MemoryHighPercent); empty sets no key.
+	MemoryPressureKey string
+	// ControlKey names a hash read every ControlInterval for runtime
+	// settings: log_level, claim_interval and paused. A field that is
+	// removed goes back to the configured setting; empty reads no hash.
+	ControlKey string
+	// KeyPrefix is prepended to every key the consumer names: Stream,
+	// GroupName, the dead letter, receipts, control and memory pressure
+	// keys, the enrich hashes and the consumer's own keys. In multi-stream
+	// mode only streams under it are discovered. Empty adds nothing.
+	KeyPrefix string
+	// ReplicaAddress is a read replica of Address that answers the calls
+	// that only watch the streams: discovery, the group lag and read-only
+	// passes. Consuming, acknowledging and deleting stay on Address, and so
+	// does a call the replica fails. Empty sends everything to Address.
+	ReplicaAddress string
+	// BackfillPolicy orders the Backfill phase: drain-first, claim-first,
+	// interleaved:<backlog>:<fresh> or fresh-only (see ParseBackfillPolicy).
+	BackfillPolicy string
+	// StreamOverridesFile is a JSON file of per-stream overrides, parsed
+	// into StreamOverrides while loading; empty applies none.
+	StreamOverridesFile string
+	// StreamInclude and StreamExclude filter the streams found in
+	// multi-stream mode by glob pattern (path.Match syntax, where * does
+	// not match /): a stream is consumed when it matches an include
+	// pattern, or there are none, and no exclude pattern.
+	StreamInclude []string
+	StreamExclude []string
+	// ReadFields lists the entry fields kept when entries are read, claimed
+	// or backfilled; the others are deleted as soon as the reply is parsed,
+	// before the entry is queued. Empty keeps every field. Dead-lettered
+	// entries are copied from the stream, whole.
+	ReadFields []string
+	// StreamOverrides tune the streams matching their pattern over the
+	// global settings. The first override whose pattern matches a stream
+	// applies to it, resolved when the stream is discovered.
+	StreamOverrides    []StreamOverride
+	BatchSize          int
+	DiscoveryScanCount int
+	BlockTimeout       time.Duration
+	ClaimIdle          time.Duration
+	// ClaimInterval is how often the claim loop looks for entries idle
+	// longer than ClaimIdle; at most ClaimIdle. Zero uses ClaimIdle.
+	ClaimInterval       time.Duration
 	ConsumerIdleTimeout time.Duration
 	CleanupInterval     time.Duration
 	DialTimeout         time.Duration
 	ReadTimeout         time.Duration
 	WriteTimeout        time.Duration
 	PingTimeout         time.Duration
+	// OperationTimeout bounds each Redis call, retries included; a read
+	// that blocks gets BlockTimeout on top. Zero uses ReadTimeout plus
+	// WriteTimeout.
+	OperationTimeout time.Duration
 	// ConnMaxIdleTime recycles pooled connections that have been idle longer
 	// than this. Protects against silently-dead TCP connections (NAT/conntrack
 	// eviction) the client would otherwise reuse and fail on. Zero disables.
@@ -50,17 +164,129 @@ type RedisConfig struct {
 	ConnMaxLifetime time.Duration
 	PoolSize        int
 	MinIdleConns    int
+	DB              int
+	// ClaimConcurrency bounds how many streams ClaimIdle inspects and
+	// claims from in parallel during one claim cycle.
+	ClaimConcurrency int
+	// AdaptiveBatch sizes each read between BatchSizeMin and BatchSizeMax,
+	// starting from BatchSize: the size grows by BatchSizeMin while reads
+	// come back full, shrinks by BatchSizeMin while they come back mostly
+	// empty, and halves when publishing a batch takes longer than
+	// BatchLatencyTarget. Claims keep using BatchSize.
+	BatchLatencyTarget time.Duration
+	BatchSizeMin       int
+	BatchSizeMax       int
+	AdaptiveBatch      bool
+	// ClaimCoordination lets the instances sharing the consumer group take
+	// turns running the claim cycle, one instance per ClaimInterval,
+	// instead of each of them scanning every stream's pending list.
+	ClaimCoordination bool
+	// StreamLease makes the instances sharing the consumer group contend
+	// for one Redis lease per stream, valid for StreamLeaseTTL and renewed
+	// every third of it: only the holder reads and claims the stream, the
+	// others stand by and take it over once the lease is released on
+	// shutdown or expires.
+	StreamLease bool
+	// NoAck reads with XREADGROUP NOACK: entries never enter the pending
+	// list, so nothing is claimed or redelivered and delivery is
+	// at-most-once.
+	NoAck bool
+	// CompactDryRun makes compaction (CompactInterval) only count the
+	// entries it would delete.
+	CompactDryRun bool
+	// Backfill replays this consumer's pending entries, then claims idle
+	// ones, before the first read of new entries. BackfillRate caps the
+	// replay in entries per second (0 = unlimited); BackfillStrictOrder
+	// publishes the replayed batches one at a time, in entry ID order.
+	Backfill            bool
+	BackfillStrictOrder bool
+	BackfillRate        int
+	// Dedup drops entries whose content was already read within
+	// DedupWindow, acknowledging them unpublished: DedupKeys keeps one
+	// expiring key per content hash, DedupBloom a RedisBloom filter of
+	// DedupCapacity entries per window whose memory is fixed up front.
+	// Empty disables deduplication.
+	DedupWindow   time.Duration
+	DedupCapacity int
+	// MemoryHighPercent turns on the memory monitor: every
+	// MemoryCheckInterval it compares used_memory with maxmemory, and from
+	// MemoryHighPercent of maxmemory until usage is back under
+	// MemoryLowPercent the server is under memory pressure. Under pressure
+	// the consumer reads at its largest batch size and signals producers:
+	// MemoryPressureKey is kept set and MQTTConfig.ControlTopic says so.
+	// Zero disables the monitor.
+	MemoryCheckInterval time.Duration
+	MemoryHighPercent   int
+	MemoryLowPercent    int
+	// CompactInterval runs a compaction of every stream at this interval:
+	// entries older than CompactMinAge that every consumer group has read
+	// and none has pending are deleted, CompactBatchSize at a time. Zero
+	// disables compaction.
+	CompactInterval  time.Duration
+	CompactMinAge    time.Duration
+	CompactBatchSize int
+	ControlInterval  time.Duration
+	// LagInterval reads the consumer group's lag on every stream at this
+	// interval, from XINFO GROUPS on Redis 7 and later and estimated on
+	// older servers. From LagHigh undelivered entries until the lag is back
+	// under half of it the consumer reads at its largest batch size. Zero
+	// disables the lag monitor; a zero LagHigh only reports the lag.
+	LagInterval time.Duration
+	LagHigh     int
+	// StreamLeaseTTL is how long a stream lease lasts unrenewed; see
+	// StreamLease.
+	StreamLeaseTTL time.Duration
+	// IdleStreamAfter takes a stream that returned no entry for that long
+	// out of the blocking reads in multi-stream mode: it is polled every
+	// IdleStreamPoll instead, and read again as soon as a poll returns
+	// entries. Its pending entries are still claimed. Zero disables the
+	// suspension.
+	IdleStreamAfter time.Duration
+	IdleStreamPoll  time.Duration
 }
 
+// Deduplication modes for RedisConfig.Dedup.
+const (
+	// DedupKeys marks each content hash with SET NX EX; exact, but memory
+	// grows with the number of distinct entries per window.
+	DedupKeys = "keys"
+	// DedupBloom adds content hashes to a fixed-size, non-scaling RedisBloom
+	// filter; memory is bounded by DedupCapacity at the cost of rare false
+	// positives. Requires the RedisBloom module (Redis Stack or Redis 8).
+	DedupBloom = "bloom"
+)
+
 // MQTTConfig captures broker connection, TLS, and pool settings.
 type MQTTConfig struct {
-	Broker               string
-	ClientID             string
-	PublishTopic         string
-	AckTopic             string
+	Broker       string
+	ClientID     string
+	PublishTopic string
+	AckTopic     string
+	// ControlTopic, when set, carries retained control messages to the
+	// producers, such as the Redis memory pressure signal.
+	ControlTopic string
+	// StatusTopic, when set, carries the retained availability of each pool
+	// connection on StatusTopic/<client ID>: online once connected, offline
+	// as its last will when the broker loses it, or on a clean shutdown.
+	StatusTopic string
+	// LifecycleTopic, when set, receives the lifecycle events of the
+	// instance (see PipelineConfig.LifecycleLog), not retained, at QoS 1.
+	LifecycleTopic string
+	// SampleTopic, when set, additionally receives a sample of the entries
+	// published to PublishTopic, reduced to SampleFields, for downstream
+	// analytics: SamplePercent percent of them, or every SampleEvery-th.
+	SampleTopic string
+	// AckHMACKey, when set, makes the ACK handler drop the ACKs that are
+	// not signed with it (see pkg/ackclient.Sign).
+	AckHMACKey           string
 	CACert               string
 	ClientCert           string
 	ClientKey            string
+	StoreDir             string
+	FanoutMode           string
+	OverrideTopics       []string
+	FanoutTopics         []string
+	SampleFields         []string
 	ConnectTimeout       time.Duration
 	WriteTimeout         time.Duration
 	MaxReconnectInterval time.Duration
@@ -69,31 +295,495 @@ type MQTTConfig struct {
 	KeepAlive            time.Duration
 	PingTimeout          time.Duration
 	ConnectRetryDelay    time.Duration
+	// ConnectAttempts makes startup fail after that many failed attempts
+	// per pool connection, for a crash-only start; 0 retries until the
+	// broker responds. Reconnects always retry.
+	ConnectAttempts      int
 	PoolSize             int
 	MessageChannelDepth  uint
 	MaxResumePubInFlight int
-	QoS                  byte
-	TLSEnabled           bool
-	InsecureSkip         bool
+	StoreMaxMessages     int
+	// QuarantineFailures takes a pool connection out of the publish
+	// rotation after that many consecutive failed publishes and reconnects
+	// it in the background; 0 disables quarantine.
+	QuarantineFailures int
+	// MaxInflight pipelines QoS 1/2 publishes: a publish returns once it is
+	// handed to the connection, and up to MaxInflight per connection await
+	// the broker at a time, their outcome recorded as they complete; 0
+	// waits for each publish to complete.
+	MaxInflight   int
+	SamplePercent int
+	SampleEvery   int
+	// RTTSampleEvery times the broker acknowledgement of every
+	// RTTSampleEvery-th QoS 1/2 publish, the broker RTT reported as
+	// percentiles over each RTTWindow; 0 disables the sampling.
+	RTTSampleEvery int
+	RTTWindow      time.Duration
+	QoS            byte
+	TLSEnabled     bool
+	InsecureSkip   bool
 	// UseCertCNPrefix prepends the client cert CN to publish and ACK topics
 	// to satisfy broker ACL constraints.
 	UseCertCNPrefix bool
+	// PersistentSession connects with CleanSession=false so the broker keeps
+	// the ACK subscription and queued QoS 1/2 messages across reconnects.
+	// Pair it with StoreDir to persist our own inflight publishes on disk
+	// (at most StoreMaxMessages per connection) across process restarts.
+	PersistentSession bool
+	// OriginMarker starts every published payload with a zstd skippable
+	// frame naming the consumer, which decoders skip; whatever the setting,
+	// the ACK handler drops payloads carrying one, so a consumer whose ACK
+	// subscription matches its own publishes does not take them for ACKs.
+	OriginMarker bool
+	// AckFlowControl has the ACK handler honor the flow-control messages
+	// receivers publish on AckTopic (see pkg/ackclient.Control), which
+	// pause or cap the publishes; without it they are ignored.
+	AckFlowControl bool
+}
+
+// Fanout serializers for MQTTConfig.FanoutTopics, whose items are "topic"
+// or "topic=serializer" (ParseFanoutRule). Every batch published on
+// PublishTopic is also published on each fanout topic.
+const (
+	// FanoutJSON publishes the same payload as PublishTopic.
+	FanoutJSON = "json"
+	// FanoutRaw publishes the original line of each entry, one per line,
+	// compressed like the JSON payloads.
+	FanoutRaw = "raw"
+)
+
+// Fanout modes for MQTTConfig.FanoutMode.
+const (
+	// FanoutBestEffort publishes to the fanout topics after PublishTopic;
+	// their failures are counted and otherwise ignored.
+	FanoutBestEffort = "best-effort"
+	// FanoutAll publishes to PublishTopic, whose receiver acknowledges the
+	// entries, only once every fanout topic took the batch; otherwise the
+	// entries stay pending and are redelivered to every topic.
+	FanoutAll = "all"
+)
+
+// FanoutRule is one parsed MQTTConfig.FanoutTopics item.
+type FanoutRule struct {
+	Topic      string
+	Serializer string
+}
+
+// Oversize policies for PipelineConfig.OversizePolicy.
+const (
+	// OversizeTruncate replaces the record with a cut-down copy of the
+	// original line and a "truncated" marker holding the original size.
+	OversizeTruncate = "truncate"
+	// OversizeSplit publishes the record as base64 chunk envelopes that
+	// carry chunk_seq/chunk_total so the receiver can reassemble them.
+	OversizeSplit = "split"
+	// OversizeDLQ moves the entry to RedisConfig.DeadLetterStream, which is
+	// never consumed from, and acknowledges the original instead.
+	OversizeDLQ = "dlq"
+)
+
+// NACK actions for PipelineConfig.NackPolicies.
+const (
+	// NackRetry leaves the entries pending, so the claim loop redelivers
+	// them once they have been idle for RedisConfig.ClaimIdle. Reasons
+	// without a policy are retried.
+	NackRetry = "retry"
+	// NackDLQ moves the entries to RedisConfig.DeadLetterStream, tagged with
+	// the reason, and acknowledges the originals.
+	NackDLQ = "dlq"
+	// NackBackoff leaves the entries pending but keeps the claim loop from
+	// redelivering them until the policy's delay has passed.
+	NackBackoff = "backoff"
+)
+
+// NackPolicy is one parsed PipelineConfig.NackPolicies item.
+type NackPolicy struct {
+	Reason string
+	Action string
+	// Delay is the hold of NackBackoff; zero for the other actions.
+	Delay time.Duration
+}
+
+// Max-age actions for PipelineConfig.MaxAgePolicies.
+const (
+	// MaxAgeDrop acknowledges and deletes entries past their max age
+	// without publishing them.
+	MaxAgeDrop = "drop"
+	// MaxAgeDLQ moves entries past their max age to
+	// RedisConfig.DeadLetterStream instead of publishing them.
+	MaxAgeDLQ = "dlq"
+)
+
+// MaxAgePolicy is one parsed PipelineConfig.MaxAgePolicies item.
+type MaxAgePolicy struct {
+	Pattern string // path.Match glob on the stream name
+	Action  string
+	MaxAge  time.Duration
+}
+
+// Stream quota actions for PipelineConfig.StreamQuotas.
+const (
+	// QuotaDelay holds a stream's entries back until its token buckets
+	// allow them.
+	QuotaDelay = "delay"
+	// QuotaSkip leaves the entries over a stream's quota pending, for the
+	// claim loop to bring back.
+	QuotaSkip = "skip"
+)
+
+// Backfill orders for RedisConfig.BackfillPolicy.
+const (
+	// BackfillDrainFirst replays this consumer's own pending entries,
+	// then claims idle ones, then reads new entries.
+	BackfillDrainFirst = "drain-first"
+	// BackfillClaimFirst claims idle entries, this consumer's own among
+	// them, until a claim cycle comes back empty, then reads new entries.
+	BackfillClaimFirst = "claim-first"
+	// BackfillInterleaved is BackfillDrainFirst with reads of new entries
+	// between the backlog batches.
+	BackfillInterleaved = "interleaved"
+	// BackfillFreshOnly skips the replay: new entries are read right away
+	// and the claim loop recovers the backlog.
+	BackfillFreshOnly = "fresh-only"
+)
+
+// BackfillSchedule is a parsed RedisConfig.BackfillPolicy.
+type BackfillSchedule struct {
+	Order string
+	// Backlog and Fresh are, under BackfillInterleaved, how many backlog
+	// batches to replay before each turn of up to Fresh reads of new
+	// entries.
+	Backlog int
+	Fresh   int
+}
+
+// Retry priorities for PipelineConfig.RetryPriority.
+const (
+	// RetryPriorityLow publishes retried batches only when no fetched one
+	// is waiting.
+	RetryPriorityLow = "low"
+	// RetryPriorityHigh publishes retried batches first, within the retry
+	// rate.
+	RetryPriorityHigh = "high"
+)
+
+// StreamQuota is one parsed PipelineConfig.StreamQuotas item.
+type StreamQuota struct {
+	Pattern string // path.Match glob on the stream name
+	Action  string
+	// Messages and Bytes are the per-second limits; zero leaves that
+	// dimension unlimited.
+	Messages int
+	Bytes    int
 }
 
+// ThroughputWindow is one parsed PipelineConfig.ThroughputSchedule item.
+type ThroughputWindow struct {
+	// Days are the weekdays the window starts on, indexed by time.Weekday.
+	Days [7]bool
+	// Start and End are times of day as offsets from midnight; a window
+	// whose End is not after its Start ends on the next day.
+	Start time.Duration
+	End   time.Duration
+	Rate  int // entries per second
+}
+
+// StreamOverride is one RedisConfig.StreamOverridesFile entry. Its zero
+// fields keep the global setting.
+type StreamOverride struct {
+	Pattern string // path.Match glob on the stream name
+	// PublishTopic replaces MQTTConfig.PublishTopic for the stream's
+	// entries.
+	PublishTopic string
+	// MaxAgeAction is MaxAgeDrop or MaxAgeDLQ, MaxAgeDrop when empty; with
+	// MaxAge it replaces the stream's PipelineConfig.MaxAgePolicies item.
+	MaxAgeAction string
+	// InvalidObject replaces PipelineConfig.InvalidObject for the stream's
+	// entries; empty keeps it.
+	InvalidObject string
+	ClaimIdle     time.Duration
+	MaxAge        time.Duration
+	BatchSize     int
+	// Priority orders the streams for reads and claims, highest first;
+	// streams of equal priority keep their discovery order.
+	Priority int
+	QoS      int // -1 keeps MQTTConfig.QoS
+}
+
+// EffectiveAckDrainTimeout is how long the ACK workers' final flush may
+// take: AckDrainTimeout, or by default half of what DrainTimeout leaves of
+// ShutdownTimeout, at most DrainTimeout. The drains run one after the
+// other, so this leaves the rest of the shutdown timeout to the flushes
+// that follow them.
+func EffectiveAckDrainTimeout(cfg *PipelineConfig) time.Duration {
+	if cfg.AckDrainTimeout > 0 {
+		return cfg.AckDrainTimeout
+	}
+	if cfg.ShutdownTimeout <= cfg.DrainTimeout {
+		return cfg.DrainTimeout
+	}
+	return min(cfg.DrainTimeout, (cfg.ShutdownTimeout-cfg.DrainTimeout)/2)
+}
+
+// MatchStreamOverride returns the first of overrides whose pattern matches
+// stream.
+func MatchStreamOverride(overrides []StreamOverride, stream string) (*StreamOverride, bool) {
+	for i := range overrides {
+		if ok, _ := path.Match(overrides[i].Pattern, stream); ok {
+			return &overrides[i], true
+		}
+	}
+	return nil, false
+}
+
+// Structured data modes for PipelineConfig.StructuredData.
+const (
+	// StructuredDataLeaves writes the leaves of structured_data as
+	// top-level fields under their own names.
+	StructuredDataLeaves = "leaves"
+	// StructuredDataPrefixed writes them as "sd.<SD-ID>.<PARAM-NAME>",
+	// renaming keys the record already has.
+	StructuredDataPrefixed = "prefixed"
+)
+
+// Invalid object modes for PipelineConfig.InvalidObject.
+const (
+	// InvalidObjectPass embeds the object field as is, without checking
+	// it is valid JSON.
+	InvalidObjectPass = "pass"
+	// InvalidObjectStrict dead-letters the entries whose object is not a
+	// valid JSON object.
+	InvalidObjectStrict = "strict"
+	// InvalidObjectLenient publishes them with the object as a string
+	// under "object_raw" and the reason under "parse_error".
+	InvalidObjectLenient = "lenient"
+)
+
+// RedactRule is one PipelineConfig.RedactFile entry. It masks either the
+// value of every object field called Field, at any depth of nested
+// objects, or the matches of Pattern, an RE2 expression, in the raw line
+// and the object; with Luhn a match is masked only when its digits pass
+// the Luhn check, as card numbers do.
+type RedactRule struct {
+	Name    string `json:"name"` // keys consumer.redactions
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+	Luhn    bool   `json:"luhn"`
+}
+
+// Publish deduplication modes for PipelineConfig.PublishDedup.
+const (
+	// PublishDedupMemory reserves published entries in process memory,
+	// which only a single consumer instance can rely on.
+	PublishDedupMemory = "memory"
+	// PublishDedupRedis reserves them with SET NX PX keys shared by every
+	// consumer of the group.
+	PublishDedupRedis = "redis"
+)
+
+// Special PipelineConfig.TimestampLayouts entries for numeric epochs.
+const (
+	// TimestampUnix parses seconds since the epoch, with optional fraction.
+	TimestampUnix = "unix"
+	// TimestampUnixMillis parses milliseconds since the epoch.
+	TimestampUnixMillis = "unix_ms"
+)
+
 // PipelineConfig sizes the worker pools, queues, and timeouts that govern
 // the fetch → publish → ACK flow and the health endpoint.
 type PipelineConfig struct {
-	HealthAddr              string
+	HealthAddr string
+	// HealthTLSCert and HealthTLSKey serve the health endpoint over TLS;
+	// empty serves it in plaintext. With HealthClientCA set as well,
+	// clients must present a certificate signed by that CA bundle.
+	HealthTLSCert  string
+	HealthTLSKey   string
+	HealthClientCA string
+	OversizePolicy string
+	SpillDir       string
+	// SnapshotPath is the file the consumer saves its in-memory state to on
+	// shutdown and restores it from at startup, as versioned JSON; empty
+	// disables snapshots.
+	SnapshotPath string
+	// OrderingKey names the top-level payload field whose value replaces
+	// the stream name as the ordering key under OrderedPublish; entries
+	// without it fall back to their stream name.
+	OrderingKey string
+	// RetryPriority is RetryPriorityLow or RetryPriorityHigh, and says
+	// whether publish workers take retried batches after or before fresh
+	// ones.
+	RetryPriority string
+	// StructuredData is StructuredDataLeaves or StructuredDataPrefixed, and
+	// says how the RFC 5424 structured_data object is flattened into the
+	// published payload.
+	StructuredData string
+	// InvalidObject is InvalidObjectPass, InvalidObjectStrict or
+	// InvalidObjectLenient, and says what happens to entries whose object
+	// field is not a valid JSON object; the invalid_object of a stream
+	// override replaces it for its streams.
+	InvalidObject string
+	// RedactFile is a JSON file of RedactRules, parsed into RedactRules at
+	// load time, that mask sensitive content in the entries before they are
+	// published or dead-lettered; empty disables redaction.
+	RedactFile  string
+	RedactRules []RedactRule
+	// RedactStrict dead-letters the entries that cannot be redacted rather
+	// than publishing them without their object.
+	RedactStrict bool
+	// PublishDedup reserves each entry's stream and ID just before it is
+	// published, for PublishDedupWindow, and leaves pending unpublished the
+	// entries already reserved, so an entry the claim loop hands out while
+	// its first publisher is still at work is delivered once: the first
+	// publisher's ACK acknowledges it. PublishDedupMemory keeps the
+	// reservations in this instance, PublishDedupRedis in Redis, shared by
+	// every consumer of the group; empty disables it.
+	PublishDedup string
+	// EnrichKey names the top-level payload field whose value is looked up
+	// in the Redis hash EnrichHashPrefix+value; the EnrichFields found there
+	// are added to the published payload, unless it has them already.
+	// Lookups are cached for EnrichCacheTTL, at most EnrichCacheSize keys,
+	// including the keys without a hash. Empty disables enrichment.
+	EnrichKey        string
+	EnrichHashPrefix string
+	EnrichFields     []string
+	// StatsdAddr sends the consumer.* metrics to a StatsD server at this
+	// UDP "host:port" every StatsdInterval, in the DogStatsD format, with
+	// StatsdPrefix and a dot before every name and StatsdTags on every
+	// metric; empty disables the exporter.
+	StatsdAddr   string
+	StatsdPrefix string
+	StatsdTags   []string
+	// MetricsStatePath is the file the consumer.* counters are saved to
+	// every MetricsStateInterval and on shutdown, and added back from at
+	// startup, so they carry on across restarts; empty disables it.
+	MetricsStatePath string
+	// LifecycleLog, when set, receives the lifecycle events of the instance
+	// (started, broker-connected, paused, shutdown-begin, ...) as JSON
+	// lines: "stderr", or the path of a file they are appended to.
+	LifecycleLog string
+	// NackPolicies says what happens to NACKed entries by the reason the
+	// receiver gave, as "reason=action" items (ParseNackPolicy); unlisted
+	// reasons are retried.
+	NackPolicies []string
+	// MaxAgePolicies skip publishing entries older than a per-stream
+	// threshold, going by the time in their entry ID, as
+	// "pattern=action:<duration>" items (ParseMaxAgePolicy). The first
+	// pattern matching the stream applies; unmatched streams have no limit.
+	MaxAgePolicies []string
+	// StreamQuotas limit the entries and bytes published per second from
+	// each stream, as "pattern=action:<messages>:<bytes>" items
+	// (ParseStreamQuota). The first pattern matching a stream applies, and
+	// every matching stream gets a quota of its own.
+	StreamQuotas []string
+	// ThroughputSchedule caps the entries this instance publishes per
+	// second during time windows, as "<days> <HH:MM>-<HH:MM>=<entries>"
+	// items (ParseThroughputWindow) read in ThroughputLocation. The first
+	// window holding the current time applies; outside them publishing is
+	// unlimited. A change of cap is spread over ThroughputRamp.
+	ThroughputSchedule []string
+	ThroughputLocation string
+	// TimestampField names the payload field normalized to UTC RFC3339;
+	// empty disables normalization. Values are tried against each of
+	// TimestampLayouts in turn (Go layouts or TimestampUnix/Millis), read in
+	// TimestampLocation when they carry no offset, and the original value is
+	// kept under "<field>_raw". Entries further than TimestampMaxSkew from the
+	// consumer clock are flagged with "<field>_skewed":true.
+	TimestampField          string
+	TimestampLocation       string
+	TimestampLayouts        []string
+	FieldAllowlist          []string
+	FieldDenylist           []string
+	TimestampMaxSkew        time.Duration
 	HealthPingTimeout       time.Duration
 	HealthReadHeaderTimeout time.Duration
 	ShutdownTimeout         time.Duration
+	DrainTimeout            time.Duration
 	ErrorBackoff            time.Duration
 	AckTimeout              time.Duration
 	RefreshInterval         time.Duration
 	AckFlushInterval        time.Duration
-	BufferCapacity          int
-	MessageQueueCapacity    int
-	PublishWorkers          int
-	AckWorkers              int
-	AckBatchSize            int
+	ReceiptFlushInterval    time.Duration
+	StatsdInterval          time.Duration
+	MetricsStateInterval    time.Duration
+	// AckDrainTimeout bounds the final flush of the ACK workers at
+	// shutdown, once the ACK topic is unsubscribed; the ACKs and NACKs not
+	// applied by then are abandoned and their entries left pending. Zero
+	// uses EffectiveAckDrainTimeout's default.
+	AckDrainTimeout time.Duration
+	ThroughputRamp  time.Duration // see ThroughputSchedule
+	// PublishTimeout bounds each publish of a publish worker, retries of
+	// an ordered worker included, apart from MQTT WriteTimeout. A publish
+	// past it is abandoned and its entries left pending for the claim loop.
+	// Zero disables the deadline.
+	PublishTimeout time.Duration
+	// AckSLOLatency is the ack latency objective: the AckSLOPercentile
+	// percentile of the time from an entry's addition to its stream, per
+	// its ID, to the receiver's ACK, per stream over each AckSLOWindow. A
+	// stream over it for AckSLOWindows windows in a row is announced on
+	// MQTTConfig.ControlTopic and degrades the health endpoint until a
+	// window meets it again. Zero disables the tracking.
+	AckSLOLatency        time.Duration
+	AckSLOWindow         time.Duration
+	AckSLOPercentile     float64
+	AckSLOWindows        int
+	PublishDedupWindow   time.Duration
+	EnrichCacheTTL       time.Duration
+	BufferCapacity       int
+	MessageQueueCapacity int
+	PublishWorkers       int
+	// PublishStallFactor replaces a publish worker whose publish has been
+	// in progress for longer than MQTT WriteTimeout times this factor, and
+	// reconnects the MQTT connections that made no progress as long; zero
+	// disables the watchdog.
+	PublishStallFactor   int
+	AckWorkers           int
+	AckBatchSize         int
+	ReceiptQueueCapacity int
+	ReceiptBatchSize     int
+	// RetryQueueCapacity gives the entries the claim loop brings back
+	// (NACKed, failed or abandoned ones) a queue of that many batches apart
+	// from the fetched ones, drained to the publish workers at no more than
+	// RetryRate entries per second (zero: unlimited). Zero sends them
+	// through the main queue.
+	RetryQueueCapacity int
+	RetryRate          int
+	EnrichCacheSize    int
+	// MaxPayloadBytes caps each published record and MQTT payload; records
+	// over it are handled by OversizePolicy. Zero disables the limit.
+	MaxPayloadBytes int
+	// MemoryBudget caps the entry bodies held in the publish queues, in
+	// bytes; over it, bodies of at least SpillMinBytes move to an mmap arena
+	// of SpillArenaBytes in SpillDir (the system temporary directory when
+	// empty) and are read back just before publish. Zero disables spilling.
+	MemoryBudget    int
+	SpillMinBytes   int
+	SpillArenaBytes int
+	// CaptureSize keeps the last CaptureSize published payloads and
+	// received ACKs in memory, served on HealthAddr at GET /debug/capture
+	// and logged on SIGUSR1. Zero disables the capture.
+	CaptureSize int
+	// NackFloodThreshold quarantines a stream whose receivers NACK more
+	// than that many entries within NackFloodWindow: for NackFloodCooldown
+	// the claim loop leaves the stream's entries pending instead of
+	// redelivering them. Zero disables the quarantine.
+	NackFloodThreshold int
+	NackFloodWindow    time.Duration
+	NackFloodCooldown  time.Duration
+	// OrderedPublish routes every ordering key (the stream name unless
+	// OrderingKey is set) to a fixed publish worker and pool connection, by
+	// hash, and retries a failed publish in place, so per-key order
+	// survives the fan-out and publish errors. Parallelism is bounded by
+	// the number of distinct keys.
+	OrderedPublish bool
+	// AdminDLQReplay serves POST /admin/dlq/replay on HealthAddr, which
+	// moves dead letter entries back to their source streams.
+	AdminDLQReplay bool
+	// AdminStreams serves POST /admin/streams/attach and
+	// /admin/streams/detach on HealthAddr, which add a stream to the
+	// streams read, or drain one and stop reading it, without waiting for
+	// the next discovery.
+	AdminStreams bool
+	// AdminBuffer serves POST /admin/buffer on HealthAddr, which resizes
+	// the ACK queues that BufferCapacity sizes at startup.
+	AdminBuffer bool
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -62,7 +63,7 @@ func TestRedisConfig_Fields(t *testing.T) {
 		PoolSize:            10,
 		MinIdleConns:        2,
 	}
-	if got != want {
+	if !reflect.DeepEqual(got, want) {
 		t.Errorf("RedisConfig mismatch\ngot:  %+v\nwant: %+v", got, want)
 	}
 }
@@ -76,6 +77,7 @@ func TestMQTTConfig_Fields(t *testing.T) {
 		CACert:               "/path/ca.pem",
 		ClientCert:           "/path/cert.pem",
 		ClientKey:            "/path/key.pem",
+		OverrideTopics:       []string{"alerts/#"},
 		ConnectTimeout:       10 * time.Second,
 		WriteTimeout:         5 * time.Second,
 		MaxReconnectInterval: 30 * time.Second,
@@ -100,6 +102,7 @@ func TestMQTTConfig_Fields(t *testing.T) {
 		CACert:               "/path/ca.pem",
 		ClientCert:           "/path/cert.pem",
 		ClientKey:            "/path/key.pem",
+		OverrideTopics:       []string{"alerts/#"},
 		ConnectTimeout:       10 * time.Second,
 		WriteTimeout:         5 * time.Second,
 		MaxReconnectInterval: 30 * time.Second,
@@ -116,7 +119,7 @@ func TestMQTTConfig_Fields(t *testing.T) {
 		InsecureSkip:         false,
 		UseCertCNPrefix:      true,
 	}
-	if got != want {
+	if !reflect.DeepEqual(got, want) {
 		t.Errorf("MQTTConfig mismatch\ngot:  %+v\nwant: %+v", got, want)
 	}
 }
@@ -152,7 +155,7 @@ func TestPipelineConfig_Fields(t *testing.T) {
 		AckWorkers:              2,
 		AckBatchSize:            50,
 	}
-	if got != want {
+	if !reflect.DeepEqual(got, want) {
 		t.Errorf("PipelineConfig mismatch\ngot:  %+v\nwant: %+v", got, want)
 	}
 }
@@ -191,3 +194,20 @@ func TestConfig_Composite(t *testing.T) {
 		t.Errorf("Pipeline.PublishWorkers = %d", cfg.Pipeline.PublishWorkers)
 	}
 }
+
+func TestEffectiveAckDrainTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  PipelineConfig
+		want time.Duration
+	}{
+		{"set", PipelineConfig{AckDrainTimeout: time.Second, DrainTimeout: 5 * time.Second}, time.Second},
+		{"defaults", defaultPipelineConfig(), 2500 * time.Millisecond},
+		{"capped by drain", PipelineConfig{ShutdownTimeout: time.Minute, DrainTimeout: 5 * time.Second}, 5 * time.Second},
+		{"no shutdown timeout", PipelineConfig{DrainTimeout: 5 * time.Second}, 5 * time.Second},
+	} {
+		if got := EffectiveAckDrainTimeout(&tt.cfg); got != tt.want {
+			t.Errorf("%s: EffectiveAckDrainTimeout() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -12,19 +12,31 @@ func Load() (*Config, error) {
 		flag.Parse()
 	}
 
-	cfg := defaultConfig()
+	cfg, err := loadProfile()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	tuned := *cfg
 
 	loadLogFromEnv(&cfg.Log)
 	loadRedisFromEnv(&cfg.Redis)
 	loadMQTTFromEnv(&cfg.MQTT)
 	loadPipelineFromEnv(&cfg.Pipeline)
 	loadCompressFromEnv(&cfg.Compress)
+	loadRuntimeFromEnv(&cfg.Runtime)
+	loadArchiveFromEnv(&cfg.Archive)
 
 	applyLogFlags(&cfg.Log)
 	applyRedisFlags(&cfg.Redis)
 	applyMQTTFlags(&cfg.MQTT)
 	applyPipelineFlags(&cfg.Pipeline)
 	applyCompressFlags(&cfg.Compress)
+	applyRuntimeFlags(&cfg.Runtime)
+	applyArchiveFlags(&cfg.Archive)
+
+	if cfg.Profile != "" {
+		cfg.ProfileOverrides = profileOverrides(&tuned, cfg)
+	}
 
 	if err := applyRuntimeValidation(cfg); err != nil {
 		return nil, err
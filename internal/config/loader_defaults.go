@@ -40,11 +40,37 @@ func defaultRedisConfig() RedisConfig {
 		ConnMaxLifetime: 0,
 		PoolSize:        50,
 		MinIdleConns:    10,
+		// Stays well below PoolSize so a claim cycle cannot starve the
+		// fetch loop and ACK workers of connections.
+		ClaimConcurrency: 8,
+		DedupWindow:      10 * time.Minute,
+		DedupCapacity:    1_000_000,
+		// Adaptive sizing is off by default; when on, it never reads more
+		// per call than the static default.
+		BatchSizeMin:       100,
+		BatchSizeMax:       20000,
+		BatchLatencyTarget: 250 * time.Millisecond,
+		// The memory monitor is off by default; when on, pressure clears
+		// 10 points of maxmemory below where it started.
+		MemoryCheckInterval: 5 * time.Second,
+		MemoryLowPercent:    80,
+		// Compaction is off by default; when on, it leaves the last hour
+		// alone so XDEL never races a slow ACK.
+		CompactMinAge:    time.Hour,
+		CompactBatchSize: 1000,
+		BackfillPolicy:   BackfillDrainFirst,
+		// Control hash changes apply within seconds across the fleet.
+		ControlInterval: 5 * time.Second,
+		// With stream leases on, a standby takes over a stream within 10s
+		// of its holder dying.
+		StreamLeaseTTL: 10 * time.Second,
+		// A suspended stream's entries wait at most this long to be read.
+		IdleStreamPoll: 5 * time.Second,
 	}
 }
 
 func defaultLogConfig() LogConfig {
-	return LogConfig{Level: defaultLogLevel}
+	return LogConfig{Level: defaultLogLevel, QueueSize: 8192, PayloadPreviewBytes: 256}
 }
 
 func defaultMQTTConfig() MQTTConfig {
@@ -65,6 +91,14 @@ func defaultMQTTConfig() MQTTConfig {
 		ConnectRetryDelay:    2 * time.Second,
 		MessageChannelDepth:  10000,
 		MaxResumePubInFlight: 1000,
+		StoreDir:             "",
+		StoreMaxMessages:     10000,
+		FanoutMode:           FanoutBestEffort,
+		SampleFields:         []string{"severity", "hostname", "program"},
+		RTTWindow:            time.Minute,
+		PersistentSession:    false,
+		OriginMarker:         false,
+		AckFlowControl:       false,
 		TLSEnabled:           false,
 		CACert:               "",
 		ClientCert:           "",
@@ -79,6 +113,9 @@ func defaultPipelineConfig() PipelineConfig {
 		BufferCapacity:          10000,
 		MessageQueueCapacity:    500,
 		ShutdownTimeout:         10 * time.Second,
+		DrainTimeout:            5 * time.Second,
+		PublishTimeout:          0,
+		CaptureSize:             0,
 		ErrorBackoff:            50 * time.Millisecond,
 		AckTimeout:              5 * time.Second,
 		PublishWorkers:          25,
@@ -89,6 +126,50 @@ func defaultPipelineConfig() PipelineConfig {
 		HealthPingTimeout:       2 * time.Second,
 		HealthReadHeaderTimeout: 5 * time.Second,
 		HealthAddr:              defaultHealthAddr,
+		OrderedPublish:          false,
+		AdminDLQReplay:          false,
+		AdminStreams:            false,
+		AdminBuffer:             false,
+		OversizePolicy:          OversizeTruncate,
+		MaxPayloadBytes:         0,
+		MemoryBudget:            0,
+		SpillMinBytes:           1024,
+		SpillArenaBytes:         1 << 30,
+		RetryPriority:           RetryPriorityLow,
+		StructuredData:          StructuredDataLeaves,
+		InvalidObject:           InvalidObjectPass,
+		StatsdInterval:          10 * time.Second,
+		MetricsStateInterval:    30 * time.Second,
+		AckSLOWindow:            1 * time.Minute,
+		AckSLOPercentile:        99,
+		AckSLOWindows:           3,
+		PublishDedupWindow:      1 * time.Minute,
+		NackFloodWindow:         10 * time.Second,
+		NackFloodCooldown:       1 * time.Minute,
+		EnrichCacheTTL:          5 * time.Minute,
+		EnrichCacheSize:         10000,
+		ReceiptQueueCapacity:    10000,
+		ReceiptBatchSize:        256,
+		ReceiptFlushInterval:    1 * time.Second,
+		TimestampLocation:       "UTC",
+		TimestampLayouts:        defaultTimestampLayouts(),
+		TimestampMaxSkew:        5 * time.Minute,
+		ThroughputLocation:      "UTC",
+		ThroughputRamp:          1 * time.Minute,
+	}
+}
+
+// defaultTimestampLayouts covers RFC 5424, RFC 3164 and the common
+// ISO-like forms without a "T", then falls back to Unix seconds.
+func defaultTimestampLayouts() []string {
+	return []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		time.Stamp,
+		time.RFC1123Z,
+		time.RFC1123,
+		TimestampUnix,
 	}
 }
 
@@ -100,6 +181,23 @@ func defaultCompressConfig() CompressConfig {
 	}
 }
 
+func defaultRuntimeConfig() RuntimeConfig {
+	return RuntimeConfig{
+		GCPercent:          200,
+		MemoryLimitPercent: 90,
+	}
+}
+
+func defaultArchiveConfig() ArchiveConfig {
+	return ArchiveConfig{
+		S3Region:    "us-east-1",
+		MaxAge:      5 * time.Minute,
+		UploadRetry: 30 * time.Second,
+		MaxBytes:    64 << 20,
+		S3PartBytes: 16 << 20,
+	}
+}
+
 func defaultConfig() *Config {
 	return &Config{
 		Log:      defaultLogConfig(),
@@ -107,5 +205,7 @@ func defaultConfig() *Config {
 		MQTT:     defaultMQTTConfig(),
 		Pipeline: defaultPipelineConfig(),
 		Compress: defaultCompressConfig(),
+		Runtime:  defaultRuntimeConfig(),
+		Archive:  defaultArchiveConfig(),
 	}
 }
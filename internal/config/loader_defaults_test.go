@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -19,6 +20,7 @@ func TestDefaultRedisConfig(t *testing.T) {
 		{cfg.BatchSize, 20000, "BatchSize"},
 		{cfg.BlockTimeout, 1 * time.Second, "BlockTimeout"},
 		{cfg.ClaimIdle, 10 * time.Second, "ClaimIdle"},
+		{cfg.ClaimInterval, time.Duration(0), "ClaimInterval"},
 		{cfg.ConsumerIdleTimeout, 5 * time.Minute, "ConsumerIdleTimeout"},
 		{cfg.CleanupInterval, 1 * time.Minute, "CleanupInterval"},
 		{cfg.DialTimeout, 5 * time.Second, "DialTimeout"},
@@ -29,6 +31,25 @@ func TestDefaultRedisConfig(t *testing.T) {
 		{cfg.ConnMaxLifetime, time.Duration(0), "ConnMaxLifetime"},
 		{cfg.PoolSize, 50, "PoolSize"},
 		{cfg.MinIdleConns, 10, "MinIdleConns"},
+		{cfg.ClaimConcurrency, 8, "ClaimConcurrency"},
+		{cfg.AdaptiveBatch, false, "AdaptiveBatch"},
+		{cfg.BatchSizeMin, 100, "BatchSizeMin"},
+		{cfg.BatchSizeMax, 20000, "BatchSizeMax"},
+		{cfg.BatchLatencyTarget, 250 * time.Millisecond, "BatchLatencyTarget"},
+		{cfg.MemoryCheckInterval, 5 * time.Second, "MemoryCheckInterval"},
+		{cfg.MemoryLowPercent, 80, "MemoryLowPercent"},
+		{cfg.CompactMinAge, time.Hour, "CompactMinAge"},
+		{cfg.CompactBatchSize, 1000, "CompactBatchSize"},
+		{cfg.BackfillPolicy, BackfillDrainFirst, "BackfillPolicy"},
+		{cfg.ControlInterval, 5 * time.Second, "ControlInterval"},
+		{cfg.LagInterval, time.Duration(0), "LagInterval"},
+		{cfg.LagHigh, 0, "LagHigh"},
+		{cfg.StreamLease, false, "StreamLease"},
+		{cfg.StreamLeaseTTL, 10 * time.Second, "StreamLeaseTTL"},
+		{cfg.IdleStreamAfter, time.Duration(0), "IdleStreamAfter"},
+		{cfg.IdleStreamPoll, 5 * time.Second, "IdleStreamPoll"},
+		{cfg.DB, 0, "DB"},
+		{cfg.Username, "", "Username"},
 	}
 
 	for _, tt := range tests {
@@ -65,6 +86,13 @@ func TestDefaultMQTTConfig(t *testing.T) {
 		{cfg.ClientKey, "", "ClientKey"},
 		{cfg.InsecureSkip, false, "InsecureSkip"},
 		{cfg.UseCertCNPrefix, false, "UseCertCNPrefix"},
+		{cfg.PersistentSession, false, "PersistentSession"},
+		{cfg.OriginMarker, false, "OriginMarker"},
+		{cfg.AckFlowControl, false, "AckFlowControl"},
+		{cfg.StoreDir, "", "StoreDir"},
+		{cfg.StoreMaxMessages, 10000, "StoreMaxMessages"},
+		{cfg.RTTSampleEvery, 0, "RTTSampleEvery"},
+		{cfg.RTTWindow, time.Minute, "RTTWindow"},
 	}
 
 	for _, tt := range tests {
@@ -91,6 +119,48 @@ func TestDefaultPipelineConfig(t *testing.T) {
 		{cfg.PublishWorkers, 25, "PublishWorkers"},
 		{cfg.RefreshInterval, 1 * time.Minute, "RefreshInterval"},
 		{cfg.HealthAddr, defaultHealthAddr, "HealthAddr"},
+		{cfg.OrderedPublish, false, "OrderedPublish"},
+		{cfg.AdminDLQReplay, false, "AdminDLQReplay"},
+		{cfg.AdminStreams, false, "AdminStreams"},
+		{cfg.AdminBuffer, false, "AdminBuffer"},
+		{cfg.OversizePolicy, OversizeTruncate, "OversizePolicy"},
+		{cfg.MaxPayloadBytes, 0, "MaxPayloadBytes"},
+		{cfg.MemoryBudget, 0, "MemoryBudget"},
+		{cfg.SpillMinBytes, 1024, "SpillMinBytes"},
+		{cfg.SpillArenaBytes, 1 << 30, "SpillArenaBytes"},
+		{cfg.DrainTimeout, 5 * time.Second, "DrainTimeout"},
+		{cfg.AckDrainTimeout, time.Duration(0), "AckDrainTimeout"},
+		{cfg.ThroughputLocation, "UTC", "ThroughputLocation"},
+		{cfg.ThroughputRamp, 1 * time.Minute, "ThroughputRamp"},
+		{cfg.PublishTimeout, time.Duration(0), "PublishTimeout"},
+		{cfg.CaptureSize, 0, "CaptureSize"},
+		{cfg.ReceiptQueueCapacity, 10000, "ReceiptQueueCapacity"},
+		{cfg.ReceiptBatchSize, 256, "ReceiptBatchSize"},
+		{cfg.ReceiptFlushInterval, 1 * time.Second, "ReceiptFlushInterval"},
+		{cfg.RetryQueueCapacity, 0, "RetryQueueCapacity"},
+		{cfg.RetryRate, 0, "RetryRate"},
+		{cfg.RetryPriority, RetryPriorityLow, "RetryPriority"},
+		{cfg.StructuredData, StructuredDataLeaves, "StructuredData"},
+		{cfg.InvalidObject, InvalidObjectPass, "InvalidObject"},
+		{cfg.StatsdAddr, "", "StatsdAddr"},
+		{cfg.StatsdInterval, 10 * time.Second, "StatsdInterval"},
+		{cfg.MetricsStatePath, "", "MetricsStatePath"},
+		{cfg.MetricsStateInterval, 30 * time.Second, "MetricsStateInterval"},
+		{cfg.LifecycleLog, "", "LifecycleLog"},
+		{cfg.RedactFile, "", "RedactFile"},
+		{cfg.RedactStrict, false, "RedactStrict"},
+		{cfg.AckSLOLatency, time.Duration(0), "AckSLOLatency"},
+		{cfg.AckSLOWindow, time.Minute, "AckSLOWindow"},
+		{cfg.AckSLOPercentile, 99.0, "AckSLOPercentile"},
+		{cfg.AckSLOWindows, 3, "AckSLOWindows"},
+		{cfg.PublishDedup, "", "PublishDedup"},
+		{cfg.PublishDedupWindow, time.Minute, "PublishDedupWindow"},
+		{cfg.NackFloodThreshold, 0, "NackFloodThreshold"},
+		{cfg.NackFloodWindow, 10 * time.Second, "NackFloodWindow"},
+		{cfg.NackFloodCooldown, time.Minute, "NackFloodCooldown"},
+		{cfg.EnrichKey, "", "EnrichKey"},
+		{cfg.EnrichCacheTTL, 5 * time.Minute, "EnrichCacheTTL"},
+		{cfg.EnrichCacheSize, 10000, "EnrichCacheSize"},
 	}
 
 	for _, tt := range tests {
@@ -124,6 +194,22 @@ func TestDefaultCompressConfig(t *testing.T) {
 	}
 }
 
+func TestDefaultRuntimeConfig(t *testing.T) {
+	if want := (RuntimeConfig{GCPercent: 200, MemoryLimitPercent: 90}); defaultRuntimeConfig() != want {
+		t.Errorf("defaultRuntimeConfig() = %+v; want %+v", defaultRuntimeConfig(), want)
+	}
+}
+
+func TestDefaultArchiveConfig(t *testing.T) {
+	want := ArchiveConfig{
+		S3Region: "us-east-1", MaxAge: 5 * time.Minute, UploadRetry: 30 * time.Second,
+		MaxBytes: 64 << 20, S3PartBytes: 16 << 20,
+	}
+	if defaultArchiveConfig() != want {
+		t.Errorf("defaultArchiveConfig() = %+v; want %+v", defaultArchiveConfig(), want)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := defaultConfig()
 
@@ -131,6 +217,10 @@ func TestDefaultConfig(t *testing.T) {
 		t.Fatal("defaultConfig() returned nil")
 	}
 
+	if want := (LogConfig{Level: defaultLogLevel, QueueSize: 8192, PayloadPreviewBytes: 256}); cfg.Log != want {
+		t.Errorf("defaultConfig().Log = %+v; want %+v", cfg.Log, want)
+	}
+
 	// Verify Redis defaults
 	if cfg.Redis.Address != defaultRedisAddress {
 		t.Errorf("defaultConfig().Redis.Address = %s; want %s", cfg.Redis.Address, defaultRedisAddress)
@@ -146,6 +236,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.MQTT.PoolSize != 25 {
 		t.Errorf("defaultConfig().MQTT.PoolSize = %d; want 25", cfg.MQTT.PoolSize)
 	}
+	if want := []string{"severity", "hostname", "program"}; !reflect.DeepEqual(cfg.MQTT.SampleFields, want) {
+		t.Errorf("defaultConfig().MQTT.SampleFields = %q; want %q", cfg.MQTT.SampleFields, want)
+	}
 
 	// Verify Pipeline defaults
 	if cfg.Pipeline.BufferCapacity != 10000 {
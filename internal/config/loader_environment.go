@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,13 +12,83 @@ func loadLogFromEnv(cfg *LogConfig) {
 	if v := getEnvString("LOG_LEVEL"); v != "" {
 		cfg.Level = v
 	}
+	if v, ok := lookupEnvBool("LOG_ASYNC"); ok {
+		cfg.Async = v
+	}
+	if v := getEnvInt("LOG_QUEUE_SIZE"); v != 0 {
+		cfg.QueueSize = v
+	}
+	loadOptionalInt("LOG_PAYLOAD_PREVIEW_BYTES", &cfg.PayloadPreviewBytes)
 }
 
 func loadRedisFromEnv(cfg *RedisConfig) {
 	loadRedisStrings(cfg)
+	loadRedisReplica(cfg)
 	loadRedisInts(cfg)
 	loadRedisTimeouts(cfg)
 	loadRedisPoolLifecycle(cfg)
+	loadRedisAdaptiveBatch(cfg)
+	loadRedisMemoryMonitor(cfg)
+	loadRedisCompaction(cfg)
+	loadRedisControl(cfg)
+	loadRedisLag(cfg)
+	loadRedisBackfill(cfg)
+	loadRedisStreamLease(cfg)
+	loadRedisIdleStreams(cfg)
+	if v := getEnvList("REDIS_STREAM_INCLUDE"); v != nil {
+		cfg.StreamInclude = v
+	}
+	if v := getEnvList("REDIS_STREAM_EXCLUDE"); v != nil {
+		cfg.StreamExclude = v
+	}
+	if v := getEnvList("REDIS_READ_FIELDS"); v != nil {
+		cfg.ReadFields = v
+	}
+	if v := getEnvString("REDIS_STREAM_OVERRIDES_FILE"); v != "" {
+		cfg.StreamOverridesFile = v
+	}
+	if v := getEnvString("REDIS_PASSWORD_FILE"); v != "" {
+		cfg.PasswordFile = v
+	}
+	if v := getEnvString("REDIS_KEY_PREFIX"); v != "" {
+		cfg.KeyPrefix = v
+	}
+	if v, ok := lookupEnvBool("REDIS_CLAIM_COORDINATION"); ok {
+		cfg.ClaimCoordination = v
+	}
+	if v, ok := lookupEnvBool("REDIS_NOACK"); ok {
+		cfg.NoAck = v
+	}
+}
+
+func loadRedisStreamLease(cfg *RedisConfig) {
+	if v, ok := lookupEnvBool("REDIS_STREAM_LEASE"); ok {
+		cfg.StreamLease = v
+	}
+	if v := getEnvDuration("REDIS_STREAM_LEASE_TTL"); v != 0 {
+		cfg.StreamLeaseTTL = v
+	}
+}
+
+func loadRedisIdleStreams(cfg *RedisConfig) {
+	if v := getEnvDuration("REDIS_IDLE_STREAM_AFTER"); v != 0 {
+		cfg.IdleStreamAfter = v
+	}
+	if v := getEnvDuration("REDIS_IDLE_STREAM_POLL"); v != 0 {
+		cfg.IdleStreamPoll = v
+	}
+}
+
+func loadRedisBackfill(cfg *RedisConfig) {
+	if v, ok := lookupEnvBool("REDIS_BACKFILL"); ok {
+		cfg.Backfill = v
+	}
+	if v, ok := lookupEnvBool("REDIS_BACKFILL_STRICT_ORDER"); ok {
+		cfg.BackfillStrictOrder = v
+	}
+	if v := getEnvString("REDIS_BACKFILL_POLICY"); v != "" {
+		cfg.BackfillPolicy = v
+	}
 }
 
 func loadRedisStrings(cfg *RedisConfig) {
@@ -34,6 +105,90 @@ func loadRedisStrings(cfg *RedisConfig) {
 	if v := getEnvString("REDIS_GROUP_NAME"); v != "" {
 		cfg.GroupName = v
 	}
+	if v := getEnvString("REDIS_USERNAME"); v != "" {
+		cfg.Username = v
+	}
+	if v := getEnvString("REDIS_PASSWORD"); v != "" {
+		cfg.Password = v
+	}
+	if v := getEnvString("REDIS_DEAD_LETTER_STREAM"); v != "" {
+		cfg.DeadLetterStream = v
+	}
+	if v := getEnvString("REDIS_RECEIPTS_STREAM"); v != "" {
+		cfg.ReceiptsStream = v
+	}
+	if v := getEnvString("REDIS_DEDUP"); v != "" {
+		cfg.Dedup = v
+	}
+}
+
+func loadRedisReplica(cfg *RedisConfig) {
+	if v := getEnvString("REDIS_REPLICA_ADDRESS"); v != "" {
+		cfg.ReplicaAddress = v
+	}
+}
+
+func loadRedisAdaptiveBatch(cfg *RedisConfig) {
+	if v, ok := lookupEnvBool("REDIS_ADAPTIVE_BATCH"); ok {
+		cfg.AdaptiveBatch = v
+	}
+	if v := getEnvInt("REDIS_BATCH_SIZE_MIN"); v != 0 {
+		cfg.BatchSizeMin = v
+	}
+	if v := getEnvInt("REDIS_BATCH_SIZE_MAX"); v != 0 {
+		cfg.BatchSizeMax = v
+	}
+	if v := getEnvDuration("REDIS_BATCH_LATENCY_TARGET"); v != 0 {
+		cfg.BatchLatencyTarget = v
+	}
+}
+
+func loadRedisMemoryMonitor(cfg *RedisConfig) {
+	if v := getEnvInt("REDIS_MEMORY_HIGH_PERCENT"); v != 0 {
+		cfg.MemoryHighPercent = v
+	}
+	if v := getEnvInt("REDIS_MEMORY_LOW_PERCENT"); v != 0 {
+		cfg.MemoryLowPercent = v
+	}
+	if v := getEnvDuration("REDIS_MEMORY_CHECK_INTERVAL"); v != 0 {
+		cfg.MemoryCheckInterval = v
+	}
+	if v := getEnvString("REDIS_MEMORY_PRESSURE_KEY"); v != "" {
+		cfg.MemoryPressureKey = v
+	}
+}
+
+func loadRedisControl(cfg *RedisConfig) {
+	if v := getEnvString("REDIS_CONTROL_KEY"); v != "" {
+		cfg.ControlKey = v
+	}
+	if v := getEnvDuration("REDIS_CONTROL_INTERVAL"); v != 0 {
+		cfg.ControlInterval = v
+	}
+}
+
+func loadRedisLag(cfg *RedisConfig) {
+	if v := getEnvDuration("REDIS_LAG_INTERVAL"); v != 0 {
+		cfg.LagInterval = v
+	}
+	if v := getEnvInt("REDIS_LAG_HIGH"); v != 0 {
+		cfg.LagHigh = v
+	}
+}
+
+func loadRedisCompaction(cfg *RedisConfig) {
+	if v := getEnvDuration("REDIS_COMPACT_INTERVAL"); v != 0 {
+		cfg.CompactInterval = v
+	}
+	if v := getEnvDuration("REDIS_COMPACT_MIN_AGE"); v != 0 {
+		cfg.CompactMinAge = v
+	}
+	if v := getEnvInt("REDIS_COMPACT_BATCH_SIZE"); v != 0 {
+		cfg.CompactBatchSize = v
+	}
+	if v, ok := lookupEnvBool("REDIS_COMPACT_DRY_RUN"); ok {
+		cfg.CompactDryRun = v
+	}
 }
 
 func loadRedisInts(cfg *RedisConfig) {
@@ -49,6 +204,18 @@ func loadRedisInts(cfg *RedisConfig) {
 	if v := getEnvInt("REDIS_DISCOVERY_SCAN_COUNT"); v != 0 {
 		cfg.DiscoveryScanCount = v
 	}
+	if v := getEnvInt("REDIS_CLAIM_CONCURRENCY"); v != 0 {
+		cfg.ClaimConcurrency = v
+	}
+	if v := getEnvInt("REDIS_BACKFILL_RATE"); v != 0 {
+		cfg.BackfillRate = v
+	}
+	if v := getEnvInt("REDIS_DEDUP_CAPACITY"); v != 0 {
+		cfg.DedupCapacity = v
+	}
+	if v := getEnvInt("REDIS_DB"); v != 0 {
+		cfg.DB = v
+	}
 }
 
 func loadRedisTimeouts(cfg *RedisConfig) {
@@ -58,12 +225,22 @@ func loadRedisTimeouts(cfg *RedisConfig) {
 	if v := getEnvDuration("REDIS_CLAIM_IDLE"); v != 0 {
 		cfg.ClaimIdle = v
 	}
+	if v := getEnvDuration("REDIS_CLAIM_INTERVAL"); v != 0 {
+		cfg.ClaimInterval = v
+	}
 	if v := getEnvDuration("REDIS_CONSUMER_IDLE_TIMEOUT"); v != 0 {
 		cfg.ConsumerIdleTimeout = v
 	}
 	if v := getEnvDuration("REDIS_CLEANUP_INTERVAL"); v != 0 {
 		cfg.CleanupInterval = v
 	}
+	if v := getEnvDuration("REDIS_DEDUP_WINDOW"); v != 0 {
+		cfg.DedupWindow = v
+	}
+	loadRedisCallTimeouts(cfg)
+}
+
+func loadRedisCallTimeouts(cfg *RedisConfig) {
 	if v := getEnvDuration("REDIS_DIAL_TIMEOUT"); v != 0 {
 		cfg.DialTimeout = v
 	}
@@ -76,6 +253,9 @@ func loadRedisTimeouts(cfg *RedisConfig) {
 	if v := getEnvDuration("REDIS_PING_TIMEOUT"); v != 0 {
 		cfg.PingTimeout = v
 	}
+	if v := getEnvDuration("REDIS_OPERATION_TIMEOUT"); v != 0 {
+		cfg.OperationTimeout = v
+	}
 }
 
 // loadRedisPoolLifecycle treats an explicit "0s" as a request to disable
@@ -106,6 +286,8 @@ func loadMQTTFromEnv(cfg *MQTTConfig) {
 	loadMQTTTimeouts(cfg)
 	loadMQTTTLS(cfg)
 	loadMQTTBools(cfg)
+	loadMQTTSession(cfg)
+	loadMQTTLifecycle(cfg)
 }
 
 func loadMQTTStrings(cfg *MQTTConfig) {
@@ -121,6 +303,43 @@ func loadMQTTStrings(cfg *MQTTConfig) {
 	if v := getEnvString("MQTT_ACK_TOPIC"); v != "" {
 		cfg.AckTopic = v
 	}
+	if v := getEnvString("MQTT_CONTROL_TOPIC"); v != "" {
+		cfg.ControlTopic = v
+	}
+	if v := getEnvString("MQTT_STATUS_TOPIC"); v != "" {
+		cfg.StatusTopic = v
+	}
+	if v := getEnvList("MQTT_OVERRIDE_TOPICS"); v != nil {
+		cfg.OverrideTopics = v
+	}
+	if v := getEnvList("MQTT_FANOUT_TOPICS"); v != nil {
+		cfg.FanoutTopics = v
+	}
+	if v := getEnvString("MQTT_FANOUT_MODE"); v != "" {
+		cfg.FanoutMode = v
+	}
+	loadMQTTSample(cfg)
+}
+
+func loadMQTTLifecycle(cfg *MQTTConfig) {
+	if v := getEnvString("MQTT_LIFECYCLE_TOPIC"); v != "" {
+		cfg.LifecycleTopic = v
+	}
+}
+
+func loadMQTTSample(cfg *MQTTConfig) {
+	if v := getEnvString("MQTT_SAMPLE_TOPIC"); v != "" {
+		cfg.SampleTopic = v
+	}
+	if v := getEnvList("MQTT_SAMPLE_FIELDS"); v != nil {
+		cfg.SampleFields = v
+	}
+	if v := getEnvInt("MQTT_SAMPLE_PERCENT"); v != 0 {
+		cfg.SamplePercent = v
+	}
+	if v := getEnvInt("MQTT_SAMPLE_EVERY"); v != 0 {
+		cfg.SampleEvery = v
+	}
 }
 
 func loadMQTTInts(cfg *MQTTConfig) {
@@ -142,6 +361,22 @@ func loadMQTTInts(cfg *MQTTConfig) {
 	if v := getEnvInt("MQTT_MAX_RESUME_PUB_IN_FLIGHT"); v != 0 {
 		cfg.MaxResumePubInFlight = v
 	}
+	if v := getEnvInt("MQTT_QUARANTINE_FAILURES"); v != 0 {
+		cfg.QuarantineFailures = v
+	}
+	if v := getEnvInt("MQTT_MAX_INFLIGHT"); v != 0 {
+		cfg.MaxInflight = v
+	}
+	loadMQTTRTT(cfg)
+}
+
+func loadMQTTRTT(cfg *MQTTConfig) {
+	if v := getEnvInt("MQTT_RTT_SAMPLE_EVERY"); v != 0 {
+		cfg.RTTSampleEvery = v
+	}
+	if v := getEnvDuration("MQTT_RTT_WINDOW"); v != 0 {
+		cfg.RTTWindow = v
+	}
 }
 
 func loadMQTTTimeouts(cfg *MQTTConfig) {
@@ -166,6 +401,9 @@ func loadMQTTTimeouts(cfg *MQTTConfig) {
 	if v := getEnvDuration("MQTT_CONNECT_RETRY_DELAY"); v != 0 {
 		cfg.ConnectRetryDelay = v
 	}
+	if v := getEnvInt("MQTT_CONNECT_ATTEMPTS"); v != 0 {
+		cfg.ConnectAttempts = v
+	}
 }
 
 func loadMQTTTLS(cfg *MQTTConfig) {
@@ -178,6 +416,9 @@ func loadMQTTTLS(cfg *MQTTConfig) {
 	if v := getEnvString("MQTT_CLIENT_KEY"); v != "" {
 		cfg.ClientKey = v
 	}
+	if v := getEnvString("MQTT_ACK_HMAC_KEY"); v != "" {
+		cfg.AckHMACKey = v
+	}
 }
 
 func loadMQTTBools(cfg *MQTTConfig) {
@@ -190,6 +431,24 @@ func loadMQTTBools(cfg *MQTTConfig) {
 	if v, ok := lookupEnvBool("MQTT_USE_CERT_CN_PREFIX"); ok {
 		cfg.UseCertCNPrefix = v
 	}
+	if v, ok := lookupEnvBool("MQTT_ORIGIN_MARKER"); ok {
+		cfg.OriginMarker = v
+	}
+	if v, ok := lookupEnvBool("MQTT_ACK_FLOW_CONTROL"); ok {
+		cfg.AckFlowControl = v
+	}
+}
+
+func loadMQTTSession(cfg *MQTTConfig) {
+	if v, ok := lookupEnvBool("MQTT_PERSISTENT_SESSION"); ok {
+		cfg.PersistentSession = v
+	}
+	if v := getEnvString("MQTT_STORE_DIR"); v != "" {
+		cfg.StoreDir = v
+	}
+	if v := getEnvInt("MQTT_STORE_MAX_MESSAGES"); v != 0 {
+		cfg.StoreMaxMessages = v
+	}
 }
 
 func loadCompressFromEnv(cfg *CompressConfig) {
@@ -204,12 +463,283 @@ func loadCompressFromEnv(cfg *CompressConfig) {
 	}
 }
 
+func loadRuntimeFromEnv(cfg *RuntimeConfig) {
+	if v := getEnvInt("RUNTIME_GC_PERCENT"); v != 0 {
+		cfg.GCPercent = v
+	}
+	if v := getEnvInt("RUNTIME_MEMORY_LIMIT"); v != 0 {
+		cfg.MemoryLimit = v
+	}
+	if v := getEnvInt("RUNTIME_MEMORY_LIMIT_PERCENT"); v != 0 {
+		cfg.MemoryLimitPercent = v
+	}
+}
+
+func loadArchiveFromEnv(cfg *ArchiveConfig) {
+	if v := getEnvString("ARCHIVE_MODE"); v != "" {
+		cfg.Mode = v
+	}
+	if v := getEnvString("ARCHIVE_DIR"); v != "" {
+		cfg.Dir = v
+	}
+	if v := getEnvInt("ARCHIVE_MAX_BYTES"); v != 0 {
+		cfg.MaxBytes = v
+	}
+	if v := getEnvDuration("ARCHIVE_MAX_AGE"); v != 0 {
+		cfg.MaxAge = v
+	}
+	if v, ok := lookupEnvBool("ARCHIVE_GZIP"); ok {
+		cfg.Gzip = v
+	}
+	if v := getEnvDuration("ARCHIVE_UPLOAD_RETRY"); v != 0 {
+		cfg.UploadRetry = v
+	}
+	loadArchiveS3FromEnv(cfg)
+}
+
+func loadArchiveS3FromEnv(cfg *ArchiveConfig) {
+	if v := getEnvString("ARCHIVE_S3_ENDPOINT"); v != "" {
+		cfg.S3Endpoint = v
+	}
+	if v := getEnvString("ARCHIVE_S3_BUCKET"); v != "" {
+		cfg.S3Bucket = v
+	}
+	if v := getEnvString("ARCHIVE_S3_REGION"); v != "" {
+		cfg.S3Region = v
+	}
+	if v := getEnvString("ARCHIVE_S3_PREFIX"); v != "" {
+		cfg.S3Prefix = v
+	}
+	if v := getEnvString("ARCHIVE_S3_ACCESS_KEY_ID"); v != "" {
+		cfg.S3AccessKeyID = v
+	}
+	if v := getEnvString("ARCHIVE_S3_SECRET_ACCESS_KEY"); v != "" {
+		cfg.S3SecretAccessKey = v
+	}
+	if v := getEnvInt("ARCHIVE_S3_PART_BYTES"); v != 0 {
+		cfg.S3PartBytes = v
+	}
+}
+
+func loadPipelineHealthTLSFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_HEALTH_TLS_CERT"); v != "" {
+		cfg.HealthTLSCert = v
+	}
+	if v := getEnvString("PIPELINE_HEALTH_TLS_KEY"); v != "" {
+		cfg.HealthTLSKey = v
+	}
+	if v := getEnvString("PIPELINE_HEALTH_CLIENT_CA"); v != "" {
+		cfg.HealthClientCA = v
+	}
+}
+
 func loadPipelineFromEnv(cfg *PipelineConfig) {
 	loadPipelineIntsFromEnv(cfg)
 	loadPipelineDurationsFromEnv(cfg)
 	if v := getEnvString("PIPELINE_HEALTH_ADDR"); v != "" {
 		cfg.HealthAddr = v
 	}
+	loadPipelineHealthTLSFromEnv(cfg)
+	loadPipelineOrderingFromEnv(cfg)
+	if v, ok := lookupEnvBool("PIPELINE_ADMIN_DLQ_REPLAY"); ok {
+		cfg.AdminDLQReplay = v
+	}
+	if v, ok := lookupEnvBool("PIPELINE_ADMIN_STREAMS"); ok {
+		cfg.AdminStreams = v
+	}
+	if v, ok := lookupEnvBool("PIPELINE_ADMIN_BUFFER"); ok {
+		cfg.AdminBuffer = v
+	}
+	if v := getEnvString("PIPELINE_OVERSIZE_POLICY"); v != "" {
+		cfg.OversizePolicy = v
+	}
+	if v := getEnvString("PIPELINE_SPILL_DIR"); v != "" {
+		cfg.SpillDir = v
+	}
+	if v := getEnvString("PIPELINE_SNAPSHOT_PATH"); v != "" {
+		cfg.SnapshotPath = v
+	}
+	if v := getEnvList("PIPELINE_FIELD_ALLOWLIST"); v != nil {
+		cfg.FieldAllowlist = v
+	}
+	if v := getEnvList("PIPELINE_FIELD_DENYLIST"); v != nil {
+		cfg.FieldDenylist = v
+	}
+	if v := getEnvString("PIPELINE_STRUCTURED_DATA"); v != "" {
+		cfg.StructuredData = v
+	}
+	if v := getEnvString("PIPELINE_INVALID_OBJECT"); v != "" {
+		cfg.InvalidObject = v
+	}
+	loadPipelinePoliciesFromEnv(cfg)
+	loadPipelineTimestampsFromEnv(cfg)
+	loadPipelineRetryFromEnv(cfg)
+	loadPipelineStatsdFromEnv(cfg)
+	loadPipelineAckSLOFromEnv(cfg)
+	loadPipelinePublishDedupFromEnv(cfg)
+	loadPipelineEnrichFromEnv(cfg)
+	loadPipelinePublishFromEnv(cfg)
+	loadPipelineLifecycleFromEnv(cfg)
+	loadPipelineRedactFromEnv(cfg)
+}
+
+func loadPipelineRedactFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_REDACT_FILE"); v != "" {
+		cfg.RedactFile = v
+	}
+	if v, ok := lookupEnvBool("PIPELINE_REDACT_STRICT"); ok {
+		cfg.RedactStrict = v
+	}
+}
+
+func loadPipelineLifecycleFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_LIFECYCLE_LOG"); v != "" {
+		cfg.LifecycleLog = v
+	}
+}
+
+func loadPipelinePublishFromEnv(cfg *PipelineConfig) {
+	if v := getEnvDuration("PIPELINE_PUBLISH_TIMEOUT"); v != 0 {
+		cfg.PublishTimeout = v
+	}
+	if v := getEnvInt("PIPELINE_CAPTURE_SIZE"); v != 0 {
+		cfg.CaptureSize = v
+	}
+}
+
+func loadPipelineEnrichFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_ENRICH_KEY"); v != "" {
+		cfg.EnrichKey = v
+	}
+	if v := getEnvString("PIPELINE_ENRICH_HASH_PREFIX"); v != "" {
+		cfg.EnrichHashPrefix = v
+	}
+	if v := getEnvList("PIPELINE_ENRICH_FIELDS"); v != nil {
+		cfg.EnrichFields = v
+	}
+	if v := getEnvDuration("PIPELINE_ENRICH_CACHE_TTL"); v != 0 {
+		cfg.EnrichCacheTTL = v
+	}
+	if v := getEnvInt("PIPELINE_ENRICH_CACHE_SIZE"); v != 0 {
+		cfg.EnrichCacheSize = v
+	}
+}
+
+func loadPipelineAckSLOFromEnv(cfg *PipelineConfig) {
+	if v := getEnvDuration("PIPELINE_ACK_SLO_LATENCY"); v != 0 {
+		cfg.AckSLOLatency = v
+	}
+	if v := getEnvFloat("PIPELINE_ACK_SLO_PERCENTILE"); v != 0 {
+		cfg.AckSLOPercentile = v
+	}
+	if v := getEnvDuration("PIPELINE_ACK_SLO_WINDOW"); v != 0 {
+		cfg.AckSLOWindow = v
+	}
+	if v := getEnvInt("PIPELINE_ACK_SLO_WINDOWS"); v != 0 {
+		cfg.AckSLOWindows = v
+	}
+}
+
+func loadPipelinePublishDedupFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_PUBLISH_DEDUP"); v != "" {
+		cfg.PublishDedup = v
+	}
+	if v := getEnvDuration("PIPELINE_PUBLISH_DEDUP_WINDOW"); v != 0 {
+		cfg.PublishDedupWindow = v
+	}
+}
+
+func loadPipelineStatsdFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_STATSD_ADDR"); v != "" {
+		cfg.StatsdAddr = v
+	}
+	if v := getEnvString("PIPELINE_STATSD_PREFIX"); v != "" {
+		cfg.StatsdPrefix = v
+	}
+	if v := getEnvList("PIPELINE_STATSD_TAGS"); v != nil {
+		cfg.StatsdTags = v
+	}
+	if v := getEnvDuration("PIPELINE_STATSD_INTERVAL"); v != 0 {
+		cfg.StatsdInterval = v
+	}
+	if v := getEnvString("PIPELINE_METRICS_STATE_PATH"); v != "" {
+		cfg.MetricsStatePath = v
+	}
+	if v := getEnvDuration("PIPELINE_METRICS_STATE_INTERVAL"); v != 0 {
+		cfg.MetricsStateInterval = v
+	}
+}
+
+func loadPipelineRetryFromEnv(cfg *PipelineConfig) {
+	if v := getEnvInt("PIPELINE_RETRY_QUEUE_CAPACITY"); v != 0 {
+		cfg.RetryQueueCapacity = v
+	}
+	if v := getEnvInt("PIPELINE_RETRY_RATE"); v != 0 {
+		cfg.RetryRate = v
+	}
+	if v := getEnvString("PIPELINE_RETRY_PRIORITY"); v != "" {
+		cfg.RetryPriority = v
+	}
+}
+
+func loadPipelinePoliciesFromEnv(cfg *PipelineConfig) {
+	if v := getEnvList("PIPELINE_NACK_POLICIES"); v != nil {
+		cfg.NackPolicies = v
+	}
+	if v := getEnvList("PIPELINE_MAX_AGE_POLICIES"); v != nil {
+		cfg.MaxAgePolicies = v
+	}
+	if v := getEnvList("PIPELINE_STREAM_QUOTAS"); v != nil {
+		cfg.StreamQuotas = v
+	}
+	loadPipelineNackFloodFromEnv(cfg)
+	loadPipelineThroughputFromEnv(cfg)
+}
+
+func loadPipelineThroughputFromEnv(cfg *PipelineConfig) {
+	if v := getEnvList("PIPELINE_THROUGHPUT_SCHEDULE"); v != nil {
+		cfg.ThroughputSchedule = v
+	}
+	if v := getEnvString("PIPELINE_THROUGHPUT_LOCATION"); v != "" {
+		cfg.ThroughputLocation = v
+	}
+	loadOptionalDuration("PIPELINE_THROUGHPUT_RAMP", &cfg.ThroughputRamp)
+}
+
+func loadPipelineNackFloodFromEnv(cfg *PipelineConfig) {
+	if v := getEnvInt("PIPELINE_NACK_FLOOD_THRESHOLD"); v != 0 {
+		cfg.NackFloodThreshold = v
+	}
+	if v := getEnvDuration("PIPELINE_NACK_FLOOD_WINDOW"); v != 0 {
+		cfg.NackFloodWindow = v
+	}
+	if v := getEnvDuration("PIPELINE_NACK_FLOOD_COOLDOWN"); v != 0 {
+		cfg.NackFloodCooldown = v
+	}
+}
+
+func loadPipelineOrderingFromEnv(cfg *PipelineConfig) {
+	if v, ok := lookupEnvBool("PIPELINE_ORDERED_PUBLISH"); ok {
+		cfg.OrderedPublish = v
+	}
+	if v := getEnvString("PIPELINE_ORDERING_KEY"); v != "" {
+		cfg.OrderingKey = v
+	}
+}
+
+func loadPipelineTimestampsFromEnv(cfg *PipelineConfig) {
+	if v := getEnvString("PIPELINE_TIMESTAMP_FIELD"); v != "" {
+		cfg.TimestampField = v
+	}
+	if v := splitLayouts(os.Getenv("PIPELINE_TIMESTAMP_LAYOUTS")); v != nil {
+		cfg.TimestampLayouts = v
+	}
+	if v := getEnvString("PIPELINE_TIMESTAMP_LOCATION"); v != "" {
+		cfg.TimestampLocation = v
+	}
+	if v := getEnvDuration("PIPELINE_TIMESTAMP_MAX_SKEW"); v != 0 {
+		cfg.TimestampMaxSkew = v
+	}
 }
 
 func loadPipelineIntsFromEnv(cfg *PipelineConfig) {
@@ -228,12 +758,43 @@ func loadPipelineIntsFromEnv(cfg *PipelineConfig) {
 	if v := getEnvInt("PIPELINE_ACK_WORKERS"); v != 0 {
 		cfg.AckWorkers = v
 	}
+	if v := getEnvInt("PIPELINE_PUBLISH_STALL_FACTOR"); v != 0 {
+		cfg.PublishStallFactor = v
+	}
+	if v := getEnvInt("PIPELINE_MAX_PAYLOAD_BYTES"); v != 0 {
+		cfg.MaxPayloadBytes = v
+	}
+	if v := getEnvInt("PIPELINE_MEMORY_BUDGET"); v != 0 {
+		cfg.MemoryBudget = v
+	}
+	if v := getEnvInt("PIPELINE_SPILL_MIN_BYTES"); v != 0 {
+		cfg.SpillMinBytes = v
+	}
+	if v := getEnvInt("PIPELINE_SPILL_ARENA_BYTES"); v != 0 {
+		cfg.SpillArenaBytes = v
+	}
+	if v := getEnvInt("PIPELINE_RECEIPT_QUEUE_CAPACITY"); v != 0 {
+		cfg.ReceiptQueueCapacity = v
+	}
+	if v := getEnvInt("PIPELINE_RECEIPT_BATCH_SIZE"); v != 0 {
+		cfg.ReceiptBatchSize = v
+	}
+}
+
+func loadPipelineAckDrainFromEnv(cfg *PipelineConfig) {
+	if v := getEnvDuration("PIPELINE_ACK_DRAIN_TIMEOUT"); v != 0 {
+		cfg.AckDrainTimeout = v
+	}
 }
 
 func loadPipelineDurationsFromEnv(cfg *PipelineConfig) {
+	loadPipelineAckDrainFromEnv(cfg)
 	if v := getEnvDuration("PIPELINE_SHUTDOWN_TIMEOUT"); v != 0 {
 		cfg.ShutdownTimeout = v
 	}
+	if v := getEnvDuration("PIPELINE_DRAIN_TIMEOUT"); v != 0 {
+		cfg.DrainTimeout = v
+	}
 	if v := getEnvDuration("PIPELINE_ERROR_BACKOFF"); v != 0 {
 		cfg.ErrorBackoff = v
 	}
@@ -246,6 +807,9 @@ func loadPipelineDurationsFromEnv(cfg *PipelineConfig) {
 	if v := getEnvDuration("PIPELINE_ACK_FLUSH_INTERVAL"); v != 0 {
 		cfg.AckFlushInterval = v
 	}
+	if v := getEnvDuration("PIPELINE_RECEIPT_FLUSH_INTERVAL"); v != 0 {
+		cfg.ReceiptFlushInterval = v
+	}
 	if v := getEnvDuration("PIPELINE_HEALTH_PING_TIMEOUT"); v != 0 {
 		cfg.HealthPingTimeout = v
 	}
@@ -258,6 +822,35 @@ func getEnvString(key string) string {
 	return os.Getenv(key)
 }
 
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// splitList parses a comma-separated list, dropping blanks; it returns nil
+// when no names remain so callers can treat that as "unset".
+func splitList(value string) []string {
+	var out []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// splitLayouts parses a '|'-separated list of time layouts, which may
+// themselves contain commas and spaces; blanks are dropped and nil means
+// "unset".
+func splitLayouts(value string) []string {
+	var out []string
+	for item := range strings.SplitSeq(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnvInt(key string) int {
 	value := os.Getenv(key)
 	if value == "" {
@@ -271,6 +864,20 @@ func getEnvInt(key string) int {
 	return intValue
 }
 
+// loadOptionalInt is loadOptionalDuration for an integer.
+func loadOptionalInt(key string, dst *int) {
+	raw, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: invalid integer for %s=%q, using default\n", key, raw)
+		return
+	}
+	*dst = v
+}
+
 func getEnvUint(key string) uint {
 	value := os.Getenv(key)
 	if value == "" {
@@ -284,6 +891,19 @@ func getEnvUint(key string) uint {
 	return uint(v)
 }
 
+func getEnvFloat(key string) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: invalid number for %s=%q, using default\n", key, value)
+		return 0
+	}
+	return v
+}
+
 func getEnvDuration(key string) time.Duration {
 	value := os.Getenv(key)
 	if value == "" {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -16,14 +17,31 @@ func TestLoadRedisFromEnv(t *testing.T) {
 	t.Setenv("REDIS_BATCH_SIZE", "100")
 	t.Setenv("REDIS_BLOCK_TIMEOUT", "3s")
 	t.Setenv("REDIS_CLAIM_IDLE", "20s")
+	t.Setenv("REDIS_CLAIM_INTERVAL", "5s")
 	t.Setenv("REDIS_CONSUMER_IDLE_TIMEOUT", "3m")
 	t.Setenv("REDIS_CLEANUP_INTERVAL", "2m")
 	t.Setenv("REDIS_DIAL_TIMEOUT", "5s")
 	t.Setenv("REDIS_READ_TIMEOUT", "7s")
 	t.Setenv("REDIS_WRITE_TIMEOUT", "3s")
 	t.Setenv("REDIS_PING_TIMEOUT", "2s")
+	t.Setenv("REDIS_OPERATION_TIMEOUT", "12s")
 	t.Setenv("REDIS_CONN_MAX_IDLE_TIME", "4m")
 	t.Setenv("REDIS_CONN_MAX_LIFETIME", "20m")
+	t.Setenv("REDIS_CLAIM_CONCURRENCY", "16")
+	t.Setenv("REDIS_CLAIM_COORDINATION", "true")
+	t.Setenv("REDIS_NOACK", "true")
+	t.Setenv("REDIS_BACKFILL", "true")
+	t.Setenv("REDIS_BACKFILL_RATE", "250")
+	t.Setenv("REDIS_BACKFILL_STRICT_ORDER", "true")
+	t.Setenv("REDIS_BACKFILL_POLICY", "claim-first")
+	t.Setenv("REDIS_USERNAME", "consumer-acl")
+	t.Setenv("REDIS_PASSWORD", "s3cret")
+	t.Setenv("REDIS_PASSWORD_FILE", "/run/secrets/redis")
+	t.Setenv("REDIS_KEY_PREFIX", "tenant-a:")
+	t.Setenv("REDIS_REPLICA_ADDRESS", "replica:6379")
+	t.Setenv("REDIS_DB", "3")
+	t.Setenv("REDIS_DEAD_LETTER_STREAM", "syslog-dlq")
+	t.Setenv("REDIS_RECEIPTS_STREAM", "syslog-receipts")
 
 	// Load from environment
 	loadRedisFromEnv(&cfg)
@@ -40,12 +58,29 @@ func TestLoadRedisFromEnv(t *testing.T) {
 		{cfg.BatchSize, 100, "BatchSize"},
 		{cfg.BlockTimeout, 3 * time.Second, "BlockTimeout"},
 		{cfg.ClaimIdle, 20 * time.Second, "ClaimIdle"},
+		{cfg.ClaimInterval, 5 * time.Second, "ClaimInterval"},
+		{cfg.ClaimConcurrency, 16, "ClaimConcurrency"},
+		{cfg.ClaimCoordination, true, "ClaimCoordination"},
+		{cfg.NoAck, true, "NoAck"},
+		{cfg.Backfill, true, "Backfill"},
+		{cfg.BackfillRate, 250, "BackfillRate"},
+		{cfg.BackfillStrictOrder, true, "BackfillStrictOrder"},
+		{cfg.BackfillPolicy, "claim-first", "BackfillPolicy"},
+		{cfg.Username, "consumer-acl", "Username"},
+		{cfg.Password, "s3cret", "Password"},
+		{cfg.PasswordFile, "/run/secrets/redis", "PasswordFile"},
+		{cfg.KeyPrefix, "tenant-a:", "KeyPrefix"},
+		{cfg.ReplicaAddress, "replica:6379", "ReplicaAddress"},
+		{cfg.DB, 3, "DB"},
+		{cfg.DeadLetterStream, "syslog-dlq", "DeadLetterStream"},
+		{cfg.ReceiptsStream, "syslog-receipts", "ReceiptsStream"},
 		{cfg.ConsumerIdleTimeout, 3 * time.Minute, "ConsumerIdleTimeout"},
 		{cfg.CleanupInterval, 2 * time.Minute, "CleanupInterval"},
 		{cfg.DialTimeout, 5 * time.Second, "DialTimeout"},
 		{cfg.ReadTimeout, 7 * time.Second, "ReadTimeout"},
 		{cfg.WriteTimeout, 3 * time.Second, tcWriteTimeout},
 		{cfg.PingTimeout, 2 * time.Second, "PingTimeout"},
+		{cfg.OperationTimeout, 12 * time.Second, "OperationTimeout"},
 		{cfg.ConnMaxIdleTime, 4 * time.Minute, "ConnMaxIdleTime"},
 		{cfg.ConnMaxLifetime, 20 * time.Minute, "ConnMaxLifetime"},
 	}
@@ -59,6 +94,196 @@ func TestLoadRedisFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadRedisFromEnv_Dedup(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_DEDUP", "keys")
+	t.Setenv("REDIS_DEDUP_WINDOW", "1h")
+	t.Setenv("REDIS_DEDUP_CAPACITY", "20000")
+
+	loadRedisFromEnv(&cfg)
+
+	if cfg.Dedup != DedupKeys || cfg.DedupWindow != time.Hour || cfg.DedupCapacity != 20000 {
+		t.Errorf("Dedup/Window/Capacity = %s/%v/%d; want keys/1h/20000",
+			cfg.Dedup, cfg.DedupWindow, cfg.DedupCapacity)
+	}
+}
+
+func TestLoadRedisFromEnv_AdaptiveBatch(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_ADAPTIVE_BATCH", "true")
+	t.Setenv("REDIS_BATCH_SIZE_MIN", "50")
+	t.Setenv("REDIS_BATCH_SIZE_MAX", "5000")
+	t.Setenv("REDIS_BATCH_LATENCY_TARGET", "100ms")
+
+	loadRedisFromEnv(&cfg)
+
+	if !cfg.AdaptiveBatch || cfg.BatchSizeMin != 50 || cfg.BatchSizeMax != 5000 ||
+		cfg.BatchLatencyTarget != 100*time.Millisecond {
+		t.Errorf("AdaptiveBatch/Min/Max/Target = %v/%d/%d/%v; want true/50/5000/100ms",
+			cfg.AdaptiveBatch, cfg.BatchSizeMin, cfg.BatchSizeMax, cfg.BatchLatencyTarget)
+	}
+}
+
+func TestLoadRedisFromEnv_MemoryMonitor(t *testing.T) {
+	cfg := defaultConfig()
+	t.Setenv("REDIS_MEMORY_HIGH_PERCENT", "90")
+	t.Setenv("REDIS_MEMORY_LOW_PERCENT", "75")
+	t.Setenv("REDIS_MEMORY_CHECK_INTERVAL", "2s")
+	t.Setenv("REDIS_MEMORY_PRESSURE_KEY", "syslog:pressure")
+	t.Setenv("MQTT_CONTROL_TOPIC", "syslog/control")
+	t.Setenv("MQTT_STATUS_TOPIC", "syslog/status")
+
+	loadRedisFromEnv(&cfg.Redis)
+	loadMQTTFromEnv(&cfg.MQTT)
+
+	if cfg.MQTT.StatusTopic != "syslog/status" {
+		t.Errorf("StatusTopic = %q; want syslog/status", cfg.MQTT.StatusTopic)
+	}
+
+	if cfg.Redis.MemoryHighPercent != 90 || cfg.Redis.MemoryLowPercent != 75 ||
+		cfg.Redis.MemoryCheckInterval != 2*time.Second || cfg.Redis.MemoryPressureKey != "syslog:pressure" {
+		t.Errorf("High/Low/Interval/Key = %d/%d/%v/%q; want 90/75/2s/syslog:pressure", cfg.Redis.MemoryHighPercent,
+			cfg.Redis.MemoryLowPercent, cfg.Redis.MemoryCheckInterval, cfg.Redis.MemoryPressureKey)
+	}
+	if cfg.MQTT.ControlTopic != "syslog/control" {
+		t.Errorf("ControlTopic = %q; want syslog/control", cfg.MQTT.ControlTopic)
+	}
+}
+
+func TestLoadRedisFromEnv_Compaction(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_COMPACT_INTERVAL", "10m")
+	t.Setenv("REDIS_COMPACT_MIN_AGE", "2h")
+	t.Setenv("REDIS_COMPACT_BATCH_SIZE", "500")
+	t.Setenv("REDIS_COMPACT_DRY_RUN", "true")
+
+	loadRedisFromEnv(&cfg)
+
+	if cfg.CompactInterval != 10*time.Minute || cfg.CompactMinAge != 2*time.Hour ||
+		cfg.CompactBatchSize != 500 || !cfg.CompactDryRun {
+		t.Errorf("Interval/MinAge/BatchSize/DryRun = %v/%v/%d/%v; want 10m/2h/500/true",
+			cfg.CompactInterval, cfg.CompactMinAge, cfg.CompactBatchSize, cfg.CompactDryRun)
+	}
+}
+
+func TestLoadRedisFromEnv_Control(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_CONTROL_KEY", "syslog-consumer:control")
+	t.Setenv("REDIS_CONTROL_INTERVAL", "2s")
+
+	loadRedisFromEnv(&cfg)
+
+	if cfg.ControlKey != "syslog-consumer:control" || cfg.ControlInterval != 2*time.Second {
+		t.Errorf("Key/Interval = %q/%v; want syslog-consumer:control/2s", cfg.ControlKey, cfg.ControlInterval)
+	}
+}
+
+func TestLoadRedisFromEnv_StreamLease(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_STREAM_LEASE", "true")
+	t.Setenv("REDIS_STREAM_LEASE_TTL", "30s")
+
+	loadRedisFromEnv(&cfg)
+
+	if !cfg.StreamLease || cfg.StreamLeaseTTL != 30*time.Second {
+		t.Errorf("StreamLease/TTL = %v/%v; want true/30s", cfg.StreamLease, cfg.StreamLeaseTTL)
+	}
+}
+
+func TestLoadRedisFromEnv_IdleStreams(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_IDLE_STREAM_AFTER", "10m")
+	t.Setenv("REDIS_IDLE_STREAM_POLL", "30s")
+
+	loadRedisFromEnv(&cfg)
+
+	if cfg.IdleStreamAfter != 10*time.Minute || cfg.IdleStreamPoll != 30*time.Second {
+		t.Errorf("IdleStreamAfter/Poll = %v/%v; want 10m/30s", cfg.IdleStreamAfter, cfg.IdleStreamPoll)
+	}
+}
+
+func TestLoadRedisFromEnv_Lag(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_LAG_INTERVAL", "10s")
+	t.Setenv("REDIS_LAG_HIGH", "50000")
+
+	loadRedisFromEnv(&cfg)
+
+	if cfg.LagInterval != 10*time.Second || cfg.LagHigh != 50000 {
+		t.Errorf("Interval/High = %v/%d; want 10s/50000", cfg.LagInterval, cfg.LagHigh)
+	}
+}
+
+func TestLoadRedisFromEnv_StreamFilters(t *testing.T) {
+	cfg := defaultRedisConfig()
+	t.Setenv("REDIS_STREAM_INCLUDE", "syslog:*, audit:*")
+	t.Setenv("REDIS_STREAM_EXCLUDE", "syslog:test*")
+	t.Setenv("REDIS_STREAM_OVERRIDES_FILE", "/etc/syslog-consumer/streams.json")
+	t.Setenv("REDIS_READ_FIELDS", "object, _topic")
+
+	loadRedisFromEnv(&cfg)
+
+	if want := []string{"object", "_topic"}; !reflect.DeepEqual(cfg.ReadFields, want) {
+		t.Errorf("ReadFields = %q; want %q", cfg.ReadFields, want)
+	}
+
+	if want := []string{"syslog:*", "audit:*"}; !reflect.DeepEqual(cfg.StreamInclude, want) {
+		t.Errorf("StreamInclude = %q; want %q", cfg.StreamInclude, want)
+	}
+	if want := []string{"syslog:test*"}; !reflect.DeepEqual(cfg.StreamExclude, want) {
+		t.Errorf("StreamExclude = %q; want %q", cfg.StreamExclude, want)
+	}
+	if cfg.StreamOverridesFile != "/etc/syslog-consumer/streams.json" {
+		t.Errorf("StreamOverridesFile = %q; want /etc/syslog-consumer/streams.json", cfg.StreamOverridesFile)
+	}
+}
+
+func TestLoadMQTTFromEnv_OverrideTopics(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	t.Setenv("MQTT_OVERRIDE_TOPICS", "alerts/#, audit/+")
+
+	loadMQTTFromEnv(&cfg)
+
+	if want := []string{"alerts/#", "audit/+"}; !reflect.DeepEqual(cfg.OverrideTopics, want) {
+		t.Errorf("OverrideTopics = %q; want %q", cfg.OverrideTopics, want)
+	}
+}
+
+func TestLoadMQTTFromEnv_Fanout(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	t.Setenv("MQTT_FANOUT_TOPICS", "archive/raw=raw, audit/copy")
+	t.Setenv("MQTT_FANOUT_MODE", "all")
+
+	loadMQTTFromEnv(&cfg)
+
+	if want := []string{"archive/raw=raw", "audit/copy"}; !reflect.DeepEqual(cfg.FanoutTopics, want) {
+		t.Errorf("FanoutTopics = %q; want %q", cfg.FanoutTopics, want)
+	}
+	if cfg.FanoutMode != FanoutAll {
+		t.Errorf("FanoutMode = %q; want %q", cfg.FanoutMode, FanoutAll)
+	}
+}
+
+func TestLoadMQTTFromEnv_Sample(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	t.Setenv("MQTT_SAMPLE_TOPIC", "analytics/sample")
+	t.Setenv("MQTT_SAMPLE_FIELDS", "severity, hostname")
+	t.Setenv("MQTT_SAMPLE_PERCENT", "5")
+	t.Setenv("MQTT_SAMPLE_EVERY", "100")
+
+	loadMQTTFromEnv(&cfg)
+
+	if cfg.SampleTopic != "analytics/sample" {
+		t.Errorf("SampleTopic = %q; want analytics/sample", cfg.SampleTopic)
+	}
+	if want := []string{"severity", "hostname"}; !reflect.DeepEqual(cfg.SampleFields, want) {
+		t.Errorf("SampleFields = %q; want %q", cfg.SampleFields, want)
+	}
+	if cfg.SamplePercent != 5 || cfg.SampleEvery != 100 {
+		t.Errorf("SamplePercent = %d, SampleEvery = %d; want 5 and 100", cfg.SamplePercent, cfg.SampleEvery)
+	}
+}
+
 func TestLoadMQTTFromEnv(t *testing.T) {
 	// Start with defaults
 	cfg := defaultMQTTConfig()
@@ -78,9 +303,20 @@ func TestLoadMQTTFromEnv(t *testing.T) {
 	t.Setenv("MQTT_CA_CERT", "/path/ca.crt")
 	t.Setenv("MQTT_CLIENT_CERT", "/path/client.crt")
 	t.Setenv("MQTT_CLIENT_KEY", "/path/client.key")
+	t.Setenv("MQTT_ACK_HMAC_KEY", "secret")
 	t.Setenv("MQTT_TLS_ENABLED", "true")
 	t.Setenv("MQTT_TLS_INSECURE_SKIP", "true")
 	t.Setenv("MQTT_USE_CERT_CN_PREFIX", "true")
+	t.Setenv("MQTT_PERSISTENT_SESSION", "true")
+	t.Setenv("MQTT_ORIGIN_MARKER", "true")
+	t.Setenv("MQTT_ACK_FLOW_CONTROL", "true")
+	t.Setenv("MQTT_STORE_DIR", "/var/lib/consumer/mqtt")
+	t.Setenv("MQTT_STORE_MAX_MESSAGES", "500")
+	t.Setenv("MQTT_QUARANTINE_FAILURES", "3")
+	t.Setenv("MQTT_CONNECT_ATTEMPTS", "5")
+	t.Setenv("MQTT_MAX_INFLIGHT", "128")
+	t.Setenv("MQTT_RTT_SAMPLE_EVERY", "50")
+	t.Setenv("MQTT_RTT_WINDOW", "30s")
 
 	// Load from environment
 	loadMQTTFromEnv(&cfg)
@@ -105,9 +341,20 @@ func TestLoadMQTTFromEnv(t *testing.T) {
 		{cfg.CACert, "/path/ca.crt", "CACert"},
 		{cfg.ClientCert, "/path/client.crt", "ClientCert"},
 		{cfg.ClientKey, "/path/client.key", "ClientKey"},
+		{cfg.AckHMACKey, "secret", "AckHMACKey"},
 		{cfg.TLSEnabled, true, "TLSEnabled"},
 		{cfg.InsecureSkip, true, "InsecureSkip"},
 		{cfg.UseCertCNPrefix, true, "UseCertCNPrefix"},
+		{cfg.PersistentSession, true, "PersistentSession"},
+		{cfg.OriginMarker, true, "OriginMarker"},
+		{cfg.AckFlowControl, true, "AckFlowControl"},
+		{cfg.StoreDir, "/var/lib/consumer/mqtt", "StoreDir"},
+		{cfg.StoreMaxMessages, 500, "StoreMaxMessages"},
+		{cfg.QuarantineFailures, 3, "QuarantineFailures"},
+		{cfg.ConnectAttempts, 5, "ConnectAttempts"},
+		{cfg.MaxInflight, 128, "MaxInflight"},
+		{cfg.RTTSampleEvery, 50, "RTTSampleEvery"},
+		{cfg.RTTWindow, 30 * time.Second, "RTTWindow"},
 	}
 
 	for _, tt := range tests {
@@ -131,6 +378,21 @@ func TestLoadPipelineFromEnv(t *testing.T) {
 	t.Setenv("PIPELINE_PUBLISH_WORKERS", "10")
 	t.Setenv("PIPELINE_REFRESH_INTERVAL", "2m")
 	t.Setenv("PIPELINE_HEALTH_ADDR", ":9090")
+	t.Setenv("PIPELINE_ORDERED_PUBLISH", "true")
+	t.Setenv("PIPELINE_ORDERING_KEY", "hostname")
+	t.Setenv("PIPELINE_ADMIN_DLQ_REPLAY", "true")
+	t.Setenv("PIPELINE_ADMIN_STREAMS", "true")
+	t.Setenv("PIPELINE_ADMIN_BUFFER", "true")
+	t.Setenv("PIPELINE_MAX_PAYLOAD_BYTES", "262144")
+	t.Setenv("PIPELINE_PUBLISH_STALL_FACTOR", "3")
+	t.Setenv("PIPELINE_OVERSIZE_POLICY", "split")
+	t.Setenv("PIPELINE_DRAIN_TIMEOUT", "7s")
+	t.Setenv("PIPELINE_ACK_DRAIN_TIMEOUT", "2s")
+	t.Setenv("PIPELINE_PUBLISH_TIMEOUT", "4s")
+	t.Setenv("PIPELINE_CAPTURE_SIZE", "50")
+	t.Setenv("PIPELINE_RECEIPT_QUEUE_CAPACITY", "2048")
+	t.Setenv("PIPELINE_RECEIPT_BATCH_SIZE", "64")
+	t.Setenv("PIPELINE_RECEIPT_FLUSH_INTERVAL", "250ms")
 
 	// Load from environment
 	loadPipelineFromEnv(&cfg)
@@ -148,6 +410,21 @@ func TestLoadPipelineFromEnv(t *testing.T) {
 		{cfg.PublishWorkers, 10, "PublishWorkers"},
 		{cfg.RefreshInterval, 2 * time.Minute, "RefreshInterval"},
 		{cfg.HealthAddr, ":9090", "HealthAddr"},
+		{cfg.OrderedPublish, true, "OrderedPublish"},
+		{cfg.OrderingKey, "hostname", "OrderingKey"},
+		{cfg.AdminDLQReplay, true, "AdminDLQReplay"},
+		{cfg.AdminStreams, true, "AdminStreams"},
+		{cfg.AdminBuffer, true, "AdminBuffer"},
+		{cfg.MaxPayloadBytes, 262144, "MaxPayloadBytes"},
+		{cfg.PublishStallFactor, 3, "PublishStallFactor"},
+		{cfg.OversizePolicy, OversizeSplit, "OversizePolicy"},
+		{cfg.DrainTimeout, 7 * time.Second, "DrainTimeout"},
+		{cfg.AckDrainTimeout, 2 * time.Second, "AckDrainTimeout"},
+		{cfg.PublishTimeout, 4 * time.Second, "PublishTimeout"},
+		{cfg.CaptureSize, 50, "CaptureSize"},
+		{cfg.ReceiptQueueCapacity, 2048, "ReceiptQueueCapacity"},
+		{cfg.ReceiptBatchSize, 64, "ReceiptBatchSize"},
+		{cfg.ReceiptFlushInterval, 250 * time.Millisecond, "ReceiptFlushInterval"},
 	}
 
 	for _, tt := range tests {
@@ -159,6 +436,323 @@ func TestLoadPipelineFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadPipelineFromEnv_FieldProjection(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_FIELD_DENYLIST", " raw, structured_data ,,")
+
+	loadPipelineFromEnv(&cfg)
+
+	if want := []string{"raw", "structured_data"}; !reflect.DeepEqual(cfg.FieldDenylist, want) {
+		t.Errorf("FieldDenylist = %q; want %q", cfg.FieldDenylist, want)
+	}
+	if cfg.FieldAllowlist != nil {
+		t.Errorf("FieldAllowlist = %q; want nil", cfg.FieldAllowlist)
+	}
+}
+
+func TestLoadPipelineFromEnv_NackPolicies(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_NACK_POLICIES", "schema_error=dlq, overloaded=backoff:5m")
+
+	loadPipelineFromEnv(&cfg)
+
+	if want := []string{"schema_error=dlq", "overloaded=backoff:5m"}; !reflect.DeepEqual(cfg.NackPolicies, want) {
+		t.Errorf("NackPolicies = %q; want %q", cfg.NackPolicies, want)
+	}
+}
+
+func TestLoadLogFromEnv(t *testing.T) {
+	cfg := defaultLogConfig()
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_ASYNC", "true")
+	t.Setenv("LOG_QUEUE_SIZE", "1024")
+	t.Setenv("LOG_PAYLOAD_PREVIEW_BYTES", "64")
+
+	loadLogFromEnv(&cfg)
+
+	if want := (LogConfig{Level: "warn", QueueSize: 1024, PayloadPreviewBytes: 64, Async: true}); cfg != want {
+		t.Errorf("LogConfig = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestLoadPipelineFromEnv_MaxAgePolicies(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_MAX_AGE_POLICIES", "debug-*=drop:15m, audit=dlq:1h")
+
+	loadPipelineFromEnv(&cfg)
+
+	if want := []string{"debug-*=drop:15m", "audit=dlq:1h"}; !reflect.DeepEqual(cfg.MaxAgePolicies, want) {
+		t.Errorf("MaxAgePolicies = %q; want %q", cfg.MaxAgePolicies, want)
+	}
+}
+
+func TestLoadPipelineFromEnv_HealthTLS(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_HEALTH_TLS_CERT", "/etc/consumer/health.crt")
+	t.Setenv("PIPELINE_HEALTH_TLS_KEY", "/etc/consumer/health.key")
+	t.Setenv("PIPELINE_HEALTH_CLIENT_CA", "/etc/consumer/clients.pem")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.HealthTLSCert != "/etc/consumer/health.crt" || cfg.HealthTLSKey != "/etc/consumer/health.key" {
+		t.Errorf("HealthTLSCert, HealthTLSKey = %q, %q; want the env values", cfg.HealthTLSCert, cfg.HealthTLSKey)
+	}
+	if cfg.HealthClientCA != "/etc/consumer/clients.pem" {
+		t.Errorf("HealthClientCA = %q; want /etc/consumer/clients.pem", cfg.HealthClientCA)
+	}
+}
+
+func TestLoadPipelineFromEnv_StreamQuotas(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_STREAM_QUOTAS", "noisy-*=delay:500:0, audit=skip:0:65536")
+
+	loadPipelineFromEnv(&cfg)
+
+	if want := []string{"noisy-*=delay:500:0", "audit=skip:0:65536"}; !reflect.DeepEqual(cfg.StreamQuotas, want) {
+		t.Errorf("StreamQuotas = %q; want %q", cfg.StreamQuotas, want)
+	}
+}
+
+func TestLoadPipelineFromEnv_ThroughputSchedule(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_THROUGHPUT_SCHEDULE", "mon-fri 08:00-18:00=20000, sat 10:00-12:00=5000")
+	t.Setenv("PIPELINE_THROUGHPUT_LOCATION", "Europe/Rome")
+	t.Setenv("PIPELINE_THROUGHPUT_RAMP", "0s")
+
+	loadPipelineFromEnv(&cfg)
+
+	want := []string{"mon-fri 08:00-18:00=20000", "sat 10:00-12:00=5000"}
+	if !reflect.DeepEqual(cfg.ThroughputSchedule, want) {
+		t.Errorf("ThroughputSchedule = %q; want %q", cfg.ThroughputSchedule, want)
+	}
+	if cfg.ThroughputLocation != "Europe/Rome" || cfg.ThroughputRamp != 0 {
+		t.Errorf("ThroughputLocation, ThroughputRamp = %q, %v; want Europe/Rome, 0s",
+			cfg.ThroughputLocation, cfg.ThroughputRamp)
+	}
+}
+
+func TestLoadPipelineFromEnv_NackFlood(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_NACK_FLOOD_THRESHOLD", "500")
+	t.Setenv("PIPELINE_NACK_FLOOD_WINDOW", "5s")
+	t.Setenv("PIPELINE_NACK_FLOOD_COOLDOWN", "2m")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.NackFloodThreshold != 500 || cfg.NackFloodWindow != 5*time.Second || cfg.NackFloodCooldown != 2*time.Minute {
+		t.Errorf("NackFlood = %d in %v for %v; want 500 in 5s for 2m",
+			cfg.NackFloodThreshold, cfg.NackFloodWindow, cfg.NackFloodCooldown)
+	}
+}
+
+func TestLoadPipelineFromEnv_Timestamps(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_TIMESTAMP_FIELD", "ts")
+	t.Setenv("PIPELINE_TIMESTAMP_LAYOUTS", "Mon, 02 Jan 2006 15:04:05 MST | unix_ms |")
+	t.Setenv("PIPELINE_TIMESTAMP_LOCATION", "Europe/Rome")
+	t.Setenv("PIPELINE_TIMESTAMP_MAX_SKEW", "30s")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.TimestampField != "ts" {
+		t.Errorf("TimestampField = %q; want ts", cfg.TimestampField)
+	}
+	if want := []string{time.RFC1123, TimestampUnixMillis}; !reflect.DeepEqual(cfg.TimestampLayouts, want) {
+		t.Errorf("TimestampLayouts = %q; want %q", cfg.TimestampLayouts, want)
+	}
+	if cfg.TimestampLocation != "Europe/Rome" {
+		t.Errorf("TimestampLocation = %q; want Europe/Rome", cfg.TimestampLocation)
+	}
+	if cfg.TimestampMaxSkew != 30*time.Second {
+		t.Errorf("TimestampMaxSkew = %v; want 30s", cfg.TimestampMaxSkew)
+	}
+}
+
+func TestLoadPipelineFromEnv_Spill(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_MEMORY_BUDGET", "67108864")
+	t.Setenv("PIPELINE_SPILL_MIN_BYTES", "4096")
+	t.Setenv("PIPELINE_SPILL_ARENA_BYTES", "268435456")
+	t.Setenv("PIPELINE_SPILL_DIR", "/var/lib/syslog-consumer")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.MemoryBudget != 64<<20 || cfg.SpillMinBytes != 4096 || cfg.SpillArenaBytes != 256<<20 {
+		t.Errorf("MemoryBudget/SpillMinBytes/SpillArenaBytes = %d/%d/%d; want 64 MiB/4096/256 MiB",
+			cfg.MemoryBudget, cfg.SpillMinBytes, cfg.SpillArenaBytes)
+	}
+	if cfg.SpillDir != "/var/lib/syslog-consumer" {
+		t.Errorf("SpillDir = %q; want /var/lib/syslog-consumer", cfg.SpillDir)
+	}
+}
+
+func TestLoadPipelineFromEnv_SnapshotPath(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_SNAPSHOT_PATH", "/var/lib/syslog-consumer/state.json")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.SnapshotPath != "/var/lib/syslog-consumer/state.json" {
+		t.Errorf("SnapshotPath = %q; want /var/lib/syslog-consumer/state.json", cfg.SnapshotPath)
+	}
+}
+
+func TestLoadPipelineFromEnv_Retry(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_RETRY_QUEUE_CAPACITY", "64")
+	t.Setenv("PIPELINE_RETRY_RATE", "500")
+	t.Setenv("PIPELINE_RETRY_PRIORITY", "high")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.RetryQueueCapacity != 64 || cfg.RetryRate != 500 || cfg.RetryPriority != RetryPriorityHigh {
+		t.Errorf("RetryQueueCapacity/RetryRate/RetryPriority = %d/%d/%q; want 64/500/high",
+			cfg.RetryQueueCapacity, cfg.RetryRate, cfg.RetryPriority)
+	}
+}
+
+func TestLoadPipelineFromEnv_StructuredData(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_STRUCTURED_DATA", "prefixed")
+	t.Setenv("PIPELINE_INVALID_OBJECT", "lenient")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.StructuredData != StructuredDataPrefixed || cfg.InvalidObject != InvalidObjectLenient {
+		t.Errorf("StructuredData/InvalidObject = %q/%q; want prefixed/lenient", cfg.StructuredData, cfg.InvalidObject)
+	}
+}
+
+func TestLoadPipelineFromEnv_MetricsState(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_METRICS_STATE_PATH", "/var/lib/consumer/counters.json")
+	t.Setenv("PIPELINE_METRICS_STATE_INTERVAL", "1m")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.MetricsStatePath != "/var/lib/consumer/counters.json" || cfg.MetricsStateInterval != time.Minute {
+		t.Errorf("MetricsStatePath/MetricsStateInterval = %q/%v; want /var/lib/consumer/counters.json/1m",
+			cfg.MetricsStatePath, cfg.MetricsStateInterval)
+	}
+}
+
+func TestLoadFromEnv_Lifecycle(t *testing.T) {
+	cfg := defaultConfig()
+	t.Setenv("PIPELINE_LIFECYCLE_LOG", "stderr")
+	t.Setenv("MQTT_LIFECYCLE_TOPIC", "syslog/lifecycle")
+
+	loadPipelineFromEnv(&cfg.Pipeline)
+	loadMQTTFromEnv(&cfg.MQTT)
+
+	if cfg.Pipeline.LifecycleLog != "stderr" || cfg.MQTT.LifecycleTopic != "syslog/lifecycle" {
+		t.Errorf("LifecycleLog/LifecycleTopic = %q/%q; want stderr/syslog/lifecycle",
+			cfg.Pipeline.LifecycleLog, cfg.MQTT.LifecycleTopic)
+	}
+}
+
+func TestLoadPipelineFromEnv_Redact(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_REDACT_FILE", "/etc/syslog-consumer/redact.json")
+	t.Setenv("PIPELINE_REDACT_STRICT", "true")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.RedactFile != "/etc/syslog-consumer/redact.json" || !cfg.RedactStrict {
+		t.Errorf("RedactFile/RedactStrict = %q/%v; want /etc/syslog-consumer/redact.json/true",
+			cfg.RedactFile, cfg.RedactStrict)
+	}
+}
+
+func TestLoadPipelineFromEnv_Statsd(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_STATSD_ADDR", "127.0.0.1:8125")
+	t.Setenv("PIPELINE_STATSD_PREFIX", "syslog")
+	t.Setenv("PIPELINE_STATSD_TAGS", "env:prod, site:mi")
+	t.Setenv("PIPELINE_STATSD_INTERVAL", "30s")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.StatsdAddr != "127.0.0.1:8125" || cfg.StatsdPrefix != "syslog" || cfg.StatsdInterval != 30*time.Second {
+		t.Errorf("StatsdAddr/StatsdPrefix/StatsdInterval = %q/%q/%v; want 127.0.0.1:8125/syslog/30s",
+			cfg.StatsdAddr, cfg.StatsdPrefix, cfg.StatsdInterval)
+	}
+	if !reflect.DeepEqual(cfg.StatsdTags, []string{"env:prod", "site:mi"}) {
+		t.Errorf("StatsdTags = %v; want [env:prod site:mi]", cfg.StatsdTags)
+	}
+}
+
+func TestLoadPipelineFromEnv_AckSLO(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_ACK_SLO_LATENCY", "2s")
+	t.Setenv("PIPELINE_ACK_SLO_PERCENTILE", "99.9")
+	t.Setenv("PIPELINE_ACK_SLO_WINDOW", "30s")
+	t.Setenv("PIPELINE_ACK_SLO_WINDOWS", "5")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.AckSLOLatency != 2*time.Second || cfg.AckSLOPercentile != 99.9 ||
+		cfg.AckSLOWindow != 30*time.Second || cfg.AckSLOWindows != 5 {
+		t.Errorf("AckSLO = %v p%g over %v x%d; want 2s p99.9 over 30s x5",
+			cfg.AckSLOLatency, cfg.AckSLOPercentile, cfg.AckSLOWindow, cfg.AckSLOWindows)
+	}
+
+	t.Setenv("PIPELINE_ACK_SLO_PERCENTILE", "high")
+	cfg = defaultPipelineConfig()
+	loadPipelineFromEnv(&cfg)
+	if cfg.AckSLOPercentile != 99 {
+		t.Errorf("AckSLOPercentile = %g; want the default 99 for an invalid value", cfg.AckSLOPercentile)
+	}
+}
+
+func TestLoadPipelineFromEnv_PublishDedup(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_PUBLISH_DEDUP", "redis")
+	t.Setenv("PIPELINE_PUBLISH_DEDUP_WINDOW", "45s")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.PublishDedup != PublishDedupRedis || cfg.PublishDedupWindow != 45*time.Second {
+		t.Errorf("PublishDedup = %q over %v; want redis over 45s", cfg.PublishDedup, cfg.PublishDedupWindow)
+	}
+}
+
+func TestLoadPipelineFromEnv_Enrich(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	t.Setenv("PIPELINE_ENRICH_KEY", "host")
+	t.Setenv("PIPELINE_ENRICH_HASH_PREFIX", "hosts:")
+	t.Setenv("PIPELINE_ENRICH_FIELDS", "site, rack,owner")
+	t.Setenv("PIPELINE_ENRICH_CACHE_TTL", "30s")
+	t.Setenv("PIPELINE_ENRICH_CACHE_SIZE", "500")
+
+	loadPipelineFromEnv(&cfg)
+
+	if cfg.EnrichKey != "host" || cfg.EnrichHashPrefix != "hosts:" ||
+		!reflect.DeepEqual(cfg.EnrichFields, []string{"site", "rack", "owner"}) {
+		t.Errorf("Enrich = %q in %q* for %v; want host in hosts:* for site, rack, owner",
+			cfg.EnrichKey, cfg.EnrichHashPrefix, cfg.EnrichFields)
+	}
+	if cfg.EnrichCacheTTL != 30*time.Second || cfg.EnrichCacheSize != 500 {
+		t.Errorf("Enrich cache = %v, %d; want 30s, 500", cfg.EnrichCacheTTL, cfg.EnrichCacheSize)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{" , ,", nil},
+		{"raw", []string{"raw"}},
+		{"a, b ,c", []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestGetEnvHelpers(t *testing.T) {
 	t.Run("getEnvString", testGetEnvString)
 	t.Run("getEnvInt", testGetEnvInt)
@@ -266,6 +860,46 @@ func TestLoadCompressFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadRuntimeFromEnv(t *testing.T) {
+	cfg := defaultRuntimeConfig()
+	t.Setenv("RUNTIME_GC_PERCENT", "150")
+	t.Setenv("RUNTIME_MEMORY_LIMIT", "536870912")
+	t.Setenv("RUNTIME_MEMORY_LIMIT_PERCENT", "80")
+
+	loadRuntimeFromEnv(&cfg)
+
+	if want := (RuntimeConfig{GCPercent: 150, MemoryLimit: 512 << 20, MemoryLimitPercent: 80}); cfg != want {
+		t.Errorf("loadRuntimeFromEnv() = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestLoadArchiveFromEnv(t *testing.T) {
+	cfg := defaultArchiveConfig()
+	t.Setenv("ARCHIVE_MODE", "copy")
+	t.Setenv("ARCHIVE_DIR", "/var/lib/archive")
+	t.Setenv("ARCHIVE_MAX_BYTES", "1048576")
+	t.Setenv("ARCHIVE_MAX_AGE", "1m")
+	t.Setenv("ARCHIVE_GZIP", "true")
+	t.Setenv("ARCHIVE_UPLOAD_RETRY", "10s")
+	t.Setenv("ARCHIVE_S3_ENDPOINT", "http://minio:9000")
+	t.Setenv("ARCHIVE_S3_BUCKET", "logs")
+	t.Setenv("ARCHIVE_S3_PREFIX", "syslog/")
+	t.Setenv("ARCHIVE_S3_ACCESS_KEY_ID", "AKID")
+	t.Setenv("ARCHIVE_S3_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("ARCHIVE_S3_PART_BYTES", "8388608")
+
+	loadArchiveFromEnv(&cfg)
+
+	want := ArchiveConfig{
+		Mode: ArchiveCopy, Dir: "/var/lib/archive", S3Endpoint: "http://minio:9000", S3Bucket: "logs",
+		S3Region: "us-east-1", S3Prefix: "syslog/", S3AccessKeyID: "AKID", S3SecretAccessKey: "secret",
+		MaxAge: time.Minute, UploadRetry: 10 * time.Second, MaxBytes: 1 << 20, S3PartBytes: 8 << 20, Gzip: true,
+	}
+	if cfg != want {
+		t.Errorf("loadArchiveFromEnv() = %+v; want %+v", cfg, want)
+	}
+}
+
 func TestLoadCompressFromEnv_PartialOverride(t *testing.T) {
 	cfg := defaultCompressConfig()
 
@@ -6,15 +6,38 @@ import (
 
 // Flags take precedence over environment variables.
 var (
-	flagLogLevel = flag.String("log-level", "", "Log level (trace, debug, info, warn, error, fatal, panic)")
-
-	flagRedisAddress         = flag.String("redis-address", "", "Redis address")
-	flagRedisStream          = flag.String("redis-stream", "", "Redis stream name (empty for multi-stream mode)")
-	flagRedisConsumer        = flag.String("redis-consumer", "", "Redis consumer name")
-	flagRedisGroupName       = flag.String("redis-group-name", "", "Redis consumer group name")
-	flagRedisBatchSize       = flag.Int("redis-batch-size", 0, "Redis batch size")
-	flagRedisBlockTimeout    = flag.Duration("redis-block-timeout", 0, "Redis block timeout")
-	flagRedisClaimIdle       = flag.Duration("redis-claim-idle", 0, "Redis claim idle time")
+	flagAppProfile = flag.String("app-profile", "", "Defaults profile: edge, datacenter or dev")
+
+	flagLogLevel     = flag.String("log-level", "", "Log level (trace, debug, info, warn, error, fatal, panic)")
+	flagLogAsync     = flag.Bool("log-async", false, "Write logs from a background goroutine through a bounded queue")
+	flagLogQueueSize = flag.Int("log-queue-size", 0, "Log records queued under -log-async before dropping")
+
+	flagLogPayloadPreviewBytes = flag.Int(
+		"log-payload-preview-bytes", -1, "Payload bytes shown in log records (0 for the size only)",
+	)
+
+	flagRedisAddress          = flag.String("redis-address", "", "Redis address")
+	flagRedisStream           = flag.String("redis-stream", "", "Redis stream name (empty for multi-stream mode)")
+	flagRedisConsumer         = flag.String("redis-consumer", "", "Redis consumer name")
+	flagRedisGroupName        = flag.String("redis-group-name", "", "Redis consumer group name")
+	flagRedisUsername         = flag.String("redis-username", "", "Redis ACL username")
+	flagRedisPassword         = flag.String("redis-password", "", "Redis password")
+	flagRedisPasswordFile     = flag.String("redis-password-file", "", "File holding the Redis password")
+	flagRedisKeyPrefix        = flag.String("redis-key-prefix", "", "Prefix of every Redis key the consumer names")
+	flagRedisReplicaAddress   = flag.String("redis-replica-address", "", "Redis read replica address for monitoring calls")
+	flagRedisDB               = flag.Int("redis-db", -1, "Redis logical database index")
+	flagRedisDeadLetterStream = flag.String(
+		"redis-dead-letter-stream", "", "Stream receiving entries the pipeline gives up on",
+	)
+	flagRedisReceiptsStream = flag.String(
+		"redis-receipts-stream", "", "Stream receiving publish and ACK receipts (empty disables)",
+	)
+	flagRedisBatchSize     = flag.Int("redis-batch-size", 0, "Redis batch size")
+	flagRedisBlockTimeout  = flag.Duration("redis-block-timeout", 0, "Redis block timeout")
+	flagRedisClaimIdle     = flag.Duration("redis-claim-idle", 0, "Redis claim idle time")
+	flagRedisClaimInterval = flag.Duration(
+		"redis-claim-interval", 0, "Redis claim loop interval (0 uses the claim idle time)",
+	)
 	flagRedisConsumerIdle    = flag.Duration("redis-consumer-idle-timeout", 0, "Redis consumer idle timeout")
 	flagRedisCleanupInterval = flag.Duration("redis-cleanup-interval", 0, "Redis cleanup interval")
 	flagRedisDialTimeout     = flag.Duration("redis-dial-timeout", 0, "Redis dial timeout")
@@ -29,14 +52,92 @@ var (
 		"redis-conn-max-lifetime", -1,
 		"Max lifetime of a pooled connection (0 disables)",
 	)
+	flagRedisOperationTimeout = flag.Duration(
+		"redis-operation-timeout", 0, "Max duration of one Redis call (0 uses read plus write timeout)",
+	)
 	flagRedisPoolSize           = flag.Int("redis-pool-size", 0, "Redis connection pool size")
 	flagRedisMinIdleConns       = flag.Int("redis-min-idle-conns", 0, "Redis minimum idle connections")
 	flagRedisDiscoveryScanCount = flag.Int("redis-discovery-scan-count", 0, "Redis SCAN count hint for stream discovery")
+	flagRedisClaimConcurrency   = flag.Int("redis-claim-concurrency", 0, "Streams claimed in parallel per cycle")
+	flagRedisClaimCoordination  = flag.Bool(
+		"redis-claim-coordination", false, "Take turns with the other instances of the group to run the claim cycle",
+	)
+	flagRedisNoAck               = flag.Bool("redis-noack", false, "Read with NOACK (at-most-once, no claims)")
+	flagRedisBackfill            = flag.Bool("redis-backfill", false, "Replay pending entries before reading new ones")
+	flagRedisBackfillRate        = flag.Int("redis-backfill-rate", 0, "Backfill entries per second (0 = unlimited)")
+	flagRedisBackfillStrictOrder = flag.Bool(
+		"redis-backfill-strict-order", false, "Publish backfilled batches one at a time, in entry ID order",
+	)
+	flagRedisBackfillPolicy = flag.String(
+		"redis-backfill-policy", "", "Backfill order: drain-first, claim-first, interleaved:<backlog>:<fresh> or fresh-only",
+	)
+	flagRedisStreamLease = flag.Bool(
+		"redis-stream-lease", false, "Read and claim only the streams whose lease this instance holds (active/standby)",
+	)
+	flagRedisStreamLeaseTTL = flag.Duration(
+		"redis-stream-lease-ttl", 0, "How long a stream lease lasts unrenewed",
+	)
+	flagRedisIdleStreamAfter = flag.Duration(
+		"redis-idle-stream-after", 0, "Poll a stream instead of blocking on it after it is idle this long (0 disables)",
+	)
+	flagRedisIdleStreamPoll = flag.Duration(
+		"redis-idle-stream-poll", 0, "How often the idle streams are polled",
+	)
+	flagRedisDedup         = flag.String("redis-dedup", "", "Drop entries already read: keys or bloom (empty disables)")
+	flagRedisDedupWindow   = flag.Duration("redis-dedup-window", 0, "How long an entry's content counts as seen")
+	flagRedisDedupCapacity = flag.Int("redis-dedup-capacity", 0, "Bloom filter capacity per dedup window")
+	flagRedisAdaptiveBatch = flag.Bool(
+		"redis-adaptive-batch", false, "Size reads between the batch size min and max from fill ratio and publish latency",
+	)
+	flagRedisBatchSizeMin       = flag.Int("redis-batch-size-min", 0, "Smallest adaptive read batch size")
+	flagRedisBatchSizeMax       = flag.Int("redis-batch-size-max", 0, "Largest adaptive read batch size")
+	flagRedisBatchLatencyTarget = flag.Duration(
+		"redis-batch-latency-target", 0, "Batch publish time over which the adaptive batch size halves",
+	)
+	flagRedisMemoryHighPercent = flag.Int(
+		"redis-memory-high-percent", 0, "Share of maxmemory at which Redis is under memory pressure (0 disables)",
+	)
+	flagRedisMemoryLowPercent = flag.Int(
+		"redis-memory-low-percent", 0, "Share of maxmemory under which memory pressure clears",
+	)
+	flagRedisMemoryCheckInterval = flag.Duration("redis-memory-check-interval", 0, "How often Redis memory is checked")
+	flagRedisMemoryPressureKey   = flag.String(
+		"redis-memory-pressure-key", "", "Redis key kept set while Redis is under memory pressure",
+	)
+	flagRedisControlKey = flag.String(
+		"redis-control-key", "", "Redis hash of runtime settings: log_level, claim_interval and paused",
+	)
+	flagRedisControlInterval = flag.Duration("redis-control-interval", 0, "How often the Redis control hash is read")
+	flagRedisLagInterval     = flag.Duration("redis-lag-interval", 0, "How often the consumer group lag is read")
+	flagRedisLagHigh         = flag.Int(
+		"redis-lag-high", 0, "Consumer group lag at which reads drain at the largest batch size (0 disables)",
+	)
+	flagRedisCompactInterval = flag.Duration(
+		"redis-compact-interval", 0, "How often acknowledged entries are compacted out of the streams (0 disables)",
+	)
+	flagRedisCompactMinAge    = flag.Duration("redis-compact-min-age", 0, "Age under which entries are never compacted")
+	flagRedisCompactBatchSize = flag.Int("redis-compact-batch-size", 0, "Entries scanned and deleted per compaction step")
+	flagRedisCompactDryRun    = flag.Bool("redis-compact-dry-run", false, "Count the entries compaction would delete")
+	flagRedisStreamInclude    = flag.String(
+		"redis-stream-include", "", "Comma-separated stream globs to consume in multi-stream mode",
+	)
+	flagRedisStreamExclude = flag.String(
+		"redis-stream-exclude", "", "Comma-separated stream globs to skip in multi-stream mode",
+	)
+	flagRedisReadFields = flag.String(
+		"redis-read-fields", "", "Comma-separated entry fields kept on read (empty keeps all)",
+	)
+	flagRedisStreamOverridesFile = flag.String(
+		"redis-stream-overrides-file", "", "JSON file of per-stream overrides keyed by stream pattern",
+	)
 
 	flagMQTTBroker               = flag.String("mqtt-broker", "", "MQTT broker URL")
 	flagMQTTClientID             = flag.String("mqtt-client-id", "", "MQTT client ID")
 	flagMQTTPublishTopic         = flag.String("mqtt-publish-topic", "", "MQTT publish topic")
 	flagMQTTAckTopic             = flag.String("mqtt-ack-topic", "", "MQTT ACK topic")
+	flagMQTTControlTopic         = flag.String("mqtt-control-topic", "", "MQTT topic of retained control messages")
+	flagMQTTStatusTopic          = flag.String("mqtt-status-topic", "", "MQTT topic prefix of retained connection status")
+	flagMQTTOverrideTopics       = flag.String("mqtt-override-topics", "", "Topic filters an entry's _topic may name")
 	flagMQTTQoS                  = flag.Int("mqtt-qos", -1, "MQTT QoS (0, 1, or 2)")
 	flagMQTTConnectTimeout       = flag.Duration("mqtt-connect-timeout", 0, "MQTT connect timeout")
 	flagMQTTWriteTimeout         = flag.Duration("mqtt-write-timeout", 0, "MQTT write timeout")
@@ -53,18 +154,74 @@ var (
 	flagMQTTKeepAlive            = flag.Duration("mqtt-keep-alive", 0, "MQTT keep-alive interval")
 	flagMQTTPingTimeout          = flag.Duration("mqtt-ping-timeout", 0, "MQTT ping response timeout")
 	flagMQTTConnectRetryDelay    = flag.Duration("mqtt-connect-retry-delay", 0, "MQTT connect retry delay")
+	flagMQTTConnectAttempts      = flag.Int("mqtt-connect-attempts", 0, "Failed MQTT connects before startup fails")
 	flagMQTTMessageChannelDepth  = flag.Int("mqtt-message-channel-depth", 0, "MQTT internal message queue depth")
 	flagMQTTMaxResumePubInFlight = flag.Int("mqtt-max-resume-pub-in-flight", 0, "MQTT max resumed unacked publishes")
+	flagMQTTPersistentSession    = flag.Bool("mqtt-persistent-session", false, "Connect with CleanSession=false")
+	flagMQTTStoreDir             = flag.String("mqtt-store-dir", "", "Directory for the file-backed inflight store")
+	flagMQTTStoreMaxMessages     = flag.Int("mqtt-store-max-messages", 0, "Max packets persisted per connection")
+	flagMQTTQuarantineFailures   = flag.Int(
+		"mqtt-quarantine-failures", 0, "Consecutive failed publishes before a pool connection is quarantined",
+	)
+	flagMQTTMaxInflight = flag.Int(
+		"mqtt-max-inflight", 0, "QoS 1/2 publishes awaiting the broker per connection (0 waits for each)",
+	)
+	flagMQTTRTTSampleEvery = flag.Int(
+		"mqtt-rtt-sample-every", 0, "Time the broker acknowledgement of every Nth QoS 1/2 publish (0 disables)",
+	)
+	flagMQTTRTTWindow    = flag.Duration("mqtt-rtt-window", 0, "Window the broker RTT percentiles cover")
+	flagMQTTFanoutTopics = flag.String(
+		"mqtt-fanout-topics", "", "Comma-separated topic[=json|raw] items every batch is also published to",
+	)
+	flagMQTTFanoutMode    = flag.String("mqtt-fanout-mode", "", "Fanout delivery mode: best-effort or all")
+	flagMQTTSampleTopic   = flag.String("mqtt-sample-topic", "", "MQTT topic a sample of the entries is also sent to")
+	flagMQTTSampleFields  = flag.String("mqtt-sample-fields", "", "Comma-separated fields kept in sampled entries")
+	flagMQTTSamplePercent = flag.Int("mqtt-sample-percent", 0, "Percentage of entries sent to the sample topic")
+	flagMQTTSampleEvery   = flag.Int("mqtt-sample-every", 0, "Send every Nth entry to the sample topic")
+	flagMQTTOriginMarker  = flag.Bool("mqtt-origin-marker", false, "Start published payloads with an origin frame")
+	flagMQTTAckHMACKey    = flag.String("mqtt-ack-hmac-key", "", "Key ACKs must be signed with (HMAC-SHA256)")
+
+	flagMQTTAckFlowControl = flag.Bool(
+		"mqtt-ack-flow-control", false, "Honor the flow-control messages published on the ACK topic",
+	)
 
 	flagCompressFreelistSize       = flag.Int("compress-freelist-size", 0, "Decoder freelist channel capacity")
 	flagCompressMaxDecompressBytes = flag.Int("max-decompress-bytes", 0, "Max decompressed payload size in bytes")
 	flagCompressWarmupCount        = flag.Int("compress-warmup-count", 0, "Decoders pre-created at init")
 
+	flagRuntimeGCPercent          = flag.Int("runtime-gc-percent", 0, "GOGC of the garbage collector")
+	flagRuntimeMemoryLimit        = flag.Int("runtime-memory-limit", 0, "Go runtime soft memory limit in bytes")
+	flagRuntimeMemoryLimitPercent = flag.Int(
+		"runtime-memory-limit-percent", 0, "Share of the cgroup memory limit used as the soft memory limit",
+	)
+
+	flagArchiveMode        = flag.String("archive-mode", "", "Archive sink: copy (besides MQTT) or only (instead of MQTT)")
+	flagArchiveDir         = flag.String("archive-dir", "", "Directory the NDJSON archive files are written to")
+	flagArchiveMaxBytes    = flag.Int("archive-max-bytes", 0, "Record bytes after which an archive file is rotated")
+	flagArchiveMaxAge      = flag.Duration("archive-max-age", 0, "Age after which an archive file is rotated")
+	flagArchiveGzip        = flag.Bool("archive-gzip", false, "Gzip the archive files")
+	flagArchiveUploadRetry = flag.Duration("archive-upload-retry", 0, "Interval between retries of failed uploads")
+	flagArchiveS3Endpoint  = flag.String("archive-s3-endpoint", "", "S3-compatible endpoint URL archive files go to")
+	flagArchiveS3Bucket    = flag.String("archive-s3-bucket", "", "Bucket archive files are uploaded to")
+	flagArchiveS3Region    = flag.String("archive-s3-region", "", "Region of the archive bucket")
+	flagArchiveS3Prefix    = flag.String("archive-s3-prefix", "", "Key prefix of the uploaded archive files")
+	flagArchiveS3AccessKey = flag.String("archive-s3-access-key-id", "", "Access key ID of the archive bucket")
+	flagArchiveS3SecretKey = flag.String("archive-s3-secret-access-key", "", "Secret access key of the archive bucket")
+	flagArchiveS3PartBytes = flag.Int("archive-s3-part-bytes", 0, "Size of the multipart upload parts")
+
 	flagPipelineBufferCapacity  = flag.Int("pipeline-buffer-capacity", 0, "Pipeline buffer capacity")
 	flagPipelineShutdownTimeout = flag.Duration("pipeline-shutdown-timeout", 0, "Pipeline shutdown timeout")
 	flagPipelineErrorBackoff    = flag.Duration("pipeline-error-backoff", 0, "Pipeline error backoff")
-	flagPipelineAckTimeout      = flag.Duration("pipeline-ack-timeout", 0, "Pipeline ACK timeout")
-	flagPipelinePublishWorkers  = flag.Int(
+	flagPipelineDrainTimeout    = flag.Duration("pipeline-drain-timeout", 0, "Max time to publish queued batches on stop")
+	flagPipelineAckDrainTimeout = flag.Duration("pipeline-ack-drain-timeout", 0, "Max time to flush queued ACKs on stop")
+	flagPipelinePublishTimeout  = flag.Duration(
+		"pipeline-publish-timeout", 0, "Max time for one publish before its entries are left to the claim loop (0 disables)",
+	)
+	flagPipelineCaptureSize = flag.Int(
+		"pipeline-capture-size", 0, "Published payloads and ACKs kept for GET /debug/capture and SIGUSR1 (0 disables)",
+	)
+	flagPipelineAckTimeout     = flag.Duration("pipeline-ack-timeout", 0, "Pipeline ACK timeout")
+	flagPipelinePublishWorkers = flag.Int(
 		"pipeline-publish-workers", 0, "Number of concurrent publish workers",
 	)
 	flagPipelineRefreshInterval = flag.Duration(
@@ -73,6 +230,15 @@ var (
 	flagPipelineHealthAddr = flag.String(
 		"pipeline-health-addr", "", "Health/metrics HTTP address (e.g. :9980)",
 	)
+	flagPipelineHealthTLSCert = flag.String(
+		"pipeline-health-tls-cert", "", "Certificate to serve the health endpoint over TLS",
+	)
+	flagPipelineHealthTLSKey = flag.String(
+		"pipeline-health-tls-key", "", "Private key of the health TLS certificate",
+	)
+	flagPipelineHealthClientCA = flag.String(
+		"pipeline-health-client-ca", "", "CA bundle health clients must present a certificate from",
+	)
 	flagPipelineAckFlushInterval = flag.Duration(
 		"pipeline-ack-flush-interval", 0, "ACK batch flush interval",
 	)
@@ -91,19 +257,325 @@ var (
 	flagPipelineHealthReadHeaderTimeout = flag.Duration(
 		"pipeline-health-read-header-timeout", 0, "Health server ReadHeaderTimeout",
 	)
+	flagPipelineOrderedPublish = flag.Bool(
+		"pipeline-ordered-publish", false, "Pin each ordering key to one publish worker and connection",
+	)
+	flagPipelineOrderingKey = flag.String(
+		"pipeline-ordering-key", "", "Payload field used as the ordering key instead of the stream name",
+	)
+	flagPipelineAdminDLQReplay = flag.Bool(
+		"pipeline-admin-dlq-replay", false, "Serve POST /admin/dlq/replay on the health address",
+	)
+	flagPipelineAdminStreams = flag.Bool(
+		"pipeline-admin-streams", false, "Serve POST /admin/streams/attach and /admin/streams/detach on the health address",
+	)
+	flagPipelineAdminBuffer = flag.Bool(
+		"pipeline-admin-buffer", false, "Serve POST /admin/buffer, which resizes the ACK queues, on the health address",
+	)
+	flagPipelineFieldAllowlist = flag.String(
+		"pipeline-field-allowlist", "", "Comma-separated payload fields to keep (all others dropped)",
+	)
+	flagPipelineFieldDenylist = flag.String(
+		"pipeline-field-denylist", "", "Comma-separated payload fields to drop",
+	)
+	flagPipelineStructuredData = flag.String(
+		"pipeline-structured-data", "", "How structured_data is flattened: leaves or prefixed (sd.<id>.<param>)",
+	)
+	flagPipelineInvalidObject = flag.String(
+		"pipeline-invalid-object", "", "What happens to entries whose object is not valid JSON: pass, strict or lenient",
+	)
+	flagPipelineNackPolicies = flag.String(
+		"pipeline-nack-policies", "", "Comma-separated reason=action NACK policies (retry, dlq, backoff:<duration>)",
+	)
+	flagPipelineNackFloodThreshold = flag.Int(
+		"pipeline-nack-flood-threshold", 0, "NACKed entries per stream within the window that quarantine it (0 disables)",
+	)
+	flagPipelineNackFloodWindow = flag.Duration(
+		"pipeline-nack-flood-window", 0, "Window NACKed entries are counted over per stream",
+	)
+	flagPipelineNackFloodCooldown = flag.Duration(
+		"pipeline-nack-flood-cooldown", 0, "How long a quarantined stream's entries are not redelivered",
+	)
+	flagPipelineMaxAgePolicies = flag.String(
+		"pipeline-max-age-policies", "", "Comma-separated stream-glob=action:<duration> max-age policies (drop, dlq)",
+	)
+	flagPipelineStreamQuotas = flag.String(
+		"pipeline-stream-quotas", "", "Comma-separated stream-glob=action:<messages>:<bytes> per-second quotas (delay, skip)",
+	)
+	flagPipelineThroughputSchedule = flag.String(
+		"pipeline-throughput-schedule", "", "Comma-separated '<days> <HH:MM>-<HH:MM>=<entries/s>' publish caps",
+	)
+	flagPipelineThroughputLocation = flag.String(
+		"pipeline-throughput-location", "", "Time zone the throughput schedule is read in",
+	)
+	flagPipelineThroughputRamp = flag.Duration(
+		"pipeline-throughput-ramp", -1, "Time a change of throughput cap is spread over (0 switches at once)",
+	)
+	flagPipelinePublishStallFactor = flag.Int(
+		"pipeline-publish-stall-factor", 0, "Replace publish workers stuck for this many MQTT write timeouts (0 disables)",
+	)
+	flagPipelineMaxPayloadBytes = flag.Int(
+		"pipeline-max-payload-bytes", 0, "Max bytes per published record and MQTT payload (0 disables)",
+	)
+	flagPipelineOversizePolicy = flag.String(
+		"pipeline-oversize-policy", "", "Oversize record policy: truncate, split, or dlq",
+	)
+	flagPipelineMemoryBudget = flag.Int(
+		"pipeline-memory-budget", 0, "Max bytes of entry bodies held in the publish queues (0 disables spilling)",
+	)
+	flagPipelineSpillMinBytes = flag.Int(
+		"pipeline-spill-min-bytes", 0, "Smallest entry body spilled to the arena",
+	)
+	flagPipelineSpillArenaBytes = flag.Int(
+		"pipeline-spill-arena-bytes", 0, "Size of the mmap spill arena",
+	)
+	flagPipelineSpillDir = flag.String(
+		"pipeline-spill-dir", "", "Directory of the spill arena file",
+	)
+	flagPipelineRetryQueueCapacity = flag.Int(
+		"pipeline-retry-queue-capacity", 0, "Batches held by the retry queue of claimed entries (0 uses the main queue)",
+	)
+	flagPipelineRetryRate = flag.Int(
+		"pipeline-retry-rate", 0, "Max retried entries handed to the publish workers per second (0 unlimited)",
+	)
+	flagPipelineRetryPriority = flag.String(
+		"pipeline-retry-priority", "", "Whether retried batches are published after (low) or before (high) fetched ones",
+	)
+	flagPipelineStatsdAddr = flag.String(
+		"pipeline-statsd-addr", "", "StatsD/DogStatsD UDP address metrics are sent to (empty disables it)",
+	)
+	flagPipelineStatsdPrefix = flag.String(
+		"pipeline-statsd-prefix", "", "Prefix of the metric names sent to StatsD",
+	)
+	flagPipelineStatsdTags = flag.String(
+		"pipeline-statsd-tags", "", "Comma-separated key:value tags added to every StatsD metric",
+	)
+	flagPipelineStatsdInterval = flag.Duration(
+		"pipeline-statsd-interval", 0, "Interval between StatsD flushes",
+	)
+	flagPipelineMetricsStatePath = flag.String(
+		"pipeline-metrics-state-path", "", "File the counters are saved to and restored from across restarts",
+	)
+	flagPipelineMetricsStateInterval = flag.Duration(
+		"pipeline-metrics-state-interval", 0, "Interval between saves of the counters",
+	)
+	flagPipelineLifecycleLog = flag.String(
+		"pipeline-lifecycle-log", "", "Where lifecycle events are written as JSON lines: stderr or a file path",
+	)
+	flagPipelineRedactFile = flag.String(
+		"pipeline-redact-file", "", "JSON file of the rules masking sensitive content before publishing",
+	)
+	flagPipelineRedactStrict = flag.Bool(
+		"pipeline-redact-strict", false, "Dead-letter the entries that cannot be redacted",
+	)
+	flagMQTTLifecycleTopic = flag.String(
+		"mqtt-lifecycle-topic", "", "MQTT topic lifecycle events are published to",
+	)
+	flagPipelineAckSLOLatency = flag.Duration(
+		"pipeline-ack-slo-latency", 0, "Ack latency objective per stream (0 disables SLO tracking)",
+	)
+	flagPipelineAckSLOPercentile = flag.Float64(
+		"pipeline-ack-slo-percentile", 0, "Percentile of the ack latency held to the objective",
+	)
+	flagPipelineAckSLOWindow = flag.Duration(
+		"pipeline-ack-slo-window", 0, "Window the ack latency percentile is computed over",
+	)
+	flagPipelineAckSLOWindows = flag.Int(
+		"pipeline-ack-slo-windows", 0, "Consecutive windows over the objective that raise an alert",
+	)
+	flagPipelinePublishDedup = flag.String(
+		"pipeline-publish-dedup", "", "Suppress duplicate publishes by entry ID: memory or redis (empty disables)",
+	)
+	flagPipelinePublishDedupWindow = flag.Duration(
+		"pipeline-publish-dedup-window", 0, "How long a published entry ID stays reserved",
+	)
+	flagPipelineEnrichKey = flag.String(
+		"pipeline-enrich-key", "", "Payload field looked up in Redis hashes to enrich the payload (empty disables)",
+	)
+	flagPipelineEnrichHashPrefix = flag.String(
+		"pipeline-enrich-hash-prefix", "", "Prefix of the Redis hash names the enrich key value is appended to",
+	)
+	flagPipelineEnrichFields = flag.String(
+		"pipeline-enrich-fields", "", "Comma-separated hash fields added to the payload",
+	)
+	flagPipelineEnrichCacheTTL = flag.Duration(
+		"pipeline-enrich-cache-ttl", 0, "How long an enrichment lookup is cached",
+	)
+	flagPipelineEnrichCacheSize = flag.Int(
+		"pipeline-enrich-cache-size", 0, "Maximum number of enrichment lookups cached",
+	)
+	flagPipelineSnapshotPath = flag.String(
+		"pipeline-snapshot-path", "", "File the state snapshot is saved to and restored from (empty disables it)",
+	)
+	flagPipelineReceiptQueueCapacity = flag.Int(
+		"pipeline-receipt-queue-capacity", 0, "Receipts buffered before new ones are dropped",
+	)
+	flagPipelineReceiptBatchSize = flag.Int(
+		"pipeline-receipt-batch-size", 0, "Receipts written per Redis pipeline",
+	)
+	flagPipelineReceiptFlushInterval = flag.Duration(
+		"pipeline-receipt-flush-interval", 0, "Max time a receipt waits before it is written",
+	)
+	flagPipelineTimestampField = flag.String(
+		"pipeline-timestamp-field", "", "Payload field normalized to UTC RFC3339 (empty disables)",
+	)
+	flagPipelineTimestampLayouts = flag.String(
+		"pipeline-timestamp-layouts", "", "'|'-separated Go time layouts, unix or unix_ms, tried in order",
+	)
+	flagPipelineTimestampLocation = flag.String(
+		"pipeline-timestamp-location", "", "Time zone for timestamps without an offset",
+	)
+	flagPipelineTimestampMaxSkew = flag.Duration(
+		"pipeline-timestamp-max-skew", 0, "Clock skew beyond which entries are flagged",
+	)
 )
 
 func applyLogFlags(cfg *LogConfig) {
 	if *flagLogLevel != "" {
 		cfg.Level = *flagLogLevel
 	}
+	if isFlagSet("log-async") {
+		cfg.Async = *flagLogAsync
+	}
+	if *flagLogQueueSize != 0 {
+		cfg.QueueSize = *flagLogQueueSize
+	}
+	if *flagLogPayloadPreviewBytes >= 0 {
+		cfg.PayloadPreviewBytes = *flagLogPayloadPreviewBytes
+	}
 }
 
 func applyRedisFlags(cfg *RedisConfig) {
 	applyRedisFlagStrings(cfg)
+	applyRedisFlagReplica(cfg)
 	applyRedisFlagInts(cfg)
 	applyRedisFlagTimeouts(cfg)
 	applyRedisFlagPoolLifecycle(cfg)
+	applyRedisFlagAdaptiveBatch(cfg)
+	applyRedisFlagMemoryMonitor(cfg)
+	applyRedisFlagCompaction(cfg)
+	applyRedisFlagControl(cfg)
+	applyRedisFlagLag(cfg)
+	applyRedisFlagBackfill(cfg)
+	applyRedisFlagStreamLease(cfg)
+	applyRedisFlagIdleStreams(cfg)
+	if v := splitList(*flagRedisStreamInclude); v != nil {
+		cfg.StreamInclude = v
+	}
+	if v := splitList(*flagRedisStreamExclude); v != nil {
+		cfg.StreamExclude = v
+	}
+	if v := splitList(*flagRedisReadFields); v != nil {
+		cfg.ReadFields = v
+	}
+	if *flagRedisStreamOverridesFile != "" {
+		cfg.StreamOverridesFile = *flagRedisStreamOverridesFile
+	}
+	if *flagRedisPasswordFile != "" {
+		cfg.PasswordFile = *flagRedisPasswordFile
+	}
+	if *flagRedisKeyPrefix != "" {
+		cfg.KeyPrefix = *flagRedisKeyPrefix
+	}
+	if isFlagSet("redis-claim-coordination") {
+		cfg.ClaimCoordination = *flagRedisClaimCoordination
+	}
+	if isFlagSet("redis-noack") {
+		cfg.NoAck = *flagRedisNoAck
+	}
+}
+
+func applyRedisFlagBackfill(cfg *RedisConfig) {
+	if isFlagSet("redis-backfill") {
+		cfg.Backfill = *flagRedisBackfill
+	}
+	if isFlagSet("redis-backfill-strict-order") {
+		cfg.BackfillStrictOrder = *flagRedisBackfillStrictOrder
+	}
+	if *flagRedisBackfillPolicy != "" {
+		cfg.BackfillPolicy = *flagRedisBackfillPolicy
+	}
+}
+
+func applyRedisFlagStreamLease(cfg *RedisConfig) {
+	if isFlagSet("redis-stream-lease") {
+		cfg.StreamLease = *flagRedisStreamLease
+	}
+	if *flagRedisStreamLeaseTTL != 0 {
+		cfg.StreamLeaseTTL = *flagRedisStreamLeaseTTL
+	}
+}
+
+func applyRedisFlagIdleStreams(cfg *RedisConfig) {
+	if *flagRedisIdleStreamAfter != 0 {
+		cfg.IdleStreamAfter = *flagRedisIdleStreamAfter
+	}
+	if *flagRedisIdleStreamPoll != 0 {
+		cfg.IdleStreamPoll = *flagRedisIdleStreamPoll
+	}
+}
+
+func applyRedisFlagAdaptiveBatch(cfg *RedisConfig) {
+	if isFlagSet("redis-adaptive-batch") {
+		cfg.AdaptiveBatch = *flagRedisAdaptiveBatch
+	}
+	if *flagRedisBatchSizeMin != 0 {
+		cfg.BatchSizeMin = *flagRedisBatchSizeMin
+	}
+	if *flagRedisBatchSizeMax != 0 {
+		cfg.BatchSizeMax = *flagRedisBatchSizeMax
+	}
+	if *flagRedisBatchLatencyTarget != 0 {
+		cfg.BatchLatencyTarget = *flagRedisBatchLatencyTarget
+	}
+}
+
+func applyRedisFlagMemoryMonitor(cfg *RedisConfig) {
+	if *flagRedisMemoryHighPercent != 0 {
+		cfg.MemoryHighPercent = *flagRedisMemoryHighPercent
+	}
+	if *flagRedisMemoryLowPercent != 0 {
+		cfg.MemoryLowPercent = *flagRedisMemoryLowPercent
+	}
+	if *flagRedisMemoryCheckInterval != 0 {
+		cfg.MemoryCheckInterval = *flagRedisMemoryCheckInterval
+	}
+	if *flagRedisMemoryPressureKey != "" {
+		cfg.MemoryPressureKey = *flagRedisMemoryPressureKey
+	}
+}
+
+func applyRedisFlagControl(cfg *RedisConfig) {
+	if *flagRedisControlKey != "" {
+		cfg.ControlKey = *flagRedisControlKey
+	}
+	if *flagRedisControlInterval != 0 {
+		cfg.ControlInterval = *flagRedisControlInterval
+	}
+}
+
+func applyRedisFlagLag(cfg *RedisConfig) {
+	if *flagRedisLagInterval != 0 {
+		cfg.LagInterval = *flagRedisLagInterval
+	}
+	if *flagRedisLagHigh != 0 {
+		cfg.LagHigh = *flagRedisLagHigh
+	}
+}
+
+func applyRedisFlagCompaction(cfg *RedisConfig) {
+	if *flagRedisCompactInterval != 0 {
+		cfg.CompactInterval = *flagRedisCompactInterval
+	}
+	if *flagRedisCompactMinAge != 0 {
+		cfg.CompactMinAge = *flagRedisCompactMinAge
+	}
+	if *flagRedisCompactBatchSize != 0 {
+		cfg.CompactBatchSize = *flagRedisCompactBatchSize
+	}
+	if isFlagSet("redis-compact-dry-run") {
+		cfg.CompactDryRun = *flagRedisCompactDryRun
+	}
 }
 
 func applyRedisFlagStrings(cfg *RedisConfig) {
@@ -119,6 +591,27 @@ func applyRedisFlagStrings(cfg *RedisConfig) {
 	if *flagRedisGroupName != "" {
 		cfg.GroupName = *flagRedisGroupName
 	}
+	if *flagRedisUsername != "" {
+		cfg.Username = *flagRedisUsername
+	}
+	if *flagRedisPassword != "" {
+		cfg.Password = *flagRedisPassword
+	}
+	if *flagRedisDeadLetterStream != "" {
+		cfg.DeadLetterStream = *flagRedisDeadLetterStream
+	}
+	if *flagRedisReceiptsStream != "" {
+		cfg.ReceiptsStream = *flagRedisReceiptsStream
+	}
+	if *flagRedisDedup != "" {
+		cfg.Dedup = *flagRedisDedup
+	}
+}
+
+func applyRedisFlagReplica(cfg *RedisConfig) {
+	if *flagRedisReplicaAddress != "" {
+		cfg.ReplicaAddress = *flagRedisReplicaAddress
+	}
 }
 
 func applyRedisFlagInts(cfg *RedisConfig) {
@@ -134,6 +627,19 @@ func applyRedisFlagInts(cfg *RedisConfig) {
 	if *flagRedisDiscoveryScanCount != 0 {
 		cfg.DiscoveryScanCount = *flagRedisDiscoveryScanCount
 	}
+	if *flagRedisClaimConcurrency != 0 {
+		cfg.ClaimConcurrency = *flagRedisClaimConcurrency
+	}
+	if *flagRedisBackfillRate != 0 {
+		cfg.BackfillRate = *flagRedisBackfillRate
+	}
+	if *flagRedisDedupCapacity != 0 {
+		cfg.DedupCapacity = *flagRedisDedupCapacity
+	}
+	// -1 means "not set" so -redis-db=0 can override REDIS_DB.
+	if *flagRedisDB >= 0 {
+		cfg.DB = *flagRedisDB
+	}
 }
 
 func applyRedisFlagTimeouts(cfg *RedisConfig) {
@@ -143,12 +649,22 @@ func applyRedisFlagTimeouts(cfg *RedisConfig) {
 	if *flagRedisClaimIdle != 0 {
 		cfg.ClaimIdle = *flagRedisClaimIdle
 	}
+	if *flagRedisClaimInterval != 0 {
+		cfg.ClaimInterval = *flagRedisClaimInterval
+	}
 	if *flagRedisConsumerIdle != 0 {
 		cfg.ConsumerIdleTimeout = *flagRedisConsumerIdle
 	}
 	if *flagRedisCleanupInterval != 0 {
 		cfg.CleanupInterval = *flagRedisCleanupInterval
 	}
+	if *flagRedisDedupWindow != 0 {
+		cfg.DedupWindow = *flagRedisDedupWindow
+	}
+	applyRedisFlagCallTimeouts(cfg)
+}
+
+func applyRedisFlagCallTimeouts(cfg *RedisConfig) {
 	if *flagRedisDialTimeout != 0 {
 		cfg.DialTimeout = *flagRedisDialTimeout
 	}
@@ -161,6 +677,9 @@ func applyRedisFlagTimeouts(cfg *RedisConfig) {
 	if *flagRedisPingTimeout != 0 {
 		cfg.PingTimeout = *flagRedisPingTimeout
 	}
+	if *flagRedisOperationTimeout != 0 {
+		cfg.OperationTimeout = *flagRedisOperationTimeout
+	}
 }
 
 // applyRedisFlagPoolLifecycle uses -1 as "not set" so that 0 can still be a
@@ -180,6 +699,10 @@ func applyMQTTFlags(cfg *MQTTConfig) {
 	applyMQTTFlagTimeouts(cfg)
 	applyMQTTFlagTLS(cfg)
 	applyMQTTFlagBools(cfg)
+	applyMQTTFlagSession(cfg)
+	if *flagMQTTLifecycleTopic != "" {
+		cfg.LifecycleTopic = *flagMQTTLifecycleTopic
+	}
 }
 
 func applyMQTTFlagStrings(cfg *MQTTConfig) {
@@ -195,6 +718,37 @@ func applyMQTTFlagStrings(cfg *MQTTConfig) {
 	if *flagMQTTAckTopic != "" {
 		cfg.AckTopic = *flagMQTTAckTopic
 	}
+	if *flagMQTTControlTopic != "" {
+		cfg.ControlTopic = *flagMQTTControlTopic
+	}
+	if *flagMQTTStatusTopic != "" {
+		cfg.StatusTopic = *flagMQTTStatusTopic
+	}
+	if v := splitList(*flagMQTTOverrideTopics); v != nil {
+		cfg.OverrideTopics = v
+	}
+	if v := splitList(*flagMQTTFanoutTopics); v != nil {
+		cfg.FanoutTopics = v
+	}
+	if *flagMQTTFanoutMode != "" {
+		cfg.FanoutMode = *flagMQTTFanoutMode
+	}
+	applyMQTTFlagSample(cfg)
+}
+
+func applyMQTTFlagSample(cfg *MQTTConfig) {
+	if *flagMQTTSampleTopic != "" {
+		cfg.SampleTopic = *flagMQTTSampleTopic
+	}
+	if v := splitList(*flagMQTTSampleFields); v != nil {
+		cfg.SampleFields = v
+	}
+	if *flagMQTTSamplePercent != 0 {
+		cfg.SamplePercent = *flagMQTTSamplePercent
+	}
+	if *flagMQTTSampleEvery != 0 {
+		cfg.SampleEvery = *flagMQTTSampleEvery
+	}
 }
 
 func applyMQTTFlagInts(cfg *MQTTConfig) {
@@ -213,6 +767,22 @@ func applyMQTTFlagInts(cfg *MQTTConfig) {
 	if *flagMQTTMaxResumePubInFlight != 0 {
 		cfg.MaxResumePubInFlight = *flagMQTTMaxResumePubInFlight
 	}
+	if *flagMQTTQuarantineFailures != 0 {
+		cfg.QuarantineFailures = *flagMQTTQuarantineFailures
+	}
+	if *flagMQTTMaxInflight != 0 {
+		cfg.MaxInflight = *flagMQTTMaxInflight
+	}
+	applyMQTTFlagRTT(cfg)
+}
+
+func applyMQTTFlagRTT(cfg *MQTTConfig) {
+	if *flagMQTTRTTSampleEvery != 0 {
+		cfg.RTTSampleEvery = *flagMQTTRTTSampleEvery
+	}
+	if *flagMQTTRTTWindow != 0 {
+		cfg.RTTWindow = *flagMQTTRTTWindow
+	}
 }
 
 func applyMQTTFlagTimeouts(cfg *MQTTConfig) {
@@ -237,6 +807,9 @@ func applyMQTTFlagTimeouts(cfg *MQTTConfig) {
 	if *flagMQTTConnectRetryDelay != 0 {
 		cfg.ConnectRetryDelay = *flagMQTTConnectRetryDelay
 	}
+	if *flagMQTTConnectAttempts != 0 {
+		cfg.ConnectAttempts = *flagMQTTConnectAttempts
+	}
 }
 
 func applyMQTTFlagTLS(cfg *MQTTConfig) {
@@ -249,6 +822,9 @@ func applyMQTTFlagTLS(cfg *MQTTConfig) {
 	if *flagMQTTClientKey != "" {
 		cfg.ClientKey = *flagMQTTClientKey
 	}
+	if *flagMQTTAckHMACKey != "" {
+		cfg.AckHMACKey = *flagMQTTAckHMACKey
+	}
 }
 
 func applyMQTTFlagBools(cfg *MQTTConfig) {
@@ -261,6 +837,24 @@ func applyMQTTFlagBools(cfg *MQTTConfig) {
 	if isFlagSet("mqtt-use-cert-cn-prefix") {
 		cfg.UseCertCNPrefix = *flagMQTTUseCertCNPrefix
 	}
+	if isFlagSet("mqtt-origin-marker") {
+		cfg.OriginMarker = *flagMQTTOriginMarker
+	}
+	if isFlagSet("mqtt-ack-flow-control") {
+		cfg.AckFlowControl = *flagMQTTAckFlowControl
+	}
+}
+
+func applyMQTTFlagSession(cfg *MQTTConfig) {
+	if isFlagSet("mqtt-persistent-session") {
+		cfg.PersistentSession = *flagMQTTPersistentSession
+	}
+	if *flagMQTTStoreDir != "" {
+		cfg.StoreDir = *flagMQTTStoreDir
+	}
+	if *flagMQTTStoreMaxMessages != 0 {
+		cfg.StoreMaxMessages = *flagMQTTStoreMaxMessages
+	}
 }
 
 func applyCompressFlags(cfg *CompressConfig) {
@@ -275,12 +869,285 @@ func applyCompressFlags(cfg *CompressConfig) {
 	}
 }
 
+func applyRuntimeFlags(cfg *RuntimeConfig) {
+	if *flagRuntimeGCPercent != 0 {
+		cfg.GCPercent = *flagRuntimeGCPercent
+	}
+	if *flagRuntimeMemoryLimit != 0 {
+		cfg.MemoryLimit = *flagRuntimeMemoryLimit
+	}
+	if *flagRuntimeMemoryLimitPercent != 0 {
+		cfg.MemoryLimitPercent = *flagRuntimeMemoryLimitPercent
+	}
+}
+
+func applyArchiveFlags(cfg *ArchiveConfig) {
+	if *flagArchiveMode != "" {
+		cfg.Mode = *flagArchiveMode
+	}
+	if *flagArchiveDir != "" {
+		cfg.Dir = *flagArchiveDir
+	}
+	if *flagArchiveMaxBytes != 0 {
+		cfg.MaxBytes = *flagArchiveMaxBytes
+	}
+	if *flagArchiveMaxAge != 0 {
+		cfg.MaxAge = *flagArchiveMaxAge
+	}
+	if isFlagSet("archive-gzip") {
+		cfg.Gzip = *flagArchiveGzip
+	}
+	if *flagArchiveUploadRetry != 0 {
+		cfg.UploadRetry = *flagArchiveUploadRetry
+	}
+	applyArchiveFlagsS3(cfg)
+}
+
+func applyArchiveFlagsS3(cfg *ArchiveConfig) {
+	if *flagArchiveS3Endpoint != "" {
+		cfg.S3Endpoint = *flagArchiveS3Endpoint
+	}
+	if *flagArchiveS3Bucket != "" {
+		cfg.S3Bucket = *flagArchiveS3Bucket
+	}
+	if *flagArchiveS3Region != "" {
+		cfg.S3Region = *flagArchiveS3Region
+	}
+	if *flagArchiveS3Prefix != "" {
+		cfg.S3Prefix = *flagArchiveS3Prefix
+	}
+	if *flagArchiveS3AccessKey != "" {
+		cfg.S3AccessKeyID = *flagArchiveS3AccessKey
+	}
+	if *flagArchiveS3SecretKey != "" {
+		cfg.S3SecretAccessKey = *flagArchiveS3SecretKey
+	}
+	if *flagArchiveS3PartBytes != 0 {
+		cfg.S3PartBytes = *flagArchiveS3PartBytes
+	}
+}
+
+func applyPipelineFlagHealthTLS(cfg *PipelineConfig) {
+	if *flagPipelineHealthTLSCert != "" {
+		cfg.HealthTLSCert = *flagPipelineHealthTLSCert
+	}
+	if *flagPipelineHealthTLSKey != "" {
+		cfg.HealthTLSKey = *flagPipelineHealthTLSKey
+	}
+	if *flagPipelineHealthClientCA != "" {
+		cfg.HealthClientCA = *flagPipelineHealthClientCA
+	}
+}
+
 func applyPipelineFlags(cfg *PipelineConfig) {
 	applyPipelineFlagInts(cfg)
 	applyPipelineFlagDurations(cfg)
 	if *flagPipelineHealthAddr != "" {
 		cfg.HealthAddr = *flagPipelineHealthAddr
 	}
+	applyPipelineFlagHealthTLS(cfg)
+	applyPipelineFlagOrdering(cfg)
+	if isFlagSet("pipeline-admin-dlq-replay") {
+		cfg.AdminDLQReplay = *flagPipelineAdminDLQReplay
+	}
+	if isFlagSet("pipeline-admin-streams") {
+		cfg.AdminStreams = *flagPipelineAdminStreams
+	}
+	if isFlagSet("pipeline-admin-buffer") {
+		cfg.AdminBuffer = *flagPipelineAdminBuffer
+	}
+	if *flagPipelineOversizePolicy != "" {
+		cfg.OversizePolicy = *flagPipelineOversizePolicy
+	}
+	if *flagPipelineSpillDir != "" {
+		cfg.SpillDir = *flagPipelineSpillDir
+	}
+	if *flagPipelineSnapshotPath != "" {
+		cfg.SnapshotPath = *flagPipelineSnapshotPath
+	}
+	if v := splitList(*flagPipelineFieldAllowlist); v != nil {
+		cfg.FieldAllowlist = v
+	}
+	if v := splitList(*flagPipelineFieldDenylist); v != nil {
+		cfg.FieldDenylist = v
+	}
+	if *flagPipelineStructuredData != "" {
+		cfg.StructuredData = *flagPipelineStructuredData
+	}
+	if *flagPipelineInvalidObject != "" {
+		cfg.InvalidObject = *flagPipelineInvalidObject
+	}
+	applyPipelineFlagPolicies(cfg)
+	applyPipelineFlagTimestamps(cfg)
+	applyPipelineFlagRetry(cfg)
+	applyPipelineFlagStatsd(cfg)
+	applyPipelineFlagAckSLO(cfg)
+	applyPipelineFlagPublishDedup(cfg)
+	applyPipelineFlagEnrich(cfg)
+	applyPipelineFlagPublish(cfg)
+	applyPipelineFlagLifecycle(cfg)
+	applyPipelineFlagRedact(cfg)
+}
+
+func applyPipelineFlagRedact(cfg *PipelineConfig) {
+	if *flagPipelineRedactFile != "" {
+		cfg.RedactFile = *flagPipelineRedactFile
+	}
+	if isFlagSet("pipeline-redact-strict") {
+		cfg.RedactStrict = *flagPipelineRedactStrict
+	}
+}
+
+func applyPipelineFlagLifecycle(cfg *PipelineConfig) {
+	if *flagPipelineLifecycleLog != "" {
+		cfg.LifecycleLog = *flagPipelineLifecycleLog
+	}
+}
+
+func applyPipelineFlagPublish(cfg *PipelineConfig) {
+	if *flagPipelinePublishTimeout != 0 {
+		cfg.PublishTimeout = *flagPipelinePublishTimeout
+	}
+	if *flagPipelineCaptureSize != 0 {
+		cfg.CaptureSize = *flagPipelineCaptureSize
+	}
+}
+
+func applyPipelineFlagEnrich(cfg *PipelineConfig) {
+	if *flagPipelineEnrichKey != "" {
+		cfg.EnrichKey = *flagPipelineEnrichKey
+	}
+	if *flagPipelineEnrichHashPrefix != "" {
+		cfg.EnrichHashPrefix = *flagPipelineEnrichHashPrefix
+	}
+	if v := splitList(*flagPipelineEnrichFields); v != nil {
+		cfg.EnrichFields = v
+	}
+	if *flagPipelineEnrichCacheTTL != 0 {
+		cfg.EnrichCacheTTL = *flagPipelineEnrichCacheTTL
+	}
+	if *flagPipelineEnrichCacheSize != 0 {
+		cfg.EnrichCacheSize = *flagPipelineEnrichCacheSize
+	}
+}
+
+func applyPipelineFlagAckSLO(cfg *PipelineConfig) {
+	if *flagPipelineAckSLOLatency != 0 {
+		cfg.AckSLOLatency = *flagPipelineAckSLOLatency
+	}
+	if *flagPipelineAckSLOPercentile != 0 {
+		cfg.AckSLOPercentile = *flagPipelineAckSLOPercentile
+	}
+	if *flagPipelineAckSLOWindow != 0 {
+		cfg.AckSLOWindow = *flagPipelineAckSLOWindow
+	}
+	if *flagPipelineAckSLOWindows != 0 {
+		cfg.AckSLOWindows = *flagPipelineAckSLOWindows
+	}
+}
+
+func applyPipelineFlagPublishDedup(cfg *PipelineConfig) {
+	if *flagPipelinePublishDedup != "" {
+		cfg.PublishDedup = *flagPipelinePublishDedup
+	}
+	if *flagPipelinePublishDedupWindow != 0 {
+		cfg.PublishDedupWindow = *flagPipelinePublishDedupWindow
+	}
+}
+
+func applyPipelineFlagStatsd(cfg *PipelineConfig) {
+	if *flagPipelineStatsdAddr != "" {
+		cfg.StatsdAddr = *flagPipelineStatsdAddr
+	}
+	if *flagPipelineStatsdPrefix != "" {
+		cfg.StatsdPrefix = *flagPipelineStatsdPrefix
+	}
+	if v := splitList(*flagPipelineStatsdTags); v != nil {
+		cfg.StatsdTags = v
+	}
+	if *flagPipelineStatsdInterval != 0 {
+		cfg.StatsdInterval = *flagPipelineStatsdInterval
+	}
+	if *flagPipelineMetricsStatePath != "" {
+		cfg.MetricsStatePath = *flagPipelineMetricsStatePath
+	}
+	if *flagPipelineMetricsStateInterval != 0 {
+		cfg.MetricsStateInterval = *flagPipelineMetricsStateInterval
+	}
+}
+
+func applyPipelineFlagRetry(cfg *PipelineConfig) {
+	if *flagPipelineRetryQueueCapacity != 0 {
+		cfg.RetryQueueCapacity = *flagPipelineRetryQueueCapacity
+	}
+	if *flagPipelineRetryRate != 0 {
+		cfg.RetryRate = *flagPipelineRetryRate
+	}
+	if *flagPipelineRetryPriority != "" {
+		cfg.RetryPriority = *flagPipelineRetryPriority
+	}
+}
+
+func applyPipelineFlagPolicies(cfg *PipelineConfig) {
+	if v := splitList(*flagPipelineNackPolicies); v != nil {
+		cfg.NackPolicies = v
+	}
+	if v := splitList(*flagPipelineMaxAgePolicies); v != nil {
+		cfg.MaxAgePolicies = v
+	}
+	if v := splitList(*flagPipelineStreamQuotas); v != nil {
+		cfg.StreamQuotas = v
+	}
+	applyPipelineFlagNackFlood(cfg)
+	applyPipelineFlagThroughput(cfg)
+}
+
+func applyPipelineFlagThroughput(cfg *PipelineConfig) {
+	if v := splitList(*flagPipelineThroughputSchedule); v != nil {
+		cfg.ThroughputSchedule = v
+	}
+	if *flagPipelineThroughputLocation != "" {
+		cfg.ThroughputLocation = *flagPipelineThroughputLocation
+	}
+	if *flagPipelineThroughputRamp >= 0 {
+		cfg.ThroughputRamp = *flagPipelineThroughputRamp
+	}
+}
+
+func applyPipelineFlagNackFlood(cfg *PipelineConfig) {
+	if *flagPipelineNackFloodThreshold != 0 {
+		cfg.NackFloodThreshold = *flagPipelineNackFloodThreshold
+	}
+	if *flagPipelineNackFloodWindow != 0 {
+		cfg.NackFloodWindow = *flagPipelineNackFloodWindow
+	}
+	if *flagPipelineNackFloodCooldown != 0 {
+		cfg.NackFloodCooldown = *flagPipelineNackFloodCooldown
+	}
+}
+
+func applyPipelineFlagOrdering(cfg *PipelineConfig) {
+	if isFlagSet("pipeline-ordered-publish") {
+		cfg.OrderedPublish = *flagPipelineOrderedPublish
+	}
+	if *flagPipelineOrderingKey != "" {
+		cfg.OrderingKey = *flagPipelineOrderingKey
+	}
+}
+
+func applyPipelineFlagTimestamps(cfg *PipelineConfig) {
+	if *flagPipelineTimestampField != "" {
+		cfg.TimestampField = *flagPipelineTimestampField
+	}
+	if v := splitLayouts(*flagPipelineTimestampLayouts); v != nil {
+		cfg.TimestampLayouts = v
+	}
+	if *flagPipelineTimestampLocation != "" {
+		cfg.TimestampLocation = *flagPipelineTimestampLocation
+	}
+	if *flagPipelineTimestampMaxSkew != 0 {
+		cfg.TimestampMaxSkew = *flagPipelineTimestampMaxSkew
+	}
 }
 
 func applyPipelineFlagInts(cfg *PipelineConfig) {
@@ -296,15 +1163,46 @@ func applyPipelineFlagInts(cfg *PipelineConfig) {
 	if *flagPipelineAckWorkers != 0 {
 		cfg.AckWorkers = *flagPipelineAckWorkers
 	}
+	if *flagPipelinePublishStallFactor != 0 {
+		cfg.PublishStallFactor = *flagPipelinePublishStallFactor
+	}
+	if *flagPipelineMaxPayloadBytes != 0 {
+		cfg.MaxPayloadBytes = *flagPipelineMaxPayloadBytes
+	}
+	if *flagPipelineMemoryBudget != 0 {
+		cfg.MemoryBudget = *flagPipelineMemoryBudget
+	}
+	if *flagPipelineSpillMinBytes != 0 {
+		cfg.SpillMinBytes = *flagPipelineSpillMinBytes
+	}
+	if *flagPipelineSpillArenaBytes != 0 {
+		cfg.SpillArenaBytes = *flagPipelineSpillArenaBytes
+	}
 	if *flagPipelineMessageQueueCapacity != 0 {
 		cfg.MessageQueueCapacity = *flagPipelineMessageQueueCapacity
 	}
+	if *flagPipelineReceiptQueueCapacity != 0 {
+		cfg.ReceiptQueueCapacity = *flagPipelineReceiptQueueCapacity
+	}
+	if *flagPipelineReceiptBatchSize != 0 {
+		cfg.ReceiptBatchSize = *flagPipelineReceiptBatchSize
+	}
+}
+
+func applyPipelineFlagAckDrain(cfg *PipelineConfig) {
+	if *flagPipelineAckDrainTimeout != 0 {
+		cfg.AckDrainTimeout = *flagPipelineAckDrainTimeout
+	}
 }
 
 func applyPipelineFlagDurations(cfg *PipelineConfig) {
+	applyPipelineFlagAckDrain(cfg)
 	if *flagPipelineShutdownTimeout != 0 {
 		cfg.ShutdownTimeout = *flagPipelineShutdownTimeout
 	}
+	if *flagPipelineDrainTimeout != 0 {
+		cfg.DrainTimeout = *flagPipelineDrainTimeout
+	}
 	if *flagPipelineErrorBackoff != 0 {
 		cfg.ErrorBackoff = *flagPipelineErrorBackoff
 	}
@@ -323,6 +1221,9 @@ func applyPipelineFlagDurations(cfg *PipelineConfig) {
 	if *flagPipelineHealthReadHeaderTimeout != 0 {
 		cfg.HealthReadHeaderTimeout = *flagPipelineHealthReadHeaderTimeout
 	}
+	if *flagPipelineReceiptFlushInterval != 0 {
+		cfg.ReceiptFlushInterval = *flagPipelineReceiptFlushInterval
+	}
 }
 
 func isFlagSet(name string) bool {
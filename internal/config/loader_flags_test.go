@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -20,6 +21,21 @@ func TestApplyRedisFlags(t *testing.T) {
 		"-redis-consumer=flag-consumer",
 		"-redis-batch-size=200",
 		"-redis-block-timeout=8s",
+		"-redis-claim-concurrency=4",
+		"-redis-claim-coordination",
+		"-redis-noack",
+		"-redis-backfill",
+		"-redis-backfill-rate=500",
+		"-redis-backfill-strict-order",
+		"-redis-backfill-policy=interleaved:4:1",
+		"-redis-username=flag-user",
+		"-redis-password=flag-pass",
+		"-redis-password-file=/run/secrets/redis",
+		"-redis-key-prefix=tenant-a:",
+		"-redis-replica-address=replica:6379",
+		"-redis-db=2",
+		"-redis-dead-letter-stream=flag-dlq",
+		"-redis-receipts-stream=flag-receipts",
 	}
 
 	// Reset flags and parse
@@ -49,6 +65,332 @@ func TestApplyRedisFlags(t *testing.T) {
 	if cfg.BlockTimeout != 8*time.Second {
 		t.Errorf("BlockTimeout = %v; want 8s", cfg.BlockTimeout)
 	}
+	if cfg.ClaimConcurrency != 4 {
+		t.Errorf("ClaimConcurrency = %d; want 4", cfg.ClaimConcurrency)
+	}
+	if !cfg.ClaimCoordination {
+		t.Error("ClaimCoordination = false; want true")
+	}
+	if !cfg.NoAck {
+		t.Error("NoAck = false; want true")
+	}
+	if !cfg.Backfill || !cfg.BackfillStrictOrder || cfg.BackfillRate != 500 {
+		t.Errorf("Backfill/StrictOrder/Rate = %v/%v/%d; want true/true/500",
+			cfg.Backfill, cfg.BackfillStrictOrder, cfg.BackfillRate)
+	}
+	if cfg.BackfillPolicy != "interleaved:4:1" {
+		t.Errorf("BackfillPolicy = %s; want interleaved:4:1", cfg.BackfillPolicy)
+	}
+	if cfg.Username != "flag-user" || cfg.Password != "flag-pass" {
+		t.Errorf("Username/Password = %s/%s; want flag-user/flag-pass", cfg.Username, cfg.Password)
+	}
+	if cfg.PasswordFile != "/run/secrets/redis" {
+		t.Errorf("PasswordFile = %s; want /run/secrets/redis", cfg.PasswordFile)
+	}
+	if cfg.KeyPrefix != "tenant-a:" {
+		t.Errorf("KeyPrefix = %s; want tenant-a:", cfg.KeyPrefix)
+	}
+	if cfg.ReplicaAddress != "replica:6379" {
+		t.Errorf("ReplicaAddress = %s; want replica:6379", cfg.ReplicaAddress)
+	}
+	if cfg.DB != 2 {
+		t.Errorf("DB = %d; want 2", cfg.DB)
+	}
+	if cfg.DeadLetterStream != "flag-dlq" {
+		t.Errorf("DeadLetterStream = %s; want flag-dlq", cfg.DeadLetterStream)
+	}
+	if cfg.ReceiptsStream != "flag-receipts" {
+		t.Errorf("ReceiptsStream = %s; want flag-receipts", cfg.ReceiptsStream)
+	}
+}
+
+func TestApplyRedisFlags_Dedup(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-dedup=bloom", "-redis-dedup-window=30m", "-redis-dedup-capacity=5000"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultRedisConfig()
+	applyRedisFlags(&cfg)
+
+	if cfg.Dedup != DedupBloom || cfg.DedupWindow != 30*time.Minute || cfg.DedupCapacity != 5000 {
+		t.Errorf("Dedup/Window/Capacity = %s/%v/%d; want bloom/30m/5000",
+			cfg.Dedup, cfg.DedupWindow, cfg.DedupCapacity)
+	}
+}
+
+func TestApplyRedisFlags_MemoryMonitor(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-redis-memory-high-percent=90", "-redis-memory-low-percent=75",
+		"-redis-memory-check-interval=2s", "-redis-memory-pressure-key=syslog:pressure",
+		"-mqtt-control-topic=syslog/control", "-mqtt-status-topic=syslog/status",
+	}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+	applyMQTTFlags(&cfg.MQTT)
+
+	if cfg.MQTT.StatusTopic != "syslog/status" {
+		t.Errorf("StatusTopic = %q; want syslog/status", cfg.MQTT.StatusTopic)
+	}
+
+	if cfg.Redis.MemoryHighPercent != 90 || cfg.Redis.MemoryLowPercent != 75 ||
+		cfg.Redis.MemoryCheckInterval != 2*time.Second || cfg.Redis.MemoryPressureKey != "syslog:pressure" {
+		t.Errorf("High/Low/Interval/Key = %d/%d/%v/%q; want 90/75/2s/syslog:pressure", cfg.Redis.MemoryHighPercent,
+			cfg.Redis.MemoryLowPercent, cfg.Redis.MemoryCheckInterval, cfg.Redis.MemoryPressureKey)
+	}
+	if cfg.MQTT.ControlTopic != "syslog/control" {
+		t.Errorf("ControlTopic = %q; want syslog/control", cfg.MQTT.ControlTopic)
+	}
+}
+
+func TestApplyRedisFlags_Compaction(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-redis-compact-interval=10m", "-redis-compact-min-age=2h",
+		"-redis-compact-batch-size=500", "-redis-compact-dry-run",
+	}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+
+	if cfg.Redis.CompactInterval != 10*time.Minute || cfg.Redis.CompactMinAge != 2*time.Hour ||
+		cfg.Redis.CompactBatchSize != 500 || !cfg.Redis.CompactDryRun {
+		t.Errorf("Interval/MinAge/BatchSize/DryRun = %v/%v/%d/%v; want 10m/2h/500/true", cfg.Redis.CompactInterval,
+			cfg.Redis.CompactMinAge, cfg.Redis.CompactBatchSize, cfg.Redis.CompactDryRun)
+	}
+}
+
+func TestApplyRedisFlags_Control(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-control-key=syslog-consumer:control", "-redis-control-interval=2s"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+
+	if cfg.Redis.ControlKey != "syslog-consumer:control" || cfg.Redis.ControlInterval != 2*time.Second {
+		t.Errorf("Key/Interval = %q/%v; want syslog-consumer:control/2s", cfg.Redis.ControlKey, cfg.Redis.ControlInterval)
+	}
+}
+
+func TestApplyRedisFlags_StreamLease(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-stream-lease", "-redis-stream-lease-ttl=30s"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+
+	if !cfg.Redis.StreamLease || cfg.Redis.StreamLeaseTTL != 30*time.Second {
+		t.Errorf("StreamLease/TTL = %v/%v; want true/30s", cfg.Redis.StreamLease, cfg.Redis.StreamLeaseTTL)
+	}
+}
+
+func TestApplyRedisFlags_IdleStreams(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-idle-stream-after=10m", "-redis-idle-stream-poll=30s"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+
+	if cfg.Redis.IdleStreamAfter != 10*time.Minute || cfg.Redis.IdleStreamPoll != 30*time.Second {
+		t.Errorf("IdleStreamAfter/Poll = %v/%v; want 10m/30s", cfg.Redis.IdleStreamAfter, cfg.Redis.IdleStreamPoll)
+	}
+}
+
+func TestApplyRedisFlags_Lag(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-lag-interval=10s", "-redis-lag-high=50000"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyRedisFlags(&cfg.Redis)
+
+	if cfg.Redis.LagInterval != 10*time.Second || cfg.Redis.LagHigh != 50000 {
+		t.Errorf("Interval/High = %v/%d; want 10s/50000", cfg.Redis.LagInterval, cfg.Redis.LagHigh)
+	}
+}
+
+func TestApplyRedisFlags_AdaptiveBatch(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-redis-adaptive-batch", "-redis-batch-size-min=50",
+		"-redis-batch-size-max=5000", "-redis-batch-latency-target=100ms",
+	}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultRedisConfig()
+	applyRedisFlags(&cfg)
+
+	if !cfg.AdaptiveBatch || cfg.BatchSizeMin != 50 || cfg.BatchSizeMax != 5000 ||
+		cfg.BatchLatencyTarget != 100*time.Millisecond {
+		t.Errorf("AdaptiveBatch/Min/Max/Target = %v/%d/%d/%v; want true/50/5000/100ms",
+			cfg.AdaptiveBatch, cfg.BatchSizeMin, cfg.BatchSizeMax, cfg.BatchLatencyTarget)
+	}
+}
+
+func TestApplyRedisFlags_StreamFilters(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-redis-stream-include=syslog:*, audit:*", "-redis-stream-exclude=syslog:test*",
+		"-redis-stream-overrides-file=streams.json", "-redis-read-fields=raw",
+	}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultRedisConfig()
+	applyRedisFlags(&cfg)
+
+	if want := []string{"raw"}; !reflect.DeepEqual(cfg.ReadFields, want) {
+		t.Errorf("ReadFields = %q; want %q", cfg.ReadFields, want)
+	}
+
+	if want := []string{"syslog:*", "audit:*"}; !reflect.DeepEqual(cfg.StreamInclude, want) {
+		t.Errorf("StreamInclude = %q; want %q", cfg.StreamInclude, want)
+	}
+	if want := []string{"syslog:test*"}; !reflect.DeepEqual(cfg.StreamExclude, want) {
+		t.Errorf("StreamExclude = %q; want %q", cfg.StreamExclude, want)
+	}
+	if cfg.StreamOverridesFile != "streams.json" {
+		t.Errorf("StreamOverridesFile = %q; want streams.json", cfg.StreamOverridesFile)
+	}
+}
+
+func TestApplyLogFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-log-level=debug", "-log-async", "-log-queue-size=256", "-log-payload-preview-bytes=0"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultLogConfig()
+	applyLogFlags(&cfg)
+
+	if want := (LogConfig{Level: "debug", QueueSize: 256, Async: true}); cfg != want {
+		t.Errorf("LogConfig = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestApplyMQTTFlags_OverrideTopics(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-mqtt-override-topics=alerts/#,audit/+"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultMQTTConfig()
+	applyMQTTFlags(&cfg)
+
+	if want := []string{"alerts/#", "audit/+"}; !reflect.DeepEqual(cfg.OverrideTopics, want) {
+		t.Errorf("OverrideTopics = %q; want %q", cfg.OverrideTopics, want)
+	}
+}
+
+func TestApplyMQTTFlags_Fanout(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-mqtt-fanout-topics=archive/raw=raw,audit/copy", "-mqtt-fanout-mode=all"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultMQTTConfig()
+	applyMQTTFlags(&cfg)
+
+	if want := []string{"archive/raw=raw", "audit/copy"}; !reflect.DeepEqual(cfg.FanoutTopics, want) {
+		t.Errorf("FanoutTopics = %q; want %q", cfg.FanoutTopics, want)
+	}
+	if cfg.FanoutMode != FanoutAll {
+		t.Errorf("FanoutMode = %q; want %q", cfg.FanoutMode, FanoutAll)
+	}
+}
+
+func TestApplyMQTTFlags_Sample(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-mqtt-sample-topic=analytics/sample", "-mqtt-sample-fields=severity,program",
+		"-mqtt-sample-percent=10", "-mqtt-sample-every=50",
+	}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultMQTTConfig()
+	applyMQTTFlags(&cfg)
+
+	if cfg.SampleTopic != "analytics/sample" {
+		t.Errorf("SampleTopic = %q; want analytics/sample", cfg.SampleTopic)
+	}
+	if want := []string{"severity", "program"}; !reflect.DeepEqual(cfg.SampleFields, want) {
+		t.Errorf("SampleFields = %q; want %q", cfg.SampleFields, want)
+	}
+	if cfg.SamplePercent != 10 || cfg.SampleEvery != 50 {
+		t.Errorf("SamplePercent = %d, SampleEvery = %d; want 10 and 50", cfg.SamplePercent, cfg.SampleEvery)
+	}
+}
+
+func TestApplyRedisFlags_DBZeroOverridesEnv(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-redis-db=0"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultRedisConfig()
+	cfg.DB = 5 // as if REDIS_DB=5
+	applyRedisFlags(&cfg)
+
+	if cfg.DB != 0 {
+		t.Errorf("DB = %d; want 0", cfg.DB)
+	}
 }
 
 func TestApplyMQTTFlags(t *testing.T) {
@@ -166,12 +508,14 @@ func TestApplyAllRedisFlags(t *testing.T) {
 	os.Args = []string{
 		tcTest,
 		"-redis-claim-idle=1m",
+		"-redis-claim-interval=15s",
 		"-redis-consumer-idle-timeout=10m",
 		"-redis-cleanup-interval=2m",
 		"-redis-dial-timeout=3s",
 		"-redis-read-timeout=4s",
 		"-redis-write-timeout=5s",
 		"-redis-ping-timeout=2s",
+		"-redis-operation-timeout=9s",
 		"-redis-conn-max-idle-time=7m",
 		"-redis-conn-max-lifetime=45m",
 	}
@@ -186,6 +530,9 @@ func TestApplyAllRedisFlags(t *testing.T) {
 	if cfg.ClaimIdle != 1*time.Minute {
 		t.Errorf("ClaimIdle = %v; want 1m", cfg.ClaimIdle)
 	}
+	if cfg.ClaimInterval != 15*time.Second {
+		t.Errorf("ClaimInterval = %v; want 15s", cfg.ClaimInterval)
+	}
 	if cfg.ConsumerIdleTimeout != 10*time.Minute {
 		t.Errorf("ConsumerIdleTimeout = %v; want 10m", cfg.ConsumerIdleTimeout)
 	}
@@ -204,6 +551,9 @@ func TestApplyAllRedisFlags(t *testing.T) {
 	if cfg.PingTimeout != 2*time.Second {
 		t.Errorf("PingTimeout = %v; want 2s", cfg.PingTimeout)
 	}
+	if cfg.OperationTimeout != 9*time.Second {
+		t.Errorf("OperationTimeout = %v; want 9s", cfg.OperationTimeout)
+	}
 	if cfg.ConnMaxIdleTime != 7*time.Minute {
 		t.Errorf("ConnMaxIdleTime = %v; want 7m", cfg.ConnMaxIdleTime)
 	}
@@ -281,8 +631,19 @@ func TestApplyAllMQTTFlags(t *testing.T) {
 		"-mqtt-ca-cert=/path/ca.pem",
 		"-mqtt-client-cert=/path/cert.pem",
 		"-mqtt-client-key=/path/key.pem",
+		"-mqtt-ack-hmac-key=flag-secret",
 		"-mqtt-tls-insecure-skip=true",
 		"-mqtt-use-cert-cn-prefix=true",
+		"-mqtt-persistent-session=true",
+		"-mqtt-origin-marker=true",
+		"-mqtt-ack-flow-control=true",
+		"-mqtt-store-dir=/data/mqtt",
+		"-mqtt-store-max-messages=250",
+		"-mqtt-quarantine-failures=4",
+		"-mqtt-connect-attempts=6",
+		"-mqtt-max-inflight=64",
+		"-mqtt-rtt-sample-every=10",
+		"-mqtt-rtt-window=2m",
 	}
 
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -295,6 +656,38 @@ func TestApplyAllMQTTFlags(t *testing.T) {
 	assertMQTTTopics(t, &cfg)
 	assertMQTTTimeouts(t, &cfg)
 	assertMQTTTLS(t, &cfg)
+	assertMQTTSession(t, &cfg)
+}
+
+func assertMQTTSession(t *testing.T, cfg *MQTTConfig) {
+	t.Helper()
+	if !cfg.PersistentSession {
+		t.Error("PersistentSession = false; want true")
+	}
+	if !cfg.OriginMarker {
+		t.Error("OriginMarker = false; want true")
+	}
+	if !cfg.AckFlowControl {
+		t.Error("AckFlowControl = false; want true")
+	}
+	if cfg.StoreDir != "/data/mqtt" {
+		t.Errorf("StoreDir = %s; want /data/mqtt", cfg.StoreDir)
+	}
+	if cfg.StoreMaxMessages != 250 {
+		t.Errorf("StoreMaxMessages = %d; want 250", cfg.StoreMaxMessages)
+	}
+	if cfg.QuarantineFailures != 4 {
+		t.Errorf("QuarantineFailures = %d; want 4", cfg.QuarantineFailures)
+	}
+	if cfg.ConnectAttempts != 6 {
+		t.Errorf("ConnectAttempts = %d; want 6", cfg.ConnectAttempts)
+	}
+	if cfg.MaxInflight != 64 {
+		t.Errorf("MaxInflight = %d; want 64", cfg.MaxInflight)
+	}
+	if cfg.RTTSampleEvery != 10 || cfg.RTTWindow != 2*time.Minute {
+		t.Errorf("RTTSampleEvery, RTTWindow = %d, %v; want 10, 2m", cfg.RTTSampleEvery, cfg.RTTWindow)
+	}
 }
 
 func assertMQTTTopics(t *testing.T, cfg *MQTTConfig) {
@@ -337,6 +730,9 @@ func assertMQTTTLS(t *testing.T, cfg *MQTTConfig) {
 	if cfg.ClientKey != "/path/key.pem" {
 		t.Errorf("ClientKey = %s; want /path/key.pem", cfg.ClientKey)
 	}
+	if cfg.AckHMACKey != "flag-secret" {
+		t.Errorf("AckHMACKey = %s; want flag-secret", cfg.AckHMACKey)
+	}
 	if !cfg.InsecureSkip {
 		t.Error("InsecureSkip = false; want true")
 	}
@@ -345,16 +741,16 @@ func assertMQTTTLS(t *testing.T, cfg *MQTTConfig) {
 	}
 }
 
-// TestApplyAllPipelineFlags sets ALL remaining pipeline flags.
-func TestApplyAllPipelineFlags(t *testing.T) {
+func TestApplyPipelineFlagTimestamps(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
 
 	os.Args = []string{
 		tcTest,
-		"-pipeline-error-backoff=200ms",
-		"-pipeline-ack-timeout=10s",
-		"-pipeline-refresh-interval=5m",
+		"-pipeline-timestamp-field=ts",
+		"-pipeline-timestamp-layouts=2006-01-02|unix",
+		"-pipeline-timestamp-location=America/New_York",
+		"-pipeline-timestamp-max-skew=1m",
 	}
 
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -364,51 +760,528 @@ func TestApplyAllPipelineFlags(t *testing.T) {
 	cfg := defaultPipelineConfig()
 	applyPipelineFlags(&cfg)
 
-	if cfg.ErrorBackoff != 200*time.Millisecond {
-		t.Errorf("ErrorBackoff = %v; want 200ms", cfg.ErrorBackoff)
+	if cfg.TimestampField != "ts" {
+		t.Errorf("TimestampField = %q; want ts", cfg.TimestampField)
 	}
-	if cfg.AckTimeout != 10*time.Second {
-		t.Errorf("AckTimeout = %v; want 10s", cfg.AckTimeout)
+	if want := []string{time.DateOnly, TimestampUnix}; !reflect.DeepEqual(cfg.TimestampLayouts, want) {
+		t.Errorf("TimestampLayouts = %q; want %q", cfg.TimestampLayouts, want)
 	}
-	if cfg.RefreshInterval != 5*time.Minute {
-		t.Errorf("RefreshInterval = %v; want 5m", cfg.RefreshInterval)
+	if cfg.TimestampLocation != "America/New_York" {
+		t.Errorf("TimestampLocation = %q; want America/New_York", cfg.TimestampLocation)
+	}
+	if cfg.TimestampMaxSkew != time.Minute {
+		t.Errorf("TimestampMaxSkew = %v; want 1m", cfg.TimestampMaxSkew)
 	}
 }
 
-func TestApplyCompressFlags(t *testing.T) {
-	cfg := defaultCompressConfig()
-
-	// No flags set → defaults should remain.
-	applyCompressFlags(&cfg)
+func TestApplyPipelineFlagSpill(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
 
-	if cfg.FreelistSize != 128 {
-		t.Errorf("FreelistSize = %d; want 128", cfg.FreelistSize)
+	os.Args = []string{
+		tcTest,
+		"-pipeline-memory-budget=1048576",
+		"-pipeline-spill-min-bytes=512",
+		"-pipeline-spill-arena-bytes=8388608",
+		"-pipeline-spill-dir=/tmp/spill",
 	}
-	if cfg.MaxDecompressBytes != 256*1024*1024 {
-		t.Errorf("MaxDecompressBytes = %d; want %d", cfg.MaxDecompressBytes, 256*1024*1024)
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.MemoryBudget != 1<<20 || cfg.SpillMinBytes != 512 || cfg.SpillArenaBytes != 8<<20 {
+		t.Errorf("MemoryBudget/SpillMinBytes/SpillArenaBytes = %d/%d/%d; want 1 MiB/512/8 MiB",
+			cfg.MemoryBudget, cfg.SpillMinBytes, cfg.SpillArenaBytes)
 	}
-	if cfg.WarmupCount != 4 {
-		t.Errorf("WarmupCount = %d; want 4", cfg.WarmupCount)
+	if cfg.SpillDir != "/tmp/spill" {
+		t.Errorf("SpillDir = %q; want /tmp/spill", cfg.SpillDir)
 	}
 }
 
-func TestApplyAllCompressFlags(t *testing.T) {
+func TestApplyPipelineFlagHealthTLS(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
 
 	os.Args = []string{
 		tcTest,
-		"-compress-freelist-size=64",
-		"-max-decompress-bytes=134217728",
-		"-compress-warmup-count=8",
+		"-pipeline-health-tls-cert=/etc/consumer/health.crt",
+		"-pipeline-health-tls-key=/etc/consumer/health.key",
+		"-pipeline-health-client-ca=/etc/consumer/clients.pem",
 	}
 
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	resetFlags()
 	flag.Parse()
 
-	cfg := defaultCompressConfig()
-	applyCompressFlags(&cfg)
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.HealthTLSCert != "/etc/consumer/health.crt" || cfg.HealthTLSKey != "/etc/consumer/health.key" {
+		t.Errorf("HealthTLSCert, HealthTLSKey = %q, %q; want the flag values", cfg.HealthTLSCert, cfg.HealthTLSKey)
+	}
+	if cfg.HealthClientCA != "/etc/consumer/clients.pem" {
+		t.Errorf("HealthClientCA = %q; want /etc/consumer/clients.pem", cfg.HealthClientCA)
+	}
+}
+
+func TestApplyPipelineFlagStreamQuotas(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-stream-quotas=noisy-*=delay:500:0,audit=skip:0:65536"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if want := []string{"noisy-*=delay:500:0", "audit=skip:0:65536"}; !reflect.DeepEqual(cfg.StreamQuotas, want) {
+		t.Errorf("StreamQuotas = %q; want %q", cfg.StreamQuotas, want)
+	}
+}
+
+func TestApplyPipelineFlagThroughputSchedule(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest,
+		"-pipeline-throughput-schedule=mon-fri 08:00-18:00=20000",
+		"-pipeline-throughput-location=America/New_York",
+		"-pipeline-throughput-ramp=0s",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if want := []string{"mon-fri 08:00-18:00=20000"}; !reflect.DeepEqual(cfg.ThroughputSchedule, want) {
+		t.Errorf("ThroughputSchedule = %q; want %q", cfg.ThroughputSchedule, want)
+	}
+	if cfg.ThroughputLocation != "America/New_York" || cfg.ThroughputRamp != 0 {
+		t.Errorf("ThroughputLocation, ThroughputRamp = %q, %v; want America/New_York, 0s",
+			cfg.ThroughputLocation, cfg.ThroughputRamp)
+	}
+}
+
+func TestApplyPipelineFlagNackFlood(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest,
+		"-pipeline-nack-flood-threshold=200",
+		"-pipeline-nack-flood-window=30s",
+		"-pipeline-nack-flood-cooldown=5m",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.NackFloodThreshold != 200 || cfg.NackFloodWindow != 30*time.Second || cfg.NackFloodCooldown != 5*time.Minute {
+		t.Errorf("NackFlood = %d in %v for %v; want 200 in 30s for 5m",
+			cfg.NackFloodThreshold, cfg.NackFloodWindow, cfg.NackFloodCooldown)
+	}
+}
+
+func TestApplyPipelineFlagSnapshotPath(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-snapshot-path=/tmp/state.json"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.SnapshotPath != "/tmp/state.json" {
+		t.Errorf("SnapshotPath = %q; want /tmp/state.json", cfg.SnapshotPath)
+	}
+}
+
+func TestApplyPipelineFlagRetry(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest,
+		"-pipeline-retry-queue-capacity=32",
+		"-pipeline-retry-rate=250",
+		"-pipeline-retry-priority=high",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.RetryQueueCapacity != 32 || cfg.RetryRate != 250 || cfg.RetryPriority != RetryPriorityHigh {
+		t.Errorf("RetryQueueCapacity/RetryRate/RetryPriority = %d/%d/%q; want 32/250/high",
+			cfg.RetryQueueCapacity, cfg.RetryRate, cfg.RetryPriority)
+	}
+}
+
+func TestApplyPipelineFlagStructuredData(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-structured-data=prefixed", "-pipeline-invalid-object=strict"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.StructuredData != StructuredDataPrefixed || cfg.InvalidObject != InvalidObjectStrict {
+		t.Errorf("StructuredData/InvalidObject = %q/%q; want prefixed/strict", cfg.StructuredData, cfg.InvalidObject)
+	}
+}
+
+func TestApplyFlags_Lifecycle(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-lifecycle-log=/var/log/lifecycle.jsonl", "-mqtt-lifecycle-topic=fleet/lifecycle"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultConfig()
+	applyPipelineFlags(&cfg.Pipeline)
+	applyMQTTFlags(&cfg.MQTT)
+
+	if cfg.Pipeline.LifecycleLog != "/var/log/lifecycle.jsonl" || cfg.MQTT.LifecycleTopic != "fleet/lifecycle" {
+		t.Errorf("LifecycleLog/LifecycleTopic = %q/%q; want /var/log/lifecycle.jsonl/fleet/lifecycle",
+			cfg.Pipeline.LifecycleLog, cfg.MQTT.LifecycleTopic)
+	}
+}
+
+func TestApplyFlags_Redact(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-redact-file=/etc/syslog-consumer/redact.json", "-pipeline-redact-strict"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.RedactFile != "/etc/syslog-consumer/redact.json" || !cfg.RedactStrict {
+		t.Errorf("RedactFile/RedactStrict = %q/%v; want /etc/syslog-consumer/redact.json/true",
+			cfg.RedactFile, cfg.RedactStrict)
+	}
+}
+
+func TestApplyPipelineFlagMetricsState(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-pipeline-metrics-state-path=/tmp/counters.json", "-pipeline-metrics-state-interval=15s",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.MetricsStatePath != "/tmp/counters.json" || cfg.MetricsStateInterval != 15*time.Second {
+		t.Errorf("MetricsStatePath/MetricsStateInterval = %q/%v; want /tmp/counters.json/15s",
+			cfg.MetricsStatePath, cfg.MetricsStateInterval)
+	}
+}
+
+func TestApplyPipelineFlagStatsd(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-pipeline-statsd-addr=dd-agent:8125", "-pipeline-statsd-prefix=syslog",
+		"-pipeline-statsd-tags=env:prod,site:mi", "-pipeline-statsd-interval=5s",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.StatsdAddr != "dd-agent:8125" || cfg.StatsdPrefix != "syslog" || cfg.StatsdInterval != 5*time.Second {
+		t.Errorf("StatsdAddr/StatsdPrefix/StatsdInterval = %q/%q/%v; want dd-agent:8125/syslog/5s",
+			cfg.StatsdAddr, cfg.StatsdPrefix, cfg.StatsdInterval)
+	}
+	if !reflect.DeepEqual(cfg.StatsdTags, []string{"env:prod", "site:mi"}) {
+		t.Errorf("StatsdTags = %v; want [env:prod site:mi]", cfg.StatsdTags)
+	}
+}
+
+func TestApplyPipelineFlagAckSLO(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-pipeline-ack-slo-latency=2s", "-pipeline-ack-slo-percentile=95",
+		"-pipeline-ack-slo-window=10s", "-pipeline-ack-slo-windows=2",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.AckSLOLatency != 2*time.Second || cfg.AckSLOPercentile != 95 ||
+		cfg.AckSLOWindow != 10*time.Second || cfg.AckSLOWindows != 2 {
+		t.Errorf("AckSLO = %v p%g over %v x%d; want 2s p95 over 10s x2",
+			cfg.AckSLOLatency, cfg.AckSLOPercentile, cfg.AckSLOWindow, cfg.AckSLOWindows)
+	}
+}
+
+func TestApplyPipelineFlagPublishDedup(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{tcTest, "-pipeline-publish-dedup=memory", "-pipeline-publish-dedup-window=20s"}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.PublishDedup != PublishDedupMemory || cfg.PublishDedupWindow != 20*time.Second {
+		t.Errorf("PublishDedup = %q over %v; want memory over 20s", cfg.PublishDedup, cfg.PublishDedupWindow)
+	}
+}
+
+func TestApplyPipelineFlagEnrich(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-pipeline-enrich-key=host", "-pipeline-enrich-hash-prefix=hosts:",
+		"-pipeline-enrich-fields=site,rack", "-pipeline-enrich-cache-ttl=1m", "-pipeline-enrich-cache-size=100",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.EnrichKey != "host" || cfg.EnrichHashPrefix != "hosts:" ||
+		!reflect.DeepEqual(cfg.EnrichFields, []string{"site", "rack"}) {
+		t.Errorf("Enrich = %q in %q* for %v; want host in hosts:* for site, rack",
+			cfg.EnrichKey, cfg.EnrichHashPrefix, cfg.EnrichFields)
+	}
+	if cfg.EnrichCacheTTL != time.Minute || cfg.EnrichCacheSize != 100 {
+		t.Errorf("Enrich cache = %v, %d; want 1m, 100", cfg.EnrichCacheTTL, cfg.EnrichCacheSize)
+	}
+}
+
+// TestApplyAllPipelineFlags sets ALL remaining pipeline flags.
+func TestApplyAllPipelineFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest,
+		"-pipeline-error-backoff=200ms",
+		"-pipeline-ack-timeout=10s",
+		"-pipeline-refresh-interval=5m",
+		"-pipeline-ordered-publish=true",
+		"-pipeline-ordering-key=hostname",
+		"-pipeline-admin-dlq-replay=true",
+		"-pipeline-admin-streams=true",
+		"-pipeline-admin-buffer=true",
+		"-pipeline-max-payload-bytes=65536",
+		"-pipeline-publish-stall-factor=4",
+		"-pipeline-oversize-policy=dlq",
+		"-pipeline-drain-timeout=3s",
+		"-pipeline-ack-drain-timeout=2s",
+		"-pipeline-publish-timeout=2s",
+		"-pipeline-capture-size=20",
+		"-pipeline-field-allowlist=hostname,severity",
+		"-pipeline-max-age-policies=debug-*=drop:15m",
+		"-pipeline-receipt-queue-capacity=4096",
+		"-pipeline-receipt-batch-size=32",
+		"-pipeline-receipt-flush-interval=2s",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultPipelineConfig()
+	applyPipelineFlags(&cfg)
+
+	if cfg.ErrorBackoff != 200*time.Millisecond {
+		t.Errorf("ErrorBackoff = %v; want 200ms", cfg.ErrorBackoff)
+	}
+	if cfg.AckTimeout != 10*time.Second {
+		t.Errorf("AckTimeout = %v; want 10s", cfg.AckTimeout)
+	}
+	if cfg.RefreshInterval != 5*time.Minute {
+		t.Errorf("RefreshInterval = %v; want 5m", cfg.RefreshInterval)
+	}
+	if !cfg.OrderedPublish || cfg.OrderingKey != "hostname" {
+		t.Errorf("OrderedPublish/OrderingKey = %v/%q; want true/hostname", cfg.OrderedPublish, cfg.OrderingKey)
+	}
+	if !cfg.AdminDLQReplay {
+		t.Error("AdminDLQReplay = false; want true")
+	}
+	if !cfg.AdminStreams {
+		t.Error("AdminStreams = false; want true")
+	}
+	if !cfg.AdminBuffer {
+		t.Error("AdminBuffer = false; want true")
+	}
+	if cfg.MaxPayloadBytes != 65536 {
+		t.Errorf("MaxPayloadBytes = %d; want 65536", cfg.MaxPayloadBytes)
+	}
+	if cfg.PublishStallFactor != 4 {
+		t.Errorf("PublishStallFactor = %d; want 4", cfg.PublishStallFactor)
+	}
+	if cfg.OversizePolicy != OversizeDLQ {
+		t.Errorf("OversizePolicy = %s; want dlq", cfg.OversizePolicy)
+	}
+	if cfg.DrainTimeout != 3*time.Second {
+		t.Errorf("DrainTimeout = %v; want 3s", cfg.DrainTimeout)
+	}
+	if cfg.AckDrainTimeout != 2*time.Second {
+		t.Errorf("AckDrainTimeout = %v; want 2s", cfg.AckDrainTimeout)
+	}
+	if cfg.PublishTimeout != 2*time.Second {
+		t.Errorf("PublishTimeout = %v; want 2s", cfg.PublishTimeout)
+	}
+	if cfg.CaptureSize != 20 {
+		t.Errorf("CaptureSize = %d; want 20", cfg.CaptureSize)
+	}
+	if want := []string{"hostname", "severity"}; !reflect.DeepEqual(cfg.FieldAllowlist, want) {
+		t.Errorf("FieldAllowlist = %q; want %q", cfg.FieldAllowlist, want)
+	}
+	if want := []string{"debug-*=drop:15m"}; !reflect.DeepEqual(cfg.MaxAgePolicies, want) {
+		t.Errorf("MaxAgePolicies = %q; want %q", cfg.MaxAgePolicies, want)
+	}
+	if cfg.ReceiptQueueCapacity != 4096 || cfg.ReceiptBatchSize != 32 {
+		t.Errorf("ReceiptQueueCapacity/BatchSize = %d/%d; want 4096/32", cfg.ReceiptQueueCapacity, cfg.ReceiptBatchSize)
+	}
+	if cfg.ReceiptFlushInterval != 2*time.Second {
+		t.Errorf("ReceiptFlushInterval = %v; want 2s", cfg.ReceiptFlushInterval)
+	}
+}
+
+func TestApplyCompressFlags(t *testing.T) {
+	cfg := defaultCompressConfig()
+
+	// No flags set → defaults should remain.
+	applyCompressFlags(&cfg)
+
+	if cfg.FreelistSize != 128 {
+		t.Errorf("FreelistSize = %d; want 128", cfg.FreelistSize)
+	}
+	if cfg.MaxDecompressBytes != 256*1024*1024 {
+		t.Errorf("MaxDecompressBytes = %d; want %d", cfg.MaxDecompressBytes, 256*1024*1024)
+	}
+	if cfg.WarmupCount != 4 {
+		t.Errorf("WarmupCount = %d; want 4", cfg.WarmupCount)
+	}
+}
+
+func TestApplyRuntimeFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-runtime-gc-percent=100", "-runtime-memory-limit=1073741824", "-runtime-memory-limit-percent=75",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultRuntimeConfig()
+	applyRuntimeFlags(&cfg)
+
+	if want := (RuntimeConfig{GCPercent: 100, MemoryLimit: 1 << 30, MemoryLimitPercent: 75}); cfg != want {
+		t.Errorf("applyRuntimeFlags() = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestApplyArchiveFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest, "-archive-mode=only", "-archive-dir=/var/lib/archive", "-archive-max-bytes=1048576",
+		"-archive-max-age=1m", "-archive-gzip", "-archive-upload-retry=10s",
+		"-archive-s3-endpoint=https://s3.example.com", "-archive-s3-bucket=logs", "-archive-s3-region=eu-west-1",
+		"-archive-s3-prefix=syslog/", "-archive-s3-access-key-id=AKID", "-archive-s3-secret-access-key=secret",
+		"-archive-s3-part-bytes=8388608",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultArchiveConfig()
+	applyArchiveFlags(&cfg)
+
+	want := ArchiveConfig{
+		Mode: ArchiveOnly, Dir: "/var/lib/archive", S3Endpoint: "https://s3.example.com", S3Bucket: "logs",
+		S3Region: "eu-west-1", S3Prefix: "syslog/", S3AccessKeyID: "AKID", S3SecretAccessKey: "secret",
+		MaxAge: time.Minute, UploadRetry: 10 * time.Second, MaxBytes: 1 << 20, S3PartBytes: 8 << 20, Gzip: true,
+	}
+	if cfg != want {
+		t.Errorf("applyArchiveFlags() = %+v; want %+v", cfg, want)
+	}
+}
+
+func TestApplyAllCompressFlags(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{
+		tcTest,
+		"-compress-freelist-size=64",
+		"-max-decompress-bytes=134217728",
+		"-compress-warmup-count=8",
+	}
+
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	flag.Parse()
+
+	cfg := defaultCompressConfig()
+	applyCompressFlags(&cfg)
 
 	if cfg.FreelistSize != 64 {
 		t.Errorf("FreelistSize = %d; want 64", cfg.FreelistSize)
@@ -423,13 +1296,66 @@ func TestApplyAllCompressFlags(t *testing.T) {
 
 // resetFlags re-initializes all flag variables for testing
 func resetFlags() {
+	flagAppProfile = flag.String("app-profile", "", "Defaults profile")
+
+	// Log flags
+	flagLogLevel = flag.String("log-level", "", "Log level")
+	flagLogAsync = flag.Bool("log-async", false, "Write logs from a background goroutine")
+	flagLogQueueSize = flag.Int("log-queue-size", 0, "Log records queued under -log-async")
+	flagLogPayloadPreviewBytes = flag.Int("log-payload-preview-bytes", -1, "Payload bytes shown in log records")
+
 	// Redis flags
 	flagRedisAddress = flag.String("redis-address", "", "Redis address")
 	flagRedisStream = flag.String("redis-stream", "", "Redis stream name (empty for multi-stream mode)")
 	flagRedisConsumer = flag.String("redis-consumer", "", "Redis consumer name")
 	flagRedisBatchSize = flag.Int("redis-batch-size", 0, "Redis batch size")
+	flagRedisUsername = flag.String("redis-username", "", "Redis ACL username")
+	flagRedisPassword = flag.String("redis-password", "", "Redis password")
+	flagRedisPasswordFile = flag.String("redis-password-file", "", "File holding the Redis password")
+	flagRedisKeyPrefix = flag.String("redis-key-prefix", "", "Prefix of every Redis key the consumer names")
+	flagRedisReplicaAddress = flag.String("redis-replica-address", "", "Redis read replica address for monitoring calls")
+	flagRedisDB = flag.Int("redis-db", -1, "Redis logical database index")
+	flagRedisDeadLetterStream = flag.String("redis-dead-letter-stream", "", "Dead letter stream")
+	flagRedisReceiptsStream = flag.String("redis-receipts-stream", "", "Receipts stream")
+	flagRedisClaimConcurrency = flag.Int("redis-claim-concurrency", 0, "Streams claimed in parallel per cycle")
+	flagRedisClaimCoordination = flag.Bool("redis-claim-coordination", false, "Take turns running the claim cycle")
+	flagRedisNoAck = flag.Bool("redis-noack", false, "Read with NOACK")
+	flagRedisBackfill = flag.Bool("redis-backfill", false, "Replay pending entries first")
+	flagRedisBackfillRate = flag.Int("redis-backfill-rate", 0, "Backfill entries per second")
+	flagRedisBackfillStrictOrder = flag.Bool("redis-backfill-strict-order", false, "Backfill in ID order")
+	flagRedisBackfillPolicy = flag.String("redis-backfill-policy", "", "Backfill order")
+	flagRedisStreamLease = flag.Bool("redis-stream-lease", false, "Read and claim only leased streams")
+	flagRedisStreamLeaseTTL = flag.Duration("redis-stream-lease-ttl", 0, "Stream lease TTL")
+	flagRedisIdleStreamAfter = flag.Duration("redis-idle-stream-after", 0, "Poll streams idle this long")
+	flagRedisIdleStreamPoll = flag.Duration("redis-idle-stream-poll", 0, "Idle stream poll interval")
+	flagRedisDedup = flag.String("redis-dedup", "", "Dedup mode")
+	flagRedisDedupWindow = flag.Duration("redis-dedup-window", 0, "Dedup window")
+	flagRedisDedupCapacity = flag.Int("redis-dedup-capacity", 0, "Dedup bloom capacity")
+	flagRedisAdaptiveBatch = flag.Bool("redis-adaptive-batch", false, "Adaptive read batch size")
+	flagRedisBatchSizeMin = flag.Int("redis-batch-size-min", 0, "Smallest adaptive read batch size")
+	flagRedisBatchSizeMax = flag.Int("redis-batch-size-max", 0, "Largest adaptive read batch size")
+	flagRedisBatchLatencyTarget = flag.Duration("redis-batch-latency-target", 0, "Adaptive batch latency target")
+	flagRedisMemoryHighPercent = flag.Int("redis-memory-high-percent", 0, "Memory pressure high percent")
+	flagRedisMemoryLowPercent = flag.Int("redis-memory-low-percent", 0, "Memory pressure low percent")
+	flagRedisMemoryCheckInterval = flag.Duration("redis-memory-check-interval", 0, "Memory check interval")
+	flagRedisMemoryPressureKey = flag.String("redis-memory-pressure-key", "", "Memory pressure key")
+	flagRedisControlKey = flag.String("redis-control-key", "", "Control hash")
+	flagRedisControlInterval = flag.Duration("redis-control-interval", 0, "Control hash interval")
+	flagRedisLagInterval = flag.Duration("redis-lag-interval", 0, "Consumer group lag interval")
+	flagRedisLagHigh = flag.Int("redis-lag-high", 0, "Consumer group lag drain threshold")
+	flagRedisCompactInterval = flag.Duration("redis-compact-interval", 0, "Compaction interval")
+	flagRedisCompactMinAge = flag.Duration("redis-compact-min-age", 0, "Compaction min age")
+	flagRedisCompactBatchSize = flag.Int("redis-compact-batch-size", 0, "Compaction batch size")
+	flagRedisCompactDryRun = flag.Bool("redis-compact-dry-run", false, "Compaction dry run")
+	flagRedisStreamInclude = flag.String("redis-stream-include", "", "Stream globs to consume")
+	flagRedisStreamExclude = flag.String("redis-stream-exclude", "", "Stream globs to skip")
+	flagRedisReadFields = flag.String("redis-read-fields", "", "Entry fields kept on read")
+	flagRedisStreamOverridesFile = flag.String("redis-stream-overrides-file", "", "Stream overrides file")
 	flagRedisBlockTimeout = flag.Duration("redis-block-timeout", 0, "Redis block timeout")
 	flagRedisClaimIdle = flag.Duration("redis-claim-idle", 0, "Redis claim idle time")
+	flagRedisClaimInterval = flag.Duration(
+		"redis-claim-interval", 0, "Redis claim loop interval (0 uses the claim idle time)",
+	)
 	flagRedisConsumerIdle = flag.Duration("redis-consumer-idle-timeout", 0, "Redis consumer idle timeout")
 	flagRedisCleanupInterval = flag.Duration("redis-cleanup-interval", 0, "Redis cleanup interval")
 	flagRedisDialTimeout = flag.Duration("redis-dial-timeout", 0, "Redis dial timeout")
@@ -444,12 +1370,18 @@ func resetFlags() {
 		"redis-conn-max-lifetime", -1,
 		"Max lifetime of a pooled connection (0 disables)",
 	)
+	flagRedisOperationTimeout = flag.Duration(
+		"redis-operation-timeout", 0, "Max duration of one Redis call (0 uses read plus write timeout)",
+	)
 
 	// MQTT flags
 	flagMQTTBroker = flag.String("mqtt-broker", "", "MQTT broker URL")
 	flagMQTTClientID = flag.String("mqtt-client-id", "", "MQTT client ID")
 	flagMQTTPublishTopic = flag.String("mqtt-publish-topic", "", "MQTT publish topic")
 	flagMQTTAckTopic = flag.String("mqtt-ack-topic", "", "MQTT ACK topic")
+	flagMQTTControlTopic = flag.String("mqtt-control-topic", "", "MQTT control topic")
+	flagMQTTStatusTopic = flag.String("mqtt-status-topic", "", "MQTT status topic")
+	flagMQTTOverrideTopics = flag.String("mqtt-override-topics", "", "Topic filters an entry's _topic may name")
 	flagMQTTQoS = flag.Int("mqtt-qos", -1, "MQTT QoS (0, 1, or 2)")
 	flagMQTTConnectTimeout = flag.Duration("mqtt-connect-timeout", 0, "MQTT connect timeout")
 	flagMQTTWriteTimeout = flag.Duration("mqtt-write-timeout", 0, "MQTT write timeout")
@@ -463,17 +1395,120 @@ func resetFlags() {
 	flagMQTTClientKey = flag.String("mqtt-client-key", "", "MQTT client key path")
 	flagMQTTTLSInsecureSkip = flag.Bool("mqtt-tls-insecure-skip", false, "Skip MQTT TLS verification")
 	flagMQTTUseCertCNPrefix = flag.Bool("mqtt-use-cert-cn-prefix", false, "Prefix topics with client cert CN")
+	flagMQTTOriginMarker = flag.Bool("mqtt-origin-marker", false, "Mark published payloads with their origin")
+	flagMQTTAckHMACKey = flag.String("mqtt-ack-hmac-key", "", "Key ACKs must be signed with (HMAC-SHA256)")
+	flagMQTTAckFlowControl = flag.Bool("mqtt-ack-flow-control", false, "Honor flow-control messages on the ACK topic")
+	flagMQTTPersistentSession = flag.Bool("mqtt-persistent-session", false, "Connect with CleanSession=false")
+	flagMQTTStoreDir = flag.String("mqtt-store-dir", "", "Directory for the file-backed inflight store")
+	flagMQTTStoreMaxMessages = flag.Int("mqtt-store-max-messages", 0, "Max packets persisted per connection")
+	flagMQTTConnectAttempts = flag.Int("mqtt-connect-attempts", 0, "Failed MQTT connects before startup fails")
+	flagMQTTQuarantineFailures = flag.Int(
+		"mqtt-quarantine-failures", 0, "Consecutive failed publishes before a pool connection is quarantined",
+	)
+	flagMQTTMaxInflight = flag.Int("mqtt-max-inflight", 0, "QoS 1/2 publishes awaiting the broker per connection")
+	flagMQTTRTTSampleEvery = flag.Int("mqtt-rtt-sample-every", 0, "Time every Nth QoS 1/2 publish")
+	flagMQTTRTTWindow = flag.Duration("mqtt-rtt-window", 0, "Window the broker RTT percentiles cover")
+	flagMQTTFanoutTopics = flag.String(
+		"mqtt-fanout-topics", "", "Comma-separated topic[=json|raw] items every batch is also published to",
+	)
+	flagMQTTFanoutMode = flag.String("mqtt-fanout-mode", "", "Fanout delivery mode: best-effort or all")
+	flagMQTTSampleTopic = flag.String("mqtt-sample-topic", "", "MQTT sample topic")
+	flagMQTTSampleFields = flag.String("mqtt-sample-fields", "", "Fields kept in sampled entries")
+	flagMQTTSamplePercent = flag.Int("mqtt-sample-percent", 0, "Percentage of entries sampled")
+	flagMQTTSampleEvery = flag.Int("mqtt-sample-every", 0, "Sample every Nth entry")
 
 	// Pipeline flags
 	flagPipelineBufferCapacity = flag.Int("pipeline-buffer-capacity", 0, "Pipeline buffer capacity")
 	flagPipelineShutdownTimeout = flag.Duration("pipeline-shutdown-timeout", 0, "Pipeline shutdown timeout")
 	flagPipelineErrorBackoff = flag.Duration("pipeline-error-backoff", 0, "Pipeline error backoff")
+	flagPipelineDrainTimeout = flag.Duration("pipeline-drain-timeout", 0, "Max time to publish queued batches on stop")
+	flagPipelineAckDrainTimeout = flag.Duration("pipeline-ack-drain-timeout", 0, "Max time to flush queued ACKs on stop")
+	flagPipelinePublishTimeout = flag.Duration("pipeline-publish-timeout", 0, "Max time for one publish")
+	flagPipelineCaptureSize = flag.Int("pipeline-capture-size", 0, "Published payloads and ACKs kept")
 	flagPipelineAckTimeout = flag.Duration("pipeline-ack-timeout", 0, "Pipeline ACK timeout")
 	flagPipelinePublishWorkers = flag.Int("pipeline-publish-workers", 0, "Number of concurrent publish workers")
 	flagPipelineRefreshInterval = flag.Duration("pipeline-refresh-interval", 0, "Pipeline stream refresh interval")
+	flagPipelineHealthTLSCert = flag.String("pipeline-health-tls-cert", "", "Health TLS certificate")
+	flagPipelineHealthTLSKey = flag.String("pipeline-health-tls-key", "", "Health TLS key")
+	flagPipelineHealthClientCA = flag.String("pipeline-health-client-ca", "", "Health client CA bundle")
+	flagPipelineOrderedPublish = flag.Bool("pipeline-ordered-publish", false, "Pin each stream to one publish worker")
+	flagPipelineOrderingKey = flag.String("pipeline-ordering-key", "", "Payload field used as the ordering key")
+	flagPipelineAdminDLQReplay = flag.Bool("pipeline-admin-dlq-replay", false, "Serve the DLQ replay endpoint")
+	flagPipelineAdminStreams = flag.Bool("pipeline-admin-streams", false, "Serve the stream attach/detach endpoints")
+	flagPipelineAdminBuffer = flag.Bool("pipeline-admin-buffer", false, "Serve the ACK buffer resize endpoint")
+	flagPipelinePublishStallFactor = flag.Int("pipeline-publish-stall-factor", 0, "Publish stall factor")
+	flagPipelineMaxPayloadBytes = flag.Int("pipeline-max-payload-bytes", 0, "Max bytes per published record")
+	flagPipelineOversizePolicy = flag.String("pipeline-oversize-policy", "", "Oversize record policy")
+	flagPipelineMemoryBudget = flag.Int("pipeline-memory-budget", 0, "Max bytes of queued entry bodies")
+	flagPipelineSpillMinBytes = flag.Int("pipeline-spill-min-bytes", 0, "Smallest spilled entry body")
+	flagPipelineSpillArenaBytes = flag.Int("pipeline-spill-arena-bytes", 0, "Size of the spill arena")
+	flagPipelineSpillDir = flag.String("pipeline-spill-dir", "", "Directory of the spill arena file")
+	flagPipelineSnapshotPath = flag.String("pipeline-snapshot-path", "", "File of the state snapshot")
+	flagPipelineRetryQueueCapacity = flag.Int("pipeline-retry-queue-capacity", 0, "Batches held by the retry queue")
+	flagPipelineRetryRate = flag.Int("pipeline-retry-rate", 0, "Max retried entries per second")
+	flagPipelineRetryPriority = flag.String("pipeline-retry-priority", "", "Retry priority: low or high")
+	flagPipelineFieldAllowlist = flag.String("pipeline-field-allowlist", "", "Payload fields to keep")
+	flagPipelineFieldDenylist = flag.String("pipeline-field-denylist", "", "Payload fields to drop")
+	flagPipelineStructuredData = flag.String("pipeline-structured-data", "", "Structured data flattening")
+	flagPipelineInvalidObject = flag.String("pipeline-invalid-object", "", "Invalid object mode")
+	flagPipelineStatsdAddr = flag.String("pipeline-statsd-addr", "", "StatsD address")
+	flagPipelineStatsdPrefix = flag.String("pipeline-statsd-prefix", "", "StatsD metric prefix")
+	flagPipelineStatsdTags = flag.String("pipeline-statsd-tags", "", "StatsD tags")
+	flagPipelineStatsdInterval = flag.Duration("pipeline-statsd-interval", 0, "StatsD flush interval")
+	flagPipelineMetricsStatePath = flag.String("pipeline-metrics-state-path", "", "Counter state file")
+	flagPipelineLifecycleLog = flag.String("pipeline-lifecycle-log", "", "Lifecycle event log")
+	flagMQTTLifecycleTopic = flag.String("mqtt-lifecycle-topic", "", "MQTT lifecycle topic")
+	flagPipelineRedactFile = flag.String("pipeline-redact-file", "", "Redact rules file")
+	flagPipelineRedactStrict = flag.Bool("pipeline-redact-strict", false, "Dead-letter unredactable entries")
+	flagPipelineMetricsStateInterval = flag.Duration("pipeline-metrics-state-interval", 0, "Counter save interval")
+	flagPipelineAckSLOLatency = flag.Duration("pipeline-ack-slo-latency", 0, "Ack latency objective")
+	flagPipelineAckSLOPercentile = flag.Float64("pipeline-ack-slo-percentile", 0, "Ack latency percentile")
+	flagPipelineAckSLOWindow = flag.Duration("pipeline-ack-slo-window", 0, "Ack latency window")
+	flagPipelineAckSLOWindows = flag.Int("pipeline-ack-slo-windows", 0, "Missed windows before an alert")
+	flagPipelinePublishDedup = flag.String("pipeline-publish-dedup", "", "Publish dedup mode")
+	flagPipelinePublishDedupWindow = flag.Duration("pipeline-publish-dedup-window", 0, "Publish dedup window")
+	flagPipelineEnrichKey = flag.String("pipeline-enrich-key", "", "Enrich key field")
+	flagPipelineEnrichHashPrefix = flag.String("pipeline-enrich-hash-prefix", "", "Enrich hash prefix")
+	flagPipelineEnrichFields = flag.String("pipeline-enrich-fields", "", "Enrich fields")
+	flagPipelineEnrichCacheTTL = flag.Duration("pipeline-enrich-cache-ttl", 0, "Enrich cache TTL")
+	flagPipelineEnrichCacheSize = flag.Int("pipeline-enrich-cache-size", 0, "Enrich cache size")
+	flagPipelineNackPolicies = flag.String("pipeline-nack-policies", "", "NACK policies by reason")
+	flagPipelineMaxAgePolicies = flag.String("pipeline-max-age-policies", "", "Max-age policies by stream")
+	flagPipelineStreamQuotas = flag.String("pipeline-stream-quotas", "", "Per-second quotas by stream")
+	flagPipelineThroughputSchedule = flag.String("pipeline-throughput-schedule", "", "Throughput caps by time window")
+	flagPipelineThroughputLocation = flag.String("pipeline-throughput-location", "", "Time zone of the throughput windows")
+	flagPipelineThroughputRamp = flag.Duration("pipeline-throughput-ramp", -1, "Ramp between throughput caps")
+	flagPipelineNackFloodThreshold = flag.Int("pipeline-nack-flood-threshold", 0, "NACK flood threshold")
+	flagPipelineNackFloodWindow = flag.Duration("pipeline-nack-flood-window", 0, "NACK flood window")
+	flagPipelineNackFloodCooldown = flag.Duration("pipeline-nack-flood-cooldown", 0, "NACK flood cooldown")
+	flagPipelineReceiptQueueCapacity = flag.Int("pipeline-receipt-queue-capacity", 0, "Receipt queue capacity")
+	flagPipelineReceiptBatchSize = flag.Int("pipeline-receipt-batch-size", 0, "Receipts per Redis pipeline")
+	flagPipelineReceiptFlushInterval = flag.Duration("pipeline-receipt-flush-interval", 0, "Receipt flush interval")
+	flagPipelineTimestampField = flag.String("pipeline-timestamp-field", "", "Payload field to normalize")
+	flagPipelineTimestampLayouts = flag.String("pipeline-timestamp-layouts", "", "Timestamp layouts")
+	flagPipelineTimestampLocation = flag.String("pipeline-timestamp-location", "", "Timestamp time zone")
+	flagPipelineTimestampMaxSkew = flag.Duration("pipeline-timestamp-max-skew", 0, "Timestamp max skew")
 
 	// Compress flags
 	flagCompressFreelistSize = flag.Int("compress-freelist-size", 0, "Decoder freelist channel capacity")
 	flagCompressMaxDecompressBytes = flag.Int("max-decompress-bytes", 0, "Max decompressed payload size in bytes")
 	flagCompressWarmupCount = flag.Int("compress-warmup-count", 0, "Decoders pre-created at init")
+
+	flagRuntimeGCPercent = flag.Int("runtime-gc-percent", 0, "GOGC of the garbage collector")
+	flagRuntimeMemoryLimit = flag.Int("runtime-memory-limit", 0, "Soft memory limit in bytes")
+	flagRuntimeMemoryLimitPercent = flag.Int("runtime-memory-limit-percent", 0, "Share of the cgroup memory limit")
+
+	flagArchiveMode = flag.String("archive-mode", "", "Archive sink: copy (besides MQTT) or only (instead of MQTT)")
+	flagArchiveDir = flag.String("archive-dir", "", "Directory the NDJSON archive files are written to")
+	flagArchiveMaxBytes = flag.Int("archive-max-bytes", 0, "Record bytes after which an archive file is rotated")
+	flagArchiveMaxAge = flag.Duration("archive-max-age", 0, "Age after which an archive file is rotated")
+	flagArchiveGzip = flag.Bool("archive-gzip", false, "Gzip the archive files")
+	flagArchiveUploadRetry = flag.Duration("archive-upload-retry", 0, "Interval between retries of failed uploads")
+	flagArchiveS3Endpoint = flag.String("archive-s3-endpoint", "", "S3-compatible endpoint URL archive files go to")
+	flagArchiveS3Bucket = flag.String("archive-s3-bucket", "", "Bucket archive files are uploaded to")
+	flagArchiveS3Region = flag.String("archive-s3-region", "", "Region of the archive bucket")
+	flagArchiveS3Prefix = flag.String("archive-s3-prefix", "", "Key prefix of the uploaded archive files")
+	flagArchiveS3AccessKey = flag.String("archive-s3-access-key-id", "", "Access key ID of the archive bucket")
+	flagArchiveS3SecretKey = flag.String("archive-s3-secret-access-key", "", "Secret access key of the archive bucket")
+	flagArchiveS3PartBytes = flag.Int("archive-s3-part-bytes", 0, "Size of the multipart upload parts")
 }
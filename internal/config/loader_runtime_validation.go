@@ -1,18 +1,128 @@
 package config
 
 import (
+	"bytes"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/certs"
 )
 
 func applyRuntimeValidation(cfg *Config) error {
+	if err := loadSecretFiles(&cfg.Redis); err != nil {
+		return err
+	}
+	if err := loadStreamOverrides(&cfg.Redis); err != nil {
+		return err
+	}
+	if err := loadRedactRules(&cfg.Pipeline); err != nil {
+		return err
+	}
 	return applyTopicPrefix(cfg)
 }
 
+// streamOverrideEntry is a RedisConfig.StreamOverridesFile entry as
+// written, with durations in time.ParseDuration syntax.
+type streamOverrideEntry struct {
+	QoS           *int   `json:"qos"`
+	Pattern       string `json:"pattern"`
+	PublishTopic  string `json:"publish_topic"`
+	MaxAgeAction  string `json:"max_age_action"`
+	InvalidObject string `json:"invalid_object"`
+	ClaimIdle     string `json:"claim_idle"`
+	MaxAge        string `json:"max_age"`
+	BatchSize     int    `json:"batch_size"`
+	Priority      int    `json:"priority"`
+}
+
+// loadStreamOverrides parses the JSON array of cfg.StreamOverridesFile
+// into cfg.StreamOverrides, after expanding its ${VAR} references; Validate
+// checks the values. Unknown fields are rejected, so a misspelled setting
+// is not silently ignored.
+func loadStreamOverrides(cfg *RedisConfig) error {
+	if cfg.StreamOverridesFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Clean(cfg.StreamOverridesFile))
+	if err != nil {
+		return fmt.Errorf("failed to read stream overrides: %w", err)
+	}
+	if data, err = expandEnvTemplate(data); err != nil {
+		return fmt.Errorf("failed to expand stream overrides: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var entries []streamOverrideEntry
+	if err := dec.Decode(&entries); err != nil {
+		return fmt.Errorf("failed to parse stream overrides: %w", err)
+	}
+	cfg.StreamOverrides = make([]StreamOverride, 0, len(entries))
+	for i := range entries {
+		override, err := entries[i].parse()
+		if err != nil {
+			return fmt.Errorf("failed to parse stream overrides: %w", err)
+		}
+		cfg.StreamOverrides = append(cfg.StreamOverrides, override)
+	}
+	return nil
+}
+
+// loadRedactRules parses the JSON array of cfg.RedactFile into
+// cfg.RedactRules, rejecting unknown fields like loadStreamOverrides;
+// Validate checks the rules.
+func loadRedactRules(cfg *PipelineConfig) error {
+	if cfg.RedactFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Clean(cfg.RedactFile))
+	if err != nil {
+		return fmt.Errorf("failed to read redact rules: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg.RedactRules); err != nil {
+		return fmt.Errorf("failed to parse redact rules: %w", err)
+	}
+	return nil
+}
+
+func (e *streamOverrideEntry) parse() (StreamOverride, error) {
+	o := StreamOverride{
+		Pattern:       e.Pattern,
+		PublishTopic:  e.PublishTopic,
+		MaxAgeAction:  e.MaxAgeAction,
+		InvalidObject: e.InvalidObject,
+		BatchSize:     e.BatchSize,
+		Priority:      e.Priority,
+		QoS:           -1,
+	}
+	if e.QoS != nil {
+		o.QoS = *e.QoS
+	}
+	var err error
+	if o.ClaimIdle, err = parseOverrideDuration(e.ClaimIdle); err != nil {
+		return StreamOverride{}, fmt.Errorf("stream override %q has a malformed claim_idle: %w", e.Pattern, err)
+	}
+	if o.MaxAge, err = parseOverrideDuration(e.MaxAge); err != nil {
+		return StreamOverride{}, fmt.Errorf("stream override %q has a malformed max_age: %w", e.Pattern, err)
+	}
+	return o, nil
+}
+
+// parseOverrideDuration parses an optional duration; empty is zero.
+func parseOverrideDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
 func applyTopicPrefix(cfg *Config) error {
 	if cfg.MQTT.UseCertCNPrefix && cfg.MQTT.ClientCert != "" {
 		cn, err := extractCNFromCertFile(cfg.MQTT.ClientCert)
@@ -21,12 +131,17 @@ func applyTopicPrefix(cfg *Config) error {
 		}
 		cfg.MQTT.PublishTopic = cn + "/" + cfg.MQTT.PublishTopic
 		cfg.MQTT.AckTopic = cn + "/" + cfg.MQTT.AckTopic
+		for i := range cfg.Redis.StreamOverrides {
+			if o := &cfg.Redis.StreamOverrides[i]; o.PublishTopic != "" {
+				o.PublishTopic = cn + "/" + o.PublishTopic
+			}
+		}
 	}
 	return nil
 }
 
 func extractCNFromCertFile(certPath string) (string, error) {
-	certPEM, err := os.ReadFile(filepath.Clean(certPath))
+	certPEM, err := certs.Read(certPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read certificate: %w", err)
 	}
@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -99,6 +100,95 @@ func TestApplyRuntimeValidation_WithCertCN(t *testing.T) {
 	}
 }
 
+// writeStreamOverrides writes data to a stream overrides file and returns
+// its path.
+func writeStreamOverrides(t *testing.T, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "streams.json")
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+		t.Fatalf("write stream overrides: %v", err)
+	}
+	return p
+}
+
+func TestApplyRuntimeValidation_StreamOverrides(t *testing.T) {
+	cfg := &Config{
+		Redis: RedisConfig{StreamOverridesFile: writeStreamOverrides(t, `[
+			{"pattern": "fw-*", "claim_idle": "5m", "batch_size": 500, "publish_topic": "syslog/fw",
+			 "qos": 0, "priority": 10, "max_age": "1h", "max_age_action": "dlq", "invalid_object": "lenient"},
+			{"pattern": "*"}
+		]`)},
+		MQTT: MQTTConfig{
+			PublishTopic:    "syslog/remote",
+			AckTopic:        "syslog/remote/ack",
+			UseCertCNPrefix: true,
+			ClientCert:      generateTestCert(t, "device-42"),
+		},
+	}
+	if err := applyRuntimeValidation(cfg); err != nil {
+		t.Fatalf("applyRuntimeValidation() error = %v; want nil", err)
+	}
+	want := []StreamOverride{
+		{
+			Pattern: "fw-*", PublishTopic: "device-42/syslog/fw", MaxAgeAction: MaxAgeDLQ, InvalidObject: InvalidObjectLenient,
+			ClaimIdle: 5 * time.Minute, MaxAge: time.Hour, BatchSize: 500, Priority: 10, QoS: 0,
+		},
+		{Pattern: "*", QoS: -1},
+	}
+	if !reflect.DeepEqual(cfg.Redis.StreamOverrides, want) {
+		t.Errorf("StreamOverrides = %+v; want %+v", cfg.Redis.StreamOverrides, want)
+	}
+	if o, ok := MatchStreamOverride(cfg.Redis.StreamOverrides, "fw-edge"); !ok || o.Pattern != "fw-*" {
+		t.Errorf("MatchStreamOverride(fw-edge) = %+v, %v; want the fw-* override", o, ok)
+	}
+	if o, ok := MatchStreamOverride(cfg.Redis.StreamOverrides[:1], "app"); ok {
+		t.Errorf("MatchStreamOverride(app) = %+v; want no match", o)
+	}
+}
+
+func TestApplyRuntimeValidation_MalformedStreamOverrides(t *testing.T) {
+	for _, data := range []string{
+		`{"pattern": "fw-*"}`,
+		`[{"pattern": "fw-*", "claim_idel": "5m"}]`,
+		`[{"pattern": "fw-*", "max_age": "an hour"}]`,
+	} {
+		cfg := &Config{Redis: RedisConfig{StreamOverridesFile: writeStreamOverrides(t, data)}}
+		if err := applyRuntimeValidation(cfg); err == nil {
+			t.Errorf("applyRuntimeValidation(%s) error = nil; want error", data)
+		}
+	}
+	cfg := &Config{Redis: RedisConfig{StreamOverridesFile: "/nonexistent/streams.json"}}
+	if err := applyRuntimeValidation(cfg); err == nil {
+		t.Error("applyRuntimeValidation() error = nil; want error for a missing file")
+	}
+}
+
+func TestApplyRuntimeValidation_RedactRules(t *testing.T) {
+	cfg := &Config{Pipeline: PipelineConfig{RedactFile: writeStreamOverrides(t, `[
+		{"name": "card", "pattern": "\\b[0-9]{13,19}\\b", "luhn": true},
+		{"name": "password", "field": "password"}
+	]`)}}
+	if err := applyRuntimeValidation(cfg); err != nil {
+		t.Fatalf("applyRuntimeValidation() error = %v; want nil", err)
+	}
+	want := []RedactRule{
+		{Name: "card", Pattern: `\b[0-9]{13,19}\b`, Luhn: true},
+		{Name: "password", Field: "password"},
+	}
+	if !reflect.DeepEqual(cfg.Pipeline.RedactRules, want) {
+		t.Errorf("RedactRules = %+v; want %+v", cfg.Pipeline.RedactRules, want)
+	}
+
+	cfg = &Config{Pipeline: PipelineConfig{RedactFile: writeStreamOverrides(t, `[{"name": "card", "regex": "x"}]`)}}
+	if err := applyRuntimeValidation(cfg); err == nil {
+		t.Error("applyRuntimeValidation() error = nil; want error for an unknown field")
+	}
+	cfg = &Config{Pipeline: PipelineConfig{RedactFile: "/nonexistent/redact.json"}}
+	if err := applyRuntimeValidation(cfg); err == nil {
+		t.Error("applyRuntimeValidation() error = nil; want error for a missing file")
+	}
+}
+
 func TestApplyRuntimeValidation_MissingCert(t *testing.T) {
 	cfg := &Config{
 		MQTT: MQTTConfig{
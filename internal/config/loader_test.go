@@ -222,11 +222,70 @@ func verifyPipelineConfig(t *testing.T, cfg *Config) {
 
 // Helper functions for tests
 
+func TestLoad_Profile(t *testing.T) {
+	clearTestEnv(t)
+	resetTestFlags(t)
+	t.Setenv("APP_PROFILE", ProfileEdge)
+	t.Setenv("REDIS_BATCH_SIZE", "500")
+	t.Setenv("PIPELINE_ACK_WORKERS", "8") // the profile's own value
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Profile != ProfileEdge || cfg.Pipeline.PublishWorkers != 4 || cfg.MQTT.PoolSize != 2 {
+		t.Errorf("Profile = %q, PublishWorkers = %d, MQTT.PoolSize = %d; want the edge defaults",
+			cfg.Profile, cfg.Pipeline.PublishWorkers, cfg.MQTT.PoolSize)
+	}
+	if cfg.Redis.BatchSize != 500 {
+		t.Errorf("Redis.BatchSize = %d; want the environment's 500 over the profile", cfg.Redis.BatchSize)
+	}
+	if len(cfg.ProfileOverrides) != 1 || cfg.ProfileOverrides[0] != "REDIS_BATCH_SIZE" {
+		t.Errorf("ProfileOverrides = %v; want [REDIS_BATCH_SIZE]", cfg.ProfileOverrides)
+	}
+}
+
+func TestLoad_ProfileFlag(t *testing.T) {
+	clearTestEnv(t)
+	resetTestFlags(t)
+	t.Setenv("APP_PROFILE", ProfileEdge)
+	os.Args = []string{tcTest, "-app-profile=dev", "-pipeline-publish-workers=3"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Profile != ProfileDev || cfg.Log.Level != "debug" || cfg.Pipeline.PublishWorkers != 3 {
+		t.Errorf("Profile = %q, Log.Level = %q, PublishWorkers = %d; want dev with the flag's 3 workers",
+			cfg.Profile, cfg.Log.Level, cfg.Pipeline.PublishWorkers)
+	}
+
+	t.Setenv("APP_PROFILE", "mainframe")
+	os.Args = []string{tcTest}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	resetFlags()
+	if _, err := Load(); err == nil {
+		t.Error("Load() error = nil; want error for an unknown profile")
+	}
+}
+
+func TestProfiles_Validate(t *testing.T) {
+	for name, tune := range profiles {
+		cfg := defaultConfig()
+		tune(cfg)
+		if err := Validate(cfg); err != nil {
+			t.Errorf("profile %s: Validate() error = %v", name, err)
+		}
+	}
+}
+
 func clearTestEnv(t *testing.T) {
 	t.Helper()
 	envVars := []string{
 		"REDIS_ADDRESS", "REDIS_STREAM", "REDIS_CONSUMER",
-		"REDIS_BATCH_SIZE", "REDIS_BLOCK_TIMEOUT", "REDIS_CLAIM_IDLE",
+		"REDIS_BATCH_SIZE", "REDIS_BLOCK_TIMEOUT", "REDIS_CLAIM_IDLE", "REDIS_CLAIM_INTERVAL",
 		"REDIS_CONSUMER_IDLE_TIMEOUT", "REDIS_CLEANUP_INTERVAL",
 		"REDIS_DIAL_TIMEOUT", "REDIS_READ_TIMEOUT", "REDIS_WRITE_TIMEOUT", "REDIS_PING_TIMEOUT",
 		"MQTT_BROKER", "MQTT_CLIENT_ID", "MQTT_PUBLISH_TOPIC", "MQTT_ACK_TOPIC",
@@ -236,7 +295,7 @@ func clearTestEnv(t *testing.T) {
 		"MQTT_TLS_INSECURE_SKIP", "MQTT_USE_CERT_CN_PREFIX",
 		"PIPELINE_BUFFER_CAPACITY", "PIPELINE_SHUTDOWN_TIMEOUT",
 		"PIPELINE_ERROR_BACKOFF", "PIPELINE_ACK_TIMEOUT", "PIPELINE_PUBLISH_WORKERS",
-		"PIPELINE_REFRESH_INTERVAL",
+		"PIPELINE_REFRESH_INTERVAL", "APP_PROFILE", "LOG_LEVEL",
 	}
 	for _, v := range envVars {
 		// t.Setenv saves the original value and restores it via t.Cleanup,
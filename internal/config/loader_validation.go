@@ -1,6 +1,17 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Validate enforces the subsystem invariants assumed by the rest of the code.
 func Validate(cfg *Config) error {
@@ -16,33 +27,844 @@ func Validate(cfg *Config) error {
 	if err := validatePipeline(&cfg.Pipeline); err != nil {
 		return err
 	}
-	return validateCompress(&cfg.Compress)
+	if err := validateDeadLetterUse(cfg); err != nil {
+		return err
+	}
+	if err := validateReceipts(cfg); err != nil {
+		return err
+	}
+	if err := validateStreamPolicies(cfg); err != nil {
+		return err
+	}
+	if err := validatePublishing(cfg); err != nil {
+		return err
+	}
+	if err := validateAckTopic(cfg); err != nil {
+		return err
+	}
+	return validateProcess(cfg)
+}
+
+// validateProcess checks the process-wide settings.
+func validateProcess(cfg *Config) error {
+	if err := validateCompress(&cfg.Compress); err != nil {
+		return err
+	}
+	if err := validateRuntime(&cfg.Runtime); err != nil {
+		return err
+	}
+	return validateArchive(&cfg.Archive)
+}
+
+func validateArchive(cfg *ArchiveConfig) error {
+	switch cfg.Mode {
+	case "":
+		return nil
+	case ArchiveCopy, ArchiveOnly:
+	default:
+		return errors.New("archive mode must be empty, copy or only")
+	}
+	if cfg.Dir == "" {
+		return errors.New("archive dir cannot be empty")
+	}
+	if cfg.MaxBytes < 1 {
+		return errors.New("archive max bytes must be positive")
+	}
+	if cfg.MaxAge <= 0 {
+		return errors.New("archive max age must be positive")
+	}
+	return validateArchiveS3(cfg)
+}
+
+// validEndpoint reports whether endpoint is an absolute http or https URL.
+func validEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// minS3PartBytes is the smallest part S3 accepts in a multipart upload,
+// the last one aside.
+const minS3PartBytes = 5 << 20
+
+func validateArchiveS3(cfg *ArchiveConfig) error {
+	if cfg.S3Bucket == "" && cfg.S3Endpoint == "" {
+		return nil
+	}
+	if cfg.S3Bucket == "" {
+		return errors.New("archive s3 bucket cannot be empty with an s3 endpoint")
+	}
+	if !validEndpoint(cfg.S3Endpoint) {
+		return errors.New("archive s3 endpoint must be an http or https URL")
+	}
+	if cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "" {
+		return errors.New("archive s3 access key id and secret access key must be set")
+	}
+	if cfg.S3Region == "" {
+		return errors.New("archive s3 region cannot be empty")
+	}
+	if cfg.S3PartBytes < minS3PartBytes {
+		return errors.New("archive s3 part bytes must be at least 5242880")
+	}
+	if cfg.UploadRetry <= 0 {
+		return errors.New("archive upload retry must be positive")
+	}
+	return nil
+}
+
+func validateRuntime(cfg *RuntimeConfig) error {
+	if cfg.GCPercent < 1 {
+		return errors.New("runtime gc percent must be positive")
+	}
+	if cfg.MemoryLimit < 0 {
+		return errors.New("runtime memory limit must not be negative")
+	}
+	if cfg.MemoryLimitPercent < 1 || cfg.MemoryLimitPercent > 100 {
+		return errors.New("runtime memory limit percent must be between 1 and 100")
+	}
+	return nil
+}
+
+// validateStreamPolicies checks the policies applied to entries by NACK
+// reason or stream.
+func validateStreamPolicies(cfg *Config) error {
+	if err := validateNackPolicies(cfg); err != nil {
+		return err
+	}
+	if err := validateNackFlood(&cfg.Pipeline); err != nil {
+		return err
+	}
+	if err := validateMaxAgePolicies(cfg); err != nil {
+		return err
+	}
+	if err := validateStreamQuotas(cfg); err != nil {
+		return err
+	}
+	if err := validateThroughputSchedule(&cfg.Pipeline); err != nil {
+		return err
+	}
+	if err := validateInvalidObject(cfg); err != nil {
+		return err
+	}
+	if err := validateRedactRules(cfg); err != nil {
+		return err
+	}
+	return validateStreamOverrides(cfg)
+}
+
+// validateRedactRules checks the PipelineConfig.RedactFile rules: each
+// has a unique name and masks either a field or a pattern.
+func validateRedactRules(cfg *Config) error {
+	rules := cfg.Pipeline.RedactRules
+	if cfg.Pipeline.RedactStrict {
+		if len(rules) == 0 {
+			return errors.New("pipeline redact strict requires redact rules")
+		}
+		if cfg.Redis.DeadLetterStream == "" {
+			return errors.New("pipeline redact strict requires a redis dead letter stream")
+		}
+	}
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		if err := validateRedactRule(&rules[i]); err != nil {
+			return err
+		}
+		if seen[rules[i].Name] {
+			return fmt.Errorf("redact rule %q is defined twice", rules[i].Name)
+		}
+		seen[rules[i].Name] = true
+	}
+	return nil
+}
+
+func validateRedactRule(r *RedactRule) error {
+	if r.Name == "" {
+		return errors.New("redact rule name cannot be empty")
+	}
+	if (r.Field == "") == (r.Pattern == "") {
+		return fmt.Errorf("redact rule %q must have exactly one of field and pattern", r.Name)
+	}
+	if r.Field != "" {
+		if r.Luhn {
+			return fmt.Errorf("redact rule %q can only check luhn on a pattern", r.Name)
+		}
+		return nil
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("redact rule %q has an invalid pattern: %w", r.Name, err)
+	}
+	return nil
+}
+
+func validateInvalidObject(cfg *Config) error {
+	switch cfg.Pipeline.InvalidObject {
+	case InvalidObjectPass, InvalidObjectLenient:
+	case InvalidObjectStrict:
+		if cfg.Redis.DeadLetterStream == "" {
+			return errors.New("pipeline invalid object strict requires a redis dead letter stream")
+		}
+	default:
+		return errors.New("pipeline invalid object must be pass, strict or lenient")
+	}
+	return nil
+}
+
+func validateStreamOverrides(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Redis.StreamOverrides))
+	for i := range cfg.Redis.StreamOverrides {
+		o := &cfg.Redis.StreamOverrides[i]
+		if o.Pattern == "" {
+			return errors.New("redis stream overrides must each have a pattern")
+		}
+		if _, err := path.Match(o.Pattern, ""); err != nil {
+			return fmt.Errorf("redis stream override %q has a malformed pattern", o.Pattern)
+		}
+		if seen[o.Pattern] {
+			return fmt.Errorf("redis stream overrides list pattern %q twice", o.Pattern)
+		}
+		seen[o.Pattern] = true
+		if err := validateStreamOverride(cfg, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateStreamOverride(cfg *Config, o *StreamOverride) error {
+	if o.ClaimIdle < 0 || o.MaxAge < 0 {
+		return fmt.Errorf("redis stream override %q cannot have a negative duration", o.Pattern)
+	}
+	if o.BatchSize < 0 {
+		return fmt.Errorf("redis stream override %q cannot have a negative batch size", o.Pattern)
+	}
+	if o.QoS < -1 || o.QoS > 2 {
+		return fmt.Errorf("redis stream override %q must have a qos of 0, 1 or 2", o.Pattern)
+	}
+	if strings.ContainsAny(o.PublishTopic, "+#\x00") || (o.PublishTopic != "" && o.PublishTopic == cfg.MQTT.AckTopic) {
+		return fmt.Errorf("redis stream override %q must publish to a topic name other than the ack topic", o.Pattern)
+	}
+	if err := validateStreamOverrideInvalidObject(cfg, o); err != nil {
+		return err
+	}
+	return validateStreamOverrideMaxAge(cfg, o)
+}
+
+func validateStreamOverrideInvalidObject(cfg *Config, o *StreamOverride) error {
+	switch o.InvalidObject {
+	case "", InvalidObjectPass, InvalidObjectLenient:
+	case InvalidObjectStrict:
+		if cfg.Redis.DeadLetterStream == "" {
+			return fmt.Errorf("redis stream override %q invalid object strict requires a redis dead letter stream",
+				o.Pattern)
+		}
+	default:
+		return fmt.Errorf("redis stream override %q invalid object must be pass, strict or lenient", o.Pattern)
+	}
+	return nil
+}
+
+func validateStreamOverrideMaxAge(cfg *Config, o *StreamOverride) error {
+	switch o.MaxAgeAction {
+	case "", MaxAgeDrop:
+	case MaxAgeDLQ:
+		if cfg.Redis.DeadLetterStream == "" {
+			return fmt.Errorf("redis stream override %q max age action dlq requires a redis dead letter stream", o.Pattern)
+		}
+	default:
+		return fmt.Errorf("redis stream override %q max age action must be drop or dlq", o.Pattern)
+	}
+	if o.MaxAgeAction != "" && o.MaxAge == 0 {
+		return fmt.Errorf("redis stream override %q has a max age action without a max age", o.Pattern)
+	}
+	return nil
+}
+
+func validatePublishing(cfg *Config) error {
+	if cfg.Pipeline.OrderingKey != "" && !cfg.Pipeline.OrderedPublish {
+		return errors.New("pipeline ordering key requires ordered publish")
+	}
+	if len(cfg.MQTT.FanoutTopics) > 0 && cfg.Pipeline.MaxPayloadBytes > 0 {
+		return errors.New("mqtt fanout topics cannot be combined with pipeline max payload bytes")
+	}
+	if cfg.MQTT.SampleTopic != "" && cfg.Pipeline.MaxPayloadBytes > 0 {
+		return errors.New("mqtt sample topic cannot be combined with pipeline max payload bytes")
+	}
+	if cfg.MQTT.MaxInflight > 0 && cfg.Pipeline.OrderedPublish {
+		// Ordered workers retry a failed publish in place, which needs its outcome.
+		return errors.New("mqtt max inflight cannot be combined with ordered publish")
+	}
+	return nil
+}
+
+func validateDeadLetterUse(cfg *Config) error {
+	if cfg.Redis.DeadLetterStream != "" {
+		return nil
+	}
+	if cfg.Pipeline.OversizePolicy == OversizeDLQ && cfg.Pipeline.MaxPayloadBytes > 0 {
+		return errors.New("pipeline oversize policy dlq requires a redis dead letter stream")
+	}
+	if cfg.Pipeline.AdminDLQReplay {
+		return errors.New("pipeline admin dlq replay requires a redis dead letter stream")
+	}
+	return nil
+}
+
+func validateReceipts(cfg *Config) error {
+	if cfg.Pipeline.ReceiptQueueCapacity < 1 {
+		return errors.New("pipeline receipt queue capacity must be positive")
+	}
+	if cfg.Pipeline.ReceiptBatchSize < 1 {
+		return errors.New("pipeline receipt batch size must be positive")
+	}
+	if cfg.Pipeline.ReceiptFlushInterval <= 0 {
+		return errors.New("pipeline receipt flush interval must be positive")
+	}
+	stream := cfg.Redis.ReceiptsStream
+	if stream != "" && stream == cfg.Redis.Stream {
+		return errors.New("redis receipts stream must differ from the consumed stream")
+	}
+	if stream != "" && stream == cfg.Redis.DeadLetterStream {
+		return errors.New("redis receipts stream must differ from the dead letter stream")
+	}
+	return nil
+}
+
+func validateNackPolicies(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Pipeline.NackPolicies))
+	for _, item := range cfg.Pipeline.NackPolicies {
+		policy, err := ParseNackPolicy(item)
+		if err != nil {
+			return err
+		}
+		if seen[policy.Reason] {
+			return fmt.Errorf("pipeline nack policies list reason %q twice", policy.Reason)
+		}
+		seen[policy.Reason] = true
+		if policy.Action == NackDLQ && cfg.Redis.DeadLetterStream == "" {
+			return errors.New("pipeline nack policy dlq requires a redis dead letter stream")
+		}
+	}
+	return nil
+}
+
+func validateNackFlood(cfg *PipelineConfig) error {
+	if cfg.NackFloodThreshold < 0 {
+		return errors.New("pipeline nack flood threshold cannot be negative")
+	}
+	if cfg.NackFloodThreshold == 0 {
+		return nil
+	}
+	if cfg.NackFloodWindow <= 0 {
+		return errors.New("pipeline nack flood window must be positive")
+	}
+	if cfg.NackFloodCooldown <= 0 {
+		return errors.New("pipeline nack flood cooldown must be positive")
+	}
+	return nil
+}
+
+// ParseNackPolicy parses a PipelineConfig.NackPolicies item:
+// "reason=retry", "reason=dlq" or "reason=backoff:<duration>".
+func ParseNackPolicy(item string) (NackPolicy, error) {
+	reason, action, ok := strings.Cut(item, "=")
+	reason, action = strings.TrimSpace(reason), strings.TrimSpace(action)
+	if !ok || reason == "" {
+		return NackPolicy{}, fmt.Errorf("pipeline nack policy %q must be reason=action", item)
+	}
+	policy := NackPolicy{Reason: reason, Action: action}
+	if delay, ok := strings.CutPrefix(action, NackBackoff+":"); ok {
+		d, err := time.ParseDuration(delay)
+		if err != nil || d <= 0 {
+			return NackPolicy{}, fmt.Errorf("pipeline nack policy %q must back off for a positive duration", item)
+		}
+		policy.Action, policy.Delay = NackBackoff, d
+		return policy, nil
+	}
+	if action != NackRetry && action != NackDLQ {
+		return NackPolicy{}, fmt.Errorf("pipeline nack policy %q must be retry, dlq or backoff:<duration>", item)
+	}
+	return policy, nil
+}
+
+func validateMaxAgePolicies(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Pipeline.MaxAgePolicies))
+	for _, item := range cfg.Pipeline.MaxAgePolicies {
+		policy, err := ParseMaxAgePolicy(item)
+		if err != nil {
+			return err
+		}
+		if seen[policy.Pattern] {
+			return fmt.Errorf("pipeline max age policies list pattern %q twice", policy.Pattern)
+		}
+		seen[policy.Pattern] = true
+		if policy.Action == MaxAgeDLQ && cfg.Redis.DeadLetterStream == "" {
+			return errors.New("pipeline max age policy dlq requires a redis dead letter stream")
+		}
+	}
+	return nil
+}
+
+// ParseMaxAgePolicy parses a PipelineConfig.MaxAgePolicies item:
+// "pattern=drop:<duration>" or "pattern=dlq:<duration>".
+func ParseMaxAgePolicy(item string) (MaxAgePolicy, error) {
+	pattern, rule, ok := strings.Cut(item, "=")
+	pattern, rule = strings.TrimSpace(pattern), strings.TrimSpace(rule)
+	if !ok || pattern == "" {
+		return MaxAgePolicy{}, fmt.Errorf("pipeline max age policy %q must be pattern=action:<duration>", item)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return MaxAgePolicy{}, fmt.Errorf("pipeline max age policy %q has a malformed pattern", item)
+	}
+	action, age, _ := strings.Cut(rule, ":")
+	if action != MaxAgeDrop && action != MaxAgeDLQ {
+		return MaxAgePolicy{}, fmt.Errorf("pipeline max age policy %q must be drop:<duration> or dlq:<duration>", item)
+	}
+	d, err := time.ParseDuration(age)
+	if err != nil || d <= 0 {
+		return MaxAgePolicy{}, fmt.Errorf("pipeline max age policy %q must have a positive duration", item)
+	}
+	return MaxAgePolicy{Pattern: pattern, Action: action, MaxAge: d}, nil
+}
+
+func validateStreamQuotas(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Pipeline.StreamQuotas))
+	for _, item := range cfg.Pipeline.StreamQuotas {
+		quota, err := ParseStreamQuota(item)
+		if err != nil {
+			return err
+		}
+		if seen[quota.Pattern] {
+			return fmt.Errorf("pipeline stream quotas list pattern %q twice", quota.Pattern)
+		}
+		seen[quota.Pattern] = true
+		if quota.Action != QuotaSkip {
+			continue
+		}
+		if cfg.Redis.NoAck {
+			return errors.New("pipeline stream quota skip cannot be combined with redis noack")
+		}
+		if cfg.Pipeline.OrderedPublish {
+			return errors.New("pipeline stream quota skip cannot be combined with ordered publish")
+		}
+	}
+	return nil
+}
+
+func validateThroughputSchedule(cfg *PipelineConfig) error {
+	for _, item := range cfg.ThroughputSchedule {
+		if _, err := ParseThroughputWindow(item); err != nil {
+			return err
+		}
+	}
+	if _, err := time.LoadLocation(cfg.ThroughputLocation); err != nil {
+		return errors.New("pipeline throughput location must be a valid time zone name")
+	}
+	if cfg.ThroughputRamp < 0 {
+		return errors.New("pipeline throughput ramp must not be negative")
+	}
+	return nil
+}
+
+// ParseThroughputWindow parses a PipelineConfig.ThroughputSchedule item:
+// "<days> <HH:MM>-<HH:MM>=<entries>", where days is "*", a weekday such
+// as "sat" or a range such as "mon-fri", and an end of "24:00" is
+// midnight. A window ending before it starts runs into the next day.
+func ParseThroughputWindow(item string) (ThroughputWindow, error) {
+	spec, rate, ok := strings.Cut(item, "=")
+	fields := strings.Fields(spec)
+	if !ok || len(fields) != 2 {
+		return ThroughputWindow{}, fmt.Errorf(
+			"pipeline throughput window %q must be <days> <HH:MM>-<HH:MM>=<entries>", item)
+	}
+	var w ThroughputWindow
+	var err error
+	if w.Days, err = parseWeekdays(fields[0]); err != nil {
+		return ThroughputWindow{}, fmt.Errorf("pipeline throughput window %q: %w", item, err)
+	}
+	start, end, _ := strings.Cut(fields[1], "-")
+	w.Start, err = parseTimeOfDay(start, false)
+	if err == nil {
+		w.End, err = parseTimeOfDay(end, true)
+	}
+	if err != nil {
+		return ThroughputWindow{}, fmt.Errorf("pipeline throughput window %q: %w", item, err)
+	}
+	if w.Start == w.End {
+		return ThroughputWindow{}, fmt.Errorf("pipeline throughput window %q must not start when it ends", item)
+	}
+	if w.Rate, err = strconv.Atoi(strings.TrimSpace(rate)); err != nil || w.Rate < 1 {
+		return ThroughputWindow{}, fmt.Errorf("pipeline throughput window %q must have a positive rate", item)
+	}
+	return w, nil
+}
+
+// weekdays are the names parseWeekdays takes, indexed by time.Weekday.
+var weekdays = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseWeekdays parses "*", a weekday or a range of weekdays, which may
+// wrap past Sunday as in "fri-mon".
+func parseWeekdays(spec string) ([7]bool, error) {
+	var days [7]bool
+	if spec == "*" {
+		return [7]bool{true, true, true, true, true, true, true}, nil
+	}
+	first, last, isRange := strings.Cut(strings.ToLower(spec), "-")
+	if !isRange {
+		last = first
+	}
+	from, to := slices.Index(weekdays[:], first), slices.Index(weekdays[:], last)
+	if from < 0 || to < 0 {
+		return days, fmt.Errorf("days %q must be *, a weekday (mon, tue, ...) or a range of them", spec)
+	}
+	for d := from; ; d = (d + 1) % len(days) {
+		days[d] = true
+		if d == to {
+			return days, nil
+		}
+	}
+}
+
+// parseTimeOfDay parses "HH:MM" as an offset from midnight; "24:00" is
+// only taken as an end.
+func parseTimeOfDay(s string, end bool) (time.Duration, error) {
+	if end && s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be HH:MM, or 24:00 as an end", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseStreamQuota parses a PipelineConfig.StreamQuotas item:
+// "pattern=delay:<messages>:<bytes>" or "pattern=skip:<messages>:<bytes>",
+// per second, where 0 leaves that limit off.
+func ParseStreamQuota(item string) (StreamQuota, error) {
+	pattern, rule, ok := strings.Cut(item, "=")
+	pattern, rule = strings.TrimSpace(pattern), strings.TrimSpace(rule)
+	if !ok || pattern == "" {
+		return StreamQuota{}, fmt.Errorf("pipeline stream quota %q must be pattern=action:<messages>:<bytes>", item)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return StreamQuota{}, fmt.Errorf("pipeline stream quota %q has a malformed pattern", item)
+	}
+	parts := strings.Split(rule, ":")
+	if len(parts) != 3 || (parts[0] != QuotaDelay && parts[0] != QuotaSkip) {
+		return StreamQuota{}, fmt.Errorf(
+			"pipeline stream quota %q must be delay:<messages>:<bytes> or skip:<messages>:<bytes>", item)
+	}
+	messages, errMessages := strconv.Atoi(parts[1])
+	bytes, errBytes := strconv.Atoi(parts[2])
+	if errMessages != nil || errBytes != nil || messages < 0 || bytes < 0 || messages+bytes == 0 {
+		return StreamQuota{}, fmt.Errorf("pipeline stream quota %q must have non-negative limits, one of them positive", item)
+	}
+	return StreamQuota{Pattern: pattern, Action: parts[0], Messages: messages, Bytes: bytes}, nil
+}
+
+func validateLog(cfg *LogConfig) error {
+	switch cfg.Level {
+	case "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+	default:
+		return errors.New("log level must be one of trace, debug, info, warn, error, fatal, panic")
+	}
+	if cfg.Async && cfg.QueueSize < 1 {
+		return errors.New("log queue size must be positive")
+	}
+	if cfg.PayloadPreviewBytes < 0 {
+		return errors.New("log payload preview bytes cannot be negative")
+	}
+	return nil
+}
+
+func validateRedis(cfg *RedisConfig) error {
+	if cfg.Address == "" {
+		return errors.New("redis address cannot be empty")
+	}
+	if cfg.Consumer == "" {
+		return errors.New("redis consumer name cannot be empty")
+	}
+	if cfg.GroupName == "" {
+		return errors.New("redis group name cannot be empty")
+	}
+	if cfg.BatchSize < 1 {
+		return errors.New("redis batch size must be positive")
+	}
+	if cfg.DiscoveryScanCount < 1 {
+		return errors.New("redis discovery scan count must be positive")
+	}
+	if err := validateRedisClaims(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisAdaptiveBatch(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisMemoryMonitor(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisCompaction(cfg); err != nil {
+		return err
+	}
+	return validateRedisOptions(cfg)
+}
+
+// validateRedisClaims checks the claim loop settings. A claim interval
+// longer than the claim idle time would leave entries idle past it between
+// two cycles; zero follows the claim idle time.
+func validateRedisClaims(cfg *RedisConfig) error {
+	if cfg.ClaimConcurrency < 1 {
+		return errors.New("redis claim concurrency must be positive")
+	}
+	if cfg.ClaimInterval < 0 {
+		return errors.New("redis claim interval cannot be negative")
+	}
+	if cfg.ClaimInterval > cfg.ClaimIdle {
+		return errors.New("redis claim interval cannot exceed the claim idle time")
+	}
+	if !cfg.ClaimCoordination {
+		return nil
+	}
+	if cfg.ClaimIdle < time.Second {
+		return errors.New("redis claim idle must be at least 1s when claim coordination is enabled")
+	}
+	if cfg.ClaimInterval > 0 && cfg.ClaimInterval < time.Second {
+		return errors.New("redis claim interval must be at least 1s when claim coordination is enabled")
+	}
+	return nil
+}
+
+func validateRedisOptions(cfg *RedisConfig) error {
+	if err := validateRedisStreamFilters(cfg); err != nil {
+		return err
+	}
+	if cfg.OperationTimeout < 0 {
+		return errors.New("redis operation timeout cannot be negative")
+	}
+	if cfg.ControlKey != "" && cfg.ControlInterval <= 0 {
+		return errors.New("redis control interval must be positive")
+	}
+	if err := validateRedisLag(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisStreamLease(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisIdleStreams(cfg); err != nil {
+		return err
+	}
+	return validateRedisAuth(cfg)
+}
+
+func validateRedisLag(cfg *RedisConfig) error {
+	if cfg.LagInterval < 0 {
+		return errors.New("redis lag interval cannot be negative")
+	}
+	if cfg.LagHigh < 0 {
+		return errors.New("redis lag high cannot be negative")
+	}
+	if cfg.LagHigh > 0 && cfg.LagInterval == 0 {
+		return errors.New("redis lag high requires a redis lag interval")
+	}
+	return nil
+}
+
+func validateRedisStreamFilters(cfg *RedisConfig) error {
+	if len(cfg.StreamInclude)+len(cfg.StreamExclude) == 0 {
+		return nil
+	}
+	if cfg.Stream != "" {
+		return errors.New("redis stream include and exclude apply to multi-stream mode only")
+	}
+	for _, pattern := range slices.Concat(cfg.StreamInclude, cfg.StreamExclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("redis stream pattern %q is malformed", pattern)
+		}
+	}
+	return nil
+}
+
+func validateRedisAdaptiveBatch(cfg *RedisConfig) error {
+	if !cfg.AdaptiveBatch {
+		return nil
+	}
+	if cfg.BatchSizeMin < 1 {
+		return errors.New("redis batch size min must be positive")
+	}
+	if cfg.BatchSizeMax < cfg.BatchSizeMin {
+		return errors.New("redis batch size max must be at least the batch size min")
+	}
+	if cfg.BatchLatencyTarget <= 0 {
+		return errors.New("redis batch latency target must be positive")
+	}
+	return nil
+}
+
+func validateRedisMemoryMonitor(cfg *RedisConfig) error {
+	if cfg.MemoryHighPercent == 0 {
+		if cfg.MemoryPressureKey != "" {
+			return errors.New("redis memory pressure key requires redis memory high percent")
+		}
+		return nil
+	}
+	if cfg.MemoryHighPercent < 1 || cfg.MemoryHighPercent > 100 {
+		return errors.New("redis memory high percent must be between 1 and 100")
+	}
+	if cfg.MemoryLowPercent < 1 || cfg.MemoryLowPercent >= cfg.MemoryHighPercent {
+		return errors.New("redis memory low percent must be positive and below the high percent")
+	}
+	if cfg.MemoryCheckInterval <= 0 {
+		return errors.New("redis memory check interval must be positive")
+	}
+	return nil
+}
+
+func validateRedisCompaction(cfg *RedisConfig) error {
+	if cfg.CompactInterval < 0 {
+		return errors.New("redis compact interval cannot be negative")
+	}
+	if cfg.CompactInterval == 0 {
+		return nil
+	}
+	if cfg.CompactMinAge <= 0 {
+		return errors.New("redis compact min age must be positive")
+	}
+	if cfg.CompactBatchSize < 1 {
+		return errors.New("redis compact batch size must be positive")
+	}
+	return nil
+}
+
+func validateRedisAuth(cfg *RedisConfig) error {
+	if cfg.Username != "" && cfg.Password == "" {
+		return errors.New("redis password is required when a username is set")
+	}
+	if cfg.DB < 0 {
+		return errors.New("redis db must not be negative")
+	}
+	if cfg.DeadLetterStream != "" && cfg.DeadLetterStream == cfg.Stream {
+		return errors.New("redis dead letter stream must differ from the consumed stream")
+	}
+	if err := validateRedisBackfill(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisNoAck(cfg); err != nil {
+		return err
+	}
+	if err := validateRedisReadFields(cfg); err != nil {
+		return err
+	}
+	return validateRedisDedup(cfg)
 }
 
-func validateLog(cfg *LogConfig) error {
-	switch cfg.Level {
-	case "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+func validateRedisBackfill(cfg *RedisConfig) error {
+	if cfg.BackfillRate < 0 {
+		return errors.New("redis backfill rate must not be negative")
+	}
+	_, err := ParseBackfillPolicy(cfg.BackfillPolicy)
+	return err
+}
+
+// ParseBackfillPolicy parses RedisConfig.BackfillPolicy: "drain-first",
+// "claim-first", "fresh-only" or "interleaved:<backlog>:<fresh>", with
+// positive batch counts.
+func ParseBackfillPolicy(policy string) (BackfillSchedule, error) {
+	switch policy {
+	case BackfillDrainFirst, BackfillClaimFirst, BackfillFreshOnly:
+		return BackfillSchedule{Order: policy}, nil
+	}
+	parts := strings.Split(policy, ":")
+	if len(parts) != 3 || parts[0] != BackfillInterleaved {
+		return BackfillSchedule{}, fmt.Errorf(
+			"redis backfill policy %q must be drain-first, claim-first, interleaved:<backlog>:<fresh> or fresh-only",
+			policy)
+	}
+	backlog, errBacklog := strconv.Atoi(parts[1])
+	fresh, errFresh := strconv.Atoi(parts[2])
+	if errBacklog != nil || errFresh != nil || backlog < 1 || fresh < 1 {
+		return BackfillSchedule{}, fmt.Errorf("redis backfill policy %q must interleave positive batch counts", policy)
+	}
+	return BackfillSchedule{Order: BackfillInterleaved, Backlog: backlog, Fresh: fresh}, nil
+}
+
+// validateRedisReadFields refuses an allowlist that would leave entries
+// with nothing to publish.
+func validateRedisReadFields(cfg *RedisConfig) error {
+	if len(cfg.ReadFields) == 0 {
+		return nil
+	}
+	if !slices.Contains(cfg.ReadFields, "object") && !slices.Contains(cfg.ReadFields, "raw") {
+		return errors.New("redis read fields must include object or raw")
+	}
+	return nil
+}
+
+func validateRedisDedup(cfg *RedisConfig) error {
+	switch cfg.Dedup {
+	case "":
 		return nil
+	case DedupKeys, DedupBloom:
 	default:
-		return errors.New("log level must be one of trace, debug, info, warn, error, fatal, panic")
+		return errors.New("redis dedup must be one of keys, bloom")
+	}
+	if cfg.DedupWindow < time.Second {
+		return errors.New("redis dedup window must be at least 1s")
+	}
+	if cfg.Dedup == DedupBloom && cfg.DedupCapacity < 1 {
+		return errors.New("redis dedup capacity must be positive")
 	}
+	return nil
 }
 
-func validateRedis(cfg *RedisConfig) error {
-	if cfg.Address == "" {
-		return errors.New("redis address cannot be empty")
+// validateRedisStreamLease keeps a lease from expiring between two
+// renewals, one every third of the TTL, while a read that went out before
+// a failed renewal may still be blocked for BlockTimeout.
+func validateRedisStreamLease(cfg *RedisConfig) error {
+	if !cfg.StreamLease {
+		return nil
 	}
-	if cfg.Consumer == "" {
-		return errors.New("redis consumer name cannot be empty")
+	if cfg.StreamLeaseTTL < time.Second {
+		return errors.New("redis stream lease ttl must be at least 1s")
 	}
-	if cfg.GroupName == "" {
-		return errors.New("redis group name cannot be empty")
+	if cfg.StreamLeaseTTL <= 2*cfg.BlockTimeout {
+		return errors.New("redis stream lease ttl must be more than twice the block timeout")
 	}
-	if cfg.BatchSize < 1 {
-		return errors.New("redis batch size must be positive")
+	return nil
+}
+
+// validateRedisIdleStreams keeps a stream from being suspended for being
+// idle during a single blocking read.
+func validateRedisIdleStreams(cfg *RedisConfig) error {
+	if cfg.IdleStreamAfter < 0 {
+		return errors.New("redis idle stream after cannot be negative")
 	}
-	if cfg.DiscoveryScanCount < 1 {
-		return errors.New("redis discovery scan count must be positive")
+	if cfg.IdleStreamAfter == 0 {
+		return nil
+	}
+	if cfg.IdleStreamAfter < cfg.BlockTimeout {
+		return errors.New("redis idle stream after must be at least the block timeout")
+	}
+	if cfg.IdleStreamPoll <= 0 {
+		return errors.New("redis idle stream poll must be positive")
+	}
+	return nil
+}
+
+// validateRedisNoAck refuses the settings that rely on the pending list
+// NOACK reads leave empty.
+func validateRedisNoAck(cfg *RedisConfig) error {
+	if !cfg.NoAck {
+		return nil
+	}
+	if cfg.DeadLetterStream != "" {
+		return errors.New("redis noack cannot be combined with a dead letter stream")
+	}
+	if cfg.ClaimCoordination {
+		return errors.New("redis noack cannot be combined with claim coordination")
+	}
+	if cfg.Backfill {
+		return errors.New("redis noack cannot be combined with backfill")
 	}
 	return nil
 }
@@ -63,9 +885,276 @@ func validateMQTT(cfg *MQTTConfig) error {
 	if cfg.AckTopic == "" {
 		return errors.New("mqtt ack topic cannot be empty")
 	}
+	if cfg.StoreDir != "" && cfg.StoreMaxMessages < 1 {
+		return errors.New("mqtt store max messages must be positive when a store dir is set")
+	}
+	if err := validateMQTTLimits(cfg); err != nil {
+		return err
+	}
+	if err := validateMQTTRTT(cfg); err != nil {
+		return err
+	}
+	return validateMQTTTopics(cfg)
+}
+
+func validateMQTTLimits(cfg *MQTTConfig) error {
+	if cfg.QuarantineFailures < 0 {
+		return errors.New("mqtt quarantine failures cannot be negative")
+	}
+	if cfg.MaxInflight < 0 {
+		return errors.New("mqtt max inflight cannot be negative")
+	}
+	if cfg.ConnectAttempts < 0 {
+		return errors.New("mqtt connect attempts cannot be negative")
+	}
+	return nil
+}
+
+func validateMQTTRTT(cfg *MQTTConfig) error {
+	if cfg.RTTSampleEvery < 0 {
+		return errors.New("mqtt rtt sample every cannot be negative")
+	}
+	if cfg.RTTSampleEvery > 0 && cfg.RTTWindow < time.Second {
+		return errors.New("mqtt rtt window must be at least 1s")
+	}
+	return nil
+}
+
+func validateMQTTTopics(cfg *MQTTConfig) error {
+	for _, filter := range cfg.OverrideTopics {
+		if !validTopicFilter(filter) {
+			return errors.New("mqtt override topics must be valid topic filters")
+		}
+	}
+	if cfg.FanoutMode != FanoutBestEffort && cfg.FanoutMode != FanoutAll {
+		return errors.New("mqtt fanout mode must be best-effort or all")
+	}
+	seen := map[string]bool{cfg.PublishTopic: true, cfg.AckTopic: true}
+	for _, item := range cfg.FanoutTopics {
+		rule, err := ParseFanoutRule(item)
+		if err != nil {
+			return err
+		}
+		if seen[rule.Topic] {
+			return fmt.Errorf("mqtt fanout topic %q repeats the publish, ack or another fanout topic", rule.Topic)
+		}
+		seen[rule.Topic] = true
+	}
+	if err := validateMQTTControlTopic(cfg.ControlTopic, seen); err != nil {
+		return err
+	}
+	if cfg.ControlTopic != "" {
+		seen[cfg.ControlTopic] = true
+	}
+	return validateMQTTStatusTopic(cfg, seen)
+}
+
+// validateMQTTStatusTopic checks MQTTConfig.StatusTopic, which prefixes
+// the topic of each connection, then the lifecycle topic and the sampler
+// settings.
+func validateMQTTStatusTopic(cfg *MQTTConfig, seen map[string]bool) error {
+	if strings.ContainsAny(cfg.StatusTopic, "+#\x00") {
+		return errors.New("mqtt status topic must be a topic name without wildcards")
+	}
+	if err := validateMQTTLifecycleTopic(cfg, seen); err != nil {
+		return err
+	}
+	return validateMQTTSample(cfg, seen)
+}
+
+// validateMQTTLifecycleTopic checks MQTTConfig.LifecycleTopic against the
+// topics already in use and the sample topic.
+func validateMQTTLifecycleTopic(cfg *MQTTConfig, seen map[string]bool) error {
+	topic := cfg.LifecycleTopic
+	if topic == "" {
+		return nil
+	}
+	if strings.ContainsAny(topic, "+#\x00") {
+		return errors.New("mqtt lifecycle topic must be a topic name without wildcards")
+	}
+	if seen[topic] || topic == cfg.SampleTopic {
+		return errors.New("mqtt lifecycle topic repeats the publish, ack, control, sample or a fanout topic")
+	}
+	return nil
+}
+
+// validateMQTTControlTopic checks MQTTConfig.ControlTopic against the
+// topics already in use.
+func validateMQTTControlTopic(topic string, seen map[string]bool) error {
+	if topic == "" {
+		return nil
+	}
+	if strings.ContainsAny(topic, "+#\x00") {
+		return errors.New("mqtt control topic must be a topic name without wildcards")
+	}
+	if seen[topic] {
+		return errors.New("mqtt control topic repeats the publish, ack or a fanout topic")
+	}
+	return nil
+}
+
+// validateMQTTSample checks the sampler settings, and MQTTConfig.SampleTopic
+// against the topics already in use.
+func validateMQTTSample(cfg *MQTTConfig, seen map[string]bool) error {
+	if err := validateMQTTSampleRate(cfg); err != nil || cfg.SampleTopic == "" {
+		return err
+	}
+	if len(cfg.SampleFields) == 0 {
+		return errors.New("mqtt sample fields cannot be empty")
+	}
+	if strings.ContainsAny(cfg.SampleTopic, "+#\x00") {
+		return errors.New("mqtt sample topic must be a topic name without wildcards")
+	}
+	if seen[cfg.SampleTopic] {
+		return errors.New("mqtt sample topic repeats the publish, ack, control or a fanout topic")
+	}
+	return nil
+}
+
+func validateMQTTSampleRate(cfg *MQTTConfig) error {
+	if cfg.SamplePercent < 0 || cfg.SamplePercent > 100 {
+		return errors.New("mqtt sample percent must be between 0 and 100")
+	}
+	if cfg.SampleEvery < 0 {
+		return errors.New("mqtt sample every cannot be negative")
+	}
+	if cfg.SampleTopic == "" {
+		if cfg.SamplePercent > 0 || cfg.SampleEvery > 0 {
+			return errors.New("mqtt sample percent and sample every require a sample topic")
+		}
+		return nil
+	}
+	if (cfg.SamplePercent > 0) == (cfg.SampleEvery > 0) {
+		return errors.New("mqtt sample topic requires exactly one of sample percent and sample every")
+	}
+	return nil
+}
+
+// ParseFanoutRule parses a MQTTConfig.FanoutTopics item: "topic",
+// "topic=json" or "topic=raw".
+func ParseFanoutRule(item string) (FanoutRule, error) {
+	topic, serializer, ok := strings.Cut(item, "=")
+	rule := FanoutRule{Topic: strings.TrimSpace(topic), Serializer: strings.TrimSpace(serializer)}
+	if !ok {
+		rule.Serializer = FanoutJSON
+	}
+	if rule.Topic == "" || strings.ContainsAny(rule.Topic, "+#\x00") {
+		return FanoutRule{}, fmt.Errorf("mqtt fanout topic %q must be a topic name without wildcards", item)
+	}
+	if rule.Serializer != FanoutJSON && rule.Serializer != FanoutRaw {
+		return FanoutRule{}, fmt.Errorf("mqtt fanout topic %q must use the json or raw serializer", item)
+	}
+	return rule, nil
+}
+
+// validateAckTopic checks that the ACK subscription cannot match a topic
+// the consumer publishes to, which would feed its own payloads back to it
+// as ACKs. Override topics and an ACK topic with wildcards are compared as
+// filters: they overlap when some topic name matches both. Routes never
+// publish to the ACK topic itself, so override topics only matter against
+// an ACK topic with wildcards.
+func validateAckTopic(cfg *Config) error {
+	ack := cfg.MQTT.AckTopic
+	if !validTopicFilter(ack) {
+		return errors.New("mqtt ack topic must be a valid topic filter")
+	}
+	wildcard := strings.ContainsAny(ack, "+#")
+	for _, published := range publishedTopics(cfg) {
+		if published.filter && !wildcard {
+			continue
+		}
+		if topicFiltersOverlap(ack, published.topic) {
+			return fmt.Errorf("mqtt ack topic %q overlaps the %s %q", ack, published.kind, published.topic)
+		}
+	}
 	return nil
 }
 
+type publishedTopic struct {
+	kind   string
+	topic  string
+	filter bool
+}
+
+// publishedTopics lists the topics and topic filters entries and the
+// consumer's own messages can be published to.
+func publishedTopics(cfg *Config) []publishedTopic {
+	topics := []publishedTopic{{kind: "publish topic", topic: cfg.MQTT.PublishTopic}}
+	for _, item := range cfg.MQTT.FanoutTopics {
+		if rule, err := ParseFanoutRule(item); err == nil {
+			topics = append(topics, publishedTopic{kind: "fanout topic", topic: rule.Topic})
+		}
+	}
+	if cfg.MQTT.SampleTopic != "" {
+		topics = append(topics, publishedTopic{kind: "sample topic", topic: cfg.MQTT.SampleTopic})
+	}
+	if cfg.MQTT.ControlTopic != "" {
+		topics = append(topics, publishedTopic{kind: "control topic", topic: cfg.MQTT.ControlTopic})
+	}
+	if cfg.MQTT.StatusTopic != "" {
+		topics = append(topics, publishedTopic{kind: "status topic", topic: cfg.MQTT.StatusTopic + "/+"})
+	}
+	if cfg.MQTT.LifecycleTopic != "" {
+		topics = append(topics, publishedTopic{kind: "lifecycle topic", topic: cfg.MQTT.LifecycleTopic})
+	}
+	for _, filter := range cfg.MQTT.OverrideTopics {
+		topics = append(topics, publishedTopic{kind: "override topic", topic: filter, filter: true})
+	}
+	for i := range cfg.Redis.StreamOverrides {
+		if topic := cfg.Redis.StreamOverrides[i].PublishTopic; topic != "" {
+			topics = append(topics, publishedTopic{kind: "stream override topic", topic: topic})
+		}
+	}
+	return topics
+}
+
+// topicFiltersOverlap reports whether some topic name matches both a and b,
+// under the MQTT matching rules: "+" matches one level, a trailing "#" the
+// parent level and everything below it, and neither matches a first level
+// starting with "$".
+func topicFiltersOverlap(a, b string) bool {
+	if dollarExcluded(a, b) || dollarExcluded(b, a) {
+		return false
+	}
+	for {
+		levelA, restA, moreA := strings.Cut(a, "/")
+		levelB, restB, moreB := strings.Cut(b, "/")
+		if levelA == "#" || levelB == "#" {
+			return true
+		}
+		if levelA != "+" && levelB != "+" && levelA != levelB {
+			return false
+		}
+		if !moreA || !moreB {
+			return moreA == moreB || restA == "#" || restB == "#"
+		}
+		a, b = restA, restB
+	}
+}
+
+// dollarExcluded reports whether filter's first level only matches topics
+// starting with "$", which a leading wildcard of other never matches.
+func dollarExcluded(filter, other string) bool {
+	return strings.HasPrefix(filter, "$") && (strings.HasPrefix(other, "+") || strings.HasPrefix(other, "#"))
+}
+
+// validTopicFilter reports whether filter is a valid MQTT topic filter:
+// "+" and "#" may only fill a whole level, and "#" only the last one.
+func validTopicFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		switch {
+		case level == "#" && i == len(levels)-1, level == "+":
+		case strings.ContainsAny(level, "+#\x00"):
+			return false
+		}
+	}
+	return true
+}
+
 func validateCompress(cfg *CompressConfig) error {
 	if cfg.FreelistSize < 1 {
 		return errors.New("compress freelist size must be positive")
@@ -79,6 +1168,16 @@ func validateCompress(cfg *CompressConfig) error {
 	return nil
 }
 
+func validatePipelineHealthTLS(cfg *PipelineConfig) error {
+	if (cfg.HealthTLSCert == "") != (cfg.HealthTLSKey == "") {
+		return errors.New("pipeline health tls cert and key must be set together")
+	}
+	if cfg.HealthClientCA != "" && cfg.HealthTLSCert == "" {
+		return errors.New("pipeline health client ca requires a health tls cert and key")
+	}
+	return nil
+}
+
 func validatePipeline(cfg *PipelineConfig) error {
 	if cfg.BufferCapacity < 1 {
 		return errors.New("pipeline buffer capacity must be positive")
@@ -89,6 +1188,9 @@ func validatePipeline(cfg *PipelineConfig) error {
 	if cfg.PublishWorkers < 1 {
 		return errors.New("pipeline publish workers must be positive")
 	}
+	if err := validatePipelinePublish(cfg); err != nil {
+		return err
+	}
 	if cfg.AckWorkers < 1 {
 		return errors.New("pipeline ack workers must be positive")
 	}
@@ -101,5 +1203,235 @@ func validatePipeline(cfg *PipelineConfig) error {
 	if cfg.HealthReadHeaderTimeout <= 0 {
 		return errors.New("pipeline health read header timeout must be positive")
 	}
+	return validatePipelineFeatures(cfg)
+}
+
+// validatePipelineFeatures checks the pipeline settings that come in groups.
+// maxCaptureSize bounds PIPELINE_CAPTURE_SIZE; each slot holds a whole
+// published payload.
+const maxCaptureSize = 10000
+
+func validatePipelinePublish(cfg *PipelineConfig) error {
+	if cfg.PublishStallFactor < 0 || cfg.PublishStallFactor == 1 {
+		// A publish may wait on its token for a whole write timeout.
+		return errors.New("pipeline publish stall factor must be 0 or at least 2")
+	}
+	if cfg.PublishTimeout < 0 {
+		return errors.New("pipeline publish timeout cannot be negative")
+	}
+	if cfg.CaptureSize < 0 || cfg.CaptureSize > maxCaptureSize {
+		return fmt.Errorf("pipeline capture size must be between 0 and %d", maxCaptureSize)
+	}
+	return nil
+}
+
+func validatePipelineFeatures(cfg *PipelineConfig) error {
+	if err := validatePipelineHealthTLS(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineShutdown(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineProjection(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineSpill(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineRetry(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineStatsd(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineAckSLO(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelinePublishDedup(cfg); err != nil {
+		return err
+	}
+	if err := validatePipelineEnrich(cfg); err != nil {
+		return err
+	}
+	return validatePipelineOversize(cfg)
+}
+
+func validatePipelineRetry(cfg *PipelineConfig) error {
+	if cfg.RetryQueueCapacity < 0 {
+		return errors.New("pipeline retry queue capacity cannot be negative")
+	}
+	if cfg.RetryRate < 0 {
+		return errors.New("pipeline retry rate cannot be negative")
+	}
+	if cfg.RetryPriority != RetryPriorityLow && cfg.RetryPriority != RetryPriorityHigh {
+		return errors.New("pipeline retry priority must be low or high")
+	}
+	if cfg.RetryQueueCapacity > 0 && cfg.OrderedPublish {
+		// Ordered workers own their queues; a retry queue would reorder keys.
+		return errors.New("pipeline retry queue cannot be combined with ordered publish")
+	}
+	return nil
+}
+
+func validatePipelineSpill(cfg *PipelineConfig) error {
+	if cfg.MemoryBudget < 0 {
+		return errors.New("pipeline memory budget must not be negative")
+	}
+	if cfg.MemoryBudget == 0 {
+		return nil
+	}
+	if cfg.SpillMinBytes < 1 {
+		return errors.New("pipeline spill min bytes must be positive")
+	}
+	if cfg.SpillArenaBytes < cfg.SpillMinBytes {
+		return errors.New("pipeline spill arena bytes must be at least the spill min bytes")
+	}
+	return nil
+}
+
+func validatePipelineAckSLO(cfg *PipelineConfig) error {
+	if cfg.AckSLOLatency == 0 {
+		return nil
+	}
+	if cfg.AckSLOLatency < 0 {
+		return errors.New("pipeline ack slo latency must not be negative")
+	}
+	if !(cfg.AckSLOPercentile > 0 && cfg.AckSLOPercentile < 100) { // NaN too
+		return errors.New("pipeline ack slo percentile must be between 0 and 100, exclusive")
+	}
+	if cfg.AckSLOWindow <= 0 {
+		return errors.New("pipeline ack slo window must be positive")
+	}
+	if cfg.AckSLOWindows < 1 {
+		return errors.New("pipeline ack slo windows must be at least 1")
+	}
+	return nil
+}
+
+func validatePipelinePublishDedup(cfg *PipelineConfig) error {
+	switch cfg.PublishDedup {
+	case "":
+		return nil
+	case PublishDedupMemory, PublishDedupRedis:
+	default:
+		return errors.New("pipeline publish dedup must be one of memory, redis")
+	}
+	if cfg.PublishDedupWindow < time.Second {
+		return errors.New("pipeline publish dedup window must be at least 1s")
+	}
+	return nil
+}
+
+// maxEnrichFields bounds PIPELINE_ENRICH_FIELDS; the hot path tracks the
+// fields a payload already has in a 64-bit mask.
+const maxEnrichFields = 64
+
+func validatePipelineEnrich(cfg *PipelineConfig) error {
+	if cfg.EnrichKey == "" {
+		return nil
+	}
+	if len(cfg.EnrichFields) == 0 || len(cfg.EnrichFields) > maxEnrichFields {
+		return fmt.Errorf("pipeline enrich fields must list 1 to %d fields", maxEnrichFields)
+	}
+	for _, field := range cfg.EnrichFields {
+		if strings.ContainsFunc(field, func(r rune) bool { return r == '"' || r == '\\' || r < 0x20 }) {
+			return fmt.Errorf("pipeline enrich field %q must not contain quotes, backslashes or control characters", field)
+		}
+	}
+	if cfg.EnrichCacheTTL <= 0 {
+		return errors.New("pipeline enrich cache ttl must be positive")
+	}
+	if cfg.EnrichCacheSize < 1 {
+		return errors.New("pipeline enrich cache size must be positive")
+	}
+	return nil
+}
+
+// statsdDelimiters separate the parts of a StatsD line, and may not appear
+// in a prefix or tag.
+const statsdDelimiters = "|,#@\n"
+
+func validatePipelineStatsd(cfg *PipelineConfig) error {
+	if cfg.MetricsStatePath != "" && cfg.MetricsStateInterval <= 0 {
+		return errors.New("pipeline metrics state interval must be positive")
+	}
+	if cfg.StatsdAddr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(cfg.StatsdAddr); err != nil {
+		return errors.New("pipeline statsd addr must be host:port")
+	}
+	if cfg.StatsdInterval <= 0 {
+		return errors.New("pipeline statsd interval must be positive")
+	}
+	if strings.ContainsAny(cfg.StatsdPrefix, statsdDelimiters+":") {
+		return fmt.Errorf("pipeline statsd prefix %q must not contain any of | , # @ :", cfg.StatsdPrefix)
+	}
+	for _, tag := range cfg.StatsdTags {
+		if strings.ContainsAny(tag, statsdDelimiters) {
+			return fmt.Errorf("pipeline statsd tag %q must not contain any of | , # @", tag)
+		}
+	}
+	return nil
+}
+
+func validatePipelineShutdown(cfg *PipelineConfig) error {
+	if cfg.DrainTimeout <= 0 {
+		return errors.New("pipeline drain timeout must be positive")
+	}
+	if cfg.ShutdownTimeout > 0 && cfg.DrainTimeout >= cfg.ShutdownTimeout {
+		return errors.New("pipeline drain timeout must be shorter than the shutdown timeout")
+	}
+	if cfg.AckDrainTimeout < 0 {
+		return errors.New("pipeline ACK drain timeout must not be negative")
+	}
+	if cfg.ShutdownTimeout > 0 && cfg.DrainTimeout+EffectiveAckDrainTimeout(cfg) >= cfg.ShutdownTimeout {
+		return errors.New("pipeline drain and ACK drain timeouts together must be shorter than the shutdown timeout")
+	}
+	return nil
+}
+
+// minMaxPayloadBytes leaves room for the ID/stream header and the chunk
+// envelope of OversizeSplit.
+const minMaxPayloadBytes = 512
+
+func validatePipelineOversize(cfg *PipelineConfig) error {
+	switch cfg.OversizePolicy {
+	case OversizeTruncate, OversizeSplit, OversizeDLQ:
+	default:
+		return errors.New("pipeline oversize policy must be one of truncate, split, dlq")
+	}
+	if cfg.MaxPayloadBytes < 0 {
+		return errors.New("pipeline max payload bytes must not be negative")
+	}
+	if cfg.MaxPayloadBytes > 0 && cfg.MaxPayloadBytes < minMaxPayloadBytes {
+		return errors.New("pipeline max payload bytes must be 0 or at least 512")
+	}
+	return nil
+}
+
+func validatePipelineProjection(cfg *PipelineConfig) error {
+	if len(cfg.FieldAllowlist) > 0 && len(cfg.FieldDenylist) > 0 {
+		return errors.New("pipeline field allowlist and denylist are mutually exclusive")
+	}
+	if cfg.StructuredData != StructuredDataLeaves && cfg.StructuredData != StructuredDataPrefixed {
+		return errors.New("pipeline structured data must be leaves or prefixed")
+	}
+	return validatePipelineTimestamps(cfg)
+}
+
+func validatePipelineTimestamps(cfg *PipelineConfig) error {
+	if cfg.TimestampField == "" {
+		return nil
+	}
+	if len(cfg.TimestampLayouts) == 0 {
+		return errors.New("pipeline timestamp layouts must not be empty")
+	}
+	if _, err := time.LoadLocation(cfg.TimestampLocation); err != nil {
+		return errors.New("pipeline timestamp location must be a valid time zone name")
+	}
+	if cfg.TimestampMaxSkew <= 0 {
+		return errors.New("pipeline timestamp max skew must be positive")
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 type redisTestCase struct {
@@ -87,6 +88,27 @@ func getRedisValidationTests() []redisTestCase {
 	zeroScanCount := valid
 	zeroScanCount.DiscoveryScanCount = 0
 
+	userWithoutPassword := valid
+	userWithoutPassword.Username = "consumer-acl"
+
+	negativeDB := valid
+	negativeDB.DB = -1
+
+	deadLetterIsSource := valid
+	deadLetterIsSource.DeadLetterStream = valid.Stream
+
+	zeroClaimConcurrency := valid
+	zeroClaimConcurrency.ClaimConcurrency = 0
+
+	coordinated := valid
+	coordinated.ClaimCoordination = true
+
+	coordinatedShortClaim := coordinated
+	coordinatedShortClaim.ClaimIdle = 500 * time.Millisecond
+
+	negativeOperationTimeout := valid
+	negativeOperationTimeout.OperationTimeout = -time.Second
+
 	return []redisTestCase{
 		{name: tcValidConfig, cfg: valid, wantError: ""},
 		{name: "empty address", cfg: emptyAddress, wantError: "redis address cannot be empty"},
@@ -94,9 +116,370 @@ func getRedisValidationTests() []redisTestCase {
 		{name: "zero batch size", cfg: zeroBatch, wantError: "redis batch size must be positive"},
 		{name: "negative batch size", cfg: negativeBatch, wantError: "redis batch size must be positive"},
 		{name: "zero discovery scan count", cfg: zeroScanCount, wantError: "redis discovery scan count must be positive"},
+		{
+			name: "username without password", cfg: userWithoutPassword,
+			wantError: "redis password is required when a username is set",
+		},
+		{name: "negative db", cfg: negativeDB, wantError: "redis db must not be negative"},
+		{
+			name: "dead letter stream is the consumed stream", cfg: deadLetterIsSource,
+			wantError: "redis dead letter stream must differ from the consumed stream",
+		},
+		{
+			name: "zero claim concurrency", cfg: zeroClaimConcurrency,
+			wantError: "redis claim concurrency must be positive",
+		},
+		{name: "claim coordination", cfg: coordinated, wantError: ""},
+		{
+			name: "claim coordination with sub-second claim idle", cfg: coordinatedShortClaim,
+			wantError: "redis claim idle must be at least 1s when claim coordination is enabled",
+		},
+		{
+			name: "negative operation timeout", cfg: negativeOperationTimeout,
+			wantError: "redis operation timeout cannot be negative",
+		},
+	}
+}
+
+func TestValidateRedis_ClaimInterval(t *testing.T) {
+	shorter := defaultRedisConfig()
+	shorter.ClaimInterval = time.Second
+
+	negative := defaultRedisConfig()
+	negative.ClaimInterval = -time.Second
+
+	longer := defaultRedisConfig()
+	longer.ClaimInterval = shorter.ClaimIdle + time.Second
+
+	coordinatedShort := shorter
+	coordinatedShort.ClaimCoordination = true
+	coordinatedShort.ClaimInterval = 500 * time.Millisecond
+
+	tests := []redisTestCase{
+		{name: "shorter than claim idle", cfg: shorter, wantError: ""},
+		{name: "negative", cfg: negative, wantError: "redis claim interval cannot be negative"},
+		{name: "longer than claim idle", cfg: longer, wantError: "redis claim interval cannot exceed the claim idle time"},
+		{
+			name: "sub-second with claim coordination", cfg: coordinatedShort,
+			wantError: "redis claim interval must be at least 1s when claim coordination is enabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidationError(t, validateRedis(&tt.cfg), tt.wantError)
+		})
+	}
+}
+
+func TestParseBackfillPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    BackfillSchedule
+		wantErr bool
+	}{
+		{policy: "drain-first", want: BackfillSchedule{Order: BackfillDrainFirst}},
+		{policy: "claim-first", want: BackfillSchedule{Order: BackfillClaimFirst}},
+		{policy: "fresh-only", want: BackfillSchedule{Order: BackfillFreshOnly}},
+		{policy: "interleaved:3:1", want: BackfillSchedule{Order: BackfillInterleaved, Backlog: 3, Fresh: 1}},
+		{policy: "", wantErr: true},
+		{policy: "newest-first", wantErr: true},
+		{policy: "interleaved:3", wantErr: true},
+		{policy: "interleaved:0:1", wantErr: true},
+		{policy: "interleaved:3:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := ParseBackfillPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackfillPolicy(%q) error = %v; wantErr %v", tt.policy, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBackfillPolicy(%q) = %+v; want %+v", tt.policy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRedis_NoAck(t *testing.T) {
+	noAck := defaultRedisConfig()
+	noAck.NoAck = true
+
+	withDeadLetter := noAck
+	withDeadLetter.DeadLetterStream = "syslog-dlq"
+
+	withCoordination := noAck
+	withCoordination.ClaimCoordination = true
+
+	withBackfill := noAck
+	withBackfill.Backfill = true
+
+	tests := []redisTestCase{
+		{name: "noack", cfg: noAck, wantError: ""},
+		{
+			name: "noack with dead letter stream", cfg: withDeadLetter,
+			wantError: "redis noack cannot be combined with a dead letter stream",
+		},
+		{
+			name: "noack with claim coordination", cfg: withCoordination,
+			wantError: "redis noack cannot be combined with claim coordination",
+		},
+		{
+			name: "noack with backfill", cfg: withBackfill,
+			wantError: "redis noack cannot be combined with backfill",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidationError(t, validateRedis(&tt.cfg), tt.wantError)
+		})
 	}
 }
 
+func TestValidateRedis_Backfill(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.Backfill = true
+	cfg.BackfillRate = 100
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.BackfillRate = -1
+	checkValidationError(t, validateRedis(&cfg), "redis backfill rate must not be negative")
+}
+
+func TestValidateRedis_Dedup(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.Dedup = DedupKeys
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.Dedup = "lru"
+	checkValidationError(t, validateRedis(&cfg), "redis dedup must be one of keys, bloom")
+
+	cfg.Dedup = DedupKeys
+	cfg.DedupWindow = 0
+	checkValidationError(t, validateRedis(&cfg), "redis dedup window must be at least 1s")
+
+	cfg = defaultRedisConfig()
+	cfg.Dedup = DedupBloom
+	cfg.DedupCapacity = 0
+	checkValidationError(t, validateRedis(&cfg), "redis dedup capacity must be positive")
+}
+
+func TestValidateRedis_AdaptiveBatch(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.AdaptiveBatch = true
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.BatchSizeMin = 0
+	checkValidationError(t, validateRedis(&cfg), "redis batch size min must be positive")
+
+	cfg.BatchSizeMin = 500
+	cfg.BatchSizeMax = 100
+	checkValidationError(t, validateRedis(&cfg), "redis batch size max must be at least the batch size min")
+
+	cfg = defaultRedisConfig()
+	cfg.AdaptiveBatch = true
+	cfg.BatchLatencyTarget = 0
+	checkValidationError(t, validateRedis(&cfg), "redis batch latency target must be positive")
+
+	cfg.AdaptiveBatch = false
+	checkValidationError(t, validateRedis(&cfg), "")
+}
+
+func TestValidateRedis_MemoryMonitor(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.MemoryPressureKey = "syslog:pressure"
+	checkValidationError(t, validateRedis(&cfg), "redis memory pressure key requires redis memory high percent")
+
+	cfg.MemoryHighPercent = 90
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.MemoryHighPercent = 101
+	checkValidationError(t, validateRedis(&cfg), "redis memory high percent must be between 1 and 100")
+
+	cfg.MemoryHighPercent = 80
+	checkValidationError(t, validateRedis(&cfg), "redis memory low percent must be positive and below the high percent")
+
+	cfg.MemoryHighPercent = 90
+	cfg.MemoryCheckInterval = 0
+	checkValidationError(t, validateRedis(&cfg), "redis memory check interval must be positive")
+}
+
+func TestValidateRedis_Control(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.ControlKey = "syslog-consumer:control"
+	cfg.ControlInterval = 0
+	checkValidationError(t, validateRedis(&cfg), "redis control interval must be positive")
+
+	cfg.ControlInterval = time.Second
+	checkValidationError(t, validateRedis(&cfg), "")
+}
+
+func TestValidateRedis_StreamLease(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.StreamLeaseTTL = 0
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.StreamLease = true
+	checkValidationError(t, validateRedis(&cfg), "redis stream lease ttl must be at least 1s")
+
+	cfg.StreamLeaseTTL = 2 * time.Second
+	cfg.BlockTimeout = time.Second
+	checkValidationError(t, validateRedis(&cfg), "redis stream lease ttl must be more than twice the block timeout")
+
+	cfg.StreamLeaseTTL = 10 * time.Second
+	checkValidationError(t, validateRedis(&cfg), "")
+}
+
+func TestValidateRedis_IdleStreams(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.IdleStreamPoll = 0
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.IdleStreamAfter = -time.Second
+	checkValidationError(t, validateRedis(&cfg), "redis idle stream after cannot be negative")
+
+	cfg.IdleStreamAfter = time.Second
+	cfg.BlockTimeout = 5 * time.Second
+	checkValidationError(t, validateRedis(&cfg), "redis idle stream after must be at least the block timeout")
+
+	cfg.IdleStreamAfter = time.Minute
+	checkValidationError(t, validateRedis(&cfg), "redis idle stream poll must be positive")
+
+	cfg.IdleStreamPoll = 5 * time.Second
+	checkValidationError(t, validateRedis(&cfg), "")
+}
+
+func TestValidateRedis_Lag(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.LagHigh = 1000
+	checkValidationError(t, validateRedis(&cfg), "redis lag high requires a redis lag interval")
+
+	cfg.LagInterval = -time.Second
+	checkValidationError(t, validateRedis(&cfg), "redis lag interval cannot be negative")
+
+	cfg.LagInterval = 10 * time.Second
+	cfg.LagHigh = -1
+	checkValidationError(t, validateRedis(&cfg), "redis lag high cannot be negative")
+
+	cfg.LagHigh = 1000
+	checkValidationError(t, validateRedis(&cfg), "")
+}
+
+func TestValidateRedis_Compaction(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.CompactInterval = -time.Minute
+	checkValidationError(t, validateRedis(&cfg), "redis compact interval cannot be negative")
+
+	cfg.CompactInterval = 10 * time.Minute
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.CompactMinAge = 0
+	checkValidationError(t, validateRedis(&cfg), "redis compact min age must be positive")
+
+	cfg.CompactMinAge = time.Hour
+	cfg.CompactBatchSize = 0
+	checkValidationError(t, validateRedis(&cfg), "redis compact batch size must be positive")
+}
+
+func TestValidateMQTT_ControlTopic(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	cfg.ControlTopic = "syslog/control"
+	checkValidationError(t, validateMQTT(&cfg), "")
+
+	cfg.ControlTopic = "syslog/+"
+	checkValidationError(t, validateMQTT(&cfg), "mqtt control topic must be a topic name without wildcards")
+
+	cfg.ControlTopic = cfg.AckTopic
+	checkValidationError(t, validateMQTT(&cfg), "mqtt control topic repeats the publish, ack or a fanout topic")
+}
+
+func TestValidateMQTT_StatusTopic(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	cfg.StatusTopic = "syslog/status"
+	checkValidationError(t, validateMQTT(&cfg), "")
+
+	cfg.StatusTopic = "syslog/status/#"
+	checkValidationError(t, validateMQTT(&cfg), "mqtt status topic must be a topic name without wildcards")
+}
+
+func TestValidateMQTT_LifecycleTopic(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	cfg.LifecycleTopic = "syslog/lifecycle"
+	checkValidationError(t, validateMQTT(&cfg), "")
+
+	cfg.LifecycleTopic = "syslog/+"
+	checkValidationError(t, validateMQTT(&cfg), "mqtt lifecycle topic must be a topic name without wildcards")
+
+	cfg.LifecycleTopic = cfg.AckTopic
+	checkValidationError(t, validateMQTT(&cfg),
+		"mqtt lifecycle topic repeats the publish, ack, control, sample or a fanout topic")
+}
+
+func TestValidateMQTT_Sample(t *testing.T) {
+	cfg := defaultMQTTConfig()
+	cfg.SampleTopic = "analytics/sample"
+	cfg.SamplePercent = 5
+	checkValidationError(t, validateMQTT(&cfg), "")
+
+	cfg.SampleEvery = 10
+	checkValidationError(t, validateMQTT(&cfg),
+		"mqtt sample topic requires exactly one of sample percent and sample every")
+
+	cfg.SamplePercent = 0
+	cfg.SampleTopic = "analytics/#"
+	checkValidationError(t, validateMQTT(&cfg), "mqtt sample topic must be a topic name without wildcards")
+
+	cfg.SampleTopic = cfg.PublishTopic
+	checkValidationError(t, validateMQTT(&cfg),
+		"mqtt sample topic repeats the publish, ack, control or a fanout topic")
+
+	cfg.SampleTopic = "analytics/sample"
+	cfg.SampleFields = nil
+	checkValidationError(t, validateMQTT(&cfg), "mqtt sample fields cannot be empty")
+
+	cfg.SampleTopic = ""
+	checkValidationError(t, validateMQTT(&cfg), "mqtt sample percent and sample every require a sample topic")
+
+	cfg.SampleEvery = 0
+	cfg.SamplePercent = 101
+	checkValidationError(t, validateMQTT(&cfg), "mqtt sample percent must be between 0 and 100")
+
+	cfg.SamplePercent = 0
+	cfg.SampleEvery = -1
+	checkValidationError(t, validateMQTT(&cfg), "mqtt sample every cannot be negative")
+}
+
+func TestValidate_SampleWithMaxPayloadBytes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MQTT.SampleTopic = "analytics/sample"
+	cfg.MQTT.SampleEvery = 100
+	cfg.Pipeline.MaxPayloadBytes = 1 << 20
+	checkValidationError(t, Validate(cfg), "mqtt sample topic cannot be combined with pipeline max payload bytes")
+}
+
+func TestValidateRedis_StreamFilters(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.Stream = ""
+	cfg.StreamInclude = []string{"syslog:*"}
+	cfg.StreamExclude = []string{"syslog:test-[0-9]*"}
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.StreamExclude = []string{"syslog:[test"}
+	checkValidationError(t, validateRedis(&cfg), `redis stream pattern "syslog:[test" is malformed`)
+
+	cfg.StreamExclude = nil
+	cfg.Stream = defaultStreamName
+	checkValidationError(t, validateRedis(&cfg), "redis stream include and exclude apply to multi-stream mode only")
+}
+
+func TestValidateRedis_ReadFields(t *testing.T) {
+	cfg := defaultRedisConfig()
+	cfg.ReadFields = []string{"object", "_topic"}
+	checkValidationError(t, validateRedis(&cfg), "")
+
+	cfg.ReadFields = []string{"_topic", "host"}
+	checkValidationError(t, validateRedis(&cfg), "redis read fields must include object or raw")
+}
+
 func TestValidateMQTT(t *testing.T) {
 	for _, tt := range getMQTTValidationTests() {
 		t.Run(tt.name, func(t *testing.T) {
@@ -129,6 +512,25 @@ func getMQTTValidationTests() []mqttTestCase {
 	emptyAck := valid
 	emptyAck.AckTopic = ""
 
+	zeroStoreCap := valid
+	zeroStoreCap.StoreDir = "/data/mqtt"
+	zeroStoreCap.StoreMaxMessages = 0
+
+	negativeQuarantine := valid
+	negativeQuarantine.QuarantineFailures = -1
+
+	negativeInflight := valid
+	negativeInflight.MaxInflight = -1
+
+	negativeAttempts := valid
+	negativeAttempts.ConnectAttempts = -1
+
+	overrideTopics := valid
+	overrideTopics.OverrideTopics = []string{"alerts/#", "+/critical", "#"}
+
+	badOverrideTopic := valid
+	badOverrideTopic.OverrideTopics = []string{"alerts/#/x"}
+
 	return []mqttTestCase{
 		{name: tcValidConfig, cfg: valid, wantError: ""},
 		{name: "empty broker", cfg: emptyBroker, wantError: "mqtt broker cannot be empty"},
@@ -136,6 +538,211 @@ func getMQTTValidationTests() []mqttTestCase {
 		{name: "zero pool size", cfg: zeroPool, wantError: "mqtt pool size must be positive"},
 		{name: "empty publish topic", cfg: emptyPublish, wantError: "mqtt publish topic cannot be empty"},
 		{name: "empty ack topic", cfg: emptyAck, wantError: "mqtt ack topic cannot be empty"},
+		{
+			name: "store dir without cap", cfg: zeroStoreCap,
+			wantError: "mqtt store max messages must be positive when a store dir is set",
+		},
+		{
+			name: "negative quarantine failures", cfg: negativeQuarantine,
+			wantError: "mqtt quarantine failures cannot be negative",
+		},
+		{
+			name: "negative max inflight", cfg: negativeInflight,
+			wantError: "mqtt max inflight cannot be negative",
+		},
+		{
+			name: "negative connect attempts", cfg: negativeAttempts,
+			wantError: "mqtt connect attempts cannot be negative",
+		},
+		{name: "override topic filters", cfg: overrideTopics, wantError: ""},
+		{
+			name: "invalid override topic filter", cfg: badOverrideTopic,
+			wantError: "mqtt override topics must be valid topic filters",
+		},
+	}
+}
+
+func TestValidateMQTT_RTT(t *testing.T) {
+	cfg := getMQTTValidationTests()[0].cfg
+	cfg.RTTSampleEvery = 100
+	cfg.RTTWindow = time.Minute
+	checkValidationError(t, validateMQTT(&cfg), "")
+
+	cfg.RTTWindow = 500 * time.Millisecond
+	checkValidationError(t, validateMQTT(&cfg), "mqtt rtt window must be at least 1s")
+
+	cfg.RTTSampleEvery = -1
+	checkValidationError(t, validateMQTT(&cfg), "mqtt rtt sample every cannot be negative")
+}
+
+func TestValidateMQTT_Fanout(t *testing.T) {
+	valid := getMQTTValidationTests()[0].cfg
+	valid.FanoutTopics = []string{"archive/raw=raw", "audit/copy"}
+	checkValidationError(t, validateMQTT(&valid), "")
+
+	badMode := valid
+	badMode.FanoutMode = "some"
+	checkValidationError(t, validateMQTT(&badMode), "mqtt fanout mode must be best-effort or all")
+
+	for _, topic := range []string{"test/pub", "test/ack=raw"} {
+		repeated := valid
+		repeated.FanoutTopics = []string{"archive/raw", topic}
+		if err := validateMQTT(&repeated); err == nil || !strings.Contains(err.Error(), "repeats the publish") {
+			t.Errorf("validateMQTT(fanout %q) error = %v; want a repeated topic error", topic, err)
+		}
+	}
+
+	duplicate := valid
+	duplicate.FanoutTopics = []string{"archive/raw", "archive/raw=raw"}
+	if err := validateMQTT(&duplicate); err == nil {
+		t.Error("validateMQTT(duplicate fanout topics) error = nil; want an error")
+	}
+}
+
+func TestValidate_FanoutWithMaxPayloadBytes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MQTT.FanoutTopics = []string{"archive/raw"}
+	checkValidationError(t, Validate(cfg), "")
+
+	cfg.Pipeline.MaxPayloadBytes = 1 << 20
+	checkValidationError(t, Validate(cfg), "mqtt fanout topics cannot be combined with pipeline max payload bytes")
+}
+
+func TestParseFanoutRule(t *testing.T) {
+	for _, tt := range []struct {
+		item    string
+		want    FanoutRule
+		wantErr bool
+	}{
+		{item: "archive/copy", want: FanoutRule{Topic: "archive/copy", Serializer: FanoutJSON}},
+		{item: " archive/raw = raw ", want: FanoutRule{Topic: "archive/raw", Serializer: FanoutRaw}},
+		{item: "archive/copy=json", want: FanoutRule{Topic: "archive/copy", Serializer: FanoutJSON}},
+		{item: "archive/copy=xml", wantErr: true},
+		{item: "archive/copy=", wantErr: true},
+		{item: "archive/#", wantErr: true},
+		{item: "+/copy=raw", wantErr: true},
+		{item: "=raw", wantErr: true},
+	} {
+		got, err := ParseFanoutRule(tt.item)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFanoutRule(%q) = %+v, %v; want %+v, error %v", tt.item, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_AckTopicOverlap(t *testing.T) {
+	for _, tt := range []struct {
+		mutate    func(*Config)
+		name      string
+		wantError string
+	}{
+		{name: "Disjoint", mutate: func(*Config) {}},
+		{
+			name:      "InvalidFilter",
+			mutate:    func(c *Config) { c.MQTT.AckTopic = "acks/#/late" },
+			wantError: "mqtt ack topic must be a valid topic filter",
+		},
+		{
+			name:      "CoversPublishTopic",
+			mutate:    func(c *Config) { c.MQTT.AckTopic = "#" },
+			wantError: `mqtt ack topic "#" overlaps the publish topic "syslog/remote"`,
+		},
+		{
+			name: "CoversFanoutTopic",
+			mutate: func(c *Config) {
+				c.MQTT.AckTopic = "+/copy"
+				c.MQTT.FanoutTopics = []string{"archive/copy"}
+			},
+			wantError: `mqtt ack topic "+/copy" overlaps the fanout topic "archive/copy"`,
+		},
+		{
+			name: "OverlapsOverrideFilter",
+			mutate: func(c *Config) {
+				c.MQTT.AckTopic = "acks/+"
+				c.MQTT.OverrideTopics = []string{"+/eu"}
+			},
+			wantError: `mqtt ack topic "acks/+" overlaps the override topic "+/eu"`,
+		},
+		{
+			name: "CoversControlTopic",
+			mutate: func(c *Config) {
+				c.MQTT.AckTopic = "acks/#"
+				c.MQTT.ControlTopic = "acks/control"
+			},
+			wantError: `mqtt ack topic "acks/#" overlaps the control topic "acks/control"`,
+		},
+		{
+			name: "CoversStatusTopic",
+			mutate: func(c *Config) {
+				c.MQTT.AckTopic = "status/+"
+				c.MQTT.StatusTopic = "status"
+			},
+			wantError: `mqtt ack topic "status/+" overlaps the status topic "status/+"`,
+		},
+		{
+			name: "CoversLifecycleTopic",
+			mutate: func(c *Config) {
+				c.MQTT.AckTopic = "fleet/+"
+				c.MQTT.LifecycleTopic = "fleet/lifecycle"
+			},
+			wantError: `mqtt ack topic "fleet/+" overlaps the lifecycle topic "fleet/lifecycle"`,
+		},
+		{
+			name: "PlainAckUnderOverrideFilter",
+			mutate: func(c *Config) {
+				c.MQTT.OverrideTopics = []string{"#"}
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			tt.mutate(cfg)
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestTopicFiltersOverlap(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{a: "logs", b: "logs", want: true},
+		{a: "logs", b: "acks", want: false},
+		{a: "logs/#", b: "logs", want: true},
+		{a: "logs/+", b: "logs", want: false},
+		{a: "logs/+", b: "logs/eu", want: true},
+		{a: "+/eu", b: "logs/+", want: true},
+		{a: "logs/+/x", b: "logs/eu", want: false},
+		{a: "#", b: "$SYS/broker", want: false},
+		{a: "+/broker", b: "$SYS/broker", want: false},
+		{a: "$SYS/#", b: "$SYS/broker", want: true},
+	} {
+		if got := topicFiltersOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("topicFiltersOverlap(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := topicFiltersOverlap(tt.b, tt.a); got != tt.want {
+			t.Errorf("topicFiltersOverlap(%q, %q) = %v; want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestValidTopicFilter(t *testing.T) {
+	tests := map[string]bool{
+		"alerts":        true,
+		"alerts/#":      true,
+		"alerts/+/high": true,
+		"#":             true,
+		"":              false,
+		"alerts#":       false,
+		"alerts/#/high": false,
+		"alerts/hi+gh":  false,
+		"alerts/\x00/x": false,
+	}
+	for filter, want := range tests {
+		if got := validTopicFilter(filter); got != want {
+			t.Errorf("validTopicFilter(%q) = %v; want %v", filter, got, want)
+		}
 	}
 }
 
@@ -171,23 +778,346 @@ func getPipelineValidationTests() []pipelineTestCase {
 	zeroHealthPing := valid
 	zeroHealthPing.HealthPingTimeout = 0
 
+	zeroDrain := valid
+	zeroDrain.DrainTimeout = 0
+
+	drainPastShutdown := valid
+	drainPastShutdown.DrainTimeout = valid.ShutdownTimeout
+
+	negativeAckDrain := valid
+	negativeAckDrain.AckDrainTimeout = -time.Second
+
+	ackDrainPastShutdown := valid
+	ackDrainPastShutdown.AckDrainTimeout = valid.ShutdownTimeout
+
+	drainsPastShutdown := valid
+	drainsPastShutdown.AckDrainTimeout = valid.ShutdownTimeout - valid.DrainTimeout
+
+	badPolicy := valid
+	badPolicy.OversizePolicy = "drop"
+
+	negativeMaxPayload := valid
+	negativeMaxPayload.MaxPayloadBytes = -1
+
+	tightStall := valid
+	tightStall.PublishStallFactor = 1
+
+	negativePublishTimeout := valid
+	negativePublishTimeout.PublishTimeout = -time.Second
+
+	hugeCapture := valid
+	hugeCapture.CaptureSize = 10001
+
+	tinyMaxPayload := valid
+	tinyMaxPayload.MaxPayloadBytes = 100
+
+	bothLists := valid
+	bothLists.FieldAllowlist = []string{"hostname"}
+	bothLists.FieldDenylist = []string{"raw"}
+
 	return []pipelineTestCase{
 		{name: tcValidConfig, cfg: valid, wantError: ""},
 		{name: "zero buffer capacity", cfg: zeroBuffer, wantError: "pipeline buffer capacity must be positive"},
 		{name: "zero publish workers", cfg: zeroWorkers, wantError: "pipeline publish workers must be positive"},
 		{name: "negative buffer capacity", cfg: negativeBuffer, wantError: "pipeline buffer capacity must be positive"},
 		{name: "negative publish workers", cfg: negativeWorkers, wantError: "pipeline publish workers must be positive"},
+		{
+			name: "publish stall factor of 1", cfg: tightStall,
+			wantError: "pipeline publish stall factor must be 0 or at least 2",
+		},
+		{
+			name: "negative publish timeout", cfg: negativePublishTimeout,
+			wantError: "pipeline publish timeout cannot be negative",
+		},
+		{
+			name: "capture size over the limit", cfg: hugeCapture,
+			wantError: "pipeline capture size must be between 0 and 10000",
+		},
 		{name: "zero ack batch size", cfg: zeroAckBatch, wantError: "pipeline ack batch size must be positive"},
 		{name: "zero health ping timeout", cfg: zeroHealthPing, wantError: "pipeline health ping timeout must be positive"},
+		{name: "zero drain timeout", cfg: zeroDrain, wantError: "pipeline drain timeout must be positive"},
+		{
+			name: "drain timeout not below shutdown timeout", cfg: drainPastShutdown,
+			wantError: "pipeline drain timeout must be shorter than the shutdown timeout",
+		},
+		{
+			name: "negative ACK drain timeout", cfg: negativeAckDrain,
+			wantError: "pipeline ACK drain timeout must not be negative",
+		},
+		{
+			name: "ACK drain timeout not below shutdown timeout", cfg: ackDrainPastShutdown,
+			wantError: "pipeline drain and ACK drain timeouts together must be shorter than the shutdown timeout",
+		},
+		{
+			name: "drain timeouts together not below shutdown timeout", cfg: drainsPastShutdown,
+			wantError: "pipeline drain and ACK drain timeouts together must be shorter than the shutdown timeout",
+		},
+		{
+			name: "unknown oversize policy", cfg: badPolicy,
+			wantError: "pipeline oversize policy must be one of truncate, split, dlq",
+		},
+		{
+			name: "negative max payload bytes", cfg: negativeMaxPayload,
+			wantError: "pipeline max payload bytes must not be negative",
+		},
+		{
+			name: "max payload bytes too small", cfg: tinyMaxPayload,
+			wantError: "pipeline max payload bytes must be 0 or at least 512",
+		},
+		{
+			name: "allowlist and denylist", cfg: bothLists,
+			wantError: "pipeline field allowlist and denylist are mutually exclusive",
+		},
 	}
 }
 
+func TestValidatePipeline_Spill(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.SpillMinBytes = 0 // ignored while spilling is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.MemoryBudget = -1
+	checkValidationError(t, validatePipeline(&cfg), "pipeline memory budget must not be negative")
+
+	cfg.MemoryBudget = 1 << 20
+	checkValidationError(t, validatePipeline(&cfg), "pipeline spill min bytes must be positive")
+
+	cfg.SpillMinBytes = 4096
+	cfg.SpillArenaBytes = 1024
+	checkValidationError(t, validatePipeline(&cfg), "pipeline spill arena bytes must be at least the spill min bytes")
+
+	cfg.SpillArenaBytes = 1 << 30
+	checkValidationError(t, validatePipeline(&cfg), "")
+}
+
+func TestValidatePipeline_Retry(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.RetryQueueCapacity = -1
+	checkValidationError(t, validatePipeline(&cfg), "pipeline retry queue capacity cannot be negative")
+
+	cfg.RetryQueueCapacity = 16
+	cfg.RetryRate = -1
+	checkValidationError(t, validatePipeline(&cfg), "pipeline retry rate cannot be negative")
+
+	cfg.RetryRate = 100
+	cfg.RetryPriority = "urgent"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline retry priority must be low or high")
+
+	cfg.RetryPriority = RetryPriorityHigh
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.OrderedPublish = true
+	checkValidationError(t, validatePipeline(&cfg), "pipeline retry queue cannot be combined with ordered publish")
+}
+
+func TestValidatePipeline_StructuredData(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.StructuredData = StructuredDataPrefixed
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.StructuredData = "nested"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline structured data must be leaves or prefixed")
+}
+
+func TestValidatePipeline_Statsd(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.StatsdInterval = 0 // ignored while the exporter is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg = defaultPipelineConfig()
+	cfg.StatsdAddr = "localhost:8125"
+	cfg.StatsdPrefix = "syslog.consumer"
+	cfg.StatsdTags = []string{"env:prod", "canary"}
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.StatsdAddr = "localhost"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline statsd addr must be host:port")
+
+	cfg.StatsdAddr = "localhost:8125"
+	cfg.StatsdInterval = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline statsd interval must be positive")
+
+	cfg.StatsdInterval = time.Second
+	cfg.StatsdPrefix = "syslog:"
+	checkValidationError(t, validatePipeline(&cfg), `pipeline statsd prefix "syslog:" must not contain any of | , # @ :`)
+
+	cfg.StatsdPrefix = ""
+	cfg.StatsdTags = []string{"env|prod"}
+	checkValidationError(t, validatePipeline(&cfg), `pipeline statsd tag "env|prod" must not contain any of | , # @`)
+}
+
+func TestValidatePipeline_MetricsState(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.MetricsStateInterval = 0 // ignored without a state file
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.MetricsStatePath = "/tmp/counters.json"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline metrics state interval must be positive")
+
+	cfg.MetricsStateInterval = time.Minute
+	checkValidationError(t, validatePipeline(&cfg), "")
+}
+
+func TestValidatePipeline_AckSLO(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.AckSLOWindow = 0 // ignored while the tracking is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg = defaultPipelineConfig()
+	cfg.AckSLOLatency = 2 * time.Second
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.AckSLOLatency = -time.Second
+	checkValidationError(t, validatePipeline(&cfg), "pipeline ack slo latency must not be negative")
+
+	cfg.AckSLOLatency = 2 * time.Second
+	cfg.AckSLOPercentile = 100
+	checkValidationError(t, validatePipeline(&cfg), "pipeline ack slo percentile must be between 0 and 100, exclusive")
+
+	cfg.AckSLOPercentile = 99.9
+	cfg.AckSLOWindow = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline ack slo window must be positive")
+
+	cfg.AckSLOWindow = time.Minute
+	cfg.AckSLOWindows = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline ack slo windows must be at least 1")
+}
+
+func TestValidatePipeline_PublishDedup(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.PublishDedupWindow = 0 // ignored while dedup is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg = defaultPipelineConfig()
+	cfg.PublishDedup = PublishDedupRedis
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.PublishDedup = "disk"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline publish dedup must be one of memory, redis")
+
+	cfg.PublishDedup = PublishDedupMemory
+	cfg.PublishDedupWindow = 500 * time.Millisecond
+	checkValidationError(t, validatePipeline(&cfg), "pipeline publish dedup window must be at least 1s")
+}
+
+func TestValidatePipeline_Enrich(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.EnrichCacheSize = 0 // ignored while enrichment is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg = defaultPipelineConfig()
+	cfg.EnrichKey = "host"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline enrich fields must list 1 to 64 fields")
+
+	cfg.EnrichFields = []string{"site", "ra\"ck"}
+	checkValidationError(t, validatePipeline(&cfg),
+		`pipeline enrich field "ra\"ck" must not contain quotes, backslashes or control characters`)
+
+	cfg.EnrichFields = []string{"site", "rack"}
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.EnrichCacheTTL = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline enrich cache ttl must be positive")
+
+	cfg.EnrichCacheTTL = time.Minute
+	cfg.EnrichCacheSize = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline enrich cache size must be positive")
+}
+
+func TestValidatePipeline_Timestamps(t *testing.T) {
+	cfg := defaultPipelineConfig()
+	cfg.TimestampLayouts = nil // ignored while normalization is off
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg = defaultPipelineConfig()
+	cfg.TimestampField = "timestamp"
+	cfg.TimestampLocation = "Europe/Rome"
+	checkValidationError(t, validatePipeline(&cfg), "")
+
+	cfg.TimestampLayouts = nil
+	checkValidationError(t, validatePipeline(&cfg), "pipeline timestamp layouts must not be empty")
+
+	cfg = defaultPipelineConfig()
+	cfg.TimestampField = "timestamp"
+	cfg.TimestampLocation = "Mars/Olympus_Mons"
+	checkValidationError(t, validatePipeline(&cfg), "pipeline timestamp location must be a valid time zone name")
+
+	cfg = defaultPipelineConfig()
+	cfg.TimestampField = "timestamp"
+	cfg.TimestampMaxSkew = 0
+	checkValidationError(t, validatePipeline(&cfg), "pipeline timestamp max skew must be positive")
+}
+
 type compressTestCase struct {
 	name      string
 	wantError string
 	cfg       CompressConfig
 }
 
+func TestValidateRuntime(t *testing.T) {
+	cfg := defaultRuntimeConfig()
+	checkValidationError(t, validateRuntime(&cfg), "")
+
+	cfg.GCPercent = 0
+	checkValidationError(t, validateRuntime(&cfg), "runtime gc percent must be positive")
+
+	cfg = defaultRuntimeConfig()
+	cfg.MemoryLimit = -1
+	checkValidationError(t, validateRuntime(&cfg), "runtime memory limit must not be negative")
+
+	cfg = defaultRuntimeConfig()
+	cfg.MemoryLimitPercent = 101
+	checkValidationError(t, validateRuntime(&cfg), "runtime memory limit percent must be between 1 and 100")
+}
+
+func TestValidateArchive(t *testing.T) {
+	cfg := defaultArchiveConfig()
+	checkValidationError(t, validateArchive(&cfg), "")
+
+	valid := defaultArchiveConfig()
+	valid.Mode, valid.Dir = ArchiveCopy, "/var/lib/archive"
+	checkValidationError(t, validateArchive(&valid), "")
+
+	for _, tt := range []struct {
+		modify    func(*ArchiveConfig)
+		wantError string
+	}{
+		{func(c *ArchiveConfig) { c.Mode = "mirror" }, "archive mode must be empty, copy or only"},
+		{func(c *ArchiveConfig) { c.Dir = "" }, "archive dir cannot be empty"},
+		{func(c *ArchiveConfig) { c.MaxBytes = 0 }, "archive max bytes must be positive"},
+		{func(c *ArchiveConfig) { c.MaxAge = 0 }, "archive max age must be positive"},
+	} {
+		cfg := valid
+		tt.modify(&cfg)
+		checkValidationError(t, validateArchive(&cfg), tt.wantError)
+	}
+}
+
+func TestValidateArchiveS3(t *testing.T) {
+	valid := defaultArchiveConfig()
+	valid.Mode, valid.Dir = ArchiveOnly, "/var/lib/archive"
+	valid.S3Endpoint, valid.S3Bucket = "https://s3.eu-west-1.amazonaws.com", "logs"
+	valid.S3AccessKeyID, valid.S3SecretAccessKey = "AKID", "secret"
+	checkValidationError(t, validateArchive(&valid), "")
+
+	for _, tt := range []struct {
+		modify    func(*ArchiveConfig)
+		wantError string
+	}{
+		{func(c *ArchiveConfig) { c.S3Bucket = "" }, "archive s3 bucket cannot be empty with an s3 endpoint"},
+		{func(c *ArchiveConfig) { c.S3Endpoint = "" }, "archive s3 endpoint must be an http or https URL"},
+		{func(c *ArchiveConfig) { c.S3Endpoint = "s3.amazonaws.com" }, "archive s3 endpoint must be an http or https URL"},
+		{func(c *ArchiveConfig) { c.S3SecretAccessKey = "" }, "archive s3 access key id and secret access key must be set"},
+		{func(c *ArchiveConfig) { c.S3Region = "" }, "archive s3 region cannot be empty"},
+		{func(c *ArchiveConfig) { c.S3PartBytes = 1 << 20 }, "archive s3 part bytes must be at least 5242880"},
+		{func(c *ArchiveConfig) { c.UploadRetry = 0 }, "archive upload retry must be positive"},
+	} {
+		cfg := valid
+		tt.modify(&cfg)
+		checkValidationError(t, validateArchive(&cfg), tt.wantError)
+	}
+}
+
 func TestValidateCompress(t *testing.T) {
 	valid := defaultCompressConfig()
 
@@ -219,6 +1149,466 @@ func TestValidateCompress(t *testing.T) {
 	}
 }
 
+func TestValidate_OversizeDLQNeedsStream(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Pipeline.MaxPayloadBytes = 65536
+	cfg.Pipeline.OversizePolicy = OversizeDLQ
+
+	err := Validate(cfg)
+	checkValidationError(t, err, "pipeline oversize policy dlq requires a redis dead letter stream")
+
+	cfg.Redis.DeadLetterStream = "syslog-dlq"
+	checkValidationError(t, Validate(cfg), "")
+}
+
+func TestValidate_AdminDLQReplayNeedsStream(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Pipeline.AdminDLQReplay = true
+	checkValidationError(t, Validate(cfg), "pipeline admin dlq replay requires a redis dead letter stream")
+
+	cfg.Redis.DeadLetterStream = "syslog-dlq"
+	checkValidationError(t, Validate(cfg), "")
+}
+
+func TestValidate_OrderingKeyNeedsOrderedPublish(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Pipeline.OrderingKey = "hostname"
+	checkValidationError(t, Validate(cfg), "pipeline ordering key requires ordered publish")
+
+	cfg.Pipeline.OrderedPublish = true
+	checkValidationError(t, Validate(cfg), "")
+
+	cfg.MQTT.MaxInflight = 32
+	checkValidationError(t, Validate(cfg), "mqtt max inflight cannot be combined with ordered publish")
+}
+
+func TestValidate_Receipts(t *testing.T) {
+	zeroQueue := defaultConfig()
+	zeroQueue.Pipeline.ReceiptQueueCapacity = 0
+	zeroBatch := defaultConfig()
+	zeroBatch.Pipeline.ReceiptBatchSize = 0
+	zeroFlush := defaultConfig()
+	zeroFlush.Pipeline.ReceiptFlushInterval = 0
+	receiptsIsSource := defaultConfig()
+	receiptsIsSource.Redis.ReceiptsStream = receiptsIsSource.Redis.Stream
+	receiptsIsDeadLetter := defaultConfig()
+	receiptsIsDeadLetter.Redis.DeadLetterStream = "syslog-dlq"
+	receiptsIsDeadLetter.Redis.ReceiptsStream = "syslog-dlq"
+	enabled := defaultConfig()
+	enabled.Redis.ReceiptsStream = "syslog-receipts"
+
+	for _, tt := range []struct {
+		cfg       *Config
+		name      string
+		wantError string
+	}{
+		{name: "enabled", cfg: enabled, wantError: ""},
+		{name: "zero queue capacity", cfg: zeroQueue, wantError: "pipeline receipt queue capacity must be positive"},
+		{name: "zero batch size", cfg: zeroBatch, wantError: "pipeline receipt batch size must be positive"},
+		{name: "zero flush interval", cfg: zeroFlush, wantError: "pipeline receipt flush interval must be positive"},
+		{name: "receipts stream is source", cfg: receiptsIsSource,
+			wantError: "redis receipts stream must differ from the consumed stream"},
+		{name: "receipts stream is dead letter stream", cfg: receiptsIsDeadLetter,
+			wantError: "redis receipts stream must differ from the dead letter stream"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidationError(t, Validate(tt.cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_NackPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		wantError  string
+		deadLetter string
+		policies   []string
+	}{
+		{name: "retry and backoff", policies: []string{"timeout=retry", "overloaded=backoff:5m"}},
+		{name: "dlq with stream", policies: []string{"schema_error=dlq"}, deadLetter: "syslog-dlq"},
+		{name: "dlq without stream", policies: []string{"schema_error=dlq"},
+			wantError: "pipeline nack policy dlq requires a redis dead letter stream"},
+		{name: "reason listed twice", policies: []string{"a=retry", "a=backoff:1s"},
+			wantError: `pipeline nack policies list reason "a" twice`},
+		{name: "unknown action", policies: []string{"a=drop"},
+			wantError: `pipeline nack policy "a=drop" must be retry, dlq or backoff:<duration>`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.NackPolicies = tt.policies
+			cfg.Redis.DeadLetterStream = tt.deadLetter
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestParseNackPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		item    string
+		want    NackPolicy
+		wantErr bool
+	}{
+		{item: "timeout=retry", want: NackPolicy{Reason: "timeout", Action: NackRetry}},
+		{item: " schema_error = dlq ", want: NackPolicy{Reason: "schema_error", Action: NackDLQ}},
+		{item: "overloaded=backoff:5m", want: NackPolicy{Reason: "overloaded", Action: NackBackoff, Delay: 5 * time.Minute}},
+		{item: "overloaded=backoff", wantErr: true},
+		{item: "overloaded=backoff:-1s", wantErr: true},
+		{item: "overloaded=backoff:soon", wantErr: true},
+		{item: "=dlq", wantErr: true},
+		{item: "dlq", wantErr: true},
+	} {
+		got, err := ParseNackPolicy(tt.item)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseNackPolicy(%q) = %+v, %v; want %+v, error %v", tt.item, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateLog_AsyncQueueSize(t *testing.T) {
+	cfg := LogConfig{Level: "info", Async: true}
+	checkValidationError(t, validateLog(&cfg), "log queue size must be positive")
+
+	cfg.QueueSize = 1
+	checkValidationError(t, validateLog(&cfg), "")
+
+	cfg.PayloadPreviewBytes = -1
+	checkValidationError(t, validateLog(&cfg), "log payload preview bytes cannot be negative")
+}
+
+func TestValidate_MaxAgePolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		wantError  string
+		deadLetter string
+		policies   []string
+	}{
+		{name: "drop", policies: []string{"debug-*=drop:15m", "*=drop:24h"}},
+		{name: "dlq with stream", policies: []string{"audit-*=dlq:1h"}, deadLetter: "syslog-dlq"},
+		{name: "dlq without stream", policies: []string{"audit-*=dlq:1h"},
+			wantError: "pipeline max age policy dlq requires a redis dead letter stream"},
+		{name: "pattern listed twice", policies: []string{"a*=drop:1m", "a*=drop:2m"},
+			wantError: `pipeline max age policies list pattern "a*" twice`},
+		{name: "malformed pattern", policies: []string{"[a=drop:1m"},
+			wantError: `pipeline max age policy "[a=drop:1m" has a malformed pattern`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.MaxAgePolicies = tt.policies
+			cfg.Redis.DeadLetterStream = tt.deadLetter
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_StreamOverrides(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		wantError  string
+		deadLetter string
+		overrides  []StreamOverride
+	}{
+		{name: "valid", overrides: []StreamOverride{
+			{Pattern: "fw-*", PublishTopic: "syslog/fw", ClaimIdle: time.Minute, BatchSize: 10, QoS: 2},
+			{Pattern: "*", MaxAge: time.Hour, QoS: -1},
+		}},
+		{name: "dlq with stream", deadLetter: "syslog-dlq",
+			overrides: []StreamOverride{{Pattern: "a*", MaxAge: time.Hour, MaxAgeAction: MaxAgeDLQ, QoS: -1}}},
+		{name: "dlq without stream",
+			overrides: []StreamOverride{{Pattern: "a*", MaxAge: time.Hour, MaxAgeAction: MaxAgeDLQ, QoS: -1}},
+			wantError: `redis stream override "a*" max age action dlq requires a redis dead letter stream`},
+		{name: "strict with stream", deadLetter: "syslog-dlq",
+			overrides: []StreamOverride{{Pattern: "a*", InvalidObject: InvalidObjectStrict, QoS: -1}}},
+		{name: "strict without stream",
+			overrides: []StreamOverride{{Pattern: "a*", InvalidObject: InvalidObjectStrict, QoS: -1}},
+			wantError: `redis stream override "a*" invalid object strict requires a redis dead letter stream`},
+		{name: "unknown invalid object mode",
+			overrides: []StreamOverride{{Pattern: "a*", InvalidObject: "fix", QoS: -1}},
+			wantError: `redis stream override "a*" invalid object must be pass, strict or lenient`},
+		{name: "unknown action",
+			overrides: []StreamOverride{{Pattern: "a*", MaxAge: time.Hour, MaxAgeAction: "retry", QoS: -1}},
+			wantError: `redis stream override "a*" max age action must be drop or dlq`},
+		{name: "action without max age", overrides: []StreamOverride{{Pattern: "a*", MaxAgeAction: MaxAgeDrop, QoS: -1}},
+			wantError: `redis stream override "a*" has a max age action without a max age`},
+		{name: "no pattern", overrides: []StreamOverride{{QoS: -1}},
+			wantError: "redis stream overrides must each have a pattern"},
+		{name: "malformed pattern", overrides: []StreamOverride{{Pattern: "[a", QoS: -1}},
+			wantError: `redis stream override "[a" has a malformed pattern`},
+		{name: "pattern listed twice", overrides: []StreamOverride{{Pattern: "a*", QoS: -1}, {Pattern: "a*", QoS: -1}},
+			wantError: `redis stream overrides list pattern "a*" twice`},
+		{name: "negative duration", overrides: []StreamOverride{{Pattern: "a*", ClaimIdle: -time.Second, QoS: -1}},
+			wantError: `redis stream override "a*" cannot have a negative duration`},
+		{name: "negative batch size", overrides: []StreamOverride{{Pattern: "a*", BatchSize: -1, QoS: -1}},
+			wantError: `redis stream override "a*" cannot have a negative batch size`},
+		{name: "bad qos", overrides: []StreamOverride{{Pattern: "a*", QoS: 3}},
+			wantError: `redis stream override "a*" must have a qos of 0, 1 or 2`},
+		{name: "wildcard topic", overrides: []StreamOverride{{Pattern: "a*", PublishTopic: "syslog/+", QoS: -1}},
+			wantError: `redis stream override "a*" must publish to a topic name other than the ack topic`},
+		{name: "ack topic", overrides: []StreamOverride{{Pattern: "a*", PublishTopic: defaultMQTTAckTopic, QoS: -1}},
+			wantError: `redis stream override "a*" must publish to a topic name other than the ack topic`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Redis.StreamOverrides = tt.overrides
+			cfg.Redis.DeadLetterStream = tt.deadLetter
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_InvalidObject(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		mode       string
+		deadLetter string
+		wantError  string
+	}{
+		{name: "pass", mode: InvalidObjectPass},
+		{name: "lenient", mode: InvalidObjectLenient},
+		{name: "strict with stream", mode: InvalidObjectStrict, deadLetter: "syslog-dlq"},
+		{name: "strict without stream", mode: InvalidObjectStrict,
+			wantError: "pipeline invalid object strict requires a redis dead letter stream"},
+		{name: "unknown mode", mode: "fix", wantError: "pipeline invalid object must be pass, strict or lenient"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.InvalidObject = tt.mode
+			cfg.Redis.DeadLetterStream = tt.deadLetter
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_RedactRules(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		deadLetter string
+		wantError  string
+		rules      []RedactRule
+		strict     bool
+	}{
+		{name: "none"},
+		{name: "field and pattern", rules: []RedactRule{
+			{Name: "password", Field: "password"}, {Name: "card", Pattern: `[0-9]{13,19}`, Luhn: true},
+		}},
+		{name: "strict", strict: true, deadLetter: "syslog-dlq", rules: []RedactRule{{Name: "token", Field: "token"}}},
+		{name: "strict without rules", strict: true, deadLetter: "syslog-dlq",
+			wantError: "pipeline redact strict requires redact rules"},
+		{name: "strict without stream", strict: true, rules: []RedactRule{{Name: "token", Field: "token"}},
+			wantError: "pipeline redact strict requires a redis dead letter stream"},
+		{name: "no name", rules: []RedactRule{{Field: "token"}}, wantError: "redact rule name cannot be empty"},
+		{name: "neither", rules: []RedactRule{{Name: "token"}},
+			wantError: `redact rule "token" must have exactly one of field and pattern`},
+		{name: "both", rules: []RedactRule{{Name: "token", Field: "token", Pattern: "tok"}},
+			wantError: `redact rule "token" must have exactly one of field and pattern`},
+		{name: "luhn on a field", rules: []RedactRule{{Name: "card", Field: "card", Luhn: true}},
+			wantError: `redact rule "card" can only check luhn on a pattern`},
+		{name: "bad pattern", rules: []RedactRule{{Name: "card", Pattern: "[0-9"}},
+			wantError: "redact rule \"card\" has an invalid pattern: error parsing regexp: missing closing ]: `[0-9`"},
+		{name: "duplicate", rules: []RedactRule{{Name: "token", Field: "token"}, {Name: "token", Field: "secret"}},
+			wantError: `redact rule "token" is defined twice`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.RedactRules = tt.rules
+			cfg.Pipeline.RedactStrict = tt.strict
+			cfg.Redis.DeadLetterStream = tt.deadLetter
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestParseMaxAgePolicy(t *testing.T) {
+	for _, tt := range []struct {
+		item    string
+		want    MaxAgePolicy
+		wantErr bool
+	}{
+		{item: "debug-*=drop:15m", want: MaxAgePolicy{Pattern: "debug-*", Action: MaxAgeDrop, MaxAge: 15 * time.Minute}},
+		{item: " audit = dlq:1h ", want: MaxAgePolicy{Pattern: "audit", Action: MaxAgeDLQ, MaxAge: time.Hour}},
+		{item: "audit=drop", wantErr: true},
+		{item: "audit=drop:0s", wantErr: true},
+		{item: "audit=retry:1m", wantErr: true},
+		{item: "=drop:1m", wantErr: true},
+		{item: "drop:1m", wantErr: true},
+	} {
+		got, err := ParseMaxAgePolicy(tt.item)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMaxAgePolicy(%q) = %+v, %v; want %+v, error %v", tt.item, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_HealthTLS(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		cert      string
+		key       string
+		clientCA  string
+		wantError string
+	}{
+		{name: "plaintext"},
+		{name: "tls", cert: "health.crt", key: "health.key"},
+		{name: "mutual tls", cert: "health.crt", key: "health.key", clientCA: "clients.pem"},
+		{name: "cert without key", cert: "health.crt",
+			wantError: "pipeline health tls cert and key must be set together"},
+		{name: "key without cert", key: "health.key",
+			wantError: "pipeline health tls cert and key must be set together"},
+		{name: "client ca without tls", clientCA: "clients.pem",
+			wantError: "pipeline health client ca requires a health tls cert and key"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.HealthTLSCert = tt.cert
+			cfg.Pipeline.HealthTLSKey = tt.key
+			cfg.Pipeline.HealthClientCA = tt.clientCA
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_NackFlood(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		wantError string
+		threshold int
+		window    time.Duration
+		cooldown  time.Duration
+	}{
+		{name: "disabled ignores window", window: 0, cooldown: 0},
+		{name: "enabled", threshold: 100, window: time.Second, cooldown: time.Minute},
+		{name: "negative threshold", threshold: -1, window: time.Second, cooldown: time.Minute,
+			wantError: "pipeline nack flood threshold cannot be negative"},
+		{name: "zero window", threshold: 100, cooldown: time.Minute,
+			wantError: "pipeline nack flood window must be positive"},
+		{name: "zero cooldown", threshold: 100, window: time.Second,
+			wantError: "pipeline nack flood cooldown must be positive"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.NackFloodThreshold = tt.threshold
+			cfg.Pipeline.NackFloodWindow = tt.window
+			cfg.Pipeline.NackFloodCooldown = tt.cooldown
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_StreamQuotas(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		wantError string
+		quotas    []string
+		noAck     bool
+		ordered   bool
+	}{
+		{name: "delay", quotas: []string{"noisy-*=delay:500:0", "*=delay:0:1048576"}},
+		{name: "delay ordered", quotas: []string{"noisy-*=delay:500:0"}, ordered: true},
+		{name: "skip", quotas: []string{"noisy-*=skip:500:1048576"}},
+		{name: "skip with noack", quotas: []string{"noisy-*=skip:500:0"}, noAck: true,
+			wantError: "pipeline stream quota skip cannot be combined with redis noack"},
+		{name: "skip ordered", quotas: []string{"noisy-*=skip:500:0"}, ordered: true,
+			wantError: "pipeline stream quota skip cannot be combined with ordered publish"},
+		{name: "pattern listed twice", quotas: []string{"a*=delay:1:0", "a*=skip:2:0"},
+			wantError: `pipeline stream quotas list pattern "a*" twice`},
+		{name: "malformed pattern", quotas: []string{"[a=delay:1:0"},
+			wantError: `pipeline stream quota "[a=delay:1:0" has a malformed pattern`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.StreamQuotas = tt.quotas
+			cfg.Redis.NoAck = tt.noAck
+			cfg.Pipeline.OrderedPublish = tt.ordered
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestParseStreamQuota(t *testing.T) {
+	for _, tt := range []struct {
+		item    string
+		want    StreamQuota
+		wantErr bool
+	}{
+		{item: "noisy-*=delay:500:0", want: StreamQuota{Pattern: "noisy-*", Action: QuotaDelay, Messages: 500}},
+		{item: " audit = skip:0:65536 ", want: StreamQuota{Pattern: "audit", Action: QuotaSkip, Bytes: 65536}},
+		{item: "audit=delay:0:0", wantErr: true},
+		{item: "audit=delay:-1:10", wantErr: true},
+		{item: "audit=delay:10", wantErr: true},
+		{item: "audit=drop:10:0", wantErr: true},
+		{item: "audit=delay:ten:0", wantErr: true},
+		{item: "=delay:1:0", wantErr: true},
+		{item: "delay:1:0", wantErr: true},
+	} {
+		got, err := ParseStreamQuota(tt.item)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStreamQuota(%q) = %+v, %v; want %+v, error %v", tt.item, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_ThroughputSchedule(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		wantError string
+		location  string
+		schedule  []string
+		ramp      time.Duration
+	}{
+		{name: "windows", schedule: []string{"mon-fri 08:00-18:00=20000", "sat-sun 22:00-06:00=5000"}},
+		{name: "no ramp", schedule: []string{"* 00:00-24:00=1000"}},
+		{name: "location", schedule: []string{"* 08:00-18:00=1000"}, location: "Europe/Rome"},
+		{name: "bad location", schedule: []string{"* 08:00-18:00=1000"}, location: "Mars/Olympus",
+			wantError: "pipeline throughput location must be a valid time zone name"},
+		{name: "negative ramp", schedule: []string{"* 08:00-18:00=1000"}, ramp: -time.Second,
+			wantError: "pipeline throughput ramp must not be negative"},
+		{name: "bad window", schedule: []string{"weekdays 08:00-18:00=1000"},
+			wantError: `pipeline throughput window "weekdays 08:00-18:00=1000": ` +
+				`days "weekdays" must be *, a weekday (mon, tue, ...) or a range of them`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Pipeline.ThroughputSchedule = tt.schedule
+			cfg.Pipeline.ThroughputRamp = tt.ramp
+			if tt.location != "" {
+				cfg.Pipeline.ThroughputLocation = tt.location
+			}
+			checkValidationError(t, Validate(cfg), tt.wantError)
+		})
+	}
+}
+
+func TestParseThroughputWindow(t *testing.T) {
+	weekdays := [7]bool{false, true, true, true, true, true, false}
+	weekend := [7]bool{true, false, false, false, false, true, true}
+	every := [7]bool{true, true, true, true, true, true, true}
+	for _, tt := range []struct {
+		item    string
+		want    ThroughputWindow
+		wantErr bool
+	}{
+		{item: "mon-fri 08:00-18:00=20000",
+			want: ThroughputWindow{Days: weekdays, Start: 8 * time.Hour, End: 18 * time.Hour, Rate: 20000}},
+		{item: " fri-sun  22:30-06:00 = 500 ",
+			want: ThroughputWindow{Days: weekend, Start: 22*time.Hour + 30*time.Minute, End: 6 * time.Hour, Rate: 500}},
+		{item: "* 00:00-24:00=1", want: ThroughputWindow{Days: every, End: 24 * time.Hour, Rate: 1}},
+		{item: "Wed 12:00-13:00=10",
+			want: ThroughputWindow{Days: [7]bool{3: true}, Start: 12 * time.Hour, End: 13 * time.Hour, Rate: 10}},
+		{item: "mon-fri 08:00-18:00=0", wantErr: true},
+		{item: "mon-fri 08:00-18:00=fast", wantErr: true},
+		{item: "mon-fri 08:00-08:00=10", wantErr: true},
+		{item: "mon-fri 24:00-08:00=10", wantErr: true},
+		{item: "mon-fri 8am-6pm=10", wantErr: true},
+		{item: "mon-fri 08:00=10", wantErr: true},
+		{item: "weekdays 08:00-18:00=10", wantErr: true},
+		{item: "08:00-18:00=10", wantErr: true},
+	} {
+		got, err := ParseThroughputWindow(tt.item)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseThroughputWindow(%q) = %+v, %v; want %+v, error %v", tt.item, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
 func TestValidate_CompressError(t *testing.T) {
 	cfg := defaultConfig()
 	cfg.Compress.FreelistSize = 0
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Profiles selectable with APP_PROFILE. Each pre-tunes the buffer sizes,
+// batch sizes, worker counts, pools and claim interval for a kind of
+// deployment; the environment and flags still override what it sets.
+const (
+	ProfileEdge       = "edge"
+	ProfileDatacenter = "datacenter"
+	ProfileDev        = "dev"
+)
+
+// profiles maps each profile to the defaults it tunes.
+var profiles = map[string]func(*Config){
+	// edge suits a small gateway with a few cores and little memory.
+	ProfileEdge: func(cfg *Config) {
+		cfg.Redis.BatchSize = 1000
+		cfg.Redis.BatchSizeMax = 1000
+		cfg.Redis.PoolSize = 16
+		cfg.Redis.MinIdleConns = 2
+		cfg.Redis.ClaimConcurrency = 2
+		cfg.Redis.ClaimIdle = 30 * time.Second
+		cfg.MQTT.PoolSize = 2
+		cfg.Pipeline.BufferCapacity = 2000
+		cfg.Pipeline.MessageQueueCapacity = 50
+		cfg.Pipeline.PublishWorkers = 4
+		cfg.Pipeline.AckWorkers = 8
+	},
+	// datacenter suits a dedicated host draining busy streams.
+	ProfileDatacenter: func(cfg *Config) {
+		cfg.Redis.BatchSize = 20000
+		cfg.Redis.BatchSizeMax = 50000
+		cfg.Redis.PoolSize = 200
+		cfg.Redis.MinIdleConns = 32
+		cfg.Redis.ClaimConcurrency = 16
+		cfg.Redis.ClaimIdle = 10 * time.Second
+		cfg.MQTT.PoolSize = 32
+		cfg.Pipeline.BufferCapacity = 50000
+		cfg.Pipeline.MessageQueueCapacity = 2000
+		cfg.Pipeline.PublishWorkers = 64
+		cfg.Pipeline.AckWorkers = 128
+	},
+	// dev suits a laptop: small batches, quick claims and debug logs.
+	ProfileDev: func(cfg *Config) {
+		cfg.Log.Level = "debug"
+		cfg.Redis.BatchSize = 100
+		cfg.Redis.BatchSizeMax = 100
+		cfg.Redis.PoolSize = 10
+		cfg.Redis.MinIdleConns = 1
+		cfg.Redis.ClaimConcurrency = 1
+		cfg.Redis.ClaimIdle = 5 * time.Second
+		cfg.Redis.CleanupInterval = 30 * time.Second
+		cfg.MQTT.PoolSize = 1
+		cfg.Pipeline.BufferCapacity = 1000
+		cfg.Pipeline.MessageQueueCapacity = 10
+		cfg.Pipeline.PublishWorkers = 2
+		cfg.Pipeline.AckWorkers = 2
+	},
+}
+
+// profileSetting reads a setting a profile may tune, under the name of its
+// environment variable.
+type profileSetting struct {
+	get  func(*Config) any
+	name string
+}
+
+var profileSettings = []profileSetting{
+	{name: "LOG_LEVEL", get: func(c *Config) any { return c.Log.Level }},
+	{name: "REDIS_BATCH_SIZE", get: func(c *Config) any { return c.Redis.BatchSize }},
+	{name: "REDIS_BATCH_SIZE_MAX", get: func(c *Config) any { return c.Redis.BatchSizeMax }},
+	{name: "REDIS_POOL_SIZE", get: func(c *Config) any { return c.Redis.PoolSize }},
+	{name: "REDIS_MIN_IDLE_CONNS", get: func(c *Config) any { return c.Redis.MinIdleConns }},
+	{name: "REDIS_CLAIM_CONCURRENCY", get: func(c *Config) any { return c.Redis.ClaimConcurrency }},
+	{name: "REDIS_CLAIM_IDLE", get: func(c *Config) any { return c.Redis.ClaimIdle }},
+	{name: "REDIS_CLEANUP_INTERVAL", get: func(c *Config) any { return c.Redis.CleanupInterval }},
+	{name: "MQTT_POOL_SIZE", get: func(c *Config) any { return c.MQTT.PoolSize }},
+	{name: "PIPELINE_BUFFER_CAPACITY", get: func(c *Config) any { return c.Pipeline.BufferCapacity }},
+	{name: "PIPELINE_MESSAGE_QUEUE_CAPACITY", get: func(c *Config) any { return c.Pipeline.MessageQueueCapacity }},
+	{name: "PIPELINE_PUBLISH_WORKERS", get: func(c *Config) any { return c.Pipeline.PublishWorkers }},
+	{name: "PIPELINE_ACK_WORKERS", get: func(c *Config) any { return c.Pipeline.AckWorkers }},
+}
+
+// loadProfile returns the defaults tuned by the APP_PROFILE profile, or
+// the built-in defaults when none is selected.
+func loadProfile() (*Config, error) {
+	cfg := defaultConfig()
+	name := getEnvString("APP_PROFILE")
+	if *flagAppProfile != "" {
+		name = *flagAppProfile
+	}
+	if name == "" {
+		return cfg, nil
+	}
+	tune, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q: want %s, %s or %s",
+			name, ProfileEdge, ProfileDatacenter, ProfileDev)
+	}
+	tune(cfg)
+	cfg.Profile = name
+	return cfg, nil
+}
+
+// profileOverrides returns the settings tuned by a profile that the
+// environment or flags changed in cfg from their value in tuned.
+func profileOverrides(tuned, cfg *Config) []string {
+	var names []string
+	for _, s := range profileSettings {
+		if s.get(tuned) != s.get(cfg) {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
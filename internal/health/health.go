@@ -3,6 +3,7 @@ package health
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"expvar"
 	"fmt"
@@ -22,11 +23,20 @@ type ConnectionChecker interface {
 	IsConnected() bool
 }
 
-// Server exposes /healthz and /debug/vars.
+// Degrader reports why the pipeline is degraded although its connections
+// are up, or "" when it is not.
+type Degrader interface {
+	Degraded() string
+}
+
+// Server exposes /healthz and /debug/vars, and any handler added with
+// Handle.
 type Server struct {
 	httpServer  *http.Server
+	mux         *http.ServeMux
 	redis       Pinger
 	mqtt        ConnectionChecker
+	pipeline    Degrader
 	pingTimeout time.Duration
 }
 
@@ -40,24 +50,42 @@ func NewServer(
 	readHeaderTimeout time.Duration,
 ) *Server {
 	s := &Server{
+		mux:         http.NewServeMux(),
 		redis:       redisPinger,
 		mqtt:        mqttChecker,
 		pingTimeout: pingTimeout,
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /healthz", s.handleHealth)
-	mux.Handle("GET /debug/vars", expvar.Handler())
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.Handle("GET /debug/vars", expvar.Handler())
 
 	s.httpServer = &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           s.mux,
 		ReadHeaderTimeout: readHeaderTimeout,
 	}
 
 	return s
 }
 
+// Handle serves handler for pattern, in http.ServeMux syntax, next to the
+// health endpoints. Call it before ListenAndServe.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetDegrader adds d to the checks of /healthz. Call it before
+// ListenAndServe.
+func (s *Server) SetDegrader(d Degrader) {
+	s.pipeline = d
+}
+
+// SetTLSConfig serves over TLS with cfg, which must hold the server
+// certificate. Call it before ListenAndServe.
+func (s *Server) SetTLSConfig(cfg *tls.Config) {
+	s.httpServer.TLSConfig = cfg
+}
+
 // ListenAndServe blocks until the server is shut down or fails.
 func (s *Server) ListenAndServe(ctx context.Context) error {
 	var lc net.ListenConfig
@@ -65,6 +93,9 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("health server listen: %w", err)
 	}
+	if s.httpServer.TLSConfig != nil {
+		return s.httpServer.ServeTLS(ln, "", "")
+	}
 	return s.httpServer.Serve(ln)
 }
 
@@ -80,9 +111,10 @@ const (
 )
 
 type healthResponse struct {
-	Status string `json:"status"`
-	Redis  string `json:"redis"`
-	MQTT   string `json:"mqtt"`
+	Status   string `json:"status"`
+	Redis    string `json:"redis"`
+	MQTT     string `json:"mqtt"`
+	Pipeline string `json:"pipeline,omitempty"` // set by a Degrader
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +136,14 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
+	if s.pipeline != nil {
+		if reason := s.pipeline.Degraded(); reason != "" {
+			resp.Status = statusDegraded
+			resp.Pipeline = reason
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	data, err := json.Marshal(resp)
@@ -2,8 +2,10 @@ package health
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -26,15 +28,23 @@ func (m *mockMQTT) IsConnected() bool {
 	return m.connected
 }
 
+type mockDegrader string
+
+func (m mockDegrader) Degraded() string {
+	return string(m)
+}
+
 // healthzCase describes one /healthz test scenario.
 type healthzCase struct {
-	pinger     Pinger
-	mqtt       ConnectionChecker
-	name       string
-	wantStatus string
-	wantRedis  string
-	wantMQTT   string
-	wantCode   int
+	pinger       Pinger
+	mqtt         ConnectionChecker
+	pipeline     Degrader
+	name         string
+	wantStatus   string
+	wantRedis    string
+	wantMQTT     string
+	wantPipeline string
+	wantCode     int
 }
 
 func TestHealthz(t *testing.T) {
@@ -66,6 +76,27 @@ func TestHealthz(t *testing.T) {
 			wantRedis:  statusOK,
 			wantMQTT:   statusDisconnected,
 		},
+		{
+			name:       "PipelineOK",
+			pinger:     &mockPinger{},
+			mqtt:       &mockMQTT{connected: true},
+			pipeline:   mockDegrader(""),
+			wantCode:   http.StatusOK,
+			wantStatus: statusOK,
+			wantRedis:  statusOK,
+			wantMQTT:   statusOK,
+		},
+		{
+			name:         "PipelineDegraded",
+			pinger:       &mockPinger{},
+			mqtt:         &mockMQTT{connected: true},
+			pipeline:     mockDegrader("ack latency over its objective on syslog:fw"),
+			wantCode:     http.StatusServiceUnavailable,
+			wantStatus:   statusDegraded,
+			wantRedis:    statusOK,
+			wantMQTT:     statusOK,
+			wantPipeline: "ack latency over its objective on syslog:fw",
+		},
 		{
 			name:       "NilMQTT",
 			pinger:     &mockPinger{},
@@ -86,6 +117,9 @@ func checkHealthz(t *testing.T, tc *healthzCase) {
 	t.Helper()
 
 	srv := NewServer(":0", tc.pinger, tc.mqtt, 2*time.Second, 5*time.Second)
+	if tc.pipeline != nil {
+		srv.SetDegrader(tc.pipeline)
+	}
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/healthz", http.NoBody)
 	rec := httptest.NewRecorder()
@@ -108,6 +142,9 @@ func checkHealthz(t *testing.T, tc *healthzCase) {
 	if resp.MQTT != tc.wantMQTT {
 		t.Errorf("mqtt = %q; want %q", resp.MQTT, tc.wantMQTT)
 	}
+	if resp.Pipeline != tc.wantPipeline {
+		t.Errorf("pipeline = %q; want %q", resp.Pipeline, tc.wantPipeline)
+	}
 }
 
 func TestHealthz_ContentType(t *testing.T) {
@@ -139,6 +176,27 @@ func TestDebugVars(t *testing.T) {
 	}
 }
 
+func TestHandle(t *testing.T) {
+	srv := NewServer(":0", &mockPinger{}, &mockMQTT{connected: true}, 2*time.Second, 5*time.Second)
+	srv.Handle("POST /admin/test", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/admin/test", http.NoBody)
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d; want 202", rec.Code)
+	}
+
+	req = httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/admin/test", http.NoBody)
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d; want 405", rec.Code)
+	}
+}
+
 func TestListenAndServe_InvalidAddr(t *testing.T) {
 	srv := NewServer("invalid-addr-no-port", &mockPinger{}, &mockMQTT{connected: true}, 2*time.Second, 5*time.Second)
 	err := srv.ListenAndServe(t.Context())
@@ -163,3 +221,49 @@ func TestShutdown(t *testing.T) {
 		t.Fatalf("shutdown: %v", err)
 	}
 }
+
+func TestListenAndServe_TLS(t *testing.T) {
+	// Borrow httptest's certificate for 127.0.0.1 and a client trusting it.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	cert, client := ts.TLS.Certificates[0], ts.Client()
+	ts.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close() // the server listens on the address itself
+
+	srv := NewServer(addr, &mockPinger{}, nil, 2*time.Second, 5*time.Second)
+	srv.SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(t.Context()) }()
+	defer func() {
+		if err := srv.Shutdown(t.Context()); err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("ListenAndServe() = %v; want http.ErrServerClosed", err)
+		}
+	}()
+
+	var resp *http.Response
+	for range 50 {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://"+addr+"/healthz", http.NoBody)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		if resp, err = client.Do(req); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if resp == nil {
+		t.Fatal("GET /healthz over TLS never succeeded")
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Errorf("status = %d, TLS = %v; want 200 over TLS", resp.StatusCode, resp.TLS != nil)
+	}
+}
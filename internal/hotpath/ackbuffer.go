@@ -0,0 +1,31 @@
+package hotpath
+
+import "errors"
+
+// ResizeAckBuffer replaces the ACK queues with new ones holding capacity
+// ACKs in all, split across the ACK workers like PIPELINE_BUFFER_CAPACITY.
+// ACKs go to the new queues from the moment it returns. The old queues are
+// closed, so their workers flush the ACKs they hold and exit, each
+// replaced by a worker on the new queue of its shard; a stream's ACKs may
+// be flushed by both for a moment. It fails before Run starts the workers
+// and once shutdown closed the queues.
+func (hp *HotPath) ResizeAckBuffer(capacity int) error {
+	if capacity < 1 {
+		return errors.New("hotpath: ack buffer capacity must be positive")
+	}
+	// The write lock waits for the ACK callbacks sending to the old queues.
+	hp.ackGate.Lock()
+	defer hp.ackGate.Unlock()
+	if hp.ackStart == nil || hp.acksClosed {
+		return errors.New("hotpath: the ACK workers are not running")
+	}
+	old := hp.ackChans
+	hp.ackChans = newAckChans(capacity, len(old))
+	for _, ch := range old {
+		close(ch)
+	}
+	for _, ch := range hp.ackChans {
+		hp.ackStart(ch)
+	}
+	return nil
+}
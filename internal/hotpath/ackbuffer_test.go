@@ -0,0 +1,63 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func TestResizeAckBuffer_KeepsQueuedAcks(t *testing.T) {
+	var mu sync.Mutex
+	var acked []string
+	r := &mockRedis{
+		ackAndDeleteFn: func(_ context.Context, ids []string, _ string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			acked = append(acked, ids...)
+			return nil
+		},
+	}
+	cfg := testConfig()
+	cfg.Pipeline.AckWorkers = 2
+	hp, err := New(r, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	if err := hp.ResizeAckBuffer(1000); err == nil {
+		t.Error("ResizeAckBuffer() before Run error = nil; want an error")
+	}
+	hp.startAckWorkers(t.Context(), t.Context())
+	handler := hp.makeAckHandler(t.Context())
+	handler(message.AckMessage{Stream: testStreamSimp, IDs: []string{"1-0"}, Ack: true})
+
+	if err := hp.ResizeAckBuffer(0); err == nil {
+		t.Error("ResizeAckBuffer(0) error = nil; want an error")
+	}
+	if err := hp.ResizeAckBuffer(1000); err != nil {
+		t.Fatalf("ResizeAckBuffer() error = %v", err)
+	}
+	if got := cap(hp.ackChans[0]); got != 500 {
+		t.Errorf("queue capacity = %d; want 500", got)
+	}
+	if got := metrics.AckBufferCapacity.Value(); got != 1000 {
+		t.Errorf("AckBufferCapacity = %d; want 1000", got)
+	}
+	handler(message.AckMessage{Stream: testStreamSimp, IDs: []string{"2-0"}, Ack: true})
+
+	hp.closeAckChans()
+	hp.ackWg.Wait()
+	slices.Sort(acked)
+	if !slices.Equal(acked, []string{"1-0", "2-0"}) {
+		t.Errorf("acked = %v; want both ACKs, before and after the resize", acked)
+	}
+	if err := hp.ResizeAckBuffer(1000); err == nil {
+		t.Error("ResizeAckBuffer() after shutdown error = nil; want an error")
+	}
+}
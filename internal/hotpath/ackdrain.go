@@ -0,0 +1,63 @@
+package hotpath
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ackDrain bounds the final flush of the ACK workers by
+// PIPELINE_ACK_DRAIN_TIMEOUT. Once shutdown closed the ACK queues, it
+// counts the ACKed and NACKed entries the workers apply and those they
+// abandon: the ones whose flush failed, and every one still buffered or
+// queued when the timeout ran out, which are then dropped without a call
+// to Redis. Abandoned entries stay pending and are claimed again later.
+type ackDrain struct {
+	abandon   context.CancelFunc // cancels the workers' flushes; set by Run
+	flushed   atomic.Int64
+	abandoned atomic.Int64
+	timeout   time.Duration
+	closing   atomic.Bool // set when the ACK queues are closed
+}
+
+// skip reports whether the n entries of a flush under ctx are dropped, the
+// ACK drain timeout having run out, and counts them as abandoned.
+func (d *ackDrain) skip(ctx context.Context, n int) bool {
+	if !d.closing.Load() || ctx.Err() == nil {
+		return false
+	}
+	d.abandoned.Add(int64(n))
+	return true
+}
+
+// count records the n entries of a flush that ended with err, once the ACK
+// queues are closed.
+func (d *ackDrain) count(n int, err error) {
+	if n == 0 || !d.closing.Load() {
+		return
+	}
+	if err != nil {
+		d.abandoned.Add(int64(n))
+		return
+	}
+	d.flushed.Add(int64(n))
+}
+
+// drainACKs waits for the ACK workers' final flush, up to the ACK drain
+// timeout, then abandons what is left and reports both counts.
+func (hp *HotPath) drainACKs(ctx context.Context) {
+	if !waitTimeout(&hp.ackWg, hp.ackDrain.timeout) {
+		hp.log.Warnf(ctx, "ACK drain timeout %s exceeded, abandoning the ACKs and NACKs not applied yet",
+			hp.ackDrain.timeout)
+		hp.ackDrain.abandon()
+		hp.ackWg.Wait()
+	}
+	flushed, abandoned := hp.ackDrain.flushed.Load(), hp.ackDrain.abandoned.Load()
+	switch {
+	case abandoned > 0:
+		hp.log.Warnf(ctx, "Flushed %d ACKed and NACKed entries on shutdown, abandoned %d "+
+			"(they stay pending and will be reclaimed)", flushed, abandoned)
+	case flushed > 0:
+		hp.log.Infof(ctx, "Flushed %d ACKed and NACKed entries on shutdown", flushed)
+	}
+}
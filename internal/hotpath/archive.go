@@ -0,0 +1,55 @@
+package hotpath
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ibs-source/syslog-consumer/internal/archive"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// archiveCopy is ARCHIVE_MODE=copy: every payload published to
+// MQTT_PUBLISH_TOPIC is written to the archive too, once the publish
+// succeeded. The archive is a copy: a failed write is logged and counted,
+// and the entries are acknowledged by the MQTT receivers as usual. A nil
+// archiveCopy archives nothing.
+type archiveCopy struct {
+	sink *archive.Sink
+	log  *log.Logger
+}
+
+// newArchiveCopy opens the archive; call it only under ARCHIVE_MODE=copy.
+func newArchiveCopy(cfg *config.ArchiveConfig, logger *log.Logger) (*archiveCopy, error) {
+	sink, err := archive.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("hotpath: %w", err)
+	}
+	return &archiveCopy{sink: sink, log: logger}, nil
+}
+
+// wrap returns publishFn writing what it published to the archive.
+func (a *archiveCopy) wrap(
+	publishFn func(context.Context, message.Payload) error,
+) func(context.Context, message.Payload) error {
+	if a == nil {
+		return publishFn
+	}
+	return func(ctx context.Context, payload message.Payload) error {
+		if err := publishFn(ctx, payload); err != nil {
+			return err
+		}
+		if err := a.sink.Publish(ctx, payload); err != nil {
+			a.log.Warnf(ctx, "Failed to archive a published payload: %v", err)
+		}
+		return nil
+	}
+}
+
+func (a *archiveCopy) close() error {
+	if a == nil {
+		return nil
+	}
+	return a.sink.Close()
+}
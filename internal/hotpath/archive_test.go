@@ -0,0 +1,57 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestArchiveCopy_ArchivesPublished(t *testing.T) {
+	if got := (*archiveCopy)(nil).wrap(nil); got != nil {
+		t.Error("nil archiveCopy wrapped publishFn")
+	}
+
+	cfg := config.ArchiveConfig{Mode: config.ArchiveCopy, Dir: t.TempDir(), MaxAge: time.Hour, MaxBytes: 1 << 20}
+	a, err := newArchiveCopy(&cfg, log.New())
+	if err != nil {
+		t.Fatalf("newArchiveCopy() error = %v", err)
+	}
+	failed := errors.New("broker down")
+	var fail bool
+	publishFn := a.wrap(func(context.Context, message.Payload) error {
+		if fail {
+			return failed
+		}
+		return nil
+	})
+
+	if err := publishFn(t.Context(), []byte("1-0\ts\t{}\n")); err != nil {
+		t.Fatalf("publishFn() error = %v", err)
+	}
+	fail = true
+	if err := publishFn(t.Context(), []byte("2-0\ts\t{}\n")); !errors.Is(err, failed) {
+		t.Fatalf("publishFn() error = %v; want %v", err, failed)
+	}
+	if err := a.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(cfg.Dir, "*.ndjson"))
+	if len(files) != 1 {
+		t.Fatalf("archive files = %v; want 1", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if want := `{"id":"1-0","stream":"s","record":{}}` + "\n"; string(data) != want {
+		t.Errorf("archive = %q; want only the published payload %q", data, want)
+	}
+}
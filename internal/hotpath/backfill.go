@@ -0,0 +1,285 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// pendingReader is implemented by *redis.Client; REDIS_BACKFILL requires it.
+type pendingReader interface {
+	ReadPending(ctx context.Context) (message.Batch, error)
+}
+
+func validateBackfill(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if !cfg.Backfill {
+		return nil
+	}
+	if _, ok := redisClient.(pendingReader); !ok {
+		return errors.New("hotpath: backfill needs a redis client that can read pending entries")
+	}
+	return nil
+}
+
+// backfillLogInterval spaces the backfill progress log lines.
+const backfillLogInterval = 10 * time.Second
+
+// backfill is the startup phase that replays the backlog of an outage
+// before fetchLoop reads new entries. REDIS_BACKFILL_POLICY orders it:
+// drain-first replays this consumer's own pending entries, oldest first,
+// then idle entries of the group, claimed until a claim cycle comes back
+// empty; claim-first only claims, which takes this consumer's own entries
+// once they are idle too; interleaved is drain-first with turns of new
+// reads between the backlog batches. The claim loop sits out until it is
+// done. In strict order the replayed batches are published inline, one at
+// a time on one connection, instead of being spread over the publish
+// workers. A nil backfill, as under fresh-only, skips the phase.
+type backfill struct {
+	r          pendingReader
+	lags       groupLagReader                               // nil: the backlog size is unknown
+	publishFn  func(context.Context, message.Payload) error // strict order only
+	builder    *jsonfast.Builder
+	enc        *zstd.Encoder
+	bw         *jsonfast.BatchWriter
+	compressed []byte
+	rate       int
+	backlog    int64 // pending entries of the group at the start; 0 when unknown
+	every      int   // backlog batches between turns of new reads; 0 without
+	fresh      int   // new reads per turn
+	streak     int   // backlog batches since the last turn
+	done       atomic.Bool
+	claiming   bool // own pending entries read through, or not to be read
+	strict     bool
+}
+
+func newBackfill(redisClient redis.StreamClient, mqttPublisher mqtt.Publisher, cfg *config.RedisConfig) *backfill {
+	if !cfg.Backfill {
+		return nil
+	}
+	schedule, err := config.ParseBackfillPolicy(cfg.BackfillPolicy)
+	if err != nil {
+		schedule.Order = config.BackfillDrainFirst
+	}
+	if schedule.Order == config.BackfillFreshOnly {
+		return nil
+	}
+	r, _ := redisClient.(pendingReader)
+	lags, _ := redisClient.(groupLagReader)
+	b := &backfill{
+		r:        r,
+		lags:     lags,
+		rate:     cfg.BackfillRate,
+		strict:   cfg.BackfillStrictOrder,
+		claiming: schedule.Order == config.BackfillClaimFirst,
+		every:    schedule.Backlog,
+		fresh:    schedule.Fresh,
+	}
+	if b.strict {
+		b.builder = jsonfast.New(4096)
+		b.enc = compress.NewEncoder()
+		b.bw = jsonfast.NewBatchWriter(4096)
+		b.publishFn = mqttPublisher.Publish
+		// A fixed hint keeps every payload on one pool connection, which
+		// paho publishes in order.
+		if hinted, ok := mqttPublisher.(hintedPublisher); ok {
+			b.publishFn = func(ctx context.Context, payload message.Payload) error {
+				return hinted.PublishFrom(ctx, payload, 0)
+			}
+		}
+	}
+	return b
+}
+
+// running reports whether the backfill phase is still in progress.
+func (b *backfill) running() bool {
+	return b != nil && !b.done.Load()
+}
+
+// pause is how long to wait after replaying n entries to stay within the
+// configured rate.
+func (b *backfill) pause(n int) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(b.rate)
+}
+
+// runBackfill runs the backfill phase to completion. It returns early only
+// when ctx is canceled.
+func (hp *HotPath) runBackfill(ctx context.Context) error {
+	b := hp.backfill
+	if b == nil {
+		return nil
+	}
+	defer b.done.Store(true)
+
+	hp.startBackfill(ctx)
+	start := time.Now()
+	lastLog := start
+	total := 0
+	for {
+		hp.live.tick()
+		batch, err := hp.nextBackfillBatch(ctx)
+		if err != nil {
+			hp.log.Errorf(ctx, "Backfill read failed: %v", err)
+			metrics.FetchErrors.Add(1)
+			hp.events.failed("fetch", 0, err)
+			if err := sleepCtx(ctx, hp.errorBackoff); err != nil {
+				return err
+			}
+			continue
+		}
+		hp.live.read()
+		n := len(batch.Items)
+		if n == 0 {
+			break
+		}
+		total += n
+		metrics.MessagesBackfilled.Add(int64(n))
+		metrics.BackfillProgress.Set(b.progress(total))
+		hp.events.fetched("backfill", n)
+		if batch = hp.dropInvalid(ctx, hp.dropAged(ctx, hp.redactBatch(ctx, batch), time.Now())); len(batch.Items) > 0 {
+			if err := hp.publishBackfill(ctx, batch); err != nil {
+				return err
+			}
+		}
+		if time.Since(lastLog) >= backfillLogInterval {
+			hp.log.Infof(ctx, "Backfill: %d entries replayed (%d%%) in %s",
+				total, b.progress(total), time.Since(start).Round(time.Second))
+			lastLog = time.Now()
+		}
+		if err := hp.paceBackfill(ctx, n); err != nil {
+			return err
+		}
+	}
+	metrics.BackfillProgress.Set(100)
+	hp.log.Infof(ctx, "Backfill complete: %d entries replayed in %s, reading new entries",
+		total, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// startBackfill measures the backlog and announces the phase.
+func (hp *HotPath) startBackfill(ctx context.Context) {
+	b := hp.backfill
+	if b.measure(ctx); b.backlog > 0 {
+		hp.log.Infof(ctx, "Backfill: replaying about %d pending entries before reading new ones", b.backlog)
+		return
+	}
+	hp.log.Infof(ctx, "Backfill: replaying pending entries before reading new ones")
+}
+
+// measure reads the group's pending entries, the backlog the progress is
+// reported against. Without a lag reader, or when the read fails, the
+// progress stays at 0 until the phase is over.
+func (b *backfill) measure(ctx context.Context) {
+	metrics.BackfillProgress.Set(0)
+	if b.lags == nil {
+		return
+	}
+	lags, err := b.lags.GroupLags(ctx)
+	if err != nil {
+		return
+	}
+	b.backlog = 0
+	for i := range lags {
+		b.backlog += lags[i].Pending
+	}
+	metrics.BackfillBacklog.Set(b.backlog)
+}
+
+// paceBackfill runs after a backlog batch of n entries: a turn of new
+// reads when one is due, then the pause of the backfill rate.
+func (hp *HotPath) paceBackfill(ctx context.Context, n int) error {
+	if err := hp.freshTurn(ctx); err != nil {
+		return err
+	}
+	return sleepCtx(ctx, hp.backfill.pause(n))
+}
+
+// progress is the percent of the backlog replayed after total entries. It
+// stays under 100 until the phase is over: the backlog is an estimate, and
+// entries of live consumers in it are not claimed.
+func (b *backfill) progress(total int) int64 {
+	if b.backlog <= 0 {
+		return 0
+	}
+	return min(int64(total)*100/b.backlog, 99)
+}
+
+// freshTurn reads new entries after every b.every backlog batches, up to
+// b.fresh reads, so that under an interleaved policy new entries keep
+// flowing while the backlog is replayed. A read that finds nothing ends
+// the turn.
+func (hp *HotPath) freshTurn(ctx context.Context) error {
+	b := hp.backfill
+	if b.every == 0 {
+		return nil
+	}
+	if b.streak++; b.streak < b.every {
+		return nil
+	}
+	b.streak = 0
+	for range b.fresh {
+		n, err := hp.fetchOnce(ctx)
+		if err != nil || n == 0 {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextBackfillBatch returns the next batch of the backlog, or an empty one
+// once it is exhausted. Under claim-first the own pending entries are not
+// read: the claim cycles take them once idle, and reading them as well
+// would publish the ones claimed and still in flight twice.
+func (hp *HotPath) nextBackfillBatch(ctx context.Context) (message.Batch, error) {
+	b := hp.backfill
+	if !b.claiming {
+		batch, err := b.r.ReadPending(ctx)
+		if err != nil || len(batch.Items) > 0 {
+			return batch, err
+		}
+		b.claiming = true
+	}
+	batch, err := hp.redis.ClaimIdle(ctx)
+	if err == nil {
+		metrics.MessagesClaimed.Add(int64(len(batch.Items)))
+	}
+	return batch, err
+}
+
+func (hp *HotPath) publishBackfill(ctx context.Context, batch message.Batch) error {
+	b := hp.backfill
+	if !b.strict {
+		return hp.enqueueBatch(ctx, batch)
+	}
+	hp.publishToStreams(ctx, b.builder, b.enc, &batch, b.bw, &b.compressed, b.publishFn)
+	batch.Release()
+	return nil
+}
+
+// sleepCtx waits for d, or until ctx is canceled.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
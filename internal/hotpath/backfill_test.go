@@ -0,0 +1,270 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// backfillRedis is a mockRedis that also implements pendingReader, handing
+// out pending in order, then claimed in order, and groupLagReader,
+// reporting lags as the group's backlog.
+type backfillRedis struct {
+	readErr error
+	mockRedis
+	pending [][]message.Redis
+	claimed [][]message.Redis
+	lags    []redis.GroupLag
+}
+
+func (m *backfillRedis) GroupLags(context.Context) ([]redis.GroupLag, error) {
+	return m.lags, nil
+}
+
+func (m *backfillRedis) ReadPending(context.Context) (message.Batch, error) {
+	if err := m.readErr; err != nil {
+		m.readErr = nil
+		return message.Batch{}, err
+	}
+	return next(&m.pending), nil
+}
+
+func next(batches *[][]message.Redis) message.Batch {
+	if len(*batches) == 0 {
+		return message.Batch{}
+	}
+	items := (*batches)[0]
+	*batches = (*batches)[1:]
+	return message.Batch{Items: items}
+}
+
+func newBackfillRedis(pending, claimed [][]message.Redis) *backfillRedis {
+	m := &backfillRedis{pending: pending, claimed: claimed}
+	m.claimIdleFn = func(context.Context) (message.Batch, error) { return next(&m.claimed), nil }
+	return m
+}
+
+func backfillConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Redis.Backfill = true
+	cfg.Pipeline.ErrorBackoff = time.Millisecond
+	return cfg
+}
+
+func backfillHotPath(t *testing.T, redisClient *backfillRedis, cfg *config.Config, pub *mockPublisher) *HotPath {
+	t.Helper()
+	hp, err := New(redisClient, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func queuedIDs(ch chan message.Batch) []string {
+	var ids []string
+	for {
+		select {
+		case batch := <-ch:
+			for _, item := range batch.Items {
+				ids = append(ids, item.ID)
+			}
+		default:
+			return ids
+		}
+	}
+}
+
+func TestNew_BackfillRequiresPendingReader(t *testing.T) {
+	if _, err := New(&mockRedis{}, &mockPublisher{}, backfillConfig(), log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without ReadPending")
+	}
+}
+
+func TestRunBackfill_ReplaysPendingThenClaims(t *testing.T) {
+	redisClient := newBackfillRedis(
+		[][]message.Redis{
+			{{ID: "1-0", Stream: testStreamSimp}, {ID: "2-0", Stream: testStreamSimp}},
+			{{ID: "3-0", Stream: testStreamSimp}},
+		},
+		[][]message.Redis{{{ID: "4-0", Stream: testStreamSimp}}},
+	)
+	redisClient.readErr = errors.New("redis down") // retried after the error backoff
+	hp := backfillHotPath(t, redisClient, backfillConfig(), &mockPublisher{})
+
+	if !hp.backfill.running() {
+		t.Fatal("backfill.running() = false before the phase ran")
+	}
+	if err := hp.runBackfill(t.Context()); err != nil {
+		t.Fatalf("runBackfill() error = %v", err)
+	}
+
+	if got := queuedIDs(hp.msgChan); !slices.Equal(got, []string{"1-0", "2-0", "3-0", "4-0"}) {
+		t.Errorf("queued = %v; want own pending entries oldest first, then claimed ones", got)
+	}
+	if hp.backfill.running() {
+		t.Error("backfill.running() = true after the phase completed")
+	}
+}
+
+func TestRunBackfill_ClaimFirstSkipsOwnPending(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.BackfillPolicy = config.BackfillClaimFirst
+	redisClient := newBackfillRedis(
+		[][]message.Redis{{{ID: "1-0", Stream: testStreamSimp}}},
+		[][]message.Redis{{{ID: "2-0", Stream: testStreamSimp}}, {{ID: "3-0", Stream: testStreamSimp}}},
+	)
+	hp := backfillHotPath(t, redisClient, cfg, &mockPublisher{})
+
+	if err := hp.runBackfill(t.Context()); err != nil {
+		t.Fatalf("runBackfill() error = %v", err)
+	}
+	if got := queuedIDs(hp.msgChan); !slices.Equal(got, []string{"2-0", "3-0"}) {
+		t.Errorf("queued = %v; want only claimed entries", got)
+	}
+	if len(redisClient.pending) != 1 {
+		t.Error("ReadPending called; want own pending entries left to the claim cycles")
+	}
+}
+
+func TestNew_BackfillFreshOnlySkipsPhase(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.BackfillPolicy = config.BackfillFreshOnly
+	hp := backfillHotPath(t, newBackfillRedis(nil, nil), cfg, &mockPublisher{})
+	if hp.backfill != nil {
+		t.Error("backfill != nil; want no backfill phase under fresh-only")
+	}
+}
+
+func TestRunBackfill_InterleavedReadsNewEntries(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.BackfillPolicy = config.BackfillInterleaved + ":2:1"
+	cfg.Pipeline.MessageQueueCapacity = 8
+	redisClient := newBackfillRedis([][]message.Redis{
+		{{ID: "1-0", Stream: testStreamSimp}},
+		{{ID: "2-0", Stream: testStreamSimp}},
+		{{ID: "3-0", Stream: testStreamSimp}},
+		{{ID: "4-0", Stream: testStreamSimp}},
+	}, nil)
+	fresh := [][]message.Redis{{{ID: "9-0", Stream: testStreamSimp}}}
+	redisClient.readBatchFn = func(context.Context) (message.Batch, error) { return next(&fresh), nil }
+	hp := backfillHotPath(t, redisClient, cfg, &mockPublisher{})
+
+	if err := hp.runBackfill(t.Context()); err != nil {
+		t.Fatalf("runBackfill() error = %v", err)
+	}
+	want := []string{"1-0", "2-0", "9-0", "3-0", "4-0"}
+	if got := queuedIDs(hp.msgChan); !slices.Equal(got, want) {
+		t.Errorf("queued = %v; want %v, a new read after every two backlog batches", got, want)
+	}
+}
+
+func TestRunBackfill_ReportsProgress(t *testing.T) {
+	redisClient := newBackfillRedis([][]message.Redis{
+		{{ID: "1-0", Stream: testStreamSimp}},
+		{{ID: "2-0", Stream: testStreamSimp}},
+	}, nil)
+	redisClient.lags = []redis.GroupLag{{Pending: 3}, {Pending: 1}}
+	hp := backfillHotPath(t, redisClient, backfillConfig(), &mockPublisher{})
+
+	if err := hp.runBackfill(t.Context()); err != nil {
+		t.Fatalf("runBackfill() error = %v", err)
+	}
+	if got := metrics.BackfillBacklog.Value(); got != 4 {
+		t.Errorf("backfill_backlog = %d; want the group's 4 pending entries", got)
+	}
+	if got := metrics.BackfillProgress.Value(); got != 100 {
+		t.Errorf("backfill_progress = %d; want 100 once complete", got)
+	}
+
+	b := hp.backfill
+	if got := b.progress(2); got != 50 {
+		t.Errorf("progress(2) = %d; want 50 of a backlog of 4", got)
+	}
+	if got := b.progress(5); got != 99 {
+		t.Errorf("progress(5) = %d; want 99 before the phase is over", got)
+	}
+}
+
+func TestRunBackfill_StrictOrderPublishesInline(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.BackfillStrictOrder = true
+	redisClient := newBackfillRedis([][]message.Redis{
+		{{ID: "1-0", Stream: testStreamSimp, Raw: "a"}},
+		{{ID: "2-0", Stream: testStreamSimp, Raw: "b"}},
+	}, nil)
+
+	var published int
+	pub := &mockPublisher{publishFn: func(context.Context, message.Payload) error {
+		published++
+		return nil
+	}}
+	hp := backfillHotPath(t, redisClient, cfg, pub)
+
+	if err := hp.runBackfill(t.Context()); err != nil {
+		t.Fatalf("runBackfill() error = %v", err)
+	}
+	if published != 2 {
+		t.Errorf("published = %d; want one payload per backfilled batch", published)
+	}
+	if got := queuedIDs(hp.msgChan); len(got) != 0 {
+		t.Errorf("queued = %v; want nothing handed to the publish workers", got)
+	}
+}
+
+func TestRunBackfill_StopsOnCancel(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.BackfillRate = 1 // one entry per second
+	redisClient := newBackfillRedis([][]message.Redis{
+		{{ID: "1-0", Stream: testStreamSimp}},
+		{{ID: "2-0", Stream: testStreamSimp}},
+	}, nil)
+	hp := backfillHotPath(t, redisClient, cfg, &mockPublisher{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := hp.runBackfill(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("runBackfill() error = %v; want the context error while rate limited", err)
+	}
+	if got := queuedIDs(hp.msgChan); !slices.Equal(got, []string{"1-0"}) {
+		t.Errorf("queued = %v; want only the first batch before the pause", got)
+	}
+}
+
+func TestBackfillPause(t *testing.T) {
+	b := &backfill{}
+	if got := b.pause(100); got != 0 {
+		t.Errorf("pause() = %v; want 0 without a rate", got)
+	}
+	b.rate = 200
+	if got := b.pause(100); got != 500*time.Millisecond {
+		t.Errorf("pause() = %v; want 500ms for 100 entries at 200/s", got)
+	}
+}
+
+func TestClaimLoop_SkipsWhileBackfilling(t *testing.T) {
+	cfg := backfillConfig()
+	cfg.Redis.ClaimIdle = time.Millisecond
+	redisClient := newBackfillRedis(nil, nil)
+	claims := 0
+	redisClient.claimIdleFn = func(context.Context) (message.Batch, error) {
+		claims++
+		return message.Batch{}, nil
+	}
+	hp := backfillHotPath(t, redisClient, cfg, &mockPublisher{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	checkLoopExit(t, hp.claimLoop(ctx))
+	if claims != 0 {
+		t.Errorf("ClaimIdle called %d times; want 0 while the backfill has not run", claims)
+	}
+}
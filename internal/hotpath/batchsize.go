@@ -0,0 +1,208 @@
+package hotpath
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// batchSizer is implemented by *redis.Client; REDIS_ADAPTIVE_BATCH requires
+// it.
+type batchSizer interface {
+	SetReadBatchSize(n int)
+}
+
+const (
+	// fillHigh and fillLow are the smoothed fill ratios at which the read
+	// size grows and shrinks; in between it holds.
+	fillHigh = 0.9
+	fillLow  = 0.5
+	// ewmaWeight is the weight of the newest sample in the smoothed fill
+	// ratio and publish time.
+	ewmaWeight = 0.25
+	// cutSamples is how many batches are published between two halvings,
+	// enough for the smoothed publish time to mostly reflect the new size.
+	cutSamples = 4
+)
+
+// adaptiveBatch sizes reads AIMD-style under REDIS_ADAPTIVE_BATCH. The
+// fetch loop reports how full each read came back and the publish workers
+// how long each batch took to publish. Full reads grow the size by
+// REDIS_BATCH_SIZE_MIN, so bursts take fewer round trips; mostly empty ones
+// shrink it by as much, so a quiet stream is published in small, quick
+// batches; a smoothed publish time over REDIS_BATCH_LATENCY_TARGET halves
+// it. A nil adaptiveBatch leaves REDIS_BATCH_SIZE alone.
+type adaptiveBatch struct {
+	sizer     batchSizer
+	latency   atomic.Int64 // smoothed batch publish time, in ns
+	published atomic.Int64 // batches published so far
+	target    time.Duration
+	fill      float64 // smoothed fill ratio; fetch loop only, like the fields below
+	cutAt     int64   // published at the last halving
+	size      int
+	minSize   int
+	maxSize   int
+}
+
+func validateAdaptiveBatch(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if !cfg.AdaptiveBatch {
+		return nil
+	}
+	if _, ok := redisClient.(batchSizer); !ok {
+		return errors.New("hotpath: adaptive batch sizing needs a redis client that can resize reads")
+	}
+	return nil
+}
+
+func newAdaptiveBatch(redisClient redis.StreamClient, cfg *config.RedisConfig) *adaptiveBatch {
+	if !cfg.AdaptiveBatch {
+		return nil
+	}
+	sizer, _ := redisClient.(batchSizer)
+	a := &adaptiveBatch{
+		sizer:   sizer,
+		target:  cfg.BatchLatencyTarget,
+		fill:    (fillLow + fillHigh) / 2,
+		minSize: cfg.BatchSizeMin,
+		maxSize: cfg.BatchSizeMax,
+	}
+	a.resize(cfg.BatchSize)
+	return a
+}
+
+// observeRead adjusts the size after a read that returned n entries. It is
+// called from the fetch loop only.
+func (a *adaptiveBatch) observeRead(n int) {
+	if a == nil {
+		return
+	}
+	a.fill += (float64(n)/float64(a.size) - a.fill) * ewmaWeight
+	published := a.published.Load()
+	slow := time.Duration(a.latency.Load()) > a.target
+
+	next := a.size
+	switch {
+	case slow && published-a.cutAt >= cutSamples:
+		a.cutAt = published
+		next = a.size / 2
+	case a.fill >= fillHigh && !slow:
+		next = a.size + a.minSize
+	case a.fill < fillLow:
+		next = a.size - a.minSize
+	}
+	a.resize(next)
+}
+
+// drain holds the read size at its largest while Redis is under memory
+// pressure. It is called from the fetch loop only.
+func (a *adaptiveBatch) drain() {
+	if a != nil {
+		a.resize(a.maxSize)
+	}
+}
+
+// observePublish folds the time one batch took to publish into the
+// smoothed publish time. Publish workers call it concurrently.
+func (a *adaptiveBatch) observePublish(d time.Duration) {
+	if a == nil {
+		return
+	}
+	for {
+		old := a.latency.Load()
+		next := int64(d)
+		if old != 0 {
+			next = old + int64(float64(int64(d)-old)*ewmaWeight)
+		}
+		if a.latency.CompareAndSwap(old, next) {
+			break
+		}
+	}
+	a.published.Add(1)
+}
+
+// current returns the read size, or 0 for a nil adaptiveBatch.
+func (a *adaptiveBatch) current() int {
+	if a == nil {
+		return 0
+	}
+	return a.size
+}
+
+// restore resumes at the read size of a snapshot, clamped to the current
+// bounds. Zero, a snapshot without one, keeps REDIS_BATCH_SIZE.
+func (a *adaptiveBatch) restore(n int) {
+	if a == nil || n == 0 {
+		return
+	}
+	a.resize(n)
+}
+
+func (a *adaptiveBatch) resize(n int) {
+	n = min(max(n, a.minSize), a.maxSize)
+	if n == a.size {
+		return
+	}
+	a.size = n
+	a.sizer.SetReadBatchSize(n)
+	metrics.ReadBatchSize.Set(int64(n))
+}
+
+// Drain triggers of readDrain.
+const (
+	drainMemory = 1 << iota // REDIS_MEMORY_HIGH_PERCENT
+	drainLag                // REDIS_LAG_HIGH
+)
+
+// readDrain holds the read size at REDIS_BATCH_SIZE_MAX, or REDIS_BATCH_SIZE
+// when larger, while any drain trigger is on, and puts REDIS_BATCH_SIZE
+// back once they are all off. It is nil under REDIS_ADAPTIVE_BATCH, which
+// drains in the fetch loop instead.
+type readDrain struct {
+	sizer      batchSizer
+	mu         sync.Mutex
+	triggers   int
+	normalSize int
+	drainSize  int
+}
+
+func newReadDrain(redisClient redis.StreamClient, cfg *config.RedisConfig) *readDrain {
+	if cfg.AdaptiveBatch {
+		return nil
+	}
+	sizer, ok := redisClient.(batchSizer)
+	if !ok {
+		return nil
+	}
+	return &readDrain{
+		sizer:      sizer,
+		normalSize: cfg.BatchSize,
+		drainSize:  max(cfg.BatchSizeMax, cfg.BatchSize),
+	}
+}
+
+// set turns trigger on or off, resizing reads when the first trigger goes
+// on or the last goes off.
+func (d *readDrain) set(trigger int, on bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	was := d.triggers != 0
+	if on {
+		d.triggers |= trigger
+	} else {
+		d.triggers &^= trigger
+	}
+	switch now := d.triggers != 0; {
+	case now && !was:
+		d.sizer.SetReadBatchSize(d.drainSize)
+	case !now && was:
+		d.sizer.SetReadBatchSize(d.normalSize)
+	}
+}
@@ -0,0 +1,119 @@
+package hotpath
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// batchSizerRedis is a mockRedis that also implements batchSizer.
+type batchSizerRedis struct {
+	mockRedis
+	sizes []int
+}
+
+func (m *batchSizerRedis) SetReadBatchSize(n int) {
+	m.sizes = append(m.sizes, n)
+}
+
+func (m *batchSizerRedis) size() int {
+	return m.sizes[len(m.sizes)-1]
+}
+
+func adaptiveConfig() *config.RedisConfig {
+	return &config.RedisConfig{
+		BatchSize:          1000,
+		BatchSizeMin:       100,
+		BatchSizeMax:       2000,
+		BatchLatencyTarget: 100 * time.Millisecond,
+		AdaptiveBatch:      true,
+	}
+}
+
+func TestAdaptiveBatch_GrowsOnFullReads(t *testing.T) {
+	redisClient := &batchSizerRedis{}
+	a := newAdaptiveBatch(redisClient, adaptiveConfig())
+	if redisClient.size() != 1000 || metrics.ReadBatchSize.Value() != 1000 {
+		t.Fatalf("initial size = %d, metric %d; want 1000", redisClient.size(), metrics.ReadBatchSize.Value())
+	}
+	for range 100 {
+		a.observeRead(a.size)
+	}
+	if redisClient.size() != 2000 {
+		t.Errorf("size after full reads = %d; want the 2000 maximum", redisClient.size())
+	}
+}
+
+func TestAdaptiveBatch_ShrinksOnEmptyReads(t *testing.T) {
+	redisClient := &batchSizerRedis{}
+	a := newAdaptiveBatch(redisClient, adaptiveConfig())
+	a.observeRead(0)
+	if redisClient.size() != 1000 {
+		t.Errorf("size after one empty read = %d; want 1000 held", redisClient.size())
+	}
+	for range 100 {
+		a.observeRead(0)
+	}
+	if redisClient.size() != 100 {
+		t.Errorf("size after empty reads = %d; want the 100 minimum", redisClient.size())
+	}
+}
+
+func TestAdaptiveBatch_HalvesOncePerSamplesOnSlowPublish(t *testing.T) {
+	redisClient := &batchSizerRedis{}
+	a := newAdaptiveBatch(redisClient, adaptiveConfig())
+	for range cutSamples {
+		a.observePublish(time.Second)
+	}
+	a.observeRead(a.size)
+	if redisClient.size() != 500 {
+		t.Fatalf("size after slow publishes = %d; want 500", redisClient.size())
+	}
+	for range 10 {
+		a.observeRead(a.size)
+	}
+	if redisClient.size() != 500 {
+		t.Errorf("size without new publishes = %d; want 500 held", redisClient.size())
+	}
+	for range cutSamples {
+		a.observePublish(time.Second)
+	}
+	a.observeRead(a.size)
+	if redisClient.size() != 250 {
+		t.Errorf("size after more slow publishes = %d; want 250", redisClient.size())
+	}
+}
+
+func TestAdaptiveBatch_ClampsInitialSize(t *testing.T) {
+	redisClient := &batchSizerRedis{}
+	cfg := adaptiveConfig()
+	cfg.BatchSize = 50000
+	newAdaptiveBatch(redisClient, cfg)
+	if redisClient.size() != 2000 {
+		t.Errorf("initial size = %d; want the 2000 maximum", redisClient.size())
+	}
+}
+
+func TestAdaptiveBatch_Disabled(t *testing.T) {
+	cfg := adaptiveConfig()
+	cfg.AdaptiveBatch = false
+	a := newAdaptiveBatch(&batchSizerRedis{}, cfg)
+	if a != nil {
+		t.Fatalf("newAdaptiveBatch() = %+v; want nil when disabled", a)
+	}
+	a.observeRead(10)
+	a.observePublish(time.Second)
+}
+
+func TestNew_AdaptiveBatchRequiresBatchSizer(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis = *adaptiveConfig()
+	cfg.Redis.Stream = "test-stream"
+	cfg.Redis.CleanupInterval = time.Minute
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without SetReadBatchSize")
+	}
+}
@@ -1,6 +1,8 @@
 package hotpath
 
 import (
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/ibs-source/syslog-consumer/internal/message"
@@ -54,6 +56,46 @@ func BenchmarkBuildPayload_LargePayload(b *testing.B) {
 	_ = sink
 }
 
+// BenchmarkFetchStage_4KB measures what the fetch goroutine spends per
+// 1000-entry batch of 4 KB objects between two XREADGROUPs. "lazy" is the
+// pipeline as it is: ReadBatch keeps each entry's field map and the
+// publish worker extracts and serializes it. "serialized" adds the JSON
+// lines to the fetch goroutine, as if they were built in ReadBatch; the
+// difference is what the publish workers take off the read loop.
+func BenchmarkFetchStage_4KB(b *testing.B) {
+	const entries = 1000
+	object := `{"facility":1,"severity":6,"hostname":"router1","message":"` + strings.Repeat("x", 4000) + `"}`
+	fields := make([]map[string]any, entries)
+	for i := range fields {
+		fields[i] = map[string]any{"object": object, "raw": "<14>router1: x"}
+	}
+	pool := &sync.Pool{New: func() any { return message.NewBuffer(entries) }}
+	fetch := func(serialize func(*message.Batch)) func(*testing.B) {
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				buf, _ := pool.Get().(*message.Buffer)
+				for i := range fields {
+					buf.Add(benchMsgID, benchMsgStream, fields[i])
+				}
+				batch := message.NewLazyBatch(buf, pool)
+				serialize(&batch)
+				batch.Release()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*entries), "ns/entry")
+		}
+	}
+
+	b.Run("lazy", fetch(func(*message.Batch) {}))
+	hp := &HotPath{}
+	builder := jsonfast.New(8192)
+	b.Run("serialized", fetch(func(batch *message.Batch) {
+		for msg := range batch.All() {
+			_ = hp.buildPayload(builder, msg)
+		}
+	}))
+}
+
 // BenchmarkBuildPayload_EmptyFields measures edge case with empty fields (null payload).
 func BenchmarkBuildPayload_EmptyFields(b *testing.B) {
 	hp := &HotPath{}
@@ -0,0 +1,183 @@
+package hotpath
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// Captured records are cut short so that a dump stays readable: payloads
+// after decompression, ACK reasons, and the IDs listed per ACK.
+const (
+	captureMaxBytes  = 4096
+	captureMaxReason = 256
+	captureMaxIDs    = 16
+)
+
+// capture keeps the last published payloads and received ACKs for
+// post-mortem debugging (PIPELINE_CAPTURE_SIZE), in two rings. Recording
+// copies the compressed payload into a slot reused on the next lap;
+// decompressing and cutting it short wait for a dump. A nil capture
+// records nothing.
+type capture struct {
+	publishes []capturedPublish
+	acks      []capturedAck
+	mu        sync.Mutex
+	publishN  int // publishes recorded, the next slot modulo the ring size
+	ackN      int
+}
+
+type capturedPublish struct {
+	at       time.Time
+	payload  []byte
+	messages int
+}
+
+type capturedAck struct {
+	at      time.Time
+	stream  string
+	reason  string
+	ids     []string
+	idCount int
+	ack     bool
+}
+
+func newCapture(size int) *capture {
+	if size <= 0 {
+		return nil
+	}
+	return &capture{publishes: make([]capturedPublish, size), acks: make([]capturedAck, size)}
+}
+
+// published records a payload of messages entries sent to the broker.
+func (c *capture) published(payload message.Payload, messages int) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	slot := &c.publishes[c.publishN%len(c.publishes)]
+	slot.at, slot.messages = now, messages
+	slot.payload = append(slot.payload[:0], payload...)
+	c.publishN++
+	c.mu.Unlock()
+}
+
+// acked records an ACK or NACK received from the broker.
+func (c *capture) acked(ack *message.AckMessage) {
+	if c == nil {
+		return
+	}
+	ids := ack.IDs[:min(len(ack.IDs), captureMaxIDs)]
+	now := time.Now()
+	c.mu.Lock()
+	slot := &c.acks[c.ackN%len(c.acks)]
+	slot.at, slot.stream, slot.ack = now, ack.Stream, ack.Ack
+	slot.reason = ack.Reason[:min(len(ack.Reason), captureMaxReason)]
+	slot.ids, slot.idCount = append(slot.ids[:0], ids...), len(ack.IDs)
+	c.ackN++
+	c.mu.Unlock()
+}
+
+// CaptureDump is the content of the capture, oldest first.
+type CaptureDump struct {
+	Published []CapturedPublish `json:"published"`
+	Acks      []CapturedAck     `json:"acks"`
+}
+
+// CapturedPublish is one published payload. Payload is decompressed and
+// cut to its first 4 KiB, with invalid UTF-8 replaced; a payload that does
+// not decompress is shown as sent.
+type CapturedPublish struct {
+	At        time.Time `json:"at"`
+	Payload   string    `json:"payload"`
+	Messages  int       `json:"messages"`
+	Bytes     int       `json:"bytes"` // published size
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// CapturedAck is one ACK or NACK. IDs lists the first 16 of IDCount
+// entries, and Reason is cut to 256 bytes.
+type CapturedAck struct {
+	At      time.Time `json:"at"`
+	Stream  string    `json:"stream"`
+	Reason  string    `json:"reason,omitempty"`
+	IDs     []string  `json:"ids"`
+	IDCount int       `json:"id_count"`
+	Ack     bool      `json:"ack"`
+}
+
+// dump returns the content of the capture.
+func (c *capture) dump() CaptureDump {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := CaptureDump{
+		Published: make([]CapturedPublish, 0, min(c.publishN, len(c.publishes))),
+		Acks:      make([]CapturedAck, 0, min(c.ackN, len(c.acks))),
+	}
+	for i := max(c.publishN-len(c.publishes), 0); i < c.publishN; i++ {
+		d.Published = append(d.Published, sanitizePublish(&c.publishes[i%len(c.publishes)]))
+	}
+	for i := max(c.ackN-len(c.acks), 0); i < c.ackN; i++ {
+		slot := &c.acks[i%len(c.acks)]
+		d.Acks = append(d.Acks, CapturedAck{
+			At:      slot.at,
+			Stream:  strings.ToValidUTF8(slot.stream, "�"),
+			Reason:  strings.ToValidUTF8(slot.reason, "�"),
+			IDs:     append([]string(nil), slot.ids...),
+			IDCount: slot.idCount,
+			Ack:     slot.ack,
+		})
+	}
+	return d
+}
+
+func sanitizePublish(slot *capturedPublish) CapturedPublish {
+	body := slot.payload
+	if compress.IsCompressed(body) {
+		if raw, err := compress.Decompress(nil, body); err == nil {
+			body = raw
+		}
+	}
+	p := CapturedPublish{At: slot.at, Messages: slot.messages, Bytes: len(slot.payload)}
+	if len(body) > captureMaxBytes {
+		body, p.Truncated = body[:captureMaxBytes], true
+	}
+	p.Payload = strings.ToValidUTF8(string(body), "�")
+	return p
+}
+
+// Capture returns the published payloads and ACKs kept under
+// PIPELINE_CAPTURE_SIZE, oldest first, and false when it is 0.
+func (hp *HotPath) Capture() (CaptureDump, bool) {
+	if hp.capture == nil {
+		return CaptureDump{}, false
+	}
+	return hp.capture.dump(), true
+}
+
+// LogCapture writes the capture to the log, one line per record, values
+// quoted so that a payload cannot forge log lines; payloads are cut to
+// LOG_PAYLOAD_PREVIEW_BYTES.
+func (hp *HotPath) LogCapture(ctx context.Context) {
+	d, ok := hp.Capture()
+	if !ok {
+		hp.log.Infof(ctx, "Capture: PIPELINE_CAPTURE_SIZE is 0, nothing captured")
+		return
+	}
+	hp.log.Infof(ctx, "Capture: %d published payloads and %d ACKs, oldest first", len(d.Published), len(d.Acks))
+	for i := range d.Published {
+		p := &d.Published[i]
+		hp.log.Infof(ctx, "Capture: published at %s, %d messages, %d bytes (truncated %t): %s",
+			p.At.Format(time.RFC3339Nano), p.Messages, p.Bytes, p.Truncated, hp.log.Preview([]byte(p.Payload)))
+	}
+	for i := range d.Acks {
+		a := &d.Acks[i]
+		hp.log.Infof(ctx, "Capture: ACK at %s, stream %q, ack %t, %d IDs %q, reason %q",
+			a.At.Format(time.RFC3339Nano), a.Stream, a.Ack, a.IDCount, a.IDs, a.Reason)
+	}
+}
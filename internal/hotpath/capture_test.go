@@ -0,0 +1,81 @@
+package hotpath
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestNewCapture_DisabledIsNilSafe(t *testing.T) {
+	c := newCapture(0)
+	if c != nil {
+		t.Fatal("newCapture(0) != nil; want the capture disabled")
+	}
+	c.published([]byte("x"), 1)
+	c.acked(&message.AckMessage{Stream: "s", IDs: []string{"1-0"}})
+}
+
+func TestCapture_KeepsTheLastRecordsOldestFirst(t *testing.T) {
+	c := newCapture(2)
+	for _, body := range []string{"a", "b", "c"} {
+		payload := []byte(body)
+		c.published(payload, 1)
+		payload[0] = 'z' // the caller reuses its buffer
+	}
+	for _, id := range []string{"1-0", "2-0", "3-0"} {
+		c.acked(&message.AckMessage{Stream: "s", IDs: []string{id}, Ack: true})
+	}
+
+	d := c.dump()
+	var bodies, ids []string
+	for _, p := range d.Published {
+		bodies = append(bodies, p.Payload)
+	}
+	for _, a := range d.Acks {
+		ids = append(ids, a.IDs...)
+	}
+	if !slices.Equal(bodies, []string{"b", "c"}) {
+		t.Errorf("published = %q; want the last two payloads, as sent", bodies)
+	}
+	if !slices.Equal(ids, []string{"2-0", "3-0"}) {
+		t.Errorf("acked IDs = %q; want the last two ACKs", ids)
+	}
+}
+
+func TestCapture_SanitizesRecords(t *testing.T) {
+	compress.Init(&config.CompressConfig{FreelistSize: 1, MaxDecompressBytes: 1 << 20})
+	c := newCapture(1)
+	raw := strings.Repeat("x", captureMaxBytes+10)
+	c.published(compress.EncodeWith(compress.NewEncoder(), nil, []byte(raw)), 3)
+	ids := make([]string, captureMaxIDs+4)
+	for i := range ids {
+		ids[i] = "1-0"
+	}
+	c.acked(&message.AckMessage{Stream: "s", Reason: strings.Repeat("r", 1000) + "\xff", IDs: ids})
+
+	d := c.dump()
+	p := d.Published[0]
+	if len(p.Payload) != captureMaxBytes || !p.Truncated || p.Messages != 3 || p.Bytes >= len(raw) {
+		t.Errorf("published = %d bytes, truncated %t, %d messages, %d sent; want the first %d decompressed bytes",
+			len(p.Payload), p.Truncated, p.Messages, p.Bytes, captureMaxBytes)
+	}
+	a := d.Acks[0]
+	if len(a.IDs) != captureMaxIDs || a.IDCount != len(ids) {
+		t.Errorf("ACK IDs = %d of %d; want %d of %d", len(a.IDs), a.IDCount, captureMaxIDs, len(ids))
+	}
+	if len(a.Reason) != captureMaxReason {
+		t.Errorf("ACK reason = %d bytes; want it cut to %d", len(a.Reason), captureMaxReason)
+	}
+}
+
+func TestCapture_InvalidUTF8IsReplaced(t *testing.T) {
+	c := newCapture(1)
+	c.published([]byte("ok\xffok"), 1)
+	if got := c.dump().Published[0].Payload; got != "ok�ok" {
+		t.Errorf("payload = %q; want invalid bytes replaced", got)
+	}
+}
@@ -0,0 +1,55 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// compactor is implemented by *redis.Client; REDIS_COMPACT_INTERVAL
+// requires it.
+type compactor interface {
+	CompactStreams(ctx context.Context) error
+}
+
+func validateCompaction(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if cfg.CompactInterval == 0 {
+		return nil
+	}
+	if _, ok := redisClient.(compactor); !ok {
+		return errors.New("hotpath: stream compaction needs a redis client that can compact streams")
+	}
+	return nil
+}
+
+// newCompactor returns the client's compactor, or nil when
+// REDIS_COMPACT_INTERVAL is off.
+func newCompactor(redisClient redis.StreamClient, cfg *config.RedisConfig) compactor {
+	if cfg.CompactInterval == 0 {
+		return nil
+	}
+	c, _ := redisClient.(compactor)
+	return c
+}
+
+// compactLoop deletes the acknowledged entries left in the streams every
+// REDIS_COMPACT_INTERVAL. The client logs and counts what it deletes and
+// the streams it fails on.
+func (hp *HotPath) compactLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.compactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := hp.compactor.CompactStreams(ctx); err != nil && ctx.Err() == nil {
+				hp.log.Errorf(ctx, "Failed to compact streams: %v", err)
+				hp.events.failed("compact", 0, err)
+			}
+		}
+	}
+}
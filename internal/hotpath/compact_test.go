@@ -0,0 +1,65 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+// compactRedis is a mockRedis that counts its compactions.
+type compactRedis struct {
+	mockRedis
+	compactions atomic.Int32
+}
+
+func (m *compactRedis) CompactStreams(context.Context) error {
+	m.compactions.Add(1)
+	return nil
+}
+
+func TestCompactLoop(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.CompactInterval = 5 * time.Millisecond
+	rdb := &compactRedis{}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.compactLoop(ctx) }()
+	deadline := time.Now().Add(time.Second)
+	for rdb.compactions.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("compactLoop() = %v; want context.Canceled", err)
+	}
+	if n := rdb.compactions.Load(); n < 2 {
+		t.Errorf("compactions = %d; want one per interval", n)
+	}
+}
+
+func TestNew_CompactionRequiresCompactor(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.CompactInterval = time.Minute
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without CompactStreams")
+	}
+
+	hp, err := New(&compactRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.compactor != nil {
+		t.Error("compactor != nil; want none without REDIS_COMPACT_INTERVAL")
+	}
+}
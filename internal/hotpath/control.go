@@ -0,0 +1,234 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// controlReader is implemented by *redis.Client; REDIS_CONTROL_KEY
+// requires it.
+type controlReader interface {
+	ReadControl(ctx context.Context) (map[string]string, error)
+}
+
+// claimTurner is implemented by *redis.Client; it makes claim_interval set
+// the length of the claim turns under REDIS_CLAIM_COORDINATION.
+type claimTurner interface {
+	SetClaimInterval(d time.Duration)
+}
+
+// Fields of the control hash.
+const (
+	controlLogLevel      = "log_level"
+	controlClaimInterval = "claim_interval"
+	controlPaused        = "paused"
+)
+
+// pausePoll is how often a paused fetch loop checks whether it may resume.
+// It stays short so the loop keeps beating for the systemd watchdog.
+const pausePoll = 100 * time.Millisecond
+
+// runtimeControl applies the REDIS_CONTROL_KEY hash, read every
+// REDIS_CONTROL_INTERVAL, so a fleet can be tuned where no admin endpoint
+// is reachable: log_level sets the log level, claim_interval how often the
+// claim loop runs, up to REDIS_CLAIM_IDLE, and a true paused stops the
+// fetch and claim loops from taking new entries while those in flight are
+// still published and acknowledged. A field that is removed goes back to the configured
+// setting; an invalid one is logged and ignored. A nil runtimeControl
+// never pauses.
+type runtimeControl struct {
+	reader    controlReader
+	claims    *time.Ticker      // the claim loop's
+	turns     claimTurner       // nil without REDIS_CLAIM_COORDINATION
+	applied   map[string]string // the fields in effect; control loop only
+	logLevel  string            // configured
+	claimTick time.Duration     // configured
+	claimIdle time.Duration     // bounds claim_interval
+	interval  time.Duration
+	stopped   atomic.Bool
+	// coordinated ticks redis.ClaimTurnChecks times per claim interval.
+	coordinated bool
+}
+
+func validateControl(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if cfg.ControlKey == "" {
+		return nil
+	}
+	if _, ok := redisClient.(controlReader); !ok {
+		return errors.New("hotpath: the control hash needs a redis client that can read hashes")
+	}
+	return nil
+}
+
+func newRuntimeControl(redisClient redis.StreamClient, cfg *config.Config, claims *time.Ticker) *runtimeControl {
+	if cfg.Redis.ControlKey == "" {
+		return nil
+	}
+	reader, _ := redisClient.(controlReader)
+	c := &runtimeControl{
+		reader:      reader,
+		claims:      claims,
+		applied:     make(map[string]string),
+		logLevel:    cfg.Log.Level,
+		claimTick:   claimTickInterval(&cfg.Redis),
+		claimIdle:   cfg.Redis.ClaimIdle,
+		interval:    cfg.Redis.ControlInterval,
+		coordinated: cfg.Redis.ClaimCoordination,
+	}
+	if c.coordinated {
+		c.turns, _ = redisClient.(claimTurner)
+	}
+	return c
+}
+
+// paused reports whether the control hash pauses the pipeline.
+func (c *runtimeControl) paused() bool {
+	return c != nil && c.stopped.Load()
+}
+
+// waitResume waits until the control hash no longer pauses the pipeline,
+// recording the fetch loop's rounds meanwhile: a paused loop is idle, not
+// stuck.
+func (hp *HotPath) waitResume(ctx context.Context) error {
+	for hp.control.paused() {
+		hp.live.tick()
+		if err := sleepCtx(ctx, pausePoll); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// controlLoop reads the control hash every REDIS_CONTROL_INTERVAL,
+// starting right away so a fleet paused before a restart stays paused.
+func (hp *HotPath) controlLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.control.interval)
+	defer ticker.Stop()
+	for {
+		wasPaused := hp.control.paused()
+		hp.control.check(ctx, hp.log)
+		if paused := hp.control.paused(); paused != wasPaused {
+			hp.transitions.Record(pauseTransition(paused), map[string]any{"source": "control"})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pauseTransition is the lifecycle event of the pipeline becoming paused,
+// or resuming.
+func pauseTransition(paused bool) lifecycle.Kind {
+	if paused {
+		return lifecycle.Paused
+	}
+	return lifecycle.Resumed
+}
+
+// check reads the hash and applies the fields that changed. A failed read
+// keeps the settings in effect.
+func (c *runtimeControl) check(ctx context.Context, logger *log.Logger) {
+	fields, err := c.reader.ReadControl(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf(ctx, "Failed to read the control hash: %v", err)
+		}
+		return
+	}
+	for _, name := range []string{controlLogLevel, controlClaimInterval, controlPaused} {
+		value, set := fields[name]
+		if prev, had := c.applied[name]; set == had && value == prev {
+			continue
+		}
+		if !set {
+			delete(c.applied, name)
+			c.restore(ctx, logger, name)
+			continue
+		}
+		c.applied[name] = value
+		if err := c.apply(logger, name, value); err != nil {
+			logger.Warnf(ctx, "Ignoring control field %s=%q: %v", name, value, err)
+			continue
+		}
+		logger.Infof(ctx, "Control hash set %s to %s", name, value)
+	}
+}
+
+func (c *runtimeControl) apply(logger *log.Logger, name, value string) error {
+	switch name {
+	case controlLogLevel:
+		if !log.IsLevel(value) {
+			return errors.New("unknown log level")
+		}
+		logger.SetLevel(value)
+	case controlClaimInterval:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return errors.New("want a positive duration such as 30s")
+		}
+		if d > c.claimIdle {
+			return fmt.Errorf("want at most the claim idle time, %s", c.claimIdle)
+		}
+		c.claims.Reset(c.tick(d))
+		c.setTurns(d)
+	case controlPaused:
+		paused, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("want true or false")
+		}
+		c.pause(paused)
+	}
+	return nil
+}
+
+// restore puts back the configured setting of a field removed from the
+// hash.
+func (c *runtimeControl) restore(ctx context.Context, logger *log.Logger, name string) {
+	switch name {
+	case controlLogLevel:
+		logger.SetLevel(c.logLevel)
+	case controlClaimInterval:
+		c.claims.Reset(c.claimTick)
+		c.setTurns(0)
+	case controlPaused:
+		c.pause(false)
+	}
+	logger.Infof(ctx, "Control hash dropped %s, back to the configured setting", name)
+}
+
+// tick returns how often the claim loop runs for a claim interval of d.
+func (c *runtimeControl) tick(d time.Duration) time.Duration {
+	if c.coordinated {
+		return max(d/redis.ClaimTurnChecks, time.Millisecond)
+	}
+	return d
+}
+
+// setTurns sets the length of the claim turns to d, 0 for the configured
+// claim interval.
+func (c *runtimeControl) setTurns(d time.Duration) {
+	if c.turns != nil {
+		c.turns.SetClaimInterval(d)
+	}
+}
+
+func (c *runtimeControl) pause(paused bool) {
+	c.stopped.Store(paused)
+	var flag int64
+	if paused {
+		flag = 1
+	}
+	metrics.ControlPaused.Set(flag)
+}
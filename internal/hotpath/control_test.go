@@ -0,0 +1,229 @@
+package hotpath
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// controlRedis is a mockRedis whose control hash holds fields.
+type controlRedis struct {
+	fields map[string]string
+	mockRedis
+	mu sync.Mutex
+}
+
+func (m *controlRedis) ReadControl(context.Context) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.fields), nil
+}
+
+func (m *controlRedis) set(fields map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fields = fields
+}
+
+func controlConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Log.Level = "info"
+	cfg.Redis.ControlKey = "syslog-consumer:control"
+	cfg.Redis.ControlInterval = time.Second
+	return cfg
+}
+
+func TestRuntimeControl_AppliesAndRestores(t *testing.T) {
+	rdb := &controlRedis{}
+	logger := log.New()
+	hp, err := New(rdb, &mockPublisher{}, controlConfig(), logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	rdb.set(map[string]string{"log_level": "debug", "claim_interval": "2s", "paused": "true"})
+	hp.control.check(t.Context(), logger)
+	if logger.Level() != slog.LevelDebug || !hp.control.paused() || metrics.ControlPaused.Value() != 1 {
+		t.Errorf("level = %v, paused = %v; want debug and paused", logger.Level(), hp.control.paused())
+	}
+
+	rdb.set(map[string]string{"log_level": "verbose", "claim_interval": "soon", "paused": "maybe"})
+	hp.control.check(t.Context(), logger)
+	if logger.Level() != slog.LevelDebug || !hp.control.paused() {
+		t.Errorf("level = %v, paused = %v; want invalid fields ignored", logger.Level(), hp.control.paused())
+	}
+
+	rdb.set(nil)
+	hp.control.check(t.Context(), logger)
+	if logger.Level() != slog.LevelInfo || hp.control.paused() || metrics.ControlPaused.Value() != 0 {
+		t.Errorf("level = %v, paused = %v; want the configured settings back", logger.Level(), hp.control.paused())
+	}
+}
+
+func TestRuntimeControl_ClaimIntervalBoundedByClaimIdle(t *testing.T) {
+	rdb := &controlRedis{}
+	logger := log.New()
+	hp, err := New(rdb, &mockPublisher{}, controlConfig(), logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	if err := hp.control.apply(logger, controlClaimInterval, "1m"); err == nil {
+		t.Error("apply(claim_interval=1m) error = nil; want error past the 30s claim idle time")
+	}
+	if err := hp.control.apply(logger, controlClaimInterval, "30s"); err != nil {
+		t.Errorf("apply(claim_interval=30s) error = %v; want nil", err)
+	}
+}
+
+// turnRedis is a controlRedis that records the claim interval of its claim
+// turns.
+type turnRedis struct {
+	controlRedis
+	turns atomic.Int64
+}
+
+func (m *turnRedis) SetClaimInterval(d time.Duration) {
+	m.turns.Store(int64(d))
+}
+
+func TestRuntimeControl_ClaimIntervalSetsClaimTurns(t *testing.T) {
+	rdb := &turnRedis{}
+	logger := log.New()
+	cfg := controlConfig()
+	cfg.Redis.ClaimCoordination = true
+	hp, err := New(rdb, &mockPublisher{}, cfg, logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	rdb.set(map[string]string{"claim_interval": "2s"})
+	hp.control.check(t.Context(), logger)
+	if got := time.Duration(rdb.turns.Load()); got != 2*time.Second {
+		t.Errorf("claim turn interval = %v; want 2s", got)
+	}
+	rdb.set(nil)
+	hp.control.check(t.Context(), logger)
+	if got := rdb.turns.Load(); got != 0 {
+		t.Errorf("claim turn interval = %v; want 0, the configured one", time.Duration(got))
+	}
+}
+
+func TestRun_ControlPausesFetchAndRetunesClaims(t *testing.T) {
+	var reads, claims atomic.Int64
+	rdb := &controlRedis{fields: map[string]string{"paused": "1", "claim_interval": "10ms"}}
+	rdb.readBatchFn = func(context.Context) (message.Batch, error) {
+		reads.Add(1)
+		return message.Batch{}, nil
+	}
+	rdb.claimIdleFn = func(context.Context) (message.Batch, error) {
+		claims.Add(1)
+		return message.Batch{}, nil
+	}
+	cfg := controlConfig()
+	cfg.Redis.ControlInterval = 10 * time.Millisecond
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	hp.control.check(t.Context(), hp.log) // paused before the fetch loop starts
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+
+	time.Sleep(200 * time.Millisecond)
+	if reads.Load() != 0 || claims.Load() != 0 {
+		t.Errorf("reads = %d, claims = %d; want none while paused", reads.Load(), claims.Load())
+	}
+	if !hp.Alive(time.Second) {
+		t.Error("Alive() = false; want a paused fetch loop to keep beating")
+	}
+
+	rdb.set(map[string]string{"claim_interval": "10ms"})
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	checkLoopExit(t, <-done)
+	if reads.Load() == 0 || claims.Load() < 5 {
+		t.Errorf("reads = %d, claims = %d; want reads, and claims every 10ms, once resumed", reads.Load(), claims.Load())
+	}
+}
+
+func TestControlLoop_RecordsPauseAndResume(t *testing.T) {
+	rdb := &controlRedis{fields: map[string]string{"paused": "true"}}
+	cfg := controlConfig()
+	cfg.Redis.ControlInterval = 10 * time.Millisecond
+	cfg.Pipeline.LifecycleLog = filepath.Join(t.TempDir(), "lifecycle.jsonl")
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	transitions, err := lifecycle.New(cfg, hp.log)
+	if err != nil {
+		t.Fatalf("lifecycle.New() error = %v", err)
+	}
+	hp.SetLifecycle(transitions)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.controlLoop(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	rdb.set(nil)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+	if err := transitions.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.Pipeline.LifecycleLog)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var kinds []lifecycle.Kind
+	for line := range strings.Lines(string(data)) {
+		var e lifecycle.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		kinds = append(kinds, e.Kind)
+	}
+	if !slices.Equal(kinds, []lifecycle.Kind{lifecycle.Paused, lifecycle.Resumed}) {
+		t.Errorf("events = %v; want [paused resumed], once each", kinds)
+	}
+}
+
+func TestNew_ControlRequirements(t *testing.T) {
+	if _, err := New(&mockRedis{}, &mockPublisher{}, controlConfig(), log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without ReadControl")
+	}
+
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.control != nil || hp.control.paused() {
+		t.Error("control != nil; want no control hash without REDIS_CONTROL_KEY")
+	}
+}
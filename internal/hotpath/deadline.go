@@ -0,0 +1,47 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// errPublishDeadline is the cause of a publish that ran out of
+// PIPELINE_PUBLISH_TIMEOUT.
+var errPublishDeadline = errors.New("publish deadline exceeded")
+
+// publishWithin runs publishFn under PIPELINE_PUBLISH_TIMEOUT, so a slow
+// broker cannot hold the worker past it, whatever MQTT_WRITE_TIMEOUT
+// allows. A publish past the deadline returns an error wrapping
+// errPublishDeadline.
+func (hp *HotPath) publishWithin(
+	ctx context.Context, payload message.Payload,
+	publishFn func(context.Context, message.Payload) error,
+) error {
+	if hp.publishTimeout <= 0 {
+		return publishFn(ctx, payload)
+	}
+	pubCtx, cancel := context.WithTimeoutCause(ctx, hp.publishTimeout, errPublishDeadline)
+	defer cancel()
+	err := publishFn(pubCtx, payload)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(pubCtx), errPublishDeadline) {
+		return fmt.Errorf("%w after %s: %w", errPublishDeadline, hp.publishTimeout, err)
+	}
+	return err
+}
+
+// publishAbandoned accounts for a publish given up on at its deadline.
+// Its entries stay pending for the claim loop; paho may still deliver the
+// payload, which the receiver then gets twice.
+func (hp *HotPath) publishAbandoned(ctx context.Context, err error, messages int) bool {
+	if !errors.Is(err, errPublishDeadline) {
+		return false
+	}
+	hp.log.Warnf(ctx, "Abandoned publish of %d messages, left pending: %v", messages, err)
+	metrics.PublishAbandoned.Add(int64(messages))
+	hp.events.failed("publish", messages, err)
+	return true
+}
@@ -0,0 +1,69 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// blockingPublish waits for its context, as a publish to a broker that
+// never answers does.
+func blockingPublish(ctx context.Context, _ message.Payload) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSendPayload_AbandonsPublishPastTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.PublishTimeout = 20 * time.Millisecond
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	abandoned := metrics.PublishAbandoned.Value()
+	failures := metrics.PublishErrors.Value()
+
+	start := time.Now()
+	if hp.sendPayload(t.Context(), []byte("payload"), 2, []byte("1-0\ta\t{}\n2-0\ta\t{}\n"), nil, blockingPublish) {
+		t.Fatal("sendPayload() = true; want false past the publish timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sendPayload() took %s; want it abandoned after 20ms", elapsed)
+	}
+	if got := metrics.PublishAbandoned.Value() - abandoned; got != 2 {
+		t.Errorf("publish_abandoned grew by %d; want 2", got)
+	}
+	if got := metrics.PublishErrors.Value() - failures; got != 0 {
+		t.Errorf("errors_publish grew by %d; want 0 for an abandoned publish", got)
+	}
+}
+
+func TestPublishWithin(t *testing.T) {
+	hp := &HotPath{publishTimeout: 10 * time.Millisecond}
+	if err := hp.publishWithin(t.Context(), nil, blockingPublish); !errors.Is(err, errPublishDeadline) {
+		t.Errorf("publishWithin() error = %v; want errPublishDeadline", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := hp.publishWithin(ctx, nil, blockingPublish); errors.Is(err, errPublishDeadline) {
+		t.Errorf("publishWithin() error = %v; want the caller's cancellation, not the deadline", err)
+	}
+
+	hp.publishTimeout = 0
+	publishFn := func(ctx context.Context, _ message.Payload) error {
+		if _, ok := ctx.Deadline(); ok {
+			return errors.New("deadline set")
+		}
+		return nil
+	}
+	if err := hp.publishWithin(t.Context(), nil, publishFn); err != nil {
+		t.Errorf("publishWithin() error = %v; want no deadline without a publish timeout", err)
+	}
+}
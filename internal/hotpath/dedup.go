@@ -0,0 +1,86 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// deduplicator is implemented by *redis.Client; REDIS_DEDUP requires it.
+type deduplicator interface {
+	Duplicates(ctx context.Context, msgs []message.Redis) ([]bool, error)
+}
+
+// validateDedup checks REDIS_DEDUP and PIPELINE_PUBLISH_DEDUP.
+func validateDedup(redisClient redis.StreamClient, cfg *config.Config) error {
+	if cfg.Redis.Dedup != "" {
+		if _, ok := redisClient.(deduplicator); !ok {
+			return errors.New("hotpath: dedup needs a redis client that can detect duplicates")
+		}
+	}
+	if cfg.Pipeline.PublishDedup == config.PublishDedupRedis {
+		if _, ok := redisClient.(publishReserver); !ok {
+			return errors.New("hotpath: publish dedup in redis needs a redis client that can reserve publishes")
+		}
+	}
+	return nil
+}
+
+// newDeduplicator returns nil when REDIS_DEDUP is off.
+func newDeduplicator(redisClient redis.StreamClient, cfg *config.RedisConfig) deduplicator {
+	if cfg.Dedup == "" {
+		return nil
+	}
+	d, _ := redisClient.(deduplicator)
+	return d
+}
+
+// dropDuplicates acknowledges and removes the entries of a freshly read
+// batch whose content was already read within the dedup window. Claimed
+// and backfilled entries are redeliveries of entries this check already
+// let through, so they never come here. When the check fails the batch is
+// published whole: a duplicate is cheaper than a lost entry. The returned
+// batch is released when nothing is left of it.
+func (hp *HotPath) dropDuplicates(ctx context.Context, batch message.Batch) message.Batch {
+	if hp.dedup == nil {
+		return batch
+	}
+	batch.Decode()
+	dups, err := hp.dedup.Duplicates(ctx, batch.Items)
+	if err != nil {
+		hp.log.Warnf(ctx, "Dedup check failed, publishing %d messages unchecked: %v", len(batch.Items), err)
+		metrics.DedupErrors.Add(1)
+		return batch
+	}
+
+	idsByStream := make(map[string][]string)
+	dropped := 0
+	for i, dup := range dups {
+		if dup {
+			idsByStream[batch.Items[i].Stream] = append(idsByStream[batch.Items[i].Stream], batch.Items[i].ID)
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return batch
+	}
+	batch.Compact(dups)
+	metrics.MessagesDeduplicated.Add(int64(dropped))
+
+	for stream, ids := range idsByStream {
+		hp.events.dropped("duplicate", stream, ids)
+		// Duplicates left pending are claimed and published later.
+		if err := hp.redis.AckAndDeleteBatch(ctx, ids, stream); err != nil {
+			hp.log.Warnf(ctx, "Failed to acknowledge %d duplicates on %s: %v", len(ids), stream, err)
+			metrics.AckErrors.Add(int64(len(ids)))
+		}
+	}
+	if len(batch.Items) == 0 {
+		batch.Release()
+	}
+	return batch
+}
@@ -0,0 +1,120 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// dedupRedis is a mockRedis that also implements deduplicator, flagging
+// every raw line it has seen before.
+type dedupRedis struct {
+	err error
+	mockRedis
+	seen  map[string]bool
+	acked map[string][]string
+}
+
+func (m *dedupRedis) Duplicates(_ context.Context, msgs []message.Redis) ([]bool, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	dups := make([]bool, len(msgs))
+	for i := range msgs {
+		dups[i] = m.seen[msgs[i].Raw]
+		m.seen[msgs[i].Raw] = true
+	}
+	return dups, nil
+}
+
+func newDedupHotPath(t *testing.T) (*HotPath, *dedupRedis) {
+	t.Helper()
+	redisClient := &dedupRedis{seen: map[string]bool{}, acked: map[string][]string{}}
+	redisClient.ackAndDeleteFn = func(_ context.Context, ids []string, stream string) error {
+		redisClient.acked[stream] = append(redisClient.acked[stream], ids...)
+		return nil
+	}
+	cfg := testConfig()
+	cfg.Redis.Dedup = config.DedupKeys
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, redisClient
+}
+
+func TestNew_DedupRequiresDeduplicator(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.Dedup = config.DedupBloom
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without Duplicates")
+	}
+}
+
+func TestDropDuplicates(t *testing.T) {
+	hp, redisClient := newDedupHotPath(t)
+	before := metrics.MessagesDeduplicated.Value()
+
+	batch := hp.dropDuplicates(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "a", Raw: "x"},
+		{ID: "2-0", Stream: "a", Raw: "y"},
+		{ID: "3-0", Stream: "a", Raw: "x"},
+		{ID: "1-0", Stream: "b", Raw: "y"},
+	}})
+
+	if len(batch.Items) != 2 || batch.Items[0].ID != "1-0" || batch.Items[1].ID != "2-0" {
+		t.Errorf("kept = %+v; want the first copies only", batch.Items)
+	}
+	if !slices.Equal(redisClient.acked["a"], []string{"3-0"}) || !slices.Equal(redisClient.acked["b"], []string{"1-0"}) {
+		t.Errorf("acked = %v; want each duplicate acknowledged on its stream", redisClient.acked)
+	}
+	if got := metrics.MessagesDeduplicated.Value() - before; got != 2 {
+		t.Errorf("MessagesDeduplicated delta = %d; want 2", got)
+	}
+}
+
+func TestDropDuplicates_FailsOpen(t *testing.T) {
+	hp, redisClient := newDedupHotPath(t)
+	redisClient.err = errors.New("redis down")
+	before := metrics.DedupErrors.Value()
+
+	batch := hp.dropDuplicates(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "a", Raw: "x"},
+		{ID: "2-0", Stream: "a", Raw: "x"},
+	}})
+
+	if len(batch.Items) != 2 || len(redisClient.acked) != 0 {
+		t.Errorf("kept %d, acked %v; want the batch untouched when the check fails", len(batch.Items), redisClient.acked)
+	}
+	if got := metrics.DedupErrors.Value() - before; got != 1 {
+		t.Errorf("DedupErrors delta = %d; want 1", got)
+	}
+}
+
+func TestFetchLoop_SkipsAllDuplicateBatch(t *testing.T) {
+	hp, redisClient := newDedupHotPath(t)
+	redisClient.seen["x"] = true
+	reads := 0
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	redisClient.readBatchFn = func(context.Context) (message.Batch, error) {
+		reads++
+		if reads > 1 {
+			cancel()
+			return message.Batch{}, nil
+		}
+		return message.Batch{Items: []message.Redis{{ID: "1-0", Stream: "a", Raw: "x"}}}, nil
+	}
+
+	checkLoopExit(t, hp.fetchLoop(ctx))
+	if got := queuedIDs(hp.msgChan); len(got) != 0 {
+		t.Errorf("queued = %v; want nothing for a batch of duplicates", got)
+	}
+}
@@ -0,0 +1,232 @@
+package hotpath
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// hashLookup is implemented by *redis.Client; PIPELINE_ENRICH_KEY requires
+// it.
+type hashLookup interface {
+	LookupHashes(ctx context.Context, keys, fields []string) ([]map[string]string, error)
+}
+
+// enrichment applies PIPELINE_ENRICH_KEY: the value of the key field of
+// each payload names the Redis hash PIPELINE_ENRICH_HASH_PREFIX+value,
+// whose PIPELINE_ENRICH_FIELDS are added to the published record. A publish
+// worker looks up the values of a batch missing from the cache in one
+// pipeline before building its records, which then read the cache only.
+// Values without a hash are cached too, so unknown keys cost one lookup
+// per TTL as well.
+type enrichment struct {
+	lookup hashLookup
+	cache  map[string]enrichEntry
+	prefix string
+	key    []byte
+	fields []string
+	fkeys  []jsonfast.FieldKey
+	ttl    time.Duration
+	size   int
+	mu     sync.RWMutex
+}
+
+type enrichEntry struct {
+	expires time.Time
+	found   map[string]string
+}
+
+// enrichMatch is what building one record learned about its payload: the
+// value of the key field and which of the fields it already has, by bit.
+type enrichMatch struct {
+	value   []byte
+	present uint64
+}
+
+func validateEnrichment(redisClient redis.StreamClient, cfg *config.PipelineConfig) error {
+	if cfg.EnrichKey == "" {
+		return nil
+	}
+	if _, ok := redisClient.(hashLookup); !ok {
+		return errors.New("hotpath: enrichment needs a redis client that can look up hashes")
+	}
+	return nil
+}
+
+// newEnrichment returns nil when PIPELINE_ENRICH_KEY is unset. The hashes
+// are looked up under REDIS_KEY_PREFIX.
+func newEnrichment(redisClient redis.StreamClient, cfg *config.Config) *enrichment {
+	p := &cfg.Pipeline
+	if p.EnrichKey == "" {
+		return nil
+	}
+	lookup, _ := redisClient.(hashLookup)
+	e := &enrichment{
+		lookup: lookup,
+		cache:  make(map[string]enrichEntry),
+		prefix: cfg.Redis.KeyPrefix + p.EnrichHashPrefix,
+		key:    []byte(p.EnrichKey),
+		fields: p.EnrichFields,
+		fkeys:  make([]jsonfast.FieldKey, len(p.EnrichFields)),
+		ttl:    p.EnrichCacheTTL,
+		size:   p.EnrichCacheSize,
+	}
+	for i, field := range p.EnrichFields {
+		e.fkeys[i] = jsonfast.NewFieldKey(field)
+	}
+	return e
+}
+
+// prefetchEnrichment caches the hashes of the key values of batch that are
+// not cached yet or have expired. On a failed lookup the entries are
+// published without the fields that are not cached.
+func (hp *HotPath) prefetchEnrichment(ctx context.Context, batch *message.Batch) {
+	e := hp.enrich
+	if e == nil {
+		return
+	}
+	now := time.Now()
+	values := e.missing(batch, now)
+	if len(values) == 0 {
+		return
+	}
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = e.prefix + v
+	}
+	found, err := e.lookup.LookupHashes(ctx, names, e.fields)
+	if err != nil {
+		hp.log.Warnf(ctx, "Failed to look up %d enrichment keys: %v", len(values), err)
+		metrics.EnrichErrors.Add(1)
+		return
+	}
+	metrics.EnrichLookups.Add(int64(len(values)))
+	e.store(values, found, now)
+}
+
+// missing returns the distinct key values of batch without a live cache
+// entry, and counts the others as cache hits.
+func (e *enrichment) missing(batch *message.Batch, now time.Time) []string {
+	var values []string
+	var seen map[string]struct{}
+	hits := 0
+	e.mu.RLock()
+	for _, msg := range batch.Indexed() {
+		v := keyValue(orderingValue(msg, e.key))
+		if v == nil {
+			continue
+		}
+		if entry, ok := e.cache[string(v)]; ok && now.Before(entry.expires) {
+			hits++
+			continue
+		}
+		if _, ok := seen[string(v)]; ok {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[string]struct{})
+		}
+		seen[string(v)] = struct{}{}
+		values = append(values, string(v))
+	}
+	e.mu.RUnlock()
+	metrics.EnrichCacheHits.Add(int64(hits))
+	return values
+}
+
+// store caches found, the hashes of values, making room past the size
+// limit by dropping expired entries first, then arbitrary ones.
+func (e *enrichment) store(values []string, found []map[string]string, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache)+len(values) > e.size {
+		for v, entry := range e.cache {
+			if now.After(entry.expires) {
+				delete(e.cache, v)
+			}
+		}
+		for v := range e.cache {
+			if len(e.cache)+len(values) <= e.size {
+				break
+			}
+			delete(e.cache, v)
+		}
+	}
+	expires := now.Add(e.ttl)
+	for i, v := range values {
+		e.cache[v] = enrichEntry{expires: expires, found: found[i]}
+	}
+}
+
+// observe records what the top-level field name with value of the payload
+// means for its enrichment.
+func (e *enrichment) observe(m *enrichMatch, name, value []byte) {
+	if e == nil {
+		return
+	}
+	if bytes.Equal(name, e.key) {
+		m.value = value
+	}
+	for i, field := range e.fields {
+		if string(name) == field {
+			m.present |= 1 << i
+		}
+	}
+}
+
+// add writes the cached fields of the payload m describes that the payload
+// does not have already. An expired entry is still used: the worker
+// refreshed the batch's keys just before.
+func (e *enrichment) add(builder *jsonfast.Builder, m *enrichMatch) {
+	if e == nil {
+		return
+	}
+	v := keyValue(m.value)
+	if v == nil {
+		return
+	}
+	e.mu.RLock()
+	entry, ok := e.cache[string(v)]
+	e.mu.RUnlock()
+	if !ok || len(entry.found) == 0 {
+		return
+	}
+	for i, field := range e.fields {
+		if m.present&(1<<i) != 0 {
+			continue
+		}
+		if value, ok := entry.found[field]; ok {
+			builder.AddStringFieldKey(e.fkeys[i], value)
+		}
+	}
+	metrics.MessagesEnriched.Add(1)
+}
+
+// keyValue returns the lookup key a raw JSON value stands for: a string
+// without its quotes, a number or boolean as written, nil for null,
+// objects and arrays.
+func keyValue(value []byte) []byte {
+	if len(value) == 0 {
+		return nil
+	}
+	switch value[0] {
+	case '"':
+		if len(value) < 3 {
+			return nil
+		}
+		return value[1 : len(value)-1]
+	case '{', '[', 'n':
+		return nil
+	default:
+		return value
+	}
+}
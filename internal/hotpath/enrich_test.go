@@ -0,0 +1,162 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// enrichRedis is a mockRedis that also implements hashLookup.
+type enrichRedis struct {
+	mockRedis
+	hashes map[string]map[string]string
+	err    error
+	looked [][]string
+}
+
+func (m *enrichRedis) LookupHashes(_ context.Context, keys, fields []string) ([]map[string]string, error) {
+	m.looked = append(m.looked, keys)
+	if m.err != nil {
+		return nil, m.err
+	}
+	found := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		found[i] = make(map[string]string)
+		for _, field := range fields {
+			if v, ok := m.hashes[key][field]; ok {
+				found[i][field] = v
+			}
+		}
+	}
+	return found, nil
+}
+
+func enrichHotPath(t *testing.T, redisClient *enrichRedis, size int) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.EnrichKey = "host"
+	cfg.Pipeline.EnrichHashPrefix = "hosts:"
+	cfg.Pipeline.EnrichFields = []string{"site", "rack", "owner"}
+	cfg.Pipeline.EnrichCacheTTL = time.Minute
+	cfg.Pipeline.EnrichCacheSize = size
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func enrichedPayload(t *testing.T, hp *HotPath, msg *message.Redis) string {
+	t.Helper()
+	var stats projectionStats
+	_, _, got := parseLine(t, hp.buildProjectedPayload(jsonfast.New(512), msg, &stats))
+	return got
+}
+
+func TestEnrichment_AddsCachedFields(t *testing.T) {
+	redisClient := &enrichRedis{hashes: map[string]map[string]string{
+		"hosts:10.0.0.1": {"site": "milan", "rack": "r12", "owner": "net"},
+	}}
+	hp := enrichHotPath(t, redisClient, 100)
+
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: `{"host":"10.0.0.1","site":"own"}`, Raw: "a"},
+		{ID: "2-0", Stream: testStreamSimp, Object: `{"host":"10.0.0.1"}`, Raw: "b"},
+		{ID: "3-0", Stream: testStreamSimp, Object: `{"host":"10.0.0.9"}`, Raw: "c"},
+		{ID: "4-0", Stream: testStreamSimp, Object: `{"other":1}`, Raw: "d"},
+	}}
+	hp.prefetchEnrichment(t.Context(), &batch)
+	if len(redisClient.looked) != 1 || !slices.Equal(redisClient.looked[0], []string{"hosts:10.0.0.1", "hosts:10.0.0.9"}) {
+		t.Fatalf("lookups = %v; want one of both distinct keys", redisClient.looked)
+	}
+
+	tests := []struct {
+		msg  *message.Redis
+		want string
+	}{
+		{&batch.Items[0], `{"host":"10.0.0.1","site":"own","rack":"r12","owner":"net","raw":"a"}`},
+		{&batch.Items[1], `{"host":"10.0.0.1","site":"milan","rack":"r12","owner":"net","raw":"b"}`},
+		{&batch.Items[2], `{"host":"10.0.0.9","raw":"c"}`},
+		{&batch.Items[3], `{"other":1,"raw":"d"}`},
+	}
+	for _, tt := range tests {
+		if got := enrichedPayload(t, hp, tt.msg); !jsonEqual([]byte(got), []byte(tt.want)) {
+			t.Errorf("payload of %s = %s; want %s", tt.msg.ID, got, tt.want)
+		}
+	}
+
+	hp.prefetchEnrichment(t.Context(), &batch)
+	if len(redisClient.looked) != 1 {
+		t.Errorf("lookups = %v; want the cached keys, found or not, not looked up again", redisClient.looked)
+	}
+}
+
+func TestEnrichment_LookupError(t *testing.T) {
+	redisClient := &enrichRedis{err: errors.New("redis down")}
+	hp := enrichHotPath(t, redisClient, 100)
+
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: `{"host":"10.0.0.1"}`, Raw: "a"},
+	}}
+	hp.prefetchEnrichment(t.Context(), &batch)
+	if got := enrichedPayload(t, hp, &batch.Items[0]); !jsonEqual([]byte(got), []byte(`{"host":"10.0.0.1","raw":"a"}`)) {
+		t.Errorf("payload = %s; want it published without enrichment", got)
+	}
+
+	redisClient.err = nil
+	hp.prefetchEnrichment(t.Context(), &batch)
+	if len(redisClient.looked) != 2 {
+		t.Errorf("lookups = %d; want the failed key looked up again", len(redisClient.looked))
+	}
+}
+
+func TestEnrichment_CacheSize(t *testing.T) {
+	redisClient := &enrichRedis{}
+	hp := enrichHotPath(t, redisClient, 2)
+
+	for _, host := range []string{"a", "b", "c"} {
+		batch := message.Batch{Items: []message.Redis{
+			{ID: "1-0", Stream: testStreamSimp, Object: `{"host":"` + host + `"}`, Raw: "x"},
+		}}
+		hp.prefetchEnrichment(t.Context(), &batch)
+	}
+	if n := len(hp.enrich.cache); n != 2 {
+		t.Errorf("cached keys = %d; want the size limit of 2", n)
+	}
+}
+
+func TestEnrichment_NeedsHashLookup(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.EnrichKey = "host"
+	cfg.Pipeline.EnrichFields = []string{"site"}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without LookupHashes")
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`"10.0.0.1"`, "10.0.0.1"},
+		{`42`, "42"},
+		{`true`, "true"},
+		{`""`, ""},
+		{`null`, ""},
+		{`{"a":1}`, ""},
+		{``, ""},
+	}
+	for _, tt := range tests {
+		if got := string(keyValue([]byte(tt.in))); got != tt.want {
+			t.Errorf("keyValue(%s) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package hotpath
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// Observer receives the lifecycle events of the entries going through the
+// pipeline. Its methods are called one at a time, in event order, from a
+// goroutine of their own, never from the pipeline's: a slow observer costs
+// events, not throughput. Embed NopObserver to implement only some of them.
+type Observer interface {
+	// OnFetch reports entries read (Stage "read"), claimed ("claim") or
+	// replayed ("backfill") from Redis.
+	OnFetch(e Event)
+	// OnPublish reports a payload the broker accepted.
+	OnPublish(e Event)
+	// OnAck reports an ACK or NACK from the receiver.
+	OnAck(e Event)
+	// OnDrop reports entries taken out of the pipeline unpublished, by
+	// Reason: "duplicate", "max_age" or "oversize".
+	OnDrop(e Event)
+	// OnError reports a failed call, by Stage: "fetch", "claim",
+	// "publish", "ack", "cleanup" or "lease".
+	OnError(e Event)
+}
+
+// NopObserver ignores every event.
+type NopObserver struct{}
+
+// OnFetch implements Observer.
+func (NopObserver) OnFetch(Event) {}
+
+// OnPublish implements Observer.
+func (NopObserver) OnPublish(Event) {}
+
+// OnAck implements Observer.
+func (NopObserver) OnAck(Event) {}
+
+// OnDrop implements Observer.
+func (NopObserver) OnDrop(Event) {}
+
+// OnError implements Observer.
+func (NopObserver) OnError(Event) {}
+
+// Event describes one pipeline event. Fields that do not apply to it are
+// zero; IDs must not be modified.
+type Event struct {
+	Time   time.Time
+	Err    error    // OnError
+	Stage  string   // OnFetch and OnError
+	Stream string   // OnAck and OnDrop
+	Reason string   // OnDrop, and OnAck for a NACK that gave one
+	IDs    []string // OnAck and OnDrop
+	Count  int      // entries the event is about
+	Bytes  int      // OnPublish: compressed payload size
+	Ack    bool     // OnAck: false for a NACK
+}
+
+type eventKind uint8
+
+const (
+	eventFetch eventKind = iota
+	eventPublish
+	eventAck
+	eventDrop
+	eventError
+)
+
+type queuedEvent struct {
+	Event
+	kind eventKind
+}
+
+// eventRingSize bounds the events waiting for the observers.
+const eventRingSize = 1024
+
+// eventRing hands events to the observers through a bounded queue. Sending
+// never blocks: when the queue is full the event is dropped and counted,
+// like a receipt. A nil ring, the one without observers, sends nothing.
+type eventRing struct {
+	log       *log.Logger
+	ch        chan queuedEvent
+	done      chan struct{}
+	observers []Observer
+}
+
+func newEventRing(observers []Observer, logger *log.Logger) *eventRing {
+	if len(observers) == 0 {
+		return nil
+	}
+	return &eventRing{
+		observers: observers,
+		log:       logger,
+		ch:        make(chan queuedEvent, eventRingSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// start runs the dispatcher until close.
+func (r *eventRing) start() {
+	if r == nil {
+		return
+	}
+	go r.run()
+}
+
+func (r *eventRing) run() {
+	defer close(r.done)
+	for e := range r.ch {
+		for _, o := range r.observers {
+			switch e.kind {
+			case eventFetch:
+				o.OnFetch(e.Event)
+			case eventPublish:
+				o.OnPublish(e.Event)
+			case eventAck:
+				o.OnAck(e.Event)
+			case eventDrop:
+				o.OnDrop(e.Event)
+			case eventError:
+				o.OnError(e.Event)
+			}
+		}
+	}
+}
+
+// close stops accepting events and waits up to timeout for the observers
+// to take the queued ones. Every sender must have returned before close is
+// called.
+func (r *eventRing) close(ctx context.Context, timeout time.Duration) {
+	if r == nil {
+		return
+	}
+	close(r.ch)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-r.done:
+	case <-timer.C:
+		r.log.Warnf(ctx, "Observers still busy after %s, leaving them the queued events", timeout)
+	}
+}
+
+func (r *eventRing) send(kind eventKind, e *Event) {
+	e.Time = time.Now()
+	select {
+	case r.ch <- queuedEvent{Event: *e, kind: kind}:
+	default:
+		metrics.EventsDropped.Add(1)
+	}
+}
+
+func (r *eventRing) fetched(stage string, n int) {
+	if r == nil || n == 0 {
+		return
+	}
+	r.send(eventFetch, &Event{Stage: stage, Count: n})
+}
+
+func (r *eventRing) published(messages, size int) {
+	if r == nil {
+		return
+	}
+	r.send(eventPublish, &Event{Count: messages, Bytes: size})
+}
+
+func (r *eventRing) acked(ack *message.AckMessage) {
+	if r == nil {
+		return
+	}
+	r.send(eventAck, &Event{
+		Stream: ack.Stream, Reason: ack.Reason, IDs: ack.IDs, Count: len(ack.IDs), Ack: ack.Ack,
+	})
+}
+
+func (r *eventRing) dropped(reason, stream string, ids []string) {
+	if r == nil {
+		return
+	}
+	r.send(eventDrop, &Event{Stream: stream, Reason: reason, IDs: ids, Count: len(ids)})
+}
+
+// droppedMessages is dropped for msgs, one event per run of same-stream
+// entries.
+func (r *eventRing) droppedMessages(reason string, msgs []message.Redis) {
+	if r == nil {
+		return
+	}
+	for start := 0; start < len(msgs); {
+		end := start + 1
+		for end < len(msgs) && msgs[end].Stream == msgs[start].Stream {
+			end++
+		}
+		ids := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			ids = append(ids, msgs[i].ID)
+		}
+		r.dropped(reason, msgs[start].Stream, ids)
+		start = end
+	}
+}
+
+func (r *eventRing) failed(stage string, n int, err error) {
+	if r == nil {
+		return
+	}
+	r.send(eventError, &Event{Stage: stage, Err: err, Count: n})
+}
@@ -0,0 +1,110 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// recordingObserver records the events it is given as "<method>" entries.
+type recordingObserver struct {
+	NopObserver
+	calls  []string
+	events []Event
+}
+
+func (o *recordingObserver) record(call string, e Event) {
+	o.calls = append(o.calls, call)
+	o.events = append(o.events, e)
+}
+
+func (o *recordingObserver) OnFetch(e Event)   { o.record("fetch", e) }
+func (o *recordingObserver) OnPublish(e Event) { o.record("publish", e) }
+func (o *recordingObserver) OnAck(e Event)     { o.record("ack", e) }
+func (o *recordingObserver) OnDrop(e Event)    { o.record("drop", e) }
+func (o *recordingObserver) OnError(e Event)   { o.record("error", e) }
+
+func TestEvents_DeliveredInOrder(t *testing.T) {
+	observer := &recordingObserver{}
+	calls := 0
+	pub := &mockPublisher{
+		publishFn: func(context.Context, message.Payload) error {
+			if calls++; calls > 1 {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		},
+	}
+	hp, err := New(&mockRedis{}, pub, testConfig(), log.New(), observer)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	hp.events.start()
+
+	hp.events.fetched("read", 3)
+	hp.sendPayload(t.Context(), []byte("payload"), 3, make([]byte, 30), nil, pub.Publish)
+	hp.sendPayload(t.Context(), []byte("payload"), 3, make([]byte, 30), nil, pub.Publish)
+	hp.makeAckHandler(t.Context())(message.AckMessage{Stream: testStreamSimp, IDs: []string{"1-0"}, Reason: "timeout"})
+	hp.events.droppedMessages("oversize", []message.Redis{
+		{ID: "1-0", Stream: "alpha"}, {ID: "2-0", Stream: "alpha"}, {ID: "1-0", Stream: "beta"},
+	})
+	hp.events.close(t.Context(), time.Second)
+
+	want := []string{"fetch", "publish", "error", "ack", "drop", "drop"}
+	if !slices.Equal(observer.calls, want) {
+		t.Fatalf("calls = %v; want %v", observer.calls, want)
+	}
+	e := observer.events
+	if e[0].Stage != "read" || e[0].Count != 3 {
+		t.Errorf("fetch event = %+v; want stage read, 3 entries", e[0])
+	}
+	if e[1].Count != 3 || e[1].Bytes != len("payload") {
+		t.Errorf("publish event = %+v; want 3 entries in 7 bytes", e[1])
+	}
+	if e[2].Stage != "publish" || e[2].Err == nil {
+		t.Errorf("error event = %+v; want the publish error", e[2])
+	}
+	if e[3].Ack || e[3].Reason != "timeout" || !slices.Equal(e[3].IDs, []string{"1-0"}) {
+		t.Errorf("ack event = %+v; want a NACK of 1-0 for timeout", e[3])
+	}
+	if e[4].Stream != "alpha" || !slices.Equal(e[4].IDs, []string{"1-0", "2-0"}) || e[5].Stream != "beta" {
+		t.Errorf("drop events = %+v, %+v; want one per stream", e[4], e[5])
+	}
+	if e[0].Time.IsZero() {
+		t.Error("event time is zero; want the time it happened")
+	}
+}
+
+func TestEvents_DroppedWhenFull(t *testing.T) {
+	ring := newEventRing([]Observer{NopObserver{}}, log.New())
+	before := metrics.EventsDropped.Value()
+	for range eventRingSize + 2 {
+		ring.fetched("read", 1)
+	}
+	if got := metrics.EventsDropped.Value() - before; got != 2 {
+		t.Errorf("EventsDropped delta = %d; want 2", got)
+	}
+	ring.start()
+	ring.close(t.Context(), time.Second)
+}
+
+func TestEvents_NoObservers(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.events != nil {
+		t.Fatal("events != nil; want no ring without observers")
+	}
+	hp.events.fetched("read", 1)
+	hp.events.failed("fetch", 0, errors.New("read error"))
+	hp.events.close(t.Context(), time.Second)
+}
@@ -0,0 +1,102 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+const deadLetterReasonRejected = "publish_rejected"
+
+// connectionChecker is implemented by the mqtt Client and Pool.
+type connectionChecker interface {
+	IsConnected() bool
+}
+
+// newRejectedDLQ returns where the entries of rejected publishes go: the
+// dead letter stream when REDIS_DEAD_LETTER_STREAM is set, nil otherwise.
+func newRejectedDLQ(redisClient redis.StreamClient, cfg *config.RedisConfig) nackDeadLetterer {
+	if cfg.DeadLetterStream == "" {
+		return nil
+	}
+	dlq, _ := redisClient.(nackDeadLetterer)
+	return dlq
+}
+
+// publishFailed accounts for a failed publish of the records in data, one
+// per line or one per spans entry when spans is set, and acts on the class
+// of err. The entries of a rejected publish are dead-lettered; otherwise
+// they stay pending for the claim loop, and the worker backs off on a busy
+// broker and waits out a lost connection before taking the next batch.
+func (hp *HotPath) publishFailed(ctx context.Context, err error, messages int, data []byte, spans []recordSpan) {
+	failure := mqtt.Classify(err)
+	hp.log.Errorf(ctx, "Failed to publish batch of %d messages (%s): %v", messages, failure, err)
+	metrics.PublishErrors.Add(int64(messages))
+	metrics.PublishFailures.Add(failure.String(), int64(messages))
+	hp.events.failed("publish", messages, err)
+
+	switch failure {
+	case mqtt.FailureRejected:
+		hp.deadLetterRejected(ctx, data, spans)
+	case mqtt.FailureBusy:
+		_ = sleepCtx(ctx, hp.errorBackoff)
+	case mqtt.FailureDisconnected:
+		hp.awaitConnected(ctx)
+	}
+}
+
+// deadLetterRejected moves the entries whose records make up data to the
+// dead letter stream. Without one they stay pending, as any other failure.
+func (hp *HotPath) deadLetterRejected(ctx context.Context, data []byte, spans []recordSpan) {
+	if hp.rejected == nil {
+		hp.log.Warnf(ctx, "Rejected publish left pending: no dead letter stream configured")
+		return
+	}
+	if spans == nil {
+		spans = lineSpans(data)
+	}
+	var errs []error
+	for stream, ids := range recordIDs(data, spans) {
+		if err := hp.rejected.DeadLetterIDs(ctx, stream, ids, deadLetterReasonRejected); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metrics.PublishDeadLettered.Add(int64(len(ids)))
+	}
+	if err := errors.Join(errs...); err != nil {
+		hp.log.Errorf(ctx, "Failed to dead-letter rejected messages: %v", err)
+	}
+}
+
+// awaitConnected blocks until the publisher has a connection again, checking
+// every errorBackoff, or ctx is done.
+func (hp *HotPath) awaitConnected(ctx context.Context) {
+	c, ok := hp.mqtt.(connectionChecker)
+	if !ok {
+		_ = sleepCtx(ctx, hp.errorBackoff)
+		return
+	}
+	for !c.IsConnected() {
+		if sleepCtx(ctx, hp.errorBackoff) != nil {
+			return
+		}
+	}
+}
+
+// recordIDs groups by stream the IDs of the entries whose records are in
+// spans of data; continuation chunks of a split record are skipped.
+func recordIDs(data []byte, spans []recordSpan) map[string][]string {
+	ids := make(map[string][]string)
+	for _, s := range spans {
+		if s.messages == 0 {
+			continue
+		}
+		id, stream := recordHeader(data[s.start:s.end])
+		ids[string(stream)] = append(ids[string(stream)], string(id))
+	}
+	return ids
+}
@@ -0,0 +1,109 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// errRejected is the error paho gives a publish it cannot encode.
+var errRejected = errors.New("mqtt publish failed: unknown payload type")
+
+// connectingPublisher is a mockPublisher that reports a connection once
+// IsConnected has been asked a number of times.
+type connectingPublisher struct {
+	mockPublisher
+	checks atomic.Int32
+	after  int32
+}
+
+func (p *connectingPublisher) IsConnected() bool {
+	return p.checks.Add(1) > p.after
+}
+
+func TestPublishFailed_DeadLettersRejected(t *testing.T) {
+	deadLettered := make(map[string][]string)
+	redisClient := &nackDeadLetterRedis{
+		deadLetterIDsFn: func(_ context.Context, stream string, ids []string, reason string) error {
+			if reason != deadLetterReasonRejected {
+				t.Errorf("reason = %q; want %q", reason, deadLetterReasonRejected)
+			}
+			deadLettered[stream] = ids
+			return nil
+		},
+	}
+	cfg := testConfig()
+	cfg.Redis.DeadLetterStream = "dlq"
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := metrics.PublishDeadLettered.Value()
+
+	data := []byte("1-0\ta\t{}\n2-0\tb\t{}\n3-0\ta\t{}\n")
+	publishFn := func(context.Context, message.Payload) error { return errRejected }
+	if hp.sendPayload(t.Context(), []byte("payload"), 3, data, nil, publishFn) {
+		t.Fatal("sendPayload() = true; want false")
+	}
+
+	if !slices.Equal(deadLettered["a"], []string{"1-0", "3-0"}) || !slices.Equal(deadLettered["b"], []string{"2-0"}) {
+		t.Errorf("dead-lettered = %v; want 1-0 and 3-0 of a, 2-0 of b", deadLettered)
+	}
+	if got := metrics.PublishDeadLettered.Value() - before; got != 3 {
+		t.Errorf("PublishDeadLettered delta = %d; want 3", got)
+	}
+}
+
+func TestPublishFailed_RejectedWithoutDeadLetterStream(t *testing.T) {
+	redisClient := &nackDeadLetterRedis{
+		deadLetterIDsFn: func(context.Context, string, []string, string) error {
+			t.Error("DeadLetterIDs called without a dead letter stream")
+			return nil
+		},
+	}
+	hp, err := New(redisClient, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	hp.publishFailed(t.Context(), errRejected, 1, []byte("1-0\ta\t{}\n"), nil)
+}
+
+func TestPublishFailed_AwaitsReconnect(t *testing.T) {
+	pub := &connectingPublisher{after: 3}
+	cfg := testConfig()
+	cfg.Pipeline.ErrorBackoff = time.Millisecond
+	hp, err := New(&mockRedis{}, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := publishFailures("disconnected")
+
+	hp.publishFailed(t.Context(), errors.New("mqtt publish failed: connection lost before Publish completed"),
+		2, []byte("1-0\ta\t{}\n2-0\ta\t{}\n"), nil)
+
+	if got := pub.checks.Load(); got != 4 {
+		t.Errorf("IsConnected checks = %d; want 4, until the connection is back", got)
+	}
+	if got := publishFailures("disconnected") - before; got != 2 {
+		t.Errorf("disconnected failures delta = %d; want 2", got)
+	}
+}
+
+func publishFailures(class string) int64 {
+	if v, ok := metrics.PublishFailures.Get(class).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -0,0 +1,184 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// fanout publishes every batch payload to the MQTT_FANOUT_TOPICS as well. A
+// nil fanout publishes to MQTT_PUBLISH_TOPIC only.
+//
+// The entries are XACKed once the receiver on MQTT_PUBLISH_TOPIC
+// acknowledges them, so the mode decides what that acknowledgement covers.
+// In best-effort mode the publish topic goes first and a failed fanout
+// topic is only counted. In all mode the fanout topics go first and the
+// publish topic only once every one of them took the payload; a failure
+// leaves the entries pending for the claim loop, which publishes them again
+// to every topic.
+type fanout struct {
+	publisher mqtt.TopicPublisher
+	rules     []config.FanoutRule
+	origin    []byte
+	qos       byte
+	all       bool
+	raw       bool // a rule uses the raw serializer
+}
+
+func validateFanout(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) error {
+	if len(cfg.FanoutTopics) == 0 {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: fanout topics need an mqtt publisher that can publish to other topics")
+	}
+	for _, item := range cfg.FanoutTopics {
+		if _, err := config.ParseFanoutRule(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newFanout(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) *fanout {
+	if len(cfg.FanoutTopics) == 0 {
+		return nil
+	}
+	f := &fanout{
+		publisher: topics,
+		rules:     make([]config.FanoutRule, 0, len(cfg.FanoutTopics)),
+		origin:    originOf(cfg),
+		qos:       cfg.QoS,
+		all:       cfg.FanoutMode == config.FanoutAll,
+	}
+	for _, item := range cfg.FanoutTopics {
+		rule, _ := config.ParseFanoutRule(item) // checked by validateFanout
+		f.rules = append(f.rules, rule)
+		f.raw = f.raw || rule.Serializer == config.FanoutRaw
+	}
+	return f
+}
+
+// rawLines holds the original lines of a batch's entries for the raw
+// serializer: one per entry, newline-terminated, without the id and stream
+// header of the json records. It is pooled like oversizeBatch.
+type rawLines struct {
+	data       []byte
+	compressed []byte
+}
+
+var rawLinesPool = sync.Pool{New: func() any { return new(rawLines) }}
+
+// lines returns an empty rawLines, or nil when no rule needs one.
+func (f *fanout) lines() *rawLines {
+	if f == nil || !f.raw {
+		return nil
+	}
+	l, ok := rawLinesPool.Get().(*rawLines)
+	if !ok {
+		return new(rawLines)
+	}
+	return l
+}
+
+func putRawLines(l *rawLines) {
+	if l == nil {
+		return
+	}
+	l.data = l.data[:0]
+	l.compressed = l.compressed[:0]
+	rawLinesPool.Put(l)
+}
+
+// add appends msg's original line: its raw body, or its object when it has
+// none. A nil rawLines ignores it.
+func (l *rawLines) add(msg *message.Redis) {
+	if l == nil {
+		return
+	}
+	line := msg.Raw
+	if line == "" {
+		line = msg.Object
+	}
+	l.data = append(l.data, line...)
+	l.data = append(l.data, '\n')
+}
+
+// payload compresses the lines once, after origin's frame, and returns the
+// result.
+func (l *rawLines) payload(enc *zstd.Encoder, origin []byte) message.Payload {
+	if len(l.compressed) == 0 {
+		l.compressed = compress.EncodeMarked(enc, l.compressed, l.data, origin)
+	}
+	return l.compressed
+}
+
+// withFanout returns publishFn extended to the fanout topics: the json
+// rules reuse the payload publishFn is given, the raw rules publish lines.
+// It returns publishFn itself when there is no fanout.
+func (hp *HotPath) withFanout(
+	enc *zstd.Encoder, lines *rawLines,
+	publishFn func(context.Context, message.Payload) error,
+) func(context.Context, message.Payload) error {
+	f := hp.fanout
+	if f == nil {
+		return publishFn
+	}
+	if !f.all {
+		return func(ctx context.Context, payload message.Payload) error {
+			if err := publishFn(ctx, payload); err != nil {
+				return err
+			}
+			if err := f.publish(ctx, enc, payload, lines); err != nil {
+				hp.log.Warnf(ctx, "Failed to publish batch to fanout topics: %v", err)
+			}
+			return nil
+		}
+	}
+	publishFanout := func(ctx context.Context, payload message.Payload) error {
+		return f.publish(ctx, enc, payload, lines)
+	}
+	if hp.orderedPublish {
+		publishFanout = hp.publishInOrder(publishFanout)
+	}
+	return func(ctx context.Context, payload message.Payload) error {
+		if err := publishFanout(ctx, payload); err != nil {
+			return err
+		}
+		return publishFn(ctx, payload)
+	}
+}
+
+// publish sends payload, or lines for the raw rules, to every fanout topic.
+// In all mode it stops at the first failure; in best-effort mode it tries
+// every topic and returns the failures joined.
+func (f *fanout) publish(ctx context.Context, enc *zstd.Encoder, payload message.Payload, lines *rawLines) error {
+	var errs []error
+	for i := range f.rules {
+		rule := &f.rules[i]
+		out := payload
+		if rule.Serializer == config.FanoutRaw {
+			out = lines.payload(enc, f.origin)
+		}
+		if err := f.publisher.PublishTo(ctx, out, rule.Topic, f.qos, false); err != nil {
+			metrics.FanoutErrors.Add(1)
+			err = fmt.Errorf("fanout topic %s: %w", rule.Topic, err)
+			if f.all {
+				return err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		metrics.FanoutPublished.Add(1)
+	}
+	return errors.Join(errs...)
+}
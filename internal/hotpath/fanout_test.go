@@ -0,0 +1,110 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func fanoutHotPath(t *testing.T, mode, failTopic string) (*HotPath, *mockRoutedPublisher) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.MQTT.PublishTopic = "logs"
+	cfg.MQTT.AckTopic = "logs/ack"
+	cfg.MQTT.FanoutTopics = []string{"archive/raw=raw", "analytics"}
+	cfg.MQTT.FanoutMode = mode
+	publisher := &mockRoutedPublisher{failTopic: failTopic}
+	hp, err := New(&mockRedis{}, publisher, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, publisher
+}
+
+// publishFanoutBatch publishes two entries and returns the topics in the
+// order they were published to; the publish topic is recorded as "logs".
+func publishFanoutBatch(t *testing.T, hp *HotPath, publisher *mockRoutedPublisher) []string {
+	t.Helper()
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: `{"msg":"a"}`, Raw: "<13>first line"},
+		{ID: "2-0", Stream: testStreamSimp, Object: `{"msg":"b"}`},
+	}}
+	publish := func(ctx context.Context, payload message.Payload) error {
+		return publisher.PublishTo(ctx, payload, "logs", 0, false)
+	}
+	var compressed []byte
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	var order []string
+	for _, p := range publisher.routed {
+		order = append(order, p.topic)
+	}
+	return order
+}
+
+func TestFanout_BestEffort(t *testing.T) {
+	hp, publisher := fanoutHotPath(t, config.FanoutBestEffort, "analytics")
+	publishedBefore, errorsBefore := metrics.MessagesPublished.Value(), metrics.FanoutErrors.Value()
+
+	order := publishFanoutBatch(t, hp, publisher)
+
+	if want := []string{"logs", "archive/raw"}; !slices.Equal(order, want) {
+		t.Errorf("publish order = %q; want %q", order, want)
+	}
+	if d := metrics.MessagesPublished.Value() - publishedBefore; d != 2 {
+		t.Errorf("MessagesPublished delta = %d; want 2 despite the failed fanout topic", d)
+	}
+	if d := metrics.FanoutErrors.Value() - errorsBefore; d != 1 {
+		t.Errorf("FanoutErrors delta = %d; want 1", d)
+	}
+	if want := []string{"<13>first line", `{"msg":"b"}`}; !slices.Equal(publisher.routed[1].lines, want) {
+		t.Errorf("raw fanout lines = %q; want %q", publisher.routed[1].lines, want)
+	}
+}
+
+func TestFanout_AllPublishesFanoutFirst(t *testing.T) {
+	hp, publisher := fanoutHotPath(t, config.FanoutAll, "")
+	before := metrics.FanoutPublished.Value()
+
+	order := publishFanoutBatch(t, hp, publisher)
+
+	if want := []string{"archive/raw", "analytics", "logs"}; !slices.Equal(order, want) {
+		t.Errorf("publish order = %q; want %q", order, want)
+	}
+	if lines := publisher.routed[1].lines; len(lines) != 2 || lines[0][:4] != "1-0\t" {
+		t.Errorf("json fanout lines = %q; want the records of the publish topic", lines)
+	}
+	if d := metrics.FanoutPublished.Value() - before; d != 2 {
+		t.Errorf("FanoutPublished delta = %d; want 2", d)
+	}
+}
+
+func TestFanout_AllHoldsBackPublishTopic(t *testing.T) {
+	hp, publisher := fanoutHotPath(t, config.FanoutAll, "archive/raw")
+	errorsBefore := metrics.PublishErrors.Value()
+
+	if order := publishFanoutBatch(t, hp, publisher); slices.Contains(order, "logs") {
+		t.Errorf("publish order = %q; want nothing on the publish topic", order)
+	}
+	if d := metrics.PublishErrors.Value() - errorsBefore; d != 2 {
+		t.Errorf("PublishErrors delta = %d; want 2 entries left pending", d)
+	}
+}
+
+func TestNew_FanoutNeedsRoutedPublisher(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.FanoutTopics = []string{"archive"}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a publisher without PublishTo")
+	}
+}
@@ -0,0 +1,137 @@
+package hotpath
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
+)
+
+// flowControl applies the flow-control messages receivers publish on the
+// ACK topic under MQTT_ACK_FLOW_CONTROL (see ackclient.Control): a pause
+// holds the publish workers until it ends or a resume arrives, and a rate
+// charges them to a token bucket at its limit, until its duration passes
+// or the next rate replaces it. Every pool connection delivers the same
+// message, so applying one twice changes nothing. A nil flowControl lets
+// every entry through.
+type flowControl struct {
+	pausedUntil time.Time
+	rateUntil   time.Time    // zero while the rate has no duration
+	bucket      *tokenBucket // nil while uncapped
+	mu          sync.Mutex
+}
+
+func newFlowControl(cfg *config.MQTTConfig) *flowControl {
+	if !cfg.AckFlowControl {
+		return nil
+	}
+	return &flowControl{}
+}
+
+// apply applies c at now.
+func (f *flowControl) apply(c *message.FlowControl, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch c.Kind {
+	case ackclient.ControlPause:
+		f.pausedUntil = now.Add(c.Duration)
+	case ackclient.ControlResume:
+		f.pausedUntil = time.Time{}
+	case ackclient.ControlRate:
+		f.rateUntil = time.Time{}
+		if c.Duration > 0 {
+			f.rateUntil = now.Add(c.Duration)
+		}
+		if f.bucket == nil || f.bucket.rate != float64(c.Limit) {
+			f.bucket = newTokenBucket(c.Limit, now)
+		}
+		metrics.FlowControlRate.Set(int64(c.Limit))
+	}
+}
+
+// pause returns how much longer the publishes are paused at now.
+func (f *flowControl) pause(now time.Time) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pausedUntil.Sub(now)
+}
+
+// take charges n entries to the rate at now and returns how long they
+// must wait for it.
+func (f *flowControl) take(n int, now time.Time) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.bucket != nil && !f.rateUntil.IsZero() && !now.Before(f.rateUntil) {
+		f.bucket = nil
+		metrics.FlowControlRate.Set(0)
+	}
+	f.bucket.refill(now)
+	f.bucket.take(float64(n))
+	return f.bucket.debt()
+}
+
+// handleFlowControl applies a flow-control message from the ACK topic, or
+// ignores it without MQTT_ACK_FLOW_CONTROL.
+func (hp *HotPath) handleFlowControl(ctx context.Context, c *message.FlowControl) {
+	if hp.flow == nil {
+		metrics.FlowControlIgnored.Add(1)
+		hp.log.Debugf(ctx, "Ignoring %s flow-control message: MQTT_ACK_FLOW_CONTROL is not set", c.Kind)
+		return
+	}
+	hp.flow.apply(c, time.Now())
+	metrics.FlowControlMessages.Add(1)
+	switch {
+	case c.Kind == ackclient.ControlPause:
+		hp.log.Infof(ctx, "Receivers paused publishes for %v", c.Duration)
+	case c.Kind == ackclient.ControlResume:
+		hp.log.Infof(ctx, "Receivers resumed publishes")
+	case c.Limit == 0:
+		hp.log.Infof(ctx, "Receivers lifted the publish rate")
+	case c.Duration > 0:
+		hp.log.Infof(ctx, "Receivers capped publishes at %d entries/s for %v", c.Limit, c.Duration)
+	default:
+		hp.log.Infof(ctx, "Receivers capped publishes at %d entries/s", c.Limit)
+	}
+}
+
+// applyPublishCaps waits for the throughput cap, then for the receivers'
+// flow control, before n entries are published.
+func (hp *HotPath) applyPublishCaps(ctx context.Context, n int) error {
+	if err := hp.applyThroughput(ctx, n); err != nil {
+		return err
+	}
+	return hp.applyFlowControl(ctx, n)
+}
+
+// applyFlowControl holds n entries about to be published while the
+// receivers pause the publishes, then charges them to their rate and
+// waits out its debt. A pause is checked every pausePoll, so a resume
+// takes effect soon. It returns ctx's error if ctx is done while waiting.
+func (hp *HotPath) applyFlowControl(ctx context.Context, n int) error {
+	if hp.flow == nil {
+		return nil
+	}
+	start := time.Now()
+	defer func() {
+		if waited := time.Since(start); waited >= time.Millisecond {
+			metrics.FlowControlDelayMs.Add(waited.Milliseconds())
+		}
+	}()
+	for {
+		wait := hp.flow.pause(time.Now())
+		if wait <= 0 {
+			break
+		}
+		if err := sleepCtx(ctx, min(wait, pausePoll)); err != nil {
+			return err
+		}
+	}
+	if wait := hp.flow.take(n, time.Now()); wait > 0 {
+		return sleepCtx(ctx, wait)
+	}
+	return nil
+}
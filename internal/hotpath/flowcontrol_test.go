@@ -0,0 +1,99 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
+)
+
+func TestFlowControl_PauseAndResume(t *testing.T) {
+	f := newFlowControl(&config.MQTTConfig{AckFlowControl: true})
+	now := monday
+
+	f.apply(&message.FlowControl{Kind: ackclient.ControlPause, Duration: 30 * time.Second}, now)
+	if got := f.pause(now.Add(10 * time.Second)); got != 20*time.Second {
+		t.Errorf("pause() = %v; want 20s left", got)
+	}
+	f.apply(&message.FlowControl{Kind: ackclient.ControlResume}, now.Add(10*time.Second))
+	if got := f.pause(now.Add(10 * time.Second)); got > 0 {
+		t.Errorf("pause() after resume = %v; want none", got)
+	}
+}
+
+func TestFlowControl_Rate(t *testing.T) {
+	f := newFlowControl(&config.MQTTConfig{AckFlowControl: true})
+	now := monday
+	rate := &message.FlowControl{Kind: ackclient.ControlRate, Limit: 10, Duration: time.Minute}
+
+	f.apply(rate, now)
+	if wait := f.take(10, now); wait != 0 {
+		t.Fatalf("take() = %v; want no wait within the rate", wait)
+	}
+	// The same message from another pool connection keeps the debt.
+	f.apply(rate, now)
+	if wait := f.take(5, now); wait != 500*time.Millisecond {
+		t.Errorf("take() = %v; want 500ms for 5 entries over 10/s", wait)
+	}
+	if got := metrics.FlowControlRate.Value(); got != 10 {
+		t.Errorf("FlowControlRate = %d; want 10", got)
+	}
+	if wait := f.take(1000, now.Add(time.Minute)); wait != 0 {
+		t.Errorf("take() after the duration = %v; want the rate lifted", wait)
+	}
+	if got := metrics.FlowControlRate.Value(); got != 0 {
+		t.Errorf("FlowControlRate = %d; want 0 once lifted", got)
+	}
+}
+
+func TestAckHandler_FlowControl(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.AckFlowControl = true
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := metrics.FlowControlMessages.Value()
+
+	handler := hp.makeAckHandler(t.Context())
+	handler(message.AckMessage{Control: &message.FlowControl{Kind: ackclient.ControlPause, Duration: time.Hour}})
+	if got := metrics.FlowControlMessages.Value() - before; got != 1 {
+		t.Errorf("FlowControlMessages delta = %d; want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := hp.applyPublishCaps(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("applyPublishCaps() = %v; want the publish held until ctx is done", err)
+	}
+	handler(message.AckMessage{Control: &message.FlowControl{Kind: ackclient.ControlResume}})
+	if err := hp.applyPublishCaps(t.Context(), 1); err != nil {
+		t.Errorf("applyPublishCaps() after resume error = %v", err)
+	}
+}
+
+func TestAckHandler_FlowControlOff(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := metrics.FlowControlIgnored.Value()
+
+	hp.makeAckHandler(t.Context())(message.AckMessage{
+		Control: &message.FlowControl{Kind: ackclient.ControlPause, Duration: time.Hour},
+	})
+	if got := metrics.FlowControlIgnored.Value() - before; got != 1 {
+		t.Errorf("FlowControlIgnored delta = %d; want 1", got)
+	}
+	if err := hp.applyPublishCaps(t.Context(), 1); err != nil {
+		t.Errorf("applyPublishCaps() error = %v; want no pause", err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"sync"
 	"time"
 
@@ -16,6 +15,7 @@ import (
 	"github.com/ubyte-source/go-jsonfast"
 
 	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
 	"github.com/ibs-source/syslog-consumer/internal/metrics"
@@ -28,20 +28,70 @@ import (
 type HotPath struct {
 	redis               redis.StreamClient
 	mqtt                mqtt.Publisher
+	dedup               deduplicator
+	routes              *publishRoutes
+	destinations        *streamDestinations
+	fanout              *fanout
+	sampler             *sampler
 	done                chan struct{}
 	msgChan             chan message.Batch
+	publishChans        []chan message.Batch
 	claimTicker         *time.Ticker
 	cleanupTicker       *time.Ticker
 	refreshTicker       *time.Ticker
 	log                 *log.Logger
+	projection          *fieldProjection
+	timestamps          *timestampNormalizer
+	structured          *structuredData
+	oversize            *oversizeLimit
+	receipts            *receiptLog
+	events              *eventRing
+	backfill            *backfill
+	memory              *memoryBudget
+	archive             *archiveCopy
+	nacks               *nackPolicies
+	nackFlood           *nackFlood
+	rejected            nackDeadLetterer
+	enrich              *enrichment
+	retries             *retryQueue
+	maxAge              *maxAgePolicies
+	objects             *objectChecks
+	redaction           *redaction // nil without PIPELINE_REDACT_FILE
+	quotas              *streamQuotas
+	throughput          *throughputCap // nil without PIPELINE_THROUGHPUT_SCHEDULE
+	flow                *flowControl   // nil without MQTT_ACK_FLOW_CONTROL
+	published           *publishDedup
+	pressure            *memoryPressure
+	lag                 *lagMonitor
+	slo                 *ackSLO
+	capture             *capture
+	control             *runtimeControl
+	leases              *streamLeases // nil without REDIS_STREAM_LEASE
+	watchdog            *publishWatchdog
+	transitions         *lifecycle.Recorder
+	compactor           compactor // nil unless REDIS_COMPACT_INTERVAL is set
+	orderingKey         []byte    // PIPELINE_ORDERING_KEY; nil orders by stream
+	origin              []byte    // MQTT_ORIGIN_MARKER's origin; nil marks nothing
+	readSize            *adaptiveBatch
+	live                *liveness
+	snapshotPath        string
 	ackChans            []chan message.AckMessage
+	ackDrain            ackDrain
+	ackStart            func(ch <-chan message.AckMessage) // set by Run, under ackGate
 	closeOnce           sync.Once
 	singleStream        bool
+	orderedPublish      bool
+	noAck               bool // NOACK reads leave nothing to claim
+	acksClosed          bool
 	ackWg               sync.WaitGroup
+	ackGate             sync.RWMutex
 	consumerIdleTimeout time.Duration
 	errorBackoff        time.Duration
 	ackTimeout          time.Duration
 	ackFlushInterval    time.Duration
+	drainTimeout        time.Duration
+	publishTimeout      time.Duration // PIPELINE_PUBLISH_TIMEOUT; 0 when unset
+	compactInterval     time.Duration
 	publishWorkers      int
 	ackWorkers          int
 	ackBatchSize        int
@@ -74,56 +124,258 @@ func validateNewInputs(
 	if cfg.Pipeline.MessageQueueCapacity < 1 {
 		return errors.New("hotpath: pipeline message queue capacity must be positive")
 	}
-	return nil
+	return validateFeatures(redisClient, mqttPublisher, cfg)
 }
 
-// New accepts either *mqtt.Client or *mqtt.Pool as mqttPublisher.
+// validateFeatures checks that the optional features cfg turns on are
+// supported by redisClient and mqttPublisher.
+func validateFeatures(redisClient redis.StreamClient, mqttPublisher mqtt.Publisher, cfg *config.Config) error {
+	if err := validatePayload(redisClient, cfg); err != nil {
+		return err
+	}
+	if err := validateReceipts(redisClient, cfg); err != nil {
+		return err
+	}
+	if err := validateBackfill(redisClient, &cfg.Redis); err != nil {
+		return err
+	}
+	if err := validateDedup(redisClient, cfg); err != nil {
+		return err
+	}
+	if err := validateNackPolicies(redisClient, &cfg.Pipeline); err != nil {
+		return err
+	}
+	if err := validateAdaptiveBatch(redisClient, &cfg.Redis); err != nil {
+		return err
+	}
+	if err := validateMaxAge(redisClient, cfg); err != nil {
+		return err
+	}
+	topics := topicsOf(mqttPublisher)
+	if err := validateMaintenance(redisClient, topics, cfg); err != nil {
+		return err
+	}
+	return validateTopics(topics, cfg)
+}
+
+// topicsOf returns mqttPublisher as a TopicPublisher, or nil when it has no
+// topics, as the archive sink.
+func topicsOf(mqttPublisher mqtt.Publisher) mqtt.TopicPublisher {
+	topics, _ := mqttPublisher.(mqtt.TopicPublisher)
+	return topics
+}
+
+// validatePayload checks the features that shape the published records.
+func validatePayload(redisClient redis.StreamClient, cfg *config.Config) error {
+	if err := validateOversize(redisClient, &cfg.Pipeline); err != nil {
+		return err
+	}
+	if err := validateTimestamps(&cfg.Pipeline); err != nil {
+		return err
+	}
+	if err := validateObjectChecks(redisClient, cfg); err != nil {
+		return err
+	}
+	if err := validateRedaction(redisClient, &cfg.Pipeline); err != nil {
+		return err
+	}
+	return validateEnrichment(redisClient, &cfg.Pipeline)
+}
+
+// validateMaintenance checks the loops that look after the Redis server
+// rather than the entries.
+func validateMaintenance(redisClient redis.StreamClient, topics mqtt.TopicPublisher, cfg *config.Config) error {
+	if err := validateMemoryPressure(redisClient, topics, cfg); err != nil {
+		return err
+	}
+	if err := validateControl(redisClient, &cfg.Redis); err != nil {
+		return err
+	}
+	if err := validateLeases(redisClient, &cfg.Redis); err != nil {
+		return err
+	}
+	if err := validateLag(redisClient, &cfg.Redis); err != nil {
+		return err
+	}
+	return validateCompaction(redisClient, &cfg.Redis)
+}
+
+// validateTopics checks the features that publish to topics other than
+// MQTT_PUBLISH_TOPIC.
+func validateTopics(topics mqtt.TopicPublisher, cfg *config.Config) error {
+	if err := validateRoutes(topics, &cfg.MQTT); err != nil {
+		return err
+	}
+	if err := validateStreamDestinations(topics, cfg); err != nil {
+		return err
+	}
+	if err := validateFanout(topics, &cfg.MQTT); err != nil {
+		return err
+	}
+	if err := validateAckSLO(topics, cfg); err != nil {
+		return err
+	}
+	return validateSampler(topics, &cfg.MQTT)
+}
+
+// New accepts either *mqtt.Client or *mqtt.Pool as mqttPublisher. The
+// observers, if any, receive the pipeline's events while Run runs.
 func New(
 	redisClient redis.StreamClient,
 	mqttPublisher mqtt.Publisher,
 	cfg *config.Config,
 	logger *log.Logger,
+	observers ...Observer,
 ) (*HotPath, error) {
 	if err := validateNewInputs(redisClient, mqttPublisher, cfg, logger); err != nil {
 		return nil, err
 	}
+	var memory *memoryBudget
+	if cfg.Pipeline.MemoryBudget > 0 {
+		var err error
+		if memory, err = newMemoryBudget(&cfg.Pipeline); err != nil {
+			return nil, err
+		}
+	}
+	var archived *archiveCopy
+	if cfg.Archive.Mode == config.ArchiveCopy {
+		var err error
+		if archived, err = newArchiveCopy(&cfg.Archive, logger); err != nil {
+			return nil, errors.Join(err, memory.close())
+		}
+	}
 
 	singleStream := cfg.Redis.Stream != ""
+	topics := topicsOf(mqttPublisher)
 
 	var refreshTicker *time.Ticker
 	if !singleStream {
 		refreshTicker = time.NewTicker(cfg.Pipeline.RefreshInterval)
 	}
-
-	// ACK channels are sharded by stream-name hash so same-stream ACKs land
-	// on the same worker, maximizing per-flush batch sizes.
-	ackChans := make([]chan message.AckMessage, cfg.Pipeline.AckWorkers)
-	chanCap := max(cfg.Pipeline.BufferCapacity/cfg.Pipeline.AckWorkers, 64)
-	for i := range ackChans {
-		ackChans[i] = make(chan message.AckMessage, chanCap)
-	}
+	claimTicker := time.NewTicker(claimTickInterval(&cfg.Redis))
+	drain := newReadDrain(redisClient, &cfg.Redis)
 
 	return &HotPath{
 		redis:               redisClient,
 		mqtt:                mqttPublisher,
 		msgChan:             make(chan message.Batch, cfg.Pipeline.MessageQueueCapacity),
-		ackChans:            ackChans,
+		publishChans:        newPublishChans(&cfg.Pipeline),
+		ackChans:            newAckChans(cfg.Pipeline.BufferCapacity, cfg.Pipeline.AckWorkers),
 		done:                make(chan struct{}),
-		claimTicker:         time.NewTicker(cfg.Redis.ClaimIdle),
+		claimTicker:         claimTicker,
 		cleanupTicker:       time.NewTicker(cfg.Redis.CleanupInterval),
 		refreshTicker:       refreshTicker,
 		consumerIdleTimeout: cfg.Redis.ConsumerIdleTimeout,
 		errorBackoff:        cfg.Pipeline.ErrorBackoff,
 		ackTimeout:          cfg.Pipeline.AckTimeout,
 		ackFlushInterval:    cfg.Pipeline.AckFlushInterval,
+		drainTimeout:        cfg.Pipeline.DrainTimeout,
+		ackDrain:            ackDrain{timeout: config.EffectiveAckDrainTimeout(&cfg.Pipeline)},
+		publishTimeout:      cfg.Pipeline.PublishTimeout,
 		ackBatchSize:        cfg.Pipeline.AckBatchSize,
 		publishWorkers:      cfg.Pipeline.PublishWorkers,
 		ackWorkers:          cfg.Pipeline.AckWorkers,
 		singleStream:        singleStream,
+		orderedPublish:      cfg.Pipeline.OrderedPublish,
+		orderingKey:         orderingKey(&cfg.Pipeline),
+		origin:              originOf(&cfg.MQTT),
+		noAck:               cfg.Redis.NoAck,
+		projection:          newFieldProjection(cfg.Pipeline.FieldAllowlist, cfg.Pipeline.FieldDenylist),
+		timestamps:          newTimestampNormalizer(&cfg.Pipeline),
+		structured:          newStructuredData(cfg.Pipeline.StructuredData),
+		oversize:            newOversizeLimit(redisClient, &cfg.Pipeline),
+		receipts:            newReceiptLog(redisClient, cfg, logger),
+		events:              newEventRing(observers, logger),
+		backfill:            newBackfill(redisClient, mqttPublisher, &cfg.Redis),
+		dedup:               newDeduplicator(redisClient, &cfg.Redis),
+		routes:              newPublishRoutes(topics, &cfg.MQTT),
+		destinations:        newStreamDestinations(topics, cfg),
+		fanout:              newFanout(topics, &cfg.MQTT),
+		sampler:             newSampler(topics, &cfg.MQTT),
+		memory:              memory,
+		archive:             archived,
+		nacks:               newNackPolicies(redisClient, &cfg.Pipeline),
+		nackFlood:           newNackFlood(&cfg.Pipeline),
+		rejected:            newRejectedDLQ(redisClient, &cfg.Redis),
+		enrich:              newEnrichment(redisClient, cfg),
+		retries:             newRetryQueue(&cfg.Pipeline),
+		maxAge:              newMaxAgePolicies(redisClient, cfg),
+		objects:             newObjectChecks(redisClient, cfg),
+		redaction:           newRedaction(redisClient, &cfg.Pipeline),
+		quotas:              newStreamQuotas(&cfg.Pipeline),
+		throughput:          newThroughputCap(&cfg.Pipeline, time.Now()),
+		flow:                newFlowControl(&cfg.MQTT),
+		published:           newPublishDedup(redisClient, &cfg.Pipeline),
+		pressure:            newMemoryPressure(redisClient, topics, cfg, drain),
+		lag:                 newLagMonitor(redisClient, &cfg.Redis, drain),
+		slo:                 newAckSLO(topics, cfg),
+		capture:             newCapture(cfg.Pipeline.CaptureSize),
+		control:             newRuntimeControl(redisClient, cfg, claimTicker),
+		leases:              newStreamLeases(redisClient, &cfg.Redis),
+		watchdog:            newPublishWatchdog(mqttPublisher, cfg),
+		compactor:           newCompactor(redisClient, &cfg.Redis),
+		compactInterval:     cfg.Redis.CompactInterval,
+		readSize:            newAdaptiveBatch(redisClient, &cfg.Redis),
+		snapshotPath:        cfg.Pipeline.SnapshotPath,
+		live:                newLiveness(),
 		log:                 logger,
 	}, nil
 }
 
+func orderingKey(cfg *config.PipelineConfig) []byte {
+	if cfg.OrderingKey == "" {
+		return nil
+	}
+	return []byte(cfg.OrderingKey)
+}
+
+// originOf returns the origin published payloads are marked with under
+// MQTT_ORIGIN_MARKER, or nil when they are not.
+func originOf(cfg *config.MQTTConfig) []byte {
+	if !cfg.OriginMarker {
+		return nil
+	}
+	return []byte("syslog-consumer:" + cfg.ClientID)
+}
+
+// newAckChans returns the ACK channels, sharded by stream-name hash so
+// same-stream ACKs land on the same worker, maximizing per-flush batch sizes.
+// capacity is split across them.
+func newAckChans(capacity, workers int) []chan message.AckMessage {
+	ackChans := make([]chan message.AckMessage, workers)
+	chanCap := max(capacity/workers, 64)
+	for i := range ackChans {
+		ackChans[i] = make(chan message.AckMessage, chanCap)
+	}
+	metrics.AckBufferCapacity.Set(int64(chanCap * workers))
+	return ackChans
+}
+
+// newPublishChans returns the per-worker queues of ordered mode, where each
+// publish worker owns a queue and every ordering key is pinned to one of
+// them, so a key is never published concurrently. It returns nil otherwise.
+func newPublishChans(cfg *config.PipelineConfig) []chan message.Batch {
+	if !cfg.OrderedPublish {
+		return nil
+	}
+	publishChans := make([]chan message.Batch, cfg.PublishWorkers)
+	queueCap := max(cfg.MessageQueueCapacity/cfg.PublishWorkers, 1)
+	for i := range publishChans {
+		publishChans[i] = make(chan message.Batch, queueCap)
+	}
+	return publishChans
+}
+
+// claimTickInterval is how often the claim loop runs. Under claim
+// coordination most ticks only ask for the claim turn, so it runs
+// redis.ClaimTurnChecks times per claim interval.
+func claimTickInterval(cfg *config.RedisConfig) time.Duration {
+	if cfg.ClaimCoordination {
+		return redis.ClaimInterval(cfg) / redis.ClaimTurnChecks
+	}
+	return redis.ClaimInterval(cfg)
+}
+
 func (hp *HotPath) startLoop(
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -138,10 +390,17 @@ func (hp *HotPath) startLoop(
 	})
 }
 
+// SetLifecycle has hp record the pauses and resumes of the control hash
+// and the streams the refresh discovers to r. Call it before Run.
+func (hp *HotPath) SetLifecycle(r *lifecycle.Recorder) {
+	hp.transitions = r
+}
+
 // Run blocks until ctx is canceled or a loop returns a fatal error. It
 // returns ctx.Err() on graceful shutdown.
 func (hp *HotPath) Run(ctx context.Context) error {
 	hp.log.Infof(ctx, "Starting hot path orchestrator")
+	hp.loadSnapshot(ctx)
 
 	// lifeCtx outlives ctx so ACK callbacks and the drain phase can still
 	// complete after the orchestrator's loop context is canceled.
@@ -159,109 +418,291 @@ func (hp *HotPath) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to subscribe to ACK topic: %w", err)
 	}
 
+	hp.receipts.start(lifeCtx)
+	hp.events.start()
 	hp.startAckWorkers(ctx, lifeCtx)
 
-	wg, errCh := hp.startLoops(ctx, lifeCtx)
+	// Producers get their own context so a fatal loop error stops them the
+	// same way ctx cancellation does.
+	producerCtx, stopProducers := context.WithCancel(ctx)
+	defer stopProducers()
+	loops := hp.startLoops(producerCtx, lifeCtx)
 
+	var runErr error
 	select {
 	case <-ctx.Done():
 		hp.log.Infof(ctx, "Shutting down hot path orchestrator")
-		hp.shutdown(wg)
-		return ctx.Err()
-	case err := <-errCh:
-		hp.log.Errorf(ctx, "Hot path error: %v", err)
-		hp.shutdown(wg)
-		return err
+		runErr = ctx.Err()
+	case runErr = <-loops.errCh:
+		hp.log.Errorf(ctx, "Hot path error: %v", runErr)
 	}
+	hp.shutdown(lifeCtx, stopProducers, loops)
+	return runErr
 }
 
 func (hp *HotPath) startAckWorkers(ctx, lifeCtx context.Context) {
 	hp.log.Infof(ctx, "Starting %d ACK workers", hp.ackWorkers)
-	for i := range hp.ackWorkers {
-		ch := hp.ackChans[i]
-		hp.ackWg.Go(func() { hp.ackWorker(lifeCtx, ch) })
+	flushCtx, abandon := context.WithCancel(lifeCtx)
+	hp.ackDrain.abandon = abandon
+	hp.ackGate.Lock()
+	defer hp.ackGate.Unlock()
+	hp.ackStart = func(ch <-chan message.AckMessage) {
+		hp.ackWg.Go(func() { hp.ackWorker(flushCtx, ch) })
+	}
+	for _, ch := range hp.ackChans {
+		hp.ackStart(ch)
 	}
 }
 
-func (hp *HotPath) startLoops(ctx, lifeCtx context.Context) (wg *sync.WaitGroup, errCh <-chan error) {
-	wg = &sync.WaitGroup{}
-	numLoops := 4 + hp.publishWorkers
-	ch := make(chan error, numLoops)
+// loopGroup tracks the goroutines that shutdown stops phase by phase.
+type loopGroup struct {
+	errCh chan error
+	// stopPublishers tells publish workers to drain their queue and exit;
+	// abortPublish makes them abandon whatever is still queued.
+	stopPublishers context.CancelFunc
+	abortPublish   context.CancelFunc
+	// stopWatchdog ends the publish watchdog once the workers are gone;
+	// nil without one.
+	stopWatchdog context.CancelFunc
+	producers    sync.WaitGroup
+	publishers   sync.WaitGroup
+	watchdog     sync.WaitGroup
+}
 
-	hp.startLoop(ctx, wg, "fetch", hp.fetchLoop, ch)
-	hp.startLoop(ctx, wg, "claim", hp.claimLoop, ch)
-	hp.startLoop(ctx, wg, "cleanup", hp.cleanupLoop, ch)
+func (hp *HotPath) startLoops(producerCtx, lifeCtx context.Context) *loopGroup {
+	g := &loopGroup{errCh: make(chan error, 10+hp.publishWorkers)}
 
-	if !hp.singleStream {
-		hp.startLoop(ctx, wg, "refresh", hp.refreshLoop, ch)
+	hp.startLoop(producerCtx, &g.producers, "fetch", hp.fetchLoop, g.errCh)
+	if hp.noAck {
+		hp.log.Infof(producerCtx, "Reading with NOACK: delivery is at-most-once and the claim loop is off")
+	} else {
+		hp.startLoop(producerCtx, &g.producers, "claim", hp.claimLoop, g.errCh)
 	}
+	hp.startLoop(producerCtx, &g.producers, "cleanup", hp.cleanupLoop, g.errCh)
+	hp.startMaintenance(producerCtx, g)
 
-	hp.log.Infof(ctx, "Starting %d publish workers", hp.publishWorkers)
+	if hp.orderedPublish {
+		hp.log.Infof(producerCtx, "Starting %d publish workers (ordered per stream)", hp.publishWorkers)
+	} else {
+		hp.log.Infof(producerCtx, "Starting %d publish workers", hp.publishWorkers)
+	}
+	stopCtx, stop := context.WithCancel(lifeCtx)
+	publishCtx, abort := context.WithCancel(lifeCtx)
+	g.stopPublishers, g.abortPublish = stop, abort
 	for i := range hp.publishWorkers {
-		hp.startLoop(ctx, wg, "publish-"+strconv.Itoa(i), hp.makePublishLoop(lifeCtx, i), ch)
+		hp.startPublisher(stopCtx, publishCtx, g, i)
+	}
+	if hp.watchdog != nil {
+		watchCtx, stopWatchdog := context.WithCancel(lifeCtx)
+		g.stopWatchdog = stopWatchdog
+		hp.startLoop(watchCtx, &g.watchdog, "watchdog", hp.makeWatchdogLoop(stopCtx, publishCtx, g), g.errCh)
 	}
-	errCh = ch
-	return wg, errCh
+	return g
 }
 
-func (hp *HotPath) shutdown(wg *sync.WaitGroup) {
-	hp.claimTicker.Stop()
-	hp.cleanupTicker.Stop()
-	if hp.refreshTicker != nil {
-		hp.refreshTicker.Stop()
+// startMaintenance starts the producer loops that only some settings need.
+func (hp *HotPath) startMaintenance(ctx context.Context, g *loopGroup) {
+	if !hp.singleStream {
+		hp.startLoop(ctx, &g.producers, "refresh", hp.refreshLoop, g.errCh)
+	}
+	if hp.pressure != nil {
+		hp.startLoop(ctx, &g.producers, "memory", hp.memoryLoop, g.errCh)
+	}
+	if hp.lag != nil {
+		hp.startLoop(ctx, &g.producers, "lag", hp.lagLoop, g.errCh)
+	}
+	if hp.slo != nil {
+		hp.startLoop(ctx, &g.producers, "slo", hp.sloLoop, g.errCh)
+	}
+	if hp.compactor != nil {
+		hp.startLoop(ctx, &g.producers, "compact", hp.compactLoop, g.errCh)
+	}
+	if hp.control != nil {
+		hp.startLoop(ctx, &g.producers, "control", hp.controlLoop, g.errCh)
+	}
+	if hp.leases != nil {
+		hp.startLoop(ctx, &g.producers, "lease", hp.leaseLoop, g.errCh)
+	}
+	if hp.retries != nil {
+		hp.startLoop(ctx, &g.producers, "retry", hp.retryLoop, g.errCh)
+	}
+}
+
+// shutdown stops the pipeline in dependency order so that no goroutine
+// ever sends on a closed channel:
+//
+//  1. stop the producers (fetch, claim, cleanup, refresh, memory, lag,
+//     compact, control, lease, retry) and wait for them, then their
+//     tickers, release the stream leases and what is left on the retry
+//     queue;
+//  2. let the publish workers drain their queues, bounded by drainTimeout,
+//     then stop the publish watchdog, which replaces workers stuck meanwhile;
+//  3. close the publish queues, which now have no readers or writers;
+//  4. unsubscribe from the ACK topic and close the ACK gate;
+//  5. close the ACK queues and wait for the workers' final flush, bounded
+//     by the ACK drain timeout;
+//  6. close the receipt queue and wait for the final receipt write, bounded
+//     by drainTimeout;
+//  7. close the event queue and wait for the observers, bounded likewise;
+//  8. save the state snapshot.
+//
+// The MQTT and Redis clients are closed by their owner after Run returns.
+func (hp *HotPath) shutdown(ctx context.Context, stopProducers context.CancelFunc, g *loopGroup) {
+	stopProducers()
+	g.producers.Wait()
+	hp.stopTickers() // after the control loop, which resets the claim ticker
+	hp.releaseLeases(ctx)
+	hp.clearRetries()
+
+	g.stopPublishers()
+	if !waitTimeout(&g.publishers, hp.drainTimeout) {
+		hp.log.Warnf(ctx, "Drain timeout %s exceeded, abandoning queued batches (they stay pending and will be reclaimed)",
+			hp.drainTimeout)
+		g.abortPublish()
+		g.publishers.Wait()
+	}
+	g.abortPublish()
+	if g.stopWatchdog != nil {
+		g.stopWatchdog()
+		g.watchdog.Wait()
 	}
-	// wg.Wait() must precede the channel closes: workers may still send.
-	wg.Wait()
 	close(hp.msgChan)
+	for _, ch := range hp.publishChans {
+		close(ch)
+	}
+
+	hp.unsubscribeAck(ctx)
+	hp.closeAckChans()
+	hp.drainACKs(ctx)
+
+	hp.receipts.close(ctx, hp.drainTimeout)
+	hp.events.close(ctx, hp.drainTimeout)
+	hp.saveSnapshot(ctx)
+}
+
+// waitTimeout reports whether wg finished within d.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// ackUnsubscriber is implemented by publishers that can drop the ACK
+// subscription before the ACK queues are closed.
+type ackUnsubscriber interface {
+	UnsubscribeAck(ctx context.Context) error
+}
+
+func (hp *HotPath) unsubscribeAck(ctx context.Context) {
+	u, ok := hp.mqtt.(ackUnsubscriber)
+	if !ok {
+		return
+	}
+	if err := u.UnsubscribeAck(ctx); err != nil {
+		hp.log.Warnf(ctx, "Failed to unsubscribe from ACK topic: %v", err)
+	}
+}
+
+// closeAckChans waits for in-flight ACK callbacks to leave the handler and
+// turns away later ones before closing the queues.
+func (hp *HotPath) closeAckChans() {
+	hp.ackGate.Lock()
+	defer hp.ackGate.Unlock()
+	hp.acksClosed = true
+	hp.ackDrain.closing.Store(true)
 	for _, ch := range hp.ackChans {
 		close(ch)
 	}
-	hp.ackWg.Wait()
 }
 
-func (hp *HotPath) fetchLoop(ctx context.Context) error {
-	backoffTimer := time.NewTimer(hp.errorBackoff)
-	backoffTimer.Stop()
+func (hp *HotPath) stopTickers() {
+	hp.claimTicker.Stop()
+	hp.cleanupTicker.Stop()
+	if hp.refreshTicker != nil {
+		hp.refreshTicker.Stop()
+	}
+}
 
+func (hp *HotPath) fetchLoop(ctx context.Context) error {
+	if err := hp.runBackfill(ctx); err != nil {
+		return err
+	}
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-
-		batch, err := hp.redis.ReadBatch(ctx)
-		if err != nil {
-			hp.log.Errorf(ctx, "Failed to read batch from Redis: %v", err)
-			metrics.FetchErrors.Add(1)
-			backoffTimer.Reset(hp.errorBackoff)
-			select {
-			case <-ctx.Done():
-				backoffTimer.Stop()
-				return ctx.Err()
-			case <-backoffTimer.C:
-			}
-			continue
+		if err := hp.waitResume(ctx); err != nil {
+			return err
 		}
-
-		if len(batch.Items) == 0 {
-			continue
+		if _, err := hp.fetchOnce(ctx); err != nil {
+			return err
 		}
+	}
+}
 
-		if hp.log.DebugEnabled(ctx) {
-			hp.log.Debugf(ctx, "Fetched %d messages from Redis", len(batch.Items))
-		}
-		metrics.MessagesFetched.Add(int64(len(batch.Items)))
+// fetchOnce reads one batch of new entries and queues what is left of it
+// after the drop checks, returning how many entries the read returned. A
+// failed read is logged and backed off; only ctx, or a queue closed to it,
+// makes it fail.
+func (hp *HotPath) fetchOnce(ctx context.Context) (int, error) {
+	hp.live.tick()
+	batch, err := hp.redis.ReadBatch(ctx)
+	if err != nil {
+		hp.log.Errorf(ctx, "Failed to read batch from Redis: %v", err)
+		metrics.FetchErrors.Add(1)
+		hp.events.failed("fetch", 0, err)
+		return 0, sleepCtx(ctx, hp.errorBackoff)
+	}
 
-		if err := hp.enqueueBatch(ctx, batch); err != nil {
-			return err
-		}
+	hp.live.read()
+	if hp.pressure.draining() || hp.lag.draining() {
+		hp.readSize.drain()
+	} else {
+		hp.readSize.observeRead(len(batch.Items))
+	}
+	n := len(batch.Items)
+	if n == 0 {
+		return 0, nil
+	}
+
+	if hp.log.DebugEnabled(ctx) {
+		hp.log.Debugf(ctx, "Fetched %d messages from Redis", n)
 	}
+	metrics.MessagesFetched.Add(int64(n))
+	hp.events.fetched("read", n)
+	if batch = hp.dropAged(ctx, hp.redactBatch(ctx, batch), time.Now()); len(batch.Items) == 0 {
+		return n, nil
+	}
+	if batch = hp.dropInvalid(ctx, batch); len(batch.Items) == 0 {
+		return n, nil
+	}
+	if batch = hp.dropDuplicates(ctx, batch); len(batch.Items) == 0 {
+		return n, nil
+	}
+	return n, hp.enqueueBatch(ctx, batch)
 }
 
 func (hp *HotPath) enqueueBatch(ctx context.Context, batch message.Batch) error {
+	if hp.orderedPublish {
+		return hp.enqueueOrdered(ctx, batch)
+	}
+	hp.memory.admit(&batch)
+	return enqueueTo(ctx, hp.msgChan, batch)
+}
+
+func enqueueTo(ctx context.Context, ch chan<- message.Batch, batch message.Batch) error {
 	select {
-	case hp.msgChan <- batch:
+	case ch <- batch:
 		return nil
 	default:
 	}
@@ -269,18 +710,75 @@ func (hp *HotPath) enqueueBatch(ctx context.Context, batch message.Batch) error
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case hp.msgChan <- batch:
+	case ch <- batch:
 	}
 	return nil
 }
 
+// enqueueOrdered hands each ordering key's messages to the worker that owns
+// the key. ReadBatch groups items by stream, so without an ordering key the
+// shard is only recomputed at stream boundaries. A batch that maps to a
+// single worker (always the case in single-stream mode without an ordering
+// key) is forwarded as is; otherwise it is partitioned into per-worker
+// batches and the pooled original is released. Items stay unextracted unless
+// an ordering key had to be read.
+func (hp *HotPath) enqueueOrdered(ctx context.Context, batch message.Batch) error {
+	if len(batch.Items) == 0 {
+		batch.Release()
+		return nil
+	}
+
+	// Keys are read before admit, which may spill the bodies holding them.
+	var shardOf []int
+	var mixed bool
+	if hp.orderingKey != nil {
+		shardOf, mixed = keyShards(&batch, hp.orderingKey, len(hp.publishChans))
+	} else {
+		shardOf, mixed = shardItems(batch.Items, len(hp.publishChans))
+	}
+	hp.memory.admit(&batch)
+	if !mixed {
+		return enqueueTo(ctx, hp.publishChans[shardOf[0]], batch)
+	}
+
+	for idx, part := range batch.Partition(shardOf, len(hp.publishChans)) {
+		if len(part.Items) == 0 {
+			continue
+		}
+		if err := enqueueTo(ctx, hp.publishChans[idx], part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardItems returns the owning worker of every item and whether the items
+// span more than one worker.
+func shardItems(items []message.Redis, shards int) (shardOf []int, mixed bool) {
+	shardOf = make([]int, len(items))
+	shard := streamShard(items[0].Stream, shards)
+	for i := range items {
+		if i > 0 && items[i].Stream != items[i-1].Stream {
+			shard = streamShard(items[i].Stream, shards)
+		}
+		shardOf[i] = shard
+		mixed = mixed || shard != shardOf[0]
+	}
+	return shardOf, mixed
+}
+
 // hintedPublisher lets each worker supply a routing hint instead of contending
 // on a shared atomic.
 type hintedPublisher interface {
 	PublishFrom(ctx context.Context, payload message.Payload, hint uint64) error
 }
 
-func (hp *HotPath) makePublishLoop(lifeCtx context.Context, workerIdx int) func(context.Context) error {
+// makePublishLoop returns a worker that publishes on publishCtx until its
+// loop context is canceled, then drains its queue and exits. Producers are
+// stopped before that cancellation, so the drain sees every queued batch;
+// once publishCtx is canceled the remaining batches are released unpublished
+// and left pending in Redis for the claim loop.
+func (hp *HotPath) makePublishLoop(publishCtx context.Context, workerIdx int) func(context.Context) error {
 	builder := jsonfast.New(4096)
 	enc := compress.NewEncoder()
 	bw := jsonfast.NewBatchWriter(4096)
@@ -298,63 +796,180 @@ func (hp *HotPath) makePublishLoop(lifeCtx context.Context, workerIdx int) func(
 		}
 		return hp.mqtt.Publish(ctx, payload)
 	}
+	slot := hp.watchdog.slot(workerIdx)
+	gen := slot.generation()
+	publishFn = hp.archive.wrap(slot.watch(publishFn))
+
+	// An ordered worker keeps its hint fixed so its keys always leave on
+	// the same pool connection (paho preserves order per connection), and
+	// retries a failed publish before moving on.
+	src := hp.msgChan
+	if hp.orderedPublish {
+		src = hp.publishChans[workerIdx]
+		stride = 0
+		publishFn = hp.publishInOrder(publishFn)
+	}
+
+	publish := func(batch message.Batch) {
+		held := hp.memory.checkout(&batch)
+		if publishCtx.Err() == nil && hp.applyQuotas(publishCtx, &batch) == nil {
+			hp.dropPublished(publishCtx, &batch)
+		}
+		if publishCtx.Err() == nil && len(batch.Items) > 0 && hp.applyPublishCaps(publishCtx, len(batch.Items)) == nil {
+			start := time.Now()
+			hp.publishToStreams(publishCtx, builder, enc, &batch, bw, &compressed, publishFn)
+			hp.readSize.observePublish(time.Since(start))
+		}
+		batch.Release()
+		hp.memory.release(held)
+	}
 
+	// A worker the watchdog abandoned leaves its queue to the replacement.
 	return func(ctx context.Context) error {
-		for {
-			select {
-			case <-ctx.Done():
-				for {
-					select {
-					case batch := <-hp.msgChan:
-						hp.publishBatch(lifeCtx, builder, enc, batch.Items, bw, &compressed, publishFn)
-						batch.Release()
-					default:
-						return ctx.Err()
-					}
+		for !slot.replaced(gen) {
+			batch, ok := hp.retries.next(ctx, src)
+			if ok {
+				publish(batch)
+				continue
+			}
+			for !slot.replaced(gen) {
+				select {
+				case batch := <-src:
+					publish(batch)
+				default:
+					return ctx.Err()
 				}
-			case batch := <-hp.msgChan:
-				hp.publishBatch(lifeCtx, builder, enc, batch.Items, bw, &compressed, publishFn)
-				batch.Release()
 			}
 		}
+		return nil
 	}
 }
 
 func (hp *HotPath) publishBatch(
 	ctx context.Context,
 	builder *jsonfast.Builder, enc *zstd.Encoder,
-	batch []message.Redis, bw *jsonfast.BatchWriter, compressed *[]byte,
+	batch *message.Batch, bw *jsonfast.BatchWriter, compressed *[]byte,
 	publishFn func(context.Context, message.Payload) error,
 ) {
-	bw.Reset()
+	if hp.oversize != nil {
+		hp.publishLimitedBatch(ctx, builder, enc, batch, bw, compressed, publishFn)
+		return
+	}
 
-	for i := range batch {
-		msg := &batch[i]
-		if msg.Object == "" && msg.Raw == "" {
-			hp.log.Warnf(ctx, "Skipping message %s with empty body", msg.ID)
+	bw.Reset()
+	var projected projectionStats
+	lines := hp.fanout.lines()
+	defer putRawLines(lines)
+	samples := hp.sampler.lines()
+	defer putSampleLines(samples)
+
+	for i, msg := range batch.Indexed() {
+		if hp.skipEmpty(ctx, msg) || hp.publishRouted(ctx, builder, enc, msg, batch.Route(i), compressed) {
 			continue
 		}
-		bw.Append(hp.buildPayload(builder, msg))
+		bw.Append(hp.buildProjectedPayload(builder, msg, &projected))
+		lines.add(msg)
+		samples.add(builder, msg)
 	}
 
 	if bw.Count() == 0 {
 		return
 	}
 
-	*compressed = compress.EncodeWith(enc, *compressed, bw.Bytes())
+	*compressed = compress.EncodeMarked(enc, *compressed, bw.Bytes(), hp.origin)
+	publishAll := hp.withSample(enc, samples, hp.withFanout(enc, lines, publishFn))
+	if hp.sendPayload(ctx, *compressed, bw.Count(), bw.Bytes(), nil, publishAll) {
+		addProjectionMetrics(&projected)
+		hp.receipts.recordLines(bw.Bytes())
+	}
+}
+
+// publishLimitedBatch is publishBatch under PIPELINE_MAX_PAYLOAD_BYTES:
+// oversize records go through the oversize policy and the batch may be
+// published as several payloads.
+func (hp *HotPath) publishLimitedBatch(
+	ctx context.Context,
+	builder *jsonfast.Builder, enc *zstd.Encoder,
+	batch *message.Batch, bw *jsonfast.BatchWriter, compressed *[]byte,
+	publishFn func(context.Context, message.Payload) error,
+) {
+	bw.Reset()
+	var projected projectionStats
+	ob := getOversizeBatch()
+	defer putOversizeBatch(ob)
+
+	for i, msg := range batch.Indexed() {
+		if hp.skipEmpty(ctx, msg) || hp.publishRouted(ctx, builder, enc, msg, batch.Route(i), compressed) {
+			continue
+		}
+		hp.oversize.add(bw, builder, msg, hp.buildProjectedPayload(builder, msg, &projected), ob)
+	}
 
-	if err := publishFn(ctx, *compressed); err != nil {
-		hp.log.Errorf(ctx, "Failed to publish batch of %d messages: %v",
-			bw.Count(), err)
-		metrics.PublishErrors.Add(int64(bw.Count()))
+	if len(ob.deadLetters) > 0 {
+		hp.deadLetterOversize(ctx, ob.deadLetters)
+	}
+	if len(ob.spans) == 0 {
 		return
 	}
+	if hp.publishSpans(ctx, enc, bw.Bytes(), ob.spans, compressed, publishFn) > 0 {
+		addProjectionMetrics(&projected)
+	}
+}
+
+func (hp *HotPath) skipEmpty(ctx context.Context, msg *message.Redis) bool {
+	if msg.Object == "" && msg.Raw == "" {
+		hp.log.Warnf(ctx, "Skipping message %s with empty body", msg.ID)
+		return true
+	}
+	return false
+}
+
+// sendPayload publishes one compressed payload carrying messages entries
+// and records the outcome. The payload compresses the records of data, one
+// per line, or those in spans of data when spans is set.
+func (hp *HotPath) sendPayload(
+	ctx context.Context, payload message.Payload, messages int, data []byte, spans []recordSpan,
+	publishFn func(context.Context, message.Payload) error,
+) bool {
+	if err := hp.publishWithin(ctx, payload, publishFn); err != nil {
+		if !hp.publishAbandoned(ctx, err, messages) {
+			hp.publishFailed(ctx, err, messages, data, spans)
+		}
+		return false
+	}
 
 	if hp.log.DebugEnabled(ctx) {
+		rawLen := len(data)
+		if len(spans) > 0 {
+			rawLen = spans[len(spans)-1].end - spans[0].start
+		}
 		hp.log.Debugf(ctx, "Published compressed batch: %d messages, %d→%d bytes",
-			bw.Count(), bw.Len(), len(*compressed))
+			messages, rawLen, len(payload))
+	}
+	metrics.MessagesPublished.Add(int64(messages))
+	hp.events.published(messages, len(payload))
+	hp.capture.published(payload, messages)
+	return true
+}
+
+func addProjectionMetrics(projected *projectionStats) {
+	if projected.fields > 0 {
+		metrics.ProjectionFieldsDropped.Add(projected.fields)
+		metrics.ProjectionBytesSaved.Add(projected.bytes)
+	}
+	if projected.tsNormalized > 0 {
+		metrics.TimestampsNormalized.Add(projected.tsNormalized)
+		metrics.TimestampsSkewed.Add(projected.tsSkewed)
+	}
+	if projected.tsUnparsed > 0 {
+		metrics.TimestampsUnparsed.Add(projected.tsUnparsed)
+	}
+	if projected.sdRenamed > 0 {
+		metrics.StructuredDataRenamed.Add(projected.sdRenamed)
+	}
+	if projected.objWrapped > 0 {
+		metrics.InvalidObjectsWrapped.Add(projected.objWrapped)
 	}
-	metrics.MessagesPublished.Add(int64(bw.Count()))
 }
 
 var (
@@ -365,27 +980,44 @@ var (
 var (
 	fkSeverity = jsonfast.NewFieldKey("severity")
 	fkRaw      = jsonfast.NewFieldKey("raw")
+	keyRaw     = []byte("raw")
 )
 
 // buildPayload returns a slice that is only valid until the next call on
 // the same builder.
 func (hp *HotPath) buildPayload(builder *jsonfast.Builder, msg *message.Redis) []byte {
-	builder.Reset()
-
-	builder.AppendRawString(msg.ID)
-	builder.AppendRawString("\t")
-	builder.AppendRawString(msg.Stream)
-	builder.AppendRawString("\t")
+	var discard projectionStats
+	return hp.buildProjectedPayload(builder, msg, &discard)
+}
 
+// buildProjectedPayload is buildPayload with the fields removed by the
+// configured projection accounted in stats.
+func (hp *HotPath) buildProjectedPayload(
+	builder *jsonfast.Builder, msg *message.Redis, stats *projectionStats,
+) []byte {
+	builder.Reset()
+	appendHeader(builder, msg)
 	builder.BeginObject()
 
-	if msg.Object != "" {
+	var enrich enrichMatch
+	if hp.objects.wraps(msg) {
+		appendWrapped(builder, msg, stats)
+	} else if msg.Object != "" {
 		jsonfast.IterateFieldsString(msg.Object, func(key, value []byte) bool {
 			name := key[1 : len(key)-1]
+			hp.enrich.observe(&enrich, name, value)
+			if !hp.projection.keep(name) {
+				stats.drop(len(name), len(value))
+				return true
+			}
+			if hp.timestamps.matches(name) {
+				hp.timestamps.add(builder, value, stats)
+				return true
+			}
 			switch len(name) {
 			case 15:
 				if bytes.Equal(name, keyStructuredData) {
-					jsonfast.FlattenObject(builder, value)
+					hp.structured.flatten(builder, msg.Object, value, hp.projection, stats)
 					return true
 				}
 			case 8:
@@ -399,34 +1031,61 @@ func (hp *HotPath) buildPayload(builder *jsonfast.Builder, msg *message.Redis) [
 		})
 	}
 
-	if msg.Raw == "" {
-		builder.AddStringFieldKey(fkRaw, "-")
-	} else {
-		builder.AddStringFieldKey(fkRaw, msg.Raw)
-	}
+	hp.enrich.add(builder, &enrich)
+	hp.appendRaw(builder, msg, stats)
 
 	builder.EndObject()
 
 	return builder.Bytes()
 }
 
+// appendHeader writes the "<id>\t<stream>\t" prefix of every record.
+func appendHeader(builder *jsonfast.Builder, msg *message.Redis) {
+	builder.AppendRawString(msg.ID)
+	builder.AppendRawString("\t")
+	builder.AppendRawString(msg.Stream)
+	builder.AppendRawString("\t")
+}
+
+// appendRaw emits the original line. The projection only applies to "raw"
+// when a parsed object is present, so a message is never reduced to {}.
+func (hp *HotPath) appendRaw(builder *jsonfast.Builder, msg *message.Redis, stats *projectionStats) {
+	raw := msg.Raw
+	if raw == "" {
+		raw = "-"
+	}
+	if msg.Object != "" && !hp.projection.keep(keyRaw) {
+		stats.drop(len(keyRaw), len(raw)+2)
+		return
+	}
+	builder.AddStringFieldKey(fkRaw, raw)
+}
+
 func (hp *HotPath) claimLoop(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-hp.claimTicker.C:
+			if hp.backfill.running() || hp.control.paused() {
+				continue // paused, or the backfill claims until nothing is idle
+			}
 			batch, err := hp.redis.ClaimIdle(ctx)
 			if err != nil {
 				hp.log.Errorf(ctx, "Failed to claim idle messages: %v", err)
+				hp.events.failed("claim", 0, err)
 				continue
 			}
 
+			hp.holdClaimed(ctx, &batch)
+			batch = hp.dropInvalid(ctx, hp.dropAged(ctx, hp.redactBatch(ctx, batch), time.Now()))
+
 			if len(batch.Items) > 0 {
 				hp.log.Infof(ctx, "Claimed %d idle messages", len(batch.Items))
 				metrics.MessagesClaimed.Add(int64(len(batch.Items)))
+				hp.events.fetched("claim", len(batch.Items))
 
-				if err := hp.enqueueBatch(ctx, batch); err != nil {
+				if err := hp.enqueueRetry(ctx, batch); err != nil {
 					return err
 				}
 			}
@@ -442,6 +1101,7 @@ func (hp *HotPath) cleanupLoop(ctx context.Context) error {
 		case <-hp.cleanupTicker.C:
 			if err := hp.redis.CleanupDeadConsumers(ctx, hp.consumerIdleTimeout); err != nil {
 				hp.log.Errorf(ctx, "Failed to cleanup dead consumers: %v", err)
+				hp.events.failed("cleanup", 0, err)
 			}
 		}
 	}
@@ -460,6 +1120,7 @@ func (hp *HotPath) refreshLoop(ctx context.Context) error {
 			}
 			if newCount > 0 {
 				hp.log.Infof(ctx, "Stream refresh discovered %d new streams", newCount)
+				hp.transitions.Record(lifecycle.StreamsDiscovered, map[string]any{"new_streams": newCount})
 			}
 		}
 	}
@@ -470,10 +1131,23 @@ func (hp *HotPath) refreshLoop(ctx context.Context) error {
 // safe: the claim loop reclaims them on the next start.
 func (hp *HotPath) makeAckHandler(lifeCtx context.Context) func(message.AckMessage) {
 	return func(ack message.AckMessage) {
+		if ack.Control != nil {
+			hp.handleFlowControl(lifeCtx, ack.Control)
+			return
+		}
+		hp.ackGate.RLock()
+		defer hp.ackGate.RUnlock()
+		if hp.acksClosed {
+			return
+		}
 		idx := streamShard(ack.Stream, len(hp.ackChans))
 		select {
 		case hp.ackChans[idx] <- ack:
 			metrics.AckQueueDepth.Add(1)
+			hp.receipts.recordAck(ack)
+			hp.events.acked(&ack)
+			hp.slo.record(&ack, time.Now())
+			hp.capture.acked(&ack)
 		case <-lifeCtx.Done():
 			if hp.log.DebugEnabled(lifeCtx) {
 				hp.log.Debugf(lifeCtx, "Dropping ACK for %v during shutdown", ack.IDs)
@@ -482,10 +1156,12 @@ func (hp *HotPath) makeAckHandler(lifeCtx context.Context) func(message.AckMessa
 	}
 }
 
-func streamShard(stream string, shards int) int {
+// streamShard maps key, a stream name or an ordering key value, to one of
+// shards by FNV-1a hash.
+func streamShard[K string | []byte](key K, shards int) int {
 	h := uint32(2166136261)
-	for i := range len(stream) {
-		h ^= uint32(stream[i])
+	for i := range len(key) {
+		h ^= uint32(key[i])
 		h *= 16777619
 	}
 	return int(h) % shards
@@ -546,8 +1222,7 @@ func (hp *HotPath) ackWorkerSingle(ctx context.Context, ch <-chan message.AckMes
 	flush := func() {
 		if stream != "" {
 			hp.flushACKs(ctx, stream, p)
-			p.ackIDs = p.ackIDs[:0]
-			p.nackCount = 0
+			p.reset()
 		}
 		armed = false
 	}
@@ -564,13 +1239,9 @@ func (hp *HotPath) ackWorkerSingle(ctx context.Context, ch <-chan message.AckMes
 
 			metrics.AckQueueDepth.Add(-1)
 			stream = ack.Stream
-			if ack.Ack {
-				p.ackIDs = append(p.ackIDs, ack.IDs...)
-			} else {
-				p.nackCount += len(ack.IDs)
-			}
+			hp.addACK(p, &ack)
 
-			if len(p.ackIDs)+p.nackCount >= hp.ackBatchSize {
+			if p.size() >= hp.ackBatchSize {
 				flush()
 				continue
 			}
@@ -595,18 +1266,26 @@ func (hp *HotPath) accumulateACK(pending map[string]*pendingACK, ack message.Ack
 		pending[ack.Stream] = p
 	}
 
-	if ack.Ack {
-		p.ackIDs = append(p.ackIDs, ack.IDs...)
-	} else {
-		p.nackCount += len(ack.IDs)
-	}
-
-	return len(p.ackIDs)+p.nackCount >= hp.ackBatchSize
+	hp.addACK(p, &ack)
+	return p.size() >= hp.ackBatchSize
 }
 
 type pendingACK struct {
-	ackIDs    []string
-	nackCount int
+	deadLetters map[string][]string // NACKed IDs to dead-letter, by reason
+	ackIDs      []string
+	retryIDs    []string // NACKed IDs left to the claim loop; PIPELINE_PUBLISH_DEDUP only
+	nackCount   int      // NACKed IDs, dead-lettered ones included
+}
+
+func (p *pendingACK) size() int {
+	return len(p.ackIDs) + p.nackCount
+}
+
+func (p *pendingACK) reset() {
+	p.ackIDs = p.ackIDs[:0]
+	p.retryIDs = p.retryIDs[:0]
+	clear(p.deadLetters)
+	p.nackCount = 0
 }
 
 var pendingACKPool = sync.Pool{
@@ -622,12 +1301,14 @@ func getPendingACK() *pendingACK {
 }
 
 func putPendingACK(p *pendingACK) {
-	p.ackIDs = p.ackIDs[:0]
-	p.nackCount = 0
+	p.reset()
 	pendingACKPool.Put(p)
 }
 
 func (hp *HotPath) flushACKs(parentCtx context.Context, stream string, p *pendingACK) {
+	if hp.ackDrain.skip(parentCtx, p.size()) {
+		return
+	}
 	if len(p.ackIDs) > 0 {
 		ctx, cancel := context.WithTimeout(parentCtx, hp.ackTimeout)
 		err := hp.redis.AckAndDeleteBatch(ctx, p.ackIDs, stream)
@@ -636,19 +1317,19 @@ func (hp *HotPath) flushACKs(parentCtx context.Context, stream string, p *pendin
 		if err != nil {
 			hp.log.Errorf(parentCtx, "Failed to ACK %d messages from stream %s: %v", len(p.ackIDs), stream, err)
 			metrics.AckErrors.Add(1)
+			hp.events.failed("ack", len(p.ackIDs), err)
 		} else {
 			if hp.log.DebugEnabled(parentCtx) {
 				hp.log.Debugf(parentCtx, "ACKed %d messages from stream %s", len(p.ackIDs), stream)
 			}
 			metrics.MessagesAcked.Add(int64(len(p.ackIDs)))
 		}
+		hp.ackDrain.count(len(p.ackIDs), err)
 	}
 
 	if p.nackCount > 0 {
-		metrics.MessagesNacked.Add(int64(p.nackCount))
-		if hp.log.InfoEnabled(parentCtx) {
-			hp.log.Infof(parentCtx, "%d messages from stream %s failed, will be reclaimed", p.nackCount, stream)
-		}
+		hp.flushNACKs(parentCtx, stream, p)
+		hp.ackDrain.count(p.nackCount, nil)
 	}
 }
 
@@ -657,10 +1338,6 @@ func (hp *HotPath) Close() error {
 	hp.closeOnce.Do(func() {
 		close(hp.done)
 	})
-	hp.claimTicker.Stop()
-	hp.cleanupTicker.Stop()
-	if hp.refreshTicker != nil {
-		hp.refreshTicker.Stop()
-	}
-	return nil
+	hp.stopTickers()
+	return errors.Join(hp.memory.close(), hp.archive.close())
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
 )
 
 const (
@@ -39,6 +40,7 @@ func testConfig() *config.Config {
 			MessageQueueCapacity: 4,
 			ErrorBackoff:         1 * time.Second,
 			AckTimeout:           5 * time.Second,
+			DrainTimeout:         5 * time.Second,
 			PublishWorkers:       2,
 			AckWorkers:           2,
 			RefreshInterval:      1 * time.Minute,
@@ -1060,3 +1062,209 @@ func TestFlushACKs_AckAndDeleteError(t *testing.T) {
 	// Should not panic — just logs error
 	hp.flushACKs(t.Context(), testStreamSimp, &pendingACK{ackIDs: []string{"x"}})
 }
+
+// --- ordered publish tests ---
+
+func orderedConfig(workers int) *config.Config {
+	cfg := testConfig()
+	cfg.Redis.Stream = ""
+	cfg.Pipeline.OrderedPublish = true
+	cfg.Pipeline.PublishWorkers = workers
+	cfg.Pipeline.MessageQueueCapacity = 16 * workers
+	return cfg
+}
+
+func TestEnqueueOrdered_PartitionsByStream(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, orderedConfig(4), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	items := []message.Redis{
+		{ID: "1-0", Stream: "alpha"}, {ID: "2-0", Stream: "alpha"},
+		{ID: "1-0", Stream: "beta"},
+		{ID: "1-0", Stream: "gamma"}, {ID: "2-0", Stream: "gamma"}, {ID: "3-0", Stream: "gamma"},
+		{ID: "1-0", Stream: "delta"},
+	}
+	if err := hp.enqueueBatch(t.Context(), message.Batch{Items: items}); err != nil {
+		t.Fatalf("enqueueBatch() error = %v", err)
+	}
+
+	got := make(map[string][]string)
+	for idx, ch := range hp.publishChans {
+		for len(ch) > 0 {
+			batch := <-ch
+			for _, m := range batch.Items {
+				if shard := streamShard(m.Stream, len(hp.publishChans)); shard != idx {
+					t.Errorf("stream %s routed to worker %d; want %d", m.Stream, idx, shard)
+				}
+				got[m.Stream] = append(got[m.Stream], m.ID)
+			}
+		}
+	}
+	want := map[string][]string{
+		"alpha": {"1-0", "2-0"},
+		"beta":  {"1-0"},
+		"gamma": {"1-0", "2-0", "3-0"},
+		"delta": {"1-0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routed IDs = %v; want %v", got, want)
+	}
+	if len(hp.msgChan) != 0 {
+		t.Errorf("len(msgChan) = %d; want 0 in ordered mode", len(hp.msgChan))
+	}
+}
+
+func TestEnqueueOrdered_SingleShardForwardsBatch(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, orderedConfig(3), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	items := []message.Redis{{ID: "1-0", Stream: testStreamS1}, {ID: "2-0", Stream: testStreamS1}}
+	if err := hp.enqueueBatch(t.Context(), message.Batch{Items: items}); err != nil {
+		t.Fatalf("enqueueBatch() error = %v", err)
+	}
+
+	ch := hp.publishChans[streamShard(testStreamS1, 3)]
+	if len(ch) != 1 {
+		t.Fatalf("len(owner queue) = %d; want 1", len(ch))
+	}
+	batch := <-ch
+	if &batch.Items[0] != &items[0] {
+		t.Error("single-shard batch was copied; want it forwarded as is")
+	}
+}
+
+func TestPublishLoop_OrderedUsesFixedConnection(t *testing.T) {
+	var mu sync.Mutex
+	var hints []uint64
+	pub := &mockHintedPublisher{
+		publishFromFn: func(_ context.Context, _ message.Payload, hint uint64) error {
+			mu.Lock()
+			hints = append(hints, hint)
+			mu.Unlock()
+			return nil
+		},
+	}
+
+	hp, err := New(&mockRedis{}, pub, orderedConfig(3), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	for range 3 {
+		hp.publishChans[1] <- message.Batch{Items: []message.Redis{{ID: "1", Stream: testStreamSimp, Object: testObjectKV}}}
+	}
+	// A batch on another worker's queue must not be picked up by worker 1.
+	hp.publishChans[2] <- message.Batch{Items: []message.Redis{{ID: "2", Stream: testStreamSimp, Object: testObjectKV}}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	checkLoopExit(t, hp.makePublishLoop(t.Context(), 1)(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(hints, []uint64{1, 1, 1}) {
+		t.Errorf("PublishFrom hints = %v; want [1 1 1]", hints)
+	}
+	if len(hp.publishChans[2]) != 1 {
+		t.Errorf("len(publishChans[2]) = %d; want 1", len(hp.publishChans[2]))
+	}
+}
+
+func TestClaimTickInterval(t *testing.T) {
+	cfg := config.RedisConfig{ClaimIdle: 10 * time.Second}
+	if got := claimTickInterval(&cfg); got != 10*time.Second {
+		t.Errorf("claimTickInterval() = %v; want ClaimIdle", got)
+	}
+	cfg.ClaimCoordination = true
+	if got := claimTickInterval(&cfg); got != 10*time.Second/redis.ClaimTurnChecks {
+		t.Errorf("claimTickInterval() = %v; want ClaimIdle / ClaimTurnChecks under coordination", got)
+	}
+	cfg.ClaimInterval = 4 * time.Second
+	if got := claimTickInterval(&cfg); got != 4*time.Second/redis.ClaimTurnChecks {
+		t.Errorf("claimTickInterval() = %v; want ClaimInterval / ClaimTurnChecks under coordination", got)
+	}
+	cfg.ClaimCoordination = false
+	if got := claimTickInterval(&cfg); got != 4*time.Second {
+		t.Errorf("claimTickInterval() = %v; want ClaimInterval", got)
+	}
+}
+
+func TestRun_NoAckSkipsClaimLoop(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.NoAck = true
+	cfg.Redis.ClaimIdle = time.Millisecond
+
+	var claims atomic.Int32
+	redisClient := &mockRedis{
+		claimIdleFn: func(context.Context) (message.Batch, error) {
+			claims.Add(1)
+			return message.Batch{}, nil
+		},
+	}
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	checkLoopExit(t, hp.Run(ctx))
+
+	if n := claims.Load(); n != 0 {
+		t.Errorf("ClaimIdle called %d times; want 0 in NOACK mode", n)
+	}
+}
+
+func TestFetchLoop_ReportsLiveness(t *testing.T) {
+	var callCount atomic.Int32
+	r := &mockRedis{
+		readBatchFn: func(ctx context.Context) (message.Batch, error) {
+			if callCount.Add(1) == 1 {
+				return message.Batch{}, errors.New("read error")
+			}
+			<-ctx.Done()
+			return message.Batch{}, ctx.Err()
+		},
+	}
+	cfg := testConfig()
+	cfg.Pipeline.ErrorBackoff = time.Millisecond
+	hp, err := New(r, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.Alive(time.Hour) {
+		t.Error("Alive() = true before the fetch loop started")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	checkLoopExit(t, hp.fetchLoop(ctx))
+
+	select {
+	case <-hp.Ready():
+		t.Error("Ready() closed; want it open while no read succeeded")
+	default:
+	}
+	if !hp.Alive(time.Minute) {
+		t.Error("Alive() = false; want the fetch loop's rounds recorded")
+	}
+
+	r.readBatchFn = nil
+	ctx, cancel = context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	checkLoopExit(t, hp.fetchLoop(ctx))
+	select {
+	case <-hp.Ready():
+	default:
+		t.Error("Ready() open; want it closed after a successful read")
+	}
+}
@@ -0,0 +1,159 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+const deadLetterReasonInvalidObject = "invalid_object"
+
+// parseErrorInvalidObject is the parse_error of a lenient entry. The
+// scanner says whether the object is valid, not where it is broken.
+const parseErrorInvalidObject = "object is not a valid JSON object"
+
+var (
+	fkObjectRaw  = jsonfast.NewFieldKey("object_raw")
+	fkParseError = jsonfast.NewFieldKey("parse_error")
+)
+
+// objectChecks applies PIPELINE_INVALID_OBJECT, and the invalid_object of
+// the REDIS_STREAM_OVERRIDES_FILE overrides over it, to entries whose
+// object field is not a valid JSON object. A nil objectChecks embeds every
+// object as is, without the cost of validating it.
+type objectChecks struct {
+	dlq       deadLetterer
+	mode      string
+	overrides []config.StreamOverride
+	strict    bool // some stream is strict
+}
+
+// objectModes returns the pipeline mode followed by the overrides' ones.
+func objectModes(cfg *config.Config) []string {
+	modes := make([]string, 0, 1+len(cfg.Redis.StreamOverrides))
+	modes = append(modes, cfg.Pipeline.InvalidObject)
+	for i := range cfg.Redis.StreamOverrides {
+		modes = append(modes, cfg.Redis.StreamOverrides[i].InvalidObject)
+	}
+	return modes
+}
+
+func validateObjectChecks(redisClient redis.StreamClient, cfg *config.Config) error {
+	if !slices.Contains(objectModes(cfg), config.InvalidObjectStrict) {
+		return nil
+	}
+	if _, ok := redisClient.(deadLetterer); !ok {
+		return errors.New("hotpath: invalid object strict needs a redis client that supports dead-lettering")
+	}
+	return nil
+}
+
+func newObjectChecks(redisClient redis.StreamClient, cfg *config.Config) *objectChecks {
+	modes := objectModes(cfg)
+	strict := slices.Contains(modes, config.InvalidObjectStrict)
+	if !strict && !slices.Contains(modes, config.InvalidObjectLenient) {
+		return nil
+	}
+	dlq, _ := redisClient.(deadLetterer)
+	return &objectChecks{
+		dlq:       dlq,
+		mode:      cfg.Pipeline.InvalidObject,
+		overrides: cfg.Redis.StreamOverrides,
+		strict:    strict,
+	}
+}
+
+// modeOf returns the invalid object mode of stream.
+func (c *objectChecks) modeOf(stream string) string {
+	if o, ok := config.MatchStreamOverride(c.overrides, stream); ok && o.InvalidObject != "" {
+		return o.InvalidObject
+	}
+	return c.mode
+}
+
+// validObject reports whether object is empty or a valid JSON object.
+func validObject(object string) bool {
+	return object == "" || (object[0] == '{' && jsonfast.IsStructuralJSON(object))
+}
+
+// wraps reports whether msg is published with its object under
+// object_raw: the object is invalid and msg's stream is lenient.
+func (c *objectChecks) wraps(msg *message.Redis) bool {
+	if c == nil || msg.Object == "" || c.modeOf(msg.Stream) != config.InvalidObjectLenient {
+		return false
+	}
+	return !validObject(msg.Object)
+}
+
+// mark returns which of items have an invalid object on a strict stream,
+// or nil when none has. Entries of a batch mostly share a stream, so the
+// mode is looked up once per run of them.
+func (c *objectChecks) mark(items []message.Redis) []bool {
+	var invalid []bool
+	var stream string
+	var strict bool
+	for i := range items {
+		if i == 0 || items[i].Stream != stream {
+			stream = items[i].Stream
+			strict = c.modeOf(stream) == config.InvalidObjectStrict
+		}
+		if !strict || validObject(items[i].Object) {
+			continue
+		}
+		if invalid == nil {
+			invalid = make([]bool, len(items))
+		}
+		invalid[i] = true
+	}
+	return invalid
+}
+
+// dropInvalid dead-letters the entries of batch whose object is invalid on
+// a strict stream. When that fails they are left pending, and the claim
+// loop brings them back here. The returned batch is released when nothing
+// is left of it.
+func (hp *HotPath) dropInvalid(ctx context.Context, batch message.Batch) message.Batch {
+	if hp.objects == nil || !hp.objects.strict {
+		return batch
+	}
+	batch.Decode() // the objects are checked, and dead letters carry the body
+	invalid := hp.objects.mark(batch.Items)
+	if invalid == nil {
+		return batch
+	}
+
+	var deadLetters []message.Redis
+	for i, drop := range invalid {
+		if drop {
+			deadLetters = append(deadLetters, batch.Items[i])
+		}
+	}
+	batch.Compact(invalid)
+
+	hp.events.droppedMessages(deadLetterReasonInvalidObject, deadLetters)
+	if err := hp.objects.dlq.DeadLetter(ctx, deadLetters, deadLetterReasonInvalidObject); err != nil {
+		hp.log.Errorf(ctx, "Failed to dead-letter %d messages with an invalid object: %v", len(deadLetters), err)
+		metrics.InvalidObjectsDeadLetterErrors.Add(int64(len(deadLetters)))
+	} else {
+		metrics.InvalidObjectsDeadLettered.Add(int64(len(deadLetters)))
+	}
+	if len(batch.Items) == 0 {
+		batch.Release()
+	}
+	return batch
+}
+
+// appendWrapped writes the object of a lenient entry as a string, with the
+// reason it was not embedded.
+func appendWrapped(builder *jsonfast.Builder, msg *message.Redis, stats *projectionStats) {
+	builder.AddStringFieldKey(fkObjectRaw, msg.Object)
+	builder.AddStringFieldKey(fkParseError, parseErrorInvalidObject)
+	stats.objWrapped++
+}
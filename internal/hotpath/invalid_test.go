@@ -0,0 +1,107 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func objectChecksHotPath(
+	t *testing.T, redisClient *deadLetterRedis, mode string, overrides ...config.StreamOverride,
+) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.InvalidObject = mode
+	cfg.Redis.StreamOverrides = overrides
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func TestBuildPayload_InvalidObjectLenient(t *testing.T) {
+	hp := objectChecksHotPath(t, &deadLetterRedis{}, config.InvalidObjectLenient,
+		config.StreamOverride{Pattern: "legacy", InvalidObject: config.InvalidObjectPass, QoS: -1})
+
+	for _, tt := range []struct {
+		name, stream, object, wantJSON string
+		wantWrapped                    int64
+	}{
+		{name: "valid", stream: "app", object: `{"a":1}`, wantJSON: `{"a":1,"raw":"r"}`},
+		{name: "truncated", stream: "app", object: `{"a":1,"b":`, wantWrapped: 1,
+			wantJSON: `{"object_raw":"{\"a\":1,\"b\":","parse_error":"object is not a valid JSON object","raw":"r"}`},
+		{name: "array", stream: "app", object: `[1]`, wantWrapped: 1,
+			wantJSON: `{"object_raw":"[1]","parse_error":"object is not a valid JSON object","raw":"r"}`},
+		{name: "pass stream", stream: "legacy", object: `{"a":1}x`, wantJSON: `{"a":1,"raw":"r"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats projectionStats
+			msg := message.Redis{ID: testMsgID1, Stream: tt.stream, Object: tt.object, Raw: "r"}
+			_, _, gotJSON := parseLine(t, hp.buildProjectedPayload(jsonfast.New(512), &msg, &stats))
+			if !jsonEqual([]byte(gotJSON), []byte(tt.wantJSON)) {
+				t.Errorf("JSON mismatch:\n  got:  %s\n  want: %s", gotJSON, tt.wantJSON)
+			}
+			if stats.objWrapped != tt.wantWrapped {
+				t.Errorf("objWrapped = %d; want %d", stats.objWrapped, tt.wantWrapped)
+			}
+		})
+	}
+}
+
+func TestDropInvalid_DeadLettersStrictStreams(t *testing.T) {
+	var deadLettered []message.Redis
+	redisClient := &deadLetterRedis{
+		deadLetterFn: func(_ context.Context, msgs []message.Redis, reason string) error {
+			if reason != deadLetterReasonInvalidObject {
+				t.Errorf("reason = %q; want %q", reason, deadLetterReasonInvalidObject)
+			}
+			deadLettered = append(deadLettered, msgs...)
+			return nil
+		},
+	}
+	hp := objectChecksHotPath(t, redisClient, config.InvalidObjectPass,
+		config.StreamOverride{Pattern: "audit-*", InvalidObject: config.InvalidObjectStrict, QoS: -1})
+	before := metrics.InvalidObjectsDeadLettered.Value()
+
+	batch := hp.dropInvalid(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "audit-web", Object: `{"user":`, Raw: "broken"},
+		{ID: "2-0", Stream: "audit-web", Object: `{"user":"a"}`, Raw: "valid"},
+		{ID: "3-0", Stream: "audit-web", Raw: "no object"},
+		{ID: "4-0", Stream: "app", Object: `{"user":`, Raw: "not strict"},
+	}})
+
+	var kept []string
+	for i := range batch.Items {
+		kept = append(kept, batch.Items[i].Raw)
+	}
+	if !slices.Equal(kept, []string{"valid", "no object", "not strict"}) {
+		t.Errorf("kept = %q; want all but the broken audit entry", kept)
+	}
+	if len(deadLettered) != 1 || deadLettered[0].ID != "1-0" || deadLettered[0].Object != `{"user":` {
+		t.Errorf("dead-lettered = %+v; want the broken audit entry with its body", deadLettered)
+	}
+	if got := metrics.InvalidObjectsDeadLettered.Value() - before; got != 1 {
+		t.Errorf("InvalidObjectsDeadLettered delta = %d; want 1", got)
+	}
+}
+
+func TestNewObjectChecks_OffByDefault(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.InvalidObject = config.InvalidObjectPass
+	if c := newObjectChecks(&mockRedis{}, cfg); c != nil {
+		t.Errorf("newObjectChecks(pass) = %+v; want nil", c)
+	}
+	cfg.Pipeline.InvalidObject = config.InvalidObjectStrict
+	if err := validateObjectChecks(&mockRedis{}, cfg); err == nil {
+		t.Error("validateObjectChecks(strict) error = nil; want the missing dead-lettering")
+	}
+}
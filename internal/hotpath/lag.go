@@ -0,0 +1,120 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// groupLagReader is implemented by *redis.Client; REDIS_LAG_INTERVAL
+// requires it.
+type groupLagReader interface {
+	GroupLags(ctx context.Context) ([]redis.GroupLag, error)
+}
+
+// lagMonitor reads the consumer group's lag every REDIS_LAG_INTERVAL into
+// the consumer.group_lag and consumer.group_pending metrics, which an
+// autoscaler can scale the consumers on. From REDIS_LAG_HIGH undelivered
+// entries until the lag is back under half of it, the fetch loop reads at
+// the largest batch size, as under memory pressure. A nil lagMonitor never
+// drains.
+type lagMonitor struct {
+	reader   groupLagReader
+	drain    *readDrain // nil under REDIS_ADAPTIVE_BATCH, which drains in the fetch loop
+	interval time.Duration
+	high     int64 // 0 never drains
+	active   atomic.Bool
+}
+
+func validateLag(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if cfg.LagInterval == 0 {
+		return nil
+	}
+	if _, ok := redisClient.(groupLagReader); !ok {
+		return errors.New("hotpath: the lag monitor needs a redis client that can read consumer group lag")
+	}
+	return nil
+}
+
+func newLagMonitor(redisClient redis.StreamClient, cfg *config.RedisConfig, drain *readDrain) *lagMonitor {
+	if cfg.LagInterval == 0 {
+		return nil
+	}
+	reader, _ := redisClient.(groupLagReader)
+	return &lagMonitor{
+		reader:   reader,
+		drain:    drain,
+		interval: cfg.LagInterval,
+		high:     int64(cfg.LagHigh),
+	}
+}
+
+// draining reports whether the lag is high.
+func (m *lagMonitor) draining() bool {
+	return m != nil && m.active.Load()
+}
+
+// lagLoop checks the lag every REDIS_LAG_INTERVAL, starting right away.
+func (hp *HotPath) lagLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.lag.interval)
+	defer ticker.Stop()
+	for {
+		hp.lag.check(ctx, hp.log)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reads the lag of every stream, records the totals and starts or
+// stops draining across the thresholds. A failed read keeps the current
+// state.
+func (m *lagMonitor) check(ctx context.Context, logger *log.Logger) {
+	lags, err := m.reader.GroupLags(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf(ctx, "Failed to read the consumer group lag: %v", err)
+		}
+		return
+	}
+	var lag, pending, estimated int64
+	for i := range lags {
+		lag += lags[i].Lag
+		pending += lags[i].Pending
+		if !lags[i].Exact {
+			estimated = 1
+		}
+	}
+	metrics.GroupLag.Set(lag)
+	metrics.GroupPending.Set(pending)
+	metrics.GroupLagEstimated.Set(estimated)
+	if m.high == 0 {
+		return
+	}
+	switch active := m.active.Load(); {
+	case !active && lag >= m.high:
+		logger.Warnf(ctx, "Consumer group lag at %d entries; draining at the largest batch size", lag)
+		m.set(true)
+	case active && lag < m.high/2:
+		logger.Infof(ctx, "Consumer group lag back to %d entries; draining stopped", lag)
+		m.set(false)
+	}
+}
+
+func (m *lagMonitor) set(active bool) {
+	m.active.Store(active)
+	var flag int64
+	if active {
+		flag = 1
+	}
+	metrics.LagDraining.Set(flag)
+	m.drain.set(drainLag, active)
+}
@@ -0,0 +1,133 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// lagRedis is a memoryRedis that reports lags as the consumer group's lag.
+type lagRedis struct {
+	lags []redis.GroupLag
+	memoryRedis
+}
+
+func (m *lagRedis) GroupLags(context.Context) ([]redis.GroupLag, error) {
+	return m.lags, nil
+}
+
+func (m *lagRedis) setLag(lags ...int64) {
+	m.lags = m.lags[:0]
+	for _, lag := range lags {
+		m.lags = append(m.lags, redis.GroupLag{Lag: lag, Pending: 1, Exact: lag%2 == 0})
+	}
+}
+
+func lagConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Redis.LagInterval = time.Second
+	cfg.Redis.LagHigh = 1000
+	cfg.Redis.BatchSize = 100
+	cfg.Redis.BatchSizeMax = 1000
+	return cfg
+}
+
+func TestLagMonitor_MetricsAndHysteresis(t *testing.T) {
+	rdb := &lagRedis{}
+	hp, err := New(rdb, &mockPublisher{}, lagConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	rdb.setLag(600, 401)
+	hp.lag.check(t.Context(), hp.log)
+	if metrics.GroupLag.Value() != 1001 || metrics.GroupPending.Value() != 2 || metrics.GroupLagEstimated.Value() != 1 {
+		t.Errorf("lag = %d, pending = %d, estimated = %d; want 1001, 2 and 1", metrics.GroupLag.Value(),
+			metrics.GroupPending.Value(), metrics.GroupLagEstimated.Value())
+	}
+
+	var states []bool
+	for _, lag := range []int64{1000, 600, 498, 800} {
+		rdb.setLag(lag)
+		hp.lag.check(t.Context(), hp.log)
+		states = append(states, hp.lag.draining())
+	}
+	if want := []bool{true, true, false, false}; !slices.Equal(states, want) {
+		t.Errorf("draining = %v; want %v across the thresholds", states, want)
+	}
+	if want := []int{1000, 100}; !slices.Equal(rdb.sizes, want) {
+		t.Errorf("read sizes = %v; want %v", rdb.sizes, want)
+	}
+	if metrics.LagDraining.Value() != 0 || metrics.GroupLagEstimated.Value() != 0 {
+		t.Errorf("LagDraining = %d, GroupLagEstimated = %d; want 0 once drained with an exact lag",
+			metrics.LagDraining.Value(), metrics.GroupLagEstimated.Value())
+	}
+}
+
+func TestLagMonitor_SharesDrainWithMemoryPressure(t *testing.T) {
+	cfg := lagConfig()
+	cfg.Redis.MemoryHighPercent = 90
+	cfg.Redis.MemoryLowPercent = 80
+	cfg.Redis.MemoryCheckInterval = time.Second
+	rdb := &lagRedis{memoryRedis: memoryRedis{limit: 100}}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	rdb.used = 95
+	hp.pressure.check(t.Context(), hp.log)
+	rdb.setLag(2000)
+	hp.lag.check(t.Context(), hp.log)
+	rdb.used = 50
+	hp.pressure.check(t.Context(), hp.log)
+	if want := []int{1000}; !slices.Equal(rdb.sizes, want) {
+		t.Errorf("read sizes = %v; want %v while the lag still drains", rdb.sizes, want)
+	}
+	rdb.setLag(0)
+	hp.lag.check(t.Context(), hp.log)
+	if want := []int{1000, 100}; !slices.Equal(rdb.sizes, want) {
+		t.Errorf("read sizes = %v; want %v once both cleared", rdb.sizes, want)
+	}
+}
+
+func TestLagMonitor_ReportOnly(t *testing.T) {
+	cfg := lagConfig()
+	cfg.Redis.LagHigh = 0
+	rdb := &lagRedis{}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	rdb.setLag(1 << 20)
+	hp.lag.check(t.Context(), hp.log)
+	if hp.lag.draining() || len(rdb.sizes) != 0 || metrics.GroupLag.Value() != 1<<20 {
+		t.Errorf("draining = %v, sizes = %v, lag = %d; want the lag reported only",
+			hp.lag.draining(), rdb.sizes, metrics.GroupLag.Value())
+	}
+}
+
+func TestNew_LagRequirements(t *testing.T) {
+	if _, err := New(&mockRedis{}, &mockPublisher{}, lagConfig(), log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without GroupLags")
+	}
+
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.lag != nil || hp.lag.draining() {
+		t.Error("lag != nil; want no lag monitor without REDIS_LAG_INTERVAL")
+	}
+}
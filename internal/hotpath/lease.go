@@ -0,0 +1,89 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// leaseHolder is implemented by *redis.Client; REDIS_STREAM_LEASE
+// requires it.
+type leaseHolder interface {
+	RenewLeases(ctx context.Context) (taken, lost []string, err error)
+	ReleaseLeases(ctx context.Context) error
+}
+
+// leaseRenewals is how many times per REDIS_STREAM_LEASE_TTL the leases
+// are renewed, so that two renewals in a row can fail before one expires.
+const leaseRenewals = 3
+
+// streamLeases applies REDIS_STREAM_LEASE: the instances sharing the
+// consumer group contend for one lease per stream, and the Redis client
+// reads and claims only the streams whose lease this one holds. The others
+// stand by, connected and renewing, and take a stream over as soon as its
+// lease is free. A nil streamLeases reads every stream.
+type streamLeases struct {
+	holder leaseHolder
+	renew  time.Duration
+}
+
+func validateLeases(redisClient redis.StreamClient, cfg *config.RedisConfig) error {
+	if !cfg.StreamLease {
+		return nil
+	}
+	if _, ok := redisClient.(leaseHolder); !ok {
+		return errors.New("hotpath: stream leases need a redis client that can hold leases")
+	}
+	return nil
+}
+
+func newStreamLeases(redisClient redis.StreamClient, cfg *config.RedisConfig) *streamLeases {
+	if !cfg.StreamLease {
+		return nil
+	}
+	holder, _ := redisClient.(leaseHolder)
+	return &streamLeases{holder: holder, renew: cfg.StreamLeaseTTL / leaseRenewals}
+}
+
+// leaseLoop renews the stream leases, starting right away so that an
+// instance with no contender starts reading without waiting a period.
+func (hp *HotPath) leaseLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.leases.renew)
+	defer ticker.Stop()
+	for {
+		hp.renewLeases(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (hp *HotPath) renewLeases(ctx context.Context) {
+	taken, lost, err := hp.leases.holder.RenewLeases(ctx)
+	if len(taken) > 0 {
+		hp.log.Infof(ctx, "Took the lease of %d streams, now reading them: %v", len(taken), taken)
+	}
+	if len(lost) > 0 {
+		hp.log.Warnf(ctx, "Lost the lease of %d streams, standing by for them: %v", len(lost), lost)
+	}
+	if err != nil && ctx.Err() == nil {
+		hp.log.Errorf(ctx, "Failed to renew the stream leases: %v", err)
+		hp.events.failed("lease", 0, err)
+	}
+}
+
+// releaseLeases hands the streams over to a standby instance once the
+// producers are stopped, rather than when their leases expire.
+func (hp *HotPath) releaseLeases(ctx context.Context) {
+	if hp.leases == nil {
+		return
+	}
+	if err := hp.leases.holder.ReleaseLeases(ctx); err != nil {
+		hp.log.Warnf(ctx, "Failed to release the stream leases, a standby takes over when they expire: %v", err)
+	}
+}
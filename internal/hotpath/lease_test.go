@@ -0,0 +1,83 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+// leaseRedis is a mockRedis that holds stream leases.
+type leaseRedis struct {
+	mockRedis
+	renewErr error
+	renewals atomic.Int32
+	releases atomic.Int32
+}
+
+func (m *leaseRedis) RenewLeases(context.Context) (taken, lost []string, err error) {
+	if m.renewals.Add(1) == 1 {
+		taken = []string{testStreamSimp}
+	}
+	return taken, nil, m.renewErr
+}
+
+func (m *leaseRedis) ReleaseLeases(context.Context) error {
+	m.releases.Add(1)
+	return nil
+}
+
+func TestRun_RenewsAndReleasesLeases(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.StreamLease = true
+	cfg.Redis.StreamLeaseTTL = 150 * time.Millisecond
+	rdb := &leaseRedis{}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	runFor(t, hp, 300*time.Millisecond)
+
+	if got := rdb.renewals.Load(); got < 3 {
+		t.Errorf("renewals = %d; want one right away and then every 50ms", got)
+	}
+	if got := rdb.releases.Load(); got != 1 {
+		t.Errorf("releases = %d; want 1 on shutdown", got)
+	}
+}
+
+func TestRenewLeases_ReportsFailure(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.StreamLease = true
+	cfg.Redis.StreamLeaseTTL = 3 * time.Second
+	rdb := &leaseRedis{renewErr: errors.New("connection refused")}
+	observer := &recordingObserver{}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New(), observer)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	hp.events.start()
+	hp.renewLeases(t.Context())
+	hp.events.close(t.Context(), time.Second)
+	if len(observer.events) != 1 || observer.calls[0] != "error" || observer.events[0].Stage != "lease" {
+		t.Errorf("events = %v %+v; want one lease error", observer.calls, observer.events)
+	}
+}
+
+func TestNew_LeasesNeedALeaseHolder(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.StreamLease = true
+	cfg.Redis.StreamLeaseTTL = 3 * time.Second
+	_, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err == nil || !strings.Contains(err.Error(), "stream leases") {
+		t.Errorf("New() error = %v; want one about stream leases", err)
+	}
+}
@@ -0,0 +1,46 @@
+package hotpath
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// liveness lets a supervisor watch the fetch loop: ready is closed after
+// the first read from Redis that succeeded, and beat holds the time of the
+// loop's latest round, in unix ns.
+type liveness struct {
+	ready     chan struct{}
+	beat      atomic.Int64
+	readyOnce sync.Once
+}
+
+func newLiveness() *liveness {
+	return &liveness{ready: make(chan struct{})}
+}
+
+// tick records a round of the fetch loop.
+func (l *liveness) tick() {
+	l.beat.Store(time.Now().UnixNano())
+}
+
+// read records a read from Redis that succeeded.
+func (l *liveness) read() {
+	l.readyOnce.Do(func() { close(l.ready) })
+	l.tick()
+}
+
+// Ready returns a channel closed once the fetch loop, or the backfill
+// before it, has read from Redis successfully. The MQTT pool is connected
+// by then, since New takes a connected publisher.
+func (hp *HotPath) Ready() <-chan struct{} {
+	return hp.live.ready
+}
+
+// Alive reports whether the fetch loop has gone round within d. A loop
+// waiting on a full publish queue, which a stalled broker leaves full, does
+// not go round.
+func (hp *HotPath) Alive(d time.Duration) bool {
+	beat := hp.live.beat.Load()
+	return beat != 0 && time.Since(time.Unix(0, beat)) < d
+}
@@ -0,0 +1,181 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+const deadLetterReasonMaxAge = "max_age"
+
+// maxAgePolicies applies PIPELINE_MAX_AGE_POLICIES, and the max_age of
+// the REDIS_STREAM_OVERRIDES_FILE overrides over them. An entry's age is
+// taken from the millisecond time in its ID, set by the Redis server when
+// the entry was added, so it covers time spent pending and being claimed.
+// A nil maxAgePolicies lets every entry through.
+type maxAgePolicies struct {
+	dlq       deadLetterer
+	policies  []config.MaxAgePolicy
+	overrides []config.StreamOverride
+}
+
+func validateMaxAge(redisClient redis.StreamClient, cfg *config.Config) error {
+	actions := make([]string, 0, len(cfg.Pipeline.MaxAgePolicies)+len(cfg.Redis.StreamOverrides))
+	for _, item := range cfg.Pipeline.MaxAgePolicies {
+		policy, err := config.ParseMaxAgePolicy(item)
+		if err != nil {
+			return err
+		}
+		actions = append(actions, policy.Action)
+	}
+	for i := range cfg.Redis.StreamOverrides {
+		actions = append(actions, cfg.Redis.StreamOverrides[i].MaxAgeAction)
+	}
+	if !slices.Contains(actions, config.MaxAgeDLQ) {
+		return nil
+	}
+	if _, ok := redisClient.(deadLetterer); !ok {
+		return errors.New("hotpath: max age policy dlq needs a redis client that supports dead-lettering")
+	}
+	return nil
+}
+
+func newMaxAgePolicies(redisClient redis.StreamClient, cfg *config.Config) *maxAgePolicies {
+	overrides := slices.ContainsFunc(cfg.Redis.StreamOverrides, func(o config.StreamOverride) bool {
+		return o.MaxAge > 0
+	})
+	if len(cfg.Pipeline.MaxAgePolicies) == 0 && !overrides {
+		return nil
+	}
+	dlq, _ := redisClient.(deadLetterer)
+	m := &maxAgePolicies{dlq: dlq, policies: make([]config.MaxAgePolicy, 0, len(cfg.Pipeline.MaxAgePolicies))}
+	for _, item := range cfg.Pipeline.MaxAgePolicies {
+		policy, _ := config.ParseMaxAgePolicy(item) // checked by validateMaxAge
+		m.policies = append(m.policies, policy)
+	}
+	if overrides {
+		m.overrides = cfg.Redis.StreamOverrides
+	}
+	return m
+}
+
+// policy returns the max age of stream's override, if it sets one, or the
+// first policy whose pattern matches stream.
+func (m *maxAgePolicies) policy(stream string) (config.MaxAgePolicy, bool) {
+	if o, ok := config.MatchStreamOverride(m.overrides, stream); ok && o.MaxAge > 0 {
+		action := o.MaxAgeAction
+		if action == "" {
+			action = config.MaxAgeDrop
+		}
+		return config.MaxAgePolicy{Pattern: o.Pattern, Action: action, MaxAge: o.MaxAge}, true
+	}
+	for _, p := range m.policies {
+		if ok, _ := path.Match(p.Pattern, stream); ok {
+			return p, true
+		}
+	}
+	return config.MaxAgePolicy{}, false
+}
+
+// mark returns the action for each of items that is past its stream's max
+// age at now, "" for the others, or nil when none is. Entries of a batch
+// mostly share a stream, so the policy is looked up once per run of them.
+func (m *maxAgePolicies) mark(items []message.Redis, now time.Time) []string {
+	var actions []string
+	var stream string
+	var policy config.MaxAgePolicy
+	var limited bool
+	for i := range items {
+		if i == 0 || items[i].Stream != stream {
+			stream = items[i].Stream
+			policy, limited = m.policy(stream)
+		}
+		if !limited {
+			continue
+		}
+		added, ok := entryTime(items[i].ID)
+		if !ok || now.Sub(added) <= policy.MaxAge {
+			continue
+		}
+		if actions == nil {
+			actions = make([]string, len(items))
+		}
+		actions[i] = policy.Action
+	}
+	return actions
+}
+
+// entryTime returns the time in a stream entry ID, "<ms>-<seq>".
+func entryTime(id string) (time.Time, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(n), true
+}
+
+// dropAged removes the entries of batch that are past their stream's max
+// age at now, then acknowledges and deletes or dead-letters them as their
+// policy says. When that fails they are left pending, and the claim loop
+// brings them back here. The returned batch is released when nothing is
+// left of it.
+func (hp *HotPath) dropAged(ctx context.Context, batch message.Batch, now time.Time) message.Batch {
+	if hp.maxAge == nil {
+		return batch
+	}
+	actions := hp.maxAge.mark(batch.Items, now)
+	if actions == nil {
+		return batch
+	}
+
+	if slices.Contains(actions, config.MaxAgeDLQ) {
+		batch.Decode() // dead letters carry the body
+	}
+	drop := make([]bool, len(actions))
+	idsByStream := make(map[string][]string)
+	var deadLetters []message.Redis
+	for i, action := range actions {
+		switch action {
+		case config.MaxAgeDrop:
+			idsByStream[batch.Items[i].Stream] = append(idsByStream[batch.Items[i].Stream], batch.Items[i].ID)
+		case config.MaxAgeDLQ:
+			deadLetters = append(deadLetters, batch.Items[i])
+		default:
+			continue
+		}
+		drop[i] = true
+	}
+	batch.Compact(drop)
+
+	for stream, ids := range idsByStream {
+		hp.events.dropped(deadLetterReasonMaxAge, stream, ids)
+		hp.ageOut(ctx, len(ids), hp.redis.AckAndDeleteBatch(ctx, ids, stream))
+	}
+	if len(deadLetters) > 0 {
+		hp.events.droppedMessages(deadLetterReasonMaxAge, deadLetters)
+		hp.ageOut(ctx, len(deadLetters), hp.maxAge.dlq.DeadLetter(ctx, deadLetters, deadLetterReasonMaxAge))
+	}
+	if len(batch.Items) == 0 {
+		batch.Release()
+	}
+	return batch
+}
+
+func (hp *HotPath) ageOut(ctx context.Context, n int, err error) {
+	if err != nil {
+		hp.log.Warnf(ctx, "Failed to remove %d messages past their max age: %v", n, err)
+		metrics.AgedOutErrors.Add(int64(n))
+		return
+	}
+	metrics.MessagesAgedOut.Add(int64(n))
+}
@@ -0,0 +1,124 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func maxAgeHotPath(t *testing.T, redisClient *deadLetterRedis, policies ...string) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.MaxAgePolicies = policies
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+// entryID returns a stream entry ID added age before now.
+func entryID(now time.Time, age time.Duration) string {
+	return strconv.FormatInt(now.Add(-age).UnixMilli(), 10) + "-0"
+}
+
+func TestDropAged_DropsAndDeadLettersByStream(t *testing.T) {
+	now := time.Now()
+	acked := map[string][]string{}
+	var deadLettered []message.Redis
+	redisClient := &deadLetterRedis{
+		deadLetterFn: func(_ context.Context, msgs []message.Redis, reason string) error {
+			if reason != deadLetterReasonMaxAge {
+				t.Errorf("reason = %q; want %q", reason, deadLetterReasonMaxAge)
+			}
+			deadLettered = append(deadLettered, msgs...)
+			return nil
+		},
+	}
+	redisClient.ackAndDeleteFn = func(_ context.Context, ids []string, stream string) error {
+		acked[stream] = append(acked[stream], ids...)
+		return nil
+	}
+	hp := maxAgeHotPath(t, redisClient, "debug-*=drop:15m", "audit=dlq:1h")
+	before := metrics.MessagesAgedOut.Value()
+
+	oldDebug, oldAudit := entryID(now, 20*time.Minute), entryID(now, 2*time.Hour)
+	batch := hp.dropAged(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: oldDebug, Stream: "debug-web", Raw: "stale"},
+		{ID: entryID(now, time.Minute), Stream: "debug-web", Raw: "fresh"},
+		{ID: entryID(now, 20*time.Minute), Stream: "audit", Raw: "recent enough"},
+		{ID: oldAudit, Stream: "audit", Raw: "expired"},
+		{ID: entryID(now, 48*time.Hour), Stream: "app", Raw: "no policy"},
+	}}, now)
+
+	var kept []string
+	for i := range batch.Items {
+		kept = append(kept, batch.Items[i].Raw)
+	}
+	if !slices.Equal(kept, []string{"fresh", "recent enough", "no policy"}) {
+		t.Errorf("kept = %q; want the entries within their max age", kept)
+	}
+	if !slices.Equal(acked["debug-web"], []string{oldDebug}) || len(acked) != 1 {
+		t.Errorf("acked = %v; want the stale debug entry only", acked)
+	}
+	if len(deadLettered) != 1 || deadLettered[0].ID != oldAudit || deadLettered[0].Raw != "expired" {
+		t.Errorf("dead-lettered = %+v; want the expired audit entry with its body", deadLettered)
+	}
+	if got := metrics.MessagesAgedOut.Value() - before; got != 2 {
+		t.Errorf("MessagesAgedOut delta = %d; want 2", got)
+	}
+}
+
+func TestDropAged_CountsFailures(t *testing.T) {
+	now := time.Now()
+	redisClient := &deadLetterRedis{}
+	redisClient.ackAndDeleteFn = func(context.Context, []string, string) error {
+		return errors.New("connection reset")
+	}
+	hp := maxAgeHotPath(t, redisClient, "*=drop:1m")
+	agedBefore, errorsBefore := metrics.MessagesAgedOut.Value(), metrics.AgedOutErrors.Value()
+
+	batch := hp.dropAged(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: entryID(now, time.Hour), Stream: "a"},
+	}}, now)
+
+	if len(batch.Items) != 0 {
+		t.Errorf("kept %d entries; want the aged one left pending, not published", len(batch.Items))
+	}
+	if got := metrics.AgedOutErrors.Value() - errorsBefore; got != 1 {
+		t.Errorf("AgedOutErrors delta = %d; want 1", got)
+	}
+	if got := metrics.MessagesAgedOut.Value() - agedBefore; got != 0 {
+		t.Errorf("MessagesAgedOut delta = %d; want 0", got)
+	}
+}
+
+func TestDropAged_NoPolicies(t *testing.T) {
+	hp := maxAgeHotPath(t, &deadLetterRedis{})
+	items := []message.Redis{{ID: "1-0", Stream: "a"}}
+	if batch := hp.dropAged(t.Context(), message.Batch{Items: items}, time.Now()); len(batch.Items) != 1 {
+		t.Errorf("kept %d entries; want 1 without policies", len(batch.Items))
+	}
+}
+
+func TestNew_MaxAgeDLQRequiresDeadLetterer(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.MaxAgePolicies = []string{"*=dlq:1m"}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without DeadLetter")
+	}
+	cfg.Pipeline.MaxAgePolicies = []string{"*=drop:1m"}
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v; want drop to need no dead-lettering", err)
+	}
+	closeHotPath(t, hp)
+}
@@ -0,0 +1,79 @@
+package hotpath
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/spill"
+)
+
+// memoryBudget keeps the entry bodies held by the publish queues within
+// PIPELINE_MEMORY_BUDGET by spilling the large ones to an mmap arena. A
+// nil memoryBudget keeps every body in memory.
+type memoryBudget struct {
+	arena    *spill.Arena
+	held     atomic.Int64 // body bytes admitted and not yet released
+	budget   int
+	minBytes int
+}
+
+// newMemoryBudget opens the spill arena; call it only when
+// PIPELINE_MEMORY_BUDGET is set.
+func newMemoryBudget(cfg *config.PipelineConfig) (*memoryBudget, error) {
+	arena, err := spill.Open(cfg.SpillDir, cfg.SpillArenaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("hotpath: %w", err)
+	}
+	return &memoryBudget{arena: arena, budget: cfg.MemoryBudget, minBytes: cfg.SpillMinBytes}, nil
+}
+
+// admit accounts for the bodies of a batch about to be queued, spilling
+// what goes over the budget. It extracts every item, so lazy batches lose
+// their laziness under a budget. The fetch and claim loops admit
+// concurrently, so the budget can be overshot by one batch; a full arena
+// leaves the rest in memory.
+func (m *memoryBudget) admit(batch *message.Batch) {
+	if m == nil {
+		return
+	}
+	batch.Decode()
+	size := batch.BodyBytes()
+	if over := int(m.held.Load()) + size - m.budget; over > 0 {
+		moved, ok := batch.Spill(m.arena, m.minBytes, over)
+		size -= moved
+		metrics.BodyBytesSpilled.Add(int64(moved))
+		metrics.SpillArenaBytes.Set(int64(m.arena.Used()))
+		if !ok {
+			metrics.SpillArenaFull.Add(1)
+		}
+	}
+	metrics.BufferedBodyBytes.Set(m.held.Add(int64(size)))
+}
+
+// checkout returns what a dequeued batch was admitted with, for release
+// once the batch is published and released. It must run before the batch
+// is iterated, which reads the spilled bodies back.
+func (m *memoryBudget) checkout(batch *message.Batch) int {
+	if m == nil {
+		return 0
+	}
+	return batch.BodyBytes()
+}
+
+func (m *memoryBudget) release(held int) {
+	if m == nil {
+		return
+	}
+	metrics.BufferedBodyBytes.Set(m.held.Add(-int64(held)))
+	metrics.SpillArenaBytes.Set(int64(m.arena.Used()))
+}
+
+func (m *memoryBudget) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.arena.Close()
+}
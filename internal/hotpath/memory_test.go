@@ -0,0 +1,108 @@
+package hotpath
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func budgetConfig(t *testing.T, budget int) *config.Config {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.MemoryBudget = budget
+	cfg.Pipeline.SpillMinBytes = 4
+	cfg.Pipeline.SpillArenaBytes = 1 << 20
+	cfg.Pipeline.SpillDir = t.TempDir()
+	return cfg
+}
+
+func TestMemoryBudget_AdmitSpillsOverBudget(t *testing.T) {
+	cfg := budgetConfig(t, 10)
+	m, err := newMemoryBudget(&cfg.Pipeline)
+	if err != nil {
+		t.Fatalf("newMemoryBudget() error = %v", err)
+	}
+	defer func() { _ = m.close() }()
+	spilledBefore := metrics.BodyBytesSpilled.Value()
+
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Raw: "aaaaaaaa"},
+		{ID: "2-0", Raw: "bb"},
+		{ID: "3-0", Raw: "cccccccc"},
+		{ID: "4-0", Raw: "dddddddd"},
+	}}
+	m.admit(&batch)
+
+	// 26 bytes against a budget of 10: the first two bodies of at least 4
+	// bytes leave memory, "bb" is too small to bother.
+	if batch.Items[0].Raw != "" || batch.Items[1].Raw != "bb" || batch.Items[2].Raw != "" ||
+		batch.Items[3].Raw != "dddddddd" {
+		t.Errorf("items = %+v; want 1-0 and 3-0 spilled", batch.Items)
+	}
+	if got := m.held.Load(); got != 10 {
+		t.Errorf("held = %d; want 10", got)
+	}
+	if got := metrics.BodyBytesSpilled.Value() - spilledBefore; got != 16 {
+		t.Errorf("BodyBytesSpilled delta = %d; want 16", got)
+	}
+
+	held := m.checkout(&batch)
+	batch.Decode()
+	if batch.Items[0].Raw != "aaaaaaaa" || batch.Items[2].Raw != "cccccccc" {
+		t.Errorf("items after Decode = %+v; want the spilled bodies back", batch.Items)
+	}
+	batch.Release()
+	m.release(held)
+	if got := m.held.Load(); got != 0 || m.arena.Used() != 0 {
+		t.Errorf("held = %d, arena used = %d after release; want 0, 0", got, m.arena.Used())
+	}
+}
+
+func TestPublishLoop_PublishesSpilledBodies(t *testing.T) {
+	var lines []string
+	pub := &mockPublisher{publishFn: func(_ context.Context, payload message.Payload) error {
+		lines = append(lines, decompressLines(payload)...)
+		return nil
+	}}
+	hp, err := New(&mockRedis{}, pub, budgetConfig(t, 1), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	err = hp.enqueueBatch(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Raw: "first spilled line"},
+		{ID: "2-0", Stream: testStreamSimp, Raw: "second spilled line"},
+	}})
+	if err != nil {
+		t.Fatalf("enqueueBatch() error = %v", err)
+	}
+	if hp.memory.arena.Used() == 0 {
+		t.Fatal("arena is empty; want the queued bodies spilled")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel() // drain the queue and exit
+	checkLoopExit(t, hp.makePublishLoop(t.Context(), 0)(ctx))
+
+	if len(lines) != 2 || !strings.Contains(lines[0], "first spilled line") ||
+		!strings.Contains(lines[1], "second spilled line") {
+		t.Errorf("published = %q; want both bodies read back", lines)
+	}
+	if hp.memory.held.Load() != 0 || hp.memory.arena.Used() != 0 {
+		t.Errorf("held = %d, arena used = %d; want everything released", hp.memory.held.Load(), hp.memory.arena.Used())
+	}
+}
+
+func TestNew_SpillArenaError(t *testing.T) {
+	cfg := budgetConfig(t, 1)
+	cfg.Pipeline.SpillDir = "/does/not/exist"
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want the arena open error")
+	}
+}
@@ -61,9 +61,10 @@ func (m *mockRedis) Close() error {
 
 // mockPublisher implements mqtt.Publisher for testing.
 type mockPublisher struct {
-	publishFn      func(ctx context.Context, payload message.Payload) error
-	subscribeAckFn func(ctx context.Context, handler func(message.AckMessage)) error
-	closeFn        func() error
+	publishFn        func(ctx context.Context, payload message.Payload) error
+	subscribeAckFn   func(ctx context.Context, handler func(message.AckMessage)) error
+	unsubscribeAckFn func(ctx context.Context) error
+	closeFn          func() error
 }
 
 func (m *mockPublisher) Publish(ctx context.Context, payload message.Payload) error {
@@ -80,9 +81,29 @@ func (m *mockPublisher) SubscribeAck(ctx context.Context, handler func(message.A
 	return nil
 }
 
+func (m *mockPublisher) UnsubscribeAck(ctx context.Context) error {
+	if m.unsubscribeAckFn != nil {
+		return m.unsubscribeAckFn(ctx)
+	}
+	return nil
+}
+
 func (m *mockPublisher) Close() error {
 	if m.closeFn != nil {
 		return m.closeFn()
 	}
 	return nil
 }
+
+// mockHintedPublisher additionally implements hintedPublisher.
+type mockHintedPublisher struct {
+	mockPublisher
+	publishFromFn func(ctx context.Context, payload message.Payload, hint uint64) error
+}
+
+func (m *mockHintedPublisher) PublishFrom(ctx context.Context, payload message.Payload, hint uint64) error {
+	if m.publishFromFn != nil {
+		return m.publishFromFn(ctx, payload, hint)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// nackDeadLetterer is implemented by *redis.Client; the dlq NACK policy
+// requires it.
+type nackDeadLetterer interface {
+	DeadLetterIDs(ctx context.Context, stream string, ids []string, reason string) error
+}
+
+const (
+	// nackReasonUnspecified stands for the reason of NACKs that give none,
+	// both in NacksByReason and in PIPELINE_NACK_POLICIES.
+	nackReasonUnspecified = "unspecified"
+	// nackReasonOther labels reasons without a policy once
+	// maxNackReasonLabels of them have been seen, so receivers cannot grow
+	// the metrics map without bound.
+	nackReasonOther     = "other"
+	maxNackReasonLabels = 32
+)
+
+// nackPolicies applies PIPELINE_NACK_POLICIES and the retry_after hints of
+// NACKs. Redis cannot delay a pending entry, so a backoff is a hold kept by
+// this instance: claimed entries still on hold are dropped from the claimed
+// batch, which leaves them pending for a later claim cycle. A hold therefore
+// lasts at least its delay, rounded up to REDIS_CLAIM_IDLE, and is lost
+// when another instance claims the entry, or on restart unless
+// PIPELINE_SNAPSHOT_PATH keeps it.
+type nackPolicies struct {
+	dlq      nackDeadLetterer
+	policies map[string]config.NackPolicy
+	holds    map[heldEntry]time.Time // entry -> end of its backoff
+	labels   map[string]struct{}     // reasons without a policy seen so far
+	mu       sync.Mutex
+}
+
+type heldEntry struct {
+	stream string
+	id     string
+}
+
+func validateNackPolicies(redisClient redis.StreamClient, cfg *config.PipelineConfig) error {
+	for _, item := range cfg.NackPolicies {
+		policy, err := config.ParseNackPolicy(item)
+		if err != nil {
+			return err
+		}
+		if policy.Action != config.NackDLQ {
+			continue
+		}
+		if _, ok := redisClient.(nackDeadLetterer); !ok {
+			return errors.New("hotpath: nack policy dlq needs a redis client that supports dead-lettering")
+		}
+	}
+	return nil
+}
+
+func newNackPolicies(redisClient redis.StreamClient, cfg *config.PipelineConfig) *nackPolicies {
+	dlq, _ := redisClient.(nackDeadLetterer)
+	n := &nackPolicies{
+		dlq:      dlq,
+		policies: make(map[string]config.NackPolicy, len(cfg.NackPolicies)),
+		holds:    make(map[heldEntry]time.Time),
+		labels:   make(map[string]struct{}),
+	}
+	for _, item := range cfg.NackPolicies {
+		policy, _ := config.ParseNackPolicy(item) // checked by validateNackPolicies
+		n.policies[policy.Reason] = policy
+	}
+	return n
+}
+
+// policy counts ack, a NACK, under its reason and returns what to do with
+// its entries. A retry_after hint turns a retry or backoff into a backoff
+// of that long; it does not keep a dlq reason out of the dead letter stream.
+func (n *nackPolicies) policy(ack *message.AckMessage) config.NackPolicy {
+	reason := ack.Reason
+	if reason == "" {
+		reason = nackReasonUnspecified
+	}
+	policy, ok := n.policies[reason]
+	if !ok {
+		policy = config.NackPolicy{Reason: reason, Action: config.NackRetry}
+	}
+	metrics.NacksByReason.Add(n.label(reason, ok), int64(len(ack.IDs)))
+
+	if ack.RetryAfter > 0 && policy.Action != config.NackDLQ {
+		policy.Action, policy.Delay = config.NackBackoff, ack.RetryAfter
+	}
+	return policy
+}
+
+func (n *nackPolicies) label(reason string, configured bool) string {
+	if configured {
+		return reason
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, seen := n.labels[reason]; seen {
+		return reason
+	}
+	if len(n.labels) >= maxNackReasonLabels {
+		return nackReasonOther
+	}
+	n.labels[reason] = struct{}{}
+	return reason
+}
+
+// hold keeps the claim loop from redelivering ids of stream until until.
+func (n *nackPolicies) hold(stream string, ids []string, until time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, id := range ids {
+		n.holds[heldEntry{stream: stream, id: id}] = until
+	}
+}
+
+// snapshot returns the holds still running at now, for saveSnapshot.
+func (n *nackPolicies) snapshot(now time.Time) []snapshotHold {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	holds := make([]snapshotHold, 0, len(n.holds))
+	for entry, until := range n.holds {
+		if now.Before(until) {
+			holds = append(holds, snapshotHold{Until: until, Stream: entry.stream, ID: entry.id})
+		}
+	}
+	return holds
+}
+
+// restore puts back the holds of a snapshot that have not run out at now
+// and returns how many it did.
+func (n *nackPolicies) restore(holds []snapshotHold, now time.Time) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	restored := 0
+	for _, h := range holds {
+		if now.Before(h.Until) {
+			n.holds[heldEntry{stream: h.Stream, id: h.ID}] = h.Until
+			restored++
+		}
+	}
+	return restored
+}
+
+// holdBack drops the entries of a claimed batch whose backoff has not run
+// out at now and forgets the holds that have. It returns the entries
+// dropped.
+func (n *nackPolicies) holdBack(batch *message.Batch, now time.Time) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.holds) == 0 {
+		return 0
+	}
+	maps.DeleteFunc(n.holds, func(_ heldEntry, until time.Time) bool { return !now.Before(until) })
+
+	var drop []bool
+	held := 0
+	for i := range batch.Items {
+		if _, ok := n.holds[heldEntry{stream: batch.Items[i].Stream, id: batch.Items[i].ID}]; !ok {
+			continue
+		}
+		if drop == nil {
+			drop = make([]bool, len(batch.Items))
+		}
+		drop[i] = true
+		held++
+	}
+	if held > 0 {
+		batch.Compact(drop)
+	}
+	return held
+}
+
+// addACK files ack under p and applies the policy of a NACK's reason:
+// dlq entries are dead-lettered when p is flushed, backoff entries are put
+// on hold at once. The publish reservations of the entries left to the
+// claim loop are released when p is flushed.
+func (hp *HotPath) addACK(p *pendingACK, ack *message.AckMessage) {
+	if ack.Ack {
+		p.ackIDs = append(p.ackIDs, ack.IDs...)
+		return
+	}
+	p.nackCount += len(ack.IDs)
+	switch policy := hp.nacks.policy(ack); policy.Action {
+	case config.NackDLQ:
+		if p.deadLetters == nil {
+			p.deadLetters = make(map[string][]string, 1)
+		}
+		p.deadLetters[policy.Reason] = append(p.deadLetters[policy.Reason], ack.IDs...)
+		return
+	case config.NackBackoff:
+		hp.nacks.hold(ack.Stream, ack.IDs, time.Now().Add(policy.Delay))
+	}
+	if hp.published != nil {
+		p.retryIDs = append(p.retryIDs, ack.IDs...)
+	}
+}
+
+// flushNACKs dead-letters the dlq entries of p and accounts for the rest,
+// which stay pending for the claim loop. Entries that fail to move stay
+// pending too.
+func (hp *HotPath) flushNACKs(parentCtx context.Context, stream string, p *pendingACK) {
+	retried := p.nackCount
+	for reason, ids := range p.deadLetters {
+		retried -= len(ids)
+		ctx, cancel := context.WithTimeout(parentCtx, hp.ackTimeout)
+		err := hp.nacks.dlq.DeadLetterIDs(ctx, stream, ids, reason)
+		cancel()
+		if err != nil {
+			hp.log.Errorf(parentCtx, "Failed to dead-letter %d NACKed messages from stream %s: %v",
+				len(ids), stream, err)
+			metrics.NackDeadLetterErrors.Add(1)
+			continue
+		}
+		metrics.NackDeadLettered.Add(int64(len(ids)))
+	}
+
+	hp.releasePublished(parentCtx, stream, p.retryIDs)
+	hp.recordNACKs(parentCtx, stream, p)
+	metrics.MessagesNacked.Add(int64(p.nackCount))
+	if retried > 0 && hp.log.InfoEnabled(parentCtx) {
+		hp.log.Infof(parentCtx, "%d messages from stream %s failed, will be reclaimed", retried, stream)
+	}
+}
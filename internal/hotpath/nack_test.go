@@ -0,0 +1,187 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// nackDeadLetterRedis is a mockRedis that also implements nackDeadLetterer.
+type nackDeadLetterRedis struct {
+	mockRedis
+	deadLetterIDsFn func(ctx context.Context, stream string, ids []string, reason string) error
+}
+
+func (m *nackDeadLetterRedis) DeadLetterIDs(ctx context.Context, stream string, ids []string, reason string) error {
+	return m.deadLetterIDsFn(ctx, stream, ids, reason)
+}
+
+func nackHotPath(t *testing.T, redisClient *nackDeadLetterRedis, policies ...string) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.NackPolicies = policies
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func TestAddACK_DeadLettersByReason(t *testing.T) {
+	type call struct {
+		stream, reason string
+		ids            []string
+	}
+	var calls []call
+	redisClient := &nackDeadLetterRedis{
+		deadLetterIDsFn: func(_ context.Context, stream string, ids []string, reason string) error {
+			calls = append(calls, call{stream: stream, reason: reason, ids: ids})
+			return nil
+		},
+	}
+	hp := nackHotPath(t, redisClient, "schema_error=dlq")
+	deadLetteredBefore := metrics.NackDeadLettered.Value()
+	nackedBefore := metrics.MessagesNacked.Value()
+
+	p := getPendingACK()
+	defer putPendingACK(p)
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"1-0", "2-0"}, Reason: "schema_error"})
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"3-0"}, Reason: "timeout"})
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"4-0"}, Ack: true})
+	if p.size() != 4 {
+		t.Errorf("size() = %d; want 4", p.size())
+	}
+	hp.flushACKs(t.Context(), testStreamSimp, p)
+
+	if len(calls) != 1 || calls[0].reason != "schema_error" || len(calls[0].ids) != 2 {
+		t.Fatalf("DeadLetterIDs calls = %+v; want the two schema_error entries", calls)
+	}
+	if got := metrics.NackDeadLettered.Value() - deadLetteredBefore; got != 2 {
+		t.Errorf("NackDeadLettered delta = %d; want 2", got)
+	}
+	if got := metrics.MessagesNacked.Value() - nackedBefore; got != 3 {
+		t.Errorf("MessagesNacked delta = %d; want 3", got)
+	}
+}
+
+func TestFlushNACKs_DeadLetterError(t *testing.T) {
+	redisClient := &nackDeadLetterRedis{
+		deadLetterIDsFn: func(context.Context, string, []string, string) error {
+			return errors.New("xadd failed")
+		},
+	}
+	hp := nackHotPath(t, redisClient, "schema_error=dlq")
+	errorsBefore := metrics.NackDeadLetterErrors.Value()
+
+	p := getPendingACK()
+	defer putPendingACK(p)
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"1-0"}, Reason: "schema_error"})
+	hp.flushACKs(t.Context(), testStreamSimp, p)
+
+	if got := metrics.NackDeadLetterErrors.Value() - errorsBefore; got != 1 {
+		t.Errorf("NackDeadLetterErrors delta = %d; want 1", got)
+	}
+}
+
+func TestNackPolicies_BackoffHoldsClaimedEntries(t *testing.T) {
+	hp := nackHotPath(t, &nackDeadLetterRedis{}, "overloaded=backoff:1m")
+	p := getPendingACK()
+	defer putPendingACK(p)
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"1-0"}, Reason: "overloaded"})
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"2-0"}, RetryAfter: time.Second})
+	hp.addACK(p, &message.AckMessage{Stream: testStreamSimp, IDs: []string{"3-0"}})
+
+	claimed := func() message.Batch {
+		buf := message.NewBuffer(3)
+		for _, id := range []string{"1-0", "2-0", "3-0"} {
+			buf.Add(id, testStreamSimp, map[string]any{"raw": id})
+		}
+		return message.NewLazyBatch(buf, &sync.Pool{})
+	}
+
+	batch := claimed()
+	if held := hp.nacks.holdBack(&batch, time.Now()); held != 2 || len(batch.Items) != 1 || batch.Items[0].ID != "3-0" {
+		t.Errorf("holdBack = %d, items %+v; want 2 held and 3-0 kept", held, batch.Items)
+	}
+	batch.Release()
+
+	batch = claimed()
+	if held := hp.nacks.holdBack(&batch, time.Now().Add(2*time.Second)); held != 1 || batch.Items[0].ID != "2-0" {
+		t.Errorf("holdBack after retry_after = %d, items %+v; want only 1-0 held", held, batch.Items)
+	}
+	batch.Release()
+
+	batch = claimed()
+	if held := hp.nacks.holdBack(&batch, time.Now().Add(2*time.Minute)); held != 0 || len(hp.nacks.holds) != 0 {
+		t.Errorf("holdBack after backoff = %d, holds %v; want nothing held or remembered", held, hp.nacks.holds)
+	}
+	batch.Release()
+}
+
+func TestNackPolicies_Policy(t *testing.T) {
+	n := newNackPolicies(&mockRedis{}, &config.PipelineConfig{
+		NackPolicies: []string{"schema_error=dlq", "overloaded=backoff:5m", "unspecified=backoff:1m"},
+	})
+	tests := []struct {
+		want config.NackPolicy
+		ack  message.AckMessage
+	}{
+		{
+			ack:  message.AckMessage{Reason: "schema_error", RetryAfter: time.Second},
+			want: config.NackPolicy{Reason: "schema_error", Action: config.NackDLQ},
+		},
+		{
+			ack:  message.AckMessage{Reason: "overloaded"},
+			want: config.NackPolicy{Reason: "overloaded", Action: config.NackBackoff, Delay: 5 * time.Minute},
+		},
+		{
+			ack:  message.AckMessage{Reason: "overloaded", RetryAfter: time.Second},
+			want: config.NackPolicy{Reason: "overloaded", Action: config.NackBackoff, Delay: time.Second},
+		},
+		{
+			ack:  message.AckMessage{},
+			want: config.NackPolicy{Reason: nackReasonUnspecified, Action: config.NackBackoff, Delay: time.Minute},
+		},
+		{
+			ack:  message.AckMessage{Reason: "timeout"},
+			want: config.NackPolicy{Reason: "timeout", Action: config.NackRetry},
+		},
+	}
+	for _, tt := range tests {
+		if got := n.policy(&tt.ack); got != tt.want {
+			t.Errorf("policy(%+v) = %+v; want %+v", tt.ack, got, tt.want)
+		}
+	}
+}
+
+func TestNackPolicies_LabelsAreBounded(t *testing.T) {
+	n := newNackPolicies(&mockRedis{}, &config.PipelineConfig{NackPolicies: []string{"known=retry"}})
+	for i := range maxNackReasonLabels {
+		n.label(string(rune('a'+i)), false)
+	}
+	if got := n.label("one-too-many", false); got != nackReasonOther {
+		t.Errorf("label(new reason) = %q; want %q", got, nackReasonOther)
+	}
+	if got := n.label("a", false); got != "a" {
+		t.Errorf("label(seen reason) = %q; want a", got)
+	}
+	if got := n.label("known", true); got != "known" {
+		t.Errorf("label(configured reason) = %q; want known", got)
+	}
+}
+
+func TestNew_NackDLQRequiresDeadLetterer(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.NackPolicies = []string{"schema_error=dlq"}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without DeadLetterIDs")
+	}
+}
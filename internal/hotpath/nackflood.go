@@ -0,0 +1,158 @@
+package hotpath
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// nackFlood quarantines the streams whose receivers NACK more than
+// PIPELINE_NACK_FLOOD_THRESHOLD entries within PIPELINE_NACK_FLOOD_WINDOW.
+// A receiver that rejects everything would otherwise have the claim loop
+// redeliver the same entries every REDIS_CLAIM_IDLE, only to see them
+// NACKed again at once. For PIPELINE_NACK_FLOOD_COOLDOWN the claim loop
+// leaves the entries of a quarantined stream pending; new entries are still
+// fetched and published, and their NACKs are not counted until the
+// quarantine is over. A nil nackFlood quarantines nothing.
+type nackFlood struct {
+	streams   map[string]*nackWindow
+	window    time.Duration
+	cooldown  time.Duration
+	threshold int
+	mu        sync.Mutex
+}
+
+// nackWindow counts the entries of a stream NACKed since start; until is
+// the end of the stream's quarantine, zero while it is not in one.
+type nackWindow struct {
+	start time.Time
+	until time.Time
+	count int
+}
+
+func newNackFlood(cfg *config.PipelineConfig) *nackFlood {
+	if cfg.NackFloodThreshold <= 0 {
+		return nil
+	}
+	return &nackFlood{
+		streams:   make(map[string]*nackWindow),
+		window:    cfg.NackFloodWindow,
+		cooldown:  cfg.NackFloodCooldown,
+		threshold: cfg.NackFloodThreshold,
+	}
+}
+
+// record counts n entries of stream NACKed at now and reports whether they
+// put the stream in quarantine.
+func (f *nackFlood) record(stream string, n int, now time.Time) bool {
+	if f == nil || n == 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.streams[stream]
+	if w == nil {
+		w = &nackWindow{start: now}
+		f.streams[stream] = w
+	}
+	if !w.until.IsZero() {
+		return false
+	}
+	if now.Sub(w.start) >= f.window {
+		w.start, w.count = now, 0
+	}
+	w.count += n
+	if w.count <= f.threshold {
+		return false
+	}
+	w.until, w.count = now.Add(f.cooldown), 0
+	metrics.NackQuarantines.Add(1)
+	metrics.NackQuarantinedStreams.Add(1)
+	return true
+}
+
+// release ends the quarantines over at now and returns their streams. It
+// also forgets the streams whose window has passed, so that the map only
+// holds streams NACKed lately.
+func (f *nackFlood) release(now time.Time) []string {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var released []string
+	for stream, w := range f.streams {
+		switch {
+		case w.until.IsZero():
+			if now.Sub(w.start) >= f.window {
+				delete(f.streams, stream)
+			}
+		case !now.Before(w.until):
+			delete(f.streams, stream)
+			released = append(released, stream)
+		}
+	}
+	metrics.NackQuarantinedStreams.Add(-int64(len(released)))
+	return released
+}
+
+// holdBack drops the entries of a claimed batch whose stream is in
+// quarantine and returns how many it dropped.
+func (f *nackFlood) holdBack(batch *message.Batch) int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var drop []bool
+	held := 0
+	for i := range batch.Items {
+		if w := f.streams[batch.Items[i].Stream]; w == nil || w.until.IsZero() {
+			continue
+		}
+		if drop == nil {
+			drop = make([]bool, len(batch.Items))
+		}
+		drop[i] = true
+		held++
+	}
+	if held > 0 {
+		batch.Compact(drop)
+	}
+	return held
+}
+
+// recordNACKs counts the NACKed entries of p against the flood threshold
+// of stream.
+func (hp *HotPath) recordNACKs(ctx context.Context, stream string, p *pendingACK) {
+	if !hp.nackFlood.record(stream, p.nackCount, time.Now()) {
+		return
+	}
+	hp.log.Warnf(ctx, "Stream %s had more than %d entries NACKed within %v; quarantined for %v, "+
+		"claimed entries stay pending", stream, hp.nackFlood.threshold, hp.nackFlood.window, hp.nackFlood.cooldown)
+}
+
+// holdClaimed drops from a claimed batch the entries on a NACK backoff and
+// those of the streams in quarantine, leaving them pending.
+func (hp *HotPath) holdClaimed(ctx context.Context, batch *message.Batch) {
+	now := time.Now()
+	if held := hp.nacks.holdBack(batch, now); held > 0 {
+		metrics.NackHeld.Add(int64(held))
+		if hp.log.DebugEnabled(ctx) {
+			hp.log.Debugf(ctx, "Holding back %d claimed messages until their NACK backoff ends", held)
+		}
+	}
+	for _, stream := range hp.nackFlood.release(now) {
+		hp.log.Infof(ctx, "Stream %s is out of NACK quarantine; its claimed entries are redelivered again", stream)
+	}
+	if held := hp.nackFlood.holdBack(batch); held > 0 {
+		metrics.NackQuarantineHeld.Add(int64(held))
+		if hp.log.DebugEnabled(ctx) {
+			hp.log.Debugf(ctx, "Holding back %d claimed messages of streams in NACK quarantine", held)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package hotpath
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func testNackFlood() *nackFlood {
+	return newNackFlood(&config.PipelineConfig{
+		NackFloodThreshold: 10, NackFloodWindow: time.Second, NackFloodCooldown: time.Minute,
+	})
+}
+
+// claimedFrom returns a claimed batch of one entry per stream.
+func claimedFrom(streams ...string) message.Batch {
+	buf := message.NewBuffer(len(streams))
+	for i, stream := range streams {
+		buf.Add(string(rune('1'+i))+"-0", stream, map[string]any{"raw": stream})
+	}
+	return message.NewLazyBatch(buf, &sync.Pool{})
+}
+
+func TestNewNackFlood_Disabled(t *testing.T) {
+	f := newNackFlood(&config.PipelineConfig{NackFloodWindow: time.Second, NackFloodCooldown: time.Minute})
+	if f != nil {
+		t.Fatal("newNackFlood() without a threshold is not nil")
+	}
+	batch := claimedFrom("fw")
+	defer batch.Release()
+	if f.record("fw", 100, time.Now()) || f.holdBack(&batch) != 0 || f.release(time.Now()) != nil {
+		t.Error("a nil nackFlood quarantined a stream")
+	}
+}
+
+func TestNackFlood_QuarantinesPastThreshold(t *testing.T) {
+	f := testNackFlood()
+	quarantinesBefore := metrics.NackQuarantines.Value()
+	quarantinedBefore := metrics.NackQuarantinedStreams.Value()
+	now := time.Now()
+
+	if f.record("fw", 6, now) || f.record("fw", 4, now.Add(500*time.Millisecond)) {
+		t.Fatal("record() quarantined at the threshold")
+	}
+	if !f.record("fw", 1, now.Add(600*time.Millisecond)) {
+		t.Fatal("record() past the threshold did not quarantine")
+	}
+	if f.record("fw", 100, now.Add(700*time.Millisecond)) {
+		t.Error("record() during the quarantine quarantined again")
+	}
+	if got := metrics.NackQuarantines.Value() - quarantinesBefore; got != 1 {
+		t.Errorf("NackQuarantines delta = %d; want 1", got)
+	}
+	if got := metrics.NackQuarantinedStreams.Value() - quarantinedBefore; got != 1 {
+		t.Errorf("NackQuarantinedStreams delta = %d; want 1", got)
+	}
+
+	batch := claimedFrom("fw", "app", "fw")
+	if held := f.holdBack(&batch); held != 2 || len(batch.Items) != 1 || batch.Items[0].Stream != "app" {
+		t.Errorf("holdBack() = %d, items %+v; want the 2 fw entries held", held, batch.Items)
+	}
+	batch.Release()
+
+	if released := f.release(now.Add(30 * time.Second)); released != nil {
+		t.Errorf("release() during the cooldown = %v; want none", released)
+	}
+	released := f.release(now.Add(2 * time.Minute))
+	if len(released) != 1 || released[0] != "fw" || len(f.streams) != 0 {
+		t.Errorf("release() after the cooldown = %v, streams %v; want [fw] and nothing kept", released, f.streams)
+	}
+	if got := metrics.NackQuarantinedStreams.Value() - quarantinedBefore; got != 0 {
+		t.Errorf("NackQuarantinedStreams delta after release = %d; want 0", got)
+	}
+}
+
+func TestNackFlood_WindowRestarts(t *testing.T) {
+	f := testNackFlood()
+	now := time.Now()
+	if f.record("fw", 8, now) || f.record("fw", 8, now.Add(time.Second)) {
+		t.Error("record() counted NACKs of a past window")
+	}
+	f.release(now.Add(3 * time.Second))
+	if len(f.streams) != 0 {
+		t.Errorf("streams after a quiet window = %v; want none", f.streams)
+	}
+}
+
+func TestClaimLoop_HoldsQuarantinedStreams(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.NackFloodThreshold = 1
+	cfg.Pipeline.NackFloodWindow = time.Second
+	cfg.Pipeline.NackFloodCooldown = time.Minute
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	heldBefore := metrics.NackQuarantineHeld.Value()
+
+	p := getPendingACK()
+	defer putPendingACK(p)
+	hp.addACK(p, &message.AckMessage{Stream: "fw", IDs: []string{"1-0", "2-0"}})
+	hp.flushACKs(t.Context(), "fw", p)
+
+	batch := claimedFrom("fw", "app")
+	defer batch.Release()
+	hp.holdClaimed(t.Context(), &batch)
+	if len(batch.Items) != 1 || batch.Items[0].Stream != "app" {
+		t.Errorf("claimed items = %+v; want only the app entry", batch.Items)
+	}
+	if got := metrics.NackQuarantineHeld.Value() - heldBefore; got != 1 {
+		t.Errorf("NackQuarantineHeld delta = %d; want 1", got)
+	}
+}
@@ -0,0 +1,69 @@
+package hotpath
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// keyShards is shardItems under PIPELINE_ORDERING_KEY: an item's owner is
+// chosen by the value of the key field of its object, or by its stream name
+// when the field is missing. It extracts every item.
+func keyShards(batch *message.Batch, key []byte, shards int) (shardOf []int, mixed bool) {
+	shardOf = make([]int, len(batch.Items))
+	for i, msg := range batch.Indexed() {
+		if value := orderingValue(msg, key); value != nil {
+			shardOf[i] = streamShard(value, shards)
+		} else {
+			shardOf[i] = streamShard(msg.Stream, shards)
+		}
+		mixed = mixed || shardOf[i] != shardOf[0]
+	}
+	return shardOf, mixed
+}
+
+// orderingValue returns the raw JSON value of the top-level key field of
+// msg's object, or nil when it has none. The value is only valid until msg
+// is released.
+func orderingValue(msg *message.Redis, key []byte) []byte {
+	var value []byte
+	if msg.Object == "" {
+		return nil
+	}
+	jsonfast.IterateFieldsString(msg.Object, func(name, v []byte) bool {
+		if bytes.Equal(name[1:len(name)-1], key) {
+			value = v
+			return false
+		}
+		return true
+	})
+	return value
+}
+
+// publishInOrder wraps the publish function of an ordered worker: a failed
+// publish is retried every errorBackoff until it goes through or ctx is
+// canceled, so the payloads queued behind it, which may carry the same
+// keys, never overtake it. A rejected publish is not retried: it cannot go
+// through, and its entries are dead-lettered instead.
+func (hp *HotPath) publishInOrder(
+	publish func(context.Context, message.Payload) error,
+) func(context.Context, message.Payload) error {
+	return func(ctx context.Context, payload message.Payload) error {
+		for {
+			err := publish(ctx, payload)
+			if err == nil || ctx.Err() != nil || mqtt.Classify(err) == mqtt.FailureRejected {
+				return err
+			}
+			metrics.PublishRetries.Add(1)
+			hp.log.Warnf(ctx, "Failed to publish ordered batch, retrying in %s: %v", hp.errorBackoff, err)
+			if sleepCtx(ctx, hp.errorBackoff) != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func TestEnqueueOrdered_PartitionsByOrderingKey(t *testing.T) {
+	cfg := orderedConfig(8)
+	cfg.Pipeline.OrderingKey = "host"
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	items := []message.Redis{
+		{ID: "1-0", Stream: "alpha", Object: `{"host":"web-1","msg":"a"}`},
+		{ID: "1-0", Stream: "beta", Object: `{"msg":"b","host":"web-1"}`},
+		{ID: "2-0", Stream: "alpha", Object: `{"host":"web-2"}`},
+		{ID: "3-0", Stream: "alpha", Raw: "no object"},
+	}
+	if err := hp.enqueueBatch(t.Context(), message.Batch{Items: items}); err != nil {
+		t.Fatalf("enqueueBatch() error = %v", err)
+	}
+
+	owner := make(map[string]int)
+	for idx, ch := range hp.publishChans {
+		for len(ch) > 0 {
+			batch := <-ch
+			for _, m := range batch.Items {
+				owner[m.Stream+"/"+m.ID] = idx
+			}
+		}
+	}
+	shards := len(hp.publishChans)
+	if owner["alpha/1-0"] != streamShard(`"web-1"`, shards) || owner["beta/1-0"] != owner["alpha/1-0"] {
+		t.Errorf("owners = %v; want both web-1 entries on the web-1 worker", owner)
+	}
+	if owner["alpha/2-0"] != streamShard(`"web-2"`, shards) {
+		t.Errorf("owners = %v; want the web-2 entry on the web-2 worker", owner)
+	}
+	if owner["alpha/3-0"] != streamShard("alpha", shards) {
+		t.Errorf("owners = %v; want the entry without a key on its stream's worker", owner)
+	}
+	if len(owner) != len(items) {
+		t.Errorf("routed %d entries; want %d", len(owner), len(items))
+	}
+}
+
+func TestPublishInOrder_RetriesUntilPublished(t *testing.T) {
+	cfg := orderedConfig(1)
+	cfg.Pipeline.ErrorBackoff = time.Millisecond
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := metrics.PublishRetries.Value()
+
+	calls := 0
+	publish := hp.publishInOrder(func(context.Context, message.Payload) error {
+		if calls++; calls < 3 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	})
+	if err := publish(t.Context(), []byte("x")); err != nil {
+		t.Fatalf("publish() error = %v; want the retried publish to go through", err)
+	}
+	if calls != 3 {
+		t.Errorf("publish calls = %d; want 3", calls)
+	}
+	if got := metrics.PublishRetries.Value() - before; got != 2 {
+		t.Errorf("PublishRetries delta = %d; want 2", got)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	failing := hp.publishInOrder(func(context.Context, message.Payload) error { return errors.New("broker unavailable") })
+	if err := failing(ctx, []byte("x")); err == nil {
+		t.Error("publish() error = nil; want the error once ctx is canceled")
+	}
+
+	calls = 0
+	rejected := hp.publishInOrder(func(context.Context, message.Payload) error {
+		calls++
+		return errors.New("mqtt publish failed: unknown payload type")
+	})
+	if err := rejected(t.Context(), []byte("x")); err == nil || calls != 1 {
+		t.Errorf("publish() = %v after %d calls; want the rejection without a retry", err, calls)
+	}
+}
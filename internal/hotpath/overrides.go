@@ -0,0 +1,146 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// streamDestinations applies the publish_topic and qos of the
+// REDIS_STREAM_OVERRIDES_FILE overrides: a batch holding entries of
+// streams with different destinations is split, and each part published
+// to its own. A nil streamDestinations publishes every batch with the
+// worker's publish function.
+type streamDestinations struct {
+	publisher mqtt.TopicPublisher
+	topic     string // MQTT_PUBLISH_TOPIC
+	overrides []config.StreamOverride
+	qos       byte
+}
+
+// hasDestination reports whether any override changes where its streams
+// are published.
+func hasDestination(overrides []config.StreamOverride) bool {
+	for i := range overrides {
+		if overrides[i].PublishTopic != "" || overrides[i].QoS >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func validateStreamDestinations(topics mqtt.TopicPublisher, cfg *config.Config) error {
+	if !hasDestination(cfg.Redis.StreamOverrides) {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: stream override topics need an mqtt publisher that can publish to other topics")
+	}
+	return nil
+}
+
+func newStreamDestinations(topics mqtt.TopicPublisher, cfg *config.Config) *streamDestinations {
+	if !hasDestination(cfg.Redis.StreamOverrides) {
+		return nil
+	}
+	return &streamDestinations{
+		publisher: topics,
+		overrides: cfg.Redis.StreamOverrides,
+		topic:     cfg.MQTT.PublishTopic,
+		qos:       cfg.MQTT.QoS,
+	}
+}
+
+// resolve returns the destination of stream's entries, or false when its
+// override, if any, keeps the default one.
+func (d *streamDestinations) resolve(stream string) (destination, bool) {
+	o, ok := config.MatchStreamOverride(d.overrides, stream)
+	if !ok || (o.PublishTopic == "" && o.QoS < 0) {
+		return destination{}, false
+	}
+	dest := destination{topic: d.topic, qos: d.qos}
+	if o.PublishTopic != "" {
+		dest.topic = o.PublishTopic
+	}
+	if o.QoS >= 0 {
+		dest.qos = byte(o.QoS) // 0 to 2, checked by config.Validate
+	}
+	return dest, true
+}
+
+// split returns the destination of every item, 0 being the default one
+// and i the destination dests[i-1], or nil when they all go to the
+// default. Entries of a batch mostly share a stream, so the destination is
+// resolved once per run of them.
+func (d *streamDestinations) split(items []message.Redis) (shardOf []int, dests []destination) {
+	index := make(map[destination]int)
+	shard := 0
+	for i := range items {
+		if i == 0 || items[i].Stream != items[i-1].Stream {
+			shard = 0
+			if dest, ok := d.resolve(items[i].Stream); ok {
+				if shard, ok = index[dest]; !ok {
+					dests = append(dests, dest)
+					shard = len(dests)
+					index[dest] = shard
+				}
+			}
+		}
+		if shard != 0 && shardOf == nil {
+			shardOf = make([]int, len(items))
+		}
+		if shardOf != nil {
+			shardOf[i] = shard
+		}
+	}
+	return shardOf, dests
+}
+
+// publishToStreams is publishBatch under stream override destinations:
+// the entries going to the default destination are published with
+// publishFn, and the others to their stream's topic and QoS. In ordered
+// mode the other destinations are retried like publishFn. It is also where
+// the enrichment of the batch is looked up.
+func (hp *HotPath) publishToStreams(
+	ctx context.Context,
+	builder *jsonfast.Builder, enc *zstd.Encoder,
+	batch *message.Batch, bw *jsonfast.BatchWriter, compressed *[]byte,
+	publishFn func(context.Context, message.Payload) error,
+) {
+	hp.prefetchEnrichment(ctx, batch)
+	if hp.destinations == nil {
+		hp.publishBatch(ctx, builder, enc, batch, bw, compressed, publishFn)
+		return
+	}
+	shardOf, dests := hp.destinations.split(batch.Items)
+	if shardOf == nil {
+		hp.publishBatch(ctx, builder, enc, batch, bw, compressed, publishFn)
+		return
+	}
+	for i, part := range batch.Partition(shardOf, len(dests)+1) {
+		if len(part.Items) > 0 {
+			fn := publishFn
+			if i > 0 {
+				fn = hp.destinationPublisher(dests[i-1])
+			}
+			hp.publishBatch(ctx, builder, enc, &part, bw, compressed, fn)
+		}
+		part.Release()
+	}
+}
+
+func (hp *HotPath) destinationPublisher(dest destination) func(context.Context, message.Payload) error {
+	publishFn := func(ctx context.Context, payload message.Payload) error {
+		return hp.destinations.publisher.PublishTo(ctx, payload, dest.topic, dest.qos, false)
+	}
+	if hp.orderedPublish {
+		return hp.publishInOrder(publishFn)
+	}
+	return publishFn
+}
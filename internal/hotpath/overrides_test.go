@@ -0,0 +1,109 @@
+package hotpath
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func overridesHotPath(t *testing.T, overrides ...config.StreamOverride) (*HotPath, *mockRoutedPublisher) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.MQTT.PublishTopic = "logs"
+	cfg.MQTT.QoS = 1
+	cfg.Redis.StreamOverrides = overrides
+	publisher := &mockRoutedPublisher{}
+	hp, err := New(&mockRedis{}, publisher, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, publisher
+}
+
+func TestPublishToStreams_SplitsByDestination(t *testing.T) {
+	hp, publisher := overridesHotPath(t,
+		config.StreamOverride{Pattern: "fw-*", PublishTopic: "logs/firewall", QoS: -1},
+		config.StreamOverride{Pattern: "audit", QoS: 2},
+		config.StreamOverride{Pattern: "app", BatchSize: 10, QoS: -1},
+	)
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "fw-edge", Raw: "deny"},
+		{ID: "2-0", Stream: "app", Raw: "started"},
+		{ID: "3-0", Stream: "audit", Raw: "login"},
+		{ID: "4-0", Stream: "fw-core", Raw: "allow"},
+	}}
+
+	var lines []string
+	publish := func(_ context.Context, payload message.Payload) error {
+		lines = append(lines, decompressLines(payload)...)
+		return nil
+	}
+	var compressed []byte
+	hp.publishToStreams(t.Context(), jsonfast.New(512), compress.NewEncoder(), &batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "2-0\tapp\t") {
+		t.Errorf("default payload = %q; want the app entry only", lines)
+	}
+	if len(publisher.routed) != 2 {
+		t.Fatalf("routed payloads = %+v; want 2", publisher.routed)
+	}
+	if fw := publisher.routed[0]; fw.topic != "logs/firewall" || fw.qos != 1 || len(fw.lines) != 2 {
+		t.Errorf("firewall payload = %+v; want both fw entries on logs/firewall at the default qos", fw)
+	}
+	if audit := publisher.routed[1]; audit.topic != "logs" || audit.qos != 2 || len(audit.lines) != 1 {
+		t.Errorf("audit payload = %+v; want the audit entry on the default topic at qos 2", audit)
+	}
+}
+
+func TestPublishToStreams_DefaultDestinationKeepsBatch(t *testing.T) {
+	hp, _ := overridesHotPath(t, config.StreamOverride{Pattern: "fw-*", PublishTopic: "logs/firewall", QoS: -1})
+	shardOf, dests := hp.destinations.split([]message.Redis{{Stream: "app"}, {Stream: "app"}})
+	if shardOf != nil || dests != nil {
+		t.Errorf("split() = %v, %v; want nil for entries without a destination override", shardOf, dests)
+	}
+
+	hp, _ = overridesHotPath(t, config.StreamOverride{Pattern: "app", BatchSize: 10, QoS: -1})
+	if hp.destinations != nil {
+		t.Error("destinations != nil; want none without a topic or qos override")
+	}
+}
+
+func TestNew_StreamDestinationsNeedRoutedPublisher(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.StreamOverrides = []config.StreamOverride{{Pattern: "fw-*", PublishTopic: "logs/firewall", QoS: -1}}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a publisher without PublishTo")
+	}
+}
+
+func TestMaxAgePolicies_StreamOverrideWins(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.MaxAgePolicies = []string{"*=drop:24h"}
+	cfg.Redis.StreamOverrides = []config.StreamOverride{
+		{Pattern: "debug-*", MaxAge: time.Minute, QoS: -1},
+		{Pattern: "app", BatchSize: 10, QoS: -1},
+	}
+	m := newMaxAgePolicies(&mockRedis{}, cfg)
+	if p, _ := m.policy("debug-web"); p.MaxAge != time.Minute || p.Action != config.MaxAgeDrop {
+		t.Errorf("policy(debug-web) = %+v; want the override's 1m drop", p)
+	}
+	if p, _ := m.policy("app"); p.MaxAge != 24*time.Hour {
+		t.Errorf("policy(app) = %+v; want the 24h policy for an override without a max age", p)
+	}
+
+	cfg.Pipeline.MaxAgePolicies = nil
+	cfg.Redis.StreamOverrides[0].MaxAgeAction = config.MaxAgeDLQ
+	if err := validateMaxAge(&mockRedis{}, cfg); err == nil {
+		t.Error("validateMaxAge() error = nil; want error for a dlq override without dead-lettering")
+	}
+}
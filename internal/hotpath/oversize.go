@@ -0,0 +1,228 @@
+package hotpath
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// deadLetterer is implemented by *redis.Client; the dlq oversize policy
+// requires it.
+type deadLetterer interface {
+	DeadLetter(ctx context.Context, msgs []message.Redis, reason string) error
+}
+
+const deadLetterReasonOversize = "oversize"
+
+var (
+	fkTruncated  = jsonfast.NewFieldKey("truncated")
+	fkChunkSeq   = jsonfast.NewFieldKey("chunk_seq")
+	fkChunkTotal = jsonfast.NewFieldKey("chunk_total")
+	keyChunkData = []byte("chunk_data")
+)
+
+// chunkEnvelopeOverhead is the size of a split envelope around its data:
+// the JSON punctuation and keys plus room for two 20-digit integers.
+const chunkEnvelopeOverhead = len(`{"chunk_seq":,"chunk_total":,"chunk_data":""}`) + 2*20
+
+// oversizeLimit enforces PIPELINE_MAX_PAYLOAD_BYTES. A nil limit publishes
+// every record as built, one payload per batch.
+type oversizeLimit struct {
+	dlq    deadLetterer
+	policy string
+	max    int
+}
+
+func validateOversize(redisClient redis.StreamClient, cfg *config.PipelineConfig) error {
+	if cfg.MaxPayloadBytes <= 0 || cfg.OversizePolicy != config.OversizeDLQ {
+		return nil
+	}
+	if _, ok := redisClient.(deadLetterer); !ok {
+		return errors.New("hotpath: oversize policy dlq needs a redis client that supports dead-lettering")
+	}
+	return nil
+}
+
+func newOversizeLimit(redisClient redis.StreamClient, cfg *config.PipelineConfig) *oversizeLimit {
+	if cfg.MaxPayloadBytes <= 0 {
+		return nil
+	}
+	dlq, _ := redisClient.(deadLetterer)
+	return &oversizeLimit{dlq: dlq, policy: cfg.OversizePolicy, max: cfg.MaxPayloadBytes}
+}
+
+// recordSpan locates one NDJSON record in the batch buffer. messages is 0
+// for the continuation chunks of a split record, so counts stay per entry.
+type recordSpan struct {
+	start    int
+	end      int
+	messages int
+}
+
+// oversizeBatch is the per-batch bookkeeping of a limited publish. It is
+// pooled because a batch can hold tens of thousands of records.
+type oversizeBatch struct {
+	spans       []recordSpan
+	deadLetters []message.Redis
+}
+
+var oversizeBatchPool = sync.Pool{New: func() any { return new(oversizeBatch) }}
+
+func getOversizeBatch() *oversizeBatch {
+	ob, ok := oversizeBatchPool.Get().(*oversizeBatch)
+	if !ok {
+		return new(oversizeBatch)
+	}
+	return ob
+}
+
+func putOversizeBatch(ob *oversizeBatch) {
+	clear(ob.deadLetters) // drop string references into the released batch
+	ob.spans = ob.spans[:0]
+	ob.deadLetters = ob.deadLetters[:0]
+	oversizeBatchPool.Put(ob)
+}
+
+func (ob *oversizeBatch) append(bw *jsonfast.BatchWriter, record []byte, messages int) {
+	start := bw.Len()
+	bw.Append(record)
+	ob.spans = append(ob.spans, recordSpan{start: start, end: bw.Len(), messages: messages})
+}
+
+// add appends record, or applies the oversize policy when it is over the
+// limit. record must be the current contents of builder.
+func (l *oversizeLimit) add(
+	bw *jsonfast.BatchWriter, builder *jsonfast.Builder,
+	msg *message.Redis, record []byte, ob *oversizeBatch,
+) {
+	if len(record) <= l.max {
+		ob.append(bw, record, 1)
+		return
+	}
+	switch l.policy {
+	case config.OversizeSplit:
+		l.split(bw, builder, msg, record, ob)
+		metrics.OversizeSplit.Add(1)
+	case config.OversizeDLQ:
+		ob.deadLetters = append(ob.deadLetters, *msg)
+	default:
+		ob.append(bw, l.truncate(builder, msg, len(record)), 1)
+		metrics.OversizeTruncated.Add(1)
+	}
+}
+
+// truncate rebuilds the record as {"raw":<prefix>,"truncated":<size>},
+// where prefix is the longest UTF-8-safe prefix of the original line (or
+// of the object when there is no line) that keeps the record within max.
+func (l *oversizeLimit) truncate(builder *jsonfast.Builder, msg *message.Redis, originalLen int) []byte {
+	text := msg.Raw
+	if text == "" {
+		text = msg.Object
+	}
+	cut := min(len(text), l.max)
+	for {
+		for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		builder.Reset()
+		appendHeader(builder, msg)
+		builder.BeginObject()
+		builder.AddStringFieldKey(fkRaw, text[:cut])
+		builder.AddIntFieldKey(fkTruncated, originalLen)
+		builder.EndObject()
+
+		over := builder.Len() - l.max
+		if over <= 0 || cut == 0 {
+			return builder.Bytes()
+		}
+		// Escaping only grows the output, so dropping `over` input bytes
+		// always makes progress.
+		cut = max(cut-over, 0)
+	}
+}
+
+// split cuts the record's object into base64 chunks, each published as
+// its own record {"chunk_seq":i,"chunk_total":n,"chunk_data":"..."} under
+// the original ID and stream. Decoding and concatenating the chunks in
+// chunk_seq order yields the object that would have been published.
+func (l *oversizeLimit) split(
+	bw *jsonfast.BatchWriter, builder *jsonfast.Builder,
+	msg *message.Redis, record []byte, ob *oversizeBatch,
+) {
+	headerLen := len(msg.ID) + len(msg.Stream) + 2
+	object := bytes.Clone(record[headerLen:]) // record aliases builder
+	perChunk := max((l.max-headerLen-chunkEnvelopeOverhead)/4*3, 3)
+	total := (len(object) + perChunk - 1) / perChunk
+
+	data := make([]byte, 0, base64.StdEncoding.EncodedLen(perChunk)+2)
+	for seq := range total {
+		part := object[seq*perChunk : min((seq+1)*perChunk, len(object))]
+		data = append(data[:0], '"')
+		data = base64.StdEncoding.AppendEncode(data, part)
+		data = append(data, '"')
+
+		builder.Reset()
+		appendHeader(builder, msg)
+		builder.BeginObject()
+		builder.AddIntFieldKey(fkChunkSeq, seq)
+		builder.AddIntFieldKey(fkChunkTotal, total)
+		builder.AddRawBytesField(keyChunkData, data)
+		builder.EndObject()
+
+		messages := 0
+		if seq == 0 {
+			messages = 1
+		}
+		ob.append(bw, builder.Bytes(), messages)
+	}
+}
+
+// publishSpans compresses and publishes the records in spans, halving the
+// range until each compressed payload fits the limit. A single record that
+// still does not fit after compression is published as is. It returns the
+// number of messages published.
+func (hp *HotPath) publishSpans(
+	ctx context.Context, enc *zstd.Encoder, data []byte, spans []recordSpan,
+	compressed *[]byte, publishFn func(context.Context, message.Payload) error,
+) int {
+	raw := data[spans[0].start:spans[len(spans)-1].end]
+	*compressed = compress.EncodeMarked(enc, *compressed, raw, hp.origin)
+	if len(*compressed) > hp.oversize.max && len(spans) > 1 {
+		metrics.PayloadSplits.Add(1)
+		mid := len(spans) / 2
+		return hp.publishSpans(ctx, enc, data, spans[:mid], compressed, publishFn) +
+			hp.publishSpans(ctx, enc, data, spans[mid:], compressed, publishFn)
+	}
+
+	messages := 0
+	for _, s := range spans {
+		messages += s.messages
+	}
+	if !hp.sendPayload(ctx, *compressed, messages, data, spans, publishFn) {
+		return 0
+	}
+	hp.receipts.recordPublished(data, spans)
+	return messages
+}
+
+func (hp *HotPath) deadLetterOversize(ctx context.Context, msgs []message.Redis) {
+	hp.events.droppedMessages(deadLetterReasonOversize, msgs)
+	if err := hp.oversize.dlq.DeadLetter(ctx, msgs, deadLetterReasonOversize); err != nil {
+		hp.log.Errorf(ctx, "Failed to dead-letter %d oversize messages: %v", len(msgs), err)
+		metrics.OversizeDeadLetterErrors.Add(int64(len(msgs)))
+		return
+	}
+	metrics.OversizeDeadLettered.Add(int64(len(msgs)))
+}
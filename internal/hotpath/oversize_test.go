@@ -0,0 +1,244 @@
+package hotpath
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+const testMaxPayload = 1024
+
+// deadLetterRedis is a mockRedis that also implements deadLetterer.
+type deadLetterRedis struct {
+	mockRedis
+	deadLetterFn func(ctx context.Context, msgs []message.Redis, reason string) error
+}
+
+func (m *deadLetterRedis) DeadLetter(ctx context.Context, msgs []message.Redis, reason string) error {
+	return m.deadLetterFn(ctx, msgs, reason)
+}
+
+func oversizeHotPath(t *testing.T, redisClient *deadLetterRedis, policy string) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.MaxPayloadBytes = testMaxPayload
+	cfg.Pipeline.OversizePolicy = policy
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+// publishCapture runs publishBatch and returns every NDJSON record it
+// published, one slice per MQTT payload.
+func publishCapture(t *testing.T, hp *HotPath, batch []message.Redis) [][]string {
+	t.Helper()
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatalf("zstd.NewReader() error = %v", err)
+	}
+	defer dec.Close()
+
+	var payloads [][]string
+	publish := func(_ context.Context, payload message.Payload) error {
+		if len(payload) > testMaxPayload {
+			t.Errorf("payload of %d bytes exceeds limit %d", len(payload), testMaxPayload)
+		}
+		raw, err := dec.DecodeAll(payload, nil)
+		if err != nil {
+			t.Fatalf("DecodeAll() error = %v", err)
+		}
+		payloads = append(payloads, strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n"))
+		return nil
+	}
+	var compressed []byte
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &message.Batch{Items: batch},
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+	return payloads
+}
+
+// noise returns n bytes of base64 text that zstd cannot shrink much.
+func noise(seed uint64, n int) string {
+	r := rand.New(rand.NewPCG(seed, seed))
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = byte(r.Uint32())
+	}
+	return base64.StdEncoding.EncodeToString(buf)[:n]
+}
+
+func TestPublishBatch_OversizeTruncate(t *testing.T) {
+	hp := oversizeHotPath(t, &deadLetterRedis{}, config.OversizeTruncate)
+	before := metrics.OversizeTruncated.Value()
+
+	long := strings.Repeat("é", testMaxPayload) // 2 bytes per rune
+	batch := []message.Redis{
+		{ID: testMsgID1, Stream: testStreamSimp, Raw: "short"},
+		{ID: "2-0", Stream: testStreamSimp, Object: `{"hostname":"h1"}`, Raw: long},
+	}
+	payloads := publishCapture(t, hp, batch)
+	if len(payloads) != 1 || len(payloads[0]) != 2 {
+		t.Fatalf("payloads = %v; want one payload with 2 records", payloads)
+	}
+
+	_, _, short := parseLine(t, []byte(payloads[0][0]))
+	if short != `{"raw":"short"}` {
+		t.Errorf("record under the limit = %s; want it unchanged", short)
+	}
+	record := payloads[0][1]
+	if len(record) > testMaxPayload {
+		t.Errorf("truncated record is %d bytes; want <= %d", len(record), testMaxPayload)
+	}
+	_, _, obj := parseLine(t, []byte(record))
+	var got struct {
+		Raw       string `json:"raw"`
+		Truncated int    `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(obj), &got); err != nil {
+		t.Fatalf("truncated record is not JSON: %v", err)
+	}
+	if !utf8.ValidString(got.Raw) || !strings.HasPrefix(long, got.Raw) || got.Raw == "" {
+		t.Errorf("raw = %q; want a non-empty UTF-8 prefix of the original line", got.Raw)
+	}
+	if wantLen := len(hp.buildPayload(jsonfast.New(512), &batch[1])); got.Truncated != wantLen {
+		t.Errorf("truncated = %d; want original size %d", got.Truncated, wantLen)
+	}
+	if d := metrics.OversizeTruncated.Value() - before; d != 1 {
+		t.Errorf("OversizeTruncated delta = %d; want 1", d)
+	}
+}
+
+func TestPublishBatch_OversizeSplit(t *testing.T) {
+	hp := oversizeHotPath(t, &deadLetterRedis{}, config.OversizeSplit)
+	publishedBefore := metrics.MessagesPublished.Value()
+
+	msg := message.Redis{ID: testMsgID1, Stream: testStreamSimp, Raw: noise(1, 5*testMaxPayload)}
+	want := hp.buildPayload(jsonfast.New(512), &msg)
+	_, _, wantObj := parseLine(t, want)
+
+	var chunks []string
+	for _, payload := range publishCapture(t, hp, []message.Redis{msg}) {
+		chunks = append(chunks, payload...)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunk records; want several", len(chunks))
+	}
+
+	var object []byte
+	for i, record := range chunks {
+		if len(record) > testMaxPayload {
+			t.Errorf("chunk %d is %d bytes; want <= %d", i, len(record), testMaxPayload)
+		}
+		id, stream, obj := parseLine(t, []byte(record))
+		if id != testMsgID1 || stream != testStreamSimp {
+			t.Errorf("chunk %d header = %s/%s; want original ID and stream", i, id, stream)
+		}
+		var env struct {
+			Data  string `json:"chunk_data"`
+			Seq   int    `json:"chunk_seq"`
+			Total int    `json:"chunk_total"`
+		}
+		if err := json.Unmarshal([]byte(obj), &env); err != nil {
+			t.Fatalf("chunk %d is not JSON: %v", i, err)
+		}
+		if env.Seq != i || env.Total != len(chunks) {
+			t.Errorf("chunk %d seq/total = %d/%d; want %d/%d", i, env.Seq, env.Total, i, len(chunks))
+		}
+		part, err := base64.StdEncoding.DecodeString(env.Data)
+		if err != nil {
+			t.Fatalf("chunk %d data: %v", i, err)
+		}
+		object = append(object, part...)
+	}
+	if !bytes.Equal(object, []byte(wantObj)) {
+		t.Error("reassembled chunks differ from the original object")
+	}
+	if d := metrics.MessagesPublished.Value() - publishedBefore; d != 1 {
+		t.Errorf("MessagesPublished delta = %d; want 1 for a split message", d)
+	}
+}
+
+func TestPublishBatch_OversizeDLQ(t *testing.T) {
+	var dead []message.Redis
+	var reason string
+	redisClient := &deadLetterRedis{
+		deadLetterFn: func(_ context.Context, msgs []message.Redis, r string) error {
+			dead = append(dead, msgs...)
+			reason = r
+			return nil
+		},
+	}
+	hp := oversizeHotPath(t, redisClient, config.OversizeDLQ)
+	before := metrics.OversizeDeadLettered.Value()
+
+	batch := []message.Redis{
+		{ID: testMsgID1, Stream: testStreamSimp, Raw: strings.Repeat("x", 2*testMaxPayload)},
+		{ID: "2-0", Stream: testStreamSimp, Raw: "fits"},
+	}
+	payloads := publishCapture(t, hp, batch)
+
+	if len(dead) != 1 || dead[0].ID != testMsgID1 || reason != deadLetterReasonOversize {
+		t.Errorf("dead-lettered %v (reason %q); want only %s", dead, reason, testMsgID1)
+	}
+	if len(payloads) != 1 || len(payloads[0]) != 1 || !strings.HasPrefix(payloads[0][0], "2-0\t") {
+		t.Errorf("payloads = %v; want only the fitting message", payloads)
+	}
+	if d := metrics.OversizeDeadLettered.Value() - before; d != 1 {
+		t.Errorf("OversizeDeadLettered delta = %d; want 1", d)
+	}
+}
+
+func TestPublishBatch_SplitsPayloadOverLimit(t *testing.T) {
+	hp := oversizeHotPath(t, &deadLetterRedis{}, config.OversizeTruncate)
+	splitsBefore := metrics.PayloadSplits.Value()
+	publishedBefore := metrics.MessagesPublished.Value()
+
+	batch := make([]message.Redis, 10)
+	for i := range batch {
+		batch[i] = message.Redis{ID: strconv.Itoa(i+1) + "-0", Stream: testStreamSimp, Raw: noise(uint64(i), 400)}
+	}
+	payloads := publishCapture(t, hp, batch)
+
+	if len(payloads) < 2 {
+		t.Fatalf("got %d payloads; want the batch split across several", len(payloads))
+	}
+	records := 0
+	for _, p := range payloads {
+		records += len(p)
+	}
+	if records != len(batch) {
+		t.Errorf("published %d records; want %d", records, len(batch))
+	}
+	if metrics.PayloadSplits.Value() == splitsBefore {
+		t.Error("PayloadSplits not incremented")
+	}
+	if d := metrics.MessagesPublished.Value() - publishedBefore; d != int64(len(batch)) {
+		t.Errorf("MessagesPublished delta = %d; want %d", d, len(batch))
+	}
+}
+
+func TestNew_OversizeDLQRequiresDeadLetterer(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.MaxPayloadBytes = testMaxPayload
+	cfg.Pipeline.OversizePolicy = config.OversizeDLQ
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without DeadLetter")
+	}
+}
@@ -0,0 +1,190 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// memoryMonitor is implemented by *redis.Client; REDIS_MEMORY_HIGH_PERCENT
+// requires it.
+type memoryMonitor interface {
+	MemoryUsage(ctx context.Context) (used, limit int64, err error)
+	SignalMemoryPressure(ctx context.Context, on bool, ttl time.Duration) error
+}
+
+// keyTTLChecks is how many checks the memory pressure key outlives, so it
+// lapses soon after the consumer stops refreshing it but not between two
+// checks.
+const keyTTLChecks = 3
+
+// memoryPressure runs the REDIS_MEMORY_HIGH_PERCENT monitor. The server is
+// under pressure from the high to the low percentage of maxmemory, so the
+// signal does not flap around a single threshold. Under pressure the fetch
+// loop reads at the largest batch size, so the backlog drains and its
+// entries can go, and producers are told to back off through the pressure
+// key and the control topic. A nil memoryPressure never reports pressure.
+type memoryPressure struct {
+	monitor   memoryMonitor
+	control   mqtt.TopicPublisher // nil without MQTT_CONTROL_TOPIC
+	drain     *readDrain
+	topic     string
+	interval  time.Duration
+	high      int64
+	low       int64
+	active    atomic.Bool
+	qos       byte
+	signalKey bool
+	announced bool // the control topic has the current state; memory loop only
+	unlimited bool // maxmemory 0 was reported; memory loop only
+}
+
+func validateMemoryPressure(redisClient redis.StreamClient, topics mqtt.TopicPublisher, cfg *config.Config) error {
+	if cfg.Redis.MemoryHighPercent == 0 {
+		return nil
+	}
+	if _, ok := redisClient.(memoryMonitor); !ok {
+		return errors.New("hotpath: the memory monitor needs a redis client that can read memory usage")
+	}
+	if cfg.MQTT.ControlTopic == "" {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: the control topic needs an mqtt publisher that can publish to other topics")
+	}
+	return nil
+}
+
+func newMemoryPressure(
+	redisClient redis.StreamClient, topics mqtt.TopicPublisher, cfg *config.Config, drain *readDrain,
+) *memoryPressure {
+	if cfg.Redis.MemoryHighPercent == 0 {
+		return nil
+	}
+	monitor, _ := redisClient.(memoryMonitor)
+	p := &memoryPressure{
+		monitor:   monitor,
+		drain:     drain,
+		topic:     cfg.MQTT.ControlTopic,
+		interval:  cfg.Redis.MemoryCheckInterval,
+		high:      int64(cfg.Redis.MemoryHighPercent),
+		low:       int64(cfg.Redis.MemoryLowPercent),
+		qos:       cfg.MQTT.QoS,
+		signalKey: cfg.Redis.MemoryPressureKey != "",
+	}
+	if p.topic != "" {
+		p.control = topics
+	}
+	return p
+}
+
+// draining reports whether the server is under memory pressure.
+func (p *memoryPressure) draining() bool {
+	return p != nil && p.active.Load()
+}
+
+// memoryLoop checks the server's memory every REDIS_MEMORY_CHECK_INTERVAL,
+// starting right away so the control topic has a state from the start.
+func (hp *HotPath) memoryLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.pressure.interval)
+	defer ticker.Stop()
+	for {
+		hp.pressure.check(ctx, hp.log)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reads the memory usage and moves in or out of pressure across the
+// thresholds. A failed read keeps the current state.
+func (p *memoryPressure) check(ctx context.Context, logger *log.Logger) {
+	used, limit, err := p.monitor.MemoryUsage(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf(ctx, "Failed to check Redis memory: %v", err)
+		}
+		return
+	}
+	if limit <= 0 {
+		if !p.unlimited {
+			p.unlimited = true
+			logger.Warnf(ctx, "Redis has no maxmemory; the memory monitor has no limit to compare with")
+		}
+		return
+	}
+	percent := used * 100 / limit
+	metrics.RedisMemoryUsedPercent.Set(percent)
+
+	active := p.active.Load()
+	next := p.transition(ctx, logger, active, percent)
+	if next != active {
+		p.set(next)
+	}
+	p.signal(ctx, logger, next, next != active)
+	if next != active || !p.announced {
+		p.announce(ctx, logger, next, used, limit)
+	}
+}
+
+// transition returns whether the server is under pressure at percent of
+// maxmemory, given whether it was.
+func (p *memoryPressure) transition(ctx context.Context, logger *log.Logger, active bool, percent int64) bool {
+	switch {
+	case !active && percent >= p.high:
+		logger.Warnf(ctx, "Redis memory at %d%% of maxmemory; draining and signalling producers to back off", percent)
+		return true
+	case active && percent < p.low:
+		logger.Infof(ctx, "Redis memory back to %d%% of maxmemory; memory pressure cleared", percent)
+		return false
+	}
+	return active
+}
+
+func (p *memoryPressure) set(active bool) {
+	p.active.Store(active)
+	var flag int64
+	if active {
+		flag = 1
+	}
+	metrics.RedisMemoryPressure.Set(flag)
+	p.drain.set(drainMemory, active)
+}
+
+// signal keeps the pressure key set while active, refreshing its TTL on
+// every check, and deletes it when the pressure clears.
+func (p *memoryPressure) signal(ctx context.Context, logger *log.Logger, active, changed bool) {
+	if !p.signalKey || (!active && !changed) {
+		return
+	}
+	if err := p.monitor.SignalMemoryPressure(ctx, active, keyTTLChecks*p.interval); err != nil && ctx.Err() == nil {
+		logger.Errorf(ctx, "Failed to signal Redis memory pressure: %v", err)
+	}
+}
+
+// announce publishes the state to the control topic, retained so producers
+// that connect later see it. A failed publish is retried at the next check.
+func (p *memoryPressure) announce(ctx context.Context, logger *log.Logger, active bool, used, limit int64) {
+	if p.control == nil {
+		return
+	}
+	payload := fmt.Appendf(nil, `{"memory_pressure":%t,"used_memory":%d,"maxmemory":%d}`, active, used, limit)
+	if err := p.control.PublishTo(ctx, payload, p.topic, p.qos, true); err != nil {
+		p.announced = false
+		if ctx.Err() == nil {
+			logger.Errorf(ctx, "Failed to publish memory pressure to %s: %v", p.topic, err)
+		}
+		return
+	}
+	p.announced = true
+}
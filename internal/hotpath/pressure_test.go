@@ -0,0 +1,179 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// memoryRedis is a batchSizerRedis that reports used, out of limit, bytes
+// and records the pressure signals it is sent.
+type memoryRedis struct {
+	batchSizerRedis
+	err     error
+	signals []bool
+	used    int64
+	limit   int64
+}
+
+func (m *memoryRedis) MemoryUsage(context.Context) (used, limit int64, err error) {
+	return m.used, m.limit, m.err
+}
+
+func (m *memoryRedis) SignalMemoryPressure(_ context.Context, on bool, _ time.Duration) error {
+	m.signals = append(m.signals, on)
+	return nil
+}
+
+// controlPublisher records the control messages published to it.
+type controlPublisher struct {
+	mockPublisher
+	fail     error
+	messages []string
+}
+
+func (m *controlPublisher) PublishTo(
+	_ context.Context, payload message.Payload, topic string, _ byte, retain bool,
+) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	if topic != "control" || !retain {
+		return errors.New("want a retained publish to the control topic")
+	}
+	m.messages = append(m.messages, string(payload))
+	return nil
+}
+
+func pressureHotPath(t *testing.T) (*HotPath, *memoryRedis, *controlPublisher) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Redis.MemoryHighPercent = 90
+	cfg.Redis.MemoryLowPercent = 80
+	cfg.Redis.MemoryCheckInterval = time.Second
+	cfg.Redis.MemoryPressureKey = "memory-pressure"
+	cfg.Redis.BatchSize = 100
+	cfg.Redis.BatchSizeMax = 1000
+	cfg.MQTT.ControlTopic = "control"
+	rdb := &memoryRedis{limit: 100}
+	pub := &controlPublisher{}
+	hp, err := New(rdb, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, rdb, pub
+}
+
+func TestMemoryPressure_Hysteresis(t *testing.T) {
+	hp, rdb, pub := pressureHotPath(t)
+	var states []bool
+	for _, used := range []int64{50, 92, 85, 79, 85} {
+		rdb.used = used
+		hp.pressure.check(t.Context(), hp.log)
+		states = append(states, hp.pressure.draining())
+	}
+	if want := []bool{false, true, true, false, false}; !slices.Equal(states, want) {
+		t.Errorf("draining = %v; want %v across the thresholds", states, want)
+	}
+	if want := []bool{true, true, false}; !slices.Equal(rdb.signals, want) {
+		t.Errorf("signals = %v; want the key set twice under pressure, then deleted", rdb.signals)
+	}
+	if want := []int{1000, 100}; !slices.Equal(rdb.sizes, want) {
+		t.Errorf("read sizes = %v; want %v", rdb.sizes, want)
+	}
+	want := []string{
+		`{"memory_pressure":false,"used_memory":50,"maxmemory":100}`,
+		`{"memory_pressure":true,"used_memory":92,"maxmemory":100}`,
+		`{"memory_pressure":false,"used_memory":79,"maxmemory":100}`,
+	}
+	if !slices.Equal(pub.messages, want) {
+		t.Errorf("control messages = %q; want %q", pub.messages, want)
+	}
+	if got := metrics.RedisMemoryUsedPercent.Value(); got != 85 {
+		t.Errorf("RedisMemoryUsedPercent = %d; want 85", got)
+	}
+}
+
+func TestMemoryPressure_RetriesAnnouncement(t *testing.T) {
+	hp, rdb, pub := pressureHotPath(t)
+	rdb.used = 95
+	pub.fail = errors.New("broker unavailable")
+	hp.pressure.check(t.Context(), hp.log)
+	pub.fail = nil
+	hp.pressure.check(t.Context(), hp.log)
+	hp.pressure.check(t.Context(), hp.log)
+	if len(pub.messages) != 1 || !hp.pressure.draining() {
+		t.Errorf("control messages = %q; want the failed one published once at the next check", pub.messages)
+	}
+}
+
+func TestMemoryPressure_KeepsStateWithoutUsage(t *testing.T) {
+	hp, rdb, pub := pressureHotPath(t)
+	rdb.used = 95
+	hp.pressure.check(t.Context(), hp.log)
+
+	rdb.err = errors.New("connection refused")
+	hp.pressure.check(t.Context(), hp.log)
+	rdb.err, rdb.limit = nil, 0
+	hp.pressure.check(t.Context(), hp.log)
+	if !hp.pressure.draining() || len(pub.messages) != 1 {
+		t.Errorf("draining = %v, %d messages; want the pressure kept", hp.pressure.draining(), len(pub.messages))
+	}
+}
+
+func TestMemoryPressure_DrainsAdaptiveReads(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.AdaptiveBatch = true
+	cfg.Redis.BatchSize, cfg.Redis.BatchSizeMin, cfg.Redis.BatchSizeMax = 1000, 100, 2000
+	cfg.Redis.BatchLatencyTarget = 100 * time.Millisecond
+	cfg.Redis.MemoryHighPercent = 90
+	cfg.Redis.MemoryLowPercent = 80
+	cfg.Redis.MemoryCheckInterval = time.Second
+	rdb := &memoryRedis{used: 95, limit: 100}
+	hp, err := New(rdb, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.pressure.drain != nil {
+		t.Fatal("drain != nil; want the adaptive sizing to drain instead")
+	}
+	hp.pressure.check(t.Context(), hp.log)
+	if len(rdb.signals) != 0 {
+		t.Errorf("signals = %v; want none without a pressure key", rdb.signals)
+	}
+	hp.readSize.drain()
+	if got := rdb.size(); got != cfg.Redis.BatchSizeMax {
+		t.Errorf("read size = %d; want REDIS_BATCH_SIZE_MAX %d while draining", got, cfg.Redis.BatchSizeMax)
+	}
+}
+
+func TestNew_MemoryPressureRequirements(t *testing.T) {
+	cfg := testConfig()
+	cfg.Redis.MemoryHighPercent = 90
+	cfg.Redis.MemoryLowPercent = 80
+	cfg.Redis.MemoryCheckInterval = time.Second
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without MemoryUsage")
+	}
+	cfg.MQTT.ControlTopic = "control"
+	if _, err := New(&memoryRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a control topic without PublishTo")
+	}
+
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.pressure != nil || hp.pressure.draining() {
+		t.Error("pressure != nil; want no monitor without REDIS_MEMORY_HIGH_PERCENT")
+	}
+}
@@ -0,0 +1,55 @@
+package hotpath
+
+// fieldProjection filters the top-level fields of each published object.
+// Names are matched after severity mapping, so "structured_data" drops the
+// whole flattened block and "raw" drops the original line. A nil projection
+// keeps every field.
+type fieldProjection struct {
+	fields map[string]struct{}
+	allow  bool
+}
+
+// newFieldProjection returns nil when neither list is set; config
+// validation guarantees at most one of them is non-empty.
+func newFieldProjection(allow, deny []string) *fieldProjection {
+	names, isAllow := deny, false
+	if len(allow) > 0 {
+		names, isAllow = allow, true
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	fields := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		fields[name] = struct{}{}
+	}
+	return &fieldProjection{fields: fields, allow: isAllow}
+}
+
+func (p *fieldProjection) keep(name []byte) bool {
+	if p == nil {
+		return true
+	}
+	_, listed := p.fields[string(name)]
+	return listed == p.allow
+}
+
+// projectionStats accumulates what a projection removed from one batch,
+// what the timestamp normalizer did to it and the structured data keys it
+// renamed, so the shared counters are touched once per publish, not once
+// per field. Sizes are pre-compression JSON bytes.
+type projectionStats struct {
+	bytes        int64
+	fields       int64
+	tsNormalized int64
+	tsUnparsed   int64
+	tsSkewed     int64
+	sdRenamed    int64
+	objWrapped   int64
+}
+
+// drop accounts for a removed `"name":value,` member.
+func (s *projectionStats) drop(nameLen, valueLen int) {
+	s.bytes += int64(nameLen + valueLen + 4)
+	s.fields++
+}
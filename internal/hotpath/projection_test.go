@@ -0,0 +1,139 @@
+package hotpath
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+const projectionObject = `{"hostname":"FW01","severity":3,"message":"denied",` +
+	`"structured_data":{"KV@1":{"srcip":"1.2.3.4"}}}`
+
+func TestFieldProjection_Keep(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		allow []string
+		deny  []string
+		want  bool
+	}{
+		{name: "no lists keeps all", field: "raw", want: true},
+		{name: "denied field dropped", deny: []string{"raw"}, field: "raw", want: false},
+		{name: "unlisted field kept by denylist", deny: []string{"raw"}, field: "hostname", want: true},
+		{name: "allowed field kept", allow: []string{"hostname"}, field: "hostname", want: true},
+		{name: "unlisted field dropped by allowlist", allow: []string{"hostname"}, field: "raw", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFieldProjection(tt.allow, tt.deny)
+			if got := p.keep([]byte(tt.field)); got != tt.want {
+				t.Errorf("keep(%q) = %v; want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFieldProjection_Empty(t *testing.T) {
+	if p := newFieldProjection(nil, nil); p != nil {
+		t.Errorf("newFieldProjection(nil, nil) = %+v; want nil", p)
+	}
+}
+
+func TestBuildPayload_Projection(t *testing.T) {
+	tests := []struct {
+		msg        message.Redis
+		name       string
+		wantJSON   string
+		allow      []string
+		deny       []string
+		wantFields int64
+	}{
+		{
+			name:       "deny raw once object is parsed",
+			deny:       []string{"raw"},
+			msg:        message.Redis{ID: testMsgID1, Stream: testStreamSimp, Object: `{"hostname":"h1"}`, Raw: "line"},
+			wantJSON:   `{"hostname":"h1"}`,
+			wantFields: 1,
+		},
+		{
+			name:     "deny raw keeps raw without object",
+			deny:     []string{"raw"},
+			msg:      message.Redis{ID: testMsgID1, Stream: testStreamSimp, Raw: "line"},
+			wantJSON: `{"raw":"line"}`,
+		},
+		{
+			name:       "deny structured_data drops flattened block",
+			deny:       []string{"structured_data", "message"},
+			msg:        message.Redis{ID: testMsgID1, Stream: testStreamSimp, Object: projectionObject, Raw: "r"},
+			wantJSON:   `{"hostname":"FW01","severity":"ERROR","raw":"r"}`,
+			wantFields: 2,
+		},
+		{
+			name:       "allowlist keeps only listed fields",
+			allow:      []string{"hostname", "severity"},
+			msg:        message.Redis{ID: testMsgID1, Stream: testStreamSimp, Object: projectionObject, Raw: "r"},
+			wantJSON:   `{"hostname":"FW01","severity":"ERROR"}`,
+			wantFields: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Pipeline.FieldAllowlist = tt.allow
+			cfg.Pipeline.FieldDenylist = tt.deny
+			hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer closeHotPath(t, hp)
+
+			var stats projectionStats
+			_, _, got := parseLine(t, hp.buildProjectedPayload(jsonfast.New(512), &tt.msg, &stats))
+			if !jsonEqual([]byte(got), []byte(tt.wantJSON)) {
+				t.Errorf("payload = %s; want %s", got, tt.wantJSON)
+			}
+			if stats.fields != tt.wantFields {
+				t.Errorf("dropped fields = %d; want %d", stats.fields, tt.wantFields)
+			}
+			if (stats.bytes > 0) != (tt.wantFields > 0) {
+				t.Errorf("saved bytes = %d with %d dropped fields", stats.bytes, stats.fields)
+			}
+		})
+	}
+}
+
+func TestPublishBatch_ProjectionMetrics(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.FieldDenylist = []string{"raw"}
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	fieldsBefore := metrics.ProjectionFieldsDropped.Value()
+	bytesBefore := metrics.ProjectionBytesSaved.Value()
+
+	batch := &message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: testObjectKV, Raw: "0123456789"},
+		{ID: "2-0", Stream: testStreamSimp, Object: testObjectKV, Raw: "0123456789"},
+	}}
+	var compressed []byte
+	publish := func(context.Context, message.Payload) error { return nil }
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	if got := metrics.ProjectionFieldsDropped.Value() - fieldsBefore; got != 2 {
+		t.Errorf("ProjectionFieldsDropped delta = %d; want 2", got)
+	}
+	// Each dropped member is `"raw":"0123456789",` = 19 bytes.
+	if got := metrics.ProjectionBytesSaved.Value() - bytesBefore; got != 38 {
+		t.Errorf("ProjectionBytesSaved delta = %d; want 38", got)
+	}
+}
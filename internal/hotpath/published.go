@@ -0,0 +1,147 @@
+package hotpath
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// publishReserver is implemented by *redis.Client;
+// PIPELINE_PUBLISH_DEDUP=redis requires it.
+type publishReserver interface {
+	ReservePublish(ctx context.Context, msgs []message.Redis, window time.Duration) ([]bool, error)
+	ReleasePublish(ctx context.Context, stream string, ids []string) error
+}
+
+// publishDedup applies PIPELINE_PUBLISH_DEDUP: publish workers reserve
+// each entry by stream and ID just before publishing it, and leave pending
+// the entries already reserved. Those are the entries the claim loop hands
+// out while their first publisher is still at work; that publisher's ACK
+// acknowledges them. The memory mode keeps the reservations in two
+// generations, swapped every window, so an entry stays reserved for one to
+// two windows; the redis mode keeps one expiring key per entry.
+type publishDedup struct {
+	shared   publishReserver // nil in memory mode
+	current  map[heldEntry]struct{}
+	previous map[heldEntry]struct{}
+	rotated  time.Time
+	window   time.Duration
+	mu       sync.Mutex
+}
+
+// newPublishDedup returns nil when PIPELINE_PUBLISH_DEDUP is off.
+func newPublishDedup(redisClient redis.StreamClient, cfg *config.PipelineConfig) *publishDedup {
+	switch cfg.PublishDedup {
+	case config.PublishDedupMemory:
+		return &publishDedup{
+			current:  make(map[heldEntry]struct{}),
+			previous: make(map[heldEntry]struct{}),
+			rotated:  time.Now(),
+			window:   cfg.PublishDedupWindow,
+		}
+	case config.PublishDedupRedis:
+		shared, _ := redisClient.(publishReserver)
+		return &publishDedup{shared: shared, window: cfg.PublishDedupWindow}
+	default:
+		return nil
+	}
+}
+
+// reserve reports, for each of items, whether its publish was already
+// reserved within the window, and reserves the others.
+func (d *publishDedup) reserve(ctx context.Context, items []message.Redis, now time.Time) ([]bool, error) {
+	if d.shared != nil {
+		return d.shared.ReservePublish(ctx, items, d.window)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.rotated) >= d.window {
+		d.previous, d.current = d.current, d.previous
+		clear(d.current)
+		if now.Sub(d.rotated) >= 2*d.window {
+			clear(d.previous) // idle for a whole window: nothing is recent
+		}
+		d.rotated = now
+	}
+	reserved := make([]bool, len(items))
+	for i := range items {
+		key := heldEntry{stream: items[i].Stream, id: items[i].ID}
+		_, inCurrent := d.current[key]
+		_, inPrevious := d.previous[key]
+		reserved[i] = inCurrent || inPrevious
+		if !reserved[i] {
+			d.current[key] = struct{}{}
+		}
+	}
+	return reserved, nil
+}
+
+// release drops the reservations of ids of stream, so a NACKed entry is
+// published again on its next claim rather than once the window ends.
+func (d *publishDedup) release(ctx context.Context, stream string, ids []string) error {
+	if d == nil {
+		return nil
+	}
+	if d.shared != nil {
+		return d.shared.ReleasePublish(ctx, stream, ids)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range ids {
+		key := heldEntry{stream: stream, id: id}
+		delete(d.current, key)
+		delete(d.previous, key)
+	}
+	return nil
+}
+
+// dropPublished reserves the entries of batch just before they are
+// published and removes those another publish reserved within the window.
+// They stay pending and unacknowledged: the other publisher's ACK settles
+// them, and otherwise the claim loop brings them back once the window has
+// passed. When the reservation fails the batch is published whole: a
+// duplicate is cheaper than a lost entry.
+func (hp *HotPath) dropPublished(ctx context.Context, batch *message.Batch) {
+	if hp.published == nil || len(batch.Items) == 0 {
+		return
+	}
+	reserved, err := hp.published.reserve(ctx, batch.Items, time.Now())
+	if err != nil {
+		hp.log.Warnf(ctx, "Publish dedup failed, publishing %d messages unchecked: %v", len(batch.Items), err)
+		metrics.PublishDedupErrors.Add(1)
+		return
+	}
+	suppressed := 0
+	for _, r := range reserved {
+		if r {
+			suppressed++
+		}
+	}
+	if suppressed == 0 {
+		return
+	}
+	batch.Compact(reserved)
+	metrics.PublishDuplicatesSuppressed.Add(int64(suppressed))
+}
+
+// releasePublished releases the publish reservations of the NACKed ids of
+// stream left to the claim loop. When that fails they are published again
+// once the window has passed.
+func (hp *HotPath) releasePublished(parentCtx context.Context, stream string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(parentCtx, hp.ackTimeout)
+	err := hp.published.release(ctx, stream, ids)
+	cancel()
+	if err != nil {
+		hp.log.Warnf(parentCtx, "Failed to release the publish reservations of %d NACKed messages from stream %s: %v",
+			len(ids), stream, err)
+		metrics.PublishDedupErrors.Add(1)
+	}
+}
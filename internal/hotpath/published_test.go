@@ -0,0 +1,158 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// reserveRedis is a mockRedis that also implements publishReserver.
+type reserveRedis struct {
+	err error
+	mockRedis
+	released []string
+}
+
+func (m *reserveRedis) ReservePublish(context.Context, []message.Redis, time.Duration) ([]bool, error) {
+	return nil, m.err
+}
+
+func (m *reserveRedis) ReleasePublish(_ context.Context, _ string, ids []string) error {
+	m.released = append(m.released, ids...)
+	return nil
+}
+
+func publishDedupHotPath(t *testing.T, redisClient *reserveRedis, mode string) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.PublishDedup = mode
+	cfg.Pipeline.PublishDedupWindow = time.Minute
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func lazyBatch(stream string, ids ...string) message.Batch {
+	buf := message.NewBuffer(len(ids))
+	for _, id := range ids {
+		buf.Add(id, stream, map[string]any{"raw": id})
+	}
+	return message.NewLazyBatch(buf, &sync.Pool{})
+}
+
+func batchIDs(batch *message.Batch) []string {
+	ids := make([]string, 0, len(batch.Items))
+	for i := range batch.Items {
+		ids = append(ids, batch.Items[i].ID)
+	}
+	return ids
+}
+
+func TestPublishDedup_MemoryWindow(t *testing.T) {
+	d := newPublishDedup(&mockRedis{}, &config.PipelineConfig{
+		PublishDedup: config.PublishDedupMemory, PublishDedupWindow: time.Minute,
+	})
+	start := d.rotated
+	a := message.Redis{ID: "1-0", Stream: "fw"}
+	b := message.Redis{ID: "2-0", Stream: "fw"}
+	reserve := func(now time.Time, items ...message.Redis) []bool {
+		t.Helper()
+		reserved, err := d.reserve(t.Context(), items, now)
+		if err != nil {
+			t.Fatalf("reserve() error = %v", err)
+		}
+		return reserved
+	}
+
+	if got := reserve(start, a); !slices.Equal(got, []bool{false}) {
+		t.Errorf("first reservation = %v; want [false]", got)
+	}
+	if got := reserve(start.Add(90*time.Second), a, b); !slices.Equal(got, []bool{true, false}) {
+		t.Errorf("within two windows = %v; want a still reserved", got)
+	}
+	if got := reserve(start.Add(3*time.Minute), a); !slices.Equal(got, []bool{false}) {
+		t.Errorf("two windows later = %v; want a forgotten", got)
+	}
+	if err := d.release(t.Context(), "fw", []string{a.ID}); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if got := reserve(start.Add(3*time.Minute), a); !slices.Equal(got, []bool{false}) {
+		t.Errorf("after the release = %v; want a free", got)
+	}
+}
+
+func TestDropPublished_SuppressesReservedEntries(t *testing.T) {
+	hp := publishDedupHotPath(t, &reserveRedis{}, config.PublishDedupMemory)
+	before := metrics.PublishDuplicatesSuppressed.Value()
+
+	first := lazyBatch("fw", "1-0", "2-0")
+	hp.dropPublished(t.Context(), &first)
+	claimed := lazyBatch("fw", "2-0", "3-0")
+	hp.dropPublished(t.Context(), &claimed)
+
+	if got := batchIDs(&first); !slices.Equal(got, []string{"1-0", "2-0"}) {
+		t.Errorf("first publish = %v; want both entries", got)
+	}
+	if got := batchIDs(&claimed); !slices.Equal(got, []string{"3-0"}) {
+		t.Errorf("claimed publish = %v; want the reserved entry left out", got)
+	}
+	if got := metrics.PublishDuplicatesSuppressed.Value() - before; got != 1 {
+		t.Errorf("PublishDuplicatesSuppressed delta = %d; want 1", got)
+	}
+}
+
+func TestDropPublished_ErrorPublishesUnchecked(t *testing.T) {
+	hp := publishDedupHotPath(t, &reserveRedis{err: errors.New("redis down")}, config.PublishDedupRedis)
+	before := metrics.PublishDedupErrors.Value()
+
+	batch := lazyBatch("fw", "1-0", "2-0")
+	hp.dropPublished(t.Context(), &batch)
+
+	if len(batch.Items) != 2 {
+		t.Errorf("kept %d entries; want the whole batch", len(batch.Items))
+	}
+	if got := metrics.PublishDedupErrors.Value() - before; got != 1 {
+		t.Errorf("PublishDedupErrors delta = %d; want 1", got)
+	}
+}
+
+func TestAddACK_ReleasesRetriedNACKs(t *testing.T) {
+	redisClient := &reserveRedis{}
+	hp := publishDedupHotPath(t, redisClient, config.PublishDedupRedis)
+
+	p := getPendingACK()
+	defer putPendingACK(p)
+	hp.addACK(p, &message.AckMessage{Stream: "fw", IDs: []string{"1-0"}, Ack: true})
+	hp.addACK(p, &message.AckMessage{Stream: "fw", IDs: []string{"2-0", "3-0"}})
+	hp.flushACKs(t.Context(), "fw", p)
+
+	if !slices.Equal(redisClient.released, []string{"2-0", "3-0"}) {
+		t.Errorf("released = %v; want the NACKed entries only", redisClient.released)
+	}
+}
+
+func TestNew_PublishDedupRedisRequiresReserver(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.PublishDedup = config.PublishDedupRedis
+	cfg.Pipeline.PublishDedupWindow = time.Minute
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without ReservePublish")
+	}
+	cfg.Pipeline.PublishDedup = config.PublishDedupMemory
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v; want nil in memory mode", err)
+	}
+	closeHotPath(t, hp)
+}
@@ -0,0 +1,213 @@
+package hotpath
+
+import (
+	"context"
+	"expvar"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// streamQuotas applies PIPELINE_STREAM_QUOTAS. Every limited stream gets a
+// token bucket per limit, refilled at the limit per second and holding at
+// most one second of it. Publish workers share the buckets, so a stream's
+// quota holds however its entries are spread across them. A nil
+// streamQuotas lets every entry through.
+type streamQuotas struct {
+	streams  map[string]*streamQuota // nil for streams no pattern matches
+	policies []config.StreamQuota
+	mu       sync.Mutex
+}
+
+type streamQuota struct {
+	msgs        *tokenBucket // nil when the limit is off
+	bytes       *tokenBucket
+	utilization *expvar.Float
+	action      string
+}
+
+func newStreamQuotas(cfg *config.PipelineConfig) *streamQuotas {
+	if len(cfg.StreamQuotas) == 0 {
+		return nil
+	}
+	q := &streamQuotas{
+		streams:  make(map[string]*streamQuota),
+		policies: make([]config.StreamQuota, 0, len(cfg.StreamQuotas)),
+	}
+	for _, item := range cfg.StreamQuotas {
+		quota, _ := config.ParseStreamQuota(item) // checked by config.Validate
+		q.policies = append(q.policies, quota)
+	}
+	return q
+}
+
+// quota returns the quota of stream, refilled up to now, or nil when it is
+// unlimited. The first pattern matching the stream is cached with it.
+func (q *streamQuotas) quota(stream string, now time.Time) *streamQuota {
+	sq, ok := q.streams[stream]
+	if !ok {
+		for _, p := range q.policies {
+			if match, _ := path.Match(p.Pattern, stream); match {
+				sq = &streamQuota{
+					msgs:        newTokenBucket(p.Messages, now),
+					bytes:       newTokenBucket(p.Bytes, now),
+					utilization: new(expvar.Float),
+					action:      p.Action,
+				}
+				metrics.StreamQuotaUtilization.Set(stream, sq.utilization)
+				break
+			}
+		}
+		q.streams[stream] = sq
+	}
+	if sq != nil {
+		sq.msgs.refill(now)
+		sq.bytes.refill(now)
+	}
+	return sq
+}
+
+// lookup returns the quota of each of items, or nil when none is limited.
+// Entries of a batch mostly share a stream, so the quota is looked up once
+// per run of them.
+func (q *streamQuotas) lookup(items []message.Redis, now time.Time) []*streamQuota {
+	var quotas []*streamQuota
+	var sq *streamQuota
+	for i := range items {
+		if i == 0 || items[i].Stream != items[i-1].Stream {
+			sq = q.quota(items[i].Stream, now)
+		}
+		if sq == nil {
+			continue
+		}
+		if quotas == nil {
+			quotas = make([]*streamQuota, len(items))
+		}
+		quotas[i] = sq
+	}
+	return quotas
+}
+
+// take charges the entries of batch to their streams' quotas at now. It
+// returns how long the batch must wait for its delay quotas to be out of
+// debt, and the entries over a skip quota, or nil when there are none.
+func (q *streamQuotas) take(batch *message.Batch, now time.Time) (time.Duration, []bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	quotas := q.lookup(batch.Items, now)
+	if quotas == nil {
+		return 0, nil
+	}
+	batch.Decode() // byte limits count the bodies
+
+	var skip []bool
+	for i, sq := range quotas {
+		if sq == nil {
+			continue
+		}
+		size := float64(len(batch.Items[i].Object) + len(batch.Items[i].Raw))
+		if sq.action == config.QuotaSkip && (!sq.msgs.has(1) || !sq.bytes.has(size)) {
+			if skip == nil {
+				skip = make([]bool, len(quotas))
+			}
+			skip[i] = true
+			continue
+		}
+		sq.msgs.take(1)
+		sq.bytes.take(size)
+	}
+
+	var wait time.Duration
+	for i, sq := range quotas {
+		if sq == nil || (i > 0 && quotas[i-1] == sq) {
+			continue
+		}
+		sq.utilization.Set(max(sq.msgs.used(), sq.bytes.used()))
+		if sq.action == config.QuotaDelay {
+			wait = max(wait, sq.msgs.debt(), sq.bytes.debt())
+		}
+	}
+	return wait, skip
+}
+
+// applyQuotas charges batch to the quotas of its streams before it is
+// published: it waits out the debt of the delay quotas and removes the
+// entries over a skip quota, which stay pending until the claim loop brings
+// them back. It returns ctx's error if ctx is done while waiting.
+func (hp *HotPath) applyQuotas(ctx context.Context, batch *message.Batch) error {
+	if hp.quotas == nil {
+		return nil
+	}
+	wait, skip := hp.quotas.take(batch, time.Now())
+	if skip != nil {
+		skipped := 0
+		for _, s := range skip {
+			if s {
+				skipped++
+			}
+		}
+		batch.Compact(skip)
+		metrics.QuotaSkipped.Add(int64(skipped))
+	}
+	if wait <= 0 {
+		return nil
+	}
+	metrics.QuotaDelayMs.Add(wait.Milliseconds())
+	return sleepCtx(ctx, wait)
+}
+
+// tokenBucket holds up to rate tokens and gains rate per second. Delay
+// quotas take tokens it does not have, leaving it in debt. A nil bucket is
+// an unlimited one.
+type tokenBucket struct {
+	last   time.Time
+	tokens float64
+	rate   float64
+}
+
+func newTokenBucket(rate int, now time.Time) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{last: now, tokens: float64(rate), rate: float64(rate)}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b == nil || !now.After(b.last) {
+		return
+	}
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// has reports whether n tokens can be taken without debt. An entry larger
+// than the whole bucket only needs a full one, so it is not skipped forever.
+func (b *tokenBucket) has(n float64) bool {
+	return b == nil || b.tokens >= min(n, b.rate)
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b != nil {
+		b.tokens -= n
+	}
+}
+
+// debt returns how long the bucket takes to be out of debt.
+func (b *tokenBucket) debt() time.Duration {
+	if b == nil || b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// used returns the share of the bucket taken, above 1 while in debt.
+func (b *tokenBucket) used() float64 {
+	if b == nil {
+		return 0
+	}
+	return 1 - b.tokens/b.rate
+}
@@ -0,0 +1,112 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func quotaBatch(stream string, n int, body string) message.Batch {
+	items := make([]message.Redis, n)
+	for i := range items {
+		items[i] = message.Redis{ID: strconv.Itoa(i+1) + "-0", Stream: stream, Raw: body}
+	}
+	return message.Batch{Items: items}
+}
+
+func TestStreamQuotas_DelayWaitsOutTheDebt(t *testing.T) {
+	q := newStreamQuotas(&config.PipelineConfig{StreamQuotas: []string{"noisy-*=delay:10:0"}})
+	now := time.Now()
+
+	batch := quotaBatch("noisy-web", 10, "x")
+	if wait, skip := q.take(&batch, now); wait != 0 || skip != nil {
+		t.Fatalf("take() = %v, %v; want no wait within the quota", wait, skip)
+	}
+	batch = quotaBatch("noisy-web", 5, "x")
+	wait, skip := q.take(&batch, now)
+	if wait != 500*time.Millisecond || skip != nil {
+		t.Errorf("take() = %v, %v; want 500ms for 5 entries over 10/s", wait, skip)
+	}
+	if got := metrics.StreamQuotaUtilization.Get("noisy-web").String(); got != "1.5" {
+		t.Errorf("utilization = %s; want 1.5 while in debt", got)
+	}
+
+	batch = quotaBatch("noisy-web", 1, "x")
+	if wait, _ := q.take(&batch, now.Add(time.Second)); wait != 0 {
+		t.Errorf("take() after the debt = %v; want no wait", wait)
+	}
+}
+
+func TestStreamQuotas_SkipLeavesTheExcess(t *testing.T) {
+	q := newStreamQuotas(&config.PipelineConfig{StreamQuotas: []string{"audit=skip:0:10"}})
+	now := time.Now()
+
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "audit", Raw: "12345"},
+		{ID: "2-0", Stream: "app", Raw: "unlimited stream"},
+		{ID: "3-0", Stream: "audit", Raw: "123456"},
+		{ID: "4-0", Stream: "audit", Raw: "12345"},
+	}}
+	wait, skip := q.take(&batch, now)
+	if wait != 0 || !slices.Equal(skip, []bool{false, false, true, false}) {
+		t.Errorf("take() = %v, %v; want the entry over 10 bytes skipped, no wait", wait, skip)
+	}
+
+	oversize := quotaBatch("audit", 1, strings.Repeat("x", 64))
+	if _, skip := q.take(&oversize, now.Add(time.Second)); skip != nil {
+		t.Errorf("take() = %v; want an entry larger than the quota let through on a full bucket", skip)
+	}
+}
+
+func TestApplyQuotas(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.StreamQuotas = []string{"noisy=skip:2:0", "slow=delay:1:0"}
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	before := metrics.QuotaSkipped.Value()
+	batch := quotaBatch("noisy", 5, "x")
+	if err := hp.applyQuotas(t.Context(), &batch); err != nil {
+		t.Fatalf("applyQuotas() error = %v", err)
+	}
+	if len(batch.Items) != 2 {
+		t.Errorf("len(Items) = %d; want the 2 entries within the quota", len(batch.Items))
+	}
+	if got := metrics.QuotaSkipped.Value() - before; got != 3 {
+		t.Errorf("QuotaSkipped delta = %d; want 3", got)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	batch = quotaBatch("slow", 3, "x")
+	if err := hp.applyQuotas(ctx, &batch); !errors.Is(err, context.Canceled) {
+		t.Errorf("applyQuotas() = %v; want context.Canceled while waiting", err)
+	}
+}
+
+func TestApplyQuotas_Unlimited(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.quotas != nil {
+		t.Fatal("quotas != nil; want none without PIPELINE_STREAM_QUOTAS")
+	}
+	batch := quotaBatch(testStreamSimp, 3, "x")
+	if err := hp.applyQuotas(t.Context(), &batch); err != nil || len(batch.Items) != 3 {
+		t.Errorf("applyQuotas() = %v, %d entries; want the batch untouched", err, len(batch.Items))
+	}
+}
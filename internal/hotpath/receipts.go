@@ -0,0 +1,221 @@
+package hotpath
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// receiptWriter is implemented by *redis.Client; REDIS_RECEIPTS_STREAM
+// requires it.
+type receiptWriter interface {
+	WriteReceipts(ctx context.Context, receipts []message.Receipt) error
+}
+
+func validateReceipts(redisClient redis.StreamClient, cfg *config.Config) error {
+	if cfg.Redis.ReceiptsStream == "" {
+		return nil
+	}
+	if _, ok := redisClient.(receiptWriter); !ok {
+		return errors.New("hotpath: receipts stream needs a redis client that supports writing receipts")
+	}
+	return nil
+}
+
+// receiptLog queues receipts for a background writer that appends them to
+// the receipts stream in pipelined batches. Recording never blocks: when
+// the queue is full the receipt is dropped and counted, so a slow or
+// unavailable Redis costs receipts, never throughput. A failed write keeps
+// the batch and retries it on the next flush, so a written receipt may
+// appear more than once. A nil log records nothing.
+type receiptLog struct {
+	w             receiptWriter
+	log           *log.Logger
+	ch            chan message.Receipt
+	done          chan struct{}
+	cancel        context.CancelFunc
+	writeTimeout  time.Duration
+	flushInterval time.Duration
+	batchSize     int
+}
+
+func newReceiptLog(redisClient redis.StreamClient, cfg *config.Config, logger *log.Logger) *receiptLog {
+	if cfg.Redis.ReceiptsStream == "" {
+		return nil
+	}
+	w, _ := redisClient.(receiptWriter)
+	return &receiptLog{
+		w:             w,
+		log:           logger,
+		ch:            make(chan message.Receipt, cfg.Pipeline.ReceiptQueueCapacity),
+		done:          make(chan struct{}),
+		writeTimeout:  cfg.Pipeline.AckTimeout,
+		flushInterval: cfg.Pipeline.ReceiptFlushInterval,
+		batchSize:     cfg.Pipeline.ReceiptBatchSize,
+	}
+}
+
+// start runs the writer until close; ctx bounds its Redis writes.
+func (l *receiptLog) start(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	ctx, l.cancel = context.WithCancel(ctx)
+	go l.run(ctx)
+}
+
+// close stops accepting receipts and waits up to timeout for the writer to
+// flush what is queued; whatever is still unwritten after that is dropped.
+// Every recorder must have returned before close is called.
+func (l *receiptLog) close(ctx context.Context, timeout time.Duration) {
+	if l == nil || l.cancel == nil {
+		return
+	}
+	close(l.ch)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-l.done:
+	case <-timer.C:
+		l.log.Warnf(ctx, "Receipt flush timeout %s exceeded, dropping unwritten receipts", timeout)
+		l.cancel()
+		<-l.done
+	}
+	l.cancel()
+}
+
+func (l *receiptLog) record(r message.Receipt) {
+	select {
+	case l.ch <- r:
+	default:
+		metrics.ReceiptsDropped.Add(1)
+	}
+}
+
+// recordAck records the receiver's verdict on ack.IDs.
+func (l *receiptLog) recordAck(ack message.AckMessage) {
+	if l == nil || len(ack.IDs) == 0 {
+		return
+	}
+	event := message.ReceiptAcked
+	if !ack.Ack {
+		event = message.ReceiptNacked
+	}
+	l.record(message.Receipt{At: time.Now(), Event: event, Stream: ack.Stream, IDs: ack.IDs})
+}
+
+// recordPublished records the entries whose records make up the published
+// NDJSON in data, one receipt per run of same-stream entries. Every record
+// starts with the "<id>\t<stream>\t" header; continuation chunks of a split
+// record are skipped so each entry is recorded once.
+func (l *receiptLog) recordPublished(data []byte, spans []recordSpan) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	var cur message.Receipt
+	for _, s := range spans {
+		if s.messages == 0 {
+			continue
+		}
+		id, stream := recordHeader(data[s.start:s.end])
+		if cur.IDs != nil && cur.Stream != string(stream) {
+			l.record(cur)
+			cur = message.Receipt{}
+		}
+		if cur.IDs == nil {
+			cur = message.Receipt{At: now, Event: message.ReceiptPublished, Stream: string(stream)}
+		}
+		cur.IDs = append(cur.IDs, string(id))
+	}
+	if cur.IDs != nil {
+		l.record(cur)
+	}
+}
+
+// recordLines is recordPublished for a payload built without spans: each
+// line of data is one entry's record.
+func (l *receiptLog) recordLines(data []byte) {
+	if l == nil {
+		return
+	}
+	l.recordPublished(data, lineSpans(data))
+}
+
+// lineSpans returns one span per line of data, each one entry's record.
+func lineSpans(data []byte) []recordSpan {
+	spans := make([]recordSpan, 0, bytes.Count(data, []byte{'\n'})+1)
+	start := 0
+	for line := range bytes.Lines(data) {
+		spans = append(spans, recordSpan{start: start, end: start + len(line), messages: 1})
+		start += len(line)
+	}
+	return spans
+}
+
+// recordHeader splits the "<id>\t<stream>\t" prefix off a record.
+func recordHeader(record []byte) (id, stream []byte) {
+	id, rest, _ := bytes.Cut(record, []byte{'\t'})
+	stream, _, _ = bytes.Cut(rest, []byte{'\t'})
+	return id, stream
+}
+
+func (l *receiptLog) run(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	pending := make([]message.Receipt, 0, l.batchSize)
+	for {
+		// A full batch that failed to write is retried before more
+		// receipts are taken, so the queue fills and new ones are dropped.
+		in := l.ch
+		if len(pending) >= l.batchSize {
+			in = nil
+		}
+		select {
+		case r, ok := <-in:
+			if !ok {
+				if len(l.flush(ctx, pending)) > 0 {
+					metrics.ReceiptsDropped.Add(int64(len(pending)))
+				}
+				return
+			}
+			pending = append(pending, r)
+			if len(pending) >= l.batchSize {
+				pending = l.flush(ctx, pending)
+			}
+		case <-ticker.C:
+			pending = l.flush(ctx, pending)
+		case <-ctx.Done():
+			metrics.ReceiptsDropped.Add(int64(len(pending)))
+			return
+		}
+	}
+}
+
+// flush writes pending and returns what is left to write: nothing on
+// success, pending itself on failure.
+func (l *receiptLog) flush(ctx context.Context, pending []message.Receipt) []message.Receipt {
+	if len(pending) == 0 {
+		return pending
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, l.writeTimeout)
+	err := l.w.WriteReceipts(writeCtx, pending)
+	cancel()
+	if err != nil {
+		l.log.Errorf(ctx, "Failed to write %d receipts, will retry: %v", len(pending), err)
+		metrics.ReceiptWriteErrors.Add(1)
+		return pending
+	}
+	metrics.ReceiptsWritten.Add(int64(len(pending)))
+	clear(pending) // drop ID references
+	return pending[:0]
+}
@@ -0,0 +1,218 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// receiptRedis is a mockRedis that also implements receiptWriter and keeps
+// every receipt it was asked to write.
+type receiptRedis struct {
+	mockRedis
+	writeFn  func(ctx context.Context, receipts []message.Receipt) error
+	receipts []message.Receipt
+	mu       sync.Mutex
+}
+
+func (m *receiptRedis) WriteReceipts(ctx context.Context, receipts []message.Receipt) error {
+	if m.writeFn != nil {
+		if err := m.writeFn(ctx, receipts); err != nil {
+			return err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.receipts = append(m.receipts, slices.Clone(receipts)...)
+	return nil
+}
+
+func (m *receiptRedis) written() []message.Receipt {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.receipts)
+}
+
+func receiptConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Redis.ReceiptsStream = "receipts"
+	cfg.Pipeline.ReceiptQueueCapacity = 16
+	cfg.Pipeline.ReceiptBatchSize = 4
+	cfg.Pipeline.ReceiptFlushInterval = 10 * time.Millisecond
+	return cfg
+}
+
+func receiptHotPath(t *testing.T, redisClient *receiptRedis, cfg *config.Config, pub *mockPublisher) *HotPath {
+	t.Helper()
+	hp, err := New(redisClient, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+// queued drains the receipts recorded so far without a writer running.
+func queued(l *receiptLog) []message.Receipt {
+	var out []message.Receipt
+	for {
+		select {
+		case r := <-l.ch:
+			out = append(out, r)
+		default:
+			return out
+		}
+	}
+}
+
+func publishReceipts(t *testing.T, hp *HotPath, items []message.Redis) {
+	t.Helper()
+	var compressed []byte
+	nop := func(context.Context, message.Payload) error { return nil }
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &message.Batch{Items: items},
+		jsonfast.NewBatchWriter(512), &compressed, nop)
+}
+
+func TestNew_ReceiptsRequireWriter(t *testing.T) {
+	if _, err := New(&mockRedis{}, &mockPublisher{}, receiptConfig(), log.New()); err == nil {
+		t.Error("New() error = nil; want error for a redis client without WriteReceipts")
+	}
+}
+
+func TestPublishBatch_RecordsPublishedReceipts(t *testing.T) {
+	hp := receiptHotPath(t, &receiptRedis{}, receiptConfig(), &mockPublisher{})
+
+	publishReceipts(t, hp, []message.Redis{
+		{ID: "1-0", Stream: "s1", Raw: "a"},
+		{ID: "2-0", Stream: "s1", Raw: "b"},
+		{ID: "3-0", Stream: "s2", Raw: "c"},
+		{ID: "4-0", Stream: "s2"}, // empty, skipped
+	})
+
+	got := queued(hp.receipts)
+	if len(got) != 2 {
+		t.Fatalf("receipts = %+v; want one per stream run", got)
+	}
+	if first := got[0]; first.Event != message.ReceiptPublished || first.Stream != "s1" ||
+		!slices.Equal(first.IDs, []string{"1-0", "2-0"}) {
+		t.Errorf("first receipt = %+v; want published s1 [1-0 2-0]", first)
+	}
+	if got[1].Stream != "s2" || !slices.Equal(got[1].IDs, []string{"3-0"}) {
+		t.Errorf("second receipt = %+v; want s2 [3-0]", got[1])
+	}
+}
+
+func TestPublishBatch_SplitRecordGetsOneReceipt(t *testing.T) {
+	cfg := receiptConfig()
+	cfg.Pipeline.MaxPayloadBytes = testMaxPayload
+	cfg.Pipeline.OversizePolicy = config.OversizeSplit
+	hp := receiptHotPath(t, &receiptRedis{}, cfg, &mockPublisher{})
+
+	publishReceipts(t, hp, []message.Redis{{ID: testMsgID1, Stream: testStreamSimp, Raw: noise(1, 3*testMaxPayload)}})
+
+	got := queued(hp.receipts)
+	if len(got) != 1 || !slices.Equal(got[0].IDs, []string{testMsgID1}) {
+		t.Errorf("receipts = %+v; want the split entry recorded once", got)
+	}
+}
+
+func TestPublishBatch_FailedPublishRecordsNothing(t *testing.T) {
+	hp := receiptHotPath(t, &receiptRedis{}, receiptConfig(), &mockPublisher{})
+
+	var compressed []byte
+	fail := func(context.Context, message.Payload) error { return errors.New("broker down") }
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(),
+		&message.Batch{Items: []message.Redis{{ID: testMsgID1, Stream: testStreamSimp, Raw: "a"}}},
+		jsonfast.NewBatchWriter(512), &compressed, fail)
+
+	if got := queued(hp.receipts); len(got) != 0 {
+		t.Errorf("receipts = %+v; want none for an unpublished batch", got)
+	}
+}
+
+func TestReceiptLog_DropsWhenQueueFull(t *testing.T) {
+	cfg := receiptConfig()
+	cfg.Pipeline.ReceiptQueueCapacity = 1
+	hp := receiptHotPath(t, &receiptRedis{}, cfg, &mockPublisher{})
+	before := metrics.ReceiptsDropped.Value()
+
+	ack := message.AckMessage{Stream: testStreamSimp, IDs: []string{testMsgID1}, Ack: true}
+	hp.receipts.recordAck(ack)
+	hp.receipts.recordAck(ack)
+
+	if d := metrics.ReceiptsDropped.Value() - before; d != 1 {
+		t.Errorf("ReceiptsDropped delta = %d; want 1", d)
+	}
+}
+
+func TestReceiptLog_RetriesFailedWrite(t *testing.T) {
+	var calls int
+	redisClient := &receiptRedis{
+		writeFn: func(context.Context, []message.Receipt) error {
+			calls++
+			if calls == 1 {
+				return errors.New("redis down")
+			}
+			return nil
+		},
+	}
+	hp := receiptHotPath(t, redisClient, receiptConfig(), &mockPublisher{})
+	errorsBefore := metrics.ReceiptWriteErrors.Value()
+
+	hp.receipts.start(t.Context())
+	hp.receipts.recordAck(message.AckMessage{Stream: testStreamSimp, IDs: []string{testMsgID1}})
+	time.Sleep(50 * time.Millisecond)
+	hp.receipts.close(t.Context(), time.Second)
+
+	got := redisClient.written()
+	if len(got) != 1 || got[0].Event != message.ReceiptNacked {
+		t.Errorf("written = %+v; want the nack receipt written after a retry", got)
+	}
+	if d := metrics.ReceiptWriteErrors.Value() - errorsBefore; d < 1 {
+		t.Errorf("ReceiptWriteErrors delta = %d; want >= 1", d)
+	}
+}
+
+func TestRun_FlushesAckReceiptsOnShutdown(t *testing.T) {
+	redisClient := &receiptRedis{}
+	cfg := receiptConfig()
+	cfg.Pipeline.ReceiptFlushInterval = time.Hour // only the shutdown flush can write
+
+	var handler func(message.AckMessage)
+	subscribed := make(chan struct{})
+	pub := &mockPublisher{
+		subscribeAckFn: func(_ context.Context, h func(message.AckMessage)) error {
+			handler = h
+			close(subscribed)
+			return nil
+		},
+	}
+	hp := receiptHotPath(t, redisClient, cfg, pub)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+	<-subscribed
+	handler(message.AckMessage{Stream: testStreamSimp, IDs: []string{testMsgID1}, Ack: true})
+	cancel()
+	checkLoopExit(t, <-done)
+
+	got := redisClient.written()
+	if len(got) != 1 || got[0].Event != message.ReceiptAcked || got[0].Stream != testStreamSimp {
+		t.Fatalf("written = %+v; want the acked receipt flushed at shutdown", got)
+	}
+	if !slices.Equal(got[0].IDs, []string{testMsgID1}) || got[0].At.IsZero() {
+		t.Errorf("receipt = %+v; want ID %s with a timestamp", got[0], testMsgID1)
+	}
+}
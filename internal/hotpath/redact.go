@@ -0,0 +1,377 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+const deadLetterReasonRedaction = "redaction"
+
+const (
+	// redactedValue replaces every masked value.
+	redactedValue = "[REDACTED]"
+	// maxRedactDepth bounds how deep the object is walked; a deeper one
+	// fails redaction.
+	maxRedactDepth = 64
+)
+
+// deadLetterRedactor is implemented by *redis.Client, whose DeadLetterIDs
+// reads the entries back from their stream before copying them.
+type deadLetterRedactor interface {
+	SetDeadLetterRedaction(redact func(msg *message.Redis))
+}
+
+type redactPattern struct {
+	re   *regexp.Regexp
+	rule int // index into redaction.names
+	luhn bool
+}
+
+// redaction applies the PIPELINE_REDACT_FILE rules to the entries as they
+// are read, before anything can publish or dead-letter them. Field rules
+// mask the value of the object fields they name, at any depth; pattern
+// rules mask their matches in the raw line and in the object's string and
+// number values. An object that is not a valid JSON object fails
+// redaction: its pattern matches are masked in its text, and it is
+// dead-lettered under PIPELINE_REDACT_STRICT or published without it
+// otherwise. A nil redaction leaves the entries as they are.
+type redaction struct {
+	dlq      deadLetterer
+	fields   map[string]int // field name to index into names
+	names    []string
+	patterns []redactPattern
+	strict   bool
+}
+
+func validateRedaction(redisClient redis.StreamClient, cfg *config.PipelineConfig) error {
+	if !cfg.RedactStrict {
+		return nil
+	}
+	if _, ok := redisClient.(deadLetterer); !ok {
+		return errors.New("hotpath: redact strict needs a redis client that supports dead-lettering")
+	}
+	return nil
+}
+
+// newRedaction compiles the rules, which config validation has checked,
+// and has redisClient redact what DeadLetterIDs reads back.
+func newRedaction(redisClient redis.StreamClient, cfg *config.PipelineConfig) *redaction {
+	if len(cfg.RedactRules) == 0 {
+		return nil
+	}
+	r := &redaction{
+		fields: make(map[string]int),
+		names:  make([]string, len(cfg.RedactRules)),
+		strict: cfg.RedactStrict,
+	}
+	r.dlq, _ = redisClient.(deadLetterer)
+	for i := range cfg.RedactRules {
+		rule := &cfg.RedactRules[i]
+		r.names[i] = rule.Name
+		if rule.Field != "" {
+			r.fields[rule.Field] = i
+			continue
+		}
+		r.patterns = append(r.patterns, redactPattern{re: regexp.MustCompile(rule.Pattern), rule: i, luhn: rule.Luhn})
+	}
+	if d, ok := redisClient.(deadLetterRedactor); ok {
+		d.SetDeadLetterRedaction(r.redactOne)
+	}
+	return r
+}
+
+// redactOne redacts a single entry, counting what it masked.
+func (r *redaction) redactOne(msg *message.Redis) {
+	counts := make([]int64, len(r.names))
+	masked, ok := r.redact(msg, counts)
+	r.count(counts, boolCount(masked), boolCount(!ok))
+}
+
+func boolCount(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// count adds one batch's masked values to the shared counters.
+func (r *redaction) count(counts []int64, redacted, failed int64) {
+	for i, n := range counts {
+		if n > 0 {
+			metrics.Redactions.Add(r.names[i], n)
+		}
+	}
+	metrics.MessagesRedacted.Add(redacted)
+	metrics.RedactionFailures.Add(failed)
+}
+
+// redact masks msg in place, adding each rule's masked values to counts.
+// It reports whether anything was masked and false when the object could
+// not be walked.
+func (r *redaction) redact(msg *message.Redis, counts []int64) (masked, ok bool) {
+	msg.Raw, masked = r.mask(msg.Raw, counts)
+	if msg.Object == "" {
+		return masked, true
+	}
+	object, objectMasked, ok := r.maskObject(msg.Object, counts)
+	msg.Object = object
+	return masked || objectMasked, ok
+}
+
+// mask replaces the pattern matches in s.
+func (r *redaction) mask(s string, counts []int64) (string, bool) {
+	masked := false
+	for i := range r.patterns {
+		var hit bool
+		if s, hit = r.patterns[i].replace(s, &counts[r.patterns[i].rule]); hit {
+			masked = true
+		}
+	}
+	return s, masked
+}
+
+// replace returns s itself when nothing in it is masked.
+func (p *redactPattern) replace(s string, count *int64) (string, bool) {
+	var b strings.Builder
+	last := 0
+	for _, m := range p.re.FindAllStringIndex(s, -1) {
+		if p.luhn && !luhnValid(s[m[0]:m[1]]) {
+			continue
+		}
+		if b.Cap() == 0 {
+			b.Grow(len(s))
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(redactedValue)
+		last = m[1]
+		*count++
+	}
+	if b.Cap() == 0 {
+		return s, false
+	}
+	b.WriteString(s[last:])
+	return b.String(), true
+}
+
+// luhnValid reports whether the digits of s, which may be grouped by
+// spaces or dashes, pass the Luhn check.
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '-':
+			continue
+		case c < '0' || c > '9':
+			return false
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits > 1 && sum%10 == 0
+}
+
+// maskObject returns object with its fields masked, or object itself when
+// nothing in it is. An invalid object only has its pattern matches masked.
+func (r *redaction) maskObject(object string, counts []int64) (string, bool, bool) {
+	if !validObject(object) {
+		masked, hit := r.mask(object, counts)
+		return masked, hit, false
+	}
+	b := jsonfast.Acquire()
+	defer jsonfast.Release(b)
+	masked, ok := r.appendObject(b, []byte(object), counts, 0)
+	if !ok {
+		return object, false, false
+	}
+	if !masked {
+		return object, false, true
+	}
+	return string(b.Bytes()), true, true
+}
+
+func (r *redaction) appendObject(b *jsonfast.Builder, object []byte, counts []int64, depth int) (masked, ok bool) {
+	if depth > maxRedactDepth {
+		return false, false
+	}
+	b.AppendRawString("{")
+	first := true
+	ok = jsonfast.IterateFields(object, func(key, value []byte) bool {
+		if !first {
+			b.AppendRawString(",")
+		}
+		first = false
+		b.AppendRaw(key)
+		b.AppendRawString(":")
+		if rule, hit := r.fields[fieldName(key)]; hit {
+			b.AppendRawString(`"` + redactedValue + `"`)
+			counts[rule]++
+			masked = true
+			return true
+		}
+		valueMasked, valid := r.appendValue(b, value, counts, depth)
+		masked = masked || valueMasked
+		return valid
+	})
+	b.AppendRawString("}")
+	return masked, ok
+}
+
+func (r *redaction) appendArray(b *jsonfast.Builder, array []byte, counts []int64, depth int) (masked, ok bool) {
+	if depth > maxRedactDepth {
+		return false, false
+	}
+	b.AppendRawString("[")
+	first := true
+	ok = jsonfast.IterateArray(array, func(element []byte) bool {
+		if !first {
+			b.AppendRawString(",")
+		}
+		first = false
+		elementMasked, valid := r.appendValue(b, element, counts, depth)
+		masked = masked || elementMasked
+		return valid
+	})
+	b.AppendRawString("]")
+	return masked, ok
+}
+
+// appendValue writes value with its pattern matches masked.
+func (r *redaction) appendValue(b *jsonfast.Builder, value []byte, counts []int64, depth int) (masked, ok bool) {
+	switch value[0] {
+	case '{':
+		return r.appendObject(b, value, counts, depth+1)
+	case '[':
+		return r.appendArray(b, value, counts, depth+1)
+	case '"':
+		return r.appendString(b, value, counts)
+	case 't', 'f', 'n':
+		b.AppendRaw(value)
+		return false, true
+	default:
+		return r.appendNumber(b, value, counts), true
+	}
+}
+
+func (r *redaction) appendString(b *jsonfast.Builder, value []byte, counts []int64) (masked, ok bool) {
+	if len(r.patterns) == 0 {
+		b.AppendRaw(value)
+		return false, true
+	}
+	s, ok := jsonfast.DecodeString(value)
+	if !ok {
+		return false, false
+	}
+	if s, masked = r.mask(s, counts); masked {
+		appendQuoted(b, s)
+		return true, true
+	}
+	b.AppendRaw(value)
+	return false, true
+}
+
+// appendNumber writes a number with a pattern match as a string.
+func (r *redaction) appendNumber(b *jsonfast.Builder, value []byte, counts []int64) bool {
+	if len(r.patterns) > 0 {
+		if s, masked := r.mask(string(value), counts); masked {
+			appendQuoted(b, s)
+			return true
+		}
+	}
+	b.AppendRaw(value)
+	return false
+}
+
+func appendQuoted(b *jsonfast.Builder, s string) {
+	b.AppendRawString(`"`)
+	b.AppendEscapedString(s)
+	b.AppendRawString(`"`)
+}
+
+// fieldName returns the name of a quoted key, decoding it only when it
+// holds escapes.
+func fieldName(key []byte) string {
+	name := key[1 : len(key)-1]
+	if !strings.ContainsRune(string(name), '\\') {
+		return string(name)
+	}
+	decoded, _ := jsonfast.DecodeString(key)
+	return decoded
+}
+
+// redactItems redacts items in place and returns which of them fail
+// redaction under PIPELINE_REDACT_STRICT, or nil when none does; the
+// others that fail lose their object.
+func (r *redaction) redactItems(items []message.Redis) []bool {
+	counts := make([]int64, len(r.names))
+	var redacted, failed int64
+	var fails []bool
+	for i := range items {
+		masked, ok := r.redact(&items[i], counts)
+		redacted += boolCount(masked)
+		if ok {
+			continue
+		}
+		failed++
+		if !r.strict {
+			items[i].Object = ""
+			continue
+		}
+		if fails == nil {
+			fails = make([]bool, len(items))
+		}
+		fails[i] = true
+	}
+	r.count(counts, redacted, failed)
+	return fails
+}
+
+// redactBatch redacts the entries of batch and dead-letters those that
+// fail redaction under PIPELINE_REDACT_STRICT. When that fails they are
+// left pending, and the claim loop brings them back here. The returned
+// batch is released when nothing is left of it.
+func (hp *HotPath) redactBatch(ctx context.Context, batch message.Batch) message.Batch {
+	if hp.redaction == nil || len(batch.Items) == 0 {
+		return batch
+	}
+	batch.Decode()
+	fails := hp.redaction.redactItems(batch.Items)
+	if fails == nil {
+		return batch
+	}
+
+	var deadLetters []message.Redis
+	for i, drop := range fails {
+		if drop {
+			deadLetters = append(deadLetters, batch.Items[i])
+		}
+	}
+	batch.Compact(fails)
+
+	hp.events.droppedMessages(deadLetterReasonRedaction, deadLetters)
+	if err := hp.redaction.dlq.DeadLetter(ctx, deadLetters, deadLetterReasonRedaction); err != nil {
+		hp.log.Errorf(ctx, "Failed to dead-letter %d messages that could not be redacted: %v", len(deadLetters), err)
+		metrics.RedactionDeadLetterErrors.Add(int64(len(deadLetters)))
+	} else {
+		metrics.RedactionDeadLettered.Add(int64(len(deadLetters)))
+	}
+	if len(batch.Items) == 0 {
+		batch.Release()
+	}
+	return batch
+}
@@ -0,0 +1,143 @@
+package hotpath
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+var testRedactRules = []config.RedactRule{
+	{Name: "card", Pattern: `\b[0-9][0-9 -]{11,21}[0-9]\b`, Luhn: true},
+	{Name: "password", Field: "password"},
+	{Name: "token", Pattern: `tok_[A-Za-z0-9]+`},
+}
+
+func redactHotPath(t *testing.T, redisClient *deadLetterRedis, strict bool) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Pipeline.RedactRules = testRedactRules
+	cfg.Pipeline.RedactStrict = strict
+	hp, err := New(redisClient, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func TestRedaction_Redact(t *testing.T) {
+	r := newRedaction(&mockRedis{}, &config.PipelineConfig{RedactRules: testRedactRules})
+
+	for _, tt := range []struct {
+		name, object, raw       string
+		wantObject, wantRaw     string
+		wantMasked, wantInvalid bool
+	}{
+		{name: "untouched", object: `{"a": 1, "b": "x"}`, raw: "plain line",
+			wantObject: `{"a": 1, "b": "x"}`, wantRaw: "plain line"},
+		{name: "card in raw", raw: "paid with 4111 1111 1111 1111 today", wantMasked: true,
+			wantRaw: "paid with [REDACTED] today"},
+		{name: "not luhn", raw: "order 4111111111111112", wantRaw: "order 4111111111111112"},
+		{name: "nested field", object: `{"user":{"name":"a","password":"hunter2"},"n":[{"password":1}]}`,
+			wantObject: `{"user":{"name":"a","password":"[REDACTED]"},"n":[{"password":"[REDACTED]"}]}`,
+			wantMasked: true},
+		{name: "pattern in string and number", object: `{"note":"key tok_abc1","pan":4111111111111111}`,
+			wantObject: `{"note":"key [REDACTED]","pan":"[REDACTED]"}`, wantMasked: true},
+		{name: "escaped key", object: `{"pass\u0077ord":"x"}`,
+			wantObject: `{"pass\u0077ord":"[REDACTED]"}`, wantMasked: true},
+		{name: "invalid object", object: `{"password":"x","t":"tok_abc`, wantInvalid: true, wantMasked: true,
+			wantObject: `{"password":"x","t":"[REDACTED]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := message.Redis{Object: tt.object, Raw: tt.raw}
+			masked, ok := r.redact(&msg, make([]int64, len(r.names)))
+			if msg.Object != tt.wantObject || msg.Raw != tt.wantRaw {
+				t.Errorf("redact() = %q, %q; want %q, %q", msg.Object, msg.Raw, tt.wantObject, tt.wantRaw)
+			}
+			if masked != tt.wantMasked || ok == tt.wantInvalid {
+				t.Errorf("redact() masked, ok = %v, %v; want %v, %v", masked, ok, tt.wantMasked, !tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want bool
+	}{
+		{"4111111111111111", true},
+		{"4111-1111-1111-1111", true},
+		{"4111111111111112", false},
+		{"4111x111111111111", false},
+		{"0", false},
+	} {
+		if got := luhnValid(tt.s); got != tt.want {
+			t.Errorf("luhnValid(%q) = %v; want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestRedactBatch_Lenient(t *testing.T) {
+	hp := redactHotPath(t, &deadLetterRedis{}, false)
+	before := metrics.RedactionFailures.Value()
+	beforeRedacted := metrics.MessagesRedacted.Value()
+
+	batch := hp.redactBatch(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "app", Object: `{"password":`, Raw: "broken"},
+		{ID: "2-0", Stream: "app", Object: `{"password":"a"}`, Raw: "valid"},
+	}})
+
+	if len(batch.Items) != 2 || batch.Items[0].Object != "" || batch.Items[1].Object != `{"password":"[REDACTED]"}` {
+		t.Errorf("items = %+v; want the broken object dropped and the password masked", batch.Items)
+	}
+	if got := metrics.RedactionFailures.Value() - before; got != 1 {
+		t.Errorf("RedactionFailures delta = %d; want 1", got)
+	}
+	if got := metrics.MessagesRedacted.Value() - beforeRedacted; got != 1 {
+		t.Errorf("MessagesRedacted delta = %d; want 1", got)
+	}
+}
+
+func TestRedactBatch_StrictDeadLetters(t *testing.T) {
+	var deadLettered []message.Redis
+	redisClient := &deadLetterRedis{
+		deadLetterFn: func(_ context.Context, msgs []message.Redis, reason string) error {
+			if reason != deadLetterReasonRedaction {
+				t.Errorf("reason = %q; want %q", reason, deadLetterReasonRedaction)
+			}
+			deadLettered = append(deadLettered, msgs...)
+			return nil
+		},
+	}
+	hp := redactHotPath(t, redisClient, true)
+	before := metrics.RedactionDeadLettered.Value()
+
+	batch := hp.redactBatch(t.Context(), message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: "app", Object: `{"t":"tok_abc"`, Raw: "broken"},
+		{ID: "2-0", Stream: "app", Raw: "no object"},
+	}})
+
+	if len(batch.Items) != 1 || batch.Items[0].ID != "2-0" {
+		t.Errorf("items = %+v; want only the entry without an object", batch.Items)
+	}
+	if len(deadLettered) != 1 || deadLettered[0].Object != `{"t":"[REDACTED]"` {
+		t.Errorf("dead-lettered = %+v; want the broken entry with its token masked", deadLettered)
+	}
+	if got := metrics.RedactionDeadLettered.Value() - before; got != 1 {
+		t.Errorf("RedactionDeadLettered delta = %d; want 1", got)
+	}
+}
+
+func TestNewRedaction_OffByDefault(t *testing.T) {
+	if r := newRedaction(&mockRedis{}, &config.PipelineConfig{}); r != nil {
+		t.Errorf("newRedaction() = %+v; want nil", r)
+	}
+	if err := validateRedaction(&mockRedis{}, &config.PipelineConfig{RedactStrict: true}); err == nil {
+		t.Error("validateRedaction(strict) error = nil; want the missing dead-lettering")
+	}
+}
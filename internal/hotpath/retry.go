@@ -0,0 +1,201 @@
+package hotpath
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// retryQueue holds the batches the claim loop brings back (entries NACKed,
+// failed or abandoned by a publish worker) apart from the fetched ones, so
+// retries cannot crowd fresh traffic out of the main queue. It is a ring of
+// PIPELINE_RETRY_QUEUE_CAPACITY batches: a claimed batch that finds it full
+// is released and stays pending for a later claim. The retry loop hands its
+// batches to the publish workers at PIPELINE_RETRY_RATE entries per second,
+// which take them after or before the fetched ones by
+// PIPELINE_RETRY_PRIORITY. A nil retryQueue sends claimed batches through
+// the main queue.
+type retryQueue struct {
+	bucket  *tokenBucket // nil when the rate is unlimited; retry loop only
+	out     chan message.Batch
+	ready   chan struct{} // signaled when a batch is pushed
+	items   []retryBatch
+	head    int
+	size    int
+	entries int
+	mu      sync.Mutex
+	high    bool
+}
+
+type retryBatch struct {
+	queued time.Time
+	batch  message.Batch
+}
+
+func newRetryQueue(cfg *config.PipelineConfig) *retryQueue {
+	if cfg.RetryQueueCapacity <= 0 {
+		return nil
+	}
+	return &retryQueue{
+		bucket: newTokenBucket(cfg.RetryRate, time.Now()),
+		out:    make(chan message.Batch),
+		ready:  make(chan struct{}, 1),
+		items:  make([]retryBatch, cfg.RetryQueueCapacity),
+		high:   cfg.RetryPriority == config.RetryPriorityHigh,
+	}
+}
+
+// push queues batch at now and reports whether there was room for it.
+func (q *retryQueue) push(batch message.Batch, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == len(q.items) {
+		return false
+	}
+	q.items[(q.head+q.size)%len(q.items)] = retryBatch{queued: now, batch: batch}
+	q.size++
+	q.entries += len(batch.Items)
+	q.observe(now)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop takes the oldest batch off the queue at now.
+func (q *retryQueue) pop(now time.Time) (message.Batch, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == 0 {
+		return message.Batch{}, false
+	}
+	batch := q.items[q.head].batch
+	q.items[q.head] = retryBatch{}
+	q.head = (q.head + 1) % len(q.items)
+	q.size--
+	q.entries -= len(batch.Items)
+	q.observe(now)
+	return batch, true
+}
+
+// observe publishes the depth and age of the queue; q.mu must be held.
+func (q *retryQueue) observe(now time.Time) {
+	metrics.RetryQueueDepth.Set(int64(q.entries))
+	if q.size == 0 {
+		metrics.RetryQueueAgeMs.Set(0)
+		return
+	}
+	metrics.RetryQueueAgeMs.Set(now.Sub(q.items[q.head].queued).Milliseconds())
+}
+
+// wait charges n entries to the retry rate at now and returns how long
+// they must wait for it.
+func (q *retryQueue) wait(n int, now time.Time) time.Duration {
+	q.bucket.refill(now)
+	q.bucket.take(float64(n))
+	return q.bucket.debt()
+}
+
+// next returns a publish worker's next batch, fetched from src or retried
+// from q, the retried one first under RetryPriorityHigh. It returns false
+// once ctx is done.
+func (q *retryQueue) next(ctx context.Context, src <-chan message.Batch) (message.Batch, bool) {
+	if q == nil {
+		select {
+		case <-ctx.Done():
+			return message.Batch{}, false
+		case batch := <-src:
+			return batch, true
+		}
+	}
+	first, second := src, (<-chan message.Batch)(q.out)
+	if q.high {
+		first, second = second, first
+	}
+	select {
+	case batch := <-first:
+		return batch, true
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return message.Batch{}, false
+	case batch := <-first:
+		return batch, true
+	case batch := <-second:
+		return batch, true
+	}
+}
+
+// enqueueRetry queues a claimed batch on the retry queue, or on the main
+// queue without one.
+func (hp *HotPath) enqueueRetry(ctx context.Context, batch message.Batch) error {
+	if hp.retries == nil {
+		return hp.enqueueBatch(ctx, batch)
+	}
+	hp.memory.admit(&batch)
+	if hp.retries.push(batch, time.Now()) {
+		return nil
+	}
+	hp.log.Warnf(ctx, "Retry queue full, leaving %d claimed messages pending", len(batch.Items))
+	metrics.RetryQueueDropped.Add(int64(len(batch.Items)))
+	hp.discard(batch)
+	return nil
+}
+
+// retryLoop hands the batches of the retry queue to the publish workers,
+// oldest first, within the retry rate.
+func (hp *HotPath) retryLoop(ctx context.Context) error {
+	q := hp.retries
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.ready:
+		}
+		for {
+			batch, ok := q.pop(time.Now())
+			if !ok {
+				break
+			}
+			if err := sleepCtx(ctx, q.wait(len(batch.Items), time.Now())); err != nil {
+				hp.discard(batch)
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				hp.discard(batch)
+				return ctx.Err()
+			case q.out <- batch:
+			}
+		}
+	}
+}
+
+// clearRetries releases the batches still on the retry queue once the
+// producers have stopped; their entries stay pending.
+func (hp *HotPath) clearRetries() {
+	if hp.retries == nil {
+		return
+	}
+	for {
+		batch, ok := hp.retries.pop(time.Now())
+		if !ok {
+			return
+		}
+		hp.discard(batch)
+	}
+}
+
+// discard releases a queued batch without publishing it, leaving its
+// entries pending.
+func (hp *HotPath) discard(batch message.Batch) {
+	held := hp.memory.checkout(&batch)
+	batch.Release()
+	hp.memory.release(held)
+}
@@ -0,0 +1,180 @@
+package hotpath
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func retryHotPath(t *testing.T, r *mockRedis, p *mockPublisher, mutate func(*config.PipelineConfig)) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Redis.ClaimIdle = time.Millisecond
+	cfg.Pipeline.RetryQueueCapacity = 1
+	cfg.Pipeline.RetryPriority = config.RetryPriorityLow
+	mutate(&cfg.Pipeline)
+	hp, err := New(r, p, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func retryBatchOf(n int, id string) message.Batch {
+	items := make([]message.Redis, n)
+	for i := range items {
+		items[i] = message.Redis{ID: id, Stream: testStreamSimp}
+	}
+	return message.Batch{Items: items}
+}
+
+func TestClaimLoop_RetryQueue(t *testing.T) {
+	var calls atomic.Int32
+	r := &mockRedis{
+		claimIdleFn: func(ctx context.Context) (message.Batch, error) {
+			switch calls.Add(1) {
+			case 1:
+				return retryBatchOf(2, "claimed-1"), nil
+			case 2:
+				return retryBatchOf(3, "claimed-2"), nil
+			}
+			<-ctx.Done()
+			return message.Batch{}, ctx.Err()
+		},
+	}
+	hp := retryHotPath(t, r, &mockPublisher{}, func(*config.PipelineConfig) {})
+	dropped := metrics.RetryQueueDropped.Value()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.claimLoop(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.RetryQueueDropped.Value()-dropped < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	checkLoopExit(t, <-done)
+
+	if d := metrics.RetryQueueDropped.Value() - dropped; d != 3 {
+		t.Errorf("RetryQueueDropped delta = %d; want 3 for the batch that found the queue full", d)
+	}
+	if got := metrics.RetryQueueDepth.Value(); got != 2 {
+		t.Errorf("RetryQueueDepth = %d; want 2", got)
+	}
+	if len(hp.msgChan) != 0 {
+		t.Errorf("main queue holds %d batches; want claimed batches kept out of it", len(hp.msgChan))
+	}
+
+	hp.clearRetries()
+	if got := metrics.RetryQueueDepth.Value(); got != 0 {
+		t.Errorf("RetryQueueDepth = %d after clearRetries; want 0", got)
+	}
+}
+
+func TestRetryQueue_Priority(t *testing.T) {
+	for _, tc := range []struct {
+		priority string
+		want     string
+	}{
+		{priority: config.RetryPriorityLow, want: "fresh"},
+		{priority: config.RetryPriorityHigh, want: "retried"},
+	} {
+		q := newRetryQueue(&config.PipelineConfig{RetryQueueCapacity: 1, RetryPriority: tc.priority})
+		q.out = make(chan message.Batch, 1)
+		q.out <- retryBatchOf(1, "retried")
+		src := make(chan message.Batch, 1)
+		src <- retryBatchOf(1, "fresh")
+
+		batch, ok := q.next(t.Context(), src)
+		if !ok || batch.Items[0].ID != tc.want {
+			t.Errorf("priority %s: next() = %v, %v; want the %s batch", tc.priority, batch.Items, ok, tc.want)
+		}
+	}
+}
+
+func TestRetryQueue_NilNext(t *testing.T) {
+	var q *retryQueue
+	src := make(chan message.Batch, 1)
+	src <- retryBatchOf(1, "fresh")
+	if batch, ok := q.next(t.Context(), src); !ok || batch.Items[0].ID != "fresh" {
+		t.Errorf("next() = %v, %v; want the fresh batch", batch.Items, ok)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, ok := q.next(ctx, src); ok {
+		t.Error("next() ok = true; want false once ctx is done")
+	}
+}
+
+func TestRetryLoop_Rate(t *testing.T) {
+	hp := retryHotPath(t, &mockRedis{}, &mockPublisher{}, func(cfg *config.PipelineConfig) {
+		cfg.RetryQueueCapacity = 2
+		cfg.RetryRate = 100
+	})
+	now := time.Now()
+	if !hp.retries.push(retryBatchOf(100, "first"), now) || !hp.retries.push(retryBatchOf(10, "second"), now) {
+		t.Fatal("push() = false; want room for two batches")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.retryLoop(ctx) }()
+
+	start := time.Now()
+	for _, want := range []string{"first", "second"} {
+		select {
+		case batch := <-hp.retries.out:
+			if batch.Items[0].ID != want {
+				t.Errorf("retried %s; want %s", batch.Items[0].ID, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for the %s retried batch", want)
+		}
+	}
+	// The first batch empties the bucket, so the second waits 10/100 s.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("retried both batches in %v; want the second held back by the retry rate", elapsed)
+	}
+	if got := metrics.RetryQueueAgeMs.Value(); got != 0 {
+		t.Errorf("RetryQueueAgeMs = %d; want 0 for an empty queue", got)
+	}
+	cancel()
+	checkLoopExit(t, <-done)
+}
+
+func TestPublishLoop_PublishesRetries(t *testing.T) {
+	published := make(chan struct{}, 1)
+	p := &mockPublisher{publishFn: func(context.Context, message.Payload) error {
+		published <- struct{}{}
+		return nil
+	}}
+	hp := retryHotPath(t, &mockRedis{}, p, func(*config.PipelineConfig) {})
+	batch := retryBatchOf(1, "1-0")
+	batch.Items[0].Object = `{"msg":"retried"}`
+	if !hp.retries.push(batch, time.Now()) {
+		t.Fatal("push() = false; want room for the batch")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 2)
+	go func() { done <- hp.retryLoop(ctx) }()
+	go func() { done <- hp.makePublishLoop(t.Context(), 0)(ctx) }()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the retried batch to be published")
+	}
+	cancel()
+	checkLoopExit(t, <-done)
+	checkLoopExit(t, <-done)
+}
@@ -0,0 +1,166 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// maxTopicLen is the longest topic name MQTT can encode.
+const maxTopicLen = 65535
+
+// publishRoutes applies the _topic, _qos and _retain overrides of entries.
+// A nil publishRoutes ignores them, and every entry goes out with its batch.
+type publishRoutes struct {
+	publisher mqtt.TopicPublisher
+	topic     string // MQTT_PUBLISH_TOPIC, for entries that keep the topic
+	ackTopic  string
+	filters   []string
+	qos       byte
+}
+
+// destination is a validated route.
+type destination struct {
+	topic  string
+	qos    byte
+	retain bool
+}
+
+func validateRoutes(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) error {
+	if len(cfg.OverrideTopics) == 0 {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: publish overrides need an mqtt publisher that can publish to other topics")
+	}
+	return nil
+}
+
+func newPublishRoutes(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) *publishRoutes {
+	if len(cfg.OverrideTopics) == 0 {
+		return nil
+	}
+	return &publishRoutes{
+		publisher: topics,
+		topic:     cfg.PublishTopic,
+		ackTopic:  cfg.AckTopic,
+		filters:   cfg.OverrideTopics,
+		qos:       cfg.QoS,
+	}
+}
+
+// resolve validates route against the configured filters; fields it leaves
+// empty keep the defaults, and retain defaults to false.
+func (r *publishRoutes) resolve(route *message.Route) (destination, error) {
+	dest := destination{topic: r.topic, qos: r.qos}
+	if route.Topic != "" {
+		if !r.allows(route.Topic) {
+			return destination{}, fmt.Errorf("topic %q is not allowed", route.Topic)
+		}
+		dest.topic = route.Topic
+	}
+	if route.QoS != "" {
+		qos, err := strconv.ParseUint(route.QoS, 10, 8)
+		if err != nil || qos > 2 {
+			return destination{}, fmt.Errorf("qos %q is not 0, 1 or 2", route.QoS)
+		}
+		dest.qos = byte(qos)
+	}
+	if route.Retain != "" {
+		retain, err := strconv.ParseBool(route.Retain)
+		if err != nil {
+			return destination{}, fmt.Errorf("retain %q is not a boolean", route.Retain)
+		}
+		dest.retain = retain
+	}
+	return dest, nil
+}
+
+// allows reports whether topic is a publishable topic name matched by one
+// of the filters. The ACK topic is never allowed, so a producer cannot
+// forge acknowledgements.
+func (r *publishRoutes) allows(topic string) bool {
+	if len(topic) > maxTopicLen || strings.ContainsAny(topic, "+#\x00") || topic == r.ackTopic {
+		return false
+	}
+	for _, filter := range r.filters {
+		if topicMatches(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicMatches applies the MQTT matching rules: "+" matches one level, a
+// trailing "#" the parent level and everything below it, and neither
+// matches a first level starting with "$".
+func topicMatches(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	for {
+		level, filterRest, filterMore := strings.Cut(filter, "/")
+		if level == "#" {
+			return true
+		}
+		name, topicRest, topicMore := strings.Cut(topic, "/")
+		if level != "+" && level != name {
+			return false
+		}
+		if !filterMore || !topicMore {
+			return filterMore == topicMore || filterRest == "#"
+		}
+		filter, topic = filterRest, topicRest
+	}
+}
+
+// publishRouted publishes msg on its own when route holds valid overrides,
+// as a one-record payload on the route's topic with its QoS and retain
+// flag, and reports whether it did. Entries with invalid overrides, and
+// records over PIPELINE_MAX_PAYLOAD_BYTES, are left to the batch.
+func (hp *HotPath) publishRouted(
+	ctx context.Context, builder *jsonfast.Builder, enc *zstd.Encoder,
+	msg *message.Redis, route *message.Route, compressed *[]byte,
+) bool {
+	if hp.routes == nil || route == nil {
+		return false
+	}
+	dest, err := hp.routes.resolve(route)
+	if err != nil {
+		hp.log.Warnf(ctx, "Ignoring publish overrides of message %s: %v", msg.ID, err)
+		metrics.RoutesRejected.Add(1)
+		return false
+	}
+
+	var stats projectionStats
+	record := hp.buildProjectedPayload(builder, msg, &stats)
+	if hp.oversize != nil && len(record) > hp.oversize.max {
+		hp.log.Warnf(ctx, "Ignoring publish overrides of message %s: record is over the payload limit", msg.ID)
+		metrics.RoutesRejected.Add(1)
+		return false
+	}
+	builder.AppendRawString("\n")
+	record = builder.Bytes()
+
+	*compressed = compress.EncodeMarked(enc, *compressed, record, hp.origin)
+	publishFn := func(ctx context.Context, payload message.Payload) error {
+		return hp.routes.publisher.PublishTo(ctx, payload, dest.topic, dest.qos, dest.retain)
+	}
+	if hp.sendPayload(ctx, *compressed, 1, record, nil, publishFn) {
+		addProjectionMetrics(&stats)
+		hp.receipts.recordLines(record)
+		metrics.RoutesApplied.Add(1)
+	}
+	return true
+}
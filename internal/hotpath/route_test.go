@@ -0,0 +1,237 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// routedPayload is one PublishTo call with its records decompressed.
+type routedPayload struct {
+	topic  string
+	lines  []string
+	qos    byte
+	retain bool
+}
+
+// mockRoutedPublisher is a mockPublisher that also implements
+// mqtt.TopicPublisher. Publishes to failTopic fail.
+type mockRoutedPublisher struct {
+	mockPublisher
+	failTopic string
+	routed    []routedPayload
+}
+
+func (m *mockRoutedPublisher) PublishTo(
+	_ context.Context, payload message.Payload, topic string, qos byte, retain bool,
+) error {
+	if topic == m.failTopic {
+		return errors.New("broker unavailable")
+	}
+	m.routed = append(m.routed, routedPayload{topic: topic, lines: decompressLines(payload), qos: qos, retain: retain})
+	return nil
+}
+
+func decompressLines(payload []byte) []string {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return []string{"zstd reader error: " + err.Error()}
+	}
+	defer dec.Close()
+	raw, err := dec.DecodeAll(payload, nil)
+	if err != nil {
+		return []string{"decompress error: " + err.Error()}
+	}
+	return strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+}
+
+func routeHotPath(t *testing.T, cfg *config.Config) (*HotPath, *mockRoutedPublisher) {
+	t.Helper()
+	cfg.MQTT.PublishTopic = "logs"
+	cfg.MQTT.AckTopic = "logs/ack"
+	cfg.MQTT.OverrideTopics = []string{"alerts/#"}
+	publisher := &mockRoutedPublisher{}
+	hp, err := New(&mockRedis{}, publisher, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, publisher
+}
+
+// publishRoutedBatch publishes entries as a lazy batch, the way the Redis
+// client delivers them, and returns the records of the default payload.
+func publishRoutedBatch(t *testing.T, hp *HotPath, entries ...map[string]any) []string {
+	t.Helper()
+	pool := &sync.Pool{New: func() any { return message.NewBuffer(len(entries)) }}
+	buf := message.NewBuffer(len(entries))
+	for i, fields := range entries {
+		buf.Add(string(rune('1'+i))+"-0", testStreamSimp, fields)
+	}
+	batch := message.NewLazyBatch(buf, pool)
+	defer batch.Release()
+
+	var lines []string
+	publish := func(_ context.Context, payload message.Payload) error {
+		lines = append(lines, decompressLines(payload)...)
+		return nil
+	}
+	var compressed []byte
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+	return lines
+}
+
+func TestPublishBatch_RoutedEntryPublishedAlone(t *testing.T) {
+	hp, publisher := routeHotPath(t, testConfig())
+	appliedBefore := metrics.RoutesApplied.Value()
+
+	lines := publishRoutedBatch(t, hp,
+		map[string]any{"raw": "plain"},
+		map[string]any{"raw": "disk full", "_topic": "alerts/disk", "_qos": "2", "_retain": "true"},
+		map[string]any{"raw": "plain too", "_qos": "1"},
+	)
+
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "1-0\t") {
+		t.Errorf("default payload = %q; want only the entry without overrides", lines)
+	}
+	if len(publisher.routed) != 2 {
+		t.Fatalf("routed payloads = %d; want 2", len(publisher.routed))
+	}
+	alert := publisher.routed[0]
+	if alert.topic != "alerts/disk" || alert.qos != 2 || !alert.retain || len(alert.lines) != 1 {
+		t.Errorf("alert = %+v; want one record on alerts/disk, qos 2, retained", alert)
+	}
+	if id, _, body := parseLine(t, []byte(alert.lines[0])); id != "2-0" || strings.Contains(body, "_topic") {
+		t.Errorf("alert record = %s %s; want entry 2-0 without override fields", id, body)
+	}
+	if qosOnly := publisher.routed[1]; qosOnly.topic != "logs" || qosOnly.qos != 1 || qosOnly.retain {
+		t.Errorf("qos-only entry = %+v; want the default topic at qos 1", qosOnly)
+	}
+	if got := metrics.RoutesApplied.Value() - appliedBefore; got != 2 {
+		t.Errorf("RoutesApplied delta = %d; want 2", got)
+	}
+}
+
+func TestPublishBatch_RejectedRouteStaysInBatch(t *testing.T) {
+	hp, publisher := routeHotPath(t, testConfig())
+	rejectedBefore := metrics.RoutesRejected.Value()
+
+	lines := publishRoutedBatch(t, hp,
+		map[string]any{"raw": "a", "_topic": "logs/ack"},
+		map[string]any{"raw": "b", "_topic": "alerts/x", "_qos": "3"},
+	)
+
+	if len(lines) != 2 || len(publisher.routed) != 0 {
+		t.Errorf("default payload = %q, routed = %+v; want both entries in the batch", lines, publisher.routed)
+	}
+	if got := metrics.RoutesRejected.Value() - rejectedBefore; got != 2 {
+		t.Errorf("RoutesRejected delta = %d; want 2", got)
+	}
+}
+
+func TestPublishBatch_RoutesIgnoredWhenOff(t *testing.T) {
+	publisher := &mockRoutedPublisher{}
+	hp, err := New(&mockRedis{}, publisher, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	lines := publishRoutedBatch(t, hp, map[string]any{"raw": "a", "_topic": "alerts/x"})
+	if len(lines) != 1 || len(publisher.routed) != 0 {
+		t.Errorf("default payload = %q, routed = %+v; want overrides ignored", lines, publisher.routed)
+	}
+}
+
+func TestNew_RoutesRequireRoutedPublisher(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.OverrideTopics = []string{"alerts/#"}
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a publisher without PublishTo")
+	}
+}
+
+func TestPublishRoutes_Resolve(t *testing.T) {
+	r := &publishRoutes{topic: "logs", ackTopic: "alerts/ack", filters: []string{"alerts/#"}, qos: 1}
+	tests := []struct {
+		route   message.Route
+		want    destination
+		wantErr bool
+	}{
+		{route: message.Route{Topic: "alerts/disk"}, want: destination{topic: "alerts/disk", qos: 1}},
+		{route: message.Route{Retain: "1"}, want: destination{topic: "logs", qos: 1, retain: true}},
+		{route: message.Route{QoS: "0"}, want: destination{topic: "logs"}},
+		{route: message.Route{Topic: "audit/x"}, wantErr: true},
+		{route: message.Route{Topic: "alerts/ack"}, wantErr: true},
+		{route: message.Route{Topic: "alerts/+"}, wantErr: true},
+		{route: message.Route{QoS: "-1"}, wantErr: true},
+		{route: message.Route{Retain: "yes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := r.resolve(&tt.route)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolve(%+v) = %+v, %v; want %+v, error %v", tt.route, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"alerts/#", "alerts", true},
+		{"alerts/#", "alerts/disk/full", true},
+		{"alerts/+", "alerts/disk", true},
+		{"alerts/+", "alerts", false},
+		{"alerts/+", "alerts/disk/full", false},
+		{"alerts", "alerts/disk", false},
+		{"+/disk", "alerts/disk", true},
+		{"#", "$SYS/broker", false},
+		{"alerts/disk", "alerts/disk", true},
+	}
+	for _, tt := range tests {
+		if got := topicMatches(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("topicMatches(%q, %q) = %v; want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestPublishBatch_OriginMarker(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.OriginMarker = true
+	hp, _ := routeHotPath(t, cfg)
+	batch := message.Batch{Items: []message.Redis{{ID: "1-0", Stream: testStreamSimp, Object: `{"raw":"a"}`}}}
+
+	var payloads []message.Payload
+	publish := func(_ context.Context, payload message.Payload) error {
+		payloads = append(payloads, append(message.Payload(nil), payload...))
+		return nil
+	}
+	var compressed []byte
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	if len(payloads) != 1 {
+		t.Fatalf("payloads = %d; want 1", len(payloads))
+	}
+	origin, ok := compress.Origin(payloads[0])
+	if want := "syslog-consumer:" + cfg.MQTT.ClientID; !ok || string(origin) != want {
+		t.Errorf("Origin() = %q, %v; want %q", origin, ok, want)
+	}
+	if lines := decompressLines(payloads[0]); len(lines) != 1 || !strings.HasPrefix(lines[0], "1-0\t") {
+		t.Errorf("records = %q; want entry 1-0 behind the origin frame", lines)
+	}
+}
@@ -0,0 +1,184 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// sampler sends a sample of the entries published to MQTT_PUBLISH_TOPIC to
+// MQTT_SAMPLE_TOPIC as well, for downstream analytics: MQTT_SAMPLE_PERCENT
+// percent of them picked at random, or every MQTT_SAMPLE_EVERY-th. A
+// sampled record keeps its id and stream header but only the
+// MQTT_SAMPLE_FIELDS of its object, severity mapped to its name as on the
+// publish topic. The sample is published once the batch was, and a failure
+// is only counted: the entries' fate is decided by the publish topic alone.
+// A nil sampler samples nothing.
+type sampler struct {
+	publisher mqtt.TopicPublisher
+	topic     string
+	fields    [][]byte
+	origin    []byte
+	count     atomic.Uint64
+	every     uint64
+	percent   int
+	qos       byte
+}
+
+func validateSampler(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) error {
+	if cfg.SampleTopic == "" {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: the sample topic needs an mqtt publisher that can publish to other topics")
+	}
+	return nil
+}
+
+func newSampler(topics mqtt.TopicPublisher, cfg *config.MQTTConfig) *sampler {
+	if cfg.SampleTopic == "" {
+		return nil
+	}
+	s := &sampler{
+		publisher: topics,
+		topic:     cfg.SampleTopic,
+		fields:    make([][]byte, 0, len(cfg.SampleFields)),
+		origin:    originOf(cfg),
+		every:     uint64(max(cfg.SampleEvery, 0)),
+		percent:   cfg.SamplePercent,
+		qos:       cfg.QoS,
+	}
+	for _, field := range cfg.SampleFields {
+		s.fields = append(s.fields, []byte(field))
+	}
+	return s
+}
+
+// pick reports whether the next entry goes to the sample.
+func (s *sampler) pick() bool {
+	if s.every > 0 {
+		return s.count.Add(1)%s.every == 0
+	}
+	return rand.IntN(100) < s.percent //nolint:gosec // sampling needs no cryptographic randomness
+}
+
+// keep reports whether the object field name stays in a sampled record.
+func (s *sampler) keep(name []byte) bool {
+	for _, field := range s.fields {
+		if string(field) == string(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleLines holds the reduced records of a batch's sampled entries. It is
+// pooled like rawLines.
+type sampleLines struct {
+	s          *sampler
+	data       []byte
+	compressed []byte
+	count      int
+}
+
+var sampleLinesPool = sync.Pool{New: func() any { return new(sampleLines) }}
+
+// lines returns an empty sampleLines, or nil for a nil sampler.
+func (s *sampler) lines() *sampleLines {
+	if s == nil {
+		return nil
+	}
+	l, ok := sampleLinesPool.Get().(*sampleLines)
+	if !ok {
+		l = new(sampleLines)
+	}
+	l.s = s
+	return l
+}
+
+func putSampleLines(l *sampleLines) {
+	if l == nil {
+		return
+	}
+	l.s = nil
+	l.data = l.data[:0]
+	l.compressed = l.compressed[:0]
+	l.count = 0
+	sampleLinesPool.Put(l)
+}
+
+// add appends the reduced record of msg when the sampler picks it, building
+// it in builder. A nil sampleLines ignores it.
+func (l *sampleLines) add(builder *jsonfast.Builder, msg *message.Redis) {
+	if l == nil || !l.s.pick() {
+		return
+	}
+	builder.Reset()
+	appendHeader(builder, msg)
+	builder.BeginObject()
+	if msg.Object != "" {
+		jsonfast.IterateFieldsString(msg.Object, func(key, value []byte) bool {
+			name := key[1 : len(key)-1]
+			if !l.s.keep(name) {
+				return true
+			}
+			if string(name) == string(keySeverity) {
+				builder.AddStringFieldKey(fkSeverity, severityName(value))
+				return true
+			}
+			builder.AddRawBytesField(name, value)
+			return true
+		})
+	}
+	builder.EndObject()
+	l.data = append(l.data, builder.Bytes()...)
+	l.data = append(l.data, '\n')
+	l.count++
+}
+
+// withSample returns publishFn followed, once it succeeds, by the publish
+// of samples. It returns publishFn itself when there is no sampler.
+func (hp *HotPath) withSample(
+	enc *zstd.Encoder, samples *sampleLines,
+	publishFn func(context.Context, message.Payload) error,
+) func(context.Context, message.Payload) error {
+	if samples == nil {
+		return publishFn
+	}
+	return func(ctx context.Context, payload message.Payload) error {
+		if err := publishFn(ctx, payload); err != nil {
+			return err
+		}
+		if err := samples.publish(ctx, enc); err != nil {
+			hp.log.Warnf(ctx, "Failed to publish %d sampled entries to %s: %v", samples.count, samples.s.topic, err)
+		}
+		return nil
+	}
+}
+
+// publish sends the sampled records, if any, to the sample topic.
+func (l *sampleLines) publish(ctx context.Context, enc *zstd.Encoder) error {
+	if l.count == 0 {
+		return nil
+	}
+	if len(l.compressed) == 0 {
+		l.compressed = compress.EncodeMarked(enc, l.compressed, l.data, l.s.origin)
+	}
+	if err := l.s.publisher.PublishTo(ctx, l.compressed, l.s.topic, l.s.qos, false); err != nil {
+		metrics.SampleErrors.Add(1)
+		return err
+	}
+	metrics.SamplesPublished.Add(int64(l.count))
+	return nil
+}
@@ -0,0 +1,135 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func sampleHotPath(t *testing.T, mutate func(*config.MQTTConfig)) (*HotPath, *mockRoutedPublisher) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.MQTT.PublishTopic = "logs"
+	cfg.MQTT.AckTopic = "logs/ack"
+	cfg.MQTT.SampleTopic = "analytics/sample"
+	cfg.MQTT.SampleFields = []string{"severity", "hostname", "program"}
+	cfg.MQTT.SampleEvery = 2
+	mutate(&cfg.MQTT)
+	publisher := &mockRoutedPublisher{failTopic: "logs/fail"}
+	hp, err := New(&mockRedis{}, publisher, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp, publisher
+}
+
+// publishSampleBatch publishes four entries to topic through the publish
+// path, and returns whatever was sent to the sample topic.
+func publishSampleBatch(t *testing.T, hp *HotPath, publisher *mockRoutedPublisher, topic string) []string {
+	t.Helper()
+	batch := message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: `{"severity":3,"hostname":"web1","program":"nginx","msg":"a"}`},
+		{ID: "2-0", Stream: testStreamSimp, Object: `{"severity":6,"hostname":"web2","msg":"b"}`},
+		{ID: "3-0", Stream: testStreamSimp, Object: `{"severity":4,"hostname":"web3","program":"sshd"}`},
+		{ID: "4-0", Stream: testStreamSimp, Raw: "<13>no object"},
+	}}
+	publish := func(ctx context.Context, payload message.Payload) error {
+		return publisher.PublishTo(ctx, payload, topic, 0, false)
+	}
+	var compressed []byte
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), &batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	var sampled []string
+	for _, p := range publisher.routed {
+		if p.topic == "analytics/sample" {
+			sampled = append(sampled, p.lines...)
+		}
+	}
+	return sampled
+}
+
+func TestSampler_EveryNthReduced(t *testing.T) {
+	hp, publisher := sampleHotPath(t, func(*config.MQTTConfig) {})
+	before := metrics.SamplesPublished.Value()
+
+	got := publishSampleBatch(t, hp, publisher, "logs")
+
+	want := []string{
+		"2-0\t" + testStreamSimp + "\t" + `{"severity":"INFO","hostname":"web2"}`,
+		"4-0\t" + testStreamSimp + "\t{}",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sampled lines = %q; want %q", got, want)
+	}
+	if d := metrics.SamplesPublished.Value() - before; d != 2 {
+		t.Errorf("SamplesPublished delta = %d; want 2", d)
+	}
+}
+
+func TestSampler_Percent(t *testing.T) {
+	for _, tc := range []struct {
+		percent int
+		want    int
+	}{{percent: 100, want: 4}, {percent: 0, want: 0}} {
+		hp, publisher := sampleHotPath(t, func(cfg *config.MQTTConfig) {
+			cfg.SampleEvery = 0
+			cfg.SamplePercent = tc.percent
+		})
+		if got := publishSampleBatch(t, hp, publisher, "logs"); len(got) != tc.want {
+			t.Errorf("percent %d: sampled %d lines; want %d", tc.percent, len(got), tc.want)
+		}
+		if tc.want == 0 && len(publisher.routed) != 1 {
+			t.Errorf("percent 0: %d payloads published; want no sample payload", len(publisher.routed))
+		}
+	}
+}
+
+func TestSampler_SkippedWhenPublishFails(t *testing.T) {
+	hp, publisher := sampleHotPath(t, func(*config.MQTTConfig) {})
+	if got := publishSampleBatch(t, hp, publisher, "logs/fail"); len(got) != 0 {
+		t.Errorf("sampled lines = %q; want none for a batch that failed to publish", got)
+	}
+}
+
+func TestSampler_FailureOnlyCounted(t *testing.T) {
+	hp, publisher := sampleHotPath(t, func(*config.MQTTConfig) {})
+	publisher.failTopic = "analytics/sample"
+	published, sampleErrors := metrics.MessagesPublished.Value(), metrics.SampleErrors.Value()
+
+	publishSampleBatch(t, hp, publisher, "logs")
+
+	if d := metrics.MessagesPublished.Value() - published; d != 4 {
+		t.Errorf("MessagesPublished delta = %d; want 4 despite the failed sample", d)
+	}
+	if d := metrics.SampleErrors.Value() - sampleErrors; d != 1 {
+		t.Errorf("SampleErrors delta = %d; want 1", d)
+	}
+}
+
+func TestNew_SamplerRequirements(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.SampleTopic = "analytics/sample"
+	cfg.MQTT.SamplePercent = 10
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for a publisher without PublishTo")
+	}
+
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.sampler != nil || hp.sampler.lines() != nil {
+		t.Error("sampler != nil; want no sampler without MQTT_SAMPLE_TOPIC")
+	}
+}
@@ -0,0 +1,341 @@
+package hotpath
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// eventLog records the order in which pipeline stages touch the mocks.
+type eventLog struct {
+	events []string
+	mu     sync.Mutex
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+}
+
+func (l *eventLog) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
+
+// streamingRedis hands out one-message batches as fast as they are read,
+// so the fetch loop is always mid-enqueue when shutdown starts.
+func streamingRedis(events *eventLog) *mockRedis {
+	var seq atomic.Int64
+	return &mockRedis{
+		readBatchFn: func(_ context.Context) (message.Batch, error) {
+			if events != nil {
+				events.add("fetch")
+			}
+			id := strconv.FormatInt(seq.Add(1), 10) + "-0"
+			return message.Batch{Items: []message.Redis{{ID: id, Stream: testStreamSimp, Object: testObjectKV}}}, nil
+		},
+	}
+}
+
+func runFor(t *testing.T, hp *HotPath, d time.Duration) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), d)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+	select {
+	case err := <-done:
+		checkLoopExit(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+// TestRun_ShutdownDrainsQueueAfterProducersStop runs repeated shutdowns
+// against a fetch loop that never idles: every batch that made it into the
+// queue must be published, and nothing may be left behind.
+func TestRun_ShutdownDrainsQueueAfterProducersStop(t *testing.T) {
+	for i := range 20 {
+		var published atomic.Int64
+		pub := &mockPublisher{
+			publishFn: func(_ context.Context, _ message.Payload) error {
+				published.Add(1)
+				return nil
+			},
+		}
+		hp, err := New(streamingRedis(nil), pub, testConfig(), log.New())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		runFor(t, hp, 20*time.Millisecond)
+
+		if n := len(hp.msgChan); n != 0 {
+			t.Fatalf("iteration %d: %d batches left in queue after shutdown", i, n)
+		}
+		if published.Load() == 0 {
+			t.Fatalf("iteration %d: nothing published", i)
+		}
+		closeHotPath(t, hp)
+	}
+}
+
+// TestRun_AckDuringShutdown keeps delivering ACKs while the pipeline shuts
+// down and after it has stopped; a late callback must never hit a closed
+// channel.
+func TestRun_AckDuringShutdown(t *testing.T) {
+	for range 10 {
+		handlerCh := make(chan func(message.AckMessage), 1)
+		pub := &mockPublisher{
+			subscribeAckFn: func(_ context.Context, h func(message.AckMessage)) error {
+				handlerCh <- h
+				return nil
+			},
+		}
+		hp, err := New(&mockRedis{}, pub, testConfig(), log.New())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		stop := make(chan struct{})
+		var senders sync.WaitGroup
+		senders.Go(func() {
+			handler := <-handlerCh
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					handler(message.AckMessage{IDs: []string{testMsgID1}, Stream: testStreamS1, Ack: true})
+				}
+			}
+		})
+
+		runFor(t, hp, 20*time.Millisecond)
+		time.Sleep(5 * time.Millisecond) // keep sending after the ACK queues are closed
+		close(stop)
+		senders.Wait()
+		closeHotPath(t, hp)
+	}
+}
+
+// TestRun_ShutdownOrder checks the phase order: no fetch or publish after the
+// ACK unsubscribe, and pending ACKs flushed only after it.
+func TestRun_ShutdownOrder(t *testing.T) {
+	events := &eventLog{}
+	handlerCh := make(chan func(message.AckMessage), 1)
+	pub := &mockPublisher{
+		publishFn: func(_ context.Context, _ message.Payload) error {
+			events.add("publish")
+			return nil
+		},
+		subscribeAckFn: func(_ context.Context, h func(message.AckMessage)) error {
+			handlerCh <- h
+			return nil
+		},
+		unsubscribeAckFn: func(_ context.Context) error {
+			events.add("unsubscribe")
+			return nil
+		},
+	}
+	rds := streamingRedis(events)
+	rds.ackAndDeleteFn = func(_ context.Context, _ []string, _ string) error {
+		events.add("ack")
+		return nil
+	}
+
+	cfg := testConfig()
+	cfg.Pipeline.AckFlushInterval = time.Hour // only the shutdown flush may ACK
+	cfg.Pipeline.AckBatchSize = 1000
+	hp, err := New(rds, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+	(<-handlerCh)(message.AckMessage{IDs: []string{testMsgID1}, Stream: testStreamS1, Ack: true})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	checkLoopExit(t, <-done)
+
+	got := events.snapshot()
+	unsub := slices.Index(got, "unsubscribe")
+	if unsub < 0 {
+		t.Fatalf("UnsubscribeAck was not called; events: %d", len(got))
+	}
+	for _, e := range got[unsub+1:] {
+		if e == "fetch" || e == "publish" {
+			t.Fatalf("%s happened after the ACK unsubscribe", e)
+		}
+	}
+	if !slices.Contains(got[unsub+1:], "ack") {
+		t.Error("pending ACK was not flushed after the unsubscribe")
+	}
+	if slices.Contains(got[:unsub], "ack") {
+		t.Error("ACK flushed before shutdown despite a 1h flush interval")
+	}
+}
+
+// TestRun_DrainTimeoutAbandonsQueue blocks every publish until it is
+// aborted; shutdown must give up after DrainTimeout instead of hanging.
+func TestRun_DrainTimeoutAbandonsQueue(t *testing.T) {
+	var unsubscribed atomic.Bool
+	pub := &mockPublisher{
+		publishFn: func(ctx context.Context, _ message.Payload) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		unsubscribeAckFn: func(_ context.Context) error {
+			unsubscribed.Store(true)
+			return nil
+		},
+	}
+	cfg := testConfig()
+	cfg.Pipeline.DrainTimeout = 50 * time.Millisecond
+	hp, err := New(streamingRedis(nil), pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	start := time.Now()
+	runFor(t, hp, 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %v; want about DrainTimeout", elapsed)
+	}
+	if !unsubscribed.Load() {
+		t.Error("UnsubscribeAck was not called after an aborted drain")
+	}
+}
+
+// runWithACK runs hp, delivers ack once the ACK topic is subscribed and
+// stops it; handlerCh receives the ACK handler from the mock publisher.
+func runWithACK(t *testing.T, hp *HotPath, handlerCh <-chan func(message.AckMessage), ack message.AckMessage) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+	(<-handlerCh)(ack)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		checkLoopExit(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func ackDrainPublisher() (*mockPublisher, chan func(message.AckMessage)) {
+	handlerCh := make(chan func(message.AckMessage), 1)
+	return &mockPublisher{
+		subscribeAckFn: func(_ context.Context, h func(message.AckMessage)) error {
+			handlerCh <- h
+			return nil
+		},
+	}, handlerCh
+}
+
+func TestRun_AckDrainCountsFlushed(t *testing.T) {
+	pub, handlerCh := ackDrainPublisher()
+	cfg := testConfig()
+	cfg.Pipeline.AckFlushInterval = time.Hour // only the shutdown flush may ACK
+	cfg.Pipeline.AckBatchSize = 1000
+	hp, err := New(&mockRedis{}, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	runWithACK(t, hp, handlerCh, message.AckMessage{IDs: []string{testMsgID1, "2-0"}, Stream: testStreamS1, Ack: true})
+	if flushed, abandoned := hp.ackDrain.flushed.Load(), hp.ackDrain.abandoned.Load(); flushed != 2 || abandoned != 0 {
+		t.Errorf("flushed %d, abandoned %d on shutdown; want 2 and 0", flushed, abandoned)
+	}
+}
+
+// TestRun_AckDrainTimeoutAbandons blocks every XACK until it is abandoned;
+// shutdown must give up after AckDrainTimeout instead of AckTimeout.
+func TestRun_AckDrainTimeoutAbandons(t *testing.T) {
+	pub, handlerCh := ackDrainPublisher()
+	rds := &mockRedis{ackAndDeleteFn: func(ctx context.Context, _ []string, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	cfg := testConfig()
+	cfg.Pipeline.AckFlushInterval = time.Hour
+	cfg.Pipeline.AckBatchSize = 1000
+	cfg.Pipeline.AckDrainTimeout = 50 * time.Millisecond
+	hp, err := New(rds, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	start := time.Now()
+	runWithACK(t, hp, handlerCh, message.AckMessage{IDs: []string{testMsgID1, "2-0"}, Stream: testStreamS1, Ack: true})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %v; want about AckDrainTimeout", elapsed)
+	}
+	if flushed, abandoned := hp.ackDrain.flushed.Load(), hp.ackDrain.abandoned.Load(); flushed != 0 || abandoned != 2 {
+		t.Errorf("flushed %d, abandoned %d on shutdown; want 0 and 2", flushed, abandoned)
+	}
+}
+
+// TestRun_DefaultDrainsFitShutdownTimeout stalls both the publish drain
+// and the ACK drain with the shutdown and drain timeouts in the ratio of
+// their defaults; both drains together must end within the shutdown
+// timeout, with the ACK drain's counts recorded.
+func TestRun_DefaultDrainsFitShutdownTimeout(t *testing.T) {
+	pub, handlerCh := ackDrainPublisher()
+	pub.publishFn = func(ctx context.Context, _ message.Payload) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	rds := streamingRedis(nil)
+	rds.ackAndDeleteFn = func(ctx context.Context, _ []string, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	cfg := testConfig()
+	cfg.Pipeline.AckFlushInterval = time.Hour
+	cfg.Pipeline.AckBatchSize = 1000
+	cfg.Pipeline.ShutdownTimeout = 400 * time.Millisecond
+	cfg.Pipeline.DrainTimeout = 200 * time.Millisecond
+	hp, err := New(rds, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- hp.Run(ctx) }()
+	(<-handlerCh)(message.AckMessage{IDs: []string{testMsgID1}, Stream: testStreamS1, Ack: true})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	start := time.Now()
+	select {
+	case err := <-done:
+		checkLoopExit(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	if elapsed := time.Since(start); elapsed >= cfg.Pipeline.ShutdownTimeout {
+		t.Errorf("shutdown took %v; want less than the %v shutdown timeout", elapsed, cfg.Pipeline.ShutdownTimeout)
+	}
+	if abandoned := hp.ackDrain.abandoned.Load(); abandoned != 1 {
+		t.Errorf("abandoned %d ACKs on shutdown; want 1", abandoned)
+	}
+}
@@ -0,0 +1,302 @@
+package hotpath
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// Ack latencies are counted in buckets of about 12.5%: one per millisecond
+// under 16 ms, then eight per power of two up to 2^40 ms.
+const (
+	latencyExactBuckets = 16
+	latencyMaxShift     = 37
+	latencyBuckets      = (latencyMaxShift+1)*8 + 8
+)
+
+// latencyBucket returns the bucket of a latency of ms milliseconds.
+func latencyBucket(ms int64) int {
+	if ms < latencyExactBuckets {
+		return int(max(ms, 0))
+	}
+	shift := min(bits.Len64(uint64(ms))-4, latencyMaxShift)
+	top := min(ms>>shift, 15)
+	return shift*8 + int(top)
+}
+
+// latencyUpper returns the largest latency of bucket b, in milliseconds.
+func latencyUpper(b int) int64 {
+	if b < latencyExactBuckets {
+		return int64(b)
+	}
+	shift := b/8 - 1
+	top := int64(b%8 + 8)
+	return (top+1)<<shift - 1
+}
+
+// sloStream is a stream's ack latencies over the current window, and how
+// many windows in a row it missed the objective.
+type sloStream struct {
+	latency  *expvar.Int // the stream's consumer.ack_latency_ms
+	buckets  [latencyBuckets]uint32
+	total    int64
+	over     int64 // latencies past the objective
+	missed   int
+	breached bool
+}
+
+// reset clears the window.
+func (s *sloStream) reset() {
+	clear(s.buckets[:])
+	s.total, s.over = 0, 0
+}
+
+// percentile returns the upper bound of the bucket holding the p
+// percentile of the window, in milliseconds.
+func (s *sloStream) percentile(p float64) int64 {
+	rank := uint64(math.Ceil(float64(s.total) * p / 100))
+	var seen uint64
+	for b, n := range s.buckets {
+		seen += uint64(n)
+		if seen >= rank {
+			return latencyUpper(b)
+		}
+	}
+	return latencyUpper(latencyBuckets - 1)
+}
+
+// ackSLO tracks the PIPELINE_ACK_SLO_PERCENTILE percentile of the ack
+// latency of each stream, from an entry's ID time to its ACK, against
+// PIPELINE_ACK_SLO_LATENCY. A stream that misses it for
+// PIPELINE_ACK_SLO_WINDOWS windows in a row is breached: an alert goes to
+// MQTT_CONTROL_TOPIC and the health endpoint reports the pipeline degraded,
+// until a window of the stream meets the objective or has no ACK. A nil
+// ackSLO records nothing.
+type ackSLO struct {
+	control   mqtt.TopicPublisher // nil without MQTT_CONTROL_TOPIC
+	streams   map[string]*sloStream
+	topic     string
+	mu        sync.Mutex
+	objective int64 // ms
+	window    time.Duration
+	p         float64
+	windows   int
+	qos       byte
+}
+
+// sloAlert is a change of a stream's state, published once the lock is
+// released.
+type sloAlert struct {
+	stream   string
+	latency  int64
+	breached bool
+}
+
+// sloEvent is an alert as published to the control topic.
+type sloEvent struct {
+	State      string  `json:"ack_slo"` // breached or resolved
+	Stream     string  `json:"stream"`
+	Percentile float64 `json:"percentile"`
+	LatencyMs  int64   `json:"latency_ms"`
+	Objective  int64   `json:"objective_ms"`
+	Windows    int     `json:"windows"`
+}
+
+func validateAckSLO(topics mqtt.TopicPublisher, cfg *config.Config) error {
+	if cfg.Pipeline.AckSLOLatency == 0 || cfg.MQTT.ControlTopic == "" {
+		return nil
+	}
+	if topics == nil {
+		return errors.New("hotpath: the control topic needs an mqtt publisher that can publish to other topics")
+	}
+	return nil
+}
+
+func newAckSLO(topics mqtt.TopicPublisher, cfg *config.Config) *ackSLO {
+	if cfg.Pipeline.AckSLOLatency == 0 {
+		return nil
+	}
+	s := &ackSLO{
+		streams:   make(map[string]*sloStream),
+		topic:     cfg.MQTT.ControlTopic,
+		objective: cfg.Pipeline.AckSLOLatency.Milliseconds(),
+		window:    cfg.Pipeline.AckSLOWindow,
+		p:         cfg.Pipeline.AckSLOPercentile,
+		windows:   cfg.Pipeline.AckSLOWindows,
+		qos:       cfg.MQTT.QoS,
+	}
+	if s.topic != "" {
+		s.control = topics
+	}
+	return s
+}
+
+// record adds the latencies of the entries ack acknowledges at now. NACKs
+// and IDs without a time are left out.
+func (s *ackSLO) record(ack *message.AckMessage, now time.Time) {
+	if s == nil || !ack.Ack {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.streams[ack.Stream]
+	if st == nil {
+		st = &sloStream{}
+		s.streams[ack.Stream] = st
+	}
+	for _, id := range ack.IDs {
+		t, ok := entryTime(id)
+		if !ok {
+			continue
+		}
+		ms := now.Sub(t).Milliseconds()
+		st.buckets[latencyBucket(ms)]++
+		st.total++
+		if ms > s.objective {
+			st.over++
+		}
+	}
+}
+
+// sloLoop closes a window every PIPELINE_ACK_SLO_WINDOW.
+func (hp *HotPath) sloLoop(ctx context.Context) error {
+	ticker := time.NewTicker(hp.slo.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		for _, alert := range hp.slo.check() {
+			hp.slo.alert(ctx, hp.log, alert)
+		}
+	}
+}
+
+// check closes the window of every stream and returns the streams that
+// were breached or recovered by it.
+func (s *ackSLO) check() []sloAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var alerts []sloAlert
+	var breached int64
+	for stream, st := range s.streams {
+		alert, changed := s.close(stream, st)
+		if changed {
+			alerts = append(alerts, alert)
+		}
+		if st.breached {
+			breached++
+		}
+	}
+	metrics.AckSLOBreached.Set(breached)
+	return alerts
+}
+
+// close ends the window of stream, reporting whether it changed the
+// stream's state. A stream with no ACK in the window is forgotten.
+func (s *ackSLO) close(stream string, st *sloStream) (sloAlert, bool) {
+	defer st.reset()
+	if st.total == 0 {
+		delete(s.streams, stream)
+		metrics.AckLatencyMs.Delete(stream)
+		was := st.breached
+		st.breached = false
+		return sloAlert{stream: stream}, was
+	}
+	latency := st.percentile(s.p)
+	if st.latency == nil {
+		st.latency = new(expvar.Int)
+		metrics.AckLatencyMs.Set(stream, st.latency)
+	}
+	st.latency.Set(latency)
+	// The percentile misses the objective when more than the remaining
+	// share of the window is over it.
+	if float64(st.over) <= float64(st.total)*(100-s.p)/100 {
+		st.missed = 0
+		if !st.breached {
+			return sloAlert{}, false
+		}
+		st.breached = false
+		return sloAlert{stream: stream, latency: latency}, true
+	}
+	metrics.AckSLOViolations.Add(1)
+	st.missed++
+	if st.breached || st.missed < s.windows {
+		return sloAlert{}, false
+	}
+	st.breached = true
+	metrics.AckSLOAlerts.Add(1)
+	return sloAlert{stream: stream, latency: latency, breached: true}, true
+}
+
+// alert logs a change of a stream's state and publishes it to the control
+// topic, not retained: it is an event, and every stream shares the topic.
+func (s *ackSLO) alert(ctx context.Context, logger *log.Logger, a sloAlert) {
+	state := "resolved"
+	if a.breached {
+		state = "breached"
+		logger.Warnf(ctx, "Ack latency p%g of stream %s at %d ms, over %d ms for %d windows",
+			s.p, a.stream, a.latency, s.objective, s.windows)
+	} else {
+		logger.Infof(ctx, "Ack latency of stream %s back within %d ms", a.stream, s.objective)
+	}
+	if s.control == nil {
+		return
+	}
+	payload, err := json.Marshal(sloEvent{
+		State:      state,
+		Stream:     a.stream,
+		Percentile: s.p,
+		LatencyMs:  a.latency,
+		Objective:  s.objective,
+		Windows:    s.windows,
+	})
+	if err != nil {
+		return
+	}
+	if err = s.control.PublishTo(ctx, payload, s.topic, s.qos, false); err != nil && ctx.Err() == nil {
+		logger.Errorf(ctx, "Failed to publish the ack latency alert of %s to %s: %v", a.stream, s.topic, err)
+	}
+}
+
+// degraded returns why the pipeline is degraded, or "" when no stream is
+// breached.
+func (s *ackSLO) degraded() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	var streams []string
+	for stream, st := range s.streams {
+		if st.breached {
+			streams = append(streams, stream)
+		}
+	}
+	s.mu.Unlock()
+	if len(streams) == 0 {
+		return ""
+	}
+	slices.Sort(streams)
+	return "ack latency over its objective on " + strings.Join(streams, ", ")
+}
+
+// Degraded reports the streams whose ack latency is over
+// PIPELINE_ACK_SLO_LATENCY, for the health endpoint.
+func (hp *HotPath) Degraded() string {
+	return hp.slo.degraded()
+}
@@ -0,0 +1,155 @@
+package hotpath
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// alertPublisher records the events published to the control topic.
+type alertPublisher struct {
+	mockPublisher
+	events []sloEvent
+}
+
+func (m *alertPublisher) PublishTo(
+	_ context.Context, payload message.Payload, topic string, _ byte, retain bool,
+) error {
+	if topic != "control" || retain {
+		return errors.New("want a publish to the control topic, not retained")
+	}
+	var event sloEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestLatencyBucket(t *testing.T) {
+	for _, ms := range []int64{-5, 0, 1, 15, 16, 17, 100, 1999, 2000, 2001, 60000, 1 << 40, 1 << 50} {
+		b := latencyBucket(ms)
+		if b < 0 || b >= latencyBuckets {
+			t.Fatalf("latencyBucket(%d) = %d; want in [0, %d)", ms, b, latencyBuckets)
+		}
+		upper := latencyUpper(b)
+		if ms > 1<<40 {
+			continue // past the largest bucket
+		}
+		if upper < ms || float64(upper) > float64(max(ms, 0))*1.125+1 {
+			t.Errorf("latencyUpper(latencyBucket(%d)) = %d; want within 12.5%% above it", ms, upper)
+		}
+	}
+}
+
+// ackedAt returns an ACK of n entries of stream added age before now.
+func ackedAt(stream string, now time.Time, age time.Duration, n int) *message.AckMessage {
+	id := strconv.FormatInt(now.Add(-age).UnixMilli(), 10) + "-0"
+	ack := &message.AckMessage{Stream: stream, Ack: true}
+	for range n {
+		ack.IDs = append(ack.IDs, id)
+	}
+	return ack
+}
+
+func TestAckSLO_AlertsAfterConsecutiveWindows(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.ControlTopic = "control"
+	cfg.Pipeline.AckSLOLatency = 2 * time.Second
+	cfg.Pipeline.AckSLOPercentile = 99
+	cfg.Pipeline.AckSLOWindow = time.Minute
+	cfg.Pipeline.AckSLOWindows = 2
+	pub := &alertPublisher{}
+	hp, err := New(&mockRedis{}, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	s := hp.slo
+	now := time.Now()
+	window := func(slow int) {
+		s.record(ackedAt("fw", now, 100*time.Millisecond, 100-slow), now)
+		s.record(ackedAt("fw", now, 5*time.Second, slow), now)
+		for _, a := range s.check() {
+			s.alert(t.Context(), hp.log, a)
+		}
+	}
+	violations := metrics.AckSLOViolations.Value()
+
+	window(1) // p99 is still fast
+	window(5)
+	if len(pub.events) != 0 || hp.Degraded() != "" {
+		t.Fatalf("after one slow window: events = %+v, degraded = %q; want neither", pub.events, hp.Degraded())
+	}
+	window(5)
+	if len(pub.events) != 1 || pub.events[0].State != "breached" || pub.events[0].Stream != "fw" {
+		t.Fatalf("events = %+v; want fw breached", pub.events)
+	}
+	if got := pub.events[0].LatencyMs; got < 5000 || got > 5700 {
+		t.Errorf("latency_ms = %d; want about 5000", got)
+	}
+	if want := "ack latency over its objective on fw"; hp.Degraded() != want {
+		t.Errorf("Degraded() = %q; want %q", hp.Degraded(), want)
+	}
+	if got := metrics.AckSLOViolations.Value() - violations; got != 2 {
+		t.Errorf("AckSLOViolations delta = %d; want 2", got)
+	}
+	if metrics.AckSLOBreached.Value() != 1 {
+		t.Errorf("AckSLOBreached = %d; want 1", metrics.AckSLOBreached.Value())
+	}
+
+	window(5) // still breached, no new alert
+	window(0)
+	if len(pub.events) != 2 || pub.events[1].State != "resolved" {
+		t.Fatalf("events = %+v; want fw resolved", pub.events)
+	}
+	if hp.Degraded() != "" || metrics.AckSLOBreached.Value() != 0 {
+		t.Errorf("Degraded() = %q, AckSLOBreached = %d; want neither", hp.Degraded(), metrics.AckSLOBreached.Value())
+	}
+}
+
+func TestAckSLO_IdleStreamResolves(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.AckSLOLatency = time.Second
+	cfg.Pipeline.AckSLOPercentile = 99
+	cfg.Pipeline.AckSLOWindows = 1
+	s := newAckSLO(nil, cfg)
+	now := time.Now()
+
+	s.record(&message.AckMessage{Stream: "fw", IDs: []string{"1-0"}}, now) // a NACK
+	s.record(ackedAt("fw", now, time.Minute, 1), now)
+	if alerts := s.check(); len(alerts) != 1 || !alerts[0].breached {
+		t.Fatalf("check() = %+v; want fw breached", alerts)
+	}
+	if alerts := s.check(); len(alerts) != 1 || alerts[0].breached {
+		t.Fatalf("check() = %+v; want fw resolved without ACKs", alerts)
+	}
+	if len(s.streams) != 0 || s.degraded() != "" {
+		t.Errorf("streams = %v, degraded = %q; want the idle stream forgotten", s.streams, s.degraded())
+	}
+}
+
+func TestNewAckSLO_OffByDefault(t *testing.T) {
+	cfg := testConfig()
+	if s := newAckSLO(nil, cfg); s != nil {
+		t.Errorf("newAckSLO() = %+v; want nil", s)
+	}
+	var hp HotPath
+	hp.slo.record(ackedAt("fw", time.Now(), time.Hour, 1), time.Now())
+	if hp.Degraded() != "" {
+		t.Errorf("Degraded() = %q; want \"\"", hp.Degraded())
+	}
+
+	cfg.Pipeline.AckSLOLatency = time.Second
+	cfg.MQTT.ControlTopic = "control"
+	if err := validateAckSLO(topicsOf(&mockPublisher{}), cfg); err == nil {
+		t.Error("validateAckSLO() error = nil; want error for a control topic without PublishTo")
+	}
+}
@@ -0,0 +1,116 @@
+package hotpath
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is the format of the files written by saveSnapshot. A
+// file of any other version is ignored, so a format change costs one cold
+// start rather than a misread state.
+const snapshotVersion = 1
+
+// snapshot is the in-memory state kept across restarts under
+// PIPELINE_SNAPSHOT_PATH: the NACK backoffs still running and the adaptive
+// read size. The rest of the state a warm start needs already outlives the
+// process: streams are rediscovered by the Redis client, the read position
+// is the group's, and the dedup window lives in Redis.
+type snapshot struct {
+	SavedAt  time.Time      `json:"saved_at"`
+	Holds    []snapshotHold `json:"holds,omitempty"`
+	Version  int            `json:"version"`
+	ReadSize int            `json:"read_size,omitempty"`
+}
+
+// snapshotHold is one entry of the NACK backoff table.
+type snapshotHold struct {
+	Until  time.Time `json:"until"`
+	Stream string    `json:"stream"`
+	ID     string    `json:"id"`
+}
+
+// loadSnapshot restores the state saved by the previous run. A missing,
+// unreadable, or foreign snapshot only costs a cold start.
+func (hp *HotPath) loadSnapshot(ctx context.Context) {
+	if hp.snapshotPath == "" {
+		return
+	}
+	s, err := readSnapshot(hp.snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		hp.log.Warnf(ctx, "Ignoring state snapshot, starting cold: %v", err)
+		return
+	}
+	holds := hp.nacks.restore(s.Holds, time.Now())
+	hp.readSize.restore(s.ReadSize)
+	hp.log.Infof(ctx, "Restored state snapshot saved at %s: %d NACK backoffs, read size %d",
+		s.SavedAt.Format(time.RFC3339), holds, hp.readSize.current())
+}
+
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("%s has version %d; want %d", path, s.Version, snapshotVersion)
+	}
+	return &s, nil
+}
+
+// saveSnapshot writes the state for the next run. It runs once the ACK
+// workers are done, so no backoff is added after it.
+func (hp *HotPath) saveSnapshot(ctx context.Context) {
+	if hp.snapshotPath == "" {
+		return
+	}
+	now := time.Now()
+	s := snapshot{
+		SavedAt:  now,
+		Holds:    hp.nacks.snapshot(now),
+		Version:  snapshotVersion,
+		ReadSize: hp.readSize.current(),
+	}
+	if err := writeSnapshot(hp.snapshotPath, &s); err != nil {
+		hp.log.Warnf(ctx, "Failed to save state snapshot: %v", err)
+		return
+	}
+	hp.log.Infof(ctx, "Saved state snapshot to %s: %d NACK backoffs", hp.snapshotPath, len(s.Holds))
+}
+
+// writeSnapshot replaces the file at path with s through a temporary file
+// in the same directory, so a crash mid-write leaves the old snapshot.
+func writeSnapshot(path string, s *snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name()) // best effort; the write already failed
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package hotpath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func snapshotHotPath(t *testing.T, path string) *HotPath {
+	t.Helper()
+	cfg := testConfig()
+	adaptive := adaptiveConfig()
+	adaptive.Stream = cfg.Redis.Stream
+	adaptive.ClaimIdle = cfg.Redis.ClaimIdle
+	adaptive.CleanupInterval = cfg.Redis.CleanupInterval
+	adaptive.ConsumerIdleTimeout = cfg.Redis.ConsumerIdleTimeout
+	cfg.Redis = *adaptive
+	cfg.Pipeline.SnapshotPath = path
+	hp, err := New(&batchSizerRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	return hp
+}
+
+func TestSnapshot_RestoresBackoffsAndReadSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	until := time.Now().Add(time.Hour)
+
+	saved := snapshotHotPath(t, path)
+	saved.nacks.hold(testStreamSimp, []string{"1-0", "2-0"}, until)
+	saved.nacks.hold(testStreamSimp, []string{"3-0"}, time.Now().Add(-time.Second))
+	saved.readSize.resize(1500)
+	saved.saveSnapshot(t.Context())
+
+	restored := snapshotHotPath(t, path)
+	restored.loadSnapshot(t.Context())
+	if len(restored.nacks.holds) != 2 {
+		t.Fatalf("holds = %v; want the two running backoffs", restored.nacks.holds)
+	}
+	if got := restored.nacks.holds[heldEntry{stream: testStreamSimp, id: "1-0"}]; !got.Equal(until) {
+		t.Errorf("hold of 1-0 ends at %v; want %v", got, until)
+	}
+	if got := restored.readSize.current(); got != 1500 {
+		t.Errorf("read size = %d; want 1500", got)
+	}
+}
+
+func TestLoadSnapshot_DropsExpiredBackoffs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := snapshot{
+		Holds:   []snapshotHold{{Until: time.Now().Add(-time.Minute), Stream: testStreamSimp, ID: "1-0"}},
+		Version: snapshotVersion,
+	}
+	if err := writeSnapshot(path, &s); err != nil {
+		t.Fatalf("writeSnapshot() error = %v", err)
+	}
+
+	hp := snapshotHotPath(t, path)
+	hp.loadSnapshot(t.Context())
+	if len(hp.nacks.holds) != 0 {
+		t.Errorf("holds = %v; want the expired backoff dropped", hp.nacks.holds)
+	}
+	if got := hp.readSize.current(); got != 1000 {
+		t.Errorf("read size = %d; want REDIS_BATCH_SIZE kept without a saved size", got)
+	}
+}
+
+func TestLoadSnapshot_IgnoresUnusableFiles(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"other version": `{"version":2,"holds":[{"until":"2999-01-01T00:00:00Z","stream":"s","id":"1-0"}]}`,
+		"corrupt":       `{"version":`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			hp := snapshotHotPath(t, path)
+			hp.loadSnapshot(t.Context())
+			if len(hp.nacks.holds) != 0 {
+				t.Errorf("holds = %v; want none from an unusable snapshot", hp.nacks.holds)
+			}
+		})
+	}
+
+	hp := snapshotHotPath(t, filepath.Join(dir, "missing.json"))
+	hp.loadSnapshot(t.Context())
+	if len(hp.nacks.holds) != 0 {
+		t.Errorf("holds = %v; want none without a snapshot", hp.nacks.holds)
+	}
+}
+
+func TestSaveSnapshot_LeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	hp := snapshotHotPath(t, filepath.Join(dir, "state.json"))
+	hp.saveSnapshot(t.Context())
+	hp.saveSnapshot(t.Context())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.json" {
+		t.Errorf("directory holds %v; want state.json only", entries)
+	}
+}
@@ -0,0 +1,119 @@
+package hotpath
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// sdMaxDepth bounds the nesting flattened into a key, as FlattenObject
+// does; deeper objects are written as values.
+const sdMaxDepth = 64
+
+var sdPrefix = []byte("sd")
+
+// structuredData flattens structured_data under PIPELINE_STRUCTURED_DATA=
+// prefixed: every leaf is written as "sd.<SD-ID>.<PARAM-NAME>" (one more
+// level per nested object), so rule engines can match a parameter of an
+// element without parsing nested JSON. A key the record already has, as a
+// field of the entry or an earlier structured data key, is written as
+// "<key>_2", "<key>_3" and so on instead. A nil structuredData writes the
+// leaves under their own names.
+type structuredData struct {
+	scratch sync.Pool // of *sdKeys
+}
+
+func newStructuredData(mode string) *structuredData {
+	if mode != config.StructuredDataPrefixed {
+		return nil
+	}
+	return &structuredData{scratch: sync.Pool{New: func() any { return new(sdKeys) }}}
+}
+
+// sdKeys holds the names one record has so far, back to back in names,
+// and the key being composed.
+type sdKeys struct {
+	names []byte
+	ends  []int
+	key   []byte
+}
+
+func (k *sdKeys) reset() {
+	k.names = k.names[:0]
+	k.ends = k.ends[:0]
+	k.key = append(k.key[:0], sdPrefix...)
+}
+
+func (k *sdKeys) has(name []byte) bool {
+	start := 0
+	for _, end := range k.ends {
+		if bytes.Equal(k.names[start:end], name) {
+			return true
+		}
+		start = end
+	}
+	return false
+}
+
+func (k *sdKeys) add(name []byte) {
+	k.names = append(k.names, name...)
+	k.ends = append(k.ends, len(k.names))
+}
+
+// unique records k.key, suffixed when the record already has it, and
+// returns it.
+func (k *sdKeys) unique(stats *projectionStats) []byte {
+	if !k.has(k.key) {
+		k.add(k.key)
+		return k.key
+	}
+	stats.sdRenamed++
+	base := len(k.key)
+	for n := 2; ; n++ {
+		k.key = strconv.AppendInt(append(k.key[:base], '_'), int64(n), 10)
+		if !k.has(k.key) {
+			k.add(k.key)
+			return k.key
+		}
+	}
+}
+
+// walk writes the leaves of the object data below the key k.key.
+func (k *sdKeys) walk(builder *jsonfast.Builder, data []byte, depth int, stats *projectionStats) {
+	prefix := len(k.key)
+	jsonfast.IterateFields(data, func(key, value []byte) bool {
+		k.key = append(append(k.key[:prefix], '.'), key[1:len(key)-1]...)
+		if len(value) > 0 && value[0] == '{' && depth < sdMaxDepth {
+			k.walk(builder, value, depth+1, stats)
+			return true
+		}
+		builder.AddRawBytesField(k.unique(stats), value)
+		return true
+	})
+}
+
+// flatten writes the structured_data value of the entry object; keys the
+// projection drops do not count as taken.
+func (s *structuredData) flatten(
+	builder *jsonfast.Builder, object string, value []byte, projection *fieldProjection, stats *projectionStats,
+) {
+	if s == nil {
+		jsonfast.FlattenObject(builder, value)
+		return
+	}
+	k, _ := s.scratch.Get().(*sdKeys)
+	defer s.scratch.Put(k)
+	k.reset()
+	jsonfast.IterateFieldsString(object, func(key, _ []byte) bool {
+		if name := key[1 : len(key)-1]; projection.keep(name) && !bytes.Equal(name, keyStructuredData) {
+			k.add(name)
+		}
+		return true
+	})
+	k.add(keyRaw)
+	k.walk(builder, value, 0, stats)
+}
@@ -0,0 +1,71 @@
+package hotpath
+
+import (
+	"testing"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestBuildPayload_StructuredDataPrefixed(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		object      string
+		wantJSON    string
+		deny        []string
+		wantRenamed int64
+	}{
+		{
+			name:     "elements and params",
+			object:   `{"hostname":"h","structured_data":{"origin":{"ip":"10.0.0.1"},"meta":{"sequenceId":"7"}}}`,
+			wantJSON: `{"hostname":"h","sd.origin.ip":"10.0.0.1","sd.meta.sequenceId":"7","raw":"r"}`,
+		},
+		{
+			name:        "entry field wins",
+			object:      `{"structured_data":{"origin":{"ip":"10.0.0.1"}},"sd.origin.ip":"set upstream"}`,
+			wantJSON:    `{"sd.origin.ip_2":"10.0.0.1","sd.origin.ip":"set upstream","raw":"r"}`,
+			wantRenamed: 1,
+		},
+		{
+			name:        "colliding elements",
+			object:      `{"structured_data":{"a.b":{"c":"1"},"a":{"b.c":"2"}}}`,
+			wantJSON:    `{"sd.a.b.c":"1","sd.a.b.c_2":"2","raw":"r"}`,
+			wantRenamed: 1,
+		},
+		{
+			name:     "dropped field is free",
+			object:   `{"structured_data":{"origin":{"ip":"10.0.0.1"}},"sd.origin.ip":"set upstream"}`,
+			deny:     []string{"sd.origin.ip"},
+			wantJSON: `{"sd.origin.ip":"10.0.0.1","raw":"r"}`,
+		},
+		{
+			name:     "not an object",
+			object:   `{"structured_data":"-","severity":6}`,
+			wantJSON: `{"severity":"INFO","raw":"r"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Pipeline.StructuredData = config.StructuredDataPrefixed
+			cfg.Pipeline.FieldDenylist = tt.deny
+			hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer closeHotPath(t, hp)
+
+			var stats projectionStats
+			msg := message.Redis{ID: testMsgID1, Stream: testStreamSimp, Object: tt.object, Raw: "r"}
+			_, _, gotJSON := parseLine(t, hp.buildProjectedPayload(jsonfast.New(512), &msg, &stats))
+			if !jsonEqual([]byte(gotJSON), []byte(tt.wantJSON)) {
+				t.Errorf("JSON mismatch:\n  got:  %s\n  want: %s", gotJSON, tt.wantJSON)
+			}
+			if stats.sdRenamed != tt.wantRenamed {
+				t.Errorf("sdRenamed = %d; want %d", stats.sdRenamed, tt.wantRenamed)
+			}
+		})
+	}
+}
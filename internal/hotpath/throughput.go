@@ -0,0 +1,173 @@
+package hotpath
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// throughputCap applies PIPELINE_THROUGHPUT_SCHEDULE: a token bucket shared
+// by the publish workers, refilled at the cap of the window in force, so
+// the cap holds for the instance however its batches are spread across
+// them. A change of cap is spread over PIPELINE_THROUGHPUT_RAMP: the cap
+// moves in a straight line from the one in force, or from the throughput
+// of the last second when there was none, to the new one. When a window
+// ends with no other in force, the cap instead rises by its last value
+// every ramp until the bucket refills completely, which means the backlog
+// built up under it is gone, and is then lifted. A nil throughputCap lets
+// every entry through.
+type throughputCap struct {
+	since   time.Time // when the cap started moving to target
+	loc     *time.Location
+	bucket  *tokenBucket // nil while unlimited
+	windows []config.ThroughputWindow
+	meter   rateMeter
+	ramp    time.Duration
+	from    float64 // the cap when it started moving; zero when unlimited
+	target  int     // the cap of the window in force; zero when unlimited
+	mu      sync.Mutex
+}
+
+func newThroughputCap(cfg *config.PipelineConfig, now time.Time) *throughputCap {
+	if len(cfg.ThroughputSchedule) == 0 {
+		return nil
+	}
+	loc, _ := time.LoadLocation(cfg.ThroughputLocation) // checked by config.Validate
+	c := &throughputCap{loc: loc, ramp: cfg.ThroughputRamp, since: now}
+	for _, item := range cfg.ThroughputSchedule {
+		w, _ := config.ParseThroughputWindow(item) // checked by config.Validate
+		c.windows = append(c.windows, w)
+	}
+	// The cap in force at startup applies at once.
+	c.target = c.windowRate(now)
+	c.from = float64(c.target)
+	return c
+}
+
+// windowRate returns the rate of the first window holding now, or zero.
+func (c *throughputCap) windowRate(now time.Time) int {
+	t := now.In(c.loc)
+	for i := range c.windows {
+		if windowHolds(&c.windows[i], t) {
+			return c.windows[i].Rate
+		}
+	}
+	return 0
+}
+
+// windowHolds reports whether w holds t, read on t's clock. A window that
+// ends on the next day holds the end of its day and the start of the next.
+func windowHolds(w *config.ThroughputWindow, t time.Time) bool {
+	h, m, s := t.Clock()
+	clock := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	today, yesterday := t.Weekday(), (t.Weekday()+6)%7
+	if w.End > w.Start {
+		return w.Days[today] && clock >= w.Start && clock < w.End
+	}
+	return (w.Days[today] && clock >= w.Start) || (w.Days[yesterday] && clock < w.End)
+}
+
+// rate returns the cap at now, zero when unlimited, and whether the
+// window in force changed.
+func (c *throughputCap) rate(now time.Time) (float64, bool) {
+	target := c.windowRate(now)
+	if target == c.target {
+		return c.current(now), false
+	}
+	c.from = c.current(now)
+	if c.from == 0 {
+		c.from = max(c.meter.rate(), float64(target))
+	}
+	c.target, c.since = target, now
+	return c.current(now), true
+}
+
+// current returns the cap at now, zero when unlimited, on the way from
+// c.from to c.target.
+func (c *throughputCap) current(now time.Time) float64 {
+	progress := 1.0
+	if c.ramp > 0 {
+		progress = now.Sub(c.since).Seconds() / c.ramp.Seconds()
+	}
+	switch {
+	case c.from == 0 || (c.target == 0 && c.ramp == 0):
+		return float64(c.target)
+	case c.target == 0:
+		return c.from * (1 + progress)
+	case progress >= 1:
+		return float64(c.target)
+	default:
+		return c.from + (float64(c.target)-c.from)*progress
+	}
+}
+
+// take charges n entries to the cap at now. It returns how long they must
+// wait for it, and the new window's cap when the window in force changed.
+func (c *throughputCap) take(n int, now time.Time) (wait time.Duration, target int, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.meter.add(n, now)
+	rate, changed := c.rate(now)
+	if rate > 0 && c.bucket == nil {
+		c.bucket = &tokenBucket{last: now, tokens: rate, rate: rate}
+	}
+	c.bucket.refill(now)
+	if rate > 0 && c.target == 0 && c.bucket.tokens >= c.bucket.rate {
+		c.from = 0 // the backlog is gone: lift the cap
+		rate = 0
+	}
+	metrics.ThroughputCap.Set(int64(rate))
+	if rate == 0 {
+		c.bucket = nil
+		return 0, c.target, changed
+	}
+	c.bucket.rate = rate
+	c.bucket.tokens = min(c.bucket.tokens, rate)
+	c.bucket.take(float64(n))
+	return c.bucket.debt(), c.target, changed
+}
+
+// rateMeter measures the entries taken per second, over whole seconds.
+type rateMeter struct {
+	second int64
+	count  float64
+	last   float64 // the count of the second before
+}
+
+func (m *rateMeter) add(n int, now time.Time) {
+	if sec := now.Unix(); sec != m.second {
+		m.last = 0
+		if sec == m.second+1 {
+			m.last = m.count
+		}
+		m.second, m.count = sec, 0
+	}
+	m.count += float64(n)
+}
+
+func (m *rateMeter) rate() float64 {
+	return max(m.last, m.count)
+}
+
+// applyThroughput charges n entries about to be published to the
+// throughput cap and waits out its debt. It returns ctx's error if ctx is
+// done while waiting.
+func (hp *HotPath) applyThroughput(ctx context.Context, n int) error {
+	if hp.throughput == nil {
+		return nil
+	}
+	wait, target, changed := hp.throughput.take(n, time.Now())
+	if changed && target > 0 {
+		hp.log.Infof(ctx, "Throughput window started, capping publishes at %d entries/s", target)
+	} else if changed {
+		hp.log.Infof(ctx, "Throughput window ended, lifting the publish cap")
+	}
+	if wait <= 0 {
+		return nil
+	}
+	metrics.ThroughputDelayMs.Add(wait.Milliseconds())
+	return sleepCtx(ctx, wait)
+}
@@ -0,0 +1,151 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// monday is a Monday at 00:00 UTC.
+var monday = time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC)
+
+func throughputConfig(ramp time.Duration, schedule ...string) *config.PipelineConfig {
+	return &config.PipelineConfig{ThroughputSchedule: schedule, ThroughputLocation: "UTC", ThroughputRamp: ramp}
+}
+
+func TestWindowHolds_Overnight(t *testing.T) {
+	w, err := config.ParseThroughputWindow("fri 22:00-06:00=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	friday := monday.AddDate(0, 0, 4)
+	for _, tt := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{at: friday.Add(23 * time.Hour), want: true},
+		{at: friday.Add(29 * time.Hour), want: true},
+		{at: friday.Add(30 * time.Hour), want: false},
+		{at: friday.Add(5 * time.Hour), want: false},
+		{at: friday.Add(47 * time.Hour), want: false},
+	} {
+		if got := windowHolds(&w, tt.at); got != tt.want {
+			t.Errorf("windowHolds(%s) = %v; want %v", tt.at.Format(time.RFC1123), got, tt.want)
+		}
+	}
+}
+
+func TestThroughputCap_StartupWindowAppliesAtOnce(t *testing.T) {
+	now := monday.Add(10 * time.Hour)
+	c := newThroughputCap(throughputConfig(time.Minute, "mon-fri 08:00-18:00=10"), now)
+
+	if wait, target, changed := c.take(10, now); wait != 0 || target != 10 || changed {
+		t.Fatalf("take() = %v, %d, %v; want no wait within the cap", wait, target, changed)
+	}
+	if wait, _, _ := c.take(5, now); wait != 500*time.Millisecond {
+		t.Errorf("take() = %v; want 500ms for 5 entries over 10/s", wait)
+	}
+	if got := metrics.ThroughputCap.Value(); got != 10 {
+		t.Errorf("ThroughputCap = %d; want 10", got)
+	}
+}
+
+func TestThroughputCap_RampsBetweenWindows(t *testing.T) {
+	noon := monday.Add(12 * time.Hour)
+	c := newThroughputCap(throughputConfig(10*time.Second,
+		"mon 08:00-12:00=100", "mon 12:00-18:00=200"), noon.Add(-time.Second))
+
+	if _, target, changed := c.take(1, noon); target != 200 || !changed {
+		t.Fatalf("take() = %d, %v; want the 200/s window to start", target, changed)
+	}
+	for _, tt := range []struct {
+		after time.Duration
+		want  int64
+	}{
+		{after: 5 * time.Second, want: 150},
+		{after: 20 * time.Second, want: 200},
+	} {
+		c.take(1, noon.Add(tt.after))
+		if got := metrics.ThroughputCap.Value(); got != tt.want {
+			t.Errorf("ThroughputCap %v into the window = %d; want %d", tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestThroughputCap_RampsDownFromTheMeasuredRate(t *testing.T) {
+	eight := monday.Add(8 * time.Hour)
+	c := newThroughputCap(throughputConfig(10*time.Second, "mon 08:00-09:00=100"), eight.Add(-time.Minute))
+
+	if wait, target, _ := c.take(1000, eight.Add(-time.Second)); wait != 0 || target != 0 {
+		t.Fatalf("take() = %v, %d; want unlimited before the window", wait, target)
+	}
+	c.take(1000, eight)
+	c.take(1, eight.Add(5*time.Second))
+	if got := metrics.ThroughputCap.Value(); got != 550 {
+		t.Errorf("ThroughputCap = %d; want 550 halfway from the measured 1000/s to 100/s", got)
+	}
+}
+
+func TestThroughputCap_LiftsOnceTheBacklogIsGone(t *testing.T) {
+	nine := monday.Add(9 * time.Hour)
+	c := newThroughputCap(throughputConfig(10*time.Second, "mon 08:00-09:00=10"), nine.Add(-time.Minute))
+
+	c.take(30, nine.Add(-time.Second))
+	wait, target, changed := c.take(1, nine)
+	if wait <= 0 || target != 0 || !changed {
+		t.Fatalf("take() = %v, %d, %v; want the window to end with the cap still owed", wait, target, changed)
+	}
+	c.take(1, nine.Add(time.Second))
+	if got := metrics.ThroughputCap.Value(); got != 11 {
+		t.Errorf("ThroughputCap = %d; want 11 while the backlog drains", got)
+	}
+	if wait, _, _ := c.take(100, nine.Add(3*time.Second)); wait != 0 {
+		t.Errorf("take() = %v; want no wait once the bucket refilled", wait)
+	}
+	if got := metrics.ThroughputCap.Value(); got != 0 {
+		t.Errorf("ThroughputCap = %d; want 0 once lifted", got)
+	}
+}
+
+func TestApplyThroughput(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.ThroughputSchedule = []string{"* 00:00-24:00=1"}
+	cfg.Pipeline.ThroughputRamp = 0
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+
+	if err := hp.applyThroughput(t.Context(), 1); err != nil {
+		t.Fatalf("applyThroughput() error = %v", err)
+	}
+	before := metrics.ThroughputDelayMs.Value()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := hp.applyThroughput(ctx, 3); !errors.Is(err, context.Canceled) {
+		t.Errorf("applyThroughput() = %v; want context.Canceled while waiting", err)
+	}
+	if metrics.ThroughputDelayMs.Value() <= before {
+		t.Error("ThroughputDelayMs did not grow; want the wait counted")
+	}
+}
+
+func TestApplyThroughput_Unlimited(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.throughput != nil {
+		t.Fatal("throughput != nil; want none without PIPELINE_THROUGHPUT_SCHEDULE")
+	}
+	if err := hp.applyThroughput(t.Context(), 1000); err != nil {
+		t.Errorf("applyThroughput() error = %v", err)
+	}
+}
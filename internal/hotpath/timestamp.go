@@ -0,0 +1,149 @@
+package hotpath
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// timestampNormalizer rewrites PIPELINE_TIMESTAMP_FIELD to UTC RFC3339.
+// The original value is kept under "<field>_raw" (subject to the field
+// projection), entries further than maxSkew from the consumer clock get
+// "<field>_skewed":true, and values no layout matches are published
+// unchanged. A nil normalizer leaves the field alone.
+type timestampNormalizer struct {
+	now      func() time.Time
+	loc      *time.Location
+	fkField  jsonfast.FieldKey
+	fkRaw    jsonfast.FieldKey
+	fkSkewed jsonfast.FieldKey
+	name     []byte
+	layouts  []string
+	rawLen   int
+	maxSkew  time.Duration
+	rawKept  bool
+}
+
+func validateTimestamps(cfg *config.PipelineConfig) error {
+	if cfg.TimestampField == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(cfg.TimestampLocation); err != nil {
+		return errors.New("hotpath: pipeline timestamp location must be a valid time zone name")
+	}
+	return nil
+}
+
+func newTimestampNormalizer(cfg *config.PipelineConfig) *timestampNormalizer {
+	field := cfg.TimestampField
+	if field == "" {
+		return nil
+	}
+	loc, _ := time.LoadLocation(cfg.TimestampLocation) // checked by validateTimestamps
+	rawName := field + "_raw"
+	projection := newFieldProjection(cfg.FieldAllowlist, cfg.FieldDenylist)
+	return &timestampNormalizer{
+		now:      time.Now,
+		loc:      loc,
+		name:     []byte(field),
+		layouts:  cfg.TimestampLayouts,
+		fkField:  jsonfast.NewFieldKey(field),
+		fkRaw:    jsonfast.NewFieldKey(rawName),
+		fkSkewed: jsonfast.NewFieldKey(field + "_skewed"),
+		rawLen:   len(rawName),
+		maxSkew:  cfg.TimestampMaxSkew,
+		rawKept:  projection.keep([]byte(rawName)),
+	}
+}
+
+func (n *timestampNormalizer) matches(name []byte) bool {
+	return n != nil && bytes.Equal(name, n.name)
+}
+
+// add writes the field from its raw JSON value, normalized when it parses.
+func (n *timestampNormalizer) add(builder *jsonfast.Builder, value []byte, stats *projectionStats) {
+	t, ok := n.parse(value)
+	if !ok {
+		builder.AddRawBytesField(n.name, value)
+		stats.tsUnparsed++
+		return
+	}
+	builder.AddTimeRFC3339FieldKey(n.fkField, t)
+	if n.rawKept {
+		builder.AddRawJSONFieldKey(n.fkRaw, value)
+	} else {
+		stats.drop(n.rawLen, len(value))
+	}
+	stats.tsNormalized++
+	if skew := n.now().Sub(t); skew > n.maxSkew || skew < -n.maxSkew {
+		builder.AddBoolFieldKey(n.fkSkewed, true)
+		stats.tsSkewed++
+	}
+}
+
+// parse accepts a JSON string or number. Escaped strings are not
+// timestamps any configured layout would match, so they are left as-is.
+func (n *timestampNormalizer) parse(value []byte) (time.Time, bool) {
+	if len(value) >= 2 && value[0] == '"' {
+		value = value[1 : len(value)-1]
+		if bytes.IndexByte(value, '\\') >= 0 {
+			return time.Time{}, false
+		}
+	}
+	if len(value) == 0 {
+		return time.Time{}, false
+	}
+	s := string(value)
+	for _, layout := range n.layouts {
+		if t, ok := n.parseLayout(s, layout); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (n *timestampNormalizer) parseLayout(s, layout string) (time.Time, bool) {
+	switch layout {
+	case config.TimestampUnix:
+		return parseUnix(s)
+	case config.TimestampUnixMillis:
+		ms, err := strconv.ParseInt(s, 10, 64)
+		return time.UnixMilli(ms), err == nil
+	}
+	t, err := time.ParseInLocation(layout, s, n.loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 {
+		t = n.withYear(t)
+	}
+	return t, true
+}
+
+// parseUnix parses seconds since the epoch with an optional fraction.
+func parseUnix(s string) (time.Time, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))), true
+}
+
+// withYear completes a year-less RFC 3164 timestamp with the current year,
+// or the previous one when that would put it more than a day in the
+// future (an entry from late December read in early January).
+func (n *timestampNormalizer) withYear(t time.Time) time.Time {
+	now := n.now().In(n.loc)
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.Sub(now) > 24*time.Hour {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
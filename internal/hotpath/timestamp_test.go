@@ -0,0 +1,200 @@
+package hotpath
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+var timestampNow = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func timestampConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Pipeline.TimestampField = "timestamp"
+	cfg.Pipeline.TimestampLayouts = []string{
+		time.RFC3339, time.DateTime, time.Stamp, config.TimestampUnix,
+	}
+	cfg.Pipeline.TimestampLocation = "UTC"
+	cfg.Pipeline.TimestampMaxSkew = 5 * time.Minute
+	return cfg
+}
+
+func timestampHotPath(t *testing.T, cfg *config.Config, now time.Time) *HotPath {
+	t.Helper()
+	hp, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { closeHotPath(t, hp) })
+	hp.timestamps.now = func() time.Time { return now }
+	return hp
+}
+
+func TestBuildPayload_Timestamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   string
+		location string
+		wantJSON string
+		deny     []string
+		want     projectionStats // timestamp counters only
+	}{
+		{
+			name:     "offset converted to UTC",
+			object:   `{"timestamp":"2026-10-17T13:58:00.5+02:00"}`,
+			wantJSON: `{"timestamp":"2026-10-17T11:58:00.5Z","timestamp_raw":"2026-10-17T13:58:00.5+02:00"}`,
+			want:     projectionStats{tsNormalized: 1},
+		},
+		{
+			name:     "no offset read in the configured location",
+			object:   `{"timestamp":"2026-10-17 14:01:00"}`,
+			location: "Europe/Rome",
+			wantJSON: `{"timestamp":"2026-10-17T12:01:00Z","timestamp_raw":"2026-10-17 14:01:00"}`,
+			want:     projectionStats{tsNormalized: 1},
+		},
+		{
+			name:     "RFC 3164 gets the current year",
+			object:   `{"timestamp":"Oct 17 11:59:30"}`,
+			wantJSON: `{"timestamp":"2026-10-17T11:59:30Z","timestamp_raw":"Oct 17 11:59:30"}`,
+			want:     projectionStats{tsNormalized: 1},
+		},
+		{
+			name:     "unix seconds as a number",
+			object:   `{"timestamp":1792238340}`,
+			wantJSON: `{"timestamp":"2026-10-17T11:59:00Z","timestamp_raw":1792238340}`,
+			want:     projectionStats{tsNormalized: 1},
+		},
+		{
+			name:   "skew beyond the threshold flagged",
+			object: `{"timestamp":"2026-10-17T11:00:00Z"}`,
+			wantJSON: `{"timestamp":"2026-10-17T11:00:00Z","timestamp_raw":"2026-10-17T11:00:00Z",` +
+				`"timestamp_skewed":true}`,
+			want: projectionStats{tsNormalized: 1, tsSkewed: 1},
+		},
+		{
+			name:     "unparseable value left as-is",
+			object:   `{"timestamp":"yesterday"}`,
+			wantJSON: `{"timestamp":"yesterday"}`,
+			want:     projectionStats{tsUnparsed: 1},
+		},
+		{
+			name:     "projection drops the raw copy",
+			object:   `{"timestamp":1792238340}`,
+			deny:     []string{"timestamp_raw"},
+			wantJSON: `{"timestamp":"2026-10-17T11:59:00Z"}`,
+			want:     projectionStats{tsNormalized: 1, fields: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := timestampConfig()
+			cfg.Pipeline.FieldDenylist = append(tt.deny, "raw")
+			if tt.location != "" {
+				cfg.Pipeline.TimestampLocation = tt.location
+			}
+			hp := timestampHotPath(t, cfg, timestampNow)
+
+			msg := message.Redis{ID: testMsgID1, Stream: testStreamSimp, Object: tt.object, Raw: "r"}
+			var stats projectionStats
+			_, _, got := parseLine(t, hp.buildProjectedPayload(jsonfast.New(512), &msg, &stats))
+			if !jsonEqual([]byte(got), []byte(tt.wantJSON)) {
+				t.Errorf("payload = %s; want %s", got, tt.wantJSON)
+			}
+			stats.fields-- // "raw", denied in every case to keep wantJSON short
+			stats.bytes = 0
+			if stats != tt.want {
+				t.Errorf("stats = %+v; want %+v", stats, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampNormalizer_YearRollover(t *testing.T) {
+	hp := timestampHotPath(t, timestampConfig(), time.Date(2027, 1, 1, 0, 5, 0, 0, time.UTC))
+
+	got, ok := hp.timestamps.parse([]byte(`"Dec 31 23:59:00"`))
+	if want := time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("parse() = %v, %v; want %v from the previous year", got, ok, want)
+	}
+}
+
+func TestTimestampNormalizer_Parse(t *testing.T) {
+	cfg := timestampConfig()
+	// Layouts are tried in order: unix would read milliseconds as seconds.
+	cfg.Pipeline.TimestampLayouts = []string{time.RFC3339, config.TimestampUnixMillis, config.TimestampUnix}
+	hp := timestampHotPath(t, cfg, timestampNow)
+
+	tests := []struct {
+		want  time.Time
+		value string
+		ok    bool
+	}{
+		{value: `"1792238340.25"`, want: time.Unix(1792238340, 250_000_000), ok: true},
+		{value: `1792238340123`, want: time.UnixMilli(1792238340123), ok: true},
+		{value: `"NaN"`},
+		{value: `""`},
+		{value: `"2026-13-01T00:00:00Z"`},
+		{value: `null`},
+	}
+	for _, tt := range tests {
+		got, ok := hp.timestamps.parse([]byte(tt.value))
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parse(%s) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNew_TimestampInvalidLocation(t *testing.T) {
+	cfg := timestampConfig()
+	cfg.Pipeline.TimestampLocation = "Mars/Olympus_Mons"
+	if _, err := New(&mockRedis{}, &mockPublisher{}, cfg, log.New()); err == nil {
+		t.Error("New() error = nil; want error for an unknown time zone")
+	}
+}
+
+func TestNew_TimestampDisabled(t *testing.T) {
+	hp, err := New(&mockRedis{}, &mockPublisher{}, testConfig(), log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	if hp.timestamps != nil {
+		t.Error("timestamps != nil; want normalization off without a field")
+	}
+}
+
+func TestPublishBatch_TimestampMetrics(t *testing.T) {
+	hp := timestampHotPath(t, timestampConfig(), timestampNow)
+
+	normalizedBefore := metrics.TimestampsNormalized.Value()
+	skewedBefore := metrics.TimestampsSkewed.Value()
+	unparsedBefore := metrics.TimestampsUnparsed.Value()
+
+	batch := &message.Batch{Items: []message.Redis{
+		{ID: "1-0", Stream: testStreamSimp, Object: `{"timestamp":"2026-10-17T12:00:00Z"}`},
+		{ID: "2-0", Stream: testStreamSimp, Object: `{"timestamp":"2020-01-01T00:00:00Z"}`},
+		{ID: "3-0", Stream: testStreamSimp, Object: `{"timestamp":"-"}`},
+	}}
+	var compressed []byte
+	publish := func(context.Context, message.Payload) error { return nil }
+	hp.publishBatch(t.Context(), jsonfast.New(512), compress.NewEncoder(), batch,
+		jsonfast.NewBatchWriter(512), &compressed, publish)
+
+	if got := metrics.TimestampsNormalized.Value() - normalizedBefore; got != 2 {
+		t.Errorf("TimestampsNormalized delta = %d; want 2", got)
+	}
+	if got := metrics.TimestampsSkewed.Value() - skewedBefore; got != 1 {
+		t.Errorf("TimestampsSkewed delta = %d; want 1", got)
+	}
+	if got := metrics.TimestampsUnparsed.Value() - unparsedBefore; got != 1 {
+		t.Errorf("TimestampsUnparsed delta = %d; want 1", got)
+	}
+}
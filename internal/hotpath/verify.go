@@ -0,0 +1,163 @@
+package hotpath
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ubyte-source/go-jsonfast"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// Verifier runs entries through the publish path of a hot path whose
+// publisher and Redis client only count what would have been published,
+// dead-lettered or deleted, so parsing, projection, routing and the
+// max-age, invalid object and oversize policies can be checked against
+// production streams with no side effect. The consumer.* metrics move as
+// they would in a running consumer. The features that need Redis beyond reads (receipts,
+// dedup, backfill, adaptive batch sizing, the control hash, lag and memory
+// monitoring, compaction) are off, and so are spilling and snapshots.
+// A Verifier is not safe for concurrent use.
+type Verifier struct {
+	hp         *HotPath
+	pub        *verifyPublisher
+	redis      *verifyRedis
+	builder    *jsonfast.Builder
+	enc        *zstd.Encoder
+	bw         *jsonfast.BatchWriter
+	compressed []byte
+	entries    int
+}
+
+// VerifyReport sums what a Verifier saw.
+type VerifyReport struct {
+	// Topics counts the payloads that would have been published, by topic.
+	Topics       map[string]TopicCount
+	Entries      int
+	DeadLettered int
+	Deleted      int
+}
+
+// TopicCount is the payloads, and their compressed size, one topic would
+// have received.
+type TopicCount struct {
+	Payloads int
+	Bytes    int
+}
+
+// NewVerifier builds a Verifier for cfg.
+func NewVerifier(cfg *config.Config, logger *log.Logger) (*Verifier, error) {
+	verifyCfg := *cfg
+	r := &verifyCfg.Redis
+	r.ReceiptsStream, r.Dedup, r.ControlKey = "", "", ""
+	r.Backfill, r.AdaptiveBatch = false, false
+	r.MemoryHighPercent, r.LagInterval, r.CompactInterval = 0, 0, 0
+	verifyCfg.Pipeline.MemoryBudget = 0
+	verifyCfg.Pipeline.SnapshotPath = ""
+	verifyCfg.Archive.Mode = ""
+
+	redisClient := &verifyRedis{}
+	pub := &verifyPublisher{topic: cfg.MQTT.PublishTopic, topics: make(map[string]TopicCount)}
+	hp, err := New(redisClient, pub, &verifyCfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		hp:      hp,
+		pub:     pub,
+		redis:   redisClient,
+		builder: jsonfast.New(4096),
+		enc:     compress.NewEncoder(),
+		bw:      jsonfast.NewBatchWriter(4096),
+	}, nil
+}
+
+// Check runs batch through the redaction, max-age and invalid object
+// policies and the publish path, then releases it.
+func (v *Verifier) Check(ctx context.Context, batch message.Batch) {
+	v.entries += len(batch.Items)
+	if batch = v.hp.dropInvalid(ctx, v.hp.dropAged(ctx, v.hp.redactBatch(ctx, batch), time.Now())); len(batch.Items) > 0 {
+		v.hp.publishToStreams(ctx, v.builder, v.enc, &batch, v.bw, &v.compressed, v.pub.Publish)
+	}
+	batch.Release()
+}
+
+// Report returns the totals so far.
+func (v *Verifier) Report() VerifyReport {
+	v.pub.mu.Lock()
+	defer v.pub.mu.Unlock()
+	return VerifyReport{
+		Topics:       maps.Clone(v.pub.topics),
+		Entries:      v.entries,
+		DeadLettered: v.redis.deadLettered,
+		Deleted:      v.redis.deleted,
+	}
+}
+
+// Close releases the hot path.
+func (v *Verifier) Close() error {
+	return v.hp.Close()
+}
+
+// verifyPublisher counts payloads instead of publishing them.
+type verifyPublisher struct {
+	topics map[string]TopicCount
+	topic  string
+	mu     sync.Mutex // fanout topics may be published concurrently
+}
+
+func (p *verifyPublisher) Publish(ctx context.Context, payload message.Payload) error {
+	return p.PublishTo(ctx, payload, p.topic, 0, false)
+}
+
+func (p *verifyPublisher) PublishTo(_ context.Context, payload message.Payload, topic string, _ byte, _ bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := p.topics[topic]
+	count.Payloads++
+	count.Bytes += len(payload)
+	p.topics[topic] = count
+	return nil
+}
+
+func (p *verifyPublisher) SubscribeAck(context.Context, func(message.AckMessage)) error { return nil }
+
+func (p *verifyPublisher) Close() error { return nil }
+
+// verifyRedis counts the entries the hot path would dead-letter or delete;
+// it reads nothing.
+type verifyRedis struct {
+	deadLettered int
+	deleted      int
+}
+
+func (r *verifyRedis) ReadBatch(context.Context) (message.Batch, error) { return message.Batch{}, nil }
+
+func (r *verifyRedis) ClaimIdle(context.Context) (message.Batch, error) { return message.Batch{}, nil }
+
+func (r *verifyRedis) AckAndDeleteBatch(_ context.Context, ids []string, _ string) error {
+	r.deleted += len(ids)
+	return nil
+}
+
+func (r *verifyRedis) CleanupDeadConsumers(context.Context, time.Duration) error { return nil }
+
+func (r *verifyRedis) RefreshStreams(context.Context) (int, error) { return 0, nil }
+
+func (r *verifyRedis) Close() error { return nil }
+
+func (r *verifyRedis) DeadLetter(_ context.Context, msgs []message.Redis, _ string) error {
+	r.deadLettered += len(msgs)
+	return nil
+}
+
+func (r *verifyRedis) DeadLetterIDs(_ context.Context, _ string, ids []string, _ string) error {
+	r.deadLettered += len(ids)
+	return nil
+}
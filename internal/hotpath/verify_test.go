@@ -0,0 +1,58 @@
+package hotpath
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestVerifier_Check(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTT.PublishTopic = "logs"
+	cfg.MQTT.AckTopic = "logs/ack"
+	cfg.MQTT.OverrideTopics = []string{"alerts/#"}
+	cfg.Pipeline.MaxAgePolicies = []string{"*=drop:1h"}
+	cfg.Redis.ReceiptsStream = "receipts" // turned off, not rejected
+	v, err := NewVerifier(cfg, log.New())
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	defer func() {
+		if err := v.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}()
+
+	fresh := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	pool := &sync.Pool{New: func() any { return message.NewBuffer(3) }}
+	buf := message.NewBuffer(3)
+	buf.Add(fresh+"-0", testStreamSimp, map[string]any{"raw": "a"})
+	buf.Add(fresh+"-1", testStreamSimp, map[string]any{"raw": "b", "_topic": "alerts/disk"})
+	buf.Add("1-0", testStreamSimp, map[string]any{"raw": "too old"})
+	v.Check(t.Context(), message.NewLazyBatch(buf, pool))
+
+	report := v.Report()
+	if report.Entries != 3 || report.Deleted != 1 || report.DeadLettered != 0 {
+		t.Errorf("report = %+v; want 3 entries, 1 deleted by the max-age policy", report)
+	}
+	for _, topic := range []string{"logs", "alerts/disk"} {
+		if got := report.Topics[topic]; got.Payloads != 1 || got.Bytes == 0 {
+			t.Errorf("topic %s = %+v; want one payload", topic, got)
+		}
+	}
+	if len(report.Topics) != 2 {
+		t.Errorf("topics = %v; want logs and alerts/disk only", report.Topics)
+	}
+}
+
+func TestNewVerifier_NeedsValidConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.PublishWorkers = 0
+	if _, err := NewVerifier(cfg, log.New()); err == nil {
+		t.Error("NewVerifier() error = nil; want the hot path's validation error")
+	}
+}
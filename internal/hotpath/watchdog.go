@@ -0,0 +1,191 @@
+package hotpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// stallReconnector is implemented by publishers that can reconnect the
+// connections whose publishes made no progress for longer than after.
+type stallReconnector interface {
+	ReconnectStalled(ctx context.Context, after time.Duration) int
+}
+
+// publishWatchdog replaces the publish workers stuck in a publish under
+// PIPELINE_PUBLISH_STALL_FACTOR. A publish waits for its token at most
+// MQTT_WRITE_TIMEOUT, so one still running after stallAfter is stuck in
+// the MQTT client and may never return. Each worker holds a slot with the
+// start of its publish in progress; the watchdog abandons a worker whose
+// publish is older than stallAfter and starts another on its queue. A nil
+// publishWatchdog watches nothing.
+//
+// Only the publishes to MQTT_PUBLISH_TOPIC are watched; routed, fanout and
+// stream override publishes go through the same connections, which the
+// reconnector looks after.
+type publishWatchdog struct {
+	reconnector stallReconnector // nil when the publisher cannot reconnect
+	slots       []workerSlot
+	stallAfter  time.Duration
+	interval    time.Duration
+}
+
+// workerSlot is the place of one publish worker. gen counts the workers
+// that held it: the one whose generation is current runs the slot's
+// queue, and a worker that finds its generation stale exits.
+type workerSlot struct {
+	// cancel aborts the publishes of the current worker; only the
+	// goroutine starting workers uses it.
+	cancel context.CancelFunc
+	since  atomic.Int64 // unix nanoseconds the publish in progress started; 0 when there is none
+	gen    atomic.Uint64
+}
+
+func newPublishWatchdog(mqttPublisher mqtt.Publisher, cfg *config.Config) *publishWatchdog {
+	if cfg.Pipeline.PublishStallFactor == 0 {
+		return nil
+	}
+	reconnector, _ := mqttPublisher.(stallReconnector)
+	return &publishWatchdog{
+		reconnector: reconnector,
+		slots:       make([]workerSlot, cfg.Pipeline.PublishWorkers),
+		stallAfter:  cfg.MQTT.WriteTimeout * time.Duration(cfg.Pipeline.PublishStallFactor),
+		interval:    cfg.MQTT.WriteTimeout,
+	}
+}
+
+// slot returns the slot of worker i, or nil without a watchdog.
+func (w *publishWatchdog) slot(i int) *workerSlot {
+	if w == nil {
+		return nil
+	}
+	return &w.slots[i]
+}
+
+// watch records the publishes of publishFn in the slot. A publish that
+// starts once its worker was abandoned fails with the worker's canceled
+// context instead.
+func (s *workerSlot) watch(
+	publishFn func(context.Context, message.Payload) error,
+) func(context.Context, message.Payload) error {
+	if s == nil {
+		return publishFn
+	}
+	return func(ctx context.Context, payload message.Payload) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now().UnixNano()
+		s.since.Store(start)
+		// A stuck publish returning late must not clear its successor's.
+		defer s.since.CompareAndSwap(start, 0)
+		return publishFn(ctx, payload)
+	}
+}
+
+// generation returns the current generation, 0 without a watchdog.
+func (s *workerSlot) generation() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.gen.Load()
+}
+
+// replaced reports whether the worker of generation gen was abandoned.
+func (s *workerSlot) replaced(gen uint64) bool {
+	return s != nil && s.gen.Load() != gen
+}
+
+// retire ends generation gen and reports whether it was still current,
+// which only one of the worker and the watchdog sees.
+func (s *workerSlot) retire(gen uint64) bool {
+	return s.gen.CompareAndSwap(gen, gen+1)
+}
+
+// stalled returns the generation of a worker whose publish has run for
+// longer than after, and whether there is one. The generation is read
+// first: a worker clears its publish before it retires.
+func (s *workerSlot) stalled(now time.Time, after time.Duration) (uint64, bool) {
+	gen := s.gen.Load()
+	since := s.since.Load()
+	if since == 0 || now.Sub(time.Unix(0, since)) <= after {
+		return 0, false
+	}
+	return gen, true
+}
+
+// startPublisher starts publish worker i. Under the watchdog the worker
+// publishes with a context of its own and holds the current generation of
+// its slot, so that the watchdog can abandon it; the worker leaves g once
+// it exits or, if it was abandoned, as soon as the watchdog replaces it.
+func (hp *HotPath) startPublisher(stopCtx, publishCtx context.Context, g *loopGroup, i int) {
+	name := "publish-" + strconv.Itoa(i)
+	slot := hp.watchdog.slot(i)
+	if slot == nil {
+		hp.startLoop(stopCtx, &g.publishers, name, hp.makePublishLoop(publishCtx, i), g.errCh)
+		return
+	}
+	workerCtx, cancel := context.WithCancel(publishCtx)
+	slot.cancel = cancel
+	gen := slot.generation()
+	loop := hp.makePublishLoop(workerCtx, i)
+	g.publishers.Add(1)
+	go func() {
+		defer cancel()
+		err := loop(stopCtx)
+		if !slot.retire(gen) {
+			return // the watchdog took its place in g
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			g.errCh <- fmt.Errorf("%s loop error: %w", name, err)
+		}
+		g.publishers.Done()
+	}()
+}
+
+// makeWatchdogLoop returns the loop replacing the stuck publish workers
+// and reconnecting the stalled connections every MQTT_WRITE_TIMEOUT. It
+// runs until every worker has exited, so a worker stuck while draining is
+// replaced as well.
+func (hp *HotPath) makeWatchdogLoop(stopCtx, publishCtx context.Context, g *loopGroup) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(hp.watchdog.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case now := <-ticker.C:
+				hp.replaceStalled(ctx, stopCtx, publishCtx, g, now)
+			}
+		}
+	}
+}
+
+func (hp *HotPath) replaceStalled(ctx, stopCtx, publishCtx context.Context, g *loopGroup, now time.Time) {
+	w := hp.watchdog
+	for i := range w.slots {
+		slot := &w.slots[i]
+		gen, ok := slot.stalled(now, w.stallAfter)
+		if !ok || !slot.retire(gen) {
+			continue
+		}
+		slot.cancel()
+		slot.since.Store(0)
+		metrics.PublishStalls.Add(1)
+		hp.log.Errorf(ctx, "Publish worker %d stuck in a publish for over %s, replacing it", i, w.stallAfter)
+		hp.startPublisher(stopCtx, publishCtx, g, i)
+		g.publishers.Done() // the abandoned worker's place
+	}
+	if w.reconnector != nil {
+		w.reconnector.ReconnectStalled(ctx, w.stallAfter)
+	}
+}
@@ -0,0 +1,83 @@
+package hotpath
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// mockStallPublisher additionally implements stallReconnector.
+type mockStallPublisher struct {
+	mockPublisher
+	reconnectChecks atomic.Int64
+}
+
+func (m *mockStallPublisher) ReconnectStalled(context.Context, time.Duration) int {
+	m.reconnectChecks.Add(1)
+	return 0
+}
+
+func TestRun_WatchdogReplacesStuckWorker(t *testing.T) {
+	hang := make(chan struct{})
+	t.Cleanup(func() { close(hang) })
+	var calls, published atomic.Int64
+	pub := &mockStallPublisher{}
+	pub.publishFn = func(context.Context, message.Payload) error {
+		if calls.Add(1) == 1 {
+			<-hang // a publish that never returns, whatever its context
+		}
+		published.Add(1)
+		return nil
+	}
+	cfg := testConfig()
+	cfg.Pipeline.PublishWorkers = 1
+	cfg.Pipeline.PublishStallFactor = 2
+	cfg.MQTT.WriteTimeout = 5 * time.Millisecond
+	hp, err := New(streamingRedis(nil), pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer closeHotPath(t, hp)
+	before := metrics.PublishStalls.Value()
+
+	runFor(t, hp, 200*time.Millisecond)
+
+	if got := metrics.PublishStalls.Value() - before; got != 1 {
+		t.Errorf("PublishStalls delta = %d; want the stuck worker replaced once", got)
+	}
+	if published.Load() == 0 {
+		t.Error("nothing published; want the replacement worker to take over the queue")
+	}
+	if pub.reconnectChecks.Load() == 0 {
+		t.Error("ReconnectStalled never called; want the stalled connections checked")
+	}
+}
+
+func TestWorkerSlot_Generations(t *testing.T) {
+	w := newPublishWatchdog(&mockPublisher{}, testConfig())
+	if w != nil {
+		t.Fatal("newPublishWatchdog() != nil; want no watchdog without a stall factor")
+	}
+	if s := w.slot(0); s.replaced(s.generation()) {
+		t.Error("replaced() = true; want false without a watchdog")
+	}
+
+	var s workerSlot
+	start := time.Now()
+	s.since.Store(start.UnixNano())
+	if _, ok := s.stalled(start.Add(time.Second), 2*time.Second); ok {
+		t.Error("stalled() = true; want false for a publish younger than the threshold")
+	}
+	gen, ok := s.stalled(start.Add(3*time.Second), 2*time.Second)
+	if !ok || !s.retire(gen) {
+		t.Fatalf("stalled() = %d, %v; want generation 0 retired", gen, ok)
+	}
+	if !s.replaced(0) || s.retire(0) {
+		t.Error("want generation 0 replaced and retired only once")
+	}
+}
@@ -0,0 +1,266 @@
+// Package lifecycle records the lifecycle transitions of a consumer
+// instance — started, connected, paused, shutting down — as structured
+// events, so the timeline of a fleet can be pieced together from them.
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// Kind names a lifecycle transition.
+type Kind string
+
+// The transitions recorded. BreakerOpen and BreakerClosed follow an MQTT
+// connection taken out of the rotation, after failed or stalled publishes,
+// and put back.
+const (
+	Started           Kind = "started"
+	StreamsDiscovered Kind = "streams-discovered"
+	BrokerConnected   Kind = "broker-connected"
+	BreakerOpen       Kind = "breaker-open"
+	BreakerClosed     Kind = "breaker-closed"
+	Paused            Kind = "paused"
+	Resumed           Kind = "resumed"
+	ShutdownBegin     Kind = "shutdown-begin"
+	ShutdownComplete  Kind = "shutdown-complete"
+)
+
+// StderrLog is the PIPELINE_LIFECYCLE_LOG value that writes the events to
+// the standard error, apart from the log on the standard output.
+const StderrLog = "stderr"
+
+// queueSize bounds the events waiting to be written, and the events held
+// for MQTT_LIFECYCLE_TOPIC until the broker is connected.
+const queueSize = 64
+
+// publishTimeout bounds the publish of one event.
+const publishTimeout = 5 * time.Second
+
+// Publisher publishes to a topic other than the publish topic; the MQTT
+// pool is one.
+type Publisher interface {
+	PublishTo(ctx context.Context, payload message.Payload, topic string, qos byte, retain bool) error
+}
+
+// Event is one lifecycle transition, as written and published. Seq grows
+// by one per event of the instance, so a gap shows an event was lost.
+type Event struct {
+	Time     time.Time      `json:"time"`
+	Detail   map[string]any `json:"detail,omitempty"`
+	Kind     Kind           `json:"event"`
+	Instance string         `json:"instance"`
+	Host     string         `json:"host"`
+	Version  string         `json:"version"`
+	Revision string         `json:"revision,omitempty"`
+	Seq      uint64         `json:"seq"`
+}
+
+// Recorder writes the lifecycle events to PIPELINE_LIFECYCLE_LOG and
+// publishes them to MQTT_LIFECYCLE_TOPIC, at QoS 1 and not retained, from
+// a goroutine of its own, so recording never blocks. When its queue is
+// full an event is dropped and counted in consumer.lifecycle_events_dropped.
+// Events recorded before Attach are held, up to the queue size, and
+// published with the first one after. A nil Recorder records nothing.
+type Recorder struct {
+	pub      Publisher
+	out      io.Writer
+	file     *os.File // the log file; nil for stderr
+	log      *log.Logger
+	events   chan Event
+	done     chan struct{}
+	abort    context.CancelFunc
+	topic    string
+	instance string
+	host     string
+	version  string
+	revision string
+	// held are the events recorded before Attach, encoded.
+	held   [][]byte
+	seq    atomic.Uint64
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Enabled reports whether cfg asks for lifecycle events.
+func Enabled(cfg *config.Config) bool {
+	return cfg.Pipeline.LifecycleLog != "" || cfg.MQTT.LifecycleTopic != ""
+}
+
+// New opens PIPELINE_LIFECYCLE_LOG, when set, and starts the Recorder.
+// Events name the instance by its Redis consumer name.
+func New(cfg *config.Config, logger *log.Logger) (*Recorder, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	r := &Recorder{
+		log:      logger,
+		events:   make(chan Event, queueSize),
+		done:     make(chan struct{}),
+		topic:    cfg.MQTT.LifecycleTopic,
+		instance: cfg.Redis.Consumer,
+		host:     host,
+	}
+	switch path := cfg.Pipeline.LifecycleLog; path {
+	case "":
+	case StderrLog:
+		r.out = os.Stderr
+	default:
+		f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("lifecycle: %w", err)
+		}
+		r.out, r.file = f, f
+	}
+	r.version, r.revision = buildVersion()
+	ctx, abort := context.WithCancel(context.Background())
+	r.abort = abort
+	go r.write(ctx)
+	return r, nil
+}
+
+// buildVersion returns the module version and VCS revision the binary was
+// built from, as far as the build recorded them.
+func buildVersion() (version, revision string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			revision = s.Value
+		}
+	}
+	return info.Main.Version, revision
+}
+
+// Attach has the events published through pub from now on, starting with
+// the ones held so far. Without MQTT_LIFECYCLE_TOPIC it does nothing.
+func (r *Recorder) Attach(pub Publisher) {
+	if r == nil || r.topic == "" {
+		return
+	}
+	r.mu.Lock()
+	r.pub = pub
+	r.mu.Unlock()
+}
+
+// Record records a transition of kind, with detail, when not nil, as the
+// event's detail.
+func (r *Recorder) Record(kind Kind, detail map[string]any) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		metrics.LifecycleEventsDropped.Add(1)
+		return
+	}
+	e := Event{
+		Time:     time.Now().UTC(),
+		Detail:   detail,
+		Kind:     kind,
+		Instance: r.instance,
+		Host:     r.host,
+		Version:  r.version,
+		Revision: r.revision,
+		Seq:      r.seq.Add(1),
+	}
+	select {
+	case r.events <- e:
+	default:
+		metrics.LifecycleEventsDropped.Add(1)
+	}
+}
+
+func (r *Recorder) write(ctx context.Context) {
+	defer close(r.done)
+	for e := range r.events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			metrics.LifecycleEventErrors.Add(1)
+			r.log.Warnf(ctx, "Cannot encode the %s lifecycle event: %v", e.Kind, err)
+			continue
+		}
+		metrics.LifecycleEvents.Add(1)
+		if r.out != nil {
+			if _, err := r.out.Write(append(line, '\n')); err != nil {
+				metrics.LifecycleEventErrors.Add(1)
+			}
+		}
+		r.publish(ctx, line)
+	}
+}
+
+// publish publishes line, after the events held, or holds it until Attach.
+func (r *Recorder) publish(ctx context.Context, line []byte) {
+	if r.topic == "" {
+		return
+	}
+	r.mu.RLock()
+	pub := r.pub
+	r.mu.RUnlock()
+	if pub == nil {
+		if len(r.held) < queueSize {
+			r.held = append(r.held, line)
+		} else {
+			metrics.LifecycleEventsDropped.Add(1)
+		}
+		return
+	}
+	r.held = append(r.held, line)
+	held := r.held
+	r.held = nil
+	for _, payload := range held {
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := pub.PublishTo(publishCtx, payload, r.topic, 1, false)
+		cancel()
+		if err != nil {
+			metrics.LifecycleEventErrors.Add(1)
+			r.log.Warnf(ctx, "Failed to publish a lifecycle event to %s: %v", r.topic, err)
+		}
+	}
+}
+
+// Close writes out the events recorded so far, publishing them until ctx
+// is done, and closes the log file; later events are dropped. It is safe
+// to call more than once.
+func (r *Recorder) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.events)
+	r.mu.Unlock()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		r.abort()
+		<-r.done
+	}
+	r.abort()
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+type mockPublisher struct {
+	events []Event
+	topics []string
+	mu     sync.Mutex
+}
+
+func (m *mockPublisher) PublishTo(
+	_ context.Context, payload message.Payload, topic string, qos byte, retain bool,
+) error {
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, e)
+	m.topics = append(m.topics, topic)
+	if qos != 1 || retain {
+		m.topics = append(m.topics, "unexpected qos or retain")
+	}
+	return nil
+}
+
+func newTestRecorder(t *testing.T, logPath, topic string) *Recorder {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Redis.Consumer = "consumer-a"
+	cfg.Pipeline.LifecycleLog = logPath
+	cfg.MQTT.LifecycleTopic = topic
+	r, err := New(cfg, log.New())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRecorder_WritesEventsToTheLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifecycle.jsonl")
+	r := newTestRecorder(t, path, "")
+	r.Record(Started, nil)
+	r.Record(StreamsDiscovered, map[string]any{"streams": 3})
+	if err := r.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	events := readEvents(t, path)
+	if len(events) != 2 {
+		t.Fatalf("read %d events; want 2", len(events))
+	}
+	first, second := events[0], events[1]
+	if first.Kind != Started || first.Seq != 1 || first.Instance != "consumer-a" || first.Host == "" ||
+		first.Version == "" || first.Time.IsZero() {
+		t.Errorf("first event = %+v; want started, seq 1, with the instance, host, version and time", first)
+	}
+	if second.Kind != StreamsDiscovered || second.Seq != 2 || second.Detail["streams"] != float64(3) {
+		t.Errorf("second event = %+v; want streams-discovered, seq 2, with 3 streams", second)
+	}
+
+	// The file is appended to across restarts.
+	r = newTestRecorder(t, path, "")
+	r.Record(Started, nil)
+	if err := r.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := len(readEvents(t, path)); got != 3 {
+		t.Errorf("read %d events after a restart; want 3", got)
+	}
+}
+
+func TestRecorder_PublishesHeldEventsOnAttach(t *testing.T) {
+	r := newTestRecorder(t, "", "fleet/lifecycle")
+	pub := &mockPublisher{}
+	r.Record(Started, nil)
+	r.Record(StreamsDiscovered, nil)
+	r.Attach(pub)
+	r.Record(BrokerConnected, nil)
+	if err := r.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []Kind{Started, StreamsDiscovered, BrokerConnected}
+	if len(pub.events) != len(want) {
+		t.Fatalf("published %d events; want %d", len(pub.events), len(want))
+	}
+	for i, kind := range want {
+		if pub.events[i].Kind != kind || pub.events[i].Seq != uint64(i+1) {
+			t.Errorf("event %d = %s seq %d; want %s seq %d", i, pub.events[i].Kind, pub.events[i].Seq, kind, i+1)
+		}
+	}
+	for _, topic := range pub.topics {
+		if topic != "fleet/lifecycle" {
+			t.Errorf("published to %q; want fleet/lifecycle at QoS 1, not retained", topic)
+		}
+	}
+}
+
+func TestRecorder_DropsEventsAfterClose(t *testing.T) {
+	r := newTestRecorder(t, "", "")
+	if err := r.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := r.Close(t.Context()); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	before := metrics.LifecycleEventsDropped.Value()
+	r.Record(ShutdownComplete, nil)
+	if got := metrics.LifecycleEventsDropped.Value() - before; got != 1 {
+		t.Errorf("LifecycleEventsDropped delta = %d; want 1", got)
+	}
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	r.Record(Started, nil)
+	r.Attach(&mockPublisher{})
+	if err := r.Close(t.Context()); err != nil {
+		t.Errorf("Close() error = %v; want nil", err)
+	}
+	if Enabled(&config.Config{}) {
+		t.Error("Enabled() = true; want false without a lifecycle log or topic")
+	}
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// asyncQueue carries records from an asyncHandler, and the handlers derived
+// from it with WithAttrs and WithGroup, to one writer goroutine, so records
+// keep their order.
+type asyncQueue struct {
+	records chan asyncRecord
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+type asyncRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+func newAsyncQueue(size int) *asyncQueue {
+	q := &asyncQueue{records: make(chan asyncRecord, size), done: make(chan struct{})}
+	go q.write()
+	return q
+}
+
+func (q *asyncQueue) write() {
+	defer close(q.done)
+	for rec := range q.records {
+		// The caller's context may be canceled by now; the text handler
+		// does not use it anyway.
+		_ = rec.handler.Handle(context.Background(), rec.record) //nolint:errcheck // nowhere left to report it
+	}
+}
+
+// close writes out the queued records and makes later records write
+// synchronously. It is safe to call more than once.
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.records)
+	q.mu.Unlock()
+	<-q.done
+}
+
+// asyncHandler hands records to an asyncQueue instead of writing them, so a
+// slow stdout never stalls the caller. When the queue is full the record is
+// dropped and counted in consumer.logs_dropped.
+type asyncHandler struct {
+	next  slog.Handler
+	queue *asyncQueue
+}
+
+func (h *asyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *asyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.queue.mu.RLock()
+	defer h.queue.mu.RUnlock()
+	if h.queue.closed {
+		return h.next.Handle(ctx, r)
+	}
+	select {
+	case h.queue.records <- asyncRecord{handler: h.next, record: r.Clone()}:
+	default:
+		metrics.LogsDropped.Add(1)
+	}
+	return nil
+}
+
+func (h *asyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &asyncHandler{next: h.next.WithAttrs(attrs), queue: h.queue}
+}
+
+func (h *asyncHandler) WithGroup(name string) slog.Handler {
+	return &asyncHandler{next: h.next.WithGroup(name), queue: h.queue}
+}
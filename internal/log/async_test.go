@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// newAsyncTestLogger is newTestLogger behind an async queue of size
+// records.
+func newAsyncTestLogger(buf *bytes.Buffer, size int) *Logger {
+	l := newTestLogger(buf, slog.LevelInfo)
+	l.queue = newAsyncQueue(size)
+	l.log = slog.New(&asyncHandler{next: l.log.Handler(), queue: l.queue})
+	return l
+}
+
+// blockingHandler holds every record until release is closed.
+type blockingHandler struct {
+	release chan struct{}
+}
+
+func (h *blockingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *blockingHandler) Handle(context.Context, slog.Record) error {
+	<-h.release
+	return nil
+}
+func (h *blockingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *blockingHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestAsync_CloseFlushesInOrder(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newAsyncTestLogger(&buf, 16)
+	child := logger.WithField("component", "ack")
+
+	logger.Infof(t.Context(), "first")
+	child.Infof(t.Context(), "second")
+	logger.Debugf(t.Context(), "below the level")
+	logger.Close()
+
+	out := buf.String()
+	first, second := strings.Index(out, "msg=first"), strings.Index(out, "msg=second component=ack")
+	if first < 0 || second < first {
+		t.Errorf("output = %q; want first, then second with its field", out)
+	}
+	if strings.Contains(out, "below the level") {
+		t.Errorf("output = %q; want the debug record filtered", out)
+	}
+
+	logger.Infof(t.Context(), "after close")
+	if !strings.Contains(buf.String(), "msg=\"after close\"") {
+		t.Errorf("output = %q; want records after Close written synchronously", buf.String())
+	}
+	logger.Close()
+}
+
+func TestAsync_DropsWhenFull(t *testing.T) {
+	handler := &blockingHandler{release: make(chan struct{})}
+	queue := newAsyncQueue(1)
+	logger := &Logger{log: slog.New(&asyncHandler{next: handler, queue: queue}), level: &slog.LevelVar{}, queue: queue}
+	before := metrics.LogsDropped.Value()
+
+	for range 5 {
+		logger.Infof(t.Context(), "burst")
+	}
+	// The writer holds at most one record and the queue one more.
+	if dropped := metrics.LogsDropped.Value() - before; dropped < 3 {
+		t.Errorf("LogsDropped delta = %d; want at least 3", dropped)
+	}
+	close(handler.release)
+	logger.Close()
+}
+
+func TestClose_SynchronousLogger(t *testing.T) {
+	New().Close()
+}
@@ -37,8 +37,10 @@ const (
 
 // Logger wraps *slog.Logger and a dynamically updatable level.
 type Logger struct {
-	log   *slog.Logger
-	level *slog.LevelVar
+	log     *slog.Logger
+	level   *slog.LevelVar
+	queue   *asyncQueue // nil for a synchronous Logger
+	preview int         // payload bytes shown by Preview
 }
 
 // New defaults to Info level; use NewWithLevel to override at construction.
@@ -58,7 +60,27 @@ func NewWithLevel(levelName string) *Logger {
 	}
 
 	handler := slog.NewTextHandler(os.Stdout, opts)
-	return &Logger{log: slog.New(handler), level: level}
+	return &Logger{log: slog.New(handler), level: level, preview: DefaultPayloadPreview}
+}
+
+// NewAsync is NewWithLevel with records written by a background goroutine
+// through a queue of queueSize records, so callers never wait on stdout.
+// Records that find the queue full are dropped and counted in
+// consumer.logs_dropped. Close flushes the queue.
+func NewAsync(levelName string, queueSize int) *Logger {
+	l := NewWithLevel(levelName)
+	l.queue = newAsyncQueue(queueSize)
+	l.log = slog.New(&asyncHandler{next: l.log.Handler(), queue: l.queue})
+	return l
+}
+
+// Close writes out the records still queued by an async Logger; records
+// logged afterwards are written synchronously. It does nothing for a
+// synchronous Logger.
+func (l *Logger) Close() {
+	if l.queue != nil {
+		l.queue.close()
+	}
 }
 
 // replaceAttr maps the custom TRACE/FATAL/PANIC levels to readable labels.
@@ -101,6 +123,15 @@ func setLevelVar(lv *slog.LevelVar, level string) {
 	}
 }
 
+// IsLevel reports whether SetLevel knows level.
+func IsLevel(level string) bool {
+	switch level {
+	case lvlTrace, lvlDebug, lvlInfo, lvlWarn, lvlWarning, lvlError, lvlFatal, lvlPanic:
+		return true
+	}
+	return false
+}
+
 // SetLevel updates the threshold at runtime; unknown values fall back to Info.
 func (l *Logger) SetLevel(level string) {
 	setLevelVar(l.level, level)
@@ -232,12 +263,14 @@ func (l *Logger) ErrorWithFieldsf(ctx context.Context, fields Fields, format str
 // Fatalf is intentionally not level-gated: process termination must not be skipped.
 func (l *Logger) Fatalf(ctx context.Context, format string, v ...any) {
 	l.log.Log(ctx, LevelFatal, fmt.Sprintf(format, v...))
+	l.Close()
 	os.Exit(1)
 }
 
 // FatalWithFieldsf is Fatalf with structured fields; also terminates the process.
 func (l *Logger) FatalWithFieldsf(ctx context.Context, fields Fields, format string, v ...any) {
 	l.log.LogAttrs(ctx, LevelFatal, fmt.Sprintf(format, v...), fieldsToAttrs(fields)...)
+	l.Close()
 	os.Exit(1)
 }
 
@@ -245,6 +278,7 @@ func (l *Logger) FatalWithFieldsf(ctx context.Context, fields Fields, format str
 func (l *Logger) Panicf(ctx context.Context, format string, v ...any) {
 	msg := fmt.Sprintf(format, v...)
 	l.log.Log(ctx, LevelPanic, msg)
+	l.Close()
 	panic(msg)
 }
 
@@ -252,13 +286,14 @@ func (l *Logger) Panicf(ctx context.Context, format string, v ...any) {
 func (l *Logger) PanicWithFieldsf(ctx context.Context, fields Fields, format string, v ...any) {
 	msg := fmt.Sprintf(format, v...)
 	l.log.LogAttrs(ctx, LevelPanic, msg, fieldsToAttrs(fields)...)
+	l.Close()
 	panic(msg)
 }
 
 // WithField returns a child logger; the child shares the level pointer so
-// dynamic SetLevel propagates.
+// dynamic SetLevel propagates, the async queue, if any, and the preview size.
 func (l *Logger) WithField(key string, value any) *Logger {
-	return &Logger{log: l.log.With(key, value), level: l.level}
+	return &Logger{log: l.log.With(key, value), level: l.level, queue: l.queue, preview: l.preview}
 }
 
 // WithFields is WithField for an entire Fields map. The child shares the
@@ -268,7 +303,7 @@ func (l *Logger) WithFields(fields Fields) *Logger {
 	for k, v := range fields {
 		attrs = append(attrs, k, v)
 	}
-	return &Logger{log: l.log.With(attrs...), level: l.level}
+	return &Logger{log: l.log.With(attrs...), level: l.level, queue: l.queue, preview: l.preview}
 }
 
 func fieldsToAttrs(fields Fields) []slog.Attr {
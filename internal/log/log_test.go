@@ -79,8 +79,14 @@ func TestSetLevel(t *testing.T) {
 			if logger.Level() != tt.expected {
 				t.Errorf("for SetLevel(%s), expected level %v, got %v", tt.level, tt.expected, logger.Level())
 			}
+			if !IsLevel(tt.level) {
+				t.Errorf("IsLevel(%s) = false; want true", tt.level)
+			}
 		})
 	}
+	if IsLevel("") || IsLevel("verbose") {
+		t.Error("IsLevel() = true for an unknown level; want false")
+	}
 }
 
 func TestSlog(t *testing.T) {
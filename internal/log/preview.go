@@ -0,0 +1,49 @@
+package log
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultPayloadPreview is how many bytes of a payload a Logger shows
+// unless SetPayloadPreview says otherwise.
+const DefaultPayloadPreview = 256
+
+// Preview is a payload as log records show it: quoted, so that it cannot
+// forge log lines, and cut to the Logger's preview size on a UTF-8
+// boundary, with the full size appended when it was cut. It is formatted
+// only when the record is written, and not at all when the level is
+// disabled, so the payload must stay unchanged until the log call returns.
+type Preview struct {
+	payload []byte
+	max     int
+}
+
+// Preview wraps payload for a log record, to be formatted with %s.
+func (l *Logger) Preview(payload []byte) Preview {
+	return Preview{payload: payload, max: l.preview}
+}
+
+// SetPayloadPreview sets how many bytes of a payload Preview shows, 0 for
+// none but its size. It must be called before the Logger is shared.
+func (l *Logger) SetPayloadPreview(n int) {
+	l.preview = max(n, 0)
+}
+
+// String renders the preview.
+func (p Preview) String() string {
+	if len(p.payload) <= p.max {
+		return strconv.Quote(string(p.payload))
+	}
+	if p.max == 0 {
+		return "(" + strconv.Itoa(len(p.payload)) + " bytes)"
+	}
+	cut := p.max
+	for cut > 0 && !utf8.RuneStart(p.payload[cut]) {
+		cut--
+	}
+	if cut == 0 { // no boundary within max: a long invalid sequence
+		cut = p.max
+	}
+	return strconv.Quote(string(p.payload[:cut])) + "… (" + strconv.Itoa(len(p.payload)) + " bytes)"
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	for _, tt := range []struct {
+		payload string
+		want    string
+		max     int
+	}{
+		{payload: "hello", max: 5, want: `"hello"`},
+		{payload: "hello world", max: 5, want: `"hello"… (11 bytes)`},
+		{payload: "line\nforged", max: 32, want: `"line\nforged"`},
+		{payload: "aé€", max: 4, want: `"aé"… (6 bytes)`},
+		{payload: "a€", max: 2, want: `"a"… (4 bytes)`},
+		{payload: "\x80\x80\x80\x80", max: 2, want: `"\x80\x80"… (4 bytes)`},
+		{payload: "hello", max: 0, want: `(5 bytes)`},
+		{payload: "", max: 0, want: `""`},
+	} {
+		l := New()
+		l.SetPayloadPreview(tt.max)
+		if got := fmt.Sprintf("payload %s", l.Preview([]byte(tt.payload))); got != "payload "+tt.want {
+			t.Errorf("Preview(%q) with %d bytes = %s; want %s", tt.payload, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestPreview_SharedByChildren(t *testing.T) {
+	l := New()
+	if got := l.Preview([]byte(strings.Repeat("x", 300))).String(); !strings.HasSuffix(got, "… (300 bytes)") {
+		t.Errorf("Preview() = %s; want the default cut at %d bytes", got, DefaultPayloadPreview)
+	}
+	l.SetPayloadPreview(-1)
+	if got := l.WithField("k", "v").Preview([]byte("abc")).String(); got != "(3 bytes)" {
+		t.Errorf("child Preview() = %s; want the parent's size only", got)
+	}
+	if got := l.WithFields(Fields{"k": "v"}).Preview([]byte("abc")).String(); got != "(3 bytes)" {
+		t.Errorf("child Preview() = %s; want the parent's size only", got)
+	}
+}
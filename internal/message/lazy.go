@@ -0,0 +1,204 @@
+package message
+
+import (
+	"iter"
+	"sync"
+)
+
+// Buffer is the pooled backing storage of a lazy Batch: items carry ID and
+// Stream, and fields[i] keeps the entry's field map until the item is
+// first visited.
+type Buffer struct {
+	items  []Redis
+	fields []map[string]any
+}
+
+// NewBuffer preallocates room for capacity entries.
+func NewBuffer(capacity int) *Buffer {
+	return &Buffer{
+		items:  make([]Redis, 0, capacity),
+		fields: make([]map[string]any, 0, capacity),
+	}
+}
+
+// Add appends an entry without extracting its fields.
+func (b *Buffer) Add(id, stream string, fields map[string]any) {
+	b.items = append(b.items, Redis{ID: id, Stream: stream})
+	b.fields = append(b.fields, fields)
+}
+
+// Len reports the number of entries added since the last reset.
+func (b *Buffer) Len() int {
+	return len(b.items)
+}
+
+func (b *Buffer) reset() {
+	clear(b.items) // drop string and map references so the GC can collect them
+	clear(b.fields)
+	b.items = b.items[:0]
+	b.fields = b.fields[:0]
+}
+
+// NewLazyBatch wraps buf, which must come from pool; Release returns it
+// there.
+func NewLazyBatch(buf *Buffer, pool *sync.Pool) Batch {
+	return Batch{Items: buf.items, fields: buf.fields, buf: buf, pool: pool}
+}
+
+// All yields every item in order, extracting Object and Raw on the first
+// visit. Only items yielded by All (or after Decode) have their body set;
+// ID and Stream are always available. A batch must not be iterated from
+// more than one goroutine.
+func (b *Batch) All() iter.Seq[*Redis] {
+	return func(yield func(*Redis) bool) {
+		for i := range b.Items {
+			if !yield(b.item(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Indexed is All with each item's index, for callers that need the
+// item's Route.
+func (b *Batch) Indexed() iter.Seq2[int, *Redis] {
+	return func(yield func(int, *Redis) bool) {
+		for i := range b.Items {
+			if !yield(i, b.item(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Route returns the publish overrides of item i, or nil when it has none.
+// Like the body, it is only set once the item has been visited.
+func (b *Batch) Route(i int) *Route {
+	if i < len(b.routes) {
+		return b.routes[i]
+	}
+	return nil
+}
+
+// Decode extracts every item still pending, for callers that read Items
+// directly.
+func (b *Batch) Decode() {
+	for i := range b.Items {
+		b.item(i)
+	}
+}
+
+func (b *Batch) item(i int) *Redis {
+	m := &b.Items[i]
+	if i < len(b.fields) && b.fields[i] != nil {
+		var route *Route
+		m.Object, m.Raw, route = extractFields(b.fields[i])
+		b.fields[i] = nil
+		if route != nil {
+			if b.routes == nil {
+				b.routes = make([]*Route, len(b.Items))
+			}
+			b.routes[i] = route
+		}
+	}
+	b.reload(i)
+	return m
+}
+
+// Partition splits the batch into parts batches, item i going to part
+// shardOf[i], without extracting any item. The parts are not pooled; the
+// receiver is released.
+func (b *Batch) Partition(shardOf []int, parts int) []Batch {
+	out := make([]Batch, parts)
+	for i := range b.Items {
+		p := &out[shardOf[i]]
+		p.Items = append(p.Items, b.Items[i])
+		if b.fields != nil {
+			p.fields = append(p.fields, b.fields[i])
+		}
+		if b.routes != nil {
+			p.routes = append(p.routes, b.routes[i])
+		}
+		if b.spills != nil {
+			p.spills = append(p.spills, b.spills[i])
+			p.arena = b.arena
+		}
+	}
+	b.spills = nil // the parts free them now
+	b.Release()
+	return out
+}
+
+// Compact removes the items whose drop entry is set, keeping the order of
+// the rest and leaving unvisited items unextracted. drop is indexed like
+// Items.
+func (b *Batch) Compact(drop []bool) {
+	n := 0
+	for i := range b.Items {
+		if drop[i] {
+			if i < len(b.spills) {
+				b.arena.Free(b.spills[i].ref)
+			}
+			continue
+		}
+		b.Items[n] = b.Items[i]
+		if i < len(b.fields) {
+			b.fields[n] = b.fields[i]
+		}
+		if i < len(b.routes) {
+			b.routes[n] = b.routes[i]
+		}
+		if i < len(b.spills) {
+			b.spills[n] = b.spills[i]
+		}
+		n++
+	}
+	clear(b.Items[n:])
+	b.Items = b.Items[:n]
+	if len(b.fields) > n {
+		clear(b.fields[n:])
+		b.fields = b.fields[:n]
+	}
+	if len(b.routes) > n {
+		b.routes = b.routes[:n]
+	}
+	if len(b.spills) > n {
+		b.spills = b.spills[:n]
+	}
+}
+
+// extractFields scans the field map once; Redis stream entries normally hold
+// only "object" and "raw", so one range beats two map lookups. route is
+// only allocated for entries with a publish override field.
+func extractFields(m map[string]any) (object, raw string, route *Route) {
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		switch k {
+		case "object":
+			object = s
+		case "raw":
+			raw = s
+		case "_topic", "_qos", "_retain":
+			route = setRoute(route, k, s)
+		}
+	}
+	return
+}
+
+func setRoute(route *Route, field, value string) *Route {
+	if route == nil {
+		route = new(Route)
+	}
+	switch field {
+	case "_topic":
+		route.Topic = value
+	case "_qos":
+		route.QoS = value
+	default:
+		route.Retain = value
+	}
+	return route
+}
@@ -0,0 +1,141 @@
+package message
+
+import (
+	"sync"
+	"testing"
+)
+
+func lazyBatch(pool *sync.Pool) Batch {
+	buf, ok := pool.Get().(*Buffer)
+	if !ok {
+		panic("pool.Get() returned unexpected type")
+	}
+	buf.Add("1-0", "s1", map[string]any{"object": `{"k":"v"}`, "raw": "first"})
+	buf.Add("2-0", "s2", map[string]any{"raw": "second"})
+	buf.Add("3-0", "s1", map[string]any{"raw": "third"})
+	return NewLazyBatch(buf, pool)
+}
+
+func newBufferPool() *sync.Pool {
+	return &sync.Pool{New: func() any { return NewBuffer(4) }}
+}
+
+func TestLazyBatch_AllExtractsOnVisit(t *testing.T) {
+	batch := lazyBatch(newBufferPool())
+	defer batch.Release()
+
+	for msg := range batch.All() {
+		if msg.Object != `{"k":"v"}` || msg.Raw != "first" {
+			t.Errorf("first item = %+v; want object and raw extracted", *msg)
+		}
+		break
+	}
+	if got := batch.Items[1]; got.ID != "2-0" || got.Stream != "s2" || got.Raw != "" {
+		t.Errorf("unvisited item = %+v; want ID and Stream only", got)
+	}
+
+	batch.Decode()
+	if batch.Items[1].Raw != "second" || batch.Items[2].Raw != "third" {
+		t.Errorf("items after Decode = %+v; want every body extracted", batch.Items)
+	}
+	for msg := range batch.All() { // a second pass sees the extracted values
+		if msg.Raw == "" {
+			t.Errorf("item %s lost its body on the second pass", msg.ID)
+		}
+	}
+}
+
+func TestLazyBatch_PartitionStaysLazy(t *testing.T) {
+	batch := lazyBatch(newBufferPool())
+
+	parts := batch.Partition([]int{0, 1, 0}, 2)
+	if batch.buf != nil || batch.pool != nil {
+		t.Error("Partition did not release the original batch")
+	}
+	if len(parts) != 2 || len(parts[0].Items) != 2 || len(parts[1].Items) != 1 {
+		t.Fatalf("parts = %+v; want 2 items then 1", parts)
+	}
+	if parts[0].Items[1].Raw != "" {
+		t.Error("Partition extracted an item")
+	}
+
+	var raws []string
+	for msg := range parts[0].All() {
+		raws = append(raws, msg.Raw)
+	}
+	if len(raws) != 2 || raws[0] != "first" || raws[1] != "third" {
+		t.Errorf("part 0 bodies = %v; want [first third]", raws)
+	}
+}
+
+func TestLazyBatch_CompactKeepsFieldsPaired(t *testing.T) {
+	batch := lazyBatch(newBufferPool())
+	defer batch.Release()
+
+	batch.Compact([]bool{true, false, false})
+	if len(batch.Items) != 2 || batch.Items[0].ID != "2-0" || batch.Items[1].ID != "3-0" {
+		t.Fatalf("items = %+v; want 2-0 and 3-0", batch.Items)
+	}
+	if batch.Items[0].Raw != "" {
+		t.Error("Compact extracted an item")
+	}
+	var raws []string
+	for msg := range batch.All() {
+		raws = append(raws, msg.Raw)
+	}
+	if len(raws) != 2 || raws[0] != "second" || raws[1] != "third" {
+		t.Errorf("bodies = %v; want [second third]", raws)
+	}
+}
+
+func TestLazyBatch_RouteFollowsItem(t *testing.T) {
+	buf := NewBuffer(3)
+	buf.Add("1-0", "s1", map[string]any{"raw": "plain"})
+	buf.Add("2-0", "s1", map[string]any{"raw": "alert", "_topic": "alerts/disk", "_qos": "2", "_retain": "true"})
+	buf.Add("3-0", "s1", map[string]any{"raw": "kept", "_qos": "0"})
+	batch := NewLazyBatch(buf, newBufferPool())
+	defer batch.Release()
+
+	batch.Decode()
+	want := Route{Topic: "alerts/disk", QoS: "2", Retain: "true"}
+	if r := batch.Route(1); batch.Route(0) != nil || r == nil || *r != want {
+		t.Fatalf("routes = %v, %v; want none then %+v", batch.Route(0), r, want)
+	}
+
+	batch.Compact([]bool{false, true, false})
+	if r := batch.Route(1); r == nil || r.QoS != "0" || r.Topic != "" {
+		t.Errorf("route after Compact = %+v; want the third entry's QoS only", r)
+	}
+	parts := batch.Partition([]int{1, 0}, 2)
+	if r := parts[0].Route(0); r == nil || r.QoS != "0" {
+		t.Errorf("route after Partition = %+v; want it moved with its item", r)
+	}
+	if parts[1].Route(0) != nil {
+		t.Error("Partition gave a route to an entry without one")
+	}
+}
+
+func TestLazyBatch_ReleaseRecyclesBuffer(t *testing.T) {
+	pool := newBufferPool()
+	batch := lazyBatch(pool)
+	buf := batch.buf
+
+	batch.Release()
+	batch.Release() // double release must be safe
+
+	if buf.Len() != 0 || len(buf.fields) != 0 {
+		t.Errorf("recycled buffer holds %d items, %d field maps; want 0", buf.Len(), len(buf.fields))
+	}
+	if cap(buf.items) < 3 {
+		t.Errorf("recycled cap = %d; want >= 3", cap(buf.items))
+	}
+}
+
+func TestBatch_AllOnEagerItems(t *testing.T) {
+	batch := Batch{Items: []Redis{{ID: "1-0", Stream: "s", Raw: "line"}}}
+	for msg := range batch.All() {
+		if msg.Raw != "line" {
+			t.Errorf("Raw = %q; want items without field maps yielded as is", msg.Raw)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package message
+
+import "github.com/ibs-source/syslog-consumer/internal/spill"
+
+// spilledBody is an item body moved to the arena: Object followed by Raw,
+// object bytes long.
+type spilledBody struct {
+	ref    spill.Ref
+	object int
+}
+
+// BodyBytes reports the bytes of Object and Raw the batch holds in memory.
+// Items not yet visited and items whose body is spilled count as empty.
+func (b *Batch) BodyBytes() int {
+	n := 0
+	for i := range b.Items {
+		n += len(b.Items[i].Object) + len(b.Items[i].Raw)
+	}
+	return n
+}
+
+// Spill moves item bodies of at least minBytes to arena, first item first,
+// until want bytes have left the heap or no body is left to move. It
+// returns the bytes moved, and false if it stopped because the arena was
+// full. A spilled body is read back when its item is next visited;
+// Release frees the ones that are not.
+func (b *Batch) Spill(arena *spill.Arena, minBytes, want int) (moved int, ok bool) {
+	for i := range b.Items {
+		if moved >= want {
+			return moved, true
+		}
+		m := b.item(i)
+		size := len(m.Object) + len(m.Raw)
+		if size < minBytes {
+			continue
+		}
+		ref, stored := arena.Store(m.Object, m.Raw)
+		if !stored {
+			return moved, false
+		}
+		if b.spills == nil {
+			b.spills = make([]spilledBody, len(b.Items))
+		}
+		b.spills[i] = spilledBody{ref: ref, object: len(m.Object)}
+		b.arena = arena
+		m.Object, m.Raw = "", ""
+		moved += size
+	}
+	return moved, true
+}
+
+// reload reads item i's body back from the arena if it was spilled.
+func (b *Batch) reload(i int) {
+	if i >= len(b.spills) || b.spills[i].ref.Len() == 0 {
+		return
+	}
+	s := b.spills[i]
+	body := b.arena.Load(s.ref)
+	b.arena.Free(s.ref)
+	b.spills[i] = spilledBody{}
+	if len(body) == s.ref.Len() { // empty once the arena is closed
+		b.Items[i].Object, b.Items[i].Raw = body[:s.object], body[s.object:]
+	}
+}
+
+// freeSpills drops the bodies still in the arena.
+func (b *Batch) freeSpills() {
+	for i := range b.spills {
+		b.arena.Free(b.spills[i].ref)
+	}
+}
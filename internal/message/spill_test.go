@@ -0,0 +1,82 @@
+package message
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/spill"
+)
+
+func openArena(t *testing.T, pages int) *spill.Arena {
+	t.Helper()
+	arena, err := spill.Open(t.TempDir(), pages*os.Getpagesize())
+	if err != nil {
+		t.Fatalf("spill.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = arena.Close() })
+	return arena
+}
+
+func TestBatch_SpillReloadsOnVisit(t *testing.T) {
+	arena := openArena(t, 4)
+	batch := lazyBatch(newBufferPool())
+	defer batch.Release()
+
+	moved, ok := batch.Spill(arena, 6, 1) // "first" and its object only
+	if !ok || moved != len(`{"k":"v"}`)+len("first") {
+		t.Fatalf("Spill() = %d, %v; want the first body moved", moved, ok)
+	}
+	if batch.Items[0].Raw != "" || arena.Used() == 0 {
+		t.Errorf("item 0 = %+v, arena used %d; want the body in the arena", batch.Items[0], arena.Used())
+	}
+	if got := batch.BodyBytes(); got != 0 {
+		t.Errorf("BodyBytes() = %d; want 0 with item 0 spilled and the rest unvisited", got)
+	}
+
+	var bodies []string
+	for msg := range batch.All() {
+		bodies = append(bodies, msg.Object+msg.Raw)
+	}
+	if len(bodies) != 3 || bodies[0] != `{"k":"v"}first` || bodies[2] != "third" {
+		t.Errorf("bodies = %q; want every body back in order", bodies)
+	}
+	if arena.Used() != 0 {
+		t.Errorf("arena used = %d after reload; want 0", arena.Used())
+	}
+}
+
+func TestBatch_SpillSkipsSmallAndStopsWhenFull(t *testing.T) {
+	arena := openArena(t, 1)
+	batch := lazyBatch(newBufferPool())
+	defer batch.Release()
+
+	moved, ok := batch.Spill(arena, 7, 100)
+	if !ok || moved != 14 || batch.Items[2].Raw != "third" {
+		t.Errorf("Spill() = %d, %v, item 2 = %q; want only bodies of 7 bytes or more moved", moved, ok, batch.Items[2].Raw)
+	}
+	more := Batch{Items: []Redis{{ID: "4-0", Raw: "another"}}}
+	if moved, ok := more.Spill(arena, 1, 1); ok || moved != 0 || more.Items[0].Raw != "another" {
+		t.Errorf("Spill() into a full arena = %d, %v; want nothing moved", moved, ok)
+	}
+}
+
+func TestBatch_SpillFollowsPartitionAndRelease(t *testing.T) {
+	arena := openArena(t, 4)
+	batch := lazyBatch(newBufferPool())
+	batch.Spill(arena, 1, 1000)
+
+	batch.Compact([]bool{false, true, false})
+	parts := batch.Partition([]int{1, 0}, 2)
+	if parts[0].Items[0].ID != "3-0" || arena.Used() != 2*os.Getpagesize() {
+		t.Fatalf("parts = %+v, arena used %d; want two bodies left after Compact", parts, arena.Used())
+	}
+	for msg := range parts[0].All() {
+		if msg.Raw != "third" {
+			t.Errorf("part 0 body = %q; want third", msg.Raw)
+		}
+	}
+	parts[1].Release()
+	if arena.Used() != 0 {
+		t.Errorf("arena used = %d after Release; want every body freed", arena.Used())
+	}
+}
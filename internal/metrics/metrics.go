@@ -14,11 +14,128 @@ var (
 	MessagesNacked    = expvar.NewInt("consumer.messages_nacked")
 	MessagesClaimed   = expvar.NewInt("consumer.messages_claimed")
 
+	// AcksAlreadyAcked and AcksMissing count ACKed entries that were no
+	// longer pending in the group, and ones no longer in their stream, when
+	// they were acknowledged and deleted: both mean another consumer or an
+	// earlier attempt got there first.
+	AcksAlreadyAcked = expvar.NewInt("consumer.acks_already_acked")
+	AcksMissing      = expvar.NewInt("consumer.acks_missing")
+
+	// AcksSelfOriginated counts messages on the ACK topic dropped because
+	// they carry the origin frame of a consumer's publish, which means the
+	// ACK subscription overlaps a publish topic.
+	AcksSelfOriginated = expvar.NewInt("consumer.acks_self_originated")
+	// AcksRejected counts messages on the ACK topic dropped because
+	// MQTT_ACK_HMAC_KEY is set and they are not signed with it.
+	AcksRejected = expvar.NewInt("consumer.acks_rejected")
+
+	// NacksByReason maps each NACK reason to the entries NACKed with it;
+	// "unspecified" counts NACKs without one and "other" the reasons past
+	// the first few that have no PIPELINE_NACK_POLICIES entry.
+	NacksByReason = expvar.NewMap("consumer.nacks_by_reason")
+
+	// Nack* count NACKed entries moved by the dlq policy, batches of them
+	// left pending because the move failed, and claimed entries held back
+	// because their backoff had not run out.
+	NackDeadLettered     = expvar.NewInt("consumer.nack_dead_lettered")
+	NackDeadLetterErrors = expvar.NewInt("consumer.nack_dead_letter_errors")
+	NackHeld             = expvar.NewInt("consumer.nack_held")
+
+	// NackQuarantines counts the streams PIPELINE_NACK_FLOOD_THRESHOLD put
+	// in quarantine and NackQuarantinedStreams those in quarantine now, an
+	// alert while above zero; NackQuarantineHeld counts the claimed entries
+	// left pending because their stream was.
+	NackQuarantines        = expvar.NewInt("consumer.nack_quarantines")
+	NackQuarantinedStreams = expvar.NewInt("consumer.nack_quarantined_streams")
+	NackQuarantineHeld     = expvar.NewInt("consumer.nack_quarantine_held")
+
+	// MessagesBackfilled counts entries replayed by the REDIS_BACKFILL
+	// startup phase, own pending and claimed alike.
+	MessagesBackfilled = expvar.NewInt("consumer.messages_backfilled")
+	// BackfillBacklog is the group's pending entries when the backfill
+	// started, and BackfillProgress how much of it was replayed, in
+	// percent: 100 once the phase is over.
+	BackfillBacklog  = expvar.NewInt("consumer.backfill_backlog")
+	BackfillProgress = expvar.NewInt("consumer.backfill_progress")
+
+	// MessagesDeduplicated counts entries REDIS_DEDUP acknowledged without
+	// publishing them; DedupErrors counts batches published unchecked
+	// because the duplicate check failed.
+	MessagesDeduplicated = expvar.NewInt("consumer.messages_deduplicated")
+	DedupErrors          = expvar.NewInt("consumer.errors_dedup")
+
+	// ReadFieldsDropped counts the entry fields REDIS_READ_FIELDS deleted
+	// from read, claimed and backfilled entries; ReadBytesDropped counts
+	// their names and values, in bytes.
+	ReadFieldsDropped = expvar.NewInt("consumer.read_fields_dropped")
+	ReadBytesDropped  = expvar.NewInt("consumer.read_bytes_dropped")
+
+	// PublishDuplicatesSuppressed counts entries PIPELINE_PUBLISH_DEDUP left
+	// pending unpublished because another publish of them was reserved
+	// within the window; PublishDedupErrors counts batches published
+	// unchecked because the reservation failed.
+	PublishDuplicatesSuppressed = expvar.NewInt("consumer.publish_duplicates_suppressed")
+	PublishDedupErrors          = expvar.NewInt("consumer.errors_publish_dedup")
+
+	// MessagesAgedOut counts entries PIPELINE_MAX_AGE_POLICIES dropped or
+	// dead-lettered instead of publishing them; AgedOutErrors counts those
+	// left pending because the XACK + XDEL or dead-lettering failed.
+	MessagesAgedOut = expvar.NewInt("consumer.messages_aged_out")
+	AgedOutErrors   = expvar.NewInt("consumer.errors_aged_out")
+
 	FetchErrors   = expvar.NewInt("consumer.errors_fetch")
 	PublishErrors = expvar.NewInt("consumer.errors_publish")
 	AckErrors     = expvar.NewInt("consumer.errors_ack")
 
+	// RedisTimeouts and MQTTTimeouts count Redis calls cut short by
+	// REDIS_OPERATION_TIMEOUT and MQTT operations the broker did not
+	// complete within their timeout.
+	RedisTimeouts = expvar.NewInt("consumer.redis_timeouts")
+	MQTTTimeouts  = expvar.NewInt("consumer.mqtt_timeouts")
+
+	// RedisReplicaFallbacks counts the calls for REDIS_REPLICA_ADDRESS sent
+	// to the primary because the replica failed.
+	RedisReplicaFallbacks = expvar.NewInt("consumer.redis_replica_fallbacks")
+
+	// PublishFailures maps each failure class (transient, busy,
+	// disconnected, rejected) to the entries of the publishes that failed
+	// with it; PublishDeadLettered counts the entries of rejected publishes
+	// moved to REDIS_DEAD_LETTER_STREAM.
+	PublishFailures     = expvar.NewMap("consumer.publish_failures")
+	PublishDeadLettered = expvar.NewInt("consumer.publish_dead_lettered")
+
+	// MessagesEnriched counts the records PIPELINE_ENRICH_KEY added fields
+	// to; EnrichCacheHits the entries whose key was cached, EnrichLookups
+	// the keys looked up in Redis and EnrichErrors the failed lookups.
+	MessagesEnriched = expvar.NewInt("consumer.messages_enriched")
+	EnrichCacheHits  = expvar.NewInt("consumer.enrich_cache_hits")
+	EnrichLookups    = expvar.NewInt("consumer.enrich_lookups")
+	EnrichErrors     = expvar.NewInt("consumer.errors_enrich")
+
+	// PublishRetries counts publishes an ordered worker retried in place
+	// under PIPELINE_ORDERED_PUBLISH.
+	PublishRetries = expvar.NewInt("consumer.publish_retries")
+
+	// PublishStalls counts publish workers the PIPELINE_PUBLISH_STALL_FACTOR
+	// watchdog found stuck in a publish and replaced.
+	PublishStalls = expvar.NewInt("consumer.publish_stalls")
+
+	// PublishAbandoned counts the entries of publishes given up on after
+	// PIPELINE_PUBLISH_TIMEOUT and left pending for the claim loop.
+	PublishAbandoned = expvar.NewInt("consumer.publish_abandoned")
+
 	AckQueueDepth = expvar.NewInt("consumer.ack_queue_depth")
+	// AckBufferCapacity is how many ACKs the ACK queues hold in all, as
+	// PIPELINE_BUFFER_CAPACITY or the latest POST /admin/buffer sized them.
+	AckBufferCapacity = expvar.NewInt("consumer.ack_buffer_capacity")
+
+	// LogsDropped counts log records discarded because the LOG_ASYNC queue
+	// was full.
+	LogsDropped = expvar.NewInt("consumer.logs_dropped")
+
+	// ReadBatchSize is the COUNT of the next XREADGROUP under
+	// REDIS_ADAPTIVE_BATCH; it stays 0 when adaptive sizing is off.
+	ReadBatchSize = expvar.NewInt("consumer.read_batch_size")
 
 	// FetchBackpressure is incremented every time fetchLoop's non-blocking
 	// send fails and we have to wait for a publish worker to drain.
@@ -27,5 +144,247 @@ var (
 	StreamsActive     = expvar.NewInt("consumer.streams_active")
 	StreamsDiscovered = expvar.NewInt("consumer.streams_discovered")
 
+	// StreamsMatched and StreamsSkipped count the streams the latest
+	// discovery kept and the ones REDIS_STREAM_INCLUDE/EXCLUDE filtered
+	// out, in multi-stream mode.
+	StreamsMatched = expvar.NewInt("consumer.streams_matched")
+	StreamsSkipped = expvar.NewInt("consumer.streams_skipped")
+
+	// DiscoveryScans counts the SCAN round trips of stream discovery and
+	// DiscoveryDurationMs is how long the latest discovery took, which is
+	// what a refresh costs Redis on a large keyspace.
+	DiscoveryScans      = expvar.NewInt("consumer.discovery_scans")
+	DiscoveryDurationMs = expvar.NewInt("consumer.discovery_duration_ms")
+
+	// StreamLeasesHeld is the number of streams whose lease this instance
+	// holds under REDIS_STREAM_LEASE; StreamLeasesLost counts the leases
+	// it lost to another instance or let expire while it still wanted them.
+	StreamLeasesHeld = expvar.NewInt("consumer.stream_leases_held")
+	StreamLeasesLost = expvar.NewInt("consumer.stream_leases_lost")
+
+	// StreamsSuspended is the number of streams REDIS_IDLE_STREAM_AFTER
+	// took out of the blocking reads, polled instead; StreamsPromoted
+	// counts the suspended streams a poll found entries in and read again.
+	StreamsSuspended = expvar.NewInt("consumer.streams_suspended")
+	StreamsPromoted  = expvar.NewInt("consumer.streams_promoted")
+
 	DeadConsumersRemoved = expvar.NewInt("consumer.dead_consumers_removed")
+
+	// ClaimStreamDuration maps each stream to how long its XPENDING+XCLAIM
+	// round took in the latest claim cycle, in milliseconds. Streams that
+	// are no longer consumed are dropped from the map.
+	ClaimStreamDuration = expvar.NewMap("consumer.claim_stream_duration_ms")
+
+	// ClaimContended counts pending entries a claim cycle tried to XCLAIM
+	// but another consumer claimed first, or that were deleted meanwhile;
+	// ClaimCursorWraps counts claim cycles that reached the end of a
+	// stream's pending list and start over from its oldest entry.
+	ClaimContended   = expvar.NewInt("consumer.claim_contended")
+	ClaimCursorWraps = expvar.NewInt("consumer.claim_cursor_wraps")
+
+	// ProjectionFieldsDropped and ProjectionBytesSaved measure what the
+	// PIPELINE_FIELD_ALLOWLIST/DENYLIST projection removed from published
+	// batches, in uncompressed JSON bytes.
+	ProjectionFieldsDropped = expvar.NewInt("consumer.projection_fields_dropped")
+	ProjectionBytesSaved    = expvar.NewInt("consumer.projection_bytes_saved")
+
+	// Timestamps* count PIPELINE_TIMESTAMP_FIELD values rewritten to UTC,
+	// left as-is because no layout matched, and flagged for being further
+	// than PIPELINE_TIMESTAMP_MAX_SKEW from the consumer clock.
+	TimestampsNormalized = expvar.NewInt("consumer.timestamps_normalized")
+	TimestampsUnparsed   = expvar.NewInt("consumer.timestamps_unparsed")
+	TimestampsSkewed     = expvar.NewInt("consumer.timestamps_skewed")
+
+	// StructuredDataRenamed counts structured data keys published under a
+	// suffixed name because the record already had the prefixed one.
+	StructuredDataRenamed = expvar.NewInt("consumer.structured_data_renamed")
+
+	// InvalidObjects* count entries whose object was not a valid JSON
+	// object under PIPELINE_INVALID_OBJECT strict or lenient: published
+	// under object_raw, or dead-lettered, with the failed dead-letters
+	// counted apart.
+	InvalidObjectsWrapped          = expvar.NewInt("consumer.invalid_objects_wrapped")
+	InvalidObjectsDeadLettered     = expvar.NewInt("consumer.invalid_objects_dead_lettered")
+	InvalidObjectsDeadLetterErrors = expvar.NewInt("consumer.invalid_objects_dead_letter_errors")
+
+	// Redactions maps each PIPELINE_REDACT_FILE rule to the values it
+	// masked. MessagesRedacted counts the entries with something masked and
+	// RedactionFailures the ones that could not be redacted: dead-lettered
+	// under PIPELINE_REDACT_STRICT, with the failed dead-letters counted
+	// apart, and otherwise published without their object.
+	Redactions                = expvar.NewMap("consumer.redactions")
+	MessagesRedacted          = expvar.NewInt("consumer.messages_redacted")
+	RedactionFailures         = expvar.NewInt("consumer.redaction_failures")
+	RedactionDeadLettered     = expvar.NewInt("consumer.redaction_dead_lettered")
+	RedactionDeadLetterErrors = expvar.NewInt("consumer.redaction_dead_letter_errors")
+
+	// AckLatencyMs maps each stream to the PIPELINE_ACK_SLO_PERCENTILE
+	// percentile of its ack latency over the latest PIPELINE_ACK_SLO_WINDOW,
+	// in milliseconds. AckSLOViolations counts the windows a stream spent
+	// over PIPELINE_ACK_SLO_LATENCY, AckSLOAlerts the alerts raised after
+	// PIPELINE_ACK_SLO_WINDOWS of them in a row, and AckSLOBreached is the
+	// number of streams alerting now.
+	AckLatencyMs     = expvar.NewMap("consumer.ack_latency_ms")
+	AckSLOViolations = expvar.NewInt("consumer.ack_slo_violations")
+	AckSLOAlerts     = expvar.NewInt("consumer.ack_slo_alerts")
+	AckSLOBreached   = expvar.NewInt("consumer.ack_slo_breached")
+
+	// Oversize* count entries over PIPELINE_MAX_PAYLOAD_BYTES by policy
+	// outcome; PayloadSplits counts batches halved so each compressed MQTT
+	// payload stays within the limit.
+	OversizeTruncated        = expvar.NewInt("consumer.oversize_truncated")
+	OversizeSplit            = expvar.NewInt("consumer.oversize_split")
+	OversizeDeadLettered     = expvar.NewInt("consumer.oversize_dead_lettered")
+	OversizeDeadLetterErrors = expvar.NewInt("consumer.oversize_dead_letter_errors")
+	PayloadSplits            = expvar.NewInt("consumer.payload_splits")
+
+	// RoutesApplied counts entries published on their own with their _topic,
+	// _qos and _retain overrides; RoutesRejected counts entries whose
+	// overrides were invalid and were published with their batch instead.
+	RoutesApplied  = expvar.NewInt("consumer.routes_applied")
+	RoutesRejected = expvar.NewInt("consumer.routes_rejected")
+
+	// FanoutPublished and FanoutErrors count the payloads published, and
+	// those that failed, on the MQTT_FANOUT_TOPICS.
+	FanoutPublished = expvar.NewInt("consumer.fanout_published")
+	FanoutErrors    = expvar.NewInt("consumer.fanout_errors")
+
+	// SamplesPublished counts the sampled entries published to
+	// MQTT_SAMPLE_TOPIC, and SampleErrors the sample payloads that failed.
+	SamplesPublished = expvar.NewInt("consumer.samples_published")
+	SampleErrors     = expvar.NewInt("consumer.sample_errors")
+
+	// BufferedBodyBytes is the size of the entry bodies held in memory by
+	// the publish queues under PIPELINE_MEMORY_BUDGET, and SpillArenaBytes
+	// the arena space taken by the ones spilled out, in whole pages.
+	// BodyBytesSpilled counts the bytes moved to the arena; SpillArenaFull
+	// counts batches queued over the budget because the arena was full.
+	BufferedBodyBytes = expvar.NewInt("consumer.buffered_body_bytes")
+	SpillArenaBytes   = expvar.NewInt("consumer.spill_arena_bytes")
+	BodyBytesSpilled  = expvar.NewInt("consumer.body_bytes_spilled")
+	SpillArenaFull    = expvar.NewInt("consumer.spill_arena_full")
+
+	// MQTTStorePersisted is the number of inflight packets currently held in
+	// the file-backed MQTT stores; MQTTStoreOverflow counts packets that hit
+	// MQTT_STORE_MAX_MESSAGES and were kept in memory only.
+	MQTTStorePersisted = expvar.NewInt("consumer.mqtt_store_persisted")
+	MQTTStoreOverflow  = expvar.NewInt("consumer.mqtt_store_overflow")
+
+	// MQTTConnections maps each pool connection, by index, to its publish
+	// counts, recent success rate and round-trip time, and whether it is
+	// quarantined. MQTTQuarantines counts connections taken out of rotation
+	// after MQTT_QUARANTINE_FAILURES consecutive failed publishes, and
+	// MQTTStallReconnects those the publish watchdog reconnected because
+	// their publishes made no progress.
+	MQTTConnections     = expvar.NewMap("consumer.mqtt_connections")
+	MQTTQuarantines     = expvar.NewInt("consumer.mqtt_quarantines")
+	MQTTStallReconnects = expvar.NewInt("consumer.mqtt_stall_reconnects")
+
+	// MQTTPipelinedErrors counts MQTT_MAX_INFLIGHT publishes that failed or
+	// timed out after they were handed to a connection; their entries stay
+	// pending for the claim loop.
+	MQTTPipelinedErrors = expvar.NewInt("consumer.mqtt_pipelined_errors")
+
+	// BrokerRTTMs maps p50, p90, p99 and max to the broker RTT, in
+	// milliseconds, of the QoS 1/2 publishes MQTT_RTT_SAMPLE_EVERY timed
+	// over the latest complete MQTT_RTT_WINDOW; BrokerRTTSamples counts them.
+	BrokerRTTMs      = expvar.NewMap("consumer.broker_rtt_ms")
+	BrokerRTTSamples = expvar.NewInt("consumer.broker_rtt_samples")
+
+	// LifecycleEvents counts the lifecycle events written or published
+	// under PIPELINE_LIFECYCLE_LOG and MQTT_LIFECYCLE_TOPIC; the ones
+	// dropped because the queue was full, or recorded past shutdown, and
+	// the writes and publishes that failed are counted apart.
+	LifecycleEvents        = expvar.NewInt("consumer.lifecycle_events")
+	LifecycleEventsDropped = expvar.NewInt("consumer.lifecycle_events_dropped")
+	LifecycleEventErrors   = expvar.NewInt("consumer.lifecycle_event_errors")
+
+	// Receipts* track the REDIS_RECEIPTS_STREAM writer in receipt entries:
+	// written to Redis, dropped because the receipt queue was full (or could
+	// not be flushed at shutdown), and failed pipelined writes, which are
+	// retried.
+	ReceiptsWritten    = expvar.NewInt("consumer.receipts_written")
+	ReceiptsDropped    = expvar.NewInt("consumer.receipts_dropped")
+	ReceiptWriteErrors = expvar.NewInt("consumer.receipt_write_errors")
+
+	// EventsDropped counts pipeline events not handed to the observers
+	// because their queue was full.
+	EventsDropped = expvar.NewInt("consumer.events_dropped")
+
+	// QuotaDelayMs is the time publish workers spent waiting for a delay
+	// quota of PIPELINE_STREAM_QUOTAS, in milliseconds; QuotaSkipped counts
+	// entries over a skip quota, left pending for the claim loop.
+	// StreamQuotaUtilization maps each limited stream to how much of its
+	// quota it used as of its latest batch, from 0 to 1 (more while a delay
+	// quota is in debt).
+	QuotaDelayMs           = expvar.NewInt("consumer.quota_delay_ms")
+	QuotaSkipped           = expvar.NewInt("consumer.quota_skipped")
+	StreamQuotaUtilization = expvar.NewMap("consumer.stream_quota_utilization")
+
+	// ThroughputCap is the entries per second PIPELINE_THROUGHPUT_SCHEDULE
+	// allows now, 0 while unlimited; ThroughputDelayMs is the time publish
+	// workers spent waiting for it, in milliseconds.
+	ThroughputCap     = expvar.NewInt("consumer.throughput_cap")
+	ThroughputDelayMs = expvar.NewInt("consumer.throughput_delay_ms")
+
+	// FlowControlMessages counts the flow-control messages honored under
+	// MQTT_ACK_FLOW_CONTROL, FlowControlIgnored those received without it.
+	// FlowControlRate is the entries per second the receivers' latest rate
+	// allows, 0 while uncapped; FlowControlDelayMs is the time publish
+	// workers spent paused by them or waiting for the rate, in milliseconds.
+	FlowControlMessages = expvar.NewInt("consumer.flow_control_messages")
+	FlowControlIgnored  = expvar.NewInt("consumer.flow_control_ignored")
+	FlowControlRate     = expvar.NewInt("consumer.flow_control_rate")
+	FlowControlDelayMs  = expvar.NewInt("consumer.flow_control_delay_ms")
+
+	// RedisMemoryUsedPercent is used_memory as a percentage of maxmemory at
+	// the latest check of REDIS_MEMORY_HIGH_PERCENT's monitor;
+	// RedisMemoryPressure is 1 while the server is under memory pressure.
+	RedisMemoryUsedPercent = expvar.NewInt("consumer.redis_memory_used_percent")
+	RedisMemoryPressure    = expvar.NewInt("consumer.redis_memory_pressure")
+
+	// ControlPaused is 1 while the REDIS_CONTROL_KEY hash pauses the fetch
+	// and claim loops.
+	ControlPaused = expvar.NewInt("consumer.control_paused")
+
+	// GroupLag and GroupPending are the consumer group's undelivered and
+	// unacknowledged entries over every stream, as of the last
+	// REDIS_LAG_INTERVAL check. GroupLagEstimated is 1 when the lag of a
+	// stream was estimated rather than reported by XINFO GROUPS, and
+	// LagDraining 1 while a lag over REDIS_LAG_HIGH drains the streams.
+	GroupLag          = expvar.NewInt("consumer.group_lag")
+	GroupPending      = expvar.NewInt("consumer.group_pending")
+	GroupLagEstimated = expvar.NewInt("consumer.group_lag_estimated")
+	LagDraining       = expvar.NewInt("consumer.lag_draining")
+
+	// RetryQueueDepth is the entries waiting in PIPELINE_RETRY_QUEUE_CAPACITY's
+	// retry queue and RetryQueueAgeMs how long its oldest batch has waited,
+	// as of the latest change to the queue; RetryQueueDropped counts the
+	// claimed entries turned away by a full queue, left pending for a later
+	// claim.
+	RetryQueueDepth   = expvar.NewInt("consumer.retry_queue_depth")
+	RetryQueueAgeMs   = expvar.NewInt("consumer.retry_queue_age_ms")
+	RetryQueueDropped = expvar.NewInt("consumer.retry_queue_dropped")
+
+	// CompactionDeleted counts the acknowledged entries REDIS_COMPACT_INTERVAL's
+	// compaction deleted, CompactionDryRun the ones it would have deleted
+	// under REDIS_COMPACT_DRY_RUN, and CompactionErrors the streams it
+	// failed to compact.
+	CompactionDeleted = expvar.NewInt("consumer.compaction_deleted")
+	CompactionDryRun  = expvar.NewInt("consumer.compaction_dry_run")
+	CompactionErrors  = expvar.NewInt("consumer.errors_compaction")
+
+	// ArchiveRecords and ArchiveBytes count the records ARCHIVE_MODE's sink
+	// wrote and their NDJSON size, ArchiveFiles the files it closed and
+	// ArchiveErrors the payloads it failed to write. ArchiveUploads counts
+	// the files uploaded to ARCHIVE_S3_BUCKET, ArchiveUploadErrors the
+	// failed attempts, and ArchivePendingUploads is the closed files
+	// waiting to be uploaded.
+	ArchiveRecords        = expvar.NewInt("consumer.archive_records")
+	ArchiveBytes          = expvar.NewInt("consumer.archive_bytes")
+	ArchiveFiles          = expvar.NewInt("consumer.archive_files")
+	ArchiveErrors         = expvar.NewInt("consumer.errors_archive")
+	ArchiveUploads        = expvar.NewInt("consumer.archive_uploads")
+	ArchiveUploadErrors   = expvar.NewInt("consumer.errors_archive_upload")
+	ArchivePendingUploads = expvar.NewInt("consumer.archive_pending_uploads")
 )
@@ -14,13 +14,123 @@ func TestExpvarRegistration(t *testing.T) {
 		"consumer.messages_acked",
 		"consumer.messages_nacked",
 		"consumer.messages_claimed",
+		"consumer.acks_already_acked",
+		"consumer.acks_missing",
+		"consumer.acks_self_originated",
+		"consumer.acks_rejected",
+		"consumer.nack_dead_lettered",
+		"consumer.nack_dead_letter_errors",
+		"consumer.nack_held",
+		"consumer.messages_backfilled",
+		"consumer.backfill_backlog",
+		"consumer.backfill_progress",
+		"consumer.messages_deduplicated",
+		"consumer.errors_dedup",
+		"consumer.read_fields_dropped",
+		"consumer.read_bytes_dropped",
+		"consumer.publish_duplicates_suppressed",
+		"consumer.errors_publish_dedup",
+		"consumer.messages_aged_out",
+		"consumer.errors_aged_out",
 		"consumer.errors_fetch",
 		"consumer.errors_publish",
+		"consumer.redis_timeouts",
+		"consumer.mqtt_timeouts",
+		"consumer.redis_replica_fallbacks",
+		"consumer.publish_dead_lettered",
+		"consumer.messages_enriched",
+		"consumer.enrich_cache_hits",
+		"consumer.enrich_lookups",
+		"consumer.errors_enrich",
+		"consumer.publish_retries",
+		"consumer.publish_stalls",
+		"consumer.publish_abandoned",
 		"consumer.errors_ack",
 		"consumer.ack_queue_depth",
+		"consumer.ack_buffer_capacity",
+		"consumer.logs_dropped",
+		"consumer.read_batch_size",
 		"consumer.streams_active",
 		"consumer.streams_discovered",
+		"consumer.streams_matched",
+		"consumer.streams_skipped",
+		"consumer.discovery_scans",
+		"consumer.discovery_duration_ms",
+		"consumer.stream_leases_held",
+		"consumer.stream_leases_lost",
+		"consumer.streams_suspended",
+		"consumer.streams_promoted",
 		"consumer.dead_consumers_removed",
+		"consumer.claim_contended",
+		"consumer.claim_cursor_wraps",
+		"consumer.mqtt_store_persisted",
+		"consumer.mqtt_store_overflow",
+		"consumer.mqtt_quarantines",
+		"consumer.mqtt_stall_reconnects",
+		"consumer.mqtt_pipelined_errors",
+		"consumer.broker_rtt_samples",
+		"consumer.lifecycle_events",
+		"consumer.lifecycle_events_dropped",
+		"consumer.lifecycle_event_errors",
+		"consumer.projection_fields_dropped",
+		"consumer.projection_bytes_saved",
+		"consumer.timestamps_normalized",
+		"consumer.timestamps_unparsed",
+		"consumer.timestamps_skewed",
+		"consumer.structured_data_renamed",
+		"consumer.invalid_objects_wrapped",
+		"consumer.invalid_objects_dead_lettered",
+		"consumer.invalid_objects_dead_letter_errors",
+		"consumer.oversize_truncated",
+		"consumer.oversize_split",
+		"consumer.oversize_dead_lettered",
+		"consumer.oversize_dead_letter_errors",
+		"consumer.payload_splits",
+		"consumer.routes_applied",
+		"consumer.routes_rejected",
+		"consumer.fanout_published",
+		"consumer.fanout_errors",
+		"consumer.samples_published",
+		"consumer.sample_errors",
+		"consumer.buffered_body_bytes",
+		"consumer.spill_arena_bytes",
+		"consumer.body_bytes_spilled",
+		"consumer.spill_arena_full",
+		"consumer.receipts_written",
+		"consumer.receipts_dropped",
+		"consumer.events_dropped",
+		"consumer.quota_delay_ms",
+		"consumer.quota_skipped",
+		"consumer.throughput_cap",
+		"consumer.throughput_delay_ms",
+		"consumer.flow_control_messages",
+		"consumer.flow_control_ignored",
+		"consumer.flow_control_rate",
+		"consumer.flow_control_delay_ms",
+		"consumer.redis_memory_used_percent",
+		"consumer.redis_memory_pressure",
+		"consumer.control_paused",
+		"consumer.group_lag",
+		"consumer.group_pending",
+		"consumer.group_lag_estimated",
+		"consumer.lag_draining",
+		"consumer.retry_queue_depth",
+		"consumer.retry_queue_age_ms",
+		"consumer.retry_queue_dropped",
+		"consumer.compaction_deleted",
+		"consumer.compaction_dry_run",
+		"consumer.errors_compaction",
+		"consumer.archive_records",
+		"consumer.archive_bytes",
+		"consumer.archive_files",
+		"consumer.errors_archive",
+		"consumer.archive_uploads",
+		"consumer.errors_archive_upload",
+		"consumer.archive_pending_uploads",
+		"consumer.receipt_write_errors",
+		"consumer.ack_slo_violations",
+		"consumer.ack_slo_alerts",
+		"consumer.ack_slo_breached",
 	}
 
 	for _, name := range expected {
@@ -38,18 +148,131 @@ func TestExpvarRegistration(t *testing.T) {
 // TestExpvarPointers verifies the package-level vars point to the registered expvars.
 func TestExpvarPointers(t *testing.T) {
 	vars := map[string]*expvar.Int{
-		"consumer.messages_fetched":       MessagesFetched,
-		"consumer.messages_published":     MessagesPublished,
-		"consumer.messages_acked":         MessagesAcked,
-		"consumer.messages_nacked":        MessagesNacked,
-		"consumer.messages_claimed":       MessagesClaimed,
-		"consumer.errors_fetch":           FetchErrors,
-		"consumer.errors_publish":         PublishErrors,
-		"consumer.errors_ack":             AckErrors,
-		"consumer.ack_queue_depth":        AckQueueDepth,
-		"consumer.streams_active":         StreamsActive,
-		"consumer.streams_discovered":     StreamsDiscovered,
-		"consumer.dead_consumers_removed": DeadConsumersRemoved,
+		"consumer.messages_fetched":                   MessagesFetched,
+		"consumer.messages_published":                 MessagesPublished,
+		"consumer.messages_acked":                     MessagesAcked,
+		"consumer.messages_nacked":                    MessagesNacked,
+		"consumer.messages_claimed":                   MessagesClaimed,
+		"consumer.acks_already_acked":                 AcksAlreadyAcked,
+		"consumer.acks_missing":                       AcksMissing,
+		"consumer.acks_self_originated":               AcksSelfOriginated,
+		"consumer.acks_rejected":                      AcksRejected,
+		"consumer.nack_dead_lettered":                 NackDeadLettered,
+		"consumer.nack_dead_letter_errors":            NackDeadLetterErrors,
+		"consumer.nack_held":                          NackHeld,
+		"consumer.nack_quarantines":                   NackQuarantines,
+		"consumer.nack_quarantined_streams":           NackQuarantinedStreams,
+		"consumer.nack_quarantine_held":               NackQuarantineHeld,
+		"consumer.messages_backfilled":                MessagesBackfilled,
+		"consumer.backfill_backlog":                   BackfillBacklog,
+		"consumer.backfill_progress":                  BackfillProgress,
+		"consumer.messages_deduplicated":              MessagesDeduplicated,
+		"consumer.errors_dedup":                       DedupErrors,
+		"consumer.read_fields_dropped":                ReadFieldsDropped,
+		"consumer.read_bytes_dropped":                 ReadBytesDropped,
+		"consumer.publish_duplicates_suppressed":      PublishDuplicatesSuppressed,
+		"consumer.errors_publish_dedup":               PublishDedupErrors,
+		"consumer.messages_aged_out":                  MessagesAgedOut,
+		"consumer.errors_aged_out":                    AgedOutErrors,
+		"consumer.errors_fetch":                       FetchErrors,
+		"consumer.errors_publish":                     PublishErrors,
+		"consumer.redis_timeouts":                     RedisTimeouts,
+		"consumer.redis_replica_fallbacks":            RedisReplicaFallbacks,
+		"consumer.mqtt_timeouts":                      MQTTTimeouts,
+		"consumer.publish_dead_lettered":              PublishDeadLettered,
+		"consumer.publish_retries":                    PublishRetries,
+		"consumer.publish_stalls":                     PublishStalls,
+		"consumer.publish_abandoned":                  PublishAbandoned,
+		"consumer.errors_ack":                         AckErrors,
+		"consumer.ack_queue_depth":                    AckQueueDepth,
+		"consumer.ack_buffer_capacity":                AckBufferCapacity,
+		"consumer.logs_dropped":                       LogsDropped,
+		"consumer.read_batch_size":                    ReadBatchSize,
+		"consumer.streams_active":                     StreamsActive,
+		"consumer.streams_discovered":                 StreamsDiscovered,
+		"consumer.streams_matched":                    StreamsMatched,
+		"consumer.streams_skipped":                    StreamsSkipped,
+		"consumer.discovery_scans":                    DiscoveryScans,
+		"consumer.discovery_duration_ms":              DiscoveryDurationMs,
+		"consumer.stream_leases_held":                 StreamLeasesHeld,
+		"consumer.stream_leases_lost":                 StreamLeasesLost,
+		"consumer.streams_suspended":                  StreamsSuspended,
+		"consumer.streams_promoted":                   StreamsPromoted,
+		"consumer.dead_consumers_removed":             DeadConsumersRemoved,
+		"consumer.claim_contended":                    ClaimContended,
+		"consumer.claim_cursor_wraps":                 ClaimCursorWraps,
+		"consumer.mqtt_store_persisted":               MQTTStorePersisted,
+		"consumer.mqtt_store_overflow":                MQTTStoreOverflow,
+		"consumer.mqtt_quarantines":                   MQTTQuarantines,
+		"consumer.mqtt_stall_reconnects":              MQTTStallReconnects,
+		"consumer.mqtt_pipelined_errors":              MQTTPipelinedErrors,
+		"consumer.broker_rtt_samples":                 BrokerRTTSamples,
+		"consumer.lifecycle_events":                   LifecycleEvents,
+		"consumer.lifecycle_events_dropped":           LifecycleEventsDropped,
+		"consumer.lifecycle_event_errors":             LifecycleEventErrors,
+		"consumer.projection_fields_dropped":          ProjectionFieldsDropped,
+		"consumer.projection_bytes_saved":             ProjectionBytesSaved,
+		"consumer.timestamps_normalized":              TimestampsNormalized,
+		"consumer.timestamps_unparsed":                TimestampsUnparsed,
+		"consumer.timestamps_skewed":                  TimestampsSkewed,
+		"consumer.structured_data_renamed":            StructuredDataRenamed,
+		"consumer.invalid_objects_wrapped":            InvalidObjectsWrapped,
+		"consumer.invalid_objects_dead_lettered":      InvalidObjectsDeadLettered,
+		"consumer.invalid_objects_dead_letter_errors": InvalidObjectsDeadLetterErrors,
+		"consumer.messages_redacted":                  MessagesRedacted,
+		"consumer.redaction_failures":                 RedactionFailures,
+		"consumer.redaction_dead_lettered":            RedactionDeadLettered,
+		"consumer.redaction_dead_letter_errors":       RedactionDeadLetterErrors,
+		"consumer.oversize_truncated":                 OversizeTruncated,
+		"consumer.oversize_split":                     OversizeSplit,
+		"consumer.oversize_dead_lettered":             OversizeDeadLettered,
+		"consumer.oversize_dead_letter_errors":        OversizeDeadLetterErrors,
+		"consumer.payload_splits":                     PayloadSplits,
+		"consumer.routes_applied":                     RoutesApplied,
+		"consumer.routes_rejected":                    RoutesRejected,
+		"consumer.fanout_published":                   FanoutPublished,
+		"consumer.fanout_errors":                      FanoutErrors,
+		"consumer.samples_published":                  SamplesPublished,
+		"consumer.sample_errors":                      SampleErrors,
+		"consumer.buffered_body_bytes":                BufferedBodyBytes,
+		"consumer.spill_arena_bytes":                  SpillArenaBytes,
+		"consumer.body_bytes_spilled":                 BodyBytesSpilled,
+		"consumer.spill_arena_full":                   SpillArenaFull,
+		"consumer.receipts_written":                   ReceiptsWritten,
+		"consumer.receipts_dropped":                   ReceiptsDropped,
+		"consumer.events_dropped":                     EventsDropped,
+		"consumer.quota_delay_ms":                     QuotaDelayMs,
+		"consumer.quota_skipped":                      QuotaSkipped,
+		"consumer.throughput_cap":                     ThroughputCap,
+		"consumer.throughput_delay_ms":                ThroughputDelayMs,
+		"consumer.flow_control_messages":              FlowControlMessages,
+		"consumer.flow_control_ignored":               FlowControlIgnored,
+		"consumer.flow_control_rate":                  FlowControlRate,
+		"consumer.flow_control_delay_ms":              FlowControlDelayMs,
+		"consumer.redis_memory_used_percent":          RedisMemoryUsedPercent,
+		"consumer.redis_memory_pressure":              RedisMemoryPressure,
+		"consumer.control_paused":                     ControlPaused,
+		"consumer.group_lag":                          GroupLag,
+		"consumer.group_pending":                      GroupPending,
+		"consumer.group_lag_estimated":                GroupLagEstimated,
+		"consumer.lag_draining":                       LagDraining,
+		"consumer.retry_queue_depth":                  RetryQueueDepth,
+		"consumer.retry_queue_age_ms":                 RetryQueueAgeMs,
+		"consumer.retry_queue_dropped":                RetryQueueDropped,
+		"consumer.compaction_deleted":                 CompactionDeleted,
+		"consumer.compaction_dry_run":                 CompactionDryRun,
+		"consumer.errors_compaction":                  CompactionErrors,
+		"consumer.archive_records":                    ArchiveRecords,
+		"consumer.archive_bytes":                      ArchiveBytes,
+		"consumer.archive_files":                      ArchiveFiles,
+		"consumer.errors_archive":                     ArchiveErrors,
+		"consumer.archive_uploads":                    ArchiveUploads,
+		"consumer.errors_archive_upload":              ArchiveUploadErrors,
+		"consumer.archive_pending_uploads":            ArchivePendingUploads,
+		"consumer.receipt_write_errors":               ReceiptWriteErrors,
+		"consumer.ack_slo_violations":                 AckSLOViolations,
+		"consumer.ack_slo_alerts":                     AckSLOAlerts,
+		"consumer.ack_slo_breached":                   AckSLOBreached,
 	}
 
 	for name, ptr := range vars {
@@ -71,9 +294,81 @@ func TestExpvarPointers(t *testing.T) {
 	}
 }
 
-// TestExpvarCount verifies we have exactly 13 counters (catches accidental additions/removals).
+// TestClaimStreamDurationMap verifies the per-stream claim duration map is
+// registered as a map, not a counter.
+func TestClaimStreamDurationMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.claim_stream_duration_ms").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.claim_stream_duration_ms is not a map")
+	}
+	if registered != ClaimStreamDuration {
+		t.Error("ClaimStreamDuration does not point to the registered map")
+	}
+}
+
+// TestNacksByReasonMap verifies the per-reason NACK counters are
+// registered as a map, not a counter.
+func TestNacksByReasonMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.nacks_by_reason").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.nacks_by_reason is not a map")
+	}
+	if registered != NacksByReason {
+		t.Error("NacksByReason does not point to the registered map")
+	}
+}
+
+// TestPublishFailuresMap verifies the per-class publish failure counters
+// are registered as a map, not a counter.
+func TestPublishFailuresMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.publish_failures").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.publish_failures is not a map")
+	}
+	if registered != PublishFailures {
+		t.Error("PublishFailures does not point to the registered map")
+	}
+}
+
+// TestMQTTConnectionsMap verifies the per-connection pool stats are
+// registered as a map, not a counter.
+func TestMQTTConnectionsMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.mqtt_connections").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.mqtt_connections is not a map")
+	}
+	if registered != MQTTConnections {
+		t.Error("MQTTConnections does not point to the registered map")
+	}
+}
+
+// TestStreamQuotaUtilizationMap verifies the per-stream quota utilization
+// is registered as a map, not a counter.
+func TestStreamQuotaUtilizationMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.stream_quota_utilization").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.stream_quota_utilization is not a map")
+	}
+	if registered != StreamQuotaUtilization {
+		t.Error("StreamQuotaUtilization does not point to the registered map")
+	}
+}
+
+// TestRedactionsMap verifies the per-rule redaction counters are
+// registered as a map, not a counter.
+func TestRedactionsMap(t *testing.T) {
+	registered, ok := expvar.Get("consumer.redactions").(*expvar.Map)
+	if !ok {
+		t.Fatal("expvar consumer.redactions is not a map")
+	}
+	if registered != Redactions {
+		t.Error("Redactions does not point to the registered map")
+	}
+}
+
+// TestExpvarCount verifies the number of consumer.* vars (catches accidental additions/removals).
 func TestExpvarCount(t *testing.T) {
-	const wantCount = 13
+	const wantCount = 138
 	count := 0
 	expvar.Do(func(kv expvar.KeyValue) {
 		// Filter to our namespace; expvar.Do iterates all registered vars
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// counterStateVersion is the format of the files SaveCounters writes. A
+// file of any other version is not restored, so a format change costs the
+// counters one reset rather than a misread.
+const counterStateVersion = 1
+
+// counterMaps are the consumer.* Maps whose entries count events, and are
+// kept by SaveCounters like the counter Ints.
+var counterMaps = map[string]bool{"consumer.nacks_by_reason": true}
+
+// counterState is the file SaveCounters writes: the counters by name, and
+// the entries of the counterMaps by key.
+type counterState struct {
+	SavedAt  time.Time                   `json:"saved_at"`
+	Counters map[string]int64            `json:"counters"`
+	Maps     map[string]map[string]int64 `json:"maps,omitempty"`
+	Version  int                         `json:"version"`
+}
+
+// restored holds what RestoreCounters added, by the key StatsD tracks each
+// counter under, so that an exporter created afterwards sends only what
+// was counted since, not the restored totals again.
+var (
+	restoredMu sync.Mutex
+	restored   map[string]int64
+)
+
+// SaveCounters writes the consumer.* counters to path, gauges left out. It
+// goes through a temporary file in the same directory and a rename, so a
+// crash mid-write leaves the previous file.
+func SaveCounters(path string) error {
+	state := counterState{
+		SavedAt:  time.Now(),
+		Counters: make(map[string]int64),
+		Maps:     make(map[string]map[string]int64),
+		Version:  counterStateVersion,
+	}
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !strings.HasPrefix(kv.Key, "consumer.") {
+			return
+		}
+		switch v := kv.Value.(type) {
+		case *expvar.Int:
+			if !gauges[kv.Key] {
+				state.Counters[kv.Key] = v.Value()
+			}
+		case *expvar.Map:
+			if counterMaps[kv.Key] {
+				state.Maps[kv.Key] = mapCounters(v)
+			}
+		}
+	})
+	data, err := json.Marshal(&state)
+	if err != nil {
+		return fmt.Errorf("failed to encode counters: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create counter state file: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name()) // best effort; the write already failed
+		return fmt.Errorf("failed to write counter state file: %w", err)
+	}
+	return nil
+}
+
+// mapCounters returns the Int entries of m by key.
+func mapCounters(m *expvar.Map) map[string]int64 {
+	entries := make(map[string]int64)
+	m.Do(func(entry expvar.KeyValue) {
+		if v, ok := entry.Value.(*expvar.Int); ok {
+			entries[entry.Key] = v.Value()
+		}
+	})
+	return entries
+}
+
+// RestoreCounters adds the counters saved at path to the current ones and
+// returns when they were saved. It must run once per process, before
+// StatsD exporters are created. Counters the file lacks start from zero,
+// and the ones it has that are now gauges or gone are ignored.
+func RestoreCounters(path string) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if state.Version != counterStateVersion {
+		return time.Time{}, fmt.Errorf("%s has version %d; want %d", path, state.Version, counterStateVersion)
+	}
+
+	added := addCounters(&state)
+	restoredMu.Lock()
+	restored = added
+	restoredMu.Unlock()
+	return state.SavedAt, nil
+}
+
+// addCounters adds the counters of state to the current ones and returns
+// what it added, by the key StatsD tracks each counter under.
+func addCounters(state *counterState) map[string]int64 {
+	added := make(map[string]int64)
+	for name, value := range state.Counters {
+		v, ok := expvar.Get(name).(*expvar.Int)
+		if !ok || !strings.HasPrefix(name, "consumer.") || gauges[name] {
+			continue
+		}
+		v.Add(value)
+		added[name+"|"] += value
+	}
+	for name, entries := range state.Maps {
+		m, ok := expvar.Get(name).(*expvar.Map)
+		if !ok || !counterMaps[name] {
+			continue
+		}
+		for key, value := range entries {
+			m.Add(key, value)
+			added[name+"|"+statsdMapTags[name]+":"+statsdTagValue(key)] += value
+		}
+	}
+	return added
+}
+
+// restoredCounters returns a copy of what RestoreCounters added.
+func restoredCounters() map[string]int64 {
+	restoredMu.Lock()
+	defer restoredMu.Unlock()
+	if restored == nil {
+		return make(map[string]int64)
+	}
+	return maps.Clone(restored)
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// restoreCounters restores the counters saved at path and, at cleanup,
+// takes them back off so the other tests see the values they expect.
+func restoreCounters(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if _, err := RestoreCounters(path); err != nil {
+		t.Fatalf("RestoreCounters() error = %v", err)
+	}
+	t.Cleanup(func() {
+		MessagesFetched.Add(-state.Counters["consumer.messages_fetched"])
+		for key := range state.Maps["consumer.nacks_by_reason"] {
+			NacksByReason.Delete(key)
+		}
+		restoredMu.Lock()
+		restored = nil
+		restoredMu.Unlock()
+	})
+}
+
+// writeState writes a counter state file with the given counters.
+func writeState(t *testing.T, state *counterState) string {
+	t.Helper()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "counters.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestSaveCounters(t *testing.T) {
+	MessagesFetched.Add(1)
+	NacksByReason.Add("state_test", 1)
+	defer NacksByReason.Delete("state_test")
+	path := filepath.Join(t.TempDir(), "counters.json")
+	if err := SaveCounters(path); err != nil {
+		t.Fatalf("SaveCounters() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if state.Version != counterStateVersion || state.SavedAt.IsZero() {
+		t.Errorf("saved version %d at %v; want version %d and a time", state.Version, state.SavedAt, counterStateVersion)
+	}
+	if got := state.Counters["consumer.messages_fetched"]; got != MessagesFetched.Value() {
+		t.Errorf("saved messages_fetched = %d; want %d", got, MessagesFetched.Value())
+	}
+	if _, ok := state.Counters["consumer.group_lag"]; ok {
+		t.Error("SaveCounters() saved the group_lag gauge")
+	}
+	if got := state.Maps["consumer.nacks_by_reason"]["state_test"]; got != 1 {
+		t.Errorf("saved nacks_by_reason[state_test] = %d; want 1", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("state directory holds %d files; want only the state file", len(entries))
+	}
+}
+
+func TestRestoreCounters(t *testing.T) {
+	fetched, lag := MessagesFetched.Value(), GroupLag.Value()
+	path := writeState(t, &counterState{
+		Counters: map[string]int64{"consumer.messages_fetched": 100, "consumer.group_lag": 7, "consumer.gone": 1},
+		Maps:     map[string]map[string]int64{"consumer.nacks_by_reason": {"restored": 5}},
+		Version:  counterStateVersion,
+	})
+	restoreCounters(t, path)
+
+	if got := MessagesFetched.Value() - fetched; got != 100 {
+		t.Errorf("messages_fetched delta = %d; want 100", got)
+	}
+	if got := GroupLag.Value(); got != lag {
+		t.Errorf("group_lag = %d; want the gauge left at %d", got, lag)
+	}
+	if got := NacksByReason.Get("restored"); got == nil || got.String() != "5" {
+		t.Errorf("nacks_by_reason[restored] = %v; want 5", got)
+	}
+}
+
+func TestRestoreCounters_NotResentToStatsD(t *testing.T) {
+	path := writeState(t, &counterState{
+		Counters: map[string]int64{"consumer.messages_fetched": 100},
+		Maps:     map[string]map[string]int64{"consumer.nacks_by_reason": {"restored": 5}},
+		Version:  counterStateVersion,
+	})
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	first, err := NewStatsD(conn.LocalAddr().String(), "", nil)
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer func() { _ = first.Close() }()
+	if err := first.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	readStatsD(t, conn)
+
+	fetched := MessagesFetched.Value()
+	restoreCounters(t, path)
+	exporter, err := NewStatsD(conn.LocalAddr().String(), "", nil)
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer func() { _ = exporter.Close() }()
+	MessagesFetched.Add(2)
+	defer MessagesFetched.Add(-2)
+	if err := exporter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	lines := readStatsD(t, conn)
+	want := "consumer.messages_fetched:" + strconv.FormatInt(fetched+2, 10) + "|c"
+	if !slices.Contains(lines, want) {
+		t.Errorf("lines = %v; want %s, the restored count left out", lines, want)
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "consumer.nacks_by_reason:") {
+			t.Errorf("restored nacks_by_reason sent as %q", line)
+		}
+	}
+}
+
+func TestRestoreCounters_Errors(t *testing.T) {
+	fetched := MessagesFetched.Value()
+	if _, err := RestoreCounters(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RestoreCounters() of a missing file error = %v; want not exist", err)
+	}
+	path := writeState(t, &counterState{
+		Counters: map[string]int64{"consumer.messages_fetched": 100},
+		Version:  counterStateVersion + 1,
+	})
+	if _, err := RestoreCounters(path); err == nil {
+		t.Error("RestoreCounters() of another version error = nil")
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := RestoreCounters(path); err == nil {
+		t.Error("RestoreCounters() of a corrupt file error = nil")
+	}
+	if got := MessagesFetched.Value(); got != fetched {
+		t.Errorf("messages_fetched = %d after failed restores; want %d", got, fetched)
+	}
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// statsdMaxPacket keeps each datagram within a typical 1500-byte MTU once
+// the IP and UDP headers are added.
+const statsdMaxPacket = 1432
+
+// gauges are the consumer.* Ints, and Maps of Ints, that hold a level
+// rather than count events; every other Int is a counter, sent to StatsD
+// as one and kept by SaveCounters.
+var gauges = map[string]bool{
+	"consumer.ack_queue_depth":           true,
+	"consumer.ack_buffer_capacity":       true,
+	"consumer.read_batch_size":           true,
+	"consumer.streams_active":            true,
+	"consumer.streams_discovered":        true,
+	"consumer.streams_matched":           true,
+	"consumer.streams_skipped":           true,
+	"consumer.discovery_duration_ms":     true,
+	"consumer.stream_leases_held":        true,
+	"consumer.streams_suspended":         true,
+	"consumer.buffered_body_bytes":       true,
+	"consumer.spill_arena_bytes":         true,
+	"consumer.mqtt_store_persisted":      true,
+	"consumer.redis_memory_used_percent": true,
+	"consumer.redis_memory_pressure":     true,
+	"consumer.control_paused":            true,
+	"consumer.group_lag":                 true,
+	"consumer.group_pending":             true,
+	"consumer.group_lag_estimated":       true,
+	"consumer.lag_draining":              true,
+	"consumer.retry_queue_depth":         true,
+	"consumer.retry_queue_age_ms":        true,
+	"consumer.archive_pending_uploads":   true,
+	"consumer.ack_slo_breached":          true,
+	"consumer.ack_latency_ms":            true,
+	"consumer.backfill_backlog":          true,
+	"consumer.backfill_progress":         true,
+	"consumer.nack_quarantined_streams":  true,
+	"consumer.throughput_cap":            true,
+	"consumer.flow_control_rate":         true,
+	"consumer.broker_rtt_ms":             true,
+}
+
+// statsdMapTags names the tag that carries the key of each consumer.* Map.
+var statsdMapTags = map[string]string{
+	"consumer.nacks_by_reason":          "reason",
+	"consumer.claim_stream_duration_ms": "stream",
+	"consumer.stream_quota_utilization": "stream",
+	"consumer.ack_latency_ms":           "stream",
+	"consumer.mqtt_connections":         "connection",
+	"consumer.broker_rtt_ms":            "percentile",
+	"consumer.redactions":               "rule",
+}
+
+// statsdConnectionCounters are the fields of each consumer.mqtt_connections
+// entry that count events; the others are gauges.
+var statsdConnectionCounters = map[string]bool{"published": true, "failed": true}
+
+// StatsD sends the consumer.* metrics to a StatsD server over UDP, in the
+// DogStatsD format: Ints as counters (the change since the previous Flush)
+// or gauges, consumer.claim_stream_duration_ms as timings, and the entries
+// of the other Maps tagged with their key. A StatsD is not safe for
+// concurrent use.
+type StatsD struct {
+	conn   net.Conn
+	last   map[string]int64 // counter values sent so far, by name and tags
+	prefix string
+	tags   string // ",k:v..." appended to every line's own tags
+	buf    []byte
+	line   []byte
+}
+
+// NewStatsD sends to addr ("host:port"), with prefix and a dot before every
+// metric name when prefix is not empty, and tags ("key:value" or "key")
+// on every metric. Counters restored by RestoreCounters count as sent.
+func NewStatsD(addr, prefix string, tags []string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	s := &StatsD{conn: conn, last: restoredCounters()}
+	if prefix != "" {
+		s.prefix = prefix + "."
+	}
+	for _, tag := range tags {
+		s.tags += "," + tag
+	}
+	return s, nil
+}
+
+// Flush sends the current value of every consumer.* metric. It returns the
+// write errors; the lines of a datagram that failed are lost, and counters
+// carry on from their value at this Flush.
+func (s *StatsD) Flush() error {
+	var errs []error
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !strings.HasPrefix(kv.Key, "consumer.") {
+			return
+		}
+		switch v := kv.Value.(type) {
+		case *expvar.Int:
+			errs = append(errs, s.int(kv.Key, "", v.Value(), gauges[kv.Key]))
+		case *expvar.Map:
+			tagKey, ok := statsdMapTags[kv.Key]
+			if !ok {
+				return
+			}
+			v.Do(func(entry expvar.KeyValue) {
+				errs = append(errs, s.entry(kv.Key, tagKey+":"+statsdTagValue(entry.Key), entry.Value))
+			})
+		}
+	})
+	errs = append(errs, s.send())
+	return errors.Join(errs...)
+}
+
+// Close closes the UDP socket.
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// entry sends one entry of the Map name.
+func (s *StatsD) entry(name, tag string, value expvar.Var) error {
+	switch v := value.(type) {
+	case *expvar.Int:
+		if name == "consumer.claim_stream_duration_ms" {
+			return s.add(name, "ms", strconv.AppendInt(nil, v.Value(), 10), tag)
+		}
+		return s.int(name, tag, v.Value(), gauges[name])
+	case *expvar.Float:
+		return s.add(name, "g", strconv.AppendFloat(nil, v.Value(), 'f', -1, 64), tag)
+	case expvar.Func:
+		return s.fields(name, tag, v)
+	}
+	return nil
+}
+
+// fields sends the numeric and boolean fields of the JSON object value as
+// name.<field>.
+func (s *StatsD) fields(name, tag string, value expvar.Var) error {
+	var object map[string]any
+	if json.Unmarshal([]byte(value.String()), &object) != nil {
+		return nil
+	}
+	var errs []error
+	for field, v := range object {
+		metric := name + "." + field
+		switch v := v.(type) {
+		case float64:
+			if statsdConnectionCounters[field] {
+				errs = append(errs, s.int(metric, tag, int64(v), false))
+				continue
+			}
+			errs = append(errs, s.add(metric, "g", strconv.AppendFloat(nil, v, 'f', -1, 64), tag))
+		case bool:
+			gauge := []byte("0")
+			if v {
+				gauge = []byte("1")
+			}
+			errs = append(errs, s.add(metric, "g", gauge, tag))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// int sends an Int as a gauge, or as a counter of what it gained since the
+// previous Flush; a counter that did not move is not sent.
+func (s *StatsD) int(name, tag string, value int64, gauge bool) error {
+	if gauge {
+		return s.add(name, "g", strconv.AppendInt(nil, value, 10), tag)
+	}
+	key := name + "|" + tag
+	delta := value - s.last[key]
+	if delta == 0 {
+		return nil
+	}
+	s.last[key] = value
+	return s.add(name, "c", strconv.AppendInt(nil, delta, 10), tag)
+}
+
+// add appends one line to the pending datagram, sending the datagram
+// first when the line would not fit.
+func (s *StatsD) add(name, kind string, value []byte, tag string) error {
+	line := append(s.line[:0], s.prefix...)
+	line = append(line, name...)
+	line = append(line, ':')
+	line = append(line, value...)
+	line = append(line, '|')
+	line = append(line, kind...)
+	if tags := tag + s.tags; tags != "" {
+		line = append(line, "|#"...)
+		line = append(line, strings.TrimPrefix(tags, ",")...)
+	}
+	s.line = line
+
+	var err error
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > statsdMaxPacket {
+		err = s.send()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+	return err
+}
+
+func (s *StatsD) send() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// statsdTagValue replaces the characters that delimit DogStatsD tags.
+func statsdTagValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readStatsD returns the lines of the datagrams sent to conn until none
+// arrives for a while.
+func readStatsD(t *testing.T, conn net.PacketConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 2*statsdMaxPacket)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+			t.Fatalf("SetReadDeadline() error = %v", err)
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("ReadFrom() error = %v", err)
+		}
+		if n > statsdMaxPacket {
+			t.Errorf("datagram of %d bytes; want at most %d", n, statsdMaxPacket)
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func TestStatsD_Flush(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	exporter, err := NewStatsD(conn.LocalAddr().String(), "syslog", []string{"env:test"})
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer func() { _ = exporter.Close() }()
+
+	fetched := MessagesFetched.Value()
+	MessagesFetched.Add(3)
+	GroupLag.Set(42)
+	NacksByReason.Add("bad,reason", 2)
+	duration, latency, utilization := new(expvar.Int), new(expvar.Int), new(expvar.Float)
+	duration.Set(7)
+	latency.Set(1500)
+	utilization.Set(0.5)
+	ClaimStreamDuration.Set("syslog:a", duration)
+	AckLatencyMs.Set("syslog:a", latency)
+	StreamQuotaUtilization.Set("syslog:a", utilization)
+	MQTTConnections.Set("0", expvar.Func(func() any {
+		return map[string]any{"published": 5, "rtt_ms": 1.5, "connected": true}
+	}))
+	defer MQTTConnections.Delete("0")
+	defer ClaimStreamDuration.Delete("syslog:a")
+	defer AckLatencyMs.Delete("syslog:a")
+	defer StreamQuotaUtilization.Delete("syslog:a")
+
+	if err := exporter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	lines := readStatsD(t, conn)
+	for _, want := range []string{
+		"syslog.consumer.messages_fetched:" + strconv.FormatInt(fetched+3, 10) + "|c|#env:test",
+		"syslog.consumer.group_lag:42|g|#env:test",
+		"syslog.consumer.nacks_by_reason:2|c|#reason:bad_reason,env:test",
+		"syslog.consumer.claim_stream_duration_ms:7|ms|#stream:syslog:a,env:test",
+		"syslog.consumer.stream_quota_utilization:0.5|g|#stream:syslog:a,env:test",
+		"syslog.consumer.ack_latency_ms:1500|g|#stream:syslog:a,env:test",
+		"syslog.consumer.mqtt_connections.published:5|c|#connection:0,env:test",
+		"syslog.consumer.mqtt_connections.rtt_ms:1.5|g|#connection:0,env:test",
+		"syslog.consumer.mqtt_connections.connected:1|g|#connection:0,env:test",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("first flush is missing %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+
+	MessagesFetched.Add(2)
+	if err := exporter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	lines = readStatsD(t, conn)
+	for _, want := range []string{
+		"syslog.consumer.messages_fetched:2|c|#env:test",
+		"syslog.consumer.group_lag:42|g|#env:test",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("second flush is missing %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "syslog.consumer.nacks_by_reason:") {
+			t.Errorf("second flush sent the unchanged counter %q", line)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package mqtt
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
 )
 
 func TestParseAck_Valid(t *testing.T) {
@@ -44,7 +46,7 @@ func TestParseAck_Valid(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ack, err := parseAck(tt.payload)
+			ack, err := parseAck(tt.payload, nil)
 			if err != nil {
 				t.Fatalf("parseAck() failed: %v", err)
 			}
@@ -62,9 +64,36 @@ func TestParseAck_Valid(t *testing.T) {
 	}
 }
 
+func TestParseAck_ReasonAndRetryAfter(t *testing.T) {
+	tests := []struct {
+		payload    string
+		reason     string
+		retryAfter time.Duration
+	}{
+		{
+			`{"ids":["a"],"stream":"s","ack":false,"reason":"overloaded","retry_after":1.5}`,
+			"overloaded", 1500 * time.Millisecond,
+		},
+		{`{"ids":["a"],"stream":"s","ack":false,"reason":"schema_error"}`, "schema_error", 0},
+		{`{"ids":["a"],"stream":"s","ack":false,"retry_after":-3}`, "", 0},
+		{`{"ids":["a"],"stream":"s","ack":false,"retry_after":"soon"}`, "", 0},
+		{`{"ids":["a"],"stream":"s","ack":false,"retry_after":1e12}`, "", ackclient.MaxRetryAfter},
+	}
+	for _, tt := range tests {
+		ack, err := parseAck([]byte(tt.payload), nil)
+		if err != nil {
+			t.Fatalf("parseAck(%s) error = %v", tt.payload, err)
+		}
+		if ack.Reason != tt.reason || ack.RetryAfter != tt.retryAfter {
+			t.Errorf("parseAck(%s) reason, retry after = %q, %v; want %q, %v",
+				tt.payload, ack.Reason, ack.RetryAfter, tt.reason, tt.retryAfter)
+		}
+	}
+}
+
 func TestParseAck_InvalidJSON(t *testing.T) {
 	payload := []byte(`invalid json`)
-	_, err := parseAck(payload)
+	_, err := parseAck(payload, nil)
 	if err == nil {
 		t.Error("expected error for invalid JSON, got nil")
 	}
@@ -72,7 +101,7 @@ func TestParseAck_InvalidJSON(t *testing.T) {
 
 func TestParseAck_MissingIDs(t *testing.T) {
 	payload := []byte(`{"ack":true}`)
-	_, err := parseAck(payload)
+	_, err := parseAck(payload, nil)
 	if err == nil {
 		t.Error("expected error for missing ids, got nil")
 	}
@@ -83,7 +112,7 @@ func TestParseAck_MissingIDs(t *testing.T) {
 
 func TestParseAck_EmptyIDs(t *testing.T) {
 	payload := []byte(`{"ids":[],"stream":"s","ack":true}`)
-	_, err := parseAck(payload)
+	_, err := parseAck(payload, nil)
 	if err == nil {
 		t.Error("expected error for empty ids array, got nil")
 	}
@@ -91,7 +120,7 @@ func TestParseAck_EmptyIDs(t *testing.T) {
 
 func TestParseAck_MissingStream(t *testing.T) {
 	payload := []byte(`{"ids":["msg-123"],"ack":true}`)
-	_, err := parseAck(payload)
+	_, err := parseAck(payload, nil)
 	if err == nil {
 		t.Error("expected error for missing stream, got nil")
 	}
@@ -102,12 +131,25 @@ func TestParseAck_MissingStream(t *testing.T) {
 
 func TestParseAck_EmptyStream(t *testing.T) {
 	payload := []byte(`{"ids":["msg-123"],"stream":"","ack":true}`)
-	_, err := parseAck(payload)
+	_, err := parseAck(payload, nil)
 	if err == nil {
 		t.Error("expected error for empty stream, got nil")
 	}
 }
 
+func TestParseAck_Control(t *testing.T) {
+	ack, err := parseAck([]byte(`{"control":"pause","duration":"30s"}`), nil)
+	if err != nil {
+		t.Fatalf("parseAck() error = %v", err)
+	}
+	if ack.Control == nil || ack.Control.Kind != "pause" || ack.Control.Duration != 30*time.Second || ack.IDs != nil {
+		t.Errorf("parseAck() = %+v; want only a 30s pause", ack)
+	}
+	if _, err := parseAck([]byte(`{"control":"pause"}`), nil); err == nil {
+		t.Error("parseAck(pause without duration) error = nil; want error")
+	}
+}
+
 // --- benchmarks ---
 
 var ackSink message.AckMessage
@@ -116,7 +158,7 @@ func BenchmarkParseAck(b *testing.B) {
 	payload := []byte(`{"ids":["1771419690573-2"],"stream":"syslog-stream","ack":true}`)
 	b.ReportAllocs()
 	for range b.N {
-		ack, err := parseAck(payload)
+		ack, err := parseAck(payload, nil)
 		if err != nil {
 			b.Fatalf("parseAck(): %v", err)
 		}
@@ -130,7 +172,7 @@ func BenchmarkParseAck_Parallel(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		var sink message.AckMessage
 		for pb.Next() {
-			ack, err := parseAck(payload)
+			ack, err := parseAck(payload, nil)
 			if err != nil {
 				b.Fatalf("parseAck(): %v", err)
 			}
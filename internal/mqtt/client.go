@@ -4,19 +4,21 @@ package mqtt
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ibs-source/syslog-consumer/internal/certs"
 	"github.com/ibs-source/syslog-consumer/internal/compress"
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
 )
 
 // Client wraps a single paho MQTT connection.
@@ -24,9 +26,17 @@ type Client struct {
 	client     mqtt.Client
 	ackHandler atomic.Pointer[func(message.AckMessage)]
 	log        *log.Logger
+	status     *connStatus // nil unless MQTT_STATUS_TOPIC is set
+	// inflight holds a slot per pipelined publish awaiting the broker under
+	// MQTT_MAX_INFLIGHT; nil when publishes are not pipelined.
+	inflight chan struct{}
 
+	// The topics, including the optional CN prefix, are resolved once at
+	// config load; publishing reuses them as is.
 	publishTopic string
 	ackTopic     string
+	// ackKey is MQTT_ACK_HMAC_KEY; ACKs must be signed with it when set.
+	ackKey []byte
 
 	connectTimeout    time.Duration
 	writeTimeout      time.Duration
@@ -34,27 +44,65 @@ type Client struct {
 	disconnectTimeout time.Duration
 	connectRetryDelay time.Duration
 
+	// health is kept by the Pool, which publishes through the connection.
+	health    connHealth
 	connected atomic.Bool
-	qos       byte
+	// reconnects counts the reconnects paho began; see finishPublish.
+	reconnects atomic.Uint64
+	qos        byte
 }
 
 // errNotConnected signals callers to back off and retry.
 var errNotConnected = errors.New("mqtt: broker connection not open")
 
+// ErrTLSConfig reports MQTT_CA_CERT, MQTT_CLIENT_CERT or MQTT_CLIENT_KEY
+// failing to load.
+var ErrTLSConfig = errors.New("failed to create TLS config")
+
+// errAckRefused reports a SUBACK failure code, which paho does not turn into
+// a token error; brokers answer it when their ACL denies the ACK topic.
+var errAckRefused = errors.New("mqtt: broker refused the ack subscription; check the broker ACL")
+
+// subackFailure is the SUBACK return code of a refused subscription.
+const subackFailure = 0x80
+
+// subackResult is implemented by paho's SubscribeToken.
+type subackResult interface {
+	Result() map[string]byte
+}
+
+// refused reports whether the broker answered topic's subscription with a
+// failure code.
+func refused(token mqtt.Token, topic string) bool {
+	r, ok := token.(subackResult)
+	return ok && r.Result()[topic] == subackFailure
+}
+
 // NewClient prepares the paho options but does not establish the connection;
 // call Connect afterwards.
 func NewClient(ctx context.Context, cfg *config.MQTTConfig, logger *log.Logger) (*Client, error) {
+	return newClient(ctx, cfg, cfg.ClientID, logger)
+}
+
+// newClient is NewClient for a connection of the pool of client ID
+// instance.
+func newClient(ctx context.Context, cfg *config.MQTTConfig, instance string, logger *log.Logger) (*Client, error) {
 	c := &Client{
 		publishTopic:      cfg.PublishTopic,
 		ackTopic:          cfg.AckTopic,
+		ackKey:            []byte(cfg.AckHMACKey),
 		qos:               cfg.QoS,
 		connectTimeout:    cfg.ConnectTimeout,
 		writeTimeout:      cfg.WriteTimeout,
 		subscribeTimeout:  cfg.SubscribeTimeout,
 		disconnectTimeout: cfg.DisconnectTimeout,
 		connectRetryDelay: cfg.ConnectRetryDelay,
+		status:            newConnStatus(cfg, instance),
 		log:               logger,
 	}
+	if cfg.MaxInflight > 0 {
+		c.inflight = make(chan struct{}, cfg.MaxInflight)
+	}
 
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(cfg.Broker)
@@ -70,61 +118,118 @@ func NewClient(ctx context.Context, cfg *config.MQTTConfig, logger *log.Logger)
 	opts.SetResumeSubs(true)
 	opts.SetOrderMatters(false)
 	opts.SetMaxResumePubInFlight(cfg.MaxResumePubInFlight)
+	opts.SetCleanSession(!cfg.PersistentSession)
+	if cfg.StoreDir != "" {
+		// FileStore requires a directory per client ID.
+		opts.SetStore(newBoundedStore(filepath.Join(cfg.StoreDir, cfg.ClientID), cfg.StoreMaxMessages))
+	}
+
+	c.setHandlers(ctx, opts)
+
+	if cfg.TLSEnabled {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	c.client = mqtt.NewClient(opts)
+	return c, nil
+}
+
+// setHandlers keeps c.connected and the status topic in line with the
+// connection, and restores the ACK subscription after a reconnect.
+func (c *Client) setHandlers(ctx context.Context, opts *mqtt.ClientOptions) {
+	c.status.will(opts, time.Now())
 
 	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
 		c.connected.Store(false)
 		if err != nil {
-			logger.Errorf(ctx, "MQTT connection lost: %v", err)
+			c.log.Errorf(ctx, "MQTT connection lost: %v", err)
 		}
 	})
 
-	opts.SetReconnectingHandler(func(_ mqtt.Client, _ *mqtt.ClientOptions) {
-		logger.Infof(ctx, "MQTT reconnecting...")
+	opts.SetReconnectingHandler(func(_ mqtt.Client, reconnect *mqtt.ClientOptions) {
+		c.reconnects.Add(1)
+		c.log.Infof(ctx, "MQTT reconnecting...")
+		c.status.will(reconnect, time.Now())
 	})
 
 	opts.SetOnConnectHandler(func(mc mqtt.Client) {
 		c.connected.Store(true)
-		logger.Infof(ctx, "MQTT connected successfully")
+		c.log.Infof(ctx, "MQTT connected successfully")
 		c.resubscribeAck(ctx, mc)
-	})
-
-	if cfg.TLSEnabled {
-		tlsConfig, err := newTLSConfig(cfg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create TLS config: %w", err)
+		if err := c.status.publish(ctx, mc, statusOnline, c.writeTimeout); err != nil {
+			c.log.Warnf(ctx, "Failed to publish the online status: %v", err)
 		}
-		opts.SetTLSConfig(tlsConfig)
-	}
-
-	c.client = mqtt.NewClient(opts)
-	return c, nil
+	})
 }
 
 // Connect retries with back-off until the broker responds or ctx is canceled.
 func (c *Client) Connect(ctx context.Context) error {
+	return c.connect(ctx, 0)
+}
+
+// connect is Connect giving up after attempts failed attempts, with the
+// error of the last one, unless attempts is 0.
+func (c *Client) connect(ctx context.Context, attempts int) error {
 	for attempt := 0; ; attempt++ {
-		select {
-		case <-ctx.Done():
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := c.connectOnce(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if attempts > 0 && attempt+1 >= attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+		c.log.Errorf(ctx, "%v, retrying (attempt %d)", err, attempt)
+		if retrySleep(ctx, c.connectRetryDelay) {
 			return ctx.Err()
-		default:
 		}
+	}
+}
 
-		tok := c.client.Connect()
-		if ok := tok.WaitTimeout(c.connectTimeout); !ok {
-			c.log.Errorf(ctx, "mqtt connect timeout, retrying (attempt %d)", attempt)
-			if retrySleep(ctx, c.connectRetryDelay) {
-				return ctx.Err()
-			}
-			continue
-		}
-		if err := tok.Error(); err != nil {
-			c.log.Errorf(ctx, "mqtt connect failed, retrying (attempt %d): %v", attempt, err)
-			if retrySleep(ctx, c.connectRetryDelay) {
-				return ctx.Err()
-			}
-			continue
+// connectOnce makes one connection attempt. It returns ctx's error when
+// ctx is canceled while waiting.
+func (c *Client) connectOnce(ctx context.Context) error {
+	tok := c.client.Connect()
+	if !waitToken(ctx, tok, c.connectTimeout) {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		return nil
+		return errors.New("mqtt connect timeout")
+	}
+	if err := tok.Error(); err != nil {
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+	return nil
+}
+
+// waitToken waits for token until timeout passes or ctx is canceled and
+// reports whether it completed. A timeout is counted in
+// consumer.mqtt_timeouts.
+func waitToken(ctx context.Context, token mqtt.Token, timeout time.Duration) bool {
+	select {
+	case <-token.Done():
+		return true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-token.Done():
+		return true
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		metrics.MQTTTimeouts.Add(1)
+		return false
 	}
 }
 
@@ -149,22 +254,17 @@ func newTLSConfig(cfg *config.MQTTConfig) (*tls.Config, error) {
 	}
 
 	if cfg.CACert != "" {
-		caCert, err := os.ReadFile(cfg.CACert)
+		pool, err := certs.Pool(cfg.CACert)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read CA cert: %w", err)
-		}
-
-		caCertPool := x509.NewCertPool()
-		if !caCertPool.AppendCertsFromPEM(caCert) {
-			return nil, errors.New("failed to parse CA cert")
+			return nil, err
 		}
-		tlsConfig.RootCAs = caCertPool
+		tlsConfig.RootCAs = pool
 	}
 
 	if cfg.ClientCert != "" && cfg.ClientKey != "" {
-		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		cert, err := certs.KeyPair(cfg.ClientCert, cfg.ClientKey, "client")
 		if err != nil {
-			return nil, fmt.Errorf("failed to load client cert/key: %w", err)
+			return nil, err
 		}
 		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
@@ -175,21 +275,72 @@ func newTLSConfig(cfg *config.MQTTConfig) (*tls.Config, error) {
 // Publish is fire-and-forget at QoS 0; for QoS >= 1 it waits for broker ack
 // up to writeTimeout.
 func (c *Client) Publish(ctx context.Context, payload []byte) error {
+	return c.PublishTo(ctx, payload, c.publishTopic, c.qos, false)
+}
+
+// PublishTo is Publish with the topic, QoS and retain flag of a single
+// message; the hot path uses it for entries that override them. topic is
+// used as is, without the CN prefix. At QoS 1/2 paho is handed a copy of
+// payload, since it may resend a publish after a reconnect, past the
+// return of a publish that failed or timed out.
+func (c *Client) PublishTo(ctx context.Context, payload []byte, topic string, qos byte, retain bool) error {
+	if qos > 0 {
+		token, held, err := c.startPublish(payload, topic, qos, retain)
+		if err != nil {
+			return err
+		}
+		return c.finishPublish(ctx, token, held)
+	}
 	if !c.connected.Load() {
 		return errNotConnected
 	}
+	if err := checkPayload(topic, payload); err != nil {
+		return err
+	}
+	c.client.Publish(topic, qos, retain, payload)
+	return nil
+}
+
+// heldPayload is the copy of a payload handed to paho by startPublish.
+type heldPayload struct {
+	buf        *[]byte
+	reconnects uint64 // Client.reconnects when it was handed over
+}
 
-	token := c.client.Publish(c.publishTopic, c.qos, false, payload)
+// startPublish hands a pooled copy of payload to paho without waiting for
+// the broker, so the caller may reuse payload as soon as it returns. The
+// copy is returned too, for finishPublish.
+func (c *Client) startPublish(payload []byte, topic string, qos byte, retain bool) (mqtt.Token, heldPayload, error) {
+	if !c.connected.Load() {
+		return nil, heldPayload{}, errNotConnected
+	}
+	if err := checkPayload(topic, payload); err != nil {
+		return nil, heldPayload{}, err
+	}
+	held := heldPayload{buf: copyPayload(payload), reconnects: c.reconnects.Load()}
+	return c.client.Publish(topic, qos, retain, *held.buf), held, nil
+}
 
-	if c.qos == 0 {
-		return nil
+// finishPublish awaits a publish begun by startPublish and pools its copy
+// once the broker confirmed the delivery. A publish that failed or timed
+// out may still be held, and resent, by paho, and so may one paho
+// completed when it resent it after a reconnect, for the resend takes
+// over its token: their copies are left to the garbage collector.
+func (c *Client) finishPublish(ctx context.Context, token mqtt.Token, held heldPayload) error {
+	err := c.awaitPublish(ctx, token)
+	if err == nil && c.reconnects.Load() == held.reconnects {
+		releasePayload(held.buf)
 	}
+	return err
+}
 
-	if !token.WaitTimeout(c.writeTimeout) {
+// awaitPublish waits for the broker to complete a QoS 1/2 publish.
+func (c *Client) awaitPublish(ctx context.Context, token mqtt.Token) error {
+	if !waitToken(ctx, token, c.writeTimeout) {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		return errors.New("mqtt publish timeout")
+		return errPublishTimeout
 	}
 	if err := token.Error(); err != nil {
 		return fmt.Errorf("mqtt publish failed: %w", err)
@@ -197,6 +348,27 @@ func (c *Client) Publish(ctx context.Context, payload []byte) error {
 	return nil
 }
 
+// PublishAck sends payload on the ACK topic, as the remote receiver would.
+// The consumer never answers itself in production; the selftest command
+// uses this to loop an ACK back through the broker.
+func (c *Client) PublishAck(ctx context.Context, payload []byte) error {
+	if !c.connected.Load() {
+		return errNotConnected
+	}
+
+	token := c.client.Publish(c.ackTopic, c.qos, false, payload)
+	if !waitToken(ctx, token, c.writeTimeout) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return errors.New("mqtt ack publish timeout")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("mqtt ack publish failed: %w", err)
+	}
+	return nil
+}
+
 // SubscribeAck registers handler; resubscribeAck restores it after reconnect.
 func (c *Client) SubscribeAck(ctx context.Context, handler func(message.AckMessage)) error {
 	c.ackHandler.Store(&handler)
@@ -205,16 +377,43 @@ func (c *Client) SubscribeAck(ctx context.Context, handler func(message.AckMessa
 		c.handleAckMessage(ctx, msg.Payload())
 	})
 
-	if !token.WaitTimeout(c.subscribeTimeout) {
+	if !waitToken(ctx, token, c.subscribeTimeout) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		return errors.New("mqtt ack subscription timeout")
 	}
 	if err := token.Error(); err != nil {
 		return fmt.Errorf("failed to subscribe to ack topic: %w", err)
 	}
+	if refused(token, c.ackTopic) {
+		return errAckRefused
+	}
 
 	return nil
 }
 
+// UnsubscribeAck clears the handler first so callbacks already queued by
+// paho, and resubscribeAck after a reconnect, become no-ops.
+func (c *Client) UnsubscribeAck(ctx context.Context) error {
+	c.ackHandler.Store(nil)
+	if !c.connected.Load() {
+		return nil
+	}
+
+	token := c.client.Unsubscribe(c.ackTopic)
+	if !waitToken(ctx, token, c.subscribeTimeout) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return errors.New("mqtt ack unsubscribe timeout")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to unsubscribe from ack topic: %w", err)
+	}
+	return nil
+}
+
 // ackDecompBufPool reuses decompression buffers; parseAck copies all strings
 // out so recycling is safe.
 var ackDecompBufPool = sync.Pool{
@@ -231,6 +430,11 @@ func (c *Client) handleAckMessage(ctx context.Context, payload []byte) {
 	}
 	handler := *hp
 
+	if origin, ok := compress.Origin(payload); ok {
+		metrics.AcksSelfOriginated.Add(1)
+		c.log.Debugf(ctx, "Ignoring message published by %s on the ACK topic", origin)
+		return
+	}
 	if compress.IsCompressed(payload) {
 		bufp, ok := ackDecompBufPool.Get().(*[]byte)
 		if !ok || bufp == nil {
@@ -251,9 +455,15 @@ func (c *Client) handleAckMessage(ctx context.Context, payload []byte) {
 		}()
 	}
 
-	ack, err := parseAck(payload)
+	ack, err := parseAck(payload, c.ackKey)
+	if errors.Is(err, ackclient.ErrSignature) {
+		metrics.AcksRejected.Add(1)
+		// Unauthenticated input: log its size, never its content.
+		c.log.Debugf(ctx, "Ignoring ACK without a valid signature (payload length: %d)", len(payload))
+		return
+	}
 	if err != nil {
-		c.log.Debugf(ctx, "Ignoring malformed ACK message: %v (payload length: %d)", err, len(payload))
+		c.log.Debugf(ctx, "Ignoring malformed ACK message: %v: %s", err, c.log.Preview(payload))
 		return
 	}
 
@@ -263,6 +473,12 @@ func (c *Client) handleAckMessage(ctx context.Context, payload []byte) {
 // Close issues a paho Disconnect using disconnectTimeout as the grace period.
 func (c *Client) Close() error {
 	if c.client != nil && c.client.IsConnected() {
+		// A clean DISCONNECT discards the last will, so the offline status
+		// is published first.
+		ctx := context.Background()
+		if err := c.status.publish(ctx, c.client, statusOffline, c.writeTimeout); err != nil {
+			c.log.Warnf(ctx, "Failed to publish the offline status: %v", err)
+		}
 		c.client.Disconnect(uint(max(c.disconnectTimeout.Milliseconds(), 0)))
 	}
 	return nil
@@ -284,11 +500,15 @@ func (c *Client) resubscribeAck(ctx context.Context, mc mqtt.Client) {
 	token := mc.Subscribe(c.ackTopic, c.qos, func(_ mqtt.Client, msg mqtt.Message) {
 		c.handleAckMessage(ctx, msg.Payload())
 	})
-	if !token.WaitTimeout(c.subscribeTimeout) {
+	if !waitToken(ctx, token, c.subscribeTimeout) {
 		c.log.Errorf(ctx, "Failed to re-subscribe to ACK topic: timeout")
 		return
 	}
 	if err := token.Error(); err != nil {
 		c.log.Errorf(ctx, "Failed to re-subscribe to ACK topic: %v", err)
+		return
+	}
+	if refused(token, c.ackTopic) {
+		c.log.Errorf(ctx, "Failed to re-subscribe to ACK topic: %v", errAckRefused)
 	}
 }
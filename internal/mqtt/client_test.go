@@ -12,6 +12,8 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/pkg/ackclient"
 )
 
 func TestMain(m *testing.M) {
@@ -42,6 +44,7 @@ type mockPahoClient struct {
 	connectFn        func() paho.Token
 	publishFn        func(topic string, qos byte, retained bool, payload any) paho.Token
 	subscribeFn      func(topic string, qos byte, callback paho.MessageHandler) paho.Token
+	unsubscribeFn    func(topics ...string) paho.Token
 	connected        bool
 	disconnectCalled bool
 }
@@ -70,7 +73,12 @@ func (m *mockPahoClient) Subscribe(topic string, qos byte, callback paho.Message
 func (m *mockPahoClient) SubscribeMultiple(_ map[string]byte, _ paho.MessageHandler) paho.Token {
 	return &mockPahoToken{}
 }
-func (m *mockPahoClient) Unsubscribe(_ ...string) paho.Token       { return &mockPahoToken{} }
+func (m *mockPahoClient) Unsubscribe(topics ...string) paho.Token {
+	if m.unsubscribeFn != nil {
+		return m.unsubscribeFn(topics...)
+	}
+	return &mockPahoToken{}
+}
 func (m *mockPahoClient) AddRoute(_ string, _ paho.MessageHandler) {}
 func (m *mockPahoClient) OptionsReader() paho.ClientOptionsReader {
 	return paho.NewOptionsReader(paho.NewClientOptions())
@@ -176,6 +184,25 @@ func TestClientConnect_ErrorThenCancel(t *testing.T) {
 	}
 }
 
+func TestClientConnect_GivesUpAfterAttempts(t *testing.T) {
+	calls := 0
+	refused := errors.New("connection refused")
+	c := &Client{
+		client: &mockPahoClient{connectFn: func() paho.Token {
+			calls++
+			return &mockPahoToken{err: refused}
+		}},
+		connectTimeout:    5 * time.Millisecond,
+		connectRetryDelay: time.Millisecond,
+		log:               log.New(),
+	}
+
+	err := c.connect(t.Context(), 3)
+	if !errors.Is(err, refused) || calls != 3 {
+		t.Fatalf("connect() = %v after %d attempts; want the last error after 3", err, calls)
+	}
+}
+
 func testTLSDisabled(t *testing.T) {
 	t.Helper()
 	cfg := &config.MQTTConfig{TLSEnabled: false}
@@ -428,26 +455,45 @@ func TestHandleAckMessage(t *testing.T) {
 			t.Error("Handler should not be called for invalid JSON")
 		}
 	})
+
+	t.Run("SelfOriginated", func(t *testing.T) {
+		called := false
+		handler := func(_ message.AckMessage) { called = true }
+		client.ackHandler.Store(&handler)
+		before := metrics.AcksSelfOriginated.Value()
+
+		// A well-formed ACK, but published by a consumer.
+		payload := compress.EncodeMarked(compress.NewEncoder(), nil,
+			[]byte(`{"ids":["789"],"stream":"s","ack":true}`), []byte("syslog-consumer:test"))
+		client.handleAckMessage(t.Context(), payload)
+
+		if called {
+			t.Error("Handler should not be called for a payload with an origin frame")
+		}
+		if d := metrics.AcksSelfOriginated.Value() - before; d != 1 {
+			t.Errorf("AcksSelfOriginated delta = %d; want 1", d)
+		}
+	})
 }
 
 // --- parseAck additional tests (basics are in ack_test.go) ---
 
 func TestParseAck_EmptyPayload(t *testing.T) {
-	_, err := parseAck([]byte(``))
+	_, err := parseAck([]byte(``), nil)
 	if err == nil {
 		t.Error("expected error for empty payload")
 	}
 }
 
 func TestParseAck_EmptyObject(t *testing.T) {
-	_, err := parseAck([]byte(`{}`))
+	_, err := parseAck([]byte(`{}`), nil)
 	if err == nil {
 		t.Error("expected error for empty object (missing id)")
 	}
 }
 
 func TestParseAck_WithStream(t *testing.T) {
-	ack, err := parseAck([]byte(`{"ids":["999"],"stream":"mystream","ack":true}`))
+	ack, err := parseAck([]byte(`{"ids":["999"],"stream":"mystream","ack":true}`), nil)
 	if err != nil {
 		t.Fatalf("parseAck() error = %v", err)
 	}
@@ -490,6 +536,71 @@ func TestClientClose_NotConnected(t *testing.T) {
 	}
 }
 
+// --- Client.UnsubscribeAck tests ---
+
+func TestClientUnsubscribeAck_Connected(t *testing.T) {
+	var topics []string
+	mock := &mockPahoClient{
+		connected: true,
+		unsubscribeFn: func(t ...string) paho.Token {
+			topics = append(topics, t...)
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{client: mock, ackTopic: tcTopicAck, subscribeTimeout: time.Second}
+	c.connected.Store(true)
+	handler := func(message.AckMessage) {}
+	c.ackHandler.Store(&handler)
+
+	if err := c.UnsubscribeAck(t.Context()); err != nil {
+		t.Fatalf("UnsubscribeAck() error = %v", err)
+	}
+	if len(topics) != 1 || topics[0] != tcTopicAck {
+		t.Errorf("Unsubscribe topics = %v; want [%s]", topics, tcTopicAck)
+	}
+	if c.ackHandler.Load() != nil {
+		t.Error("ackHandler still set after UnsubscribeAck")
+	}
+}
+
+func TestClientUnsubscribeAck_Disconnected(t *testing.T) {
+	called := false
+	mock := &mockPahoClient{
+		unsubscribeFn: func(...string) paho.Token {
+			called = true
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{client: mock, ackTopic: tcTopicAck}
+	handler := func(message.AckMessage) {}
+	c.ackHandler.Store(&handler)
+
+	if err := c.UnsubscribeAck(t.Context()); err != nil {
+		t.Fatalf("UnsubscribeAck() error = %v", err)
+	}
+	if called {
+		t.Error("Unsubscribe() should not be called when not connected")
+	}
+	if c.ackHandler.Load() != nil {
+		t.Error("ackHandler still set after UnsubscribeAck")
+	}
+}
+
+func TestClientUnsubscribeAck_Error(t *testing.T) {
+	mock := &mockPahoClient{
+		connected: true,
+		unsubscribeFn: func(...string) paho.Token {
+			return &mockPahoToken{err: errors.New("broker refused")}
+		},
+	}
+	c := &Client{client: mock, ackTopic: tcTopicAck, subscribeTimeout: time.Second}
+	c.connected.Store(true)
+
+	if err := c.UnsubscribeAck(t.Context()); err == nil {
+		t.Error("UnsubscribeAck() error = nil; want error")
+	}
+}
+
 // --- Client.Publish tests ---
 
 func TestClientPublish_QoS0(t *testing.T) {
@@ -542,6 +653,30 @@ func TestClientPublish_QoS1(t *testing.T) {
 	}
 }
 
+// paho resends a QoS 1/2 publish after a reconnect, possibly past the
+// return of PublishTo, so it must never hold the caller's buffer.
+func TestClientPublishTo_HandsPahoACopy(t *testing.T) {
+	var sent []byte
+	mock := &mockPahoClient{
+		connected: true,
+		publishFn: func(_ string, _ byte, _ bool, payload any) paho.Token {
+			sent, _ = payload.([]byte)
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{client: mock, writeTimeout: 5 * time.Second, log: log.New()}
+	c.connected.Store(true)
+
+	payload := []byte("batch one")
+	if err := c.PublishTo(t.Context(), payload, tcTopicPub, 2, false); err != nil {
+		t.Fatalf("PublishTo() error = %v", err)
+	}
+	copy(payload, "batch two")
+	if string(sent) != "batch one" {
+		t.Errorf("paho holds %q after the caller reused its buffer; want a copy", sent)
+	}
+}
+
 func TestClientPublish_QoS1_Timeout(t *testing.T) {
 	// Token that never completes
 	neverDone := make(chan struct{}) // never closed
@@ -559,11 +694,15 @@ func TestClientPublish_QoS1_Timeout(t *testing.T) {
 		log:          log.New(),
 	}
 	c.connected.Store(true)
+	before := metrics.MQTTTimeouts.Value()
 
 	err := c.Publish(t.Context(), []byte(`{}`))
 	if err == nil {
 		t.Error("expected timeout error")
 	}
+	if d := metrics.MQTTTimeouts.Value() - before; d != 1 {
+		t.Errorf("MQTTTimeouts delta = %d; want 1", d)
+	}
 }
 
 func TestClientPublish_QoS1_ContextCancel(t *testing.T) {
@@ -586,9 +725,13 @@ func TestClientPublish_QoS1_ContextCancel(t *testing.T) {
 	ctx, cancel := context.WithCancel(t.Context())
 	cancel() // cancel immediately
 
+	start := time.Now()
 	err := c.Publish(ctx, []byte(`{}`))
-	if err == nil {
-		t.Error("expected context cancellation error")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Publish() error = %v; want context cancellation", err)
+	}
+	if elapsed := time.Since(start); elapsed >= c.writeTimeout {
+		t.Errorf("Publish() returned after %v; want it to stop waiting once ctx is canceled", elapsed)
 	}
 }
 
@@ -682,6 +825,34 @@ func TestClientSubscribeAck_Error(t *testing.T) {
 	}
 }
 
+// subackToken carries the SUBACK return codes of a SubscribeToken.
+type subackToken struct {
+	mockPahoToken
+	codes map[string]byte
+}
+
+func (t *subackToken) Result() map[string]byte { return t.codes }
+
+func TestClientSubscribeAck_Refused(t *testing.T) {
+	mock := &mockPahoClient{
+		subscribeFn: func(topic string, _ byte, _ paho.MessageHandler) paho.Token {
+			return &subackToken{codes: map[string]byte{topic: subackFailure}}
+		},
+	}
+	c := &Client{
+		client:           mock,
+		ackTopic:         tcTopicAck,
+		qos:              0,
+		subscribeTimeout: 5 * time.Second,
+		log:              log.New(),
+	}
+
+	err := c.SubscribeAck(t.Context(), func(_ message.AckMessage) {})
+	if !errors.Is(err, errAckRefused) {
+		t.Errorf("SubscribeAck() error = %v; want errAckRefused", err)
+	}
+}
+
 // slowToken never completes Wait — used for timeout tests
 type slowToken struct {
 	done chan struct{}
@@ -746,6 +917,87 @@ func TestHandleAckMessage_EmptyStreamRejected(t *testing.T) {
 	}
 }
 
+func TestHandleAckMessage_Signature(t *testing.T) {
+	key := []byte("secret")
+	var got []message.AckMessage
+	client := &Client{log: log.New(), ackKey: key}
+	handler := func(ack message.AckMessage) { got = append(got, ack) }
+	client.ackHandler.Store(&handler)
+	ack := ackclient.Accept("s1", "1-0")
+	signed, _ := ackclient.Marshal(&ack, key)
+	unsigned, _ := ackclient.Marshal(&ack, nil)
+	forged, _ := ackclient.Marshal(&ack, []byte("other"))
+	rejected := metrics.AcksRejected.Value()
+
+	for _, payload := range [][]byte{unsigned, forged, signed} {
+		client.handleAckMessage(t.Context(), payload)
+	}
+	if len(got) != 1 || got[0].Stream != "s1" {
+		t.Errorf("handler got %+v; want only the signed ACK", got)
+	}
+	if n := metrics.AcksRejected.Value() - rejected; n != 2 {
+		t.Errorf("AcksRejected delta = %d; want 2", n)
+	}
+}
+
+func TestClientPublishAck_UsesAckTopic(t *testing.T) {
+	var gotTopic string
+	mock := &mockPahoClient{
+		connected: true,
+		publishFn: func(topic string, _ byte, _ bool, _ any) paho.Token {
+			gotTopic = topic
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{
+		client:       mock,
+		publishTopic: tcTopicPub,
+		ackTopic:     tcTopicAck,
+		qos:          1,
+		writeTimeout: 5 * time.Second,
+		log:          log.New(),
+	}
+	c.connected.Store(true)
+
+	if err := c.PublishAck(t.Context(), []byte(`{"ids":["1-0"],"stream":"s","ack":true}`)); err != nil {
+		t.Fatalf("PublishAck() error = %v", err)
+	}
+	if gotTopic != tcTopicAck {
+		t.Errorf("topic = %q; want %q", gotTopic, tcTopicAck)
+	}
+}
+
+func TestClientPublishTo_OverridesTopicQoSRetain(t *testing.T) {
+	var (
+		gotTopic  string
+		gotQoS    byte
+		gotRetain bool
+	)
+	mock := &mockPahoClient{
+		connected: true,
+		publishFn: func(topic string, qos byte, retained bool, _ any) paho.Token {
+			gotTopic, gotQoS, gotRetain = topic, qos, retained
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{client: mock, publishTopic: tcTopicPub, writeTimeout: 5 * time.Second, log: log.New()}
+	c.connected.Store(true)
+
+	if err := c.PublishTo(t.Context(), []byte("x"), "alerts/disk", 2, true); err != nil {
+		t.Fatalf("PublishTo() error = %v", err)
+	}
+	if gotTopic != "alerts/disk" || gotQoS != 2 || !gotRetain {
+		t.Errorf("published to %q qos %d retain %v; want alerts/disk qos 2 retain true", gotTopic, gotQoS, gotRetain)
+	}
+}
+
+func TestClientPublishAck_NotConnected(t *testing.T) {
+	c := &Client{client: &mockPahoClient{}, ackTopic: tcTopicAck, log: log.New()}
+	if err := c.PublishAck(t.Context(), []byte(`{}`)); !errors.Is(err, errNotConnected) {
+		t.Errorf("PublishAck() error = %v; want errNotConnected", err)
+	}
+}
+
 // --- Publish returns errNotConnected when connection is not open ---
 
 func TestClientPublish_NotConnected(t *testing.T) {
@@ -870,3 +1122,32 @@ func TestClientClose_NotConnected_NoDisconnect(t *testing.T) {
 		t.Error("Disconnect should not be called when not connected")
 	}
 }
+
+func TestNewClient_PersistentSession(t *testing.T) {
+	cfg := testMQTTConfig()
+	cfg.PersistentSession = true
+	cfg.StoreDir = t.TempDir()
+	cfg.StoreMaxMessages = 10
+
+	client, err := NewClient(t.Context(), cfg, log.New())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	opts := client.client.OptionsReader()
+	if opts.CleanSession() {
+		t.Error("CleanSession() = true; want false for persistent sessions")
+	}
+	if !opts.ResumeSubs() {
+		t.Error("ResumeSubs() = false; want true")
+	}
+}
+
+func TestNewClient_DefaultCleanSession(t *testing.T) {
+	client, err := NewClient(t.Context(), testMQTTConfig(), log.New())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if opts := client.client.OptionsReader(); !opts.CleanSession() {
+		t.Error("CleanSession() = false; want true by default")
+	}
+}
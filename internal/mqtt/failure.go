@@ -0,0 +1,110 @@
+package mqtt
+
+import (
+	"errors"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Failure classifies a failed publish by what the caller should do about
+// it. MQTT 3.1.1 has no reason codes on PUBACK: a broker that refuses a
+// publish, e.g. on an ACL, either drops it silently or closes the
+// connection, which surfaces as FailureDisconnected.
+type Failure int
+
+const (
+	// FailureTransient is any other failure: the entries stay pending
+	// and are claimed again.
+	FailureTransient Failure = iota
+	// FailureBusy means the broker or the client is saturated: the publish
+	// timed out or paho ran out of message IDs for in-flight publishes.
+	// The caller backs off before publishing again.
+	FailureBusy
+	// FailureDisconnected means the connection is down or was lost during
+	// the publish. The caller waits for the reconnect instead of
+	// publishing into a connection that is not there.
+	FailureDisconnected
+	// FailureRejected means the publish can never succeed as is: the
+	// payload is over the MQTT packet limit, or paho refused the topic or
+	// QoS. Retrying is pointless; the caller dead-letters the entries.
+	FailureRejected
+)
+
+// maxRemainingLength is the largest remaining length an MQTT packet can
+// encode; a PUBLISH carries the topic and packet ID in it too.
+const maxRemainingLength = 268435455
+
+var (
+	errPublishTimeout  = errors.New("mqtt publish timeout")
+	errPayloadTooLarge = errors.New("mqtt: payload over the MQTT packet size limit")
+)
+
+// pahoFailures maps the errors paho builds with fmt.Errorf, which cannot
+// be matched with errors.Is, by their text.
+var pahoFailures = []struct {
+	text    string
+	failure Failure
+}{
+	{"no message IDs available", FailureBusy},
+	{"publish was broken by timeout", FailureBusy},
+	{"connection lost before Publish completed", FailureDisconnected},
+	{"not currently connected and ResumeSubs not set", FailureDisconnected},
+	{"reconnecting state and cleansession is true", FailureDisconnected},
+	{"unknown payload type", FailureRejected},
+}
+
+// String returns the label of f used in logs and metrics.
+func (f Failure) String() string {
+	switch f {
+	case FailureBusy:
+		return "busy"
+	case FailureDisconnected:
+		return "disconnected"
+	case FailureRejected:
+		return "rejected"
+	default:
+		return "transient"
+	}
+}
+
+// Classify returns the class of a non-nil publish error.
+func Classify(err error) Failure {
+	switch {
+	case errors.Is(err, errNotConnected), errors.Is(err, mqtt.ErrNotConnected):
+		return FailureDisconnected
+	case errors.Is(err, errPublishTimeout):
+		return FailureBusy
+	case errors.Is(err, errPayloadTooLarge), errors.Is(err, mqtt.ErrInvalidQos),
+		errors.Is(err, mqtt.ErrInvalidTopicEmptyString), errors.Is(err, mqtt.ErrInvalidTopicMultilevel):
+		return FailureRejected
+	}
+	text := err.Error()
+	for _, f := range pahoFailures {
+		if strings.Contains(text, f.text) {
+			return f.failure
+		}
+	}
+	return FailureTransient
+}
+
+// connectionFault reports whether err counts toward quarantining the
+// connection it came from: a rejected publish is the payload's fault, and
+// a lost connection is reconnected by paho already.
+func connectionFault(err error) bool {
+	switch Classify(err) {
+	case FailureDisconnected, FailureRejected:
+		return false
+	default:
+		return true
+	}
+}
+
+// checkPayload rejects a payload no broker could accept on topic.
+func checkPayload(topic string, payload []byte) error {
+	// 2 bytes of topic length and 2 of packet ID.
+	if len(payload)+len(topic)+4 > maxRemainingLength {
+		return errPayloadTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Failure
+	}{
+		{errNotConnected, FailureDisconnected},
+		{fmt.Errorf("mqtt publish failed: %w", mqtt.ErrNotConnected), FailureDisconnected},
+		{fmt.Errorf("mqtt publish failed: %w", errors.New("connection lost before Publish completed")), FailureDisconnected},
+		{errPublishTimeout, FailureBusy},
+		{fmt.Errorf("mqtt publish failed: %w", errors.New("no message IDs available")), FailureBusy},
+		{errPayloadTooLarge, FailureRejected},
+		{fmt.Errorf("mqtt publish failed: %w", mqtt.ErrInvalidQos), FailureRejected},
+		{fmt.Errorf("mqtt publish failed: %w", errors.New("unknown payload type")), FailureRejected},
+		{errors.New("broker unavailable"), FailureTransient},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %s; want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionFault(t *testing.T) {
+	if connectionFault(errNotConnected) || connectionFault(errPayloadTooLarge) {
+		t.Error("connectionFault() = true for a lost connection or a rejected payload; want false")
+	}
+	if !connectionFault(errPublishTimeout) {
+		t.Error("connectionFault(timeout) = false; want true")
+	}
+	if err := checkPayload("t", []byte("x")); err != nil {
+		t.Errorf("checkPayload() error = %v; want nil", err)
+	}
+}
@@ -0,0 +1,110 @@
+package mqtt
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// healthWeight is the weight of the newest publish in the moving averages
+// of connHealth: roughly the last 20 publishes count.
+const healthWeight = 0.1
+
+// connHealth tracks the recent publishes of one connection. The zero value
+// is a healthy connection with no history.
+type connHealth struct {
+	published atomic.Uint64
+	failed    atomic.Uint64
+	// consecutive counts failed publishes since the last successful one.
+	consecutive atomic.Int64
+	// failureRate and rtt hold the moving averages of publish failures
+	// (0 to 1) and of successful publish round trips (seconds), as float64
+	// bits.
+	failureRate atomic.Uint64
+	rtt         atomic.Uint64
+	// inFlight counts the publishes in progress, and progress holds the
+	// unix nanoseconds one last started on an idle connection or ended.
+	inFlight    atomic.Int64
+	progress    atomic.Int64
+	quarantined atomic.Bool
+}
+
+// begin records the start of a publish.
+func (h *connHealth) begin(now time.Time) {
+	if h.inFlight.Add(1) == 1 {
+		h.progress.Store(now.UnixNano())
+	}
+}
+
+// end records the end of a publish, however it went.
+func (h *connHealth) end(now time.Time) {
+	h.progress.Store(now.UnixNano())
+	h.inFlight.Add(-1)
+}
+
+// stalled reports whether publishes are in progress and none has ended for
+// longer than after.
+func (h *connHealth) stalled(now time.Time, after time.Duration) bool {
+	return h.inFlight.Load() > 0 && now.Sub(time.Unix(0, h.progress.Load())) > after
+}
+
+// success records a publish acknowledged after rtt. At QoS 0 that is the
+// time paho took to queue it.
+func (h *connHealth) success(rtt time.Duration) {
+	h.published.Add(1)
+	h.consecutive.Store(0)
+	updateAverage(&h.failureRate, 0)
+	if h.rtt.Load() == 0 {
+		h.rtt.Store(math.Float64bits(rtt.Seconds()))
+		return
+	}
+	updateAverage(&h.rtt, rtt.Seconds())
+}
+
+// failure records a failed publish and returns the consecutive failures.
+func (h *connHealth) failure() int64 {
+	h.failed.Add(1)
+	updateAverage(&h.failureRate, 1)
+	return h.consecutive.Add(1)
+}
+
+// updateAverage moves the float64 in bits towards sample.
+func updateAverage(bits *atomic.Uint64, sample float64) {
+	for {
+		old := bits.Load()
+		avg := math.Float64frombits(old)
+		if bits.CompareAndSwap(old, math.Float64bits(avg+healthWeight*(sample-avg))) {
+			return
+		}
+	}
+}
+
+// ConnectionStats is a snapshot of one pool connection, as published under
+// consumer.mqtt_connections.
+type ConnectionStats struct {
+	Published   uint64  `json:"published"`
+	Failed      uint64  `json:"failed"`
+	SuccessRate float64 `json:"success_rate"`
+	RTTMillis   float64 `json:"rtt_ms"`
+	InFlight    int64   `json:"in_flight"`
+	Connected   bool    `json:"connected"`
+	Quarantined bool    `json:"quarantined"`
+}
+
+// Stats returns a snapshot of the connection's recent publishes.
+func (c *Client) Stats() ConnectionStats {
+	return ConnectionStats{
+		Published:   c.health.published.Load(),
+		Failed:      c.health.failed.Load(),
+		SuccessRate: 1 - math.Float64frombits(c.health.failureRate.Load()),
+		RTTMillis:   math.Float64frombits(c.health.rtt.Load()) * 1000,
+		InFlight:    c.health.inFlight.Load(),
+		Connected:   c.IsConnected(),
+		Quarantined: c.health.quarantined.Load(),
+	}
+}
+
+// available reports whether the pool may publish through c.
+func (c *Client) available() bool {
+	return c.connected.Load() && !c.health.quarantined.Load()
+}
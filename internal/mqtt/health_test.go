@@ -0,0 +1,56 @@
+package mqtt
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConnHealth_Averages(t *testing.T) {
+	c := &Client{}
+	c.health.success(10 * time.Millisecond)
+	if stats := c.Stats(); stats.RTTMillis != 10 || stats.SuccessRate != 1 {
+		t.Errorf("stats = %+v; want the first round trip and a full success rate", stats)
+	}
+
+	c.health.success(20 * time.Millisecond)
+	if got := c.Stats().RTTMillis; math.Abs(got-11) > 1e-9 {
+		t.Errorf("RTTMillis = %v; want 11, moved a tenth towards 20", got)
+	}
+	if n := c.health.failure(); n != 1 {
+		t.Errorf("consecutive failures = %d; want 1", n)
+	}
+	if n := c.health.failure(); n != 2 {
+		t.Errorf("consecutive failures = %d; want 2", n)
+	}
+	if got := c.Stats().SuccessRate; math.Abs(got-0.81) > 1e-9 {
+		t.Errorf("SuccessRate = %v; want 0.81 after two failures", got)
+	}
+
+	c.health.success(time.Millisecond)
+	stats := c.Stats()
+	if c.health.consecutive.Load() != 0 || stats.Published != 3 || stats.Failed != 2 {
+		t.Errorf("stats = %+v; want the failure streak reset", stats)
+	}
+}
+
+func TestConnHealth_Stalled(t *testing.T) {
+	var h connHealth
+	start := time.Unix(100, 0)
+	if h.stalled(start.Add(time.Hour), time.Second) {
+		t.Error("stalled() = true; want false without publishes in progress")
+	}
+	h.begin(start)
+	h.begin(start.Add(time.Second)) // a second publish is no progress
+	if !h.stalled(start.Add(3*time.Second), 2*time.Second) {
+		t.Error("stalled() = false; want true three seconds after the first publish started")
+	}
+	h.end(start.Add(4 * time.Second))
+	if h.stalled(start.Add(5*time.Second), 2*time.Second) {
+		t.Error("stalled() = true; want false a second after a publish ended")
+	}
+	h.end(start.Add(5 * time.Second))
+	if h.inFlight.Load() != 0 || h.stalled(start.Add(time.Hour), time.Second) {
+		t.Error("stalled() = true; want false once every publish ended")
+	}
+}
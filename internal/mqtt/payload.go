@@ -0,0 +1,54 @@
+package mqtt
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Pipelined publishes hand paho a copy of the payload, since the worker
+// reuses its buffer. The copies are taken from pools by size class, powers
+// of two from 1 KiB to 16 MiB, and returned once the broker confirmed the
+// delivery; a larger payload gets a copy of its own.
+const (
+	minPayloadShift = 10
+	payloadClasses  = 15
+)
+
+var payloadPools [payloadClasses]sync.Pool
+
+// payloadClass returns the size class of a buffer holding size bytes, or -1
+// when size is past the largest class.
+func payloadClass(size int) int {
+	class := max(bits.Len(uint(max(size-1, 0))), minPayloadShift) - minPayloadShift
+	if class >= payloadClasses {
+		return -1
+	}
+	return class
+}
+
+// copyPayload returns a copy of payload in a pooled buffer; give it back
+// with releasePayload once paho is done with it.
+func copyPayload(payload []byte) *[]byte {
+	class := payloadClass(len(payload))
+	if class < 0 {
+		b := append([]byte(nil), payload...)
+		return &b
+	}
+	bufp, ok := payloadPools[class].Get().(*[]byte)
+	if !ok {
+		b := make([]byte, 0, 1<<(class+minPayloadShift))
+		bufp = &b
+	}
+	*bufp = append((*bufp)[:0], payload...)
+	return bufp
+}
+
+// releasePayload returns a buffer from copyPayload to its pool. paho must
+// no longer hold it: the buffer is handed out again at once.
+func releasePayload(bufp *[]byte) {
+	class := payloadClass(cap(*bufp))
+	if class < 0 || cap(*bufp) != 1<<(class+minPayloadShift) {
+		return // a payload past the largest class
+	}
+	payloadPools[class].Put(bufp)
+}
@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+)
+
+func TestPayloadClass(t *testing.T) {
+	tests := []struct {
+		size, want int
+	}{
+		{0, 0},
+		{1, 0},
+		{1 << 10, 0},
+		{1<<10 + 1, 1},
+		{64 << 10, 6},
+		{16 << 20, payloadClasses - 1},
+		{16<<20 + 1, -1},
+	}
+	for _, tt := range tests {
+		if got := payloadClass(tt.size); got != tt.want {
+			t.Errorf("payloadClass(%d) = %d; want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestCopyPayload(t *testing.T) {
+	for _, size := range []int{0, 100, 5000, 16<<20 + 1} {
+		payload := bytes.Repeat([]byte{'x'}, size)
+		bufp := copyPayload(payload)
+		if !bytes.Equal(*bufp, payload) {
+			t.Fatalf("copyPayload(%d bytes) = %d bytes; want an equal copy", size, len(*bufp))
+		}
+		if class := payloadClass(size); class >= 0 && cap(*bufp) != 1<<(class+minPayloadShift) {
+			t.Errorf("copyPayload(%d bytes) cap = %d; want the size of class %d", size, cap(*bufp), class)
+		}
+		releasePayload(bufp)
+	}
+
+	// A reused buffer holds the new payload only.
+	releasePayload(copyPayload(bytes.Repeat([]byte{'y'}, 900)))
+	if bufp := copyPayload([]byte("short")); string(*bufp) != "short" {
+		t.Errorf("copyPayload() = %q; want %q", *bufp, "short")
+	}
+}
+
+// BenchmarkPipelinedPayloadCopy compares the copies of pipelined publishes
+// at 50k msg/s with the high-throughput profile: a 1000-entry batch of
+// about 200 bytes per entry per payload, 50 payloads a second, 64 of them
+// awaiting the broker at any time. gc/s is the collections a second of that
+// load costs.
+func BenchmarkPipelinedPayloadCopy(b *testing.B) {
+	const inFlight, perSecond = 64, 50
+	payload := bytes.Repeat([]byte{'x'}, 1000*200)
+	run := func(b *testing.B, copyFn func([]byte) *[]byte, release func(*[]byte)) {
+		window := make([]*[]byte, inFlight)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			slot := i % inFlight
+			if window[slot] != nil {
+				release(window[slot]) // the broker confirmed the oldest
+			}
+			window[slot] = copyFn(payload)
+		}
+		b.StopTimer()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.NumGC-before.NumGC)*perSecond/float64(b.N), "gc/s")
+	}
+
+	b.Run("clone", func(b *testing.B) {
+		run(b, func(p []byte) *[]byte { c := bytes.Clone(p); return &c }, func(*[]byte) {})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		run(b, copyPayload, releasePayload)
+	})
+}
@@ -3,24 +3,49 @@ package mqtt
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
 )
 
 // Pool fans out publishes across several paho connections to raise broker
 // throughput beyond what one TCP connection can sustain.
+//
+// It tracks the publishes of every connection. Under MQTT_QUARANTINE_FAILURES
+// a connection whose publishes keep failing is taken out of the rotation and
+// reconnected in the background, then put back. Under MQTT_MAX_INFLIGHT
+// QoS 1/2 publishes are pipelined: they return once handed to a connection
+// and complete in the background.
 type Pool struct {
-	log     *log.Logger
-	clients []*Client
-	next    atomic.Uint64
-	size    uint
+	log *log.Logger
+	// rtt times a sample of the publishes under MQTT_RTT_SAMPLE_EVERY.
+	rtt *rttSampler
+	// transitions records the connections taken out of the rotation and
+	// put back; see SetLifecycle.
+	transitions *lifecycle.Recorder
+	// stop is closed by Close to end the background reconnects.
+	stop       chan struct{}
+	clients    []*Client
+	reconnects sync.WaitGroup
+	// pipelined tracks the pipelined publishes awaiting the broker.
+	pipelined sync.WaitGroup
+	next      atomic.Uint64
+	size      uint
+	// quarantineAfter is MQTT_QUARANTINE_FAILURES; 0 disables quarantine.
+	quarantineAfter int64
+	closeOnce       sync.Once
 }
 
 func closeClients(ctx context.Context, logger *log.Logger, clients []*Client, count int) {
@@ -35,20 +60,30 @@ func closeClients(ctx context.Context, logger *log.Logger, clients []*Client, co
 	}
 }
 
-// NewPool retries each connection until the broker responds or ctx is canceled.
-func NewPool(ctx context.Context, cfg *config.MQTTConfig, poolSize int, logger *log.Logger) (*Pool, error) {
-	if poolSize < 1 {
-		return nil, errors.New("mqtt: pool size must be positive")
-	}
-
-	// Per-process suffix prevents Client ID collisions across instances.
+// poolClientIDBase adds a per-process suffix to prevent Client ID collisions
+// across instances. Persistent sessions are keyed by Client ID on the broker
+// (and by directory in the inflight store), so the PID is left out there:
+// it would change on every restart and orphan the previous session.
+func poolClientIDBase(cfg *config.MQTTConfig) string {
 	hostname, err := os.Hostname()
 	if err != nil {
 		hostname = "unknown"
 	}
-	pid := os.Getpid()
-	baseClientID := fmt.Sprintf("%s-%s-%d", cfg.ClientID, hostname, pid)
+	if cfg.PersistentSession {
+		return fmt.Sprintf("%s-%s", cfg.ClientID, hostname)
+	}
+	return fmt.Sprintf("%s-%s-%d", cfg.ClientID, hostname, os.Getpid())
+}
 
+// NewPool retries each connection until the broker responds or ctx is
+// canceled, or gives up after MQTT_CONNECT_ATTEMPTS failed attempts when
+// it is set.
+func NewPool(ctx context.Context, cfg *config.MQTTConfig, poolSize int, logger *log.Logger) (*Pool, error) {
+	if poolSize < 1 {
+		return nil, errors.New("mqtt: pool size must be positive")
+	}
+
+	baseClientID := poolClientIDBase(cfg)
 	clients := make([]*Client, poolSize)
 
 	g, gctx := errgroup.WithContext(ctx)
@@ -56,7 +91,7 @@ func NewPool(ctx context.Context, cfg *config.MQTTConfig, poolSize int, logger *
 		clientCfg := *cfg
 		clientCfg.ClientID = fmt.Sprintf("%s-%d", baseClientID, i)
 
-		client, err := NewClient(ctx, &clientCfg, logger)
+		client, err := newClient(ctx, &clientCfg, baseClientID, logger)
 		if err != nil {
 			closeClients(ctx, logger, clients, poolSize)
 			return nil, fmt.Errorf("failed to create client %d: %w", i, err)
@@ -64,7 +99,7 @@ func NewPool(ctx context.Context, cfg *config.MQTTConfig, poolSize int, logger *
 		clients[i] = client
 
 		g.Go(func() error {
-			if err := client.Connect(gctx); err != nil {
+			if err := client.connect(gctx, cfg.ConnectAttempts); err != nil {
 				return fmt.Errorf("failed to connect client %d: %w", i, err)
 			}
 			return nil
@@ -76,37 +111,220 @@ func NewPool(ctx context.Context, cfg *config.MQTTConfig, poolSize int, logger *
 		return nil, err
 	}
 
+	for i, client := range clients {
+		metrics.MQTTConnections.Set(strconv.Itoa(i), expvar.Func(func() any { return client.Stats() }))
+	}
+
 	return &Pool{
-		clients: clients,
-		size:    uint(poolSize),
-		log:     logger,
+		clients:         clients,
+		size:            uint(poolSize),
+		log:             logger,
+		stop:            make(chan struct{}),
+		rtt:             newRTTSampler(cfg, time.Now()),
+		quarantineAfter: int64(cfg.QuarantineFailures),
 	}, nil
 }
 
-// Publish skips disconnected clients and tries all pool members before failing.
+// Publish skips disconnected and quarantined clients and tries all pool
+// members before failing.
 func (p *Pool) Publish(ctx context.Context, payload message.Payload) error {
-	start := p.next.Add(1) - 1
-	sz := uint64(p.size)
-	for i := range p.size {
-		c := p.clients[(start+uint64(i))%sz]
-		if !c.IsConnected() {
-			continue
-		}
-		return c.Publish(ctx, payload)
+	idx, c := p.pick(p.next.Add(1) - 1)
+	if c == nil {
+		return errNotConnected
 	}
-	return errNotConnected
+	return p.publishOn(ctx, idx, c, payload, c.publishTopic, c.qos, false)
 }
 
 // PublishFrom takes the round-robin hint from the caller to avoid contention
 // on the shared atomic counter.
 func (p *Pool) PublishFrom(ctx context.Context, payload message.Payload, hint uint64) error {
+	idx, c := p.pick(hint)
+	if c == nil {
+		return errNotConnected
+	}
+	return p.publishOn(ctx, idx, c, payload, c.publishTopic, c.qos, false)
+}
+
+// PublishTo is Publish with the topic, QoS and retain flag of a single
+// message.
+func (p *Pool) PublishTo(ctx context.Context, payload message.Payload, topic string, qos byte, retain bool) error {
+	idx, c := p.pick(p.next.Add(1) - 1)
+	if c == nil {
+		return errNotConnected
+	}
+	return p.publishOn(ctx, idx, c, payload, topic, qos, retain)
+}
+
+// pick returns the first client from start on, round-robin, that is
+// connected and not quarantined, or nil when there is none.
+func (p *Pool) pick(start uint64) (uint64, *Client) {
 	sz := uint64(p.size)
 	for i := range p.size {
-		c := p.clients[(hint+uint64(i))%sz]
-		if !c.IsConnected() {
+		idx := (start + uint64(i)) % sz
+		if c := p.clients[idx]; c.available() {
+			return idx, c
+		}
+	}
+	return 0, nil
+}
+
+// publishOn publishes through c, the client at idx, and records the outcome
+// in its health, and in the broker RTT when the publish is sampled.
+func (p *Pool) publishOn(
+	ctx context.Context, idx uint64, c *Client, payload []byte, topic string, qos byte, retain bool,
+) error {
+	if c.inflight != nil && qos > 0 {
+		return p.publishPipelined(ctx, idx, c, payload, topic, qos, retain)
+	}
+	sampled := p.rtt.sample(qos)
+	start := time.Now()
+	c.health.begin(start)
+	err := c.PublishTo(ctx, payload, topic, qos, retain)
+	end := time.Now()
+	c.health.end(end)
+	if sampled {
+		p.rtt.observe(start, end, err)
+	}
+	p.record(ctx, idx, c, start, err)
+	return err
+}
+
+// publishPipelined hands payload to c once one of its MQTT_MAX_INFLIGHT
+// slots is free, and returns. The broker's answer is awaited in the
+// background, up to the write timeout, and recorded in c's health; a
+// failure is logged and counted, and the entries the payload carried stay
+// pending in Redis until the claim loop hands them out again.
+func (p *Pool) publishPipelined(
+	ctx context.Context, idx uint64, c *Client, payload []byte, topic string, qos byte, retain bool,
+) error {
+	select {
+	case c.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	sampled := p.rtt.sample(qos)
+	start := time.Now()
+	c.health.begin(start)
+	token, held, err := c.startPublish(payload, topic, qos, retain)
+	if err != nil {
+		c.health.end(time.Now())
+		<-c.inflight
+		return err
+	}
+	// The publish outlives the caller's context; the write timeout ends it.
+	ctx = context.WithoutCancel(ctx)
+	p.pipelined.Go(func() {
+		err := c.finishPublish(ctx, token, held)
+		end := time.Now()
+		c.health.end(end)
+		<-c.inflight
+		if sampled {
+			p.rtt.observe(start, end, err)
+		}
+		p.record(ctx, idx, c, start, err)
+		if err != nil {
+			metrics.MQTTPipelinedErrors.Add(1)
+			p.log.Errorf(ctx, "Pipelined publish on MQTT connection %d failed: %v", idx, err)
+		}
+	})
+	return nil
+}
+
+// record records the outcome of a publish started at start in c's health.
+// Failures caused by ctx, or by a connection already known to be down, are
+// not counted.
+func (p *Pool) record(ctx context.Context, idx uint64, c *Client, start time.Time, err error) {
+	switch {
+	case err == nil:
+		c.health.success(time.Since(start))
+	case ctx.Err() != nil, !connectionFault(err):
+		// Not the connection's fault, or paho is already reconnecting it.
+	default:
+		if n := c.health.failure(); p.quarantineAfter > 0 && n >= p.quarantineAfter {
+			p.quarantine(ctx, idx, c)
+		}
+	}
+}
+
+// SetLifecycle has p record to r each connection it takes out of the
+// rotation, as breaker-open, and puts back, as breaker-closed. Call it
+// before publishing.
+func (p *Pool) SetLifecycle(r *lifecycle.Recorder) {
+	p.transitions = r
+}
+
+// quarantine takes c out of the rotation and reconnects it in the
+// background; only the first publish past the threshold does so.
+func (p *Pool) quarantine(ctx context.Context, idx uint64, c *Client) {
+	if !c.health.quarantined.CompareAndSwap(false, true) {
+		return
+	}
+	metrics.MQTTQuarantines.Add(1)
+	p.log.Warnf(ctx, "MQTT connection %d quarantined after %d consecutive failed publishes, reconnecting",
+		idx, p.quarantineAfter)
+	p.transitions.Record(lifecycle.BreakerOpen, map[string]any{"connection": idx, "reason": "failures"})
+	p.reconnectInBackground(ctx, idx, c)
+}
+
+// ReconnectStalled takes every connection whose publishes have made no
+// progress for longer than after out of the rotation and reconnects it in
+// the background, like a quarantined one. It returns how many it took out.
+func (p *Pool) ReconnectStalled(ctx context.Context, after time.Duration) int {
+	now := time.Now()
+	n := 0
+	for i, c := range p.clients {
+		if !c.health.stalled(now, after) || !c.health.quarantined.CompareAndSwap(false, true) {
 			continue
 		}
-		return c.Publish(ctx, payload)
+		n++
+		metrics.MQTTStallReconnects.Add(1)
+		p.log.Warnf(ctx, "MQTT connection %d has made no publish progress for %s, reconnecting", i, after)
+		p.transitions.Record(lifecycle.BreakerOpen, map[string]any{"connection": i, "reason": "stalled"})
+		p.reconnectInBackground(ctx, uint64(i), c)
+	}
+	return n
+}
+
+// reconnectInBackground runs reconnect in the background; it outlives ctx,
+// and Close ends it.
+func (p *Pool) reconnectInBackground(ctx context.Context, idx uint64, c *Client) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	p.reconnects.Go(func() {
+		defer cancel()
+		p.reconnect(ctx, idx, c)
+	})
+}
+
+// reconnect drops c's connection and connects it again, then puts it back
+// in the rotation. The OnConnect handler restores the ACK subscription.
+func (p *Pool) reconnect(ctx context.Context, idx uint64, c *Client) {
+	c.connected.Store(false)
+	c.client.Disconnect(uint(max(c.disconnectTimeout.Milliseconds(), 0)))
+	if err := c.Connect(ctx); err != nil {
+		return // the pool is closing
+	}
+	c.health.consecutive.Store(0)
+	// A publish stuck on the old connection must not flag the new one.
+	c.health.progress.Store(time.Now().UnixNano())
+	c.health.quarantined.Store(false)
+	p.log.Infof(ctx, "MQTT connection %d reconnected, back in rotation", idx)
+	p.transitions.Record(lifecycle.BreakerClosed, map[string]any{"connection": idx})
+}
+
+// PublishAck sends payload on the ACK topic through the first connected
+// pool member.
+func (p *Pool) PublishAck(ctx context.Context, payload []byte) error {
+	for _, c := range p.clients {
+		if c.IsConnected() {
+			return c.PublishAck(ctx, payload)
+		}
 	}
 	return errNotConnected
 }
@@ -122,8 +340,29 @@ func (p *Pool) SubscribeAck(ctx context.Context, handler func(message.AckMessage
 	return nil
 }
 
-// Close disconnects every pool member; returned errors are joined.
+// UnsubscribeAck drops the ACK subscription on every client; returned
+// errors are joined.
+func (p *Pool) UnsubscribeAck(ctx context.Context) error {
+	var errs []error
+	for i, c := range p.clients {
+		if err := c.UnsubscribeAck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unsubscribe ACK on client %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close waits for the pipelined publishes, ends the background reconnects,
+// then disconnects every pool member; returned errors are joined.
 func (p *Pool) Close() error {
+	p.pipelined.Wait()
+	p.closeOnce.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+		}
+	})
+	p.reconnects.Wait()
+
 	var errs []error
 	for i, client := range p.clients {
 		if err := client.Close(); err != nil {
@@ -2,13 +2,21 @@ package mqtt
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
 )
 
 // --- NewPool tests ---
@@ -69,6 +77,31 @@ func TestPoolClose_MultipleClients(t *testing.T) {
 	}
 }
 
+func TestPoolUnsubscribeAck_JoinsErrors(t *testing.T) {
+	ok := &mockPahoClient{connected: true}
+	failing := &mockPahoClient{
+		connected: true,
+		unsubscribeFn: func(...string) paho.Token {
+			return &mockPahoToken{err: errors.New("broker refused")}
+		},
+	}
+	p := &Pool{
+		clients: []*Client{
+			{client: ok, subscribeTimeout: time.Second},
+			{client: failing, subscribeTimeout: time.Second},
+		},
+		size: 2,
+	}
+	for _, c := range p.clients {
+		c.connected.Store(true)
+	}
+
+	err := p.UnsubscribeAck(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "client 1") {
+		t.Errorf("UnsubscribeAck() error = %v; want failure on client 1", err)
+	}
+}
+
 func TestPoolClose_MixedState(t *testing.T) {
 	mock := &mockPahoClient{connected: false}
 	p := &Pool{
@@ -180,6 +213,27 @@ func TestPoolPublishFrom_Success(t *testing.T) {
 	}
 }
 
+func TestPoolPublishTo_SkipsDisconnected(t *testing.T) {
+	var gotTopic string
+	up := &Client{
+		client: &mockPahoClient{connected: true, publishFn: func(topic string, _ byte, _ bool, _ any) paho.Token {
+			gotTopic = topic
+			return &mockPahoToken{}
+		}},
+		log: log.New(),
+	}
+	up.connected.Store(true)
+	down := &Client{client: &mockPahoClient{}, log: log.New()}
+
+	p := &Pool{clients: []*Client{down, up}, size: 2}
+	if err := p.PublishTo(t.Context(), []byte(`{}`), "alerts/disk", 0, false); err != nil {
+		t.Fatalf("PublishTo() error = %v", err)
+	}
+	if gotTopic != "alerts/disk" {
+		t.Errorf("topic = %q; want alerts/disk", gotTopic)
+	}
+}
+
 func TestPoolPublishFrom_WrapsAround(t *testing.T) {
 	var calledOn []int
 	makeClient := func(idx int) *Client {
@@ -245,6 +299,234 @@ func TestPoolPublishFrom_SkipsDisconnected(t *testing.T) {
 	}
 }
 
+// --- Pool quarantine tests ---
+
+func TestPoolPublish_QuarantinesFailingConnection(t *testing.T) {
+	reconnecting, reconnect := make(chan struct{}, 1), make(chan struct{})
+	failing := &mockPahoClient{
+		connected: true,
+		publishFn: func(string, byte, bool, any) paho.Token {
+			return &mockPahoToken{err: errors.New("not authorized")}
+		},
+		connectFn: func() paho.Token {
+			reconnecting <- struct{}{}
+			<-reconnect
+			return &mockPahoToken{}
+		},
+	}
+	var healthyPublishes int
+	healthy := &mockPahoClient{
+		connected: true,
+		publishFn: func(string, byte, bool, any) paho.Token {
+			healthyPublishes++
+			return &mockPahoToken{}
+		},
+	}
+	c1 := &Client{client: failing, publishTopic: "t", qos: 1, writeTimeout: time.Second, log: log.New()}
+	c1.connected.Store(true)
+	c2 := &Client{client: healthy, publishTopic: "t", qos: 1, writeTimeout: time.Second, log: log.New()}
+	c2.connected.Store(true)
+	p := &Pool{clients: []*Client{c1, c2}, size: 2, log: log.New(), stop: make(chan struct{}), quarantineAfter: 2}
+	before := metrics.MQTTQuarantines.Value()
+
+	for range 2 {
+		if err := p.PublishFrom(t.Context(), []byte(`{}`), 0); err == nil {
+			t.Fatal("PublishFrom() error = nil; want the failing connection's error")
+		}
+	}
+	if err := p.PublishFrom(t.Context(), []byte(`{}`), 0); err != nil {
+		t.Fatalf("PublishFrom() error = %v; want the healthy connection used", err)
+	}
+	stats := c1.Stats()
+	if !stats.Quarantined || stats.Failed != 2 || stats.SuccessRate >= 1 {
+		t.Errorf("failing connection stats = %+v; want quarantined after 2 failures", stats)
+	}
+	if healthyPublishes != 1 || c2.Stats().Published != 1 {
+		t.Errorf("healthy publishes = %d; want 1", healthyPublishes)
+	}
+	if got := metrics.MQTTQuarantines.Value() - before; got != 1 {
+		t.Errorf("MQTTQuarantines delta = %d; want 1", got)
+	}
+
+	// Closing the pool first would end the reconnect before it connects.
+	<-reconnecting
+	close(reconnect)
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !failing.disconnectCalled || c1.Stats().Quarantined {
+		t.Error("expected the quarantined connection to be reconnected and put back")
+	}
+}
+
+func TestPoolQuarantine_RecordsBreakerTransitions(t *testing.T) {
+	failing := &mockPahoClient{
+		connected: true,
+		publishFn: func(string, byte, bool, any) paho.Token {
+			return &mockPahoToken{err: errors.New("not authorized")}
+		},
+	}
+	c := &Client{client: failing, publishTopic: "t", qos: 1, writeTimeout: time.Second, log: log.New()}
+	c.connected.Store(true)
+	p := &Pool{clients: []*Client{c}, size: 1, log: log.New(), stop: make(chan struct{}), quarantineAfter: 1}
+	cfg := &config.Config{}
+	cfg.Pipeline.LifecycleLog = filepath.Join(t.TempDir(), "lifecycle.jsonl")
+	transitions, err := lifecycle.New(cfg, log.New())
+	if err != nil {
+		t.Fatalf("lifecycle.New() error = %v", err)
+	}
+	p.SetLifecycle(transitions)
+
+	if err := p.Publish(t.Context(), []byte(`{}`)); err == nil {
+		t.Fatal("Publish() error = nil; want the publish error")
+	}
+	for c.Stats().Quarantined {
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := transitions.Close(t.Context()); err != nil {
+		t.Fatalf("transitions.Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.Pipeline.LifecycleLog)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var events []lifecycle.Event
+	for line := range strings.Lines(string(data)) {
+		var e lifecycle.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 || events[0].Kind != lifecycle.BreakerOpen || events[0].Detail["reason"] != "failures" ||
+		events[1].Kind != lifecycle.BreakerClosed {
+		t.Errorf("events = %+v; want breaker-open for the failures, then breaker-closed", events)
+	}
+}
+
+func TestPoolClose_EndsReconnect(t *testing.T) {
+	failing := &mockPahoClient{
+		connected: true,
+		publishFn: func(string, byte, bool, any) paho.Token {
+			return &mockPahoToken{err: errors.New("broker unavailable")}
+		},
+		connectFn: func() paho.Token {
+			return &mockPahoToken{err: errors.New("connection refused")}
+		},
+	}
+	c := &Client{
+		client: failing, publishTopic: "t", qos: 1, writeTimeout: time.Second,
+		connectRetryDelay: time.Hour, log: log.New(),
+	}
+	c.connected.Store(true)
+	p := &Pool{clients: []*Client{c}, size: 1, log: log.New(), stop: make(chan struct{}), quarantineAfter: 1}
+
+	if err := p.Publish(t.Context(), []byte(`{}`)); err == nil {
+		t.Fatal("Publish() error = nil; want the publish error")
+	}
+	if err := p.Publish(t.Context(), []byte(`{}`)); !errors.Is(err, errNotConnected) {
+		t.Errorf("Publish() error = %v; want errNotConnected while quarantined", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- p.Close() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not end the reconnect")
+	}
+}
+
+func TestPoolReconnectStalled(t *testing.T) {
+	hang := make(chan struct{})
+	stuck := &mockPahoClient{
+		connected: true,
+		publishFn: func(string, byte, bool, any) paho.Token {
+			<-hang // paho never returns a token
+			return &mockPahoToken{}
+		},
+	}
+	c := &Client{client: stuck, publishTopic: "t", qos: 1, writeTimeout: time.Second, log: log.New()}
+	c.connected.Store(true)
+	p := &Pool{clients: []*Client{c}, size: 1, log: log.New(), stop: make(chan struct{})}
+	before := metrics.MQTTStallReconnects.Value()
+
+	published := make(chan error, 1)
+	go func() { published <- p.Publish(t.Context(), []byte(`{}`)) }()
+	for c.health.inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if n := p.ReconnectStalled(t.Context(), time.Hour); n != 0 {
+		t.Errorf("ReconnectStalled(1h) = %d; want 0 for a publish under an hour old", n)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if n := p.ReconnectStalled(t.Context(), time.Millisecond); n != 1 {
+		t.Errorf("ReconnectStalled(1ms) = %d; want the stuck connection", n)
+	}
+	if got := metrics.MQTTStallReconnects.Value() - before; got != 1 {
+		t.Errorf("MQTTStallReconnects delta = %d; want 1", got)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !stuck.disconnectCalled || c.health.stalled(time.Now(), time.Hour) {
+		t.Error("expected the stalled connection to be reconnected with fresh progress")
+	}
+	close(hang)
+	<-published
+}
+
+func TestPoolPublish_Pipelined(t *testing.T) {
+	answer := make(chan struct{})
+	var sent []byte
+	mock := &mockPahoClient{
+		connected: true,
+		publishFn: func(_ string, _ byte, _ bool, payload any) paho.Token {
+			sent, _ = payload.([]byte)
+			return &slowToken{done: answer, err: errors.New("not authorized")}
+		},
+	}
+	c := &Client{
+		client: mock, publishTopic: "t", qos: 1, writeTimeout: time.Second, log: log.New(),
+		inflight: make(chan struct{}, 1),
+	}
+	c.connected.Store(true)
+	p := &Pool{clients: []*Client{c}, size: 1, log: log.New(), stop: make(chan struct{})}
+	before := metrics.MQTTPipelinedErrors.Value()
+
+	payload := []byte(`{"a":1}`)
+	if err := p.Publish(t.Context(), payload); err != nil {
+		t.Fatalf("Publish() error = %v; want nil once handed to the connection", err)
+	}
+	payload[0] = 'x'
+	if string(sent) != `{"a":1}` {
+		t.Errorf("sent payload = %q; want a copy unaffected by the caller reusing its buffer", sent)
+	}
+	if got := c.Stats().InFlight; got != 1 {
+		t.Errorf("InFlight = %d; want 1 awaiting the broker", got)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Publish(ctx, payload); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish() error = %v; want the context's while the only slot is taken", err)
+	}
+
+	close(answer)
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if stats := c.Stats(); stats.Failed != 1 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v; want the failure recorded once the broker answered", stats)
+	}
+	if got := metrics.MQTTPipelinedErrors.Value() - before; got != 1 {
+		t.Errorf("MQTTPipelinedErrors delta = %d; want 1", got)
+	}
+}
+
 // --- Pool.SubscribeAck tests ---
 
 func TestPoolSubscribeAck_AllClients(t *testing.T) {
@@ -399,3 +681,20 @@ func TestCloseClients_PartialCount(t *testing.T) {
 		t.Error("expected client 0 disconnected")
 	}
 }
+
+func TestPoolClientIDBase(t *testing.T) {
+	cfg := testMQTTConfig()
+	pid := "-" + strconv.Itoa(os.Getpid())
+
+	if got := poolClientIDBase(cfg); !strings.HasSuffix(got, pid) {
+		t.Errorf("poolClientIDBase() = %q; want PID suffix %q", got, pid)
+	}
+	cfg.PersistentSession = true
+	got := poolClientIDBase(cfg)
+	if strings.HasSuffix(got, pid) {
+		t.Errorf("poolClientIDBase(persistent) = %q; must be stable across restarts", got)
+	}
+	if again := poolClientIDBase(cfg); again != got {
+		t.Errorf("poolClientIDBase(persistent) not deterministic: %q vs %q", got, again)
+	}
+}
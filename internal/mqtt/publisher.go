@@ -6,14 +6,26 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/message"
 )
 
-// Publisher is implemented by both Client and Pool.
+// Publisher is implemented by both Client and Pool, and by archive.Sink in
+// place of them. Publish sends to MQTT_PUBLISH_TOPIC.
+// The SubscribeAck handler also receives the flow-control messages on the
+// ACK topic, as AckMessages with only Control set.
 type Publisher interface {
 	Publish(ctx context.Context, payload message.Payload) error
 	SubscribeAck(ctx context.Context, handler func(message.AckMessage)) error
 	Close() error
 }
 
+// TopicPublisher publishes to any topic. Client and Pool implement it; the
+// archive sink, which has no topics, does not, so it is kept apart from
+// Publisher. The routing, fanout, sampling and control features need it.
+type TopicPublisher interface {
+	PublishTo(ctx context.Context, payload message.Payload, topic string, qos byte, retain bool) error
+}
+
 var (
-	_ Publisher = (*Client)(nil)
-	_ Publisher = (*Pool)(nil)
+	_ Publisher      = (*Client)(nil)
+	_ Publisher      = (*Pool)(nil)
+	_ TopicPublisher = (*Client)(nil)
+	_ TopicPublisher = (*Pool)(nil)
 )
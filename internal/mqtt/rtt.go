@@ -0,0 +1,107 @@
+package mqtt
+
+import (
+	"errors"
+	"expvar"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// rttPercentiles are the keys of consumer.broker_rtt_ms and the percentile
+// each holds; max is the 100th.
+var rttPercentiles = [...]struct {
+	key     string
+	percent int
+}{{"p50", 50}, {"p90", 90}, {"p99", 99}, {"max", 100}}
+
+// rttMaxSamples bounds the samples kept per window; past it, each new
+// sample replaces the oldest.
+const rttMaxSamples = 4096
+
+// rttSampler applies MQTT_RTT_SAMPLE_EVERY: it times the broker's
+// acknowledgement of every Nth QoS 1/2 publish, across the pool, and sets
+// consumer.broker_rtt_ms from the samples of each MQTT_RTT_WINDOW as it
+// closes. A publish that timed out counts with the time it waited, so a
+// broker that stops answering shows in the percentiles rather than
+// emptying them; the write and connection failures are left out, as they
+// say nothing of the broker. A nil rttSampler times nothing.
+type rttSampler struct {
+	gauges    [len(rttPercentiles)]*expvar.Int
+	windowEnd time.Time
+	samples   []time.Duration
+	count     atomic.Uint64
+	every     uint64
+	window    time.Duration
+	taken     int // samples taken in the window, kept or not
+	mu        sync.Mutex
+}
+
+func newRTTSampler(cfg *config.MQTTConfig, now time.Time) *rttSampler {
+	if cfg.RTTSampleEvery <= 0 {
+		return nil
+	}
+	s := &rttSampler{
+		windowEnd: now.Add(cfg.RTTWindow),
+		every:     uint64(cfg.RTTSampleEvery),
+		window:    cfg.RTTWindow,
+	}
+	for i, p := range rttPercentiles {
+		s.gauges[i] = new(expvar.Int)
+		metrics.BrokerRTTMs.Set(p.key, s.gauges[i])
+	}
+	return s
+}
+
+// sample reports whether a publish at qos is to be timed: every Nth QoS 1/2
+// publish is, and none on a nil rttSampler.
+func (s *rttSampler) sample(qos byte) bool {
+	return s != nil && qos > 0 && s.count.Add(1)%s.every == 0
+}
+
+// observe records a sampled publish that started at start and completed,
+// with err, at end.
+func (s *rttSampler) observe(start, end time.Time, err error) {
+	if err != nil && !errors.Is(err, errPublishTimeout) {
+		return
+	}
+	metrics.BrokerRTTSamples.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !end.Before(s.windowEnd) {
+		s.closeWindow(end)
+	}
+	rtt := end.Sub(start)
+	if len(s.samples) < rttMaxSamples {
+		s.samples = append(s.samples, rtt)
+	} else {
+		s.samples[s.taken%rttMaxSamples] = rtt
+	}
+	s.taken++
+}
+
+// closeWindow sets consumer.broker_rtt_ms from the samples of the window
+// that ended, and starts the one holding now. The caller holds s.mu.
+func (s *rttSampler) closeWindow(now time.Time) {
+	slices.Sort(s.samples)
+	for i, p := range rttPercentiles {
+		s.gauges[i].Set(rttPercentile(s.samples, p.percent).Milliseconds())
+	}
+	s.samples = s.samples[:0]
+	s.taken = 0
+	s.windowEnd = s.windowEnd.Add((now.Sub(s.windowEnd)/s.window + 1) * s.window)
+}
+
+// rttPercentile returns the nearest-rank percent-th percentile of sorted,
+// or 0 when it is empty.
+func rttPercentile(sorted []time.Duration, percent int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (len(sorted)*percent + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
@@ -0,0 +1,123 @@
+package mqtt
+
+import (
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func brokerRTT(t *testing.T) map[string]int64 {
+	t.Helper()
+	got := make(map[string]int64)
+	metrics.BrokerRTTMs.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			got[kv.Key] = v.Value()
+		}
+	})
+	return got
+}
+
+func TestRTTSampler_SamplesEveryNthAcknowledgedPublish(t *testing.T) {
+	s := newRTTSampler(&config.MQTTConfig{RTTSampleEvery: 3, RTTWindow: time.Minute}, time.Now())
+	var sampled []int
+	for i := 1; i <= 7; i++ {
+		if s.sample(1) {
+			sampled = append(sampled, i)
+		}
+	}
+	if fmt.Sprint(sampled) != "[3 6]" {
+		t.Errorf("sampled publishes %v; want [3 6]", sampled)
+	}
+	if s.sample(0) {
+		t.Error("sample(0) = true; want QoS 0 publishes, which the broker does not acknowledge, skipped")
+	}
+	var off *rttSampler
+	if off.sample(1) {
+		t.Error("nil sample() = true; want false")
+	}
+	if newRTTSampler(&config.MQTTConfig{}, time.Now()) != nil {
+		t.Error("newRTTSampler() != nil; want nil with MQTT_RTT_SAMPLE_EVERY unset")
+	}
+}
+
+func TestRTTSampler_SetsPercentilesAsTheWindowCloses(t *testing.T) {
+	start := time.Now()
+	s := newRTTSampler(&config.MQTTConfig{RTTSampleEvery: 1, RTTWindow: time.Minute}, start)
+	before := metrics.BrokerRTTSamples.Value()
+	for ms := 1; ms <= 100; ms++ {
+		s.observe(start, start.Add(time.Duration(ms)*time.Millisecond), nil)
+	}
+	s.observe(start, start.Add(time.Second), errNotConnected)
+	s.observe(start, start.Add(2*time.Second), errPublishTimeout)
+	if got := brokerRTT(t)["max"]; got != 0 {
+		t.Errorf("max = %d before the window closed; want 0", got)
+	}
+
+	s.observe(start.Add(3*time.Minute), start.Add(3*time.Minute+time.Millisecond), nil)
+	want := map[string]int64{"p50": 51, "p90": 91, "p99": 100, "max": 2000}
+	if got := brokerRTT(t); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("consumer.broker_rtt_ms = %v; want %v, the timeout in and the connection failure out", got, want)
+	}
+	if got := metrics.BrokerRTTSamples.Value() - before; got != 102 {
+		t.Errorf("BrokerRTTSamples delta = %d; want 102", got)
+	}
+	if !s.windowEnd.Equal(start.Add(4*time.Minute)) || len(s.samples) != 1 {
+		t.Errorf("next window ends %v with %d samples; want the window holding the last sample",
+			s.windowEnd.Sub(start), len(s.samples))
+	}
+}
+
+func TestRTTSampler_KeepsTheLatestSamples(t *testing.T) {
+	start := time.Now()
+	s := newRTTSampler(&config.MQTTConfig{RTTSampleEvery: 1, RTTWindow: time.Minute}, start)
+	for range rttMaxSamples {
+		s.observe(start, start.Add(time.Millisecond), nil)
+	}
+	s.observe(start, start.Add(time.Second), nil)
+	if len(s.samples) != rttMaxSamples || s.samples[0] != time.Second {
+		t.Errorf("%d samples, oldest slot %v; want %d with the oldest replaced",
+			len(s.samples), s.samples[0], rttMaxSamples)
+	}
+}
+
+func TestPoolPublish_SamplesBrokerRTT(t *testing.T) {
+	c := &Client{client: &mockPahoClient{connected: true}, publishTopic: "t", qos: 1, writeTimeout: time.Second}
+	c.connected.Store(true)
+	p := &Pool{
+		clients: []*Client{c}, size: 1, log: log.New(), stop: make(chan struct{}),
+		rtt: newRTTSampler(&config.MQTTConfig{RTTSampleEvery: 2, RTTWindow: time.Minute}, time.Now()),
+	}
+	before := metrics.BrokerRTTSamples.Value()
+
+	for range 4 {
+		if err := p.Publish(t.Context(), []byte(`{}`)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if err := p.PublishTo(t.Context(), []byte(`{}`), "t", 0, false); err != nil {
+		t.Fatalf("PublishTo() error = %v", err)
+	}
+	if got := metrics.BrokerRTTSamples.Value() - before; got != 2 {
+		t.Errorf("BrokerRTTSamples delta = %d; want 2 of the 4 QoS 1 publishes", got)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestRTTPercentile(t *testing.T) {
+	if got := rttPercentile(nil, 99); got != 0 {
+		t.Errorf("rttPercentile(nil) = %v; want 0", got)
+	}
+	sorted := []time.Duration{1, 2, 3}
+	for percent, want := range map[int]time.Duration{1: 1, 50: 2, 67: 3, 100: 3} {
+		if got := rttPercentile(sorted, percent); got != want {
+			t.Errorf("rttPercentile(%d) = %v; want %v", percent, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// Status values of the MQTT_STATUS_TOPIC messages.
+const (
+	statusOnline  = "online"
+	statusOffline = "offline"
+)
+
+// statusQoS delivers status messages at least once; retained, a repeat is
+// harmless.
+const statusQoS = 1
+
+// statusMessage is the retained MQTT_STATUS_TOPIC payload of a pool
+// connection. Timestamp is UTC RFC3339: when the message was sent, or for
+// the offline last will, when the connection it stands for was set up.
+type statusMessage struct {
+	Status    string `json:"status"`
+	Instance  string `json:"instance"`
+	ClientID  string `json:"client_id"`
+	Timestamp string `json:"timestamp"`
+}
+
+// connStatus reports a connection's availability on topic; nil when
+// MQTT_STATUS_TOPIC is unset.
+type connStatus struct {
+	topic    string
+	instance string // the pool's client ID, shared by its connections
+	clientID string
+}
+
+func newConnStatus(cfg *config.MQTTConfig, instance string) *connStatus {
+	if cfg.StatusTopic == "" {
+		return nil
+	}
+	return &connStatus{
+		topic:    cfg.StatusTopic + "/" + cfg.ClientID,
+		instance: instance,
+		clientID: cfg.ClientID,
+	}
+}
+
+func (s *connStatus) payload(status string, now time.Time) []byte {
+	payload, _ := json.Marshal(statusMessage{ // strings only: cannot fail
+		Status:    status,
+		Instance:  s.instance,
+		ClientID:  s.clientID,
+		Timestamp: now.UTC().Format(time.RFC3339),
+	})
+	return payload
+}
+
+// will registers the offline status as the last will of opts, which the
+// broker publishes when it loses the connection without a DISCONNECT.
+// The broker sends it as is, so it is stamped now.
+func (s *connStatus) will(opts *mqtt.ClientOptions, now time.Time) {
+	if s == nil {
+		return
+	}
+	opts.SetBinaryWill(s.topic, s.payload(statusOffline, now), statusQoS, true)
+}
+
+// publish sends status, retained, and waits for the broker up to timeout.
+func (s *connStatus) publish(ctx context.Context, mc mqtt.Client, status string, timeout time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	token := mc.Publish(s.topic, statusQoS, true, s.payload(status, time.Now()))
+	if !waitToken(ctx, token, timeout) {
+		return errors.New("mqtt: status publish timed out")
+	}
+	return token.Error()
+}
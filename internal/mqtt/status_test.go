@@ -0,0 +1,99 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+// published is a status message the mock client was asked to publish.
+type published struct {
+	topic    string
+	msg      statusMessage
+	retained bool
+}
+
+func recordStatus(t *testing.T, into *[]published) func(string, byte, bool, any) paho.Token {
+	t.Helper()
+	return func(topic string, _ byte, retained bool, payload any) paho.Token {
+		var msg statusMessage
+		if err := json.Unmarshal(payload.([]byte), &msg); err != nil {
+			t.Errorf("status payload %q: %v", payload, err)
+		}
+		*into = append(*into, published{topic: topic, msg: msg, retained: retained})
+		return &mockPahoToken{}
+	}
+}
+
+func TestNewClient_StatusWill(t *testing.T) {
+	cfg := testMQTTConfig()
+	cfg.StatusTopic = "syslog/status"
+	client, err := newClient(t.Context(), cfg, "test", log.New())
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	opts := client.client.OptionsReader()
+	if !opts.WillEnabled() || opts.WillTopic() != "syslog/status/test-client" || !opts.WillRetained() {
+		t.Fatalf("will = %t %q retained %t; want a retained will on syslog/status/test-client",
+			opts.WillEnabled(), opts.WillTopic(), opts.WillRetained())
+	}
+	var will statusMessage
+	if err := json.Unmarshal(opts.WillPayload(), &will); err != nil {
+		t.Fatalf("will payload: %v", err)
+	}
+	if will.Status != statusOffline || will.Instance != "test" || will.ClientID != "test-client" {
+		t.Errorf("will = %+v; want offline for test / test-client", will)
+	}
+	if _, err := time.Parse(time.RFC3339, will.Timestamp); err != nil {
+		t.Errorf("will timestamp %q: %v", will.Timestamp, err)
+	}
+
+	client, err = NewClient(t.Context(), testMQTTConfig(), log.New())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if opts := client.client.OptionsReader(); opts.WillEnabled() {
+		t.Error("WillEnabled() = true; want no will without a status topic")
+	}
+}
+
+func TestClientClose_PublishesOfflineStatus(t *testing.T) {
+	cfg := testMQTTConfig()
+	cfg.StatusTopic = "syslog/status"
+	var sent []published
+	mc := &mockPahoClient{connected: true, publishFn: recordStatus(t, &sent)}
+	c := &Client{client: mc, status: newConnStatus(cfg, "test"), writeTimeout: time.Second, log: log.New()}
+
+	if err := c.status.publish(t.Context(), mc, statusOnline, time.Second); err != nil {
+		t.Fatalf("publish() error = %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(sent) != 2 || sent[0].msg.Status != statusOnline || sent[1].msg.Status != statusOffline {
+		t.Fatalf("sent = %+v; want online, then offline", sent)
+	}
+	for _, p := range sent {
+		if p.topic != "syslog/status/test-client" || !p.retained {
+			t.Errorf("sent %+v; want retained on syslog/status/test-client", p)
+		}
+	}
+	if !mc.disconnectCalled {
+		t.Error("Disconnect not called after the offline status")
+	}
+}
+
+func TestConnStatus_OffWithoutTopic(t *testing.T) {
+	s := newConnStatus(testMQTTConfig(), "test")
+	if s != nil {
+		t.Fatalf("newConnStatus() = %+v; want nil", s)
+	}
+	if err := s.publish(t.Context(), &mockPahoClient{}, statusOnline, time.Second); err != nil {
+		t.Errorf("publish() error = %v; want nil when off", err)
+	}
+}
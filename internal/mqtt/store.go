@@ -0,0 +1,123 @@
+package mqtt
+
+import (
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// boundedStore wraps paho's FileStore with a cap on persisted packets so a
+// long broker outage cannot fill the disk. Packets past the cap spill into a
+// MemoryStore: paho still resends them on reconnect, but they are not
+// replayed after a process restart.
+type boundedStore struct {
+	file        *mqtt.FileStore
+	mem         *mqtt.MemoryStore
+	persisted   map[string]struct{}
+	spilled     map[string]struct{}
+	mu          sync.Mutex
+	maxMessages int
+}
+
+var _ mqtt.Store = (*boundedStore)(nil)
+
+func newBoundedStore(dir string, maxMessages int) *boundedStore {
+	return &boundedStore{
+		file:        mqtt.NewFileStore(dir),
+		mem:         mqtt.NewMemoryStore(),
+		persisted:   make(map[string]struct{}),
+		spilled:     make(map[string]struct{}),
+		maxMessages: maxMessages,
+	}
+}
+
+// Open indexes the packets left on disk by a previous run so the cap
+// accounts for them.
+func (s *boundedStore) Open() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Open()
+	s.mem.Open()
+	clear(s.persisted)
+	clear(s.spilled)
+	for _, key := range s.file.All() {
+		s.persisted[key] = struct{}{}
+	}
+	metrics.MQTTStorePersisted.Add(int64(len(s.persisted)))
+}
+
+func (s *boundedStore) Put(key string, message packets.ControlPacket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.persisted[key]; ok || len(s.persisted) < s.maxMessages {
+		if !ok {
+			s.persisted[key] = struct{}{}
+			metrics.MQTTStorePersisted.Add(1)
+		}
+		s.file.Put(key, message)
+		return
+	}
+	if _, ok := s.spilled[key]; !ok {
+		s.spilled[key] = struct{}{}
+		metrics.MQTTStoreOverflow.Add(1)
+	}
+	s.mem.Put(key, message)
+}
+
+func (s *boundedStore) Get(key string) packets.ControlPacket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spilled[key]; ok {
+		return s.mem.Get(key)
+	}
+	return s.file.Get(key)
+}
+
+func (s *boundedStore) All() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.persisted)+len(s.spilled))
+	for key := range s.persisted {
+		keys = append(keys, key)
+	}
+	for key := range s.spilled {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *boundedStore) Del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spilled[key]; ok {
+		delete(s.spilled, key)
+		s.mem.Del(key)
+		return
+	}
+	if _, ok := s.persisted[key]; ok {
+		delete(s.persisted, key)
+		metrics.MQTTStorePersisted.Add(-1)
+	}
+	s.file.Del(key)
+}
+
+func (s *boundedStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.MQTTStorePersisted.Add(-int64(len(s.persisted)))
+	s.file.Close()
+	s.mem.Close()
+}
+
+func (s *boundedStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.MQTTStorePersisted.Add(-int64(len(s.persisted)))
+	clear(s.persisted)
+	clear(s.spilled)
+	s.file.Reset()
+	s.mem.Reset()
+}
@@ -0,0 +1,162 @@
+package mqtt
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func newPublishPacket(t *testing.T, id uint16) *packets.PublishPacket {
+	t.Helper()
+	p, ok := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	if !ok {
+		t.Fatal("NewControlPacket(Publish) returned unexpected type")
+	}
+	p.TopicName = tcTopicPub
+	p.Qos = 1
+	p.MessageID = id
+	p.Payload = []byte("payload-" + strconv.Itoa(int(id)))
+	return p
+}
+
+func outboundKey(id uint16) string {
+	return fmt.Sprintf("o.%d", id)
+}
+
+func sortedKeys(s paho.Store) []string {
+	keys := s.All()
+	slices.Sort(keys)
+	return keys
+}
+
+func TestBoundedStore_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	s := newBoundedStore(dir, 100)
+	s.Open()
+	for id := uint16(1); id <= 3; id++ {
+		s.Put(outboundKey(id), newPublishPacket(t, id))
+	}
+	s.Close()
+
+	reopened := newBoundedStore(dir, 100)
+	reopened.Open()
+	defer reopened.Close()
+
+	want := []string{"o.1", "o.2", "o.3"}
+	if got := sortedKeys(reopened); !slices.Equal(got, want) {
+		t.Fatalf("All() after restart = %v; want %v", got, want)
+	}
+	pkt, ok := reopened.Get("o.2").(*packets.PublishPacket)
+	if !ok {
+		t.Fatal("Get(o.2) did not return a publish packet")
+	}
+	if string(pkt.Payload) != "payload-2" || pkt.MessageID != 2 {
+		t.Errorf("Get(o.2) = id %d payload %q; want id 2 payload-2", pkt.MessageID, pkt.Payload)
+	}
+}
+
+// TestBoundedStore_RestartCycles simulates several restarts in which some
+// inflight messages are acknowledged and new ones are published, and checks
+// that every unacknowledged message is replayed exactly once per restart.
+func TestBoundedStore_RestartCycles(t *testing.T) {
+	dir := t.TempDir()
+	expected := map[string]struct{}{}
+	next := uint16(1)
+
+	for cycle := range 5 {
+		s := newBoundedStore(dir, 1000)
+		s.Open()
+
+		got := s.All()
+		seen := make(map[string]struct{}, len(got))
+		for _, key := range got {
+			if _, dup := seen[key]; dup {
+				t.Fatalf("cycle %d: duplicate key %s", cycle, key)
+			}
+			seen[key] = struct{}{}
+			if _, ok := expected[key]; !ok {
+				t.Fatalf("cycle %d: unexpected key %s", cycle, key)
+			}
+			if s.Get(key) == nil {
+				t.Fatalf("cycle %d: key %s listed but not readable", cycle, key)
+			}
+		}
+		if len(seen) != len(expected) {
+			t.Fatalf("cycle %d: replayed %d messages; want %d", cycle, len(seen), len(expected))
+		}
+
+		// Acknowledge every other survivor, then publish a fresh batch.
+		i := 0
+		for key := range seen {
+			if i%2 == 0 {
+				s.Del(key)
+				delete(expected, key)
+			}
+			i++
+		}
+		for range 4 {
+			key := outboundKey(next)
+			s.Put(key, newPublishPacket(t, next))
+			expected[key] = struct{}{}
+			next++
+		}
+		s.Close()
+	}
+}
+
+func TestBoundedStore_OverflowKeptInMemory(t *testing.T) {
+	dir := t.TempDir()
+	before := metrics.MQTTStoreOverflow.Value()
+
+	s := newBoundedStore(dir, 2)
+	s.Open()
+	for id := uint16(1); id <= 3; id++ {
+		s.Put(outboundKey(id), newPublishPacket(t, id))
+	}
+
+	if got := len(s.All()); got != 3 {
+		t.Errorf("len(All()) = %d; want 3 (2 persisted + 1 spilled)", got)
+	}
+	if s.Get("o.3") == nil {
+		t.Error("spilled packet o.3 not readable")
+	}
+	if got := metrics.MQTTStoreOverflow.Value() - before; got != 1 {
+		t.Errorf("MQTTStoreOverflow delta = %d; want 1", got)
+	}
+
+	// Replacing a persisted key must not count against the cap.
+	s.Put("o.1", newPublishPacket(t, 1))
+	if got := metrics.MQTTStoreOverflow.Value() - before; got != 1 {
+		t.Errorf("MQTTStoreOverflow delta after overwrite = %d; want 1", got)
+	}
+
+	// Freeing a slot lets the next packet reach disk again.
+	s.Del("o.2")
+	s.Put("o.4", newPublishPacket(t, 4))
+	s.Close()
+
+	reopened := newBoundedStore(dir, 2)
+	reopened.Open()
+	defer reopened.Close()
+	if got, want := sortedKeys(reopened), []string{"o.1", "o.4"}; !slices.Equal(got, want) {
+		t.Errorf("persisted keys after restart = %v; want %v", got, want)
+	}
+}
+
+func TestBoundedStore_Reset(t *testing.T) {
+	s := newBoundedStore(t.TempDir(), 1)
+	s.Open()
+	defer s.Close()
+	s.Put("o.1", newPublishPacket(t, 1))
+	s.Put("o.2", newPublishPacket(t, 2))
+	s.Reset()
+	if got := s.All(); len(got) != 0 {
+		t.Errorf("All() after Reset = %v; want empty", got)
+	}
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// Per-entry statuses returned by ackDeleteScript.
+const (
+	ackDeleted      = 0 // acknowledged and deleted
+	ackAlreadyAcked = 1 // deleted, but no longer pending in the group
+	ackMissing      = 2 // no longer in the stream; its pending entry, if any, is acknowledged
+)
+
+// ackDeleteScript runs XACK and XDEL for each entry as one atomic step, so
+// a failure between the two can no longer leave an entry acknowledged but
+// still in the stream. KEYS[1] is the stream, ARGV[1] the consumer group
+// and ARGV[2..] the entry IDs. It returns one status per ID, in order.
+var ackDeleteScript = redis.NewScript(`
+local status = {}
+for i = 2, #ARGV do
+	local acked = redis.call('XACK', KEYS[1], ARGV[1], ARGV[i])
+	local deleted = redis.call('XDEL', KEYS[1], ARGV[i])
+	if deleted == 0 then
+		status[i - 1] = 2
+	elseif acked == 0 then
+		status[i - 1] = 1
+	else
+		status[i - 1] = 0
+	end
+end
+return status
+`)
+
+// AckAndDeleteBatch acknowledges and deletes ids of stream atomically with
+// ackDeleteScript, sent as EVALSHA and falling back to EVAL once per
+// connection after a script cache flush. Entries that were already
+// acknowledged or deleted are counted, not reported as errors.
+func (c *Client) AckAndDeleteBatch(ctx context.Context, ids []string, stream string) error {
+	if stream == "" {
+		return errors.New("cannot ACK messages: stream name is empty")
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, c.groupName)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	statuses, err := ackDeleteScript.Run(ctx, c.rdb, []string{stream}, args...).Int64Slice()
+	if err != nil {
+		if isNoGroupError(err) {
+			c.log.Warnf(ctx, "Consumer group missing for stream '%s' during batch ACK, recreating", stream)
+			if gerr := c.ensureGroups(ctx, []string{stream}); gerr != nil {
+				c.log.Warnf(ctx, "Failed to recreate group for stream '%s': %v", stream, gerr)
+			}
+			return nil
+		}
+		return fmt.Errorf("ack+del script failed for %d messages in stream %s: %w", len(ids), stream, err)
+	}
+
+	var alreadyAcked, missing int64
+	for _, status := range statuses {
+		switch status {
+		case ackAlreadyAcked:
+			alreadyAcked++
+		case ackMissing:
+			missing++
+		}
+	}
+	metrics.AcksAlreadyAcked.Add(alreadyAcked)
+	metrics.AcksMissing.Add(missing)
+	return nil
+}
@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// claimCursorTTL is how long the claim cursors of a consumer outlive its
+// last claim cycle, so those of a consumer name that is not used again
+// expire.
+const claimCursorTTL = 24 * time.Hour
+
+// claimCursors is where each stream's next XPENDING of a claim cycle
+// starts. Starting every cycle at "-" would have every instance contend for
+// the same earliest entries; instead each cycle carries on after the last
+// entry the previous one looked at, and starts over at "-" once a cycle
+// reaches the end of the pending list. Instances drift apart as they claim,
+// so their claim work spreads over the list.
+//
+// The cursors are kept in a Redis hash per consumer, one field per stream
+// (see Client.claimCursorKey), so a restarted instance carries on where it
+// stopped, provided it keeps its REDIS_CONSUMER name; a new name starts at
+// "-". The hash is read at the first claim cycle, and again at the next
+// ones until a read succeeds. The zero value is ready to use.
+type claimCursors struct {
+	next   map[string]string // next pending ID to look at per stream; absent means "-"
+	mu     sync.Mutex        // claimStream runs for several streams at once
+	loaded bool
+}
+
+// claimCursorKey is the hash of this consumer's claim cursors, under
+// REDIS_KEY_PREFIX like the group name.
+func (c *Client) claimCursorKey() string {
+	group := strings.TrimPrefix(c.groupName, c.keyPrefix)
+	return c.keyPrefix + "syslog-consumer:" + group + ":claim-cursor:" + c.consumer
+}
+
+// loadClaimCursors reads the consumer's claim cursors from Redis unless
+// they were read already. On failure the cursors in memory are kept.
+func (c *Client) loadClaimCursors(ctx context.Context) {
+	cc := &c.claimCursors
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.loaded {
+		return
+	}
+	next, err := c.rdb.HGetAll(ctx, c.claimCursorKey()).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.log.Warnf(ctx, "Failed to read the claim cursors, claiming from the oldest pending entries: %v", err)
+		return
+	}
+	cc.next, cc.loaded = next, true
+}
+
+// saveClaimCursor stores stream's cursor, or drops it when next is "-".
+func (c *Client) saveClaimCursor(ctx context.Context, stream, next string) {
+	key := c.claimCursorKey()
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if next == "-" {
+			pipe.HDel(ctx, key, stream)
+		} else {
+			pipe.HSet(ctx, key, stream, next)
+		}
+		pipe.PExpire(ctx, key, claimCursorTTL)
+		return nil
+	})
+	if err != nil {
+		c.log.Warnf(ctx, "Failed to save the claim cursor of stream %s: %v", stream, err)
+	}
+}
+
+// forgetClaimCursors drops the cursors of streams not in live, in memory
+// and in Redis.
+func (c *Client) forgetClaimCursors(ctx context.Context, live map[string]struct{}) {
+	stale := c.claimCursors.keep(live)
+	if len(stale) == 0 {
+		return
+	}
+	if err := c.rdb.HDel(ctx, c.claimCursorKey(), stale...).Err(); err != nil {
+		c.log.Warnf(ctx, "Failed to drop the claim cursors of %d streams: %v", len(stale), err)
+	}
+}
+
+// start returns the XPENDING start of stream's next claim.
+func (cc *claimCursors) start(stream string) string {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if next, ok := cc.next[stream]; ok {
+		return next
+	}
+	return "-"
+}
+
+// advance moves stream's cursor past pending, the entries an XPENDING of
+// up to count entries returned, or back to "-" when it returned fewer. It
+// returns the new cursor and whether it changed.
+func (cc *claimCursors) advance(stream string, pending []redis.XPendingExt, count int64) (string, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if int64(len(pending)) < count {
+		if _, ok := cc.next[stream]; !ok {
+			return "-", false
+		}
+		delete(cc.next, stream)
+		metrics.ClaimCursorWraps.Add(1)
+		return "-", true
+	}
+	if cc.next == nil {
+		cc.next = make(map[string]string)
+	}
+	next := nextStreamID(pending[len(pending)-1].ID)
+	cc.next[stream] = next
+	return next, true
+}
+
+// keep forgets the cursors of streams not in live and returns those streams.
+func (cc *claimCursors) keep(live map[string]struct{}) []string {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	var stale []string
+	for stream := range cc.next {
+		if _, ok := live[stream]; !ok {
+			delete(cc.next, stream)
+			stale = append(stale, stream)
+		}
+	}
+	return stale
+}
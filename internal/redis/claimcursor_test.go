@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// claimIDs runs a claim cycle of c and returns the IDs it claimed.
+func claimIDs(t *testing.T, s *miniredis.Miniredis, c *Client) []string {
+	t.Helper()
+	s.FastForward(2 * time.Second)
+	batch, err := c.ClaimIdle(t.Context())
+	if err != nil {
+		t.Fatalf("ClaimIdle() error = %v", err)
+	}
+	defer batch.Release()
+	ids := make([]string, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func claimCursorClient(t *testing.T, s *miniredis.Miniredis) *Client {
+	t.Helper()
+	c := newTestClient(t, s, testStreamS1)
+	c.claimIdle = 0
+	c.batchSize = 2
+	c.readCount.Store(10)
+	return c
+}
+
+func TestClaimIdle_RotatesStartCursor(t *testing.T) {
+	s := startMiniredis(t)
+	c := claimCursorClient(t, s)
+
+	var ids []string
+	for range 3 {
+		ids = append(ids, mustXAdd(t, s, testStreamS1, "source", "10.0.0.1"))
+	}
+	mustEnsureGroups(t, c, testStreamS1)
+	mustReadBatch(t, c)
+	wraps := metrics.ClaimCursorWraps.Value()
+
+	if got := claimIDs(t, s, c); !slices.Equal(got, ids[:2]) {
+		t.Errorf("first cycle claimed %v; want %v", got, ids[:2])
+	}
+	if got := s.HGet(c.claimCursorKey(), testStreamS1); got != nextStreamID(ids[1]) {
+		t.Errorf("saved cursor = %q; want %q", got, nextStreamID(ids[1]))
+	}
+
+	// A restarted instance of the same consumer carries on after the
+	// entries claimed before the restart, then starts over.
+	restarted := claimCursorClient(t, s)
+	for i, want := range [][]string{ids[2:], ids[:2]} {
+		if got := claimIDs(t, s, restarted); !slices.Equal(got, want) {
+			t.Errorf("cycle %d after the restart claimed %v; want %v", i, got, want)
+		}
+	}
+	if got := metrics.ClaimCursorWraps.Value() - wraps; got != 1 {
+		t.Errorf("ClaimCursorWraps delta = %d; want 1", got)
+	}
+
+	restarted.reportClaimDurations(t.Context(), nil)
+	if got := restarted.claimCursors.start(testStreamS1); got != "-" {
+		t.Errorf("start() of a dropped stream = %q; want -", got)
+	}
+	if s.HGet(restarted.claimCursorKey(), testStreamS1) != "" {
+		t.Errorf("cursor of a dropped stream still saved")
+	}
+}
+
+// miniredis ignores the MIN-IDLE of XCLAIM, so an entry deleted after the
+// XPENDING stands in for one another consumer claimed first.
+func TestClaimMessages_CountsContention(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.claimIdle = 0
+
+	first := mustXAdd(t, s, testStreamS1, "source", "10.0.0.1")
+	mustXAdd(t, s, testStreamS1, "source", "10.0.0.2")
+	mustEnsureGroups(t, c, testStreamS1)
+	mustReadBatch(t, c)
+
+	pending, err := c.getPendingMessages(t.Context(), testStreamS1)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("getPendingMessages() = %v, %v; want two entries", pending, err)
+	}
+	if err := c.rdb.XDel(t.Context(), testStreamS1, first).Err(); err != nil {
+		t.Fatalf("XDel() error = %v", err)
+	}
+	before := metrics.ClaimContended.Value()
+	claimed, err := c.claimMessages(t.Context(), testStreamS1, pending)
+	if err != nil {
+		t.Fatalf("claimMessages() error = %v", err)
+	}
+	if len(claimed) != 1 {
+		t.Errorf("claimed %d entries; want 1", len(claimed))
+	}
+	if got := metrics.ClaimContended.Value() - before; got != 1 {
+		t.Errorf("ClaimContended delta = %d; want 1", got)
+	}
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// ClaimTurnChecks is how many times per claim interval an instance asks
+// for the claim turn when claim coordination is on. Checking more than
+// once lets the designated instance take its turn in the first half of the
+// interval whatever the phase of its ticker, and lets the others take over
+// in the second half when it does not.
+const ClaimTurnChecks = 4
+
+// claimTurnScript grants at most one claim cycle per interval across the
+// instances sharing a consumer group. Intervals are numbered from the
+// Redis server clock, so the instances need not agree on the time.
+//
+// KEYS[1] holds the number of the last interval that was claimed, KEYS[2]
+// is a sorted set of the instances that asked recently, scored by the time
+// they last asked. ARGV[1] is the caller's consumer name, ARGV[2] the
+// interval in milliseconds.
+//
+// Each interval is designated to one live instance, round-robin over the
+// sorted names, and only it may take the turn during the first half of the
+// interval; any instance may take it during the second half. An instance
+// that stops asking drops out of the rotation after two intervals.
+var claimTurnScript = redis.NewScript(`
+local now = redis.call('TIME')
+local ms = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local interval = tonumber(ARGV[2])
+local slot = math.floor(ms / interval)
+local slotKey = string.format('%d', slot)
+
+redis.call('ZADD', KEYS[2], ms, ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ms - 2 * interval)
+redis.call('PEXPIRE', KEYS[2], 2 * interval)
+
+if redis.call('GET', KEYS[1]) == slotKey then
+	return 0
+end
+
+local contenders = redis.call('ZRANGE', KEYS[2], 0, -1)
+table.sort(contenders)
+local designated = contenders[slot % #contenders + 1]
+if designated ~= ARGV[1] and ms - slot * interval < interval / 2 then
+	return 0
+end
+
+redis.call('SET', KEYS[1], slotKey, 'PX', 2 * interval)
+return 1
+`)
+
+// ClaimInterval is how often a claim cycle runs: REDIS_CLAIM_INTERVAL, or
+// REDIS_CLAIM_IDLE when it is unset.
+func ClaimInterval(cfg *config.RedisConfig) time.Duration {
+	if cfg.ClaimInterval > 0 {
+		return cfg.ClaimInterval
+	}
+	return cfg.ClaimIdle
+}
+
+// claimTurnKeys returns the coordination keys of the consumer group, or nil
+// when claim coordination is off. The hash tag keeps both keys in one Redis
+// Cluster slot, as the script requires.
+func claimTurnKeys(cfg *config.RedisConfig) []string {
+	if !cfg.ClaimCoordination {
+		return nil
+	}
+	prefix := Key(cfg, "syslog-consumer:{"+cfg.GroupName+"}:claim-")
+	return []string{prefix + "turn", prefix + "contenders"}
+}
+
+// SetClaimInterval sets the claim interval claim turns are granted for, 0
+// for the configured one. Every instance of the group must use the same
+// interval, as the control hash gives them. It is safe to call while
+// ClaimIdle runs.
+func (c *Client) SetClaimInterval(d time.Duration) {
+	c.claimOverride.Store(int64(max(d, 0)))
+}
+
+// turnInterval is the claim interval claim turns are granted for.
+func (c *Client) turnInterval() time.Duration {
+	if d := c.claimOverride.Load(); d > 0 {
+		return time.Duration(d)
+	}
+	return c.claimInterval
+}
+
+// takeClaimTurn reports whether this instance runs the claim cycle of the
+// current interval.
+func (c *Client) takeClaimTurn(ctx context.Context) (bool, error) {
+	interval := strconv.FormatInt(c.turnInterval().Milliseconds(), 10)
+	granted, err := claimTurnScript.Run(ctx, c.rdb, c.claimTurnKeys, c.consumer, interval).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to take claim turn: %w", err)
+	}
+	return granted == 1, nil
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+const testClaimInterval = 10 * time.Second
+
+// coordinatedClient is a test client taking part in claim coordination as
+// consumer.
+func coordinatedClient(t *testing.T, s *miniredis.Miniredis, consumer string) *Client {
+	t.Helper()
+	c := newTestClient(t, s, testStreamS1)
+	c.consumer = consumer
+	c.claimInterval = testClaimInterval
+	c.claimTurnKeys = claimTurnKeys(&config.RedisConfig{GroupName: testGroupName, ClaimCoordination: true})
+	return c
+}
+
+// atSlot sets the server clock offset into claim interval slot.
+func atSlot(s *miniredis.Miniredis, slot int64, offset time.Duration) {
+	s.SetTime(time.UnixMilli(slot * testClaimInterval.Milliseconds()).Add(offset))
+}
+
+func mustTakeTurn(t *testing.T, c *Client, want bool, step string) {
+	t.Helper()
+	got, err := c.takeClaimTurn(t.Context())
+	if err != nil {
+		t.Fatalf("%s: takeClaimTurn() error = %v", step, err)
+	}
+	if got != want {
+		t.Errorf("%s: %s takeClaimTurn() = %v; want %v", step, c.consumer, got, want)
+	}
+}
+
+func TestTakeClaimTurn_RotatesAndTakesOver(t *testing.T) {
+	s := startMiniredis(t)
+	a := coordinatedClient(t, s, "a")
+	b := coordinatedClient(t, s, "b")
+	const slot = 170_000_000 // even: designates "a" once both take part
+
+	atSlot(s, slot, time.Second)
+	mustTakeTurn(t, a, true, "sole contender")
+	mustTakeTurn(t, b, false, "interval already claimed")
+
+	atSlot(s, slot+1, time.Second)
+	mustTakeTurn(t, a, false, "first half of b's interval")
+	mustTakeTurn(t, b, true, "b's turn")
+	mustTakeTurn(t, a, false, "interval already claimed")
+
+	atSlot(s, slot+2, time.Second)
+	mustTakeTurn(t, b, false, "first half of a's interval")
+	atSlot(s, slot+2, 6*time.Second)
+	mustTakeTurn(t, b, true, "takeover in the second half")
+
+	// a last asked in slot+1, so two intervals later it has left the
+	// rotation and b is designated again.
+	atSlot(s, slot+4, time.Second)
+	mustTakeTurn(t, b, true, "a dropped out")
+}
+
+func TestClaimIdle_ClaimsOnlyOnItsTurn(t *testing.T) {
+	s := startMiniredis(t)
+	other := coordinatedClient(t, s, "other")
+	c := coordinatedClient(t, s, "test-consumer")
+	const slot = 170_000_000 // even: designates "other", then "test-consumer"
+
+	atSlot(s, slot, 0)
+	mustXAdd(t, s, testStreamS1, "source", "10.0.0.1")
+	mustEnsureGroups(t, c, testStreamS1)
+	mustReadBatch(t, c)
+
+	atSlot(s, slot+2, time.Second)
+	mustTakeTurn(t, other, true, "other's turn")
+	batch, err := c.ClaimIdle(t.Context())
+	if err != nil {
+		t.Fatalf("ClaimIdle() error = %v", err)
+	}
+	if len(batch.Items) != 0 {
+		t.Errorf("claimed %d messages; want none without the claim turn", len(batch.Items))
+	}
+
+	atSlot(s, slot+3, time.Second)
+	batch, err = c.ClaimIdle(t.Context())
+	if err != nil {
+		t.Fatalf("ClaimIdle() error = %v", err)
+	}
+	defer batch.Release()
+	if len(batch.Items) != 1 {
+		t.Errorf("claimed %d messages; want the idle entry on its own turn", len(batch.Items))
+	}
+}
+
+func TestTakeClaimTurn_FollowsSetClaimInterval(t *testing.T) {
+	s := startMiniredis(t)
+	c := coordinatedClient(t, s, "a")
+	turnKey := c.claimTurnKeys[0]
+	const slot = 170_000_000
+
+	atSlot(s, slot, time.Second)
+	mustTakeTurn(t, c, true, "configured interval")
+	if got := s.TTL(turnKey); got != 2*testClaimInterval {
+		t.Errorf("turn TTL = %v; want two configured intervals", got)
+	}
+
+	c.SetClaimInterval(time.Second)
+	s.SetTime(time.UnixMilli((slot + 1) * testClaimInterval.Milliseconds()))
+	mustTakeTurn(t, c, true, "overridden interval")
+	if got := s.TTL(turnKey); got != 2*time.Second {
+		t.Errorf("turn TTL = %v; want two overridden intervals", got)
+	}
+	if got, want := mustGet(t, s, turnKey), strconv.FormatInt((slot+1)*10, 10); got != want {
+		t.Errorf("turn slot = %s; want %s, numbered in 1s intervals", got, want)
+	}
+
+	c.SetClaimInterval(0)
+	if got := c.turnInterval(); got != testClaimInterval {
+		t.Errorf("turnInterval() = %v after reset; want the configured %v", got, testClaimInterval)
+	}
+}
+
+func mustGet(t *testing.T, s *miniredis.Miniredis, key string) string {
+	t.Helper()
+	v, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	}
+	return v
+}
+
+func TestClaimInterval(t *testing.T) {
+	cfg := config.RedisConfig{ClaimIdle: time.Minute}
+	if got := ClaimInterval(&cfg); got != time.Minute {
+		t.Errorf("ClaimInterval() = %v; want ClaimIdle when unset", got)
+	}
+	cfg.ClaimInterval = 5 * time.Second
+	if got := ClaimInterval(&cfg); got != 5*time.Second {
+		t.Errorf("ClaimInterval() = %v; want 5s", got)
+	}
+}
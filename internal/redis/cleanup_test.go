@@ -29,7 +29,7 @@ func TestCleanupDeadConsumers_Integration(t *testing.T) {
 	cfg := &fullCfg.Redis
 
 	logger := log.New()
-	client, err := NewClient(cfg, logger)
+	client, err := NewClient(t.Context(), cfg, logger)
 	if err != nil {
 		t.Skip("Redis not available, skipping integration test")
 		return
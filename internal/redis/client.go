@@ -3,7 +3,9 @@ package redis
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,6 +17,7 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/metrics"
 	"github.com/redis/go-redis/v9"
 	"github.com/redis/go-redis/v9/maintnotifications"
+	"golang.org/x/sync/errgroup"
 )
 
 // isNoGroupError matches the "NOGROUP" prefix Redis uses when the stream or
@@ -29,48 +32,92 @@ func isNoGroupError(err error) bool {
 // Client is the Redis stream consumer used by the hot path.
 type Client struct {
 	rdb                *redis.Client
+	replica            *redis.Client // REDIS_REPLICA_ADDRESS; nil without one, see reader
 	log                *log.Logger
+	deadLetterRedact   atomic.Pointer[func(*message.Redis)]
+	pendingAfter       map[string]string // ReadPending cursor per stream
+	pendingUntil       map[string]string // ReadPending end per stream: the group's last delivered ID at the first read
 	batchPool          sync.Pool
 	claimPool          sync.Pool
 	consumer           string
 	groupName          string
+	legacyGroup        string // GroupName without REDIS_KEY_PREFIX, migrated by groupStart
+	keyPrefix          string // REDIS_KEY_PREFIX
+	scanMatch          string // SCAN MATCH pattern of the streams under keyPrefix
+	deadLetterStream   string
+	receiptsStream     string
+	memoryPressureKey  string
+	controlKey         string
+	streamFilter       streamFilter // REDIS_STREAM_INCLUDE/EXCLUDE; DiscoverStreams only
+	readFields         readFields   // REDIS_READ_FIELDS; nil keeps every field
 	streams            []string
-	streamsArg         []string
-	mu                 sync.RWMutex // protects streams, streamsArg
+	draining           []string                // detached, still claimed until drained
+	overrides          []config.StreamOverride // REDIS_STREAM_OVERRIDES_FILE
+	tuning             map[string]streamTuning // per stream; nil without overrides
+	reads              []streamRead            // one per batch size; ReadBatch only
+	blockRead          streamRead              // every stream, when reads has several
+	attached           map[string]struct{}     // AttachStream; guarded by pinMu
+	detached           map[string]struct{}     // DetachStream; guarded by pinMu
+	leases             *streamLeases           // REDIS_STREAM_LEASE; nil reads every stream
+	idle               *idleStreams            // REDIS_IDLE_STREAM_AFTER; ReadBatch only
+	streamsFound       chan struct{}
+	claimTurnKeys      []string     // nil unless claim coordination is on
+	claimCursors       claimCursors // where each stream's next claim starts
+	dedup              dedupFilter  // REDIS_DEDUP settings
+	compaction         compaction   // REDIS_COMPACT_* settings
+	mu                 sync.RWMutex // protects streams, tuning, draining
+	readGate           sync.RWMutex // held by ReadBatch across its reads; see setStreams
+	pinMu              sync.Mutex   // serializes RefreshStreams with AttachStream and DetachStream
 	batchSize          int64
+	claimConcurrency   int
 	blockTimeout       time.Duration
 	claimIdle          time.Duration
+	claimInterval      time.Duration
+	claimOverride      atomic.Int64 // nanoseconds; SetClaimInterval's claim interval, 0 for claimInterval
 	discoveryScanCount int64
+	readCount          atomic.Int64 // XREADGROUP COUNT; batchSize unless adaptive sizing changes it
+	lagSupport         atomic.Int32 // whether XINFO GROUPS reports the lag; GroupLags only
 	multiStreamMode    bool
-	streamsArgDirty    atomic.Bool // forces streamsArg rebuild when streams list changed
+	noAck              bool
+	streamsArgDirty    atomic.Bool // forces a rebuild of reads when streams list changed; set under mu
 }
 
-func newBatchSlicePool(capacity int) sync.Pool {
+func newBatchBufferPool(capacity int) sync.Pool {
 	return sync.Pool{
 		New: func() any {
-			s := make([]message.Redis, 0, capacity)
-			return &s
+			return message.NewBuffer(capacity)
 		},
 	}
 }
 
+func (c *Client) getBuffer(pool *sync.Pool) *message.Buffer {
+	buf, ok := pool.Get().(*message.Buffer)
+	if !ok {
+		return message.NewBuffer(int(c.batchSize))
+	}
+	return buf
+}
+
 // NewClient dials Redis with cfg.PingTimeout and discovers streams or pins
-// to cfg.Stream depending on whether cfg.Stream is empty.
+// to cfg.Stream depending on whether cfg.Stream is empty, then creates the
+// consumer group on each stream that lacks it.
 func NewClient(ctx context.Context, cfg *config.RedisConfig, logger *log.Logger) (*Client, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:            cfg.Address,
-		DialTimeout:     cfg.DialTimeout,
-		ReadTimeout:     cfg.ReadTimeout,
-		WriteTimeout:    cfg.WriteTimeout,
-		PoolSize:        cfg.PoolSize,
-		MinIdleConns:    cfg.MinIdleConns,
-		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
-		ConnMaxLifetime: cfg.ConnMaxLifetime,
-		// Maintenance notifications add extra commands and load we don't need.
-		MaintNotificationsConfig: &maintnotifications.Config{
-			Mode: maintnotifications.ModeDisabled,
-		},
-	})
+	client, err := connect(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.ensureGroups(ctx, client.streams); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// connect is NewClient without the consumer groups; it writes nothing.
+func connect(ctx context.Context, cfg *config.RedisConfig, logger *log.Logger) (*Client, error) {
+	rdb := redis.NewClient(newOptions(cfg))
+	if timeout := operationTimeout(cfg); timeout > 0 {
+		rdb.AddHook(timeoutHook{timeout: timeout, block: cfg.BlockTimeout})
+	}
 
 	pingCtx, cancel := context.WithTimeout(ctx, cfg.PingTimeout)
 	defer cancel()
@@ -81,15 +128,37 @@ func NewClient(ctx context.Context, cfg *config.RedisConfig, logger *log.Logger)
 
 	client := &Client{
 		rdb:                rdb,
+		replica:            newReplica(cfg, rdb, logger),
 		consumer:           cfg.Consumer,
-		groupName:          cfg.GroupName,
+		groupName:          Key(cfg, cfg.GroupName),
+		keyPrefix:          cfg.KeyPrefix,
+		scanMatch:          scanPattern(cfg.KeyPrefix),
+		deadLetterStream:   Key(cfg, cfg.DeadLetterStream),
+		receiptsStream:     Key(cfg, cfg.ReceiptsStream),
+		memoryPressureKey:  Key(cfg, cfg.MemoryPressureKey),
+		controlKey:         Key(cfg, cfg.ControlKey),
 		batchSize:          int64(cfg.BatchSize),
 		blockTimeout:       cfg.BlockTimeout,
 		claimIdle:          cfg.ClaimIdle,
+		claimInterval:      ClaimInterval(cfg),
+		claimConcurrency:   max(cfg.ClaimConcurrency, 1),
+		claimTurnKeys:      claimTurnKeys(cfg),
+		noAck:              cfg.NoAck,
+		dedup:              newDedupFilter(cfg),
+		compaction:         newCompaction(cfg),
+		streamFilter:       newStreamFilter(cfg),
+		readFields:         newReadFields(cfg.ReadFields),
+		leases:             newStreamLeases(cfg),
+		idle:               newIdleStreams(cfg),
+		overrides:          cfg.StreamOverrides,
 		discoveryScanCount: int64(cfg.DiscoveryScanCount),
 		log:                logger,
-		batchPool:          newBatchSlicePool(cfg.BatchSize),
-		claimPool:          newBatchSlicePool(cfg.BatchSize),
+		batchPool:          newBatchBufferPool(cfg.BatchSize),
+		claimPool:          newBatchBufferPool(cfg.BatchSize),
+		streamsFound:       make(chan struct{}, 1),
+	}
+	if cfg.KeyPrefix != "" {
+		client.legacyGroup = cfg.GroupName
 	}
 
 	if cfg.Stream == "" {
@@ -105,36 +174,72 @@ func NewClient(ctx context.Context, cfg *config.RedisConfig, logger *log.Logger)
 			logger.Infof(ctx, "Discovered %d streams: %v", len(streams), streams)
 		}
 
-		client.streams = streams
+		client.streams, client.tuning = client.tune(streams)
 		client.multiStreamMode = true
-		client.streamsArgDirty.Store(true)
 	} else {
-		logger.Infof(ctx, "Single-stream mode: consuming from stream '%s'", cfg.Stream)
-		client.streams = []string{cfg.Stream}
-		client.multiStreamMode = false
-		client.streamsArgDirty.Store(true)
+		stream := Key(cfg, cfg.Stream)
+		logger.Infof(ctx, "Single-stream mode: consuming from stream '%s'", stream)
+		client.streams, client.tuning = client.tune([]string{stream})
 	}
+	client.readCount.Store(client.batchSize)
+	client.streamsArgDirty.Store(true)
+	return client, nil
+}
 
-	if err := client.ensureGroups(ctx, client.streams); err != nil {
-		return nil, err
+func newOptions(cfg *config.RedisConfig) *redis.Options {
+	return &redis.Options{
+		Addr:            cfg.Address,
+		Username:        cfg.Username,
+		Password:        cfg.Password,
+		DB:              cfg.DB,
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		WriteTimeout:    cfg.WriteTimeout,
+		PoolSize:        cfg.PoolSize,
+		MinIdleConns:    cfg.MinIdleConns,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		// Lets the deadlines of timeoutHook reach the socket.
+		ContextTimeoutEnabled: true,
+		// Maintenance notifications add extra commands and load we don't need.
+		MaintNotificationsConfig: &maintnotifications.Config{
+			Mode: maintnotifications.ModeDisabled,
+		},
 	}
-
-	return client, nil
 }
 
 // DiscoverStreams lists every Redis key of type stream using SCAN with the
-// server-side TYPE filter to avoid per-key round-trips.
+// server-side TYPE filter to avoid per-key round-trips; under
+// REDIS_KEY_PREFIX, only the keys under the prefix. The dead letter and
+// receipts streams are skipped so the consumer never reads its own output,
+// and so are streams REDIS_STREAM_INCLUDE/EXCLUDE filter out. The scan
+// goes to REDIS_REPLICA_ADDRESS when it is set. No key is looked up on
+// its own, so the cost is one round trip per REDIS_DISCOVERY_SCAN_COUNT
+// keys, counted in consumer.discovery_scans. Like RefreshStreams, it must
+// not be called concurrently.
 func (c *Client) DiscoverStreams(ctx context.Context) ([]string, error) {
 	streams := make([]string, 0, c.discoveryScanCount)
+	var skipped []string
 	var cursor uint64
+	start := time.Now()
 
 	for {
-		keys, nextCursor, err := c.rdb.ScanType(ctx, cursor, "*", c.discoveryScanCount, "stream").Result()
+		keys, nextCursor, err := c.reader().ScanType(ctx, cursor, c.scanMatch, c.discoveryScanCount, "stream").Result()
+		metrics.DiscoveryScans.Add(1)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan keys: %w", err)
 		}
 
-		streams = append(streams, keys...)
+		for _, key := range keys {
+			if key == c.deadLetterStream || key == c.receiptsStream {
+				continue
+			}
+			if !c.streamFilter.allows(key) {
+				skipped = append(skipped, key)
+				continue
+			}
+			streams = append(streams, key)
+		}
 
 		cursor = nextCursor
 		if cursor == 0 {
@@ -142,12 +247,23 @@ func (c *Client) DiscoverStreams(ctx context.Context) ([]string, error) {
 		}
 	}
 
+	if fresh := c.streamFilter.newlySkipped(skipped); len(fresh) > 0 {
+		c.log.Infof(ctx, "Skipping %d streams filtered out by the stream include/exclude patterns: %v",
+			len(fresh), fresh)
+	}
+	metrics.StreamsMatched.Set(int64(len(streams)))
+	metrics.StreamsSkipped.Set(int64(len(skipped)))
+	metrics.DiscoveryDurationMs.Set(time.Since(start).Milliseconds())
 	return streams, nil
 }
 
 func (c *Client) ensureGroups(ctx context.Context, streams []string) error {
 	for _, stream := range streams {
-		err := c.rdb.XGroupCreateMkStream(ctx, stream, c.groupName, "0").Err()
+		start, err := c.groupStart(ctx, stream)
+		if err != nil {
+			return err
+		}
+		err = c.rdb.XGroupCreateMkStream(ctx, stream, c.groupName, start).Err()
 		if err != nil {
 			if strings.Contains(err.Error(), "BUSYGROUP") {
 				c.log.Infof(ctx, "Consumer group '%s' already exists for stream '%s', joining existing group", c.groupName, stream)
@@ -155,39 +271,22 @@ func (c *Client) ensureGroups(ctx context.Context, streams []string) error {
 			}
 			return fmt.Errorf("failed to create consumer group for stream %s: %w", stream, err)
 		}
+		if start != "0" {
+			c.log.Warnf(ctx, "Created consumer group '%s' for stream '%s' after %s, where group '%s' left off; "+
+				"delete that group with XGROUP DESTROY once no consumer reads with it", c.groupName, stream, start, c.legacyGroup)
+			continue
+		}
 		c.log.Infof(ctx, "Created consumer group '%s' for stream '%s'", c.groupName, stream)
 	}
 	return nil
 }
 
-// ReadBatch must only be called from a single goroutine: streamsArg is not
-// guarded by the mutex.
+// ReadBatch must only be called from a single goroutine: reads is not
+// guarded by the mutex. With NOACK the entries read are not added to the
+// group's pending list. Streams whose overrides set different batch sizes
+// are read apart, higher priorities first.
 func (c *Client) ReadBatch(ctx context.Context) (message.Batch, error) {
-	c.mu.RLock()
-	streams := c.streams
-	c.mu.RUnlock()
-
-	if len(streams) == 0 {
-		return message.Batch{}, nil
-	}
-
-	if c.streamsArgDirty.CompareAndSwap(true, false) {
-		n := len(streams)
-		c.streamsArg = c.streamsArg[:0]
-		c.streamsArg = append(c.streamsArg, streams...)
-		for range n {
-			c.streamsArg = append(c.streamsArg, ">")
-		}
-	}
-
-	result, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    c.groupName,
-		Consumer: c.consumer,
-		Streams:  c.streamsArg,
-		Count:    c.batchSize,
-		Block:    c.blockTimeout,
-	}).Result()
-
+	result, err := c.read(ctx)
 	if err != nil {
 		return message.Batch{},
 			c.handleReadError(ctx, err)
@@ -197,28 +296,78 @@ func (c *Client) ReadBatch(ctx context.Context) (message.Batch, error) {
 		return message.Batch{}, nil
 	}
 
-	pv := c.batchPool.Get()
-	bp, ok := pv.(*[]message.Redis)
-	if !ok {
-		s := make([]message.Redis, 0, c.batchSize)
-		bp = &s
-	}
-	messages := (*bp)[:0]
-
+	buf := c.getBuffer(&c.batchPool)
+	var dropped projected
 	for si := range result {
 		sr := &result[si]
 		for i := range sr.Messages {
-			object, raw := extractFields(sr.Messages[i].Values)
-			messages = append(messages, message.Redis{
-				ID:     sr.Messages[i].ID,
-				Stream: sr.Stream,
-				Object: object,
-				Raw:    raw,
-			})
+			c.readFields.apply(sr.Messages[i].Values, &dropped)
+			buf.Add(sr.Messages[i].ID, sr.Stream, sr.Messages[i].Values)
 		}
 	}
+	dropped.report()
+
+	return message.NewLazyBatch(buf, &c.batchPool), nil
+}
+
+// read issues the XREADGROUP calls of ReadBatch on the current streams,
+// holding readGate so that setStreams can wait for it. With no stream to
+// read it waits for one, outside the gate. Under REDIS_IDLE_STREAM_AFTER
+// it blocks on the active streams only, and returns the entries of a poll
+// of the suspended ones when it finds some.
+func (c *Client) read(ctx context.Context) ([]redis.XStream, error) {
+	c.readGate.RLock()
+	c.mu.RLock()
+	streams, tuning := c.leases.filter(c.streams), c.tuning
+	dirty := c.streamsArgDirty.Swap(false) // taken with the streams it was set for
+	c.mu.RUnlock()
+
+	if len(streams) == 0 {
+		c.readGate.RUnlock()
+		c.waitForStreams(ctx)
+		return nil, nil
+	}
+	defer c.readGate.RUnlock()
 
-	return message.NewPooledBatch(messages, bp, &c.batchPool), nil
+	now := time.Now()
+	var result []redis.XStream
+	var err error
+	if c.idle != nil {
+		streams, dirty, result, err = c.readIdle(ctx, streams, dirty, now)
+	}
+	if dirty {
+		c.buildReads(streams, tuning)
+	}
+	if err != nil || len(result) > 0 {
+		return result, err
+	}
+	if len(c.reads) == 1 {
+		result, err = c.readGroup(ctx, &c.reads[0], c.blockTimeout)
+	} else {
+		result, err = c.readTuned(ctx)
+	}
+	c.idle.touch(result, now)
+	return result, err
+}
+
+// waitForStreams stands in for XREADGROUP BLOCK while there are no streams
+// to read: it returns when a refresh finds some, the block timeout passes
+// or ctx is canceled, so an idle multi-stream consumer does not spin.
+func (c *Client) waitForStreams(ctx context.Context) {
+	timer := time.NewTimer(c.blockTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.streamsFound:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// SetReadBatchSize sets the COUNT of the following ReadBatch calls; it is
+// safe to call while ReadBatch runs. Claims keep REDIS_BATCH_SIZE, and
+// streams with a batch size override keep theirs.
+func (c *Client) SetReadBatchSize(n int) {
+	c.readCount.Store(int64(max(n, 1)))
 }
 
 // handleReadError returns nil when the error was recovered (caller returns
@@ -242,59 +391,124 @@ func (c *Client) handleReadError(ctx context.Context, err error) error {
 }
 
 // ClaimIdle reclaims pending messages whose owner has been idle longer than
-// the configured ClaimIdle threshold.
+// the configured ClaimIdle threshold, or the stream's override of it. Streams are claimed in parallel, at
+// most claimConcurrency at a time; a failing stream is logged and skipped.
+// With claim coordination on, it returns an empty batch unless this
+// instance holds the claim turn of the current interval.
 func (c *Client) ClaimIdle(ctx context.Context) (message.Batch, error) {
+	if c.claimTurnKeys != nil {
+		ok, err := c.takeClaimTurn(ctx)
+		if err != nil || !ok {
+			return message.Batch{}, err
+		}
+	}
+
+	c.loadClaimCursors(ctx)
 	c.mu.RLock()
-	streams := c.streams
+	streams := c.leases.filter(c.streams)
+	if len(c.draining) > 0 {
+		streams = append(slices.Clone(streams), c.draining...)
+	}
 	c.mu.RUnlock()
 
-	pv := c.claimPool.Get()
-	bp, ok := pv.(*[]message.Redis)
-	if !ok {
-		s := make([]message.Redis, 0, c.batchSize)
-		bp = &s
+	claims := make([]streamClaim, len(streams))
+	var g errgroup.Group
+	g.SetLimit(c.claimConcurrency)
+	for i, stream := range streams {
+		g.Go(func() error {
+			start := time.Now()
+			claimed := c.claimStream(ctx, stream)
+			claims[i] = streamClaim{stream: stream, messages: claimed, elapsed: time.Since(start)}
+			return nil
+		})
 	}
-	allMessages := (*bp)[:0]
+	_ = g.Wait() // claimStream never fails the group; errors are per stream
+	c.reportClaimDurations(ctx, claims)
 
-	for _, stream := range streams {
-		pending, err := c.getPendingMessages(ctx, stream)
-		if err != nil {
-			c.log.Warnf(ctx, "failed to get pending messages for stream %s: %v", stream, err)
-			continue
+	buf := c.getBuffer(&c.claimPool)
+	var dropped projected
+	for i := range claims {
+		for j := range claims[i].messages {
+			c.readFields.apply(claims[i].messages[j].Values, &dropped)
+			buf.Add(claims[i].messages[j].ID, claims[i].stream, claims[i].messages[j].Values)
 		}
+	}
+	dropped.report()
 
-		if len(pending) == 0 {
-			continue
-		}
+	return message.NewLazyBatch(buf, &c.claimPool), nil
+}
 
-		claimed, err := c.claimMessages(ctx, stream, pending)
-		if err != nil {
-			c.log.Warnf(ctx, "failed to claim messages for stream %s: %v", stream, err)
-			continue
+// streamClaim is one stream's share of a claim cycle.
+type streamClaim struct {
+	stream   string
+	messages []redis.XMessage
+	elapsed  time.Duration
+}
+
+func (c *Client) claimStream(ctx context.Context, stream string) []redis.XMessage {
+	pending, err := c.getPendingMessages(ctx, stream)
+	if err != nil {
+		c.log.Warnf(ctx, "failed to get pending messages for stream %s: %v", stream, err)
+		return nil
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	claimed, err := c.claimMessages(ctx, stream, pending)
+	if err != nil {
+		c.log.Warnf(ctx, "failed to claim messages for stream %s: %v", stream, err)
+		return nil
+	}
+	return claimed
+}
+
+// reportClaimDurations publishes each stream's claim time and forgets
+// streams that were not part of this cycle, so the map and the claim
+// cursors track the live set.
+func (c *Client) reportClaimDurations(ctx context.Context, claims []streamClaim) {
+	live := make(map[string]struct{}, len(claims))
+	var slowest streamClaim
+	for i := range claims {
+		live[claims[i].stream] = struct{}{}
+		metrics.ClaimStreamDuration.Set(claims[i].stream, durationMillis(claims[i].elapsed))
+		if claims[i].elapsed > slowest.elapsed {
+			slowest = claims[i]
 		}
+	}
 
-		for _, msg := range claimed {
-			object, raw := extractFields(msg.Values)
-			allMessages = append(allMessages, message.Redis{
-				ID:     msg.ID,
-				Stream: stream,
-				Object: object,
-				Raw:    raw,
-			})
+	var stale []string
+	metrics.ClaimStreamDuration.Do(func(kv expvar.KeyValue) {
+		if _, ok := live[kv.Key]; !ok {
+			stale = append(stale, kv.Key)
 		}
+	})
+	for _, stream := range stale {
+		metrics.ClaimStreamDuration.Delete(stream)
 	}
+	c.forgetClaimCursors(ctx, live)
+
+	if slowest.stream != "" {
+		c.log.Debugf(ctx, "Claim cycle over %d streams, slowest %s took %s",
+			len(claims), slowest.stream, slowest.elapsed)
+	}
+}
 
-	return message.NewPooledBatch(allMessages, bp, &c.claimPool), nil
+func durationMillis(d time.Duration) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(d.Milliseconds())
+	return v
 }
 
 func (c *Client) getPendingMessages(ctx context.Context, stream string) ([]redis.XPendingExt, error) {
+	idle, count := c.claimSettings(stream)
 	pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
 		Stream: stream,
 		Group:  c.groupName,
-		Idle:   c.claimIdle,
-		Start:  "-",
+		Idle:   idle,
+		Start:  c.claimCursors.start(stream),
 		End:    "+",
-		Count:  c.batchSize,
+		Count:  count,
 	}).Result()
 
 	if err != nil {
@@ -313,6 +527,9 @@ func (c *Client) getPendingMessages(ctx context.Context, stream string) ([]redis
 		return nil, fmt.Errorf("xpending failed: %w", err)
 	}
 
+	if next, changed := c.claimCursors.advance(stream, pending, count); changed {
+		c.saveClaimCursor(ctx, stream, next)
+	}
 	return pending, nil
 }
 
@@ -324,11 +541,12 @@ func (c *Client) claimMessages(
 		ids[i] = p.ID
 	}
 
+	idle, _ := c.claimSettings(stream)
 	claimed, err := c.rdb.XClaim(ctx, &redis.XClaimArgs{
 		Stream:   stream,
 		Group:    c.groupName,
 		Consumer: c.consumer,
-		MinIdle:  c.claimIdle,
+		MinIdle:  idle,
 		Messages: ids,
 	}).Result()
 
@@ -336,20 +554,27 @@ func (c *Client) claimMessages(
 		return nil, fmt.Errorf("xclaim failed: %w", err)
 	}
 
+	// XCLAIM skips entries another consumer claimed since the XPENDING,
+	// which reset their idle time, and entries deleted meanwhile.
+	metrics.ClaimContended.Add(int64(len(ids) - len(claimed)))
 	return claimed, nil
 }
 
 // RefreshStreams must only be called from refreshLoop (single goroutine);
-// the RLock/Lock split relies on that. Returns the number of new streams added.
+// the RLock/Lock split relies on that. Returns the number of new streams
+// added. Streams attached or detached at runtime stay so.
 func (c *Client) RefreshStreams(ctx context.Context) (int, error) {
 	if !c.multiStreamMode {
 		return 0, nil
 	}
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
 
 	discoveredStreams, err := c.DiscoverStreams(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to discover streams: %w", err)
 	}
+	discoveredStreams = c.pin(discoveredStreams)
 
 	c.mu.RLock()
 	prevCount := len(c.streams)
@@ -373,10 +598,16 @@ func (c *Client) RefreshStreams(ctx context.Context) (int, error) {
 		}
 	}
 
-	c.mu.Lock()
-	c.streams = discoveredStreams
-	c.mu.Unlock()
-	c.streamsArgDirty.Store(true)
+	streams, tuning := c.tune(discoveredStreams)
+	c.setStreams(len(discoveredStreams)-len(newStreams) < prevCount, func() {
+		c.streams, c.tuning = streams, tuning
+	})
+	if len(discoveredStreams) > 0 {
+		select {
+		case c.streamsFound <- struct{}{}:
+		default:
+		}
+	}
 
 	metrics.StreamsActive.Set(int64(len(discoveredStreams)))
 	metrics.StreamsDiscovered.Add(int64(len(newStreams)))
@@ -388,59 +619,33 @@ func (c *Client) RefreshStreams(ctx context.Context) (int, error) {
 	return len(newStreams), nil
 }
 
-// AckAndDeleteBatch issues XACK + XDEL in a single pipeline round-trip.
-func (c *Client) AckAndDeleteBatch(ctx context.Context, ids []string, stream string) error {
-	if stream == "" {
-		return errors.New("cannot ACK messages: stream name is empty")
-	}
-	if len(ids) == 0 {
-		return nil
-	}
-
-	pipe := c.rdb.Pipeline()
-	pipe.XAck(ctx, stream, c.groupName, ids...)
-	pipe.XDel(ctx, stream, ids...)
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		if isNoGroupError(err) {
-			c.log.Warnf(ctx, "Consumer group missing for stream '%s' during batch ACK, recreating", stream)
-			if gerr := c.ensureGroups(ctx, []string{stream}); gerr != nil {
-				c.log.Warnf(ctx, "Failed to recreate group for stream '%s': %v", stream, gerr)
-			}
-			return nil
-		}
-		return fmt.Errorf("ack+del pipeline failed for %d messages in stream %s: %w", len(ids), stream, err)
+// setStreams runs update, which changes the streams read, under mu and has
+// the next read rebuilt. When the change drops streams, it first waits for
+// the read in flight, which may still name them: once it returns, no
+// XREADGROUP of ReadBatch delivers entries of a dropped stream. A read
+// lasts up to REDIS_BLOCK_TIMEOUT plus a round trip.
+func (c *Client) setStreams(dropping bool, update func()) {
+	if dropping {
+		c.readGate.Lock()
+		defer c.readGate.Unlock()
 	}
-
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update()
+	c.streamsArgDirty.Store(true)
 }
 
-// Close releases the underlying Redis connection pool; safe on a nil-backed
-// Client (e.g. ones built for tests without an rdb).
+// Close releases the underlying Redis connection pools; safe on a
+// nil-backed Client (e.g. ones built for tests without an rdb).
 func (c *Client) Close() error {
-	if c.rdb != nil {
-		return c.rdb.Close()
+	var errs []error
+	if c.replica != nil {
+		errs = append(errs, c.replica.Close())
 	}
-	return nil
-}
-
-// extractFields scans the field map once; Redis stream entries normally hold
-// only "object" and "raw", so one range beats two map lookups.
-func extractFields(m map[string]any) (object, raw string) {
-	for k, v := range m {
-		switch k {
-		case "object":
-			if s, ok := v.(string); ok {
-				object = s
-			}
-		case "raw":
-			if s, ok := v.(string); ok {
-				raw = s
-			}
-		}
+	if c.rdb != nil {
+		errs = append(errs, c.rdb.Close())
 	}
-	return
+	return errors.Join(errs...)
 }
 
 // Ping verifies the connection; used by the health endpoint.
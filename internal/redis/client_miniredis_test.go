@@ -2,7 +2,8 @@ package redis
 
 import (
 	"errors"
-	"sync"
+	"expvar"
+	"slices"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
 	goredis "github.com/redis/go-redis/v9"
 )
 
@@ -51,21 +53,14 @@ func newTestClient(t *testing.T, s *miniredis.Miniredis, stream string) *Client
 		batchSize:          10,
 		blockTimeout:       50 * time.Millisecond,
 		claimIdle:          1 * time.Second,
+		claimConcurrency:   4,
 		discoveryScanCount: 1000,
 		log:                log.New(),
-		batchPool: sync.Pool{
-			New: func() any {
-				s := make([]message.Redis, 0, 10)
-				return &s
-			},
-		},
-		claimPool: sync.Pool{
-			New: func() any {
-				s := make([]message.Redis, 0, 10)
-				return &s
-			},
-		},
+		batchPool:          newBatchBufferPool(10),
+		claimPool:          newBatchBufferPool(10),
+		streamsFound:       make(chan struct{}, 1),
 	}
+	client.readCount.Store(client.batchSize)
 	if stream != "" {
 		client.streams = []string{stream}
 		client.multiStreamMode = false
@@ -169,6 +164,44 @@ func TestNewClient_MultiStream(t *testing.T) {
 	}
 }
 
+func TestNewClient_ACLUserAndDB(t *testing.T) {
+	s := startMiniredis(t)
+	s.RequireUserAuth("consumer-acl", "s3cret")
+	if _, err := s.DB(2).XAdd("db2-stream", "*", []string{"k", "v"}); err != nil {
+		t.Fatalf("XAdd(db 2): %v", err)
+	}
+
+	cfg := &config.RedisConfig{
+		Address:            s.Addr(),
+		Username:           "consumer-acl",
+		Password:           "s3cret",
+		DB:                 2,
+		Consumer:           "c1",
+		GroupName:          testGroupName,
+		BatchSize:          10,
+		DiscoveryScanCount: 1000,
+		DialTimeout:        1 * time.Second,
+		ReadTimeout:        1 * time.Second,
+		WriteTimeout:       1 * time.Second,
+		PingTimeout:        1 * time.Second,
+	}
+
+	client, err := NewClient(t.Context(), cfg, log.New())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer closeRedisClient(t, client)
+
+	if len(client.streams) != 1 || client.streams[0] != "db2-stream" {
+		t.Errorf("streams = %v; want [db2-stream]", client.streams)
+	}
+
+	cfg.Password = "wrong"
+	if _, err := NewClient(t.Context(), cfg, log.New()); err == nil {
+		t.Error("expected authentication error with wrong password")
+	}
+}
+
 func TestNewClient_ConnectionFailure(t *testing.T) {
 	cfg := &config.RedisConfig{
 		Address:            "localhost:1", // invalid port
@@ -246,6 +279,7 @@ func TestDiscoverStreams_FindsStreams(t *testing.T) {
 
 	mustXAdd(t, s, "stream-1", "k", "v")
 	mustXAdd(t, s, "stream-2", "k", "v")
+	scans := metrics.DiscoveryScans.Value()
 
 	streams, err := c.DiscoverStreams(t.Context())
 	if err != nil {
@@ -254,6 +288,9 @@ func TestDiscoverStreams_FindsStreams(t *testing.T) {
 	if len(streams) < 2 {
 		t.Errorf("expected ≥2 streams, got %d: %v", len(streams), streams)
 	}
+	if got := metrics.DiscoveryScans.Value() - scans; got < 1 {
+		t.Errorf("DiscoveryScans delta = %d; want at least 1", got)
+	}
 }
 
 func TestDiscoverStreams_EmptyDatabase(t *testing.T) {
@@ -299,6 +336,28 @@ func TestReadBatch_ReadsMessages(t *testing.T) {
 	}
 }
 
+func TestReadBatch_ExtractsFieldsOnVisit(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	mustXAdd(t, s, testStreamS1, "object", `{"k":"v"}`, "raw", "line")
+	mustEnsureGroups(t, c, testStreamS1)
+
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	defer batch.Release()
+	if len(batch.Items) != 1 || batch.Items[0].Raw != "" {
+		t.Fatalf("items = %+v; want one entry with its body not yet extracted", batch.Items)
+	}
+
+	for msg := range batch.All() {
+		if msg.Object != `{"k":"v"}` || msg.Raw != "line" {
+			t.Errorf("visited item = %+v; want object and raw extracted", *msg)
+		}
+	}
+}
+
 func TestReadBatch_EmptyStreams(t *testing.T) {
 	s := startMiniredis(t)
 	c := newTestClient(t, s, "")
@@ -313,6 +372,42 @@ func TestReadBatch_EmptyStreams(t *testing.T) {
 	}
 }
 
+func TestReadBatch_EmptyStreamsWaitsForBlockTimeout(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+
+	start := time.Now()
+	mustReadBatch(t, c)
+	if elapsed := time.Since(start); elapsed < c.blockTimeout {
+		t.Errorf("ReadBatch() returned after %v; want it to wait the %v block timeout", elapsed, c.blockTimeout)
+	}
+}
+
+func TestReadBatch_EmptyStreamsWokenByRefresh(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.blockTimeout = time.Minute
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.ReadBatch(t.Context())
+		done <- err
+	}()
+
+	mustXAdd(t, s, testStreamS1, "k", "v")
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ReadBatch() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadBatch() still waiting after streams were found")
+	}
+}
+
 func TestReadBatch_NoNewMessages(t *testing.T) {
 	s := startMiniredis(t)
 	c := newTestClient(t, s, testStreamS1)
@@ -366,6 +461,38 @@ func TestAckAndDeleteBatch_Success(t *testing.T) {
 	}
 }
 
+func TestAckAndDeleteBatch_CountsAlreadyAckedAndMissing(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+
+	pending := mustXAdd(t, s, testStreamS1, "k", "v")
+	acked := mustXAdd(t, s, testStreamS1, "k", "v")
+	mustEnsureGroups(t, c, testStreamS1)
+	mustReadBatch(t, c)
+	if err := c.rdb.XAck(t.Context(), testStreamS1, c.groupName, acked).Err(); err != nil {
+		t.Fatalf("XAck(): %v", err)
+	}
+	alreadyAckedBefore := metrics.AcksAlreadyAcked.Value()
+	missingBefore := metrics.AcksMissing.Value()
+
+	if err := c.AckAndDeleteBatch(t.Context(), []string{pending, acked, "9999999999999-0"}, testStreamS1); err != nil {
+		t.Fatalf("AckAndDeleteBatch() error = %v", err)
+	}
+	if got := metrics.AcksAlreadyAcked.Value() - alreadyAckedBefore; got != 1 {
+		t.Errorf("AcksAlreadyAcked delta = %d; want 1", got)
+	}
+	if got := metrics.AcksMissing.Value() - missingBefore; got != 1 {
+		t.Errorf("AcksMissing delta = %d; want 1", got)
+	}
+	if n, err := c.rdb.XLen(t.Context(), testStreamS1).Result(); err != nil || n != 0 {
+		t.Errorf("XLen() = %d (err %v); want 0", n, err)
+	}
+	info, err := c.rdb.XPending(t.Context(), testStreamS1, c.groupName).Result()
+	if err != nil || info.Count != 0 {
+		t.Errorf("XPending() = %+v (err %v); want nothing pending", info, err)
+	}
+}
+
 func TestAckAndDeleteBatch_EmptyStream(t *testing.T) {
 	s := startMiniredis(t)
 	c := newTestClient(t, s, testStreamS1)
@@ -510,6 +637,30 @@ func TestReadBatch_SingleStreamMultipleMessages(t *testing.T) {
 	}
 }
 
+func TestReadBatch_SetReadBatchSize(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	for range 3 {
+		mustXAdd(t, s, testStreamS1, "source", "10.0.0.1")
+	}
+	mustEnsureGroups(t, c, testStreamS1)
+
+	c.SetReadBatchSize(2)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	defer batch.Release()
+	if len(batch.Items) != 2 {
+		t.Errorf("ReadBatch() read %d entries; want 2", len(batch.Items))
+	}
+
+	c.SetReadBatchSize(0)
+	if got := c.readCount.Load(); got != 1 {
+		t.Errorf("readCount after SetReadBatchSize(0) = %d; want 1", got)
+	}
+}
+
 // --- ClaimIdle with pending messages ---
 
 func TestClaimIdle_WithPendingMessages(t *testing.T) {
@@ -536,6 +687,60 @@ func TestClaimIdle_WithPendingMessages(t *testing.T) {
 	_ = batch
 }
 
+func TestClaimIdle_ParallelStreamsKeepStreamOrder(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.claimIdle = 0
+	c.claimConcurrency = 2
+
+	streams := []string{testStreamS1, testStreamS2, "s3", "s4", "s5"}
+	for _, stream := range streams {
+		mustXAdd(t, s, stream, "source", stream)
+	}
+	mustEnsureGroups(t, c, streams...)
+	c.streams = streams
+	c.streamsArgDirty.Store(true)
+	mustReadBatch(t, c)
+	s.FastForward(2 * time.Second)
+
+	batch, err := c.ClaimIdle(t.Context())
+	if err != nil {
+		t.Fatalf("ClaimIdle() error = %v", err)
+	}
+	defer batch.Release()
+
+	if len(batch.Items) != len(streams) {
+		t.Fatalf("claimed %d messages; want %d", len(batch.Items), len(streams))
+	}
+	for i, item := range batch.Items {
+		if item.Stream != streams[i] {
+			t.Errorf("item %d stream = %s; want %s", i, item.Stream, streams[i])
+		}
+	}
+}
+
+func TestClaimIdle_ReportsStreamDurations(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	mustEnsureGroups(t, c, testStreamS1)
+
+	metrics.ClaimStreamDuration.Set("gone", new(expvar.Int))
+	t.Cleanup(func() { metrics.ClaimStreamDuration.Delete(testStreamS1) })
+
+	batch, err := c.ClaimIdle(t.Context())
+	if err != nil {
+		t.Fatalf("ClaimIdle() error = %v", err)
+	}
+	batch.Release()
+
+	if metrics.ClaimStreamDuration.Get(testStreamS1) == nil {
+		t.Errorf("no claim duration reported for %s", testStreamS1)
+	}
+	if metrics.ClaimStreamDuration.Get("gone") != nil {
+		t.Error("stale stream still present in claim durations")
+	}
+}
+
 // --- claimMessages direct test ---
 
 func TestClaimMessages_Success(t *testing.T) {
@@ -606,3 +811,214 @@ func TestAckAndDeleteBatch_NOGROUP_Recovery(t *testing.T) {
 		t.Fatalf("AckAndDeleteBatch(): %v", err)
 	}
 }
+
+// --- DeadLetter ---
+
+func TestDeadLetter_MovesEntries(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.deadLetterStream = "dlq"
+
+	mustXAdd(t, s, testStreamS1, "object", `{"k":"v"}`, "raw", "line")
+	mustEnsureGroups(t, c, testStreamS1)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	defer batch.Release()
+	batch.Decode()
+
+	if err := c.DeadLetter(t.Context(), batch.Items, "oversize"); err != nil {
+		t.Fatalf("DeadLetter() error = %v", err)
+	}
+
+	dead, err := s.Stream("dlq")
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("dlq entries = %v (err %v); want 1", dead, err)
+	}
+	want := []string{
+		"stream", testStreamS1, "id", batch.Items[0].ID, "reason", "oversize",
+		"object", `{"k":"v"}`, "raw", "line",
+	}
+	if !slices.Equal(dead[0].Values, want) {
+		t.Errorf("dlq values = %v; want %v", dead[0].Values, want)
+	}
+	if remaining, _ := s.Stream(testStreamS1); len(remaining) != 0 {
+		t.Errorf("source stream still has %d entries; want 0", len(remaining))
+	}
+}
+
+func TestDeadLetter_NoStreamConfigured(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+
+	if err := c.DeadLetter(t.Context(), []message.Redis{{ID: "1-0", Stream: testStreamS1}}, "oversize"); err == nil {
+		t.Error("DeadLetter() error = nil; want error without a dead letter stream")
+	}
+}
+
+func TestDeadLetterIDs_ReadsEntriesByID(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.deadLetterStream = "dlq"
+
+	id := mustXAdd(t, s, testStreamS1, "object", `{"k":"v"}`, "raw", "line")
+	mustEnsureGroups(t, c, testStreamS1)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	batch.Release()
+
+	if err := c.DeadLetterIDs(t.Context(), testStreamS1, []string{id, "9-0"}, "schema_error"); err != nil {
+		t.Fatalf("DeadLetterIDs() error = %v", err)
+	}
+
+	dead, err := s.Stream("dlq")
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("dlq entries = %v (err %v); want 1", dead, err)
+	}
+	want := []string{
+		"stream", testStreamS1, "id", id, "reason", "schema_error",
+		"object", `{"k":"v"}`, "raw", "line",
+	}
+	if !slices.Equal(dead[0].Values, want) {
+		t.Errorf("dlq values = %v; want %v", dead[0].Values, want)
+	}
+	pending, err := c.rdb.XPending(t.Context(), testStreamS1, c.groupName).Result()
+	if err != nil || pending.Count != 0 {
+		t.Errorf("pending = %+v (err %v); want none", pending, err)
+	}
+}
+
+func TestDiscoverStreams_SkipsOutputStreams(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.deadLetterStream = "dlq"
+	c.receiptsStream = "receipts"
+	mustXAdd(t, s, testStreamS1, "k", "v")
+	mustXAdd(t, s, "dlq", "k", "v")
+	mustXAdd(t, s, "receipts", "k", "v")
+
+	streams, err := c.DiscoverStreams(t.Context())
+	if err != nil {
+		t.Fatalf("DiscoverStreams() error = %v", err)
+	}
+	if !slices.Equal(streams, []string{testStreamS1}) {
+		t.Errorf("streams = %v; want [%s]", streams, testStreamS1)
+	}
+}
+
+func TestDiscoverStreams_AppliesStreamFilters(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.streamFilter = newStreamFilter(&config.RedisConfig{
+		StreamInclude: []string{"syslog:*"},
+		StreamExclude: []string{"syslog:test-*"},
+	})
+	for _, stream := range []string{"syslog:a", "syslog:test-1", "orders"} {
+		mustXAdd(t, s, stream, "k", "v")
+	}
+
+	streams, err := c.DiscoverStreams(t.Context())
+	if err != nil {
+		t.Fatalf("DiscoverStreams() error = %v", err)
+	}
+	if !slices.Equal(streams, []string{"syslog:a"}) {
+		t.Errorf("streams = %v; want [syslog:a]", streams)
+	}
+	if metrics.StreamsMatched.Value() != 1 || metrics.StreamsSkipped.Value() != 2 {
+		t.Errorf("StreamsMatched/Skipped = %d/%d; want 1/2",
+			metrics.StreamsMatched.Value(), metrics.StreamsSkipped.Value())
+	}
+	fresh := c.streamFilter.newlySkipped([]string{"orders", "syslog:test-2"})
+	if !slices.Equal(fresh, []string{"syslog:test-2"}) {
+		t.Errorf("newlySkipped() = %v; want only the stream not skipped before", fresh)
+	}
+}
+
+// --- WriteReceipts ---
+
+func TestWriteReceipts_AppendsEntries(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.receiptsStream = "receipts"
+
+	at := time.UnixMilli(1700000000123)
+	receipts := []message.Receipt{
+		{At: at, Event: message.ReceiptPublished, Stream: testStreamS1, IDs: []string{"1-0", "2-0"}},
+		{At: at, Event: message.ReceiptAcked, Stream: testStreamS1, IDs: []string{"1-0"}},
+	}
+	if err := c.WriteReceipts(t.Context(), receipts); err != nil {
+		t.Fatalf("WriteReceipts() error = %v", err)
+	}
+
+	entries, err := s.Stream("receipts")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("receipts entries = %v (err %v); want 2", entries, err)
+	}
+	want := []string{
+		"event", "published", "stream", testStreamS1, "ids", "1-0,2-0",
+		"at", "1700000000123", "consumer", "test-consumer",
+	}
+	if !slices.Equal(entries[0].Values, want) {
+		t.Errorf("receipt values = %v; want %v", entries[0].Values, want)
+	}
+	if entries[1].Values[1] != "acked" {
+		t.Errorf("second receipt event = %q; want acked", entries[1].Values[1])
+	}
+}
+
+func TestWriteReceipts_NoStreamConfigured(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+
+	receipts := []message.Receipt{{Event: message.ReceiptAcked, Stream: testStreamS1, IDs: []string{"1-0"}}}
+	if err := c.WriteReceipts(t.Context(), receipts); err == nil {
+		t.Error("WriteReceipts() error = nil; want error without a receipts stream")
+	}
+}
+
+func TestAddEntryAndDeleteStream(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+
+	id, err := c.AddEntry(t.Context(), testStreamS1, "raw", "probe")
+	if err != nil {
+		t.Fatalf("AddEntry() error = %v", err)
+	}
+	entries, err := s.Stream(testStreamS1)
+	if err != nil || len(entries) != 1 || entries[0].ID != id {
+		t.Fatalf("stream entries = %v (err %v); want one entry with ID %s", entries, err, id)
+	}
+
+	if err := c.DeleteStream(t.Context(), testStreamS1); err != nil {
+		t.Fatalf("DeleteStream() error = %v", err)
+	}
+	if s.Exists(testStreamS1) {
+		t.Error("stream still exists after DeleteStream")
+	}
+}
+
+func TestReadBatch_NoAckLeavesNothingPending(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.noAck = true
+	mustXAdd(t, s, testStreamS1, "raw", "fire and forget")
+	mustEnsureGroups(t, c, testStreamS1)
+
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(batch.Items) != 1 {
+		t.Fatalf("ReadBatch() = %d items; want 1", len(batch.Items))
+	}
+	pending, err := c.rdb.XPending(t.Context(), testStreamS1, testGroupName).Result()
+	if err != nil {
+		t.Fatalf("XPending() error = %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("pending = %d; want 0 after a NOACK read", pending.Count)
+	}
+}
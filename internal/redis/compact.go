@@ -0,0 +1,222 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// compaction holds the REDIS_COMPACT_* settings.
+type compaction struct {
+	minAge    time.Duration
+	batchSize int64
+	dryRun    bool
+}
+
+func newCompaction(cfg *config.RedisConfig) compaction {
+	return compaction{
+		minAge:    cfg.CompactMinAge,
+		batchSize: int64(max(cfg.CompactBatchSize, 1)),
+		dryRun:    cfg.CompactDryRun,
+	}
+}
+
+// CompactStreams deletes from every consumed stream the entries older than
+// the compaction min age that every consumer group of the stream has read
+// and none has pending: entries acknowledged without being deleted, by
+// another group's consumers, by hand or under REDIS_NOACK. Under
+// REDIS_COMPACT_DRY_RUN it only counts them. A stream that fails is
+// logged and skipped.
+func (c *Client) CompactStreams(ctx context.Context) error {
+	c.mu.RLock()
+	streams := c.streams
+	c.mu.RUnlock()
+
+	var total int64
+	now := time.Now()
+	for _, stream := range streams {
+		n, err := c.compactStream(ctx, stream, now)
+		total += n
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			c.log.Warnf(ctx, "failed to compact stream %s: %v", stream, err)
+			metrics.CompactionErrors.Add(1)
+		}
+	}
+
+	switch {
+	case total == 0:
+	case c.compaction.dryRun:
+		c.log.Infof(ctx, "Compaction dry run: %d acknowledged entries would be deleted", total)
+		metrics.CompactionDryRun.Add(total)
+	default:
+		c.log.Infof(ctx, "Compacted %d acknowledged entries", total)
+		metrics.CompactionDeleted.Add(total)
+	}
+	return nil
+}
+
+// compactStream scans stream up to compactEnd, CompactBatchSize entries at
+// a time, and deletes the ones no group has pending. It returns how many
+// it deleted, or would have, before an error.
+func (c *Client) compactStream(ctx context.Context, stream string, now time.Time) (int64, error) {
+	groups, end, err := c.compactEnd(ctx, stream, now)
+	if err != nil || end == "" {
+		return 0, err
+	}
+
+	var total int64
+	start := "-"
+	for {
+		msgs, err := c.rdb.XRangeN(ctx, stream, start, end, c.compaction.batchSize).Result()
+		if err != nil {
+			return total, fmt.Errorf("failed to scan entries: %w", err)
+		}
+		if len(msgs) == 0 {
+			return total, nil
+		}
+		ids := make([]string, 0, len(msgs))
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+		}
+		if ids, err = c.withoutPending(ctx, stream, groups, ids); err != nil {
+			return total, err
+		}
+		n, err := c.compactIDs(ctx, stream, ids)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if int64(len(msgs)) < c.compaction.batchSize {
+			return total, nil
+		}
+		start = nextStreamID(msgs[len(msgs)-1].ID)
+	}
+}
+
+// compactEnd returns the groups of stream and the last entry ID
+// compaction may delete: the oldest of the groups' last delivered IDs, and
+// no later than the min age before now. It returns an empty end when
+// nothing can be deleted.
+func (c *Client) compactEnd(ctx context.Context, stream string, now time.Time) ([]string, string, error) {
+	infos, err := c.rdb.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get groups info: %w", err)
+	}
+	if len(infos) == 0 {
+		return nil, "", nil
+	}
+	cutoff := now.Add(-c.compaction.minAge).UnixMilli() - 1
+	if cutoff < 0 {
+		return nil, "", nil
+	}
+	end := strconv.FormatInt(cutoff, 10) + "-" + strconv.FormatUint(math.MaxUint64, 10)
+	groups := make([]string, 0, len(infos))
+	for _, info := range infos {
+		groups = append(groups, info.Name)
+		if compareStreamIDs(info.LastDeliveredID, end) < 0 {
+			end = info.LastDeliveredID
+		}
+	}
+	if ms, seq := parseStreamID(end); ms == 0 && seq == 0 {
+		return nil, "", nil // a group has read nothing yet
+	}
+	return groups, end, nil
+}
+
+// withoutPending returns the entries of ids, in ascending order, that
+// none of groups has pending.
+func (c *Client) withoutPending(ctx context.Context, stream string, groups, ids []string) ([]string, error) {
+	pending := make(map[string]bool)
+	for _, group := range groups {
+		start := ids[0]
+		for {
+			page, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: stream,
+				Group:  group,
+				Start:  start,
+				End:    ids[len(ids)-1],
+				Count:  c.compaction.batchSize,
+			}).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read pending entries of group %s: %w", group, err)
+			}
+			for _, p := range page {
+				pending[p.ID] = true
+			}
+			if int64(len(page)) < c.compaction.batchSize {
+				break
+			}
+			start = nextStreamID(page[len(page)-1].ID)
+		}
+	}
+	if len(pending) == 0 {
+		return ids, nil
+	}
+	kept := ids[:0]
+	for _, id := range ids {
+		if !pending[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+func (c *Client) compactIDs(ctx context.Context, stream string, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if c.compaction.dryRun {
+		return int64(len(ids)), nil
+	}
+	n, err := c.rdb.XDel(ctx, stream, ids...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete entries: %w", err)
+	}
+	return n, nil
+}
+
+// parseStreamID splits an entry ID into its millisecond time and sequence;
+// a malformed ID is 0-0.
+func parseStreamID(id string) (ms, seq uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	seq, _ = strconv.ParseUint(seqPart, 10, 64) // an ID may omit it
+	return ms, seq
+}
+
+// compareStreamIDs orders two entry IDs like cmp.Compare.
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+	if c := cmp.Compare(aMs, bMs); c != 0 {
+		return c
+	}
+	return cmp.Compare(aSeq, bSeq)
+}
+
+// nextStreamID returns the smallest entry ID after id, to resume a scan
+// without the exclusive ranges of Redis 6.2.
+func nextStreamID(id string) string {
+	ms, seq := parseStreamID(id)
+	if seq == math.MaxUint64 {
+		ms, seq = ms+1, 0
+	} else {
+		seq++
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq, 10)
+}
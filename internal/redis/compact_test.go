@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// newCompactClient returns a client whose group has read entries 1-1 to
+// 5-1 of testStreamS1 and acknowledged 1-1, 2-1 and 4-1 without deleting
+// them, next to a second group that has read up to 3-1.
+func newCompactClient(t *testing.T, dryRun bool) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.compaction = newCompaction(&config.RedisConfig{CompactMinAge: time.Hour, CompactBatchSize: 2, CompactDryRun: dryRun})
+	for i := 1; i <= 5; i++ {
+		if _, err := s.XAdd(testStreamS1, strconv.Itoa(i)+"-1", []string{"k", "v"}); err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+	}
+	mustEnsureGroups(t, c, testStreamS1)
+	mustReadBatch(t, c)
+	if err := c.rdb.XAck(t.Context(), testStreamS1, testGroupName, "1-1", "2-1", "4-1").Err(); err != nil {
+		t.Fatalf("XAck() error = %v", err)
+	}
+	if err := c.rdb.XGroupCreate(t.Context(), testStreamS1, "other", "3-1").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	return c, s
+}
+
+func streamIDs(t *testing.T, c *Client) []string {
+	t.Helper()
+	msgs, err := c.rdb.XRange(t.Context(), testStreamS1, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestCompactStreams_DeletesReadAndAcknowledged(t *testing.T) {
+	c, _ := newCompactClient(t, false)
+	if err := c.CompactStreams(t.Context()); err != nil {
+		t.Fatalf("CompactStreams() error = %v", err)
+	}
+	// 3-1 is pending, and 4-1 is past what the other group has read.
+	if got, want := streamIDs(t, c), []string{"3-1", "4-1", "5-1"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %v; want %v", got, want)
+	}
+}
+
+func TestCompactStreams_DryRun(t *testing.T) {
+	c, _ := newCompactClient(t, true)
+	groups, end, err := c.compactEnd(t.Context(), testStreamS1, time.Now())
+	if err != nil || end != "3-1" || len(groups) != 2 {
+		t.Fatalf("compactEnd() = %v, %q, %v; want both groups up to 3-1", groups, end, err)
+	}
+	n, err := c.compactStream(t.Context(), testStreamS1, time.Now())
+	if err != nil || n != 2 {
+		t.Errorf("compactStream() = %d, %v; want 2 entries counted", n, err)
+	}
+	if got := streamIDs(t, c); len(got) != 5 {
+		t.Errorf("entries = %v; want all of them kept in a dry run", got)
+	}
+}
+
+func TestCompactStreams_KeepsRecentEntries(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.compaction = newCompaction(&config.RedisConfig{CompactMinAge: time.Hour, CompactBatchSize: 10})
+	mustEnsureGroups(t, c, testStreamS1)
+	id := mustXAdd(t, s, testStreamS1, "k", "v")
+	mustReadBatch(t, c)
+	if err := c.rdb.XAck(t.Context(), testStreamS1, testGroupName, id).Err(); err != nil {
+		t.Fatalf("XAck() error = %v", err)
+	}
+	if n, err := c.compactStream(t.Context(), testStreamS1, time.Now()); err != nil || n != 0 {
+		t.Errorf("compactStream() = %d, %v; want an entry younger than the min age kept", n, err)
+	}
+	if n, _ := c.compactStream(t.Context(), testStreamS1, time.Now().Add(2*time.Hour)); n != 1 {
+		t.Errorf("compactStream() two hours later = %d; want the entry deleted", n)
+	}
+}
+
+func TestStreamIDs(t *testing.T) {
+	if got := compareStreamIDs("10-2", "9-5"); got != 1 {
+		t.Errorf("compareStreamIDs(10-2, 9-5) = %d; want 1", got)
+	}
+	if got := compareStreamIDs("7-1", "7-3"); got != -1 {
+		t.Errorf("compareStreamIDs(7-1, 7-3) = %d; want -1", got)
+	}
+	if got := nextStreamID("7-1"); got != "7-2" {
+		t.Errorf("nextStreamID(7-1) = %q; want 7-2", got)
+	}
+	if got := nextStreamID("7-18446744073709551615"); got != "8-0" {
+		t.Errorf("nextStreamID(7-max) = %q; want 8-0", got)
+	}
+	if ms, seq := parseStreamID("bogus"); ms != 0 || seq != 0 {
+		t.Errorf("parseStreamID(bogus) = %d-%d; want 0-0", ms, seq)
+	}
+}
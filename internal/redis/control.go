@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ReadControl returns the fields of the control hash, REDIS_CONTROL_KEY;
+// the map is empty when the hash does not exist.
+func (c *Client) ReadControl(ctx context.Context) (map[string]string, error) {
+	if c.controlKey == "" {
+		return nil, errors.New("cannot read the control hash: no control key configured")
+	}
+	fields, err := c.rdb.HGetAll(ctx, c.controlKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read control hash %s: %w", c.controlKey, err)
+	}
+	return fields, nil
+}
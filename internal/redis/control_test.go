@@ -0,0 +1,22 @@
+package redis
+
+import "testing"
+
+func TestReadControl(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	if _, err := c.ReadControl(t.Context()); err == nil {
+		t.Error("ReadControl() without a key: nil error")
+	}
+
+	c.controlKey = "syslog-consumer:control"
+	fields, err := c.ReadControl(t.Context())
+	if err != nil || len(fields) != 0 {
+		t.Fatalf("ReadControl() = %v, %v; want no fields before the hash exists", fields, err)
+	}
+	s.HSet(c.controlKey, "log_level", "debug", "paused", "1")
+	fields, err = c.ReadControl(t.Context())
+	if err != nil || fields["log_level"] != "debug" || fields["paused"] != "1" {
+		t.Errorf("ReadControl() = %v, %v; want log_level debug and paused 1", fields, err)
+	}
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// DeadLetter copies msgs to the dead letter stream, tagged with their source
+// stream, entry ID and reason, then acknowledges and deletes the originals.
+// The copies are written first, so a failed XADD leaves the originals
+// pending for a later claim instead of losing them. The "object" and "raw"
+// fields keep their names so the dead letter stream can be replayed as-is.
+func (c *Client) DeadLetter(ctx context.Context, msgs []message.Redis, reason string) error {
+	if c.deadLetterStream == "" {
+		return errors.New("cannot dead-letter messages: no dead letter stream configured")
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	for i := range msgs {
+		m := &msgs[i]
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: c.deadLetterStream,
+			Values: []any{
+				"stream", m.Stream,
+				"id", m.ID,
+				"reason", reason,
+				"object", m.Object,
+				"raw", m.Raw,
+			},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("dead letter xadd failed for %d messages: %w", len(msgs), err)
+	}
+
+	idsByStream := make(map[string][]string)
+	for i := range msgs {
+		idsByStream[msgs[i].Stream] = append(idsByStream[msgs[i].Stream], msgs[i].ID)
+	}
+	var errs []error
+	for stream, ids := range idsByStream {
+		if err := c.AckAndDeleteBatch(ctx, ids, stream); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetDeadLetterRedaction has DeadLetterIDs pass each entry it reads back
+// through redact before copying it; the entries given to DeadLetter are
+// copied as they are.
+func (c *Client) SetDeadLetterRedaction(redact func(msg *message.Redis)) {
+	c.deadLetterRedact.Store(&redact)
+}
+
+// DeadLetterIDs reads the entries ids of stream and dead-letters them like
+// DeadLetter. IDs no longer in the stream are only acknowledged, so they
+// leave the pending list.
+func (c *Client) DeadLetterIDs(ctx context.Context, stream string, ids []string, reason string) error {
+	if c.deadLetterStream == "" {
+		return errors.New("cannot dead-letter messages: no dead letter stream configured")
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.XMessageSliceCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.XRangeN(ctx, stream, id, id, 1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("dead letter xrange failed for %d messages: %w", len(ids), err)
+	}
+
+	buf := message.NewBuffer(len(ids))
+	var missing []string
+	for i, cmd := range cmds {
+		entries := cmd.Val()
+		if len(entries) == 0 {
+			missing = append(missing, ids[i])
+			continue
+		}
+		buf.Add(entries[0].ID, stream, entries[0].Values)
+	}
+	batch := message.NewLazyBatch(buf, nil)
+	batch.Decode()
+	if redact := c.deadLetterRedact.Load(); redact != nil {
+		for i := range batch.Items {
+			(*redact)(&batch.Items[i])
+		}
+	}
+	if err := c.DeadLetter(ctx, batch.Items, reason); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return c.AckAndDeleteBatch(ctx, missing, stream)
+}
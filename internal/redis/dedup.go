@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// dedupErrorRate is the false positive rate of the REDIS_DEDUP=bloom
+// filters: about one in a thousand unique entries is dropped as a
+// duplicate. At this rate a filter takes about 1.8 bytes per entry of
+// capacity.
+const dedupErrorRate = 0.001
+
+// dedupFilter holds the REDIS_DEDUP settings; keys of the consumer group
+// share prefix.
+type dedupFilter struct {
+	mode     string
+	prefix   string
+	window   time.Duration
+	capacity int64
+}
+
+func newDedupFilter(cfg *config.RedisConfig) dedupFilter {
+	return dedupFilter{
+		mode:     cfg.Dedup,
+		prefix:   Key(cfg, "syslog-consumer:"+cfg.GroupName+":dedup:"),
+		window:   cfg.DedupWindow,
+		capacity: int64(cfg.DedupCapacity),
+	}
+}
+
+// Duplicates reports, for each of msgs, whether an entry of the same stream
+// with the same content was seen within the dedup window, and marks the
+// others as seen. Bodies must be extracted (Batch.Decode) before the call.
+// An identical entry later in msgs is a duplicate of the earlier one.
+func (c *Client) Duplicates(ctx context.Context, msgs []message.Redis) ([]bool, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	switch c.dedup.mode {
+	case config.DedupKeys:
+		return c.keyDuplicates(ctx, msgs)
+	case config.DedupBloom:
+		return c.bloomDuplicates(ctx, msgs)
+	default:
+		return nil, fmt.Errorf("cannot deduplicate: unknown dedup mode %q", c.dedup.mode)
+	}
+}
+
+// keyDuplicates sets one key per content hash with SET NX EX; an entry
+// whose key already exists is a duplicate.
+func (c *Client) keyDuplicates(ctx context.Context, msgs []message.Redis) ([]bool, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(msgs))
+	for i := range msgs {
+		cmds[i] = pipe.SetNX(ctx, c.dedup.prefix+contentHash(&msgs[i]), 1, c.dedup.window)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("dedup set failed for %d messages: %w", len(msgs), err)
+	}
+	dups := make([]bool, len(msgs))
+	for i, cmd := range cmds {
+		dups[i] = !cmd.Val()
+	}
+	return dups, nil
+}
+
+// bloomDuplicates adds the content hashes to the filter of the current
+// window and checks them against the previous one, so an entry is
+// remembered for one to two windows. Each filter is created non-scaling
+// with dedupCapacity entries, so its memory never grows; once it is full
+// BF.INSERT fails until the next window.
+func (c *Client) bloomDuplicates(ctx context.Context, msgs []message.Redis) ([]bool, error) {
+	hashes := make([]any, len(msgs))
+	for i := range msgs {
+		hashes[i] = contentHash(&msgs[i])
+	}
+	window := c.dedup.window.Milliseconds()
+	slot := time.Now().UnixMilli() / window
+	current := c.dedup.prefix + strconv.FormatInt(slot, 10)
+
+	pipe := c.rdb.Pipeline()
+	seen := pipe.BFMExists(ctx, c.dedup.prefix+strconv.FormatInt(slot-1, 10), hashes...)
+	added := pipe.BFInsert(ctx, current, &redis.BFInsertOptions{
+		Capacity:   c.dedup.capacity,
+		Error:      dedupErrorRate,
+		NonScaling: true,
+	}, hashes...)
+	pipe.PExpire(ctx, current, 2*c.dedup.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("dedup bloom filter update failed for %d messages: %w", len(msgs), err)
+	}
+
+	dups := make([]bool, len(msgs))
+	previous, inserted := seen.Val(), added.Val()
+	for i := range dups {
+		dups[i] = previous[i] || !inserted[i]
+	}
+	return dups, nil
+}
+
+// contentHash is the hex FNV-128a hash of the entry's stream, object and
+// raw line; the entry ID is left out, since duplicates have different IDs.
+func contentHash(m *message.Redis) string {
+	h := fnv.New128a()
+	_, _ = io.WriteString(h, m.Stream)
+	_, _ = h.Write([]byte{0})
+	_, _ = io.WriteString(h, m.Object)
+	_, _ = h.Write([]byte{0})
+	_, _ = io.WriteString(h, m.Raw)
+	var sum [16]byte
+	return hex.EncodeToString(h.Sum(sum[:0]))
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func newDedupClient(t *testing.T, mode string) (*Client, func(time.Duration)) {
+	t.Helper()
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.dedup = newDedupFilter(&config.RedisConfig{
+		GroupName: testGroupName, Dedup: mode, DedupWindow: time.Minute, DedupCapacity: 100,
+	})
+	return c, s.FastForward
+}
+
+func duplicates(t *testing.T, c *Client, msgs ...message.Redis) []bool {
+	t.Helper()
+	dups, err := c.Duplicates(t.Context(), msgs)
+	if err != nil {
+		t.Fatalf("Duplicates() error = %v", err)
+	}
+	return dups
+}
+
+func TestDuplicates_Keys(t *testing.T) {
+	c, fastForward := newDedupClient(t, config.DedupKeys)
+	a := message.Redis{ID: "1-0", Stream: testStreamS1, Raw: "line a"}
+	b := message.Redis{ID: "2-0", Stream: testStreamS1, Raw: "line b"}
+	aAgain := message.Redis{ID: "3-0", Stream: testStreamS1, Raw: "line a"}
+	aElsewhere := message.Redis{ID: "1-0", Stream: testStreamS2, Raw: "line a"}
+
+	if got := duplicates(t, c, a, b, aAgain); !slices.Equal(got, []bool{false, false, true}) {
+		t.Errorf("first batch = %v; want the repeat within the batch flagged", got)
+	}
+	if got := duplicates(t, c, aAgain, aElsewhere); !slices.Equal(got, []bool{true, false}) {
+		t.Errorf("second batch = %v; want only the same stream's content flagged", got)
+	}
+
+	fastForward(time.Minute)
+	if got := duplicates(t, c, aAgain); !slices.Equal(got, []bool{false}) {
+		t.Errorf("after the window = %v; want the content forgotten", got)
+	}
+}
+
+func TestDuplicates_Empty(t *testing.T) {
+	c, _ := newDedupClient(t, config.DedupKeys)
+	if got := duplicates(t, c); got != nil {
+		t.Errorf("Duplicates() = %v; want nil for no messages", got)
+	}
+}
+
+// miniredis has no RedisBloom, so only the failure path is exercised here;
+// callers treat it as "no duplicates".
+func TestDuplicates_BloomError(t *testing.T) {
+	c, _ := newDedupClient(t, config.DedupBloom)
+	if _, err := c.Duplicates(t.Context(), []message.Redis{{ID: "1-0", Stream: testStreamS1}}); err == nil {
+		t.Error("Duplicates() error = nil; want the unknown BF command error")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	base := message.Redis{ID: "1-0", Stream: "s", Object: `{"k":"v"}`, Raw: "line"}
+	sameContent := base
+	sameContent.ID = "2-0"
+	if contentHash(&base) != contentHash(&sameContent) {
+		t.Error("contentHash depends on the entry ID")
+	}
+	shifted := message.Redis{ID: "1-0", Stream: "s", Object: `{"k":"v"}line`}
+	if contentHash(&base) == contentHash(&shifted) {
+		t.Error("contentHash does not separate object from raw")
+	}
+	if got := len(contentHash(&base)); got != 32 {
+		t.Errorf("len(contentHash()) = %d; want 32 hex digits", got)
+	}
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LookupHashes reads fields of each hash in keys with HMGET, in one
+// pipeline. Each result maps the fields the hash has to their values; it is
+// empty for a hash that does not exist.
+func (c *Client) LookupHashes(ctx context.Context, keys, fields []string) ([]map[string]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HMGet(ctx, key, fields...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("hash lookup failed for %d keys: %w", len(keys), err)
+	}
+	found := make([]map[string]string, len(keys))
+	for i, cmd := range cmds {
+		found[i] = make(map[string]string, len(fields))
+		for j, v := range cmd.Val() {
+			if s, ok := v.(string); ok {
+				found[i][fields[j]] = s
+			}
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestLookupHashes(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	s.HSet("hosts:10.0.0.1", "site", "milan", "rack", "r12", "other", "x")
+	s.HSet("hosts:10.0.0.2", "owner", "net")
+
+	found, err := c.LookupHashes(t.Context(), []string{"hosts:10.0.0.1", "hosts:10.0.0.2", "hosts:10.0.0.3"},
+		[]string{"site", "rack", "owner"})
+	if err != nil {
+		t.Fatalf("LookupHashes() error = %v", err)
+	}
+	want := []map[string]string{
+		{"site": "milan", "rack": "r12"},
+		{"owner": "net"},
+		{},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("LookupHashes() = %v; want %v", found, want)
+	}
+	for i := range want {
+		if !maps.Equal(found[i], want[i]) {
+			t.Errorf("LookupHashes()[%d] = %v; want %v", i, found[i], want[i])
+		}
+	}
+
+	if found, err := c.LookupHashes(t.Context(), nil, []string{"site"}); found != nil || err != nil {
+		t.Errorf("LookupHashes(no keys) = %v, %v; want nil, nil", found, err)
+	}
+}
@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// groupResetReason tags the pending entries ResetGroup dead-letters.
+const groupResetReason = "group_reset"
+
+// GroupResetOptions says what ResetGroup does with the group's pending
+// entries, and whether it changes anything.
+type GroupResetOptions struct {
+	DeadLetterPending bool // dead-letter the pending entries first
+	DryRun            bool // count the pending entries, change nothing
+}
+
+// GroupReset is what ResetGroup did to a stream's consumer group, or would
+// have done in a dry run.
+type GroupReset struct {
+	Stream       string `json:"stream"`
+	ID           string `json:"id"`
+	Pending      int64  `json:"pending"` // pending entries before the reset
+	DeadLettered int    `json:"dead_lettered"`
+}
+
+// ValidGroupID reports whether id is a position XGROUP SETID accepts: "0"
+// for the start of the stream, "$" for its end, or an entry ID.
+func ValidGroupID(id string) bool {
+	if id == "$" {
+		return true
+	}
+	ms, seq, hasSeq := strings.Cut(id, "-")
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	if !hasSeq {
+		return true
+	}
+	_, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
+
+// ResetGroup moves the consumer group of stream to id, so its consumers
+// read again the entries after it, or skip those up to it. XGROUP SETID
+// leaves the pending entries pending, and the claim loop hands them out
+// again; with opts.DeadLetterPending they are dead-lettered first, in
+// batches, so a replay does not deliver them twice. Entries acknowledged
+// before have been deleted and cannot be read again.
+func (c *Client) ResetGroup(ctx context.Context, stream, id string, opts *GroupResetOptions) (GroupReset, error) {
+	reset := GroupReset{Stream: stream, ID: id}
+	if !ValidGroupID(id) {
+		return reset, fmt.Errorf("group reset id %q must be 0, $ or an entry ID", id)
+	}
+	if opts.DeadLetterPending && c.deadLetterStream == "" {
+		return reset, errors.New("cannot dead-letter pending entries: no dead letter stream configured")
+	}
+	summary, err := c.rdb.XPending(ctx, stream, c.groupName).Result()
+	if err != nil {
+		return reset, fmt.Errorf("xpending %s: %w", stream, err)
+	}
+	reset.Pending = summary.Count
+	if opts.DryRun {
+		return reset, nil
+	}
+	if opts.DeadLetterPending {
+		if reset.DeadLettered, err = c.deadLetterPending(ctx, stream); err != nil {
+			return reset, err
+		}
+	}
+	if err := c.rdb.XGroupSetID(ctx, stream, c.groupName, id).Err(); err != nil {
+		return reset, fmt.Errorf("xgroup setid %s %s: %w", stream, id, err)
+	}
+	return reset, nil
+}
+
+// deadLetterPending dead-letters every pending entry of the group on
+// stream, whichever consumer holds it, and returns how many it moved.
+func (c *Client) deadLetterPending(ctx context.Context, stream string) (int, error) {
+	var moved int
+	for {
+		// Dead-lettered entries leave the pending list, so each batch is
+		// read from the start.
+		pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  c.groupName,
+			Start:  "-",
+			End:    "+",
+			Count:  replayBatchSize,
+		}).Result()
+		if err != nil {
+			return moved, fmt.Errorf("xpending %s: %w", stream, err)
+		}
+		if len(pending) == 0 {
+			return moved, nil
+		}
+		ids := make([]string, len(pending))
+		for i := range pending {
+			ids[i] = pending[i].ID
+		}
+		if err := c.DeadLetterIDs(ctx, stream, ids, groupResetReason); err != nil {
+			return moved, err
+		}
+		moved += len(ids)
+	}
+}
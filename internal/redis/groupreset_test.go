@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestValidGroupID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"0": true, "$": true, "1700000000000": true, "1700000000000-3": true,
+		"": false, ">": false, "-": false, "1-": false, "-1": false, "1-2-3": false, "now": false,
+	} {
+		if got := ValidGroupID(id); got != want {
+			t.Errorf("ValidGroupID(%q) = %t; want %t", id, got, want)
+		}
+	}
+}
+
+// groupResetFixture returns a client whose group on testStreamS1 has read
+// two of three entries, leaving them pending.
+func groupResetFixture(t *testing.T) *Client {
+	t.Helper()
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.deadLetterStream = "dlq"
+	for _, raw := range []string{"a", "b", "c"} {
+		mustXAdd(t, s, testStreamS1, "object", "{}", "raw", raw)
+	}
+	if err := c.rdb.XGroupCreate(t.Context(), testStreamS1, testGroupName, "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	if err := c.rdb.XReadGroup(t.Context(), &goredis.XReadGroupArgs{
+		Group: testGroupName, Consumer: "other", Streams: []string{testStreamS1, ">"}, Count: 2, Block: -1,
+	}).Err(); err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+	return c
+}
+
+// readNew returns the raw field of the entries the group delivers next.
+func readNew(t *testing.T, c *Client) []string {
+	t.Helper()
+	streams, err := c.rdb.XReadGroup(t.Context(), &goredis.XReadGroupArgs{
+		Group: testGroupName, Consumer: "after", Streams: []string{testStreamS1, ">"}, Count: 10, Block: -1,
+	}).Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+	var raws []string
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			raws = append(raws, msg.Values["raw"].(string))
+		}
+	}
+	return raws
+}
+
+func TestDeadLetterPending(t *testing.T) {
+	c := groupResetFixture(t)
+
+	moved, err := c.deadLetterPending(t.Context(), testStreamS1)
+	if err != nil || moved != 2 {
+		t.Fatalf("deadLetterPending() = %d (err %v); want 2", moved, err)
+	}
+	dlq, err := c.rdb.XRange(t.Context(), "dlq", "-", "+").Result()
+	if err != nil || len(dlq) != 2 || dlq[0].Values["reason"] != groupResetReason {
+		t.Errorf("dlq entries = %v (err %v); want the 2 pending entries", dlq, err)
+	}
+	if n := c.rdb.XPending(t.Context(), testStreamS1, testGroupName).Val().Count; n != 0 {
+		t.Errorf("pending = %d; want 0", n)
+	}
+	if got := readNew(t, c); len(got) != 1 || got[0] != "c" {
+		t.Errorf("delivered next = %v; want [c]", got)
+	}
+}
+
+// miniredis has no XGROUP SETID, so the reset itself is exercised by the
+// integration tests; the dry run stops before it.
+func TestResetGroup_DryRun(t *testing.T) {
+	c := groupResetFixture(t)
+
+	reset, err := c.ResetGroup(t.Context(), testStreamS1, "$", &GroupResetOptions{DeadLetterPending: true, DryRun: true})
+	if err != nil || reset != (GroupReset{Stream: testStreamS1, ID: "$", Pending: 2}) {
+		t.Fatalf("dry run = %+v (err %v); want 2 pending, nothing moved", reset, err)
+	}
+	if n := c.rdb.XLen(t.Context(), "dlq").Val(); n != 0 {
+		t.Errorf("dlq length = %d; want 0", n)
+	}
+	if got := readNew(t, c); len(got) != 1 {
+		t.Errorf("delivered after the dry run = %v; want the unread entry", got)
+	}
+}
+
+func TestResetGroup_RejectsBadInput(t *testing.T) {
+	c := groupResetFixture(t)
+	if _, err := c.ResetGroup(t.Context(), testStreamS1, "yesterday", &GroupResetOptions{}); err == nil {
+		t.Error("ResetGroup(yesterday) error = nil; want an invalid id error")
+	}
+	c.deadLetterStream = ""
+	if _, err := c.ResetGroup(t.Context(), testStreamS1, "0", &GroupResetOptions{DeadLetterPending: true}); err == nil {
+		t.Error("ResetGroup() error = nil; want error without a dead letter stream")
+	}
+}
@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// idleStreams applies REDIS_IDLE_STREAM_AFTER in multi-stream mode: every
+// XREADGROUP names each of its streams, so the hundreds of mostly idle
+// streams of a large deployment weigh on every read. A stream that
+// returned no entry for that long is taken out of the blocking reads and
+// polled, without blocking, every REDIS_IDLE_STREAM_POLL instead; one a
+// poll finds entries in is read again from the next read on. When every
+// stream is idle they are all read, as without suspension. The split is
+// revised at each poll. Claims are unaffected. idleStreams belongs to
+// ReadBatch, which is called from a single goroutine; a nil idleStreams
+// reads every stream.
+type idleStreams struct {
+	nextPoll   time.Time
+	lastActive map[string]time.Time // when each stream last returned entries
+	suspended  map[string]struct{}
+	hot        []string   // the streams read with BLOCK, in order
+	poll       streamRead // the suspended streams
+	after      time.Duration
+	every      time.Duration
+	promoted   bool // a suspended stream returned entries since the split
+}
+
+func newIdleStreams(cfg *config.RedisConfig) *idleStreams {
+	if cfg.IdleStreamAfter <= 0 || cfg.Stream != "" {
+		return nil
+	}
+	return &idleStreams{
+		lastActive: make(map[string]time.Time),
+		after:      cfg.IdleStreamAfter,
+		every:      cfg.IdleStreamPoll,
+	}
+}
+
+// split sorts streams into the ones active within REDIS_IDLE_STREAM_AFTER,
+// read with BLOCK, and the suspended ones, and reports whether the former
+// changed. A stream it sees for the first time counts as active.
+func (s *idleStreams) split(streams []string, now time.Time) bool {
+	lastActive := make(map[string]time.Time, len(streams))
+	suspended := make(map[string]struct{})
+	hot := make([]string, 0, len(streams))
+	var idle []string
+	for _, stream := range streams {
+		last, ok := s.lastActive[stream]
+		if !ok {
+			last = now
+		}
+		lastActive[stream] = last
+		if now.Sub(last) < s.after {
+			hot = append(hot, stream)
+			continue
+		}
+		idle = append(idle, stream)
+		suspended[stream] = struct{}{}
+	}
+	if len(hot) == 0 {
+		hot, idle, suspended = streams, nil, nil
+	}
+	changed := !slices.Equal(hot, s.hot)
+	s.lastActive, s.suspended, s.hot = lastActive, suspended, hot
+	s.poll = streamRead{args: appendNewIDs(idle)}
+	s.promoted = false
+	metrics.StreamsSuspended.Set(int64(len(idle)))
+	return changed
+}
+
+// touch marks the streams result has entries of as active at now, and has
+// the next read revise the split when one of them was suspended.
+func (s *idleStreams) touch(result []redis.XStream, now time.Time) {
+	if s == nil {
+		return
+	}
+	for i := range result {
+		if len(result[i].Messages) == 0 {
+			continue
+		}
+		stream := result[i].Stream
+		s.lastActive[stream] = now
+		if _, ok := s.suspended[stream]; ok {
+			delete(s.suspended, stream)
+			s.promoted = true
+			metrics.StreamsPromoted.Add(1)
+		}
+	}
+}
+
+// readIdle returns the streams of streams to read with BLOCK, and whether
+// the reads must be rebuilt for them, dirty included. When a poll is due
+// it polls the suspended streams and returns what it read.
+func (c *Client) readIdle(ctx context.Context, streams []string, dirty bool, now time.Time) (
+	hot []string, rebuild bool, polled []redis.XStream, err error,
+) {
+	s := c.idle
+	due := !now.Before(s.nextPoll)
+	if due {
+		s.nextPoll = now.Add(s.every)
+	}
+	if dirty || due || s.promoted {
+		dirty = s.split(streams, now) || dirty
+	}
+	if !due || len(s.poll.args) == 0 {
+		return s.hot, dirty, nil, nil
+	}
+	polled, err = c.readGroup(ctx, &s.poll, -1)
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	s.touch(polled, now)
+	return s.hot, dirty, polled, err
+}
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func readEntries(t *testing.T, c *Client) map[string]int {
+	t.Helper()
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	defer batch.Release()
+	got := make(map[string]int)
+	for i := range batch.Items {
+		got[batch.Items[i].Stream]++
+	}
+	return got
+}
+
+func TestReadBatch_SuspendsIdleStreams(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.streams = []string{testStreamS1, testStreamS2}
+	c.streamsArgDirty.Store(true)
+	c.idle = newIdleStreams(&config.RedisConfig{IdleStreamAfter: time.Minute, IdleStreamPoll: time.Minute})
+	mustEnsureGroups(t, c, testStreamS1, testStreamS2)
+
+	readEntries(t, c)
+	if !slices.Equal(c.idle.hot, []string{testStreamS1, testStreamS2}) {
+		t.Fatalf("hot = %v; want every new stream active", c.idle.hot)
+	}
+
+	c.idle.lastActive[testStreamS2] = time.Now().Add(-time.Hour)
+	c.idle.nextPoll = time.Time{}
+	mustXAdd(t, s, testStreamS1, "k", "v")
+	if got := readEntries(t, c); got[testStreamS1] != 1 {
+		t.Errorf("read %v; want the entry of the active stream", got)
+	}
+	if !slices.Equal(c.idle.hot, []string{testStreamS1}) || metrics.StreamsSuspended.Value() != 1 {
+		t.Fatalf("hot = %v, StreamsSuspended = %d; want %s suspended",
+			c.idle.hot, metrics.StreamsSuspended.Value(), testStreamS2)
+	}
+
+	mustXAdd(t, s, testStreamS2, "k", "v")
+	if got := readEntries(t, c); len(got) != 0 {
+		t.Errorf("read %v before the poll; want nothing", got)
+	}
+	before := metrics.StreamsPromoted.Value()
+	c.idle.nextPoll = time.Time{}
+	if got := readEntries(t, c); got[testStreamS2] != 1 {
+		t.Errorf("read %v on the poll; want the suspended stream's entry", got)
+	}
+	if got := metrics.StreamsPromoted.Value() - before; got != 1 {
+		t.Errorf("StreamsPromoted delta = %d; want 1", got)
+	}
+	mustXAdd(t, s, testStreamS2, "k", "v")
+	if got := readEntries(t, c); got[testStreamS2] != 1 {
+		t.Errorf("read %v after the poll; want the promoted stream read again", got)
+	}
+}
+
+func TestIdleStreams_AllIdleReadsEveryStream(t *testing.T) {
+	s := &idleStreams{lastActive: make(map[string]time.Time), after: time.Minute, every: time.Minute}
+	now := time.Now()
+	streams := []string{testStreamS1, testStreamS2}
+	s.lastActive[testStreamS1] = now.Add(-time.Hour)
+	s.lastActive[testStreamS2] = now.Add(-time.Hour)
+
+	if changed := s.split(streams, now); !changed || !slices.Equal(s.hot, streams) || len(s.poll.args) != 0 {
+		t.Errorf("split() = %v, hot %v, poll %v; want every stream read and none polled", changed, s.hot, s.poll.args)
+	}
+	s.lastActive["gone"] = now
+	s.split(streams[:1], now)
+	if _, ok := s.lastActive["gone"]; ok || len(s.lastActive) != 1 {
+		t.Errorf("lastActive = %v; want the streams no longer read dropped", s.lastActive)
+	}
+}
+
+func TestNewIdleStreams_Off(t *testing.T) {
+	if newIdleStreams(&config.RedisConfig{}) != nil {
+		t.Error("newIdleStreams() != nil; want nil without REDIS_IDLE_STREAM_AFTER")
+	}
+	if newIdleStreams(&config.RedisConfig{IdleStreamAfter: time.Minute, Stream: "s"}) != nil {
+		t.Error("newIdleStreams() != nil; want nil in single-stream mode")
+	}
+	var s *idleStreams
+	s.touch(nil, time.Now())
+}
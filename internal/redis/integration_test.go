@@ -12,6 +12,7 @@ import (
 	"github.com/ibs-source/syslog-consumer/internal/config"
 	"github.com/ibs-source/syslog-consumer/internal/log"
 	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -36,7 +37,7 @@ func TestIntegration_RedisConnection(t *testing.T) {
 	logger := log.New()
 
 	t.Run("Connect", func(t *testing.T) {
-		client, err := NewClient(cfg, logger)
+		client, err := NewClient(t.Context(), cfg, logger)
 		if err != nil {
 			t.Skipf("Skipping Redis test: %v (Redis not available?)", err)
 			return
@@ -47,7 +48,7 @@ func TestIntegration_RedisConnection(t *testing.T) {
 	})
 
 	t.Run("EnsureGroups", func(t *testing.T) {
-		client, err := NewClient(cfg, logger)
+		client, err := NewClient(t.Context(), cfg, logger)
 		if err != nil {
 			t.Skip("Redis not available")
 			return
@@ -70,7 +71,7 @@ func TestIntegration_RedisOperations(t *testing.T) {
 	cfg.Stream = "test-stream-ops"
 	logger := log.New()
 
-	client, err := NewClient(cfg, logger)
+	client, err := NewClient(t.Context(), cfg, logger)
 	if err != nil {
 		t.Skip("Redis not available")
 		return
@@ -84,6 +85,7 @@ func TestIntegration_RedisOperations(t *testing.T) {
 	t.Run("AddAndReadMessage", func(t *testing.T) { testAddAndReadMessage(t, client, cfg) })
 	t.Run("ClaimIdle", func(t *testing.T) { testClaimIdle(t, client, cfg) })
 	t.Run("AckAndDelete_Multiple", func(t *testing.T) { testAckAndDeleteMultiple(t, client, cfg) })
+	t.Run("AckAndDelete_Statuses", func(t *testing.T) { testAckAndDeleteStatuses(t, client, cfg) })
 }
 
 func testReadBatchEmpty(t *testing.T, client *Client) {
@@ -153,6 +155,7 @@ func readMessageBatch(t *testing.T, client *Client) message.Batch {
 
 func parsePayload(t *testing.T, batch message.Batch) message.Redis {
 	t.Helper()
+	batch.Decode()
 	return batch.Items[0]
 }
 
@@ -181,7 +184,7 @@ func ackAndDeleteMessage(t *testing.T, client *Client, batch message.Batch) {
 	t.Helper()
 	ctx := t.Context()
 	item := batch.Items[0]
-	if err := client.AckAndDelete(ctx, item.ID, item.Stream); err != nil {
+	if err := client.AckAndDeleteBatch(ctx, []string{item.ID}, item.Stream); err != nil {
 		t.Fatalf("AckAndDelete failed: %v", err)
 	}
 }
@@ -222,7 +225,7 @@ func testClaimIdle(t *testing.T, client *Client, cfg *config.RedisConfig) {
 
 	if len(claimed.Items) > 0 {
 		for _, msg := range claimed.Items {
-			_ = client.AckAndDelete(ctx, msg)
+			_ = client.AckAndDeleteBatch(ctx, []string{msg.ID}, msg.Stream)
 		}
 	}
 }
@@ -254,7 +257,7 @@ func testAckAndDeleteMultiple(t *testing.T, client *Client, cfg *config.RedisCon
 	}
 
 	for _, msg := range batch.Items {
-		err = client.AckAndDelete(ctx, msg)
+		err = client.AckAndDeleteBatch(ctx, []string{msg.ID}, msg.Stream)
 		if err != nil {
 			t.Fatalf("AckAndDelete failed: %v", err)
 		}
@@ -262,13 +265,61 @@ func testAckAndDeleteMultiple(t *testing.T, client *Client, cfg *config.RedisCon
 	t.Log("Successfully acknowledged and deleted multiple messages")
 }
 
+// testAckAndDeleteStatuses acknowledges a pending, an already acknowledged
+// and a missing entry after flushing the script cache, so the EVAL fallback
+// runs too.
+func testAckAndDeleteStatuses(t *testing.T, client *Client, cfg *config.RedisConfig) {
+	t.Helper()
+	ctx := t.Context()
+
+	ids := make([]string, 2)
+	for i := range ids {
+		id, err := client.rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: cfg.Stream,
+			Values: map[string]any{"object": "status test"},
+		}).Result()
+		if err != nil {
+			t.Fatalf("Failed to add message %d: %v", i, err)
+		}
+		ids[i] = id
+	}
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	batch, err := client.ReadBatch(readCtx)
+	cancel()
+	if err != nil || len(batch.Items) != 2 {
+		t.Fatalf("ReadBatch = %d items (err %v); want 2", len(batch.Items), err)
+	}
+	if err := client.rdb.XAck(ctx, cfg.Stream, client.groupName, ids[1]).Err(); err != nil {
+		t.Fatalf("XAck failed: %v", err)
+	}
+	if err := client.rdb.ScriptFlush(ctx).Err(); err != nil {
+		t.Fatalf("SCRIPT FLUSH failed: %v", err)
+	}
+
+	alreadyAckedBefore := metrics.AcksAlreadyAcked.Value()
+	missingBefore := metrics.AcksMissing.Value()
+	if err := client.AckAndDeleteBatch(ctx, append(ids, "1-1"), cfg.Stream); err != nil {
+		t.Fatalf("AckAndDeleteBatch failed: %v", err)
+	}
+	if got := metrics.AcksAlreadyAcked.Value() - alreadyAckedBefore; got != 1 {
+		t.Errorf("AcksAlreadyAcked delta = %d; want 1", got)
+	}
+	if got := metrics.AcksMissing.Value() - missingBefore; got != 1 {
+		t.Errorf("AcksMissing delta = %d; want 1", got)
+	}
+	entries, err := client.rdb.XRange(ctx, cfg.Stream, ids[0], ids[1]).Result()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("XRange = %d entries (err %v); want both deleted", len(entries), err)
+	}
+}
+
 // TestIntegration_RedisClose tests client cleanup
 func TestIntegration_RedisClose(t *testing.T) {
 	cfg := setupRedisConfig(t)
 	cfg.Stream = "test-stream-close"
 	logger := log.New()
 
-	client, err := NewClient(cfg, logger)
+	client, err := NewClient(t.Context(), cfg, logger)
 	if err != nil {
 		t.Skip("Redis not available")
 		return
@@ -294,7 +345,7 @@ func TestIntegration_RedisCleanup(t *testing.T) {
 	cfg.Stream = "test-stream-cleanup"
 	logger := log.New()
 
-	client, err := NewClient(cfg, logger)
+	client, err := NewClient(t.Context(), cfg, logger)
 	if err != nil {
 		t.Skip("Redis not available")
 		return
@@ -400,7 +451,7 @@ func TestIntegration_RedisErrors(t *testing.T) {
 		badCfg := *cfg
 		badCfg.Address = "invalid:99999"
 
-		_, err := NewClient(&badCfg, logger)
+		_, err := NewClient(t.Context(), &badCfg, logger)
 		if err == nil {
 			t.Error("Expected error for invalid address, got nil")
 		}
@@ -413,7 +464,7 @@ func TestIntegration_RedisErrors(t *testing.T) {
 		badCfg.Address = "10.255.255.1:6379"
 		badCfg.PingTimeout = 100 * time.Millisecond
 
-		_, err := NewClient(&badCfg, logger)
+		_, err := NewClient(t.Context(), &badCfg, logger)
 		if err == nil {
 			t.Error("Expected timeout error, got nil")
 		}
@@ -421,3 +472,46 @@ func TestIntegration_RedisErrors(t *testing.T) {
 		t.Logf("Correctly handled connection timeout: %v", err)
 	})
 }
+
+// TestIntegration_RedisGroupReset moves the group back to an entry, as
+// miniredis cannot.
+func TestIntegration_RedisGroupReset(t *testing.T) {
+	cfg := setupRedisConfig(t)
+	cfg.Stream = "test-stream-reset"
+	cfg.DeadLetterStream = "test-stream-reset-dlq"
+	client, err := NewClient(t.Context(), cfg, log.New())
+	if err != nil {
+		t.Skip("Redis not available")
+		return
+	}
+	defer func() { _ = client.Close() }()
+	ctx := t.Context()
+	defer client.rdb.Del(ctx, cfg.Stream, cfg.DeadLetterStream)
+
+	ids := make([]string, 3)
+	for i := range ids {
+		if ids[i], err = client.AddEntry(ctx, cfg.Stream, "object", "{}", "raw", "reset"); err != nil {
+			t.Fatalf("AddEntry() error = %v", err)
+		}
+	}
+	if err := client.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: cfg.GroupName, Consumer: "gone", Streams: []string{cfg.Stream, ">"}, Count: 3, Block: -1,
+	}).Err(); err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+
+	reset, err := client.ResetGroup(ctx, cfg.Stream, ids[0], &GroupResetOptions{DeadLetterPending: true})
+	if err != nil {
+		t.Fatalf("ResetGroup() error = %v", err)
+	}
+	if reset.Pending != 3 || reset.DeadLettered != 3 {
+		t.Errorf("reset = %+v; want 3 pending, 3 dead-lettered", reset)
+	}
+	if _, err := client.ResetGroup(ctx, cfg.Stream, "0", &GroupResetOptions{}); err != nil {
+		t.Fatalf("ResetGroup(0) error = %v", err)
+	}
+	groups, err := client.rdb.XInfoGroups(ctx, cfg.Stream).Result()
+	if err != nil || len(groups) != 1 || groups[0].LastDeliveredID != "0-0" || groups[0].Pending != 0 {
+		t.Errorf("groups = %+v (err %v); want the group at 0-0 with nothing pending", groups, err)
+	}
+}
@@ -14,7 +14,8 @@ import (
 type StreamClient interface {
 	ReadBatch(ctx context.Context) (message.Batch, error)
 	ClaimIdle(ctx context.Context) (message.Batch, error)
-	// AckAndDeleteBatch issues XACK + XDEL in a single pipeline round-trip.
+	// AckAndDeleteBatch acknowledges and deletes ids of stream atomically,
+	// in one round-trip.
 	AckAndDeleteBatch(ctx context.Context, ids []string, stream string) error
 	CleanupDeadConsumers(ctx context.Context, idleTimeout time.Duration) error
 	// RefreshStreams rediscovers streams in multi-stream mode and returns the
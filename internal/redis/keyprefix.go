@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// Key returns name under REDIS_KEY_PREFIX. An empty name stays empty: it
+// turns off the feature it names.
+func Key(cfg *config.RedisConfig, name string) string {
+	if name == "" {
+		return ""
+	}
+	return cfg.KeyPrefix + name
+}
+
+// scanPattern is the SCAN MATCH pattern of the keys under prefix, with the
+// glob characters of prefix escaped.
+func scanPattern(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('*')
+	return b.String()
+}
+
+// groupStart returns the ID the consumer group starts after when it is
+// created on stream. It is 0 unless REDIS_KEY_PREFIX renamed the group and
+// the group of the old name exists on stream. The new group then takes
+// over from it: it starts just before the oldest entry pending in the old
+// group, or after the last entry the old group was delivered when nothing
+// is pending, so switching to the prefix neither skips entries nor reads
+// acknowledged ones again, as those are deleted.
+func (c *Client) groupStart(ctx context.Context, stream string) (string, error) {
+	if c.legacyGroup == "" {
+		return "0", nil
+	}
+	infos, err := c.rdb.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return "0", nil
+		}
+		return "", fmt.Errorf("failed to get groups info of %s: %w", stream, err)
+	}
+	lastDelivered := ""
+	for i := range infos {
+		switch infos[i].Name {
+		case c.groupName:
+			return "0", nil // joined, not created
+		case c.legacyGroup:
+			lastDelivered = infos[i].LastDeliveredID
+		}
+	}
+	if lastDelivered == "" {
+		return "0", nil
+	}
+	summary, err := c.rdb.XPending(ctx, stream, c.legacyGroup).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get pending summary of %s: %w", stream, err)
+	}
+	if summary.Count == 0 {
+		return lastDelivered, nil
+	}
+	return previousStreamID(summary.Lower), nil
+}
+
+// previousStreamID returns the largest entry ID before id, which must not
+// be 0-0.
+func previousStreamID(id string) string {
+	ms, seq := parseStreamID(id)
+	if seq == 0 {
+		ms, seq = ms-1, math.MaxUint64
+	} else {
+		seq--
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq, 10)
+}
@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestKey(t *testing.T) {
+	cfg := &config.RedisConfig{KeyPrefix: "tenant-a:"}
+	if got := Key(cfg, "syslog"); got != "tenant-a:syslog" {
+		t.Errorf("Key(syslog) = %q; want tenant-a:syslog", got)
+	}
+	if got := Key(cfg, ""); got != "" {
+		t.Errorf("Key(\"\") = %q; want empty", got)
+	}
+	if got := Key(&config.RedisConfig{}, "syslog"); got != "syslog" {
+		t.Errorf("Key(syslog) without prefix = %q; want syslog", got)
+	}
+}
+
+func TestScanPattern(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"":          "*",
+		"tenant-a:": "tenant-a:*",
+		`a*b?[c]\`:  `a\*b\?\[c\]\\*`,
+	} {
+		if got := scanPattern(prefix); got != want {
+			t.Errorf("scanPattern(%q) = %q; want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestPreviousStreamID(t *testing.T) {
+	for id, want := range map[string]string{
+		"1700000000000-3": "1700000000000-2",
+		"1700000000000-0": "1699999999999-18446744073709551615",
+	} {
+		if got := previousStreamID(id); got != want {
+			t.Errorf("previousStreamID(%q) = %q; want %q", id, got, want)
+		}
+	}
+}
+
+func TestDiscoverStreams_KeyPrefix(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.scanMatch = scanPattern("tenant-a:")
+
+	mustXAdd(t, s, "tenant-a:syslog", "k", "v")
+	mustXAdd(t, s, "tenant-b:syslog", "k", "v")
+	mustXAdd(t, s, "syslog", "k", "v")
+
+	streams, err := c.DiscoverStreams(t.Context())
+	if err != nil {
+		t.Fatalf("DiscoverStreams() error = %v", err)
+	}
+	if len(streams) != 1 || streams[0] != "tenant-a:syslog" {
+		t.Errorf("DiscoverStreams() = %v; want [tenant-a:syslog]", streams)
+	}
+}
+
+// prefixedClient returns a client whose group was renamed by the prefix
+// tenant-a: from testGroupName.
+func prefixedClient(t *testing.T) *Client {
+	t.Helper()
+	c := newTestClient(t, startMiniredis(t), testStreamS1)
+	c.legacyGroup = testGroupName
+	c.groupName = "tenant-a:" + testGroupName
+	return c
+}
+
+// legacyRead has the legacy group read n entries of testStreamS1.
+func legacyRead(t *testing.T, c *Client, n int64) {
+	t.Helper()
+	if err := c.rdb.XGroupCreate(t.Context(), testStreamS1, c.legacyGroup, "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	if err := c.rdb.XReadGroup(t.Context(), &goredis.XReadGroupArgs{
+		Group: c.legacyGroup, Consumer: "old", Streams: []string{testStreamS1, ">"}, Count: n, Block: -1,
+	}).Err(); err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+}
+
+// addEntry adds an entry with raw to testStreamS1 and returns its ID.
+func addEntry(t *testing.T, c *Client, raw string) string {
+	t.Helper()
+	id, err := c.rdb.XAdd(t.Context(), &goredis.XAddArgs{Stream: testStreamS1, Values: []string{"raw", raw}}).Result()
+	if err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+	return id
+}
+
+func TestGroupStart_NoPrefix(t *testing.T) {
+	c := newTestClient(t, startMiniredis(t), testStreamS1)
+	if got, err := c.groupStart(t.Context(), testStreamS1); err != nil || got != "0" {
+		t.Errorf("groupStart() = %q (err %v); want 0", got, err)
+	}
+}
+
+func TestGroupStart_NoLegacyGroup(t *testing.T) {
+	c := prefixedClient(t)
+	if got, err := c.groupStart(t.Context(), testStreamS1); err != nil || got != "0" {
+		t.Errorf("groupStart() on a missing stream = %q (err %v); want 0", got, err)
+	}
+	addEntry(t, c, "a")
+	if got, err := c.groupStart(t.Context(), testStreamS1); err != nil || got != "0" {
+		t.Errorf("groupStart() without the legacy group = %q (err %v); want 0", got, err)
+	}
+}
+
+func TestGroupStart_MigratesPendingEntries(t *testing.T) {
+	c := prefixedClient(t)
+	ids := make([]string, 0, 3)
+	for _, raw := range []string{"a", "b", "c"} {
+		ids = append(ids, addEntry(t, c, raw))
+	}
+	legacyRead(t, c, 2)
+
+	start, err := c.groupStart(t.Context(), testStreamS1)
+	if err != nil || start != previousStreamID(ids[0]) {
+		t.Fatalf("groupStart() = %q (err %v); want just before %s", start, err, ids[0])
+	}
+	mustEnsureGroups(t, c, testStreamS1)
+	streams, err := c.rdb.XReadGroup(t.Context(), &goredis.XReadGroupArgs{
+		Group: c.groupName, Consumer: "new", Streams: []string{testStreamS1, ">"}, Count: 10, Block: -1,
+	}).Result()
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 3 {
+		t.Errorf("delivered to the new group = %v (err %v); want the 3 entries", streams, err)
+	}
+}
+
+func TestGroupStart_NothingPending(t *testing.T) {
+	c := prefixedClient(t)
+	ids := make([]string, 0, 2)
+	for _, raw := range []string{"a", "b"} {
+		ids = append(ids, addEntry(t, c, raw))
+	}
+	legacyRead(t, c, 2)
+	if err := c.rdb.XAck(t.Context(), testStreamS1, c.legacyGroup, ids...).Err(); err != nil {
+		t.Fatalf("XAck() error = %v", err)
+	}
+
+	if start, err := c.groupStart(t.Context(), testStreamS1); err != nil || start != ids[1] {
+		t.Errorf("groupStart() = %q (err %v); want the last delivered %s", start, err, ids[1])
+	}
+}
+
+func TestGroupStart_GroupAlreadyMigrated(t *testing.T) {
+	c := prefixedClient(t)
+	addEntry(t, c, "a")
+	legacyRead(t, c, 1)
+	if err := c.rdb.XGroupCreate(t.Context(), testStreamS1, c.groupName, "$").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	if start, err := c.groupStart(t.Context(), testStreamS1); err != nil || start != "0" {
+		t.Errorf("groupStart() = %q (err %v); want 0 for a group that exists", start, err)
+	}
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// minLagVersion is the first Redis release whose XINFO GROUPS reports
+// entries-read and lag.
+const minLagVersion = "7.0.0"
+
+// States of Client.lagSupport.
+const (
+	lagUnknown int32 = iota
+	lagReported
+	lagEstimated
+)
+
+// GroupLag is the consumer group's backlog on one stream.
+type GroupLag struct {
+	Stream string `json:"stream"`
+	// Lag is how many entries were not delivered to the group yet.
+	Lag int64 `json:"lag"`
+	// Pending is how many were delivered but not acknowledged yet.
+	Pending int64 `json:"pending"`
+	// EntriesRead is the group's read counter, or -1 when the server does
+	// not report it.
+	EntriesRead int64 `json:"entries_read"`
+	// Exact is false when Lag is estimated, as the stream length less the
+	// pending entries: on servers older than Redis 7, or when Redis cannot
+	// tell the lag. The estimate holds since acknowledged entries are
+	// deleted.
+	Exact bool `json:"exact"`
+}
+
+// GroupLags returns the consumer group's lag on every stream consumed,
+// read from REDIS_REPLICA_ADDRESS when it is set. Streams that no longer
+// exist or have no group yet are left out.
+func (c *Client) GroupLags(ctx context.Context) ([]GroupLag, error) {
+	c.mu.RLock()
+	streams := c.streams
+	c.mu.RUnlock()
+
+	reported := c.lagReported(ctx)
+	lags := make([]GroupLag, 0, len(streams))
+	for _, stream := range streams {
+		lag, found, err := c.groupLag(ctx, stream, reported)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			lags = append(lags, lag)
+		}
+	}
+	return lags, nil
+}
+
+func (c *Client) groupLag(ctx context.Context, stream string, reported bool) (GroupLag, bool, error) {
+	infos, err := c.reader().XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return GroupLag{}, false, nil
+		}
+		return GroupLag{}, false, fmt.Errorf("failed to get groups info of %s: %w", stream, err)
+	}
+	i := slices.IndexFunc(infos, func(g redis.XInfoGroup) bool { return g.Name == c.groupName })
+	if i < 0 {
+		return GroupLag{}, false, nil
+	}
+	group := &infos[i]
+	lag := GroupLag{Stream: stream, Lag: group.Lag, Pending: group.Pending, EntriesRead: group.EntriesRead, Exact: true}
+	if reported && group.Lag >= 0 {
+		return lag, true, nil
+	}
+	if !reported {
+		lag.EntriesRead = -1
+	}
+	length, err := c.reader().XLen(ctx, stream).Result()
+	if err != nil {
+		return GroupLag{}, false, fmt.Errorf("failed to get length of %s: %w", stream, err)
+	}
+	lag.Lag, lag.Exact = max(length-group.Pending, 0), false
+	return lag, true, nil
+}
+
+// lagReported reports whether XINFO GROUPS carries the lag, finding out
+// from the server version the first time. A server that hides its version
+// gets the estimate; a failed INFO is retried on the next call.
+func (c *Client) lagReported(ctx context.Context) bool {
+	switch c.lagSupport.Load() {
+	case lagReported:
+		return true
+	case lagEstimated:
+		return false
+	}
+	info, err := c.reader().Info(ctx, "server").Result()
+	if err != nil && !strings.Contains(err.Error(), "not supported") {
+		return false
+	}
+	version := infoField(info, "redis_version")
+	if version != "" && !olderThan(version, minLagVersion) {
+		c.lagSupport.Store(lagReported)
+		return true
+	}
+	c.lagSupport.Store(lagEstimated)
+	c.log.Warnf(ctx, "Redis %s does not report consumer group lag; estimating it from the stream length",
+		cmp.Or(version, "of unknown version"))
+	return false
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestGroupLags(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.streams = []string{testStreamS1, "gone", "foreign"}
+	for range 5 {
+		mustXAdd(t, s, testStreamS1, "object", "{}")
+	}
+	mustXAdd(t, s, "foreign", "object", "{}")
+	if err := c.rdb.XGroupCreate(t.Context(), testStreamS1, testGroupName, "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	err := c.rdb.XReadGroup(t.Context(), &goredis.XReadGroupArgs{
+		Group: testGroupName, Consumer: c.consumer, Streams: []string{testStreamS1, ">"}, Count: 2,
+	}).Err()
+	if err != nil {
+		t.Fatalf("XReadGroup() error = %v", err)
+	}
+
+	// miniredis does not report its version, so the lag is estimated.
+	lags, err := c.GroupLags(t.Context())
+	if err != nil {
+		t.Fatalf("GroupLags() error = %v", err)
+	}
+	want := GroupLag{Stream: testStreamS1, Lag: 3, Pending: 2, EntriesRead: -1}
+	if len(lags) != 1 || lags[0] != want {
+		t.Errorf("GroupLags() = %+v; want only %+v", lags, want)
+	}
+
+	c.lagSupport.Store(lagReported)
+	lags, err = c.GroupLags(t.Context())
+	if err != nil {
+		t.Fatalf("GroupLags() error = %v", err)
+	}
+	// miniredis reports the stream length as the lag.
+	if len(lags) != 1 || !lags[0].Exact || lags[0].Lag != 5 || lags[0].EntriesRead != 0 {
+		t.Errorf("GroupLags() = %+v; want the lag XINFO GROUPS reports", lags)
+	}
+}
+
+func TestLagReported(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	if c.lagReported(t.Context()) || c.lagSupport.Load() != lagEstimated {
+		t.Errorf("lagReported() = true, state %d; want the estimate without a server version", c.lagSupport.Load())
+	}
+	for version, want := range map[string]bool{"6.2.14": false, "7.0.0": true, "7.4.1": true} {
+		if got := !olderThan(version, minLagVersion); got != want {
+			t.Errorf("Redis %s reports the lag = %v; want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// leaseScript takes the lease of a stream when nobody holds it, or renews
+// it when the caller does. KEYS[1] is the lease key, ARGV[1] the caller's
+// consumer name, ARGV[2] the TTL in milliseconds. It returns 1 when the
+// caller holds the lease.
+var leaseScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if holder then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseLeaseScript deletes the lease KEYS[1] if ARGV[1] holds it.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// streamLeases are the REDIS_STREAM_LEASE leases the consumer holds, each
+// with the time until which it counts as held: the start of its latest
+// renewal plus the TTL, less a block timeout so that a read sent before
+// that time has returned when the lease expires. held is guarded by
+// Client.mu and only replaced by RenewLeases and ReleaseLeases.
+type streamLeases struct {
+	held   map[string]time.Time
+	ttl    time.Duration
+	margin time.Duration
+}
+
+func newStreamLeases(cfg *config.RedisConfig) *streamLeases {
+	if !cfg.StreamLease {
+		return nil
+	}
+	return &streamLeases{held: make(map[string]time.Time), ttl: cfg.StreamLeaseTTL, margin: cfg.BlockTimeout}
+}
+
+// filter returns the streams of streams whose lease is held, in order;
+// all of them on a nil streamLeases. The caller holds Client.mu.
+func (l *streamLeases) filter(streams []string) []string {
+	if l == nil {
+		return streams
+	}
+	held := make([]string, 0, len(l.held))
+	for _, stream := range streams {
+		if _, ok := l.held[stream]; ok {
+			held = append(held, stream)
+		}
+	}
+	return held
+}
+
+// update returns the leases held after a renewal that started at start,
+// whose replies to streams are cmds, and the streams whose lease was taken
+// or lost. A lease whose renewal failed stays held until its time is up.
+func (l *streamLeases) update(streams []string, cmds []*redis.Cmd, start time.Time) (
+	next map[string]time.Time, taken, lost []string, err error,
+) {
+	next = make(map[string]time.Time, len(streams))
+	for i, stream := range streams {
+		granted, cmdErr := cmds[i].Int()
+		until, had := l.held[stream]
+		switch {
+		case cmdErr != nil:
+			err = cmdErr
+			if had && start.Before(until) {
+				next[stream] = until
+			} else if had {
+				lost = append(lost, stream)
+			}
+		case granted == 1:
+			next[stream] = start.Add(l.ttl - l.margin)
+			if !had {
+				taken = append(taken, stream)
+			}
+		case had:
+			lost = append(lost, stream)
+		}
+	}
+	return next, taken, lost, err
+}
+
+// leaseKey is the REDIS_STREAM_LEASE key of stream for the consumer group,
+// under REDIS_KEY_PREFIX like the group name.
+func (c *Client) leaseKey(stream string) string {
+	group := strings.TrimPrefix(c.groupName, c.keyPrefix)
+	return c.keyPrefix + "syslog-consumer:" + group + ":lease:" + stream
+}
+
+// RenewLeases takes the lease of every stream the consumer would read that
+// no consumer of the group holds, renews the ones it holds, and releases
+// those of the streams it no longer reads. It returns the streams whose
+// lease it took and those it lost; from the next read on, ReadBatch and
+// ClaimIdle cover only the streams whose lease it holds. A lease lost
+// stops the reads of its stream before RenewLeases returns. A failed
+// renewal keeps each lease until it would expire. Like RefreshStreams, it
+// must not be called concurrently.
+func (c *Client) RenewLeases(ctx context.Context) (taken, lost []string, err error) {
+	if c.leases == nil {
+		return nil, nil, errors.New("cannot renew stream leases: stream leases are off")
+	}
+	c.mu.RLock()
+	streams := slices.Clone(c.streams)
+	var stale []string
+	for stream := range c.leases.held {
+		if !slices.Contains(streams, stream) {
+			stale = append(stale, stream)
+		}
+	}
+	c.mu.RUnlock()
+
+	start := time.Now()
+	ttl := strconv.FormatInt(c.leases.ttl.Milliseconds(), 10)
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(streams))
+	for i, stream := range streams {
+		cmds[i] = leaseScript.Eval(ctx, pipe, []string{c.leaseKey(stream)}, c.consumer, ttl)
+	}
+	for _, stream := range stale {
+		releaseLeaseScript.Eval(ctx, pipe, []string{c.leaseKey(stream)}, c.consumer)
+	}
+	_, _ = pipe.Exec(ctx) // errors are per command, read by update
+
+	next, taken, lost, err := c.leases.update(streams, cmds, start)
+	c.holdLeases(next, len(taken) > 0, len(lost) > 0)
+	metrics.StreamLeasesLost.Add(int64(len(lost)))
+	if err != nil {
+		return taken, lost, fmt.Errorf("failed to renew stream leases: %w", err)
+	}
+	return taken, lost, nil
+}
+
+// holdLeases replaces the leases held with next. When streams were taken
+// or dropped it changes the streams read, waiting for the read in flight
+// if any were dropped, and wakes a consumer waiting for streams.
+func (c *Client) holdLeases(next map[string]time.Time, taken, dropped bool) {
+	metrics.StreamLeasesHeld.Set(int64(len(next)))
+	if !taken && !dropped {
+		c.mu.Lock()
+		c.leases.held = next
+		c.mu.Unlock()
+		return
+	}
+	c.setStreams(dropped, func() { c.leases.held = next })
+	if taken {
+		select {
+		case c.streamsFound <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ReleaseLeases stops the reads of the streams whose lease the consumer
+// holds, then releases the leases so that a standby consumer takes the
+// streams over at its next renewal rather than when they expire.
+func (c *Client) ReleaseLeases(ctx context.Context) error {
+	if c.leases == nil {
+		return nil
+	}
+	c.mu.RLock()
+	streams := slices.Sorted(maps.Keys(c.leases.held))
+	c.mu.RUnlock()
+	c.holdLeases(map[string]time.Time{}, false, len(streams) > 0)
+
+	pipe := c.rdb.Pipeline()
+	for _, stream := range streams {
+		releaseLeaseScript.Eval(ctx, pipe, []string{c.leaseKey(stream)}, c.consumer)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to release %d stream leases: %w", len(streams), err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func newLeaseClient(t *testing.T, s *miniredis.Miniredis, consumer string) *Client {
+	t.Helper()
+	c := newTestClient(t, s, testStreamS1)
+	c.consumer = consumer
+	c.leases = newStreamLeases(&config.RedisConfig{
+		StreamLease: true, StreamLeaseTTL: 10 * time.Second, BlockTimeout: c.blockTimeout,
+	})
+	return c
+}
+
+func renew(t *testing.T, c *Client) (taken, lost []string) {
+	t.Helper()
+	taken, lost, err := c.RenewLeases(t.Context())
+	if err != nil {
+		t.Fatalf("RenewLeases() error = %v", err)
+	}
+	return taken, lost
+}
+
+func TestRenewLeases_OneHolderPerStream(t *testing.T) {
+	s := startMiniredis(t)
+	active := newLeaseClient(t, s, "active")
+	standby := newLeaseClient(t, s, "standby")
+	mustEnsureGroups(t, active, testStreamS1)
+	mustXAdd(t, s, testStreamS1, "k", "v")
+
+	if taken, _ := renew(t, active); !slices.Equal(taken, []string{testStreamS1}) {
+		t.Fatalf("active took %v; want [%s]", taken, testStreamS1)
+	}
+	if taken, _ := renew(t, standby); taken != nil {
+		t.Fatalf("standby took %v; want nothing while the lease is held", taken)
+	}
+	if holder, _ := s.Get(active.leaseKey(testStreamS1)); holder != "active" {
+		t.Errorf("lease holder = %q; want active", holder)
+	}
+
+	batch, err := standby.ReadBatch(t.Context())
+	if err != nil || len(batch.Items) != 0 {
+		t.Errorf("standby ReadBatch() = %d entries, %v; want none", len(batch.Items), err)
+	}
+	batch.Release()
+	batch, err = active.ReadBatch(t.Context())
+	if err != nil || len(batch.Items) != 1 {
+		t.Errorf("active ReadBatch() = %d entries, %v; want 1", len(batch.Items), err)
+	}
+	batch.Release()
+	if got := metrics.StreamLeasesHeld.Value(); got != 0 {
+		t.Errorf("StreamLeasesHeld = %d; want the standby's 0, set last", got)
+	}
+}
+
+func TestReleaseLeases_HandsOver(t *testing.T) {
+	s := startMiniredis(t)
+	active := newLeaseClient(t, s, "active")
+	standby := newLeaseClient(t, s, "standby")
+	renew(t, active)
+	renew(t, standby)
+
+	if err := active.ReleaseLeases(t.Context()); err != nil {
+		t.Fatalf("ReleaseLeases() error = %v", err)
+	}
+	if s.Exists(active.leaseKey(testStreamS1)) {
+		t.Error("lease key still set after the release")
+	}
+	if taken, _ := renew(t, standby); !slices.Equal(taken, []string{testStreamS1}) {
+		t.Errorf("standby took %v after the release; want [%s]", taken, testStreamS1)
+	}
+	if taken, _ := renew(t, active); taken != nil {
+		t.Errorf("active took %v back; want the standby to keep it", taken)
+	}
+	if err := active.ReleaseLeases(t.Context()); err != nil {
+		t.Fatalf("ReleaseLeases() without leases error = %v", err)
+	}
+	if holder, _ := s.Get(standby.leaseKey(testStreamS1)); holder != "standby" {
+		t.Errorf("lease holder = %q; want standby, untouched by the other's release", holder)
+	}
+}
+
+func TestRenewLeases_ExpiredLeaseIsLost(t *testing.T) {
+	s := startMiniredis(t)
+	active := newLeaseClient(t, s, "active")
+	standby := newLeaseClient(t, s, "standby")
+	renew(t, active)
+
+	s.FastForward(10 * time.Second)
+	if taken, _ := renew(t, standby); !slices.Equal(taken, []string{testStreamS1}) {
+		t.Fatalf("standby took %v after the expiry; want [%s]", taken, testStreamS1)
+	}
+	before := metrics.StreamLeasesLost.Value()
+	if _, lost := renew(t, active); !slices.Equal(lost, []string{testStreamS1}) {
+		t.Errorf("active lost %v; want [%s]", lost, testStreamS1)
+	}
+	if got := metrics.StreamLeasesLost.Value() - before; got != 1 {
+		t.Errorf("StreamLeasesLost delta = %d; want 1", got)
+	}
+	if got := active.leases.filter([]string{testStreamS1}); len(got) != 0 {
+		t.Errorf("active still reads %v", got)
+	}
+}
+
+func TestRenewLeases_FailureKeepsLeasesUntilTheyExpire(t *testing.T) {
+	l := &streamLeases{ttl: 10 * time.Second, margin: time.Second}
+	start := time.Now()
+	l.held = map[string]time.Time{"kept": start.Add(time.Second), "expired": start}
+	failed := func() *goredis.Cmd {
+		cmd := goredis.NewCmd(t.Context())
+		cmd.SetErr(errors.New("connection refused"))
+		return cmd
+	}
+
+	next, taken, lost, err := l.update([]string{"kept", "expired", "new"},
+		[]*goredis.Cmd{failed(), failed(), failed()}, start)
+	if err == nil {
+		t.Error("update() error = nil; want the renewal's")
+	}
+	if _, ok := next["kept"]; !ok || len(next) != 1 || taken != nil || !slices.Equal(lost, []string{"expired"}) {
+		t.Errorf("update() = %v, taken %v, lost %v; want only the unexpired lease kept", next, taken, lost)
+	}
+}
+
+func TestRenewLeases_Off(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	if _, _, err := c.RenewLeases(t.Context()); err == nil {
+		t.Error("RenewLeases() error = nil; want one with stream leases off")
+	}
+	if err := c.ReleaseLeases(t.Context()); err != nil {
+		t.Errorf("ReleaseLeases() error = %v; want nil with stream leases off", err)
+	}
+}
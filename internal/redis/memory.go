@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MemoryUsage returns used_memory and maxmemory from INFO memory, in
+// bytes; limit is 0 when the server has no maxmemory.
+func (c *Client) MemoryUsage(ctx context.Context) (used, limit int64, err error) {
+	info, err := c.rdb.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read memory info: %w", err)
+	}
+	return parseMemoryInfo(info)
+}
+
+func parseMemoryInfo(info string) (used, limit int64, err error) {
+	used, err = strconv.ParseInt(infoField(info, "used_memory"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("INFO memory has no used_memory: %w", err)
+	}
+	limit, err = strconv.ParseInt(infoField(info, "maxmemory"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("INFO memory has no maxmemory: %w", err)
+	}
+	return used, limit, nil
+}
+
+// SignalMemoryPressure sets the memory pressure key to "1" for ttl while on
+// is true, so the key lapses when the consumer stops refreshing it, and
+// deletes it otherwise.
+func (c *Client) SignalMemoryPressure(ctx context.Context, on bool, ttl time.Duration) error {
+	if c.memoryPressureKey == "" {
+		return errors.New("cannot signal memory pressure: no memory pressure key configured")
+	}
+	if on {
+		return c.rdb.Set(ctx, c.memoryPressureKey, "1", ttl).Err()
+	}
+	return c.rdb.Del(ctx, c.memoryPressureKey).Err()
+}
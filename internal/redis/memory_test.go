@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMemoryInfo(t *testing.T) {
+	info := "# Memory\r\nused_memory:1048576\r\nused_memory_human:1.00M\r\nmaxmemory:4194304\r\n"
+	used, limit, err := parseMemoryInfo(info)
+	if err != nil || used != 1048576 || limit != 4194304 {
+		t.Errorf("parseMemoryInfo() = %d, %d, %v; want 1048576, 4194304", used, limit, err)
+	}
+	if _, _, err := parseMemoryInfo("# Memory\r\nused_memory_human:1.00M\r\nmaxmemory:0\r\n"); err == nil {
+		t.Error("parseMemoryInfo() without used_memory: nil error")
+	}
+	if _, _, err := parseMemoryInfo("# Memory\r\nused_memory:1\r\n"); err == nil {
+		t.Error("parseMemoryInfo() without maxmemory: nil error")
+	}
+}
+
+func TestSignalMemoryPressure(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	if err := c.SignalMemoryPressure(t.Context(), true, time.Second); err == nil {
+		t.Error("SignalMemoryPressure() without a key: nil error")
+	}
+
+	c.memoryPressureKey = "syslog:memory-pressure"
+	if err := c.SignalMemoryPressure(t.Context(), true, 15*time.Second); err != nil {
+		t.Fatalf("SignalMemoryPressure(on) error = %v", err)
+	}
+	if got, _ := s.Get(c.memoryPressureKey); got != "1" {
+		t.Errorf("key = %q; want 1 while under pressure", got)
+	}
+	if ttl := s.TTL(c.memoryPressureKey); ttl != 15*time.Second {
+		t.Errorf("TTL = %v; want 15s", ttl)
+	}
+	if err := c.SignalMemoryPressure(t.Context(), false, 0); err != nil {
+		t.Fatalf("SignalMemoryPressure(off) error = %v", err)
+	}
+	if s.Exists(c.memoryPressureKey) {
+		t.Error("key still set; want it deleted once the pressure clears")
+	}
+}
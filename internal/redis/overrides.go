@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// streamTuning is the part of a stream's REDIS_STREAM_OVERRIDES_FILE
+// override the client applies; zero fields keep the client's settings.
+type streamTuning struct {
+	claimIdle time.Duration
+	batchSize int64
+	priority  int
+}
+
+// streamRead is one XREADGROUP of ReadBatch: its streams followed by their
+// ">" IDs, and the COUNT of their batch size override, or 0 for the read
+// batch size.
+type streamRead struct {
+	args  []string
+	count int64
+}
+
+// tune resolves the overrides, checked by config.Validate, for streams and
+// returns them ordered by priority, highest first, with the tuning of the
+// streams an override matches. Without overrides it returns streams as is.
+func (c *Client) tune(streams []string) ([]string, map[string]streamTuning) {
+	if len(c.overrides) == 0 {
+		return streams, nil
+	}
+	tuning := make(map[string]streamTuning)
+	for _, stream := range streams {
+		if o, ok := config.MatchStreamOverride(c.overrides, stream); ok {
+			tuning[stream] = streamTuning{claimIdle: o.ClaimIdle, batchSize: int64(o.BatchSize), priority: o.Priority}
+		}
+	}
+	sorted := slices.Clone(streams)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return tuning[b].priority - tuning[a].priority
+	})
+	return sorted, tuning
+}
+
+// claimSettings returns the claim idle time and claim batch size of stream.
+func (c *Client) claimSettings(stream string) (time.Duration, int64) {
+	c.mu.RLock()
+	t := c.tuning[stream]
+	c.mu.RUnlock()
+	idle, count := c.claimIdle, c.batchSize
+	if t.claimIdle > 0 {
+		idle = t.claimIdle
+	}
+	if t.batchSize > 0 {
+		count = t.batchSize
+	}
+	return idle, count
+}
+
+// buildReads groups streams, in order, into one read per batch size. When
+// there are several, blockRead reads every stream with the smallest of the
+// sizes.
+func (c *Client) buildReads(streams []string, tuning map[string]streamTuning) {
+	c.reads = c.reads[:0]
+	index := make(map[int64]int)
+	for _, stream := range streams {
+		count := tuning[stream].batchSize
+		i, ok := index[count]
+		if !ok {
+			i = len(c.reads)
+			index[count] = i
+			c.reads = append(c.reads, streamRead{count: count})
+		}
+		c.reads[i].args = append(c.reads[i].args, stream)
+	}
+	for i := range c.reads {
+		c.reads[i].args = appendNewIDs(c.reads[i].args)
+	}
+	if len(c.reads) < 2 {
+		return
+	}
+	c.blockRead = streamRead{args: appendNewIDs(slices.Clone(streams)), count: c.reads[0].count}
+	for _, r := range c.reads[1:] {
+		if c.blockRead.count == 0 || (r.count > 0 && r.count < c.blockRead.count) {
+			c.blockRead.count = r.count
+		}
+	}
+}
+
+// appendNewIDs appends a ">" ID for each stream of args.
+func appendNewIDs(args []string) []string {
+	for range len(args) {
+		args = append(args, ">")
+	}
+	return args
+}
+
+// readGroup runs r's XREADGROUP, blocking up to block when it is not
+// negative.
+func (c *Client) readGroup(ctx context.Context, r *streamRead, block time.Duration) ([]redis.XStream, error) {
+	count := r.count
+	if count == 0 {
+		count = c.readCount.Load()
+	}
+	return c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.groupName,
+		Consumer: c.consumer,
+		Streams:  r.args,
+		Count:    count,
+		Block:    block,
+		NoAck:    c.noAck,
+	}).Result()
+}
+
+// readTuned reads streams whose batch size overrides differ: COUNT applies
+// to every stream of an XREADGROUP, so each batch size gets a read that
+// does not block, and only when they all come back empty does blockRead
+// wait for new entries.
+func (c *Client) readTuned(ctx context.Context) ([]redis.XStream, error) {
+	var result []redis.XStream
+	for i := range c.reads {
+		res, err := c.readGroup(ctx, &c.reads[i], -1)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		result = append(result, res...)
+	}
+	if len(result) > 0 {
+		return result, nil
+	}
+	return c.readGroup(ctx, &c.blockRead, c.blockTimeout)
+}
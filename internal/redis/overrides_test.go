@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// newOverridesClient returns a multi-stream client reading s1, s2 and s3,
+// three entries each: s1 first with a batch size of 1000, then s3 with a
+// batch size of 1, then s2, which leaves its entries pending for an hour.
+func newOverridesClient(t *testing.T) *Client {
+	t.Helper()
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.claimIdle = 0
+	c.overrides = []config.StreamOverride{
+		{Pattern: "s3", BatchSize: 1, Priority: 5, QoS: -1},
+		{Pattern: testStreamS2, ClaimIdle: time.Hour, QoS: -1},
+		{Pattern: "s*", BatchSize: 1000, Priority: 9, QoS: -1}, // s1 only; the others matched first
+	}
+	streams := []string{testStreamS1, testStreamS2, "s3"}
+	for _, stream := range streams {
+		for range 3 {
+			mustXAdd(t, s, stream, "source", stream)
+		}
+	}
+	mustEnsureGroups(t, c, streams...)
+	c.streams, c.tuning = c.tune(streams)
+	c.streamsArgDirty.Store(true)
+	return c
+}
+
+func TestTune_OrdersByPriority(t *testing.T) {
+	c := newOverridesClient(t)
+	if want := []string{testStreamS1, "s3", testStreamS2}; !slices.Equal(c.streams, want) {
+		t.Errorf("streams = %v; want %v, highest priority first", c.streams, want)
+	}
+	if idle, count := c.claimSettings(testStreamS2); idle != time.Hour || count != c.batchSize {
+		t.Errorf("claimSettings(s2) = %s, %d; want 1h, %d", idle, count, c.batchSize)
+	}
+	if idle, count := c.claimSettings("s3"); idle != 0 || count != 1 {
+		t.Errorf("claimSettings(s3) = %s, %d; want 0s, 1", idle, count)
+	}
+
+	c.overrides = nil
+	if streams, tuning := c.tune([]string{"b", "a"}); !slices.Equal(streams, []string{"b", "a"}) || tuning != nil {
+		t.Errorf("tune() without overrides = %v, %v; want the streams as is", streams, tuning)
+	}
+}
+
+func TestReadBatch_PerStreamBatchSize(t *testing.T) {
+	c := newOverridesClient(t)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	defer batch.Release()
+	counts := make(map[string]int)
+	for _, msg := range batch.Items {
+		counts[msg.Stream]++
+	}
+	if counts["s3"] != 1 || counts[testStreamS1] != 3 || counts[testStreamS2] != 3 {
+		t.Errorf("entries per stream = %v; want 1 from s3 and 3 from the others", counts)
+	}
+	if c.blockRead.count != 1 || len(c.reads) != 3 {
+		t.Errorf("reads = %d, blocking count %d; want 3 reads, blocking with the smallest count 1",
+			len(c.reads), c.blockRead.count)
+	}
+}
+
+func TestClaimIdle_PerStreamClaimIdle(t *testing.T) {
+	c := newOverridesClient(t)
+	for range 3 {
+		mustReadBatch(t, c)
+	}
+	pending, err := c.getPendingMessages(t.Context(), testStreamS1)
+	if err != nil || len(pending) != 3 {
+		t.Errorf("getPendingMessages(s1) = %d entries, %v; want all 3 idle past the default", len(pending), err)
+	}
+	pending, err = c.getPendingMessages(t.Context(), testStreamS2)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("getPendingMessages(s2) = %d entries, %v; want none idle for its hour", len(pending), err)
+	}
+}
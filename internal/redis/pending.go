@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// pendingReadThrough marks a stream whose pending entries ReadPending has
+// read to the end.
+const pendingReadThrough = "$"
+
+// ReadPending returns the next batch of entries delivered to this consumer
+// but never acknowledged, oldest first, one stream at a time. Each call
+// resumes after the last entry the previous one returned, and an empty
+// batch means every stream has been read through. It stops at the last
+// entry delivered to the group before its first read of the stream, so
+// entries ReadBatch delivers meanwhile are not read twice. Entries deleted
+// since their delivery are acknowledged and left out. Like ReadBatch, it
+// must only be called from a single goroutine.
+func (c *Client) ReadPending(ctx context.Context) (message.Batch, error) {
+	c.mu.RLock()
+	streams := c.streams
+	c.mu.RUnlock()
+
+	if c.pendingAfter == nil {
+		c.pendingAfter = make(map[string]string, len(streams))
+	}
+	if c.pendingUntil == nil {
+		c.pendingUntil = make(map[string]string, len(streams))
+	}
+	for _, stream := range streams {
+		for c.pendingAfter[stream] != pendingReadThrough {
+			batch, err := c.readPendingStream(ctx, stream)
+			if err != nil || len(batch.Items) > 0 {
+				return batch, err
+			}
+		}
+	}
+	return message.Batch{}, nil
+}
+
+func (c *Client) readPendingStream(ctx context.Context, stream string) (message.Batch, error) {
+	until, ok := c.pendingUntil[stream]
+	if !ok {
+		var err error
+		if until, err = c.lastDelivered(ctx, stream); err != nil {
+			return message.Batch{}, err
+		}
+		c.pendingUntil[stream] = until
+	}
+	after := c.pendingAfter[stream]
+	if after == "" {
+		after = "0"
+	}
+	// An explicit ID reads the consumer's own pending entries and never
+	// blocks; Block -1 leaves the BLOCK argument out.
+	result, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.groupName,
+		Consumer: c.consumer,
+		Streams:  []string{stream, after},
+		Count:    c.batchSize,
+		Block:    -1,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return message.Batch{}, fmt.Errorf("xreadgroup pending %s: %w", stream, err)
+	}
+	if len(result) == 0 || len(result[0].Messages) == 0 {
+		c.pendingAfter[stream] = pendingReadThrough
+		return message.Batch{}, nil
+	}
+
+	msgs := result[0].Messages
+	c.pendingAfter[stream] = msgs[len(msgs)-1].ID
+	if n := pendingThrough(msgs, until); n < len(msgs) {
+		msgs = msgs[:n]
+		c.pendingAfter[stream] = pendingReadThrough
+		if n == 0 {
+			return message.Batch{}, nil
+		}
+	}
+	buf := c.getBuffer(&c.batchPool)
+	var deleted []string
+	var dropped projected
+	for i := range msgs {
+		if msgs[i].Values == nil {
+			deleted = append(deleted, msgs[i].ID)
+			continue
+		}
+		c.readFields.apply(msgs[i].Values, &dropped)
+		buf.Add(msgs[i].ID, stream, msgs[i].Values)
+	}
+	dropped.report()
+	if len(deleted) > 0 {
+		if err := c.rdb.XAck(ctx, stream, c.groupName, deleted...).Err(); err != nil {
+			c.log.Warnf(ctx, "failed to acknowledge %d deleted pending entries on %s: %v", len(deleted), stream, err)
+		}
+	}
+
+	batch := message.NewLazyBatch(buf, &c.batchPool)
+	if len(batch.Items) == 0 {
+		batch.Release()
+		return message.Batch{}, nil
+	}
+	return batch, nil
+}
+
+// lastDelivered returns the ID of the last entry delivered to the group on
+// stream, or an empty ID when the group or the stream does not exist.
+func (c *Client) lastDelivered(ctx context.Context, stream string) (string, error) {
+	infos, err := c.rdb.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get groups info of %s: %w", stream, err)
+	}
+	for i := range infos {
+		if infos[i].Name == c.groupName {
+			return infos[i].LastDeliveredID, nil
+		}
+	}
+	return "", nil
+}
+
+// pendingThrough returns how many of msgs, in ID order, are not past
+// until; all of them when until is empty.
+func pendingThrough(msgs []redis.XMessage, until string) int {
+	if until == "" {
+		return len(msgs)
+	}
+	for i := range msgs {
+		if compareStreamIDs(msgs[i].ID, until) > 0 {
+			return i
+		}
+	}
+	return len(msgs)
+}
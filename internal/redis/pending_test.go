@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+)
+
+func pendingIDs(t *testing.T, c *Client) []string {
+	t.Helper()
+	batch, err := c.ReadPending(t.Context())
+	if err != nil {
+		t.Fatalf("ReadPending() error = %v", err)
+	}
+	defer batch.Release()
+	ids := make([]string, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestReadPending_ReplaysOwnEntriesOldestFirst(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	first := mustXAdd(t, s, testStreamS1, "raw", "a")
+	second := mustXAdd(t, s, testStreamS1, "raw", "b")
+	third := mustXAdd(t, s, testStreamS1, "raw", "c")
+	mustEnsureGroups(t, c, testStreamS1)
+
+	// Deliver everything without acknowledging it, as before an outage.
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	batch.Release()
+	mustXAdd(t, s, testStreamS1, "raw", "not delivered yet")
+
+	// miniredis ignores COUNT on history reads, so resume from a cursor
+	// the way a COUNT-limited read would leave it.
+	c.pendingAfter = map[string]string{testStreamS1: first}
+	if got := pendingIDs(t, c); !slices.Equal(got, []string{second, third}) {
+		t.Errorf("ReadPending() = %v; want [%s %s] after %s", got, second, third, first)
+	}
+	if got := pendingIDs(t, c); len(got) != 0 {
+		t.Errorf("ReadPending() = %v; want none once read through", got)
+	}
+
+	c.pendingAfter = nil
+	if got := pendingIDs(t, c); !slices.Equal(got, []string{first, second, third}) {
+		t.Errorf("ReadPending() from the start = %v; want [%s %s %s]", got, first, second, third)
+	}
+}
+
+func TestReadPending_AcksDeletedEntries(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	gone := mustXAdd(t, s, testStreamS1, "raw", "a")
+	kept := mustXAdd(t, s, testStreamS1, "raw", "b")
+	mustEnsureGroups(t, c, testStreamS1)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	batch.Release()
+	if err := c.rdb.XDel(t.Context(), testStreamS1, gone).Err(); err != nil {
+		t.Fatalf("XDel() error = %v", err)
+	}
+
+	if got := pendingIDs(t, c); !slices.Equal(got, []string{kept}) {
+		t.Errorf("ReadPending() = %v; want only %s", got, kept)
+	}
+	pending, err := c.rdb.XPending(t.Context(), testStreamS1, testGroupName).Result()
+	if err != nil {
+		t.Fatalf("XPending() error = %v", err)
+	}
+	if pending.Count != 1 {
+		t.Errorf("pending = %d; want the deleted entry acknowledged", pending.Count)
+	}
+}
+
+func TestReadPending_StopsAtEntriesDeliveredSinceTheFirstRead(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	first := mustXAdd(t, s, testStreamS1, "raw", "a")
+	second := mustXAdd(t, s, testStreamS1, "raw", "b")
+	mustEnsureGroups(t, c, testStreamS1)
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	batch.Release()
+
+	c.pendingAfter = map[string]string{testStreamS1: first}
+	if got := pendingIDs(t, c); !slices.Equal(got, []string{second}) {
+		t.Fatalf("ReadPending() = %v; want [%s]", got, second)
+	}
+
+	// A fresh read interleaved with the replay delivers a newer entry,
+	// which the replay must not read again.
+	mustXAdd(t, s, testStreamS1, "raw", "fresh")
+	if batch, err = c.ReadBatch(t.Context()); err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	batch.Release()
+	c.pendingAfter[testStreamS1] = second
+	if got := pendingIDs(t, c); len(got) != 0 {
+		t.Errorf("ReadPending() = %v; want none past the last entry delivered before the replay", got)
+	}
+}
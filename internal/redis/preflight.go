@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// minPendingIdleVersion is the first Redis release with XPENDING ... IDLE,
+// which the claim loop depends on.
+const minPendingIdleVersion = "6.2.0"
+
+// ServerCheck is what CheckServer found out about the Redis server.
+// Problems are actionable descriptions; none means the consumer can run.
+type ServerCheck struct {
+	Version  string // redis_version from INFO server, empty if hidden
+	Problems []string
+}
+
+// serverProbe is a harmless call of a command the consumer runs, aimed at
+// a key that does not exist: it fails or does nothing, but only after the
+// server has looked the command up and checked it against the ACL. Key
+// permissions are not judged, since the probe key is not one the user
+// needs.
+type serverProbe struct {
+	hint string // what to do when the server does not know the command
+	args []any
+}
+
+// CheckServer dials Redis with cfg, without touching any stream, and
+// checks that the server is recent enough and that the ACL user may run
+// every command cfg turns on. The error is only set when the server cannot
+// be reached.
+func CheckServer(ctx context.Context, cfg *config.RedisConfig) (ServerCheck, error) {
+	rdb := redis.NewClient(newOptions(cfg))
+	defer func() { _ = rdb.Close() }()
+
+	pingCtx, cancel := context.WithTimeout(ctx, cfg.PingTimeout)
+	defer cancel()
+	if err := rdb.Ping(pingCtx).Err(); err != nil {
+		return ServerCheck{}, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	var check ServerCheck
+	// INFO may be denied or trimmed by managed services; the probes below
+	// still catch what matters.
+	if info, err := rdb.Info(ctx, "server").Result(); err == nil {
+		check.Version = infoField(info, "redis_version")
+	}
+	if !cfg.NoAck && check.Version != "" && olderThan(check.Version, minPendingIdleVersion) {
+		check.Problems = append(check.Problems, fmt.Sprintf(
+			"Redis %s is too old: claiming pending entries needs XPENDING ... IDLE, upgrade to %s or later",
+			check.Version, minPendingIdleVersion))
+	}
+	for _, probe := range serverProbes(cfg) {
+		if problem := runProbe(ctx, rdb, probe); problem != "" {
+			check.Problems = append(check.Problems, problem)
+		}
+	}
+	return check, nil
+}
+
+func runProbe(ctx context.Context, rdb *redis.Client, probe serverProbe) string {
+	err := rdb.Do(ctx, probe.args...).Err()
+	if err == nil || err == redis.Nil {
+		return ""
+	}
+	name := strings.ToUpper(fmt.Sprint(probe.args[0]))
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "ERR unknown command"):
+		return fmt.Sprintf("%s is not supported by the server; %s", name, probe.hint)
+	case strings.HasPrefix(msg, "NOPERM") && strings.Contains(msg, "permissions to run"):
+		return fmt.Sprintf("%s is denied by the ACL; grant it to the user in REDIS_USERNAME", name)
+	}
+	return ""
+}
+
+// serverProbes lists a probe for each command the consumer runs under cfg.
+func serverProbes(cfg *config.RedisConfig) []serverProbe {
+	const group, upgrade = "syslog-consumer-preflight", "upgrade Redis"
+	key := Key(cfg, "syslog-consumer:preflight")
+	probes := []serverProbe{
+		{hint: upgrade, args: []any{"xreadgroup", "group", group, "c", "count", 1, "streams", key, ">"}},
+		{hint: upgrade, args: []any{"xack", key, group, "0-1"}},
+		{hint: upgrade, args: []any{"xdel", key, "0-1"}},
+		{hint: upgrade, args: []any{"xgroup", "create", key, group, "0"}},
+		{hint: upgrade, args: []any{"xinfo", "consumers", key, group}},
+		{hint: upgrade, args: []any{"eval", "return 0", 0}},
+		{hint: upgrade, args: []any{"evalsha", strings.Repeat("0", 40), 0}},
+	}
+	if !cfg.NoAck {
+		probes = append(probes,
+			serverProbe{hint: upgrade, args: []any{"xpending", key, group, "IDLE", 1, "-", "+", 1}},
+			serverProbe{hint: upgrade, args: []any{"xclaim", key, group, "c", 0, "0-1"}},
+			serverProbe{hint: upgrade, args: []any{"hgetall", key}},
+			// The dangling field fails inside HSET, after the ACL check and
+			// before anything is written.
+			serverProbe{hint: upgrade, args: []any{"hset", key, "k", "v", "k"}},
+			serverProbe{hint: upgrade, args: []any{"hdel", key, "k"}},
+			serverProbe{hint: upgrade, args: []any{"pexpire", key, 1}})
+	}
+	if cfg.Stream == "" {
+		probes = append(probes, serverProbe{hint: upgrade, args: []any{"scan", 0, "count", 1, "type", "stream"}})
+	}
+	if cfg.DeadLetterStream != "" || cfg.ReceiptsStream != "" {
+		probes = append(probes, serverProbe{hint: upgrade, args: []any{"xadd", key, "nomkstream", "*", "k", "v"}})
+	}
+	if cfg.ControlKey != "" {
+		probes = append(probes, serverProbe{hint: upgrade, args: []any{"hgetall", key}})
+	}
+	if cfg.LagInterval > 0 {
+		probes = append(probes,
+			serverProbe{hint: upgrade, args: []any{"xinfo", "groups", key}},
+			serverProbe{hint: upgrade, args: []any{"xlen", key}})
+	}
+	probes = append(probes, memoryProbes(cfg, key)...)
+	probes = append(probes, compactProbes(cfg, key)...)
+	return append(probes, dedupProbes(cfg, key)...)
+}
+
+func compactProbes(cfg *config.RedisConfig, key string) []serverProbe {
+	if cfg.CompactInterval == 0 {
+		return nil
+	}
+	return []serverProbe{
+		{hint: "upgrade Redis", args: []any{"xinfo", "groups", key}},
+		{hint: "upgrade Redis", args: []any{"xrange", key, "-", "+", "count", 1}},
+		{hint: "upgrade Redis", args: []any{"xpending", key, "syslog-consumer-preflight", "-", "+", 1}},
+	}
+}
+
+func memoryProbes(cfg *config.RedisConfig, key string) []serverProbe {
+	if cfg.MemoryHighPercent == 0 {
+		return nil
+	}
+	probes := []serverProbe{{hint: "upgrade Redis", args: []any{"info", "memory"}}}
+	if cfg.MemoryPressureKey != "" {
+		probes = append(probes,
+			serverProbe{hint: "upgrade Redis", args: []any{"set", key, 1, "xx", "px", 1}},
+			serverProbe{hint: "upgrade Redis", args: []any{"del", key}})
+	}
+	return probes
+}
+
+func dedupProbes(cfg *config.RedisConfig, key string) []serverProbe {
+	switch cfg.Dedup {
+	case config.DedupKeys:
+		return []serverProbe{{hint: "upgrade Redis", args: []any{"set", key, 1, "xx"}}}
+	case config.DedupBloom:
+		const bloom = "REDIS_DEDUP=bloom needs RedisBloom (Redis Stack or Redis 8)"
+		return []serverProbe{
+			{hint: bloom, args: []any{"bf.mexists", key, "x"}},
+			{hint: bloom, args: []any{"bf.insert", key, "nocreate", "items", "x"}},
+			{hint: "upgrade Redis", args: []any{"pexpire", key, 1}},
+		}
+	}
+	return nil
+}
+
+// infoField returns the value of field in an INFO reply, or "".
+func infoField(info, field string) string {
+	for line := range strings.Lines(info) {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), field+":"); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// olderThan compares dotted numeric versions; a part that does not parse
+// counts as 0.
+func olderThan(version, minimum string) bool {
+	have, want := strings.Split(version, "."), strings.Split(minimum, ".")
+	for i := range want {
+		var h int
+		if i < len(have) {
+			h, _ = strconv.Atoi(have[i])
+		}
+		w, _ := strconv.Atoi(want[i])
+		if h != w {
+			return h < w
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+func preflightConfig(addr string) *config.RedisConfig {
+	return &config.RedisConfig{Address: addr, PingTimeout: time.Second, DialTimeout: time.Second}
+}
+
+func TestCheckServer_Supported(t *testing.T) {
+	s := startMiniredis(t)
+	cfg := preflightConfig(s.Addr())
+	cfg.Stream = ""
+	cfg.DeadLetterStream = "dead"
+	cfg.ClaimCoordination = true
+	cfg.Dedup = config.DedupKeys
+	cfg.MemoryHighPercent = 90
+	cfg.MemoryPressureKey = "syslog:memory-pressure"
+	cfg.CompactInterval = time.Minute
+	cfg.ControlKey = "syslog-consumer:control"
+	cfg.LagInterval = time.Minute
+
+	check, err := CheckServer(t.Context(), cfg)
+	if err != nil {
+		t.Fatalf("CheckServer() error = %v", err)
+	}
+	if len(check.Problems) != 0 {
+		t.Errorf("Problems = %q; want none", check.Problems)
+	}
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("keys after CheckServer = %q; want the server untouched", keys)
+	}
+}
+
+// Each probe must leave the server untouched on its own, so that one
+// denied by the ACL cannot strand what an earlier one wrote.
+func TestServerProbes_WriteNothing(t *testing.T) {
+	s := startMiniredis(t)
+	cfg := preflightConfig(s.Addr())
+	rdb := newTestClient(t, s, testStreamS1).rdb
+	for _, probe := range serverProbes(cfg) {
+		runProbe(t.Context(), rdb, probe)
+		if keys := s.Keys(); len(keys) != 0 {
+			t.Fatalf("keys after probe %v = %q; want none", probe.args, keys)
+		}
+	}
+}
+
+func TestCheckServer_ReportsMissingBloom(t *testing.T) {
+	s := startMiniredis(t)
+	cfg := preflightConfig(s.Addr())
+	cfg.Dedup = config.DedupBloom
+
+	check, err := CheckServer(t.Context(), cfg)
+	if err != nil {
+		t.Fatalf("CheckServer() error = %v", err)
+	}
+	if len(check.Problems) != 2 {
+		t.Fatalf("Problems = %q; want BF.MEXISTS and BF.INSERT", check.Problems)
+	}
+	for _, problem := range check.Problems {
+		if !strings.HasPrefix(problem, "BF.") || !strings.Contains(problem, "RedisBloom") {
+			t.Errorf("problem = %q; want a BF.* command pointing at RedisBloom", problem)
+		}
+	}
+}
+
+func TestCheckServer_Unreachable(t *testing.T) {
+	if _, err := CheckServer(t.Context(), preflightConfig("localhost:1")); err == nil {
+		t.Error("CheckServer() error = nil; want a connection error")
+	}
+}
+
+func TestOlderThan(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"6.0.16", true},
+		{"6.2.0", false},
+		{"6.2", false},
+		{"7.4.1", false},
+		{"5", true},
+		{"10.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := olderThan(tt.version, minPendingIdleVersion); got != tt.want {
+			t.Errorf("olderThan(%q) = %v; want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestInfoField(t *testing.T) {
+	info := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+	if got := infoField(info, "redis_version"); got != "7.2.4" {
+		t.Errorf("infoField(redis_version) = %q; want 7.2.4", got)
+	}
+	if got := infoField(info, "os"); got != "" {
+		t.Errorf("infoField(os) = %q; want empty", got)
+	}
+}
@@ -0,0 +1,27 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AddEntry appends an entry with the given field/value pairs to stream and
+// returns its ID. The consumer itself never produces entries; this exists
+// for the selftest command's probe.
+func (c *Client) AddEntry(ctx context.Context, stream string, values ...any) (string, error) {
+	id, err := c.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values}).Result()
+	if err != nil {
+		return "", fmt.Errorf("xadd to stream %s failed: %w", stream, err)
+	}
+	return id, nil
+}
+
+// DeleteStream removes stream together with its consumer groups.
+func (c *Client) DeleteStream(ctx context.Context, stream string) error {
+	if err := c.rdb.Del(ctx, stream).Err(); err != nil {
+		return fmt.Errorf("failed to delete stream %s: %w", stream, err)
+	}
+	return nil
+}
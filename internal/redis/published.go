@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// publishedKey is the PIPELINE_PUBLISH_DEDUP=redis key reserving the
+// publish of entry id of stream for the consumer group, under
+// REDIS_KEY_PREFIX like the group name.
+func (c *Client) publishedKey(stream, id string) string {
+	group := strings.TrimPrefix(c.groupName, c.keyPrefix)
+	return c.keyPrefix + "syslog-consumer:" + group + ":published:" + stream + ":" + id
+}
+
+// ReservePublish reserves the publish of each of msgs, by stream and entry
+// ID, for window with SET NX PX, and reports which were already reserved by
+// this or another consumer of the group. Bodies need not be extracted.
+func (c *Client) ReservePublish(ctx context.Context, msgs []message.Redis, window time.Duration) ([]bool, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(msgs))
+	for i := range msgs {
+		cmds[i] = pipe.SetNX(ctx, c.publishedKey(msgs[i].Stream, msgs[i].ID), 1, window)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("publish reservation failed for %d messages: %w", len(msgs), err)
+	}
+	reserved := make([]bool, len(msgs))
+	for i, cmd := range cmds {
+		reserved[i] = !cmd.Val()
+	}
+	return reserved, nil
+}
+
+// ReleasePublish drops the reservations of ids of stream, so the entries
+// can be published again before the window ends.
+func (c *Client) ReleasePublish(ctx context.Context, stream string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.publishedKey(stream, id)
+	}
+	if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("publish reservation release failed for %d messages: %w", len(ids), err)
+	}
+	return nil
+}
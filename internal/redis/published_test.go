@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+func TestReservePublish(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	other := newTestClient(t, s, testStreamS1) // another consumer of the group
+	a := message.Redis{ID: "1-0", Stream: testStreamS1}
+	b := message.Redis{ID: "2-0", Stream: testStreamS1}
+	aElsewhere := message.Redis{ID: "1-0", Stream: testStreamS2}
+
+	reserve := func(c *Client, msgs ...message.Redis) []bool {
+		t.Helper()
+		reserved, err := c.ReservePublish(t.Context(), msgs, time.Minute)
+		if err != nil {
+			t.Fatalf("ReservePublish() error = %v", err)
+		}
+		return reserved
+	}
+	if got := reserve(c, a); !slices.Equal(got, []bool{false}) {
+		t.Errorf("first reservation = %v; want [false]", got)
+	}
+	if got := reserve(other, a, b, aElsewhere); !slices.Equal(got, []bool{true, false, false}) {
+		t.Errorf("second reservation = %v; want only the same stream and ID reserved", got)
+	}
+
+	if err := c.ReleasePublish(t.Context(), testStreamS1, []string{a.ID}); err != nil {
+		t.Fatalf("ReleasePublish() error = %v", err)
+	}
+	if got := reserve(other, a, b); !slices.Equal(got, []bool{false, true}) {
+		t.Errorf("after the release = %v; want a free again", got)
+	}
+
+	s.FastForward(time.Minute)
+	if got := reserve(c, b); !slices.Equal(got, []bool{false}) {
+		t.Errorf("after the window = %v; want the reservation expired", got)
+	}
+	if got := reserve(c); got != nil {
+		t.Errorf("ReservePublish() = %v; want nil for no messages", got)
+	}
+}
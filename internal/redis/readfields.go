@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// readFields is the REDIS_READ_FIELDS allowlist; nil keeps every field.
+// Redis has no projection for XREADGROUP or XCLAIM, so the reply still
+// carries every field; deleting the others right after it is parsed keeps
+// them out of the queues, the memory budget and the spill arena, and
+// leaves them to the garbage collector at once.
+type readFields map[string]struct{}
+
+func newReadFields(fields []string) readFields {
+	if len(fields) == 0 {
+		return nil
+	}
+	f := make(readFields, len(fields))
+	for _, field := range fields {
+		f[field] = struct{}{}
+	}
+	return f
+}
+
+// projected counts the fields readFields.apply deleted from a reply, and
+// their bytes: name and string value.
+type projected struct {
+	fields int64
+	bytes  int64
+}
+
+// apply deletes the fields of values that f does not list, adding them to
+// n.
+func (f readFields) apply(values map[string]any, n *projected) {
+	if f == nil {
+		return
+	}
+	for k, v := range values {
+		if _, ok := f[k]; ok {
+			continue
+		}
+		n.fields++
+		n.bytes += int64(len(k))
+		if s, ok := v.(string); ok {
+			n.bytes += int64(len(s))
+		}
+		delete(values, k)
+	}
+}
+
+func (n *projected) report() {
+	if n.fields > 0 {
+		metrics.ReadFieldsDropped.Add(n.fields)
+		metrics.ReadBytesDropped.Add(n.bytes)
+	}
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func TestReadFields_Apply(t *testing.T) {
+	values := map[string]any{"object": "{}", "raw": "a line", "_topic": "t", "host": 7}
+	var n projected
+	newReadFields([]string{"object", "_topic"}).apply(values, &n)
+
+	if got := slices.Sorted(maps.Keys(values)); !slices.Equal(got, []string{"_topic", "object"}) {
+		t.Errorf("kept fields = %v; want [_topic object]", got)
+	}
+	// "raw" + "a line" + "host"; the non-string value has no bytes counted.
+	if n != (projected{fields: 2, bytes: 13}) {
+		t.Errorf("projected = %+v; want 2 fields, 13 bytes", n)
+	}
+
+	newReadFields(nil).apply(values, &n)
+	if len(values) != 2 || n.fields != 2 {
+		t.Errorf("values = %v, projected = %+v; want nothing more deleted without an allowlist", values, n)
+	}
+}
+
+func TestReadBatch_ReadFields(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.readFields = newReadFields([]string{"object"})
+	mustXAdd(t, s, testStreamS1, "object", `{"k":"v"}`, "raw", "a long line", "host", "fw1")
+	mustEnsureGroups(t, c, testStreamS1)
+	fields, bytes := metrics.ReadFieldsDropped.Value(), metrics.ReadBytesDropped.Value()
+
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	defer batch.Release()
+	for msg := range batch.All() {
+		if msg.Object != `{"k":"v"}` || msg.Raw != "" {
+			t.Errorf("item = %+v; want the object only", *msg)
+		}
+	}
+	if got := metrics.ReadFieldsDropped.Value() - fields; got != 2 {
+		t.Errorf("ReadFieldsDropped delta = %d; want 2", got)
+	}
+	if got := metrics.ReadBytesDropped.Value() - bytes; got != int64(len("raw")+len("a long line")+len("hostfw1")) {
+		t.Errorf("ReadBytesDropped delta = %d; want 21", got)
+	}
+}
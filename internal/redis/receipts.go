@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// WriteReceipts appends one entry per receipt to the receipts stream in a
+// single pipeline. Each entry carries the event, the source stream, the
+// comma-separated entry IDs, the event time in Unix milliseconds and this
+// consumer's name.
+func (c *Client) WriteReceipts(ctx context.Context, receipts []message.Receipt) error {
+	if c.receiptsStream == "" {
+		return errors.New("cannot write receipts: no receipts stream configured")
+	}
+	if len(receipts) == 0 {
+		return nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	for i := range receipts {
+		r := &receipts[i]
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: c.receiptsStream,
+			Values: []any{
+				"event", r.Event,
+				"stream", r.Stream,
+				"ids", strings.Join(r.IDs, ","),
+				"at", r.At.UnixMilli(),
+				"consumer", c.consumer,
+			},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("receipts xadd failed for %d receipts: %w", len(receipts), err)
+	}
+	return nil
+}
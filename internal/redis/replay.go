@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// replayBatchSize is how many dead letter entries ReplayDeadLetters reads
+// and moves per round trip.
+const replayBatchSize = 100
+
+// ReplayOptions selects the dead letter entries ReplayDeadLetters moves
+// back, and where to.
+type ReplayOptions struct {
+	Reason string // only entries dead-lettered for this reason; "" for all
+	Target string // stream to add them to; "" for the stream each came from
+	Limit  int    // stop after this many entries; 0 for no limit
+	Rate   int    // entries per second; 0 for no limit
+	DryRun bool   // count the entries that would be replayed, change nothing
+}
+
+// ReplayResult counts the dead letter entries ReplayDeadLetters moved, or
+// would have moved in a dry run, and the ones it left: filtered out by
+// reason, or missing the source stream DeadLetter tags them with.
+type ReplayResult struct {
+	Replayed int `json:"replayed"`
+	Skipped  int `json:"skipped"`
+}
+
+type replayEntry struct {
+	id     string
+	target string
+	fields []any
+}
+
+// ReplayDeadLetters moves entries of the dead letter stream, oldest first,
+// back to their source stream or opts.Target as new entries, with the
+// stream, id and reason tags DeadLetter added stripped off. Each batch is
+// added before it is deleted from the dead letter stream, so a failure
+// can replay an entry twice but never loses one. It returns what was done
+// before an error.
+func (c *Client) ReplayDeadLetters(ctx context.Context, opts *ReplayOptions) (ReplayResult, error) {
+	var result ReplayResult
+	if c.deadLetterStream == "" {
+		return result, errors.New("cannot replay dead letters: no dead letter stream configured")
+	}
+	start := "-"
+	for opts.Limit <= 0 || result.Replayed < opts.Limit {
+		msgs, err := c.rdb.XRangeN(ctx, c.deadLetterStream, start, "+", replayBatchSize).Result()
+		if err != nil {
+			return result, fmt.Errorf("dead letter xrange failed: %w", err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+
+		entries := pickReplays(msgs, opts, &result)
+		if opts.Limit > 0 {
+			entries = entries[:min(len(entries), opts.Limit-result.Replayed)]
+		}
+		if opts.DryRun {
+			result.Replayed += len(entries)
+			continue
+		}
+		n, err := c.replay(ctx, entries)
+		result.Replayed += n
+		if err != nil {
+			return result, err
+		}
+		if err := replayPause(ctx, n, opts.Rate); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func pickReplays(msgs []redis.XMessage, opts *ReplayOptions, result *ReplayResult) []replayEntry {
+	entries := make([]replayEntry, 0, len(msgs))
+	for i := range msgs {
+		values := msgs[i].Values
+		source, _ := values["stream"].(string)
+		reason, _ := values["reason"].(string)
+		if source == "" || (opts.Reason != "" && reason != opts.Reason) {
+			result.Skipped++
+			continue
+		}
+		e := replayEntry{id: msgs[i].ID, target: source, fields: make([]any, 0, 2*len(values))}
+		if opts.Target != "" {
+			e.target = opts.Target
+		}
+		for _, field := range slices.Sorted(maps.Keys(values)) {
+			if field != "stream" && field != "id" && field != "reason" {
+				e.fields = append(e.fields, field, values[field])
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// replay adds entries to their targets, then deletes the ones added from
+// the dead letter stream. It returns how many were moved.
+func (c *Client) replay(ctx context.Context, entries []replayEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.StringCmd, len(entries))
+	for i := range entries {
+		cmds[i] = pipe.XAdd(ctx, &redis.XAddArgs{Stream: entries[i].target, Values: entries[i].fields})
+	}
+	_, addErr := pipe.Exec(ctx)
+
+	added := make([]string, 0, len(entries))
+	for i, cmd := range cmds {
+		if cmd.Err() == nil {
+			added = append(added, entries[i].id)
+		}
+	}
+	if len(added) > 0 {
+		if err := c.rdb.XDel(ctx, c.deadLetterStream, added...).Err(); err != nil {
+			return 0, fmt.Errorf("dead letter xdel failed for %d replayed messages: %w", len(added), err)
+		}
+	}
+	if addErr != nil {
+		return len(added), fmt.Errorf("replay xadd failed for %d messages: %w", len(entries)-len(added), addErr)
+	}
+	return len(added), nil
+}
+
+// replayPause waits as long as moving n entries takes at rate entries per
+// second.
+func replayPause(ctx context.Context, n, rate int) error {
+	if rate <= 0 || n == 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(n) * time.Second / time.Duration(rate))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func deadLetterFixture(t *testing.T) *Client {
+	t.Helper()
+	s := startMiniredis(t)
+	c := newTestClient(t, s, testStreamS1)
+	c.deadLetterStream = "dlq"
+	mustXAdd(t, s, "dlq", "stream", testStreamS1, "id", "1-0", "reason", "oversize", "object", "{}", "raw", "a")
+	mustXAdd(t, s, "dlq", "stream", testStreamS1, "id", "2-0", "reason", "schema_error", "object", "{}", "raw", "b")
+	mustXAdd(t, s, "dlq", "object", "{}", "raw", "untagged")
+	return c
+}
+
+func TestReplayDeadLetters_MovesEntriesBack(t *testing.T) {
+	c := deadLetterFixture(t)
+
+	result, err := c.ReplayDeadLetters(t.Context(), &ReplayOptions{})
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result != (ReplayResult{Replayed: 2, Skipped: 1}) {
+		t.Errorf("result = %+v; want 2 replayed, 1 skipped", result)
+	}
+	replayed, err := c.rdb.XRange(t.Context(), testStreamS1, "-", "+").Result()
+	if err != nil || len(replayed) != 2 {
+		t.Fatalf("source entries = %v (err %v); want 2", replayed, err)
+	}
+	for _, msg := range replayed {
+		if _, tagged := msg.Values["reason"]; tagged || len(msg.Values) != 2 {
+			t.Errorf("replayed values = %v; want only object and raw", msg.Values)
+		}
+	}
+	left, err := c.rdb.XRange(t.Context(), "dlq", "-", "+").Result()
+	if err != nil || len(left) != 1 || left[0].Values["raw"] != "untagged" {
+		t.Errorf("dlq entries = %v (err %v); want only the untagged one", left, err)
+	}
+}
+
+func TestReplayDeadLetters_DryRunChangesNothing(t *testing.T) {
+	c := deadLetterFixture(t)
+
+	result, err := c.ReplayDeadLetters(t.Context(), &ReplayOptions{DryRun: true, Reason: "oversize"})
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result != (ReplayResult{Replayed: 1, Skipped: 2}) {
+		t.Errorf("result = %+v; want 1 replayed, 2 skipped", result)
+	}
+	if n := c.rdb.XLen(t.Context(), "dlq").Val(); n != 3 {
+		t.Errorf("dlq length = %d; want 3", n)
+	}
+	if n := c.rdb.XLen(t.Context(), testStreamS1).Val(); n != 0 {
+		t.Errorf("source length = %d; want 0", n)
+	}
+}
+
+func TestReplayDeadLetters_TargetAndLimit(t *testing.T) {
+	c := deadLetterFixture(t)
+
+	result, err := c.ReplayDeadLetters(t.Context(), &ReplayOptions{Target: "retry", Limit: 1})
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if result.Replayed != 1 {
+		t.Errorf("Replayed = %d; want 1", result.Replayed)
+	}
+	retried, err := c.rdb.XRange(t.Context(), "retry", "-", "+").Result()
+	if err != nil || len(retried) != 1 || retried[0].Values["raw"] != "a" {
+		t.Errorf("target entries = %v (err %v); want the oldest dead letter", retried, err)
+	}
+	left, err := c.rdb.XRange(t.Context(), "dlq", "-", "+").Result()
+	if err != nil || !slices.ContainsFunc(left, func(m goredis.XMessage) bool { return m.Values["raw"] == "b" }) {
+		t.Errorf("dlq entries = %v (err %v); want the second entry kept", left, err)
+	}
+}
+
+func TestReplayDeadLetters_NoStreamConfigured(t *testing.T) {
+	c := newTestClient(t, startMiniredis(t), testStreamS1)
+	if _, err := c.ReplayDeadLetters(t.Context(), &ReplayOptions{}); err == nil {
+		t.Error("ReplayDeadLetters() error = nil; want error without a dead letter stream")
+	}
+}
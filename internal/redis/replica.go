@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// replicaRetry is how long the calls for the replica go straight to the
+// primary once the replica failed one, before it is tried again.
+const replicaRetry = 30 * time.Second
+
+// newReplica connects to REDIS_REPLICA_ADDRESS, with the settings of the
+// primary, or returns nil when it is not set. Neither its calls nor its
+// dials are retried: replicaHook sends a failed call to the primary
+// instead. Nothing is sent until the first call, so a replica that is down
+// at startup only moves the calls to the primary.
+func newReplica(cfg *config.RedisConfig, primary *redis.Client, logger *log.Logger) *redis.Client {
+	if cfg.ReplicaAddress == "" {
+		return nil
+	}
+	opts := newOptions(cfg)
+	opts.Addr = cfg.ReplicaAddress
+	opts.MaxRetries = -1
+	opts.DialerRetries = 1
+	opts.MinIdleConns = 0
+	replica := redis.NewClient(opts)
+	// Added first so it runs outside timeoutHook: the primary gets a whole
+	// operation timeout of its own.
+	replica.AddHook(&replicaHook{primary: primary, log: logger, addr: cfg.ReplicaAddress})
+	if timeout := operationTimeout(cfg); timeout > 0 {
+		replica.AddHook(timeoutHook{timeout: timeout, block: cfg.BlockTimeout})
+	}
+	return replica
+}
+
+// replicaHook sends the calls the replica cannot answer to the primary,
+// and every call for replicaRetry after that. A call the replica answers
+// with an error reply, such as a missing key, is not sent again: the
+// primary would give the same reply. The switches to the primary and back
+// are logged, not every call.
+type replicaHook struct {
+	primary   *redis.Client
+	log       *log.Logger
+	addr      string
+	downUntil atomic.Int64 // unix nanoseconds; zero while the replica answers
+}
+
+func (*replicaHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *replicaHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		now := time.Now()
+		if now.UnixNano() < h.downUntil.Load() {
+			return h.fallback(ctx, cmd)
+		}
+		err := next(ctx, cmd)
+		if !replicaUnavailable(ctx, err) {
+			if h.downUntil.Swap(0) != 0 {
+				h.log.Infof(ctx, "Redis replica at %s answers again", h.addr)
+			}
+			return err
+		}
+		if h.downUntil.Swap(now.Add(replicaRetry).UnixNano()) == 0 {
+			h.log.Warnf(ctx, "Redis replica at %s failed, sending its calls to the primary for %v: %v",
+				h.addr, replicaRetry, err)
+		}
+		return h.fallback(ctx, cmd)
+	}
+}
+
+func (*replicaHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *replicaHook) fallback(ctx context.Context, cmd redis.Cmder) error {
+	metrics.RedisReplicaFallbacks.Add(1)
+	return h.primary.Process(ctx, cmd)
+}
+
+// replicaUnavailable reports whether err means the replica could not
+// answer: it is unreachable, timed out, still loading its dataset or cut
+// off from its primary. An error of the caller's ctx is the caller's.
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var reply redis.Error
+	if !errors.As(err, &reply) {
+		return true
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "LOADING") || strings.HasPrefix(msg, "MASTERDOWN")
+}
+
+// reader returns the client for the calls that only watch the streams:
+// the replica, when there is one, or the primary.
+func (c *Client) reader() *redis.Client {
+	if c.replica != nil {
+		return c.replica
+	}
+	return c.rdb
+}
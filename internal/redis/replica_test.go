@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// withReplica gives c a replica at addr.
+func withReplica(t *testing.T, c *Client, addr string) {
+	t.Helper()
+	c.replica = newReplica(&config.RedisConfig{
+		ReplicaAddress: addr, DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second,
+	}, c.rdb, c.log)
+	t.Cleanup(func() { _ = c.replica.Close() })
+}
+
+func TestNewReplica_Unset(t *testing.T) {
+	if newReplica(&config.RedisConfig{}, nil, nil) != nil {
+		t.Error("newReplica() without an address is not nil")
+	}
+	c := newTestClient(t, startMiniredis(t), "")
+	if c.reader() != c.rdb {
+		t.Error("reader() without a replica is not the primary")
+	}
+}
+
+func TestGroupLags_ReadFromReplica(t *testing.T) {
+	primary, replica := startMiniredis(t), startMiniredis(t)
+	c := newTestClient(t, primary, testStreamS1)
+	withReplica(t, c, replica.Addr())
+	// Only the replica has the stream, so a lag found was read there.
+	mustXAdd(t, replica, testStreamS1, "raw", "a")
+	if err := c.replica.XGroupCreate(t.Context(), testStreamS1, c.groupName, "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate() error = %v", err)
+	}
+	fallbacks := metrics.RedisReplicaFallbacks.Value()
+
+	lags, err := c.GroupLags(t.Context())
+	if err != nil || len(lags) != 1 || lags[0].Stream != testStreamS1 {
+		t.Fatalf("GroupLags() = %+v, %v; want the lag of %s on the replica", lags, err, testStreamS1)
+	}
+	if got := metrics.RedisReplicaFallbacks.Value() - fallbacks; got != 0 {
+		t.Errorf("RedisReplicaFallbacks delta = %d; want 0", got)
+	}
+}
+
+func TestReplica_ErrorReplyNotSentToPrimary(t *testing.T) {
+	primary, replica := startMiniredis(t), startMiniredis(t)
+	c := newTestClient(t, primary, "")
+	withReplica(t, c, replica.Addr())
+	mustXAdd(t, primary, testStreamS1, "raw", "a")
+	fallbacks := metrics.RedisReplicaFallbacks.Value()
+
+	if err := c.reader().XInfoGroups(t.Context(), testStreamS1).Err(); err == nil {
+		t.Error("XInfoGroups() of a stream the replica lacks error = nil")
+	}
+	if got := metrics.RedisReplicaFallbacks.Value() - fallbacks; got != 0 {
+		t.Errorf("RedisReplicaFallbacks delta = %d; want 0", got)
+	}
+}
+
+func TestReplica_FallsBackToPrimary(t *testing.T) {
+	primary, replica := startMiniredis(t), startMiniredis(t)
+	c := newTestClient(t, primary, "")
+	hook := &replicaHook{primary: c.rdb, log: c.log, addr: replica.Addr()}
+	c.replica = goredis.NewClient(&goredis.Options{Addr: replica.Addr(), MaxRetries: -1, DialerRetries: 1})
+	c.replica.AddHook(hook)
+	t.Cleanup(func() { _ = c.replica.Close() })
+	mustXAdd(t, primary, testStreamS1, "raw", "a")
+	replica.Close()
+	fallbacks := metrics.RedisReplicaFallbacks.Value()
+
+	xlen := func() {
+		t.Helper()
+		if n, err := c.reader().XLen(t.Context(), testStreamS1).Result(); err != nil || n != 1 {
+			t.Fatalf("XLen() = %d, %v; want 1 from the primary", n, err)
+		}
+	}
+	xlen()
+	if hook.downUntil.Load() == 0 {
+		t.Fatal("replica not marked down after a failed call")
+	}
+	if err := replica.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	xlen() // within replicaRetry: straight to the primary
+	if got := metrics.RedisReplicaFallbacks.Value() - fallbacks; got != 2 {
+		t.Errorf("RedisReplicaFallbacks delta = %d; want 2", got)
+	}
+
+	hook.downUntil.Store(time.Now().UnixNano()) // the retry is due
+	mustXAdd(t, replica, testStreamS1, "raw", "a")
+	xlen()
+	if hook.downUntil.Load() != 0 || metrics.RedisReplicaFallbacks.Value()-fallbacks != 2 {
+		t.Error("replica not used again once it answered")
+	}
+}
+
+func TestReplicaUnavailable(t *testing.T) {
+	ctx := t.Context()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for _, tc := range []struct {
+		ctx  context.Context
+		err  error
+		name string
+		want bool
+	}{
+		{ctx, nil, "no error", false},
+		{ctx, errors.New("dial tcp: connection refused"), "unreachable", true},
+		{canceled, errors.New("context canceled"), "caller canceled", false},
+		{ctx, goredis.Nil, "nil reply", false},
+	} {
+		if got := replicaUnavailable(tc.ctx, tc.err); got != tc.want {
+			t.Errorf("replicaUnavailable(%s) = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// detachPollInterval is how often DetachStream checks whether the entries
+// this consumer holds on the stream have been acknowledged.
+const detachPollInterval = 200 * time.Millisecond
+
+// StreamDetach is where DetachStream left a stream.
+type StreamDetach struct {
+	Stream  string `json:"stream"`
+	Pending int64  `json:"pending"` // entries this consumer still held at the last check
+	Drained bool   `json:"drained"`
+}
+
+// AttachStream adds stream to the streams read, creating it and the
+// consumer group when they do not exist, and keeps it through the
+// following discoveries even when REDIS_STREAM_INCLUDE/EXCLUDE filter it
+// out. It reports whether the stream was not read before.
+func (c *Client) AttachStream(ctx context.Context, stream string) (bool, error) {
+	if stream == "" || stream == c.deadLetterStream || stream == c.receiptsStream {
+		return false, fmt.Errorf("stream %q cannot be attached", stream)
+	}
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+	if err := c.ensureGroups(ctx, []string{stream}); err != nil {
+		return false, err
+	}
+	delete(c.detached, stream)
+	if c.attached == nil {
+		c.attached = make(map[string]struct{})
+	}
+	c.attached[stream] = struct{}{}
+
+	var added bool
+	var active int
+	c.setStreams(false, func() {
+		c.draining = slices.DeleteFunc(slices.Clone(c.draining), func(s string) bool { return s == stream })
+		added = !slices.Contains(c.streams, stream)
+		if added {
+			c.streams, c.tuning = c.tune(append(slices.Clone(c.streams), stream))
+		}
+		active = len(c.streams)
+	})
+
+	if added {
+		select {
+		case c.streamsFound <- struct{}{}:
+		default:
+		}
+		metrics.StreamsActive.Set(int64(active))
+		c.log.Infof(ctx, "Attached stream '%s'", stream)
+	}
+	return added, nil
+}
+
+// DetachStream stops reading stream, including through the following
+// discoveries, and waits until the entries this consumer was handed from
+// it have been acknowledged or ctx is done. Until then the stream is
+// draining: ClaimIdle still claims its idle entries, so those of dead
+// consumers are finished too. When ctx ends first, it returns ctx's error
+// and the stream keeps draining; calling DetachStream again waits again.
+func (c *Client) DetachStream(ctx context.Context, stream string) (StreamDetach, error) {
+	detach := StreamDetach{Stream: stream}
+	if c.unread(stream) {
+		c.log.Infof(ctx, "Detached stream '%s', draining its pending entries", stream)
+	}
+
+	ticker := time.NewTicker(detachPollInterval)
+	defer ticker.Stop()
+	for {
+		pending, err := c.consumerPending(ctx, stream)
+		if err != nil {
+			return detach, err
+		}
+		detach.Pending = pending
+		if pending == 0 {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return detach, ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	c.draining = slices.DeleteFunc(slices.Clone(c.draining), func(s string) bool { return s == stream })
+	c.mu.Unlock()
+	detach.Drained = true
+	return detach, nil
+}
+
+// unread takes stream out of the streams read and marks it draining. It
+// returns once no read names the stream, so the pending entries counted
+// after it are all there will be, and reports whether the stream was read
+// until then.
+func (c *Client) unread(stream string) bool {
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+	if c.detached == nil {
+		c.detached = make(map[string]struct{})
+	}
+	c.detached[stream] = struct{}{}
+	delete(c.attached, stream)
+
+	removed := slices.Contains(c.Streams(), stream) // pinMu keeps it current
+	var active int
+	c.setStreams(removed, func() {
+		if removed {
+			c.streams, c.tuning = c.tune(slices.DeleteFunc(slices.Clone(c.streams), func(s string) bool { return s == stream }))
+		}
+		if !slices.Contains(c.draining, stream) {
+			c.draining = append(slices.Clone(c.draining), stream)
+		}
+		active = len(c.streams)
+	})
+
+	if removed {
+		metrics.StreamsActive.Set(int64(active))
+	}
+	return removed
+}
+
+// consumerPending returns how many entries of stream this consumer holds
+// unacknowledged; none when the stream or its group is gone.
+func (c *Client) consumerPending(ctx context.Context, stream string) (int64, error) {
+	summary, err := c.rdb.XPending(ctx, stream, c.groupName).Result()
+	if err != nil {
+		if isNoGroupError(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("xpending %s: %w", stream, err)
+	}
+	return summary.Consumers[c.consumer], nil
+}
+
+// pin applies the attached and detached streams to the streams a discovery
+// found. The caller holds pinMu.
+func (c *Client) pin(discovered []string) []string {
+	if len(c.attached) == 0 && len(c.detached) == 0 {
+		return discovered
+	}
+	streams := slices.DeleteFunc(discovered, func(s string) bool {
+		_, ok := c.detached[s]
+		return ok
+	})
+	var missing []string
+	for stream := range c.attached {
+		if !slices.Contains(streams, stream) {
+			missing = append(missing, stream)
+		}
+	}
+	slices.Sort(missing)
+	return append(streams, missing...)
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAttachStream(t *testing.T) {
+	s := startMiniredis(t)
+	mustXAdd(t, s, testStreamS1, "raw", "a")
+	c := newTestClient(t, s, "")
+	c.deadLetterStream = "dlq"
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+
+	added, err := c.AttachStream(t.Context(), testStreamS2)
+	if err != nil || !added {
+		t.Fatalf("AttachStream() = %v, %v; want true, nil", added, err)
+	}
+	if !s.Exists(testStreamS2) {
+		t.Error("attached stream was not created")
+	}
+	if added, _ := c.AttachStream(t.Context(), testStreamS2); added {
+		t.Error("second AttachStream() = true; want false")
+	}
+	if _, err := c.AttachStream(t.Context(), "dlq"); err == nil {
+		t.Error("AttachStream(dead letter stream) error = nil; want error")
+	}
+
+	// The attached stream is empty, so a filter excluding it must not drop it.
+	c.streamFilter = streamFilter{exclude: []string{testStreamS2}}
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	if got := c.Streams(); !slices.Equal(got, []string{testStreamS1, testStreamS2}) {
+		t.Errorf("Streams() after refresh = %v; want the attached stream kept", got)
+	}
+}
+
+func TestDetachStream_WaitsForPending(t *testing.T) {
+	s := startMiniredis(t)
+	mustXAdd(t, s, testStreamS1, "raw", "a")
+	mustXAdd(t, s, testStreamS2, "raw", "b")
+	c := newTestClient(t, s, "")
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil || len(batch.Items) != 2 {
+		t.Fatalf("ReadBatch() = %d items, %v; want 2", len(batch.Items), err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	detach, err := c.DetachStream(ctx, testStreamS2)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || detach.Drained || detach.Pending != 1 {
+		t.Fatalf("DetachStream() = %+v, %v; want 1 pending, deadline exceeded", detach, err)
+	}
+	if got := c.Streams(); !slices.Equal(got, []string{testStreamS1}) {
+		t.Errorf("Streams() = %v; want the detached stream left out", got)
+	}
+	if !slices.Contains(c.draining, testStreamS2) {
+		t.Error("detached stream is not draining")
+	}
+
+	var id string
+	for i := range batch.Items {
+		if batch.Items[i].Stream == testStreamS2 {
+			id = batch.Items[i].ID
+		}
+	}
+	if err := c.AckAndDeleteBatch(t.Context(), []string{id}, testStreamS2); err != nil {
+		t.Fatalf("AckAndDeleteBatch() error = %v", err)
+	}
+	detach, err = c.DetachStream(t.Context(), testStreamS2)
+	if err != nil || !detach.Drained || detach.Pending != 0 {
+		t.Fatalf("DetachStream() = %+v, %v; want drained", detach, err)
+	}
+	if len(c.draining) != 0 {
+		t.Errorf("draining = %v; want none once drained", c.draining)
+	}
+
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	if got := c.Streams(); !slices.Equal(got, []string{testStreamS1}) {
+		t.Errorf("Streams() after refresh = %v; want the detached stream still left out", got)
+	}
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"path"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// streamFilter holds the REDIS_STREAM_INCLUDE and REDIS_STREAM_EXCLUDE
+// patterns, checked by config.Validate.
+type streamFilter struct {
+	skipped map[string]struct{} // streams skipped by the previous discovery
+	include []string
+	exclude []string
+}
+
+func newStreamFilter(cfg *config.RedisConfig) streamFilter {
+	return streamFilter{include: cfg.StreamInclude, exclude: cfg.StreamExclude}
+}
+
+// allows reports whether stream matches an include pattern, or there are
+// none, and no exclude pattern.
+func (f *streamFilter) allows(stream string) bool {
+	return (len(f.include) == 0 || matchAny(f.include, stream)) && !matchAny(f.exclude, stream)
+}
+
+// newlySkipped remembers the streams skipped by a discovery and returns
+// those the previous one did not skip, so each is logged once.
+func (f *streamFilter) newlySkipped(skipped []string) []string {
+	var fresh []string
+	next := make(map[string]struct{}, len(skipped))
+	for _, stream := range skipped {
+		if _, ok := f.skipped[stream]; !ok {
+			fresh = append(fresh, stream)
+		}
+		next[stream] = struct{}{}
+	}
+	f.skipped = next
+	return fresh
+}
+
+func matchAny(patterns []string, stream string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, stream); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetStreams_WaitsForReadInFlight(t *testing.T) {
+	c := newTestClient(t, startMiniredis(t), "")
+	c.streams = []string{testStreamS1, testStreamS2}
+
+	c.readGate.RLock() // a read in flight
+	done := make(chan struct{})
+	go func() {
+		c.setStreams(true, func() { c.streams = []string{testStreamS1} })
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("setStreams() dropped a stream while a read was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.readGate.RUnlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("setStreams() still waiting after the read finished")
+	}
+	if got := c.Streams(); !slices.Equal(got, []string{testStreamS1}) {
+		t.Errorf("Streams() = %v; want [%s]", got, testStreamS1)
+	}
+}
+
+func TestSetStreams_AddingDoesNotWait(t *testing.T) {
+	c := newTestClient(t, startMiniredis(t), "")
+	c.streams = []string{testStreamS1}
+
+	c.readGate.RLock()
+	defer c.readGate.RUnlock()
+	done := make(chan struct{})
+	go func() {
+		c.setStreams(false, func() { c.streams = []string{testStreamS1, testStreamS2} })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("setStreams() adding a stream waited for the read in flight")
+	}
+}
+
+func TestReadBatch_RebuildsReadsForNewStreams(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	mustXAdd(t, s, testStreamS1, "raw", "a")
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	mustReadBatch(t, c)
+
+	mustXAdd(t, s, testStreamS2, "raw", "b")
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+	batch, err := c.ReadBatch(t.Context())
+	if err != nil || len(batch.Items) != 1 || batch.Items[0].Stream != testStreamS2 {
+		t.Fatalf("ReadBatch() = %+v, %v; want the entry of the new stream", batch.Items, err)
+	}
+	batch.Release()
+}
+
+// TestReadBatch_ConcurrentStreamChanges changes the stream set while
+// ReadBatch runs; under -race it checks the set is shared safely.
+func TestReadBatch_ConcurrentStreamChanges(t *testing.T) {
+	s := startMiniredis(t)
+	c := newTestClient(t, s, "")
+	c.blockTimeout = 5 * time.Millisecond
+	mustXAdd(t, s, testStreamS1, "raw", "a")
+	if _, err := c.RefreshStreams(t.Context()); err != nil {
+		t.Fatalf("RefreshStreams() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for ctx.Err() == nil {
+			batch, err := c.ReadBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				t.Errorf("ReadBatch() error = %v", err)
+				return
+			}
+			batch.Release()
+		}
+	})
+	wg.Go(func() {
+		for ctx.Err() == nil {
+			if _, err := c.AttachStream(ctx, testStreamS2); err != nil && ctx.Err() == nil {
+				t.Errorf("AttachStream() error = %v", err)
+				return
+			}
+			c.unread(testStreamS2)
+			if _, err := c.RefreshStreams(ctx); err != nil && ctx.Err() == nil {
+				t.Errorf("RefreshStreams() error = %v", err)
+				return
+			}
+		}
+	})
+	wg.Go(func() {
+		for ctx.Err() == nil {
+			_ = c.Streams()
+			_, _ = c.claimSettings(testStreamS1)
+		}
+	})
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// errOperationTimeout is the context cause of a call that ran out of its
+// operation timeout.
+var errOperationTimeout = errors.New("redis operation timeout")
+
+// timeoutHook bounds every command, script and pipeline sent through the
+// client by REDIS_OPERATION_TIMEOUT, so a hung server fails the call instead
+// of stalling the loop that made it. go-redis retries inside the hook, so
+// the bound covers them. XREADGROUP gets the block timeout on top.
+type timeoutHook struct {
+	timeout time.Duration
+	block   time.Duration
+}
+
+// operationTimeout is REDIS_OPERATION_TIMEOUT, or the read plus write
+// timeout when it is unset.
+func operationTimeout(cfg *config.RedisConfig) time.Duration {
+	if cfg.OperationTimeout > 0 {
+		return cfg.OperationTimeout
+	}
+	return cfg.ReadTimeout + cfg.WriteTimeout
+}
+
+func (timeoutHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h timeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		timeout := h.timeout
+		if cmd.Name() == "xreadgroup" {
+			timeout += h.block
+		}
+		opCtx, cancel := context.WithTimeoutCause(ctx, timeout, errOperationTimeout)
+		defer cancel()
+		return countTimeout(ctx, opCtx, next(opCtx, cmd))
+	}
+}
+
+func (h timeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		opCtx, cancel := context.WithTimeoutCause(ctx, h.timeout, errOperationTimeout)
+		defer cancel()
+		return countTimeout(ctx, opCtx, next(opCtx, cmds))
+	}
+}
+
+// countTimeout counts a call that failed once its operation timeout passed
+// in consumer.redis_timeouts. The connection handshake runs inside the
+// command that dialed, so a timeout inherited from ctx is left to the outer
+// call to count.
+func countTimeout(ctx, opCtx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(opCtx), errOperationTimeout) &&
+		!errors.Is(context.Cause(ctx), errOperationTimeout) {
+		metrics.RedisTimeouts.Add(1)
+	}
+	return err
+}
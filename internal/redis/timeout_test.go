@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// hungServer accepts connections and never answers, like a stalled Redis.
+func hungServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := ln.Close(); err != nil {
+			t.Errorf("ln.Close(): %v", err)
+		}
+	})
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				_ = conn.Close() //nolint:errcheck // test teardown
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestTimeoutHook_BoundsHungCall(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:                  hungServer(t),
+		ReadTimeout:           time.Minute,
+		WriteTimeout:          time.Minute,
+		ContextTimeoutEnabled: true,
+	})
+	t.Cleanup(func() {
+		if err := rdb.Close(); err != nil && !errors.Is(err, redis.ErrClosed) {
+			t.Errorf("rdb.Close(): %v", err)
+		}
+	})
+	rdb.AddHook(timeoutHook{timeout: 50 * time.Millisecond})
+	before := metrics.RedisTimeouts.Value()
+
+	start := time.Now()
+	if err := rdb.Ping(t.Context()).Err(); err == nil {
+		t.Fatal("Ping() error = nil; want a timeout from the hung server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Ping() returned after %v; want it cut short by the operation timeout", elapsed)
+	}
+	if d := metrics.RedisTimeouts.Value() - before; d != 1 {
+		t.Errorf("RedisTimeouts delta = %d; want 1", d)
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	cfg := config.RedisConfig{ReadTimeout: 3 * time.Second, WriteTimeout: 2 * time.Second}
+	if got := operationTimeout(&cfg); got != 5*time.Second {
+		t.Errorf("operationTimeout() = %v; want read plus write timeout", got)
+	}
+	cfg.OperationTimeout = time.Second
+	if got := operationTimeout(&cfg); got != time.Second {
+		t.Errorf("operationTimeout() = %v; want the configured 1s", got)
+	}
+}
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// NewReader is NewClient for a read-only pass over the streams: it creates
+// no consumer group, so only Streams, RangeBatch and Close may be called
+// on the client it returns.
+func NewReader(ctx context.Context, cfg *config.RedisConfig, logger *log.Logger) (*Client, error) {
+	return connect(ctx, cfg, logger)
+}
+
+// Streams returns the streams the client reads, in priority order.
+func (c *Client) Streams() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.streams)
+}
+
+// RangeBatch reads up to count entries of stream with XRANGE, from the
+// oldest or, with after set, from the entry following that ID. Unlike
+// ReadBatch it leaves the consumer groups alone, so it goes to
+// REDIS_REPLICA_ADDRESS when it is set.
+func (c *Client) RangeBatch(ctx context.Context, stream, after string, count int) (message.Batch, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	msgs, err := c.reader().XRangeN(ctx, stream, start, "+", int64(count)).Result()
+	if err != nil {
+		return message.Batch{}, fmt.Errorf("xrange %s failed: %w", stream, err)
+	}
+	if len(msgs) == 0 {
+		return message.Batch{}, nil
+	}
+	buf := c.getBuffer(&c.batchPool)
+	for i := range msgs {
+		buf.Add(msgs[i].ID, stream, msgs[i].Values)
+	}
+	return message.NewLazyBatch(buf, &c.batchPool), nil
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func TestNewReader_RangeBatch(t *testing.T) {
+	s := startMiniredis(t)
+	ids := []string{
+		mustXAdd(t, s, "stream-a", "raw", "one"),
+		mustXAdd(t, s, "stream-a", "raw", "two"),
+		mustXAdd(t, s, "stream-a", "raw", "three"),
+	}
+	mustXAdd(t, s, "stream-b", "raw", "other")
+
+	client, err := NewReader(t.Context(), &config.RedisConfig{
+		Address:            s.Addr(),
+		GroupName:          testGroupName,
+		BatchSize:          10,
+		DiscoveryScanCount: 1000,
+		PingTimeout:        time.Second,
+	}, log.New())
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer closeRedisClient(t, client)
+
+	if streams := client.Streams(); len(streams) != 2 {
+		t.Errorf("Streams() = %v; want both streams", streams)
+	}
+	var got []string
+	after := ""
+	for {
+		batch, err := client.RangeBatch(t.Context(), "stream-a", after, 2)
+		if err != nil {
+			t.Fatalf("RangeBatch() error = %v", err)
+		}
+		if len(batch.Items) == 0 {
+			break
+		}
+		for _, msg := range batch.Indexed() {
+			got = append(got, msg.ID)
+		}
+		after = batch.Items[len(batch.Items)-1].ID
+		batch.Release()
+	}
+	if len(got) != len(ids) || got[0] != ids[0] || got[2] != ids[2] {
+		t.Errorf("ranged IDs = %v; want %v", got, ids)
+	}
+
+	groups, err := client.rdb.XInfoGroups(t.Context(), "stream-a").Result()
+	if err != nil || len(groups) != 0 {
+		t.Errorf("XINFO GROUPS = %v, %v; want no consumer group created", groups, err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package spill provides an mmap-backed arena that keeps entry bodies out
+// of the Go heap while they wait in the publish queues.
+package spill
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Ref locates a record in an Arena. The zero Ref holds nothing.
+type Ref struct {
+	off  int
+	size int
+}
+
+// Len reports the size of the record in bytes.
+func (r Ref) Len() int {
+	return r.size
+}
+
+// span is a free range of the arena, in whole pages.
+type span struct {
+	off  int
+	size int
+}
+
+// Arena stores records in a shared mapping of an unlinked temporary file.
+// Each record takes whole pages, which are dropped from the process's
+// resident set as soon as they are written and again when they are freed,
+// so the kernel can write them back and evict them like any file page.
+// An Arena is safe for concurrent use.
+type Arena struct {
+	data []byte
+	free []span // sorted by offset, never adjacent
+	page int
+	used int
+	mu   sync.RWMutex
+}
+
+// Open maps an arena of size bytes, rounded up to whole pages, backed by a
+// temporary file in dir (the system temporary directory when empty). The
+// file is unlinked at once, so nothing is left behind if the process dies.
+func Open(dir string, size int) (*Arena, error) {
+	page := os.Getpagesize()
+	size = roundUp(size, page)
+	if size <= 0 {
+		return nil, errors.New("spill: arena size must be positive")
+	}
+
+	f, err := os.CreateTemp(dir, "syslog-consumer-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("spill: failed to create arena file: %w", err)
+	}
+	defer func() { _ = f.Close() }() // the mapping outlives the descriptor
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := f.Truncate(int64(size)); err != nil {
+		return nil, fmt.Errorf("spill: failed to size arena file: %w", err)
+	}
+	data, err := mapFile(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("spill: failed to map arena file: %w", err)
+	}
+	return &Arena{data: data, free: []span{{off: 0, size: size}}, page: page}, nil
+}
+
+// Store copies the concatenation of parts into the arena. It reports false
+// when the parts are empty or the arena has no free range large enough.
+func (a *Arena) Store(parts ...string) (Ref, bool) {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	if n == 0 {
+		return Ref{}, false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	off, ok := a.alloc(roundUp(n, a.page))
+	if !ok {
+		return Ref{}, false
+	}
+	dst := a.data[off:]
+	for _, p := range parts {
+		dst = dst[copy(dst, p):]
+	}
+	dropPages(a.data[off : off+roundUp(n, a.page)])
+	return Ref{off: off, size: n}, true
+}
+
+// Load returns a heap copy of the record. It returns "" once the arena is
+// closed.
+func (a *Arena) Load(ref Ref) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.data == nil || ref.size == 0 {
+		return ""
+	}
+	return string(a.data[ref.off : ref.off+ref.size])
+}
+
+// Free returns the record's pages to the arena; ref must not be used
+// afterwards. Freeing the zero Ref is a no-op.
+func (a *Arena) Free(ref Ref) {
+	if ref.size == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.data == nil {
+		return
+	}
+	size := roundUp(ref.size, a.page)
+	dropPages(a.data[ref.off : ref.off+size])
+	a.release(span{off: ref.off, size: size})
+}
+
+// Used reports the bytes taken by live records, in whole pages.
+func (a *Arena) Used() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.used
+}
+
+// Close unmaps the arena. Records still stored are lost: Load returns ""
+// for them and Free ignores them.
+func (a *Arena) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.data == nil {
+		return nil
+	}
+	data := a.data
+	a.data, a.free, a.used = nil, nil, 0
+	return unmap(data)
+}
+
+// alloc takes size bytes from the first free range that holds them.
+func (a *Arena) alloc(size int) (int, bool) {
+	for i, s := range a.free {
+		if s.size < size {
+			continue
+		}
+		if s.size == size {
+			a.free = slices.Delete(a.free, i, i+1)
+		} else {
+			a.free[i] = span{off: s.off + size, size: s.size - size}
+		}
+		a.used += size
+		return s.off, true
+	}
+	return 0, false
+}
+
+// release puts s back into the free list, merging it with its neighbours.
+func (a *Arena) release(s span) {
+	a.used -= s.size
+	i, _ := slices.BinarySearchFunc(a.free, s.off, func(f span, off int) int { return cmp.Compare(f.off, off) })
+	a.free = slices.Insert(a.free, i, s)
+	if i+1 < len(a.free) && s.off+s.size == a.free[i+1].off {
+		a.free[i].size += a.free[i+1].size
+		a.free = slices.Delete(a.free, i+1, i+2)
+	}
+	if i > 0 && a.free[i-1].off+a.free[i-1].size == s.off {
+		a.free[i-1].size += a.free[i].size
+		a.free = slices.Delete(a.free, i, i+1)
+	}
+}
+
+func roundUp(n, page int) int {
+	return (n + page - 1) / page * page
+}
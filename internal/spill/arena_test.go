@@ -0,0 +1,103 @@
+package spill
+
+import (
+	"os"
+	"testing"
+)
+
+func openArena(t *testing.T, pages int) *Arena {
+	t.Helper()
+	a, err := Open(t.TempDir(), pages*os.Getpagesize())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := a.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return a
+}
+
+func TestArena_StoreLoadFree(t *testing.T) {
+	a := openArena(t, 4)
+	page := os.Getpagesize()
+
+	ref, ok := a.Store("hello, ", "world")
+	if !ok {
+		t.Fatal("Store() = false; want the record stored")
+	}
+	if ref.Len() != 12 || a.Used() != page {
+		t.Errorf("Len() = %d, Used() = %d; want 12 bytes in one page", ref.Len(), a.Used())
+	}
+	if got := a.Load(ref); got != "hello, world" {
+		t.Errorf("Load() = %q; want %q", got, "hello, world")
+	}
+	a.Free(ref)
+	if a.Used() != 0 {
+		t.Errorf("Used() after Free = %d; want 0", a.Used())
+	}
+}
+
+func TestArena_FullAndCoalesce(t *testing.T) {
+	a := openArena(t, 3)
+	page := os.Getpagesize()
+	big := string(make([]byte, page+1)) // two pages
+
+	first, ok1 := a.Store(big)
+	second, ok2 := a.Store("x")
+	if !ok1 || !ok2 {
+		t.Fatal("Store() = false; want both records to fit")
+	}
+	if _, ok := a.Store("y"); ok {
+		t.Error("Store() on a full arena = true; want false")
+	}
+	if _, ok := a.Store(""); ok {
+		t.Error("Store() of nothing = true; want false")
+	}
+
+	a.Free(second)
+	a.Free(first)
+	if _, ok := a.Store(string(make([]byte, 3*page))); !ok {
+		t.Error("Store() of the whole arena after freeing = false; want the free ranges merged")
+	}
+}
+
+func TestArena_Closed(t *testing.T) {
+	a, err := Open(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	ref, _ := a.Store("body")
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := a.Load(ref); got != "" {
+		t.Errorf("Load() after Close = %q; want empty", got)
+	}
+	a.Free(ref)
+	if err := a.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	if _, err := Open(t.TempDir(), 0); err == nil {
+		t.Error("Open(size 0) error = nil; want error")
+	}
+	if _, err := Open("/does/not/exist", 1); err == nil {
+		t.Error("Open(missing dir) error = nil; want error")
+	}
+}
+
+func TestOpen_LeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir, 1)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = a.Close() }()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("dir holds %d entries; want the arena file unlinked", len(entries))
+	}
+}
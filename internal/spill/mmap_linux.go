@@ -0,0 +1,21 @@
+package spill
+
+import (
+	"os"
+	"syscall"
+)
+
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func unmap(data []byte) error {
+	return syscall.Munmap(data)
+}
+
+// dropPages unmaps the pages of b from the process without discarding
+// them: in a shared file mapping they stay in the page cache and the file,
+// and the next read faults them back in.
+func dropPages(b []byte) {
+	_ = syscall.Madvise(b, syscall.MADV_DONTNEED)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package spill
+
+import (
+	"errors"
+	"os"
+)
+
+func mapFile(*os.File, int) ([]byte, error) {
+	return nil, errors.New("spill arenas are only supported on Linux")
+}
+
+func unmap([]byte) error {
+	return nil
+}
+
+func dropPages([]byte) {}
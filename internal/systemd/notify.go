@@ -0,0 +1,76 @@
+// Package systemd speaks the sd_notify protocol, so systemd can supervise
+// the consumer as a Type=notify service with a watchdog.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States sent with Notify.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notifier sends states to the service manager. A nil Notifier drops them.
+type Notifier struct {
+	socket   string
+	watchdog time.Duration
+}
+
+// FromEnv returns a Notifier for the socket systemd passes in NOTIFY_SOCKET,
+// or nil when the process is not run by systemd with Type=notify.
+func FromEnv() *Notifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	return &Notifier{socket: socket, watchdog: watchdogFromEnv()}
+}
+
+// watchdogFromEnv returns the WatchdogSec= of the service, or 0 when it has
+// none or it is meant for another process.
+func watchdogFromEnv() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Notify sends state, one of the constants above or any other sd_notify
+// assignment, in a single datagram.
+func (n *Notifier) Notify(ctx context.Context, state string) error {
+	if n == nil {
+		return nil
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unixgram", n.socket)
+	if err != nil {
+		return fmt.Errorf("systemd: failed to dial notify socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("systemd: failed to send %s: %w", state, err)
+	}
+	return nil
+}
+
+// WatchdogTimeout returns the service's WatchdogSec=, or 0 when the watchdog
+// is off. Watchdog must be sent well within it; systemd recommends every
+// half.
+func (n *Notifier) WatchdogTimeout() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.watchdog
+}
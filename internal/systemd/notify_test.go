@@ -0,0 +1,91 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+	return conn
+}
+
+func TestNotify_SendsState(t *testing.T) {
+	conn := listenNotify(t)
+	n := FromEnv()
+	if n == nil {
+		t.Fatal("FromEnv() = nil; want a notifier with NOTIFY_SOCKET set")
+	}
+
+	for _, state := range []string{Ready, Watchdog, Stopping} {
+		if err := n.Notify(t.Context(), state); err != nil {
+			t.Fatalf("Notify(%s) error = %v", state, err)
+		}
+		buf := make([]byte, 64)
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("SetReadDeadline() error = %v", err)
+		}
+		k, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if got := string(buf[:k]); got != state {
+			t.Errorf("datagram = %q; want %q", got, state)
+		}
+	}
+}
+
+func TestFromEnv_NotSupervised(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	n := FromEnv()
+	if n != nil {
+		t.Fatalf("FromEnv() = %v; want nil without NOTIFY_SOCKET", n)
+	}
+	if err := n.Notify(t.Context(), Ready); err != nil {
+		t.Errorf("nil Notify() error = %v; want nil", err)
+	}
+	if got := n.WatchdogTimeout(); got != 0 {
+		t.Errorf("nil WatchdogTimeout() = %v; want 0", got)
+	}
+}
+
+func TestFromEnv_Watchdog(t *testing.T) {
+	listenNotify(t)
+	tests := []struct {
+		name, usec, pid string
+		want            time.Duration
+	}{
+		{name: "off", want: 0},
+		{name: "this process", usec: "30000000", pid: strconv.Itoa(os.Getpid()), want: 30 * time.Second},
+		{name: "any process", usec: "500000", want: 500 * time.Millisecond},
+		{name: "another process", usec: "30000000", pid: "1", want: 0},
+		{name: "invalid", usec: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			if got := FromEnv().WatchdogTimeout(); got != tt.want {
+				t.Errorf("WatchdogTimeout() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotify_MissingSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	if err := FromEnv().Notify(t.Context(), Ready); err == nil {
+		t.Error("Notify() error = nil; want an error for a missing socket")
+	}
+}
@@ -0,0 +1,66 @@
+package tuning
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted. In a container
+// with its own cgroup namespace, its root is the container's cgroup.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is the smallest memory.limit_in_bytes cgroup v1 reports
+// for a group without a limit, which it rounds down from the maximum int64
+// to a page multiple.
+const cgroupV1Unlimited = 1 << 62
+
+// cpuLimit returns the CPUs the cgroup at root may use, from cpu.max under
+// cgroup v2 or the CFS quota under v1, and false when it has no limit.
+func cpuLimit(root string) (float64, bool) {
+	if fields := strings.Fields(readFile(filepath.Join(root, "cpu.max"))); len(fields) == 2 {
+		return quota(fields[0], fields[1])
+	}
+	return quota(
+		readFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us")),
+		readFile(filepath.Join(root, "cpu", "cpu.cfs_period_us")),
+	)
+}
+
+func quota(quotaUs, periodUs string) (float64, bool) {
+	q, err := strconv.ParseInt(quotaUs, 10, 64)
+	if err != nil || q <= 0 { // "max" under v2, -1 under v1
+		return 0, false
+	}
+	p, err := strconv.ParseInt(periodUs, 10, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return float64(q) / float64(p), true
+}
+
+// memoryLimit returns the memory limit of the cgroup at root in bytes,
+// from memory.max under cgroup v2 or memory.limit_in_bytes under v1, and
+// false when it has none.
+func memoryLimit(root string) (int64, bool) {
+	value := readFile(filepath.Join(root, "memory.max"))
+	if value == "" {
+		value = readFile(filepath.Join(root, "memory", "memory.limit_in_bytes"))
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 || limit >= cgroupV1Unlimited { // "max" under v2
+		return 0, false
+	}
+	return limit, true
+}
+
+// readFile returns the trimmed content of path, or "" when it cannot be
+// read.
+func readFile(path string) string {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
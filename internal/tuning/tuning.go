@@ -0,0 +1,102 @@
+// Package tuning sizes the Go runtime for the container the consumer runs
+// in: GOMAXPROCS for its cgroup CPU limit, and the garbage collector's
+// GOGC and soft memory limit for its cgroup memory limit.
+package tuning
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// defaultMemoryLimit is the soft memory limit of a process without a cgroup
+// memory limit or RUNTIME_MEMORY_LIMIT.
+const defaultMemoryLimit = 2 << 30
+
+// minMaxProcs is the fewest threads a CPU limit brings GOMAXPROCS down to,
+// as the runtime does, so a fractional limit still lets the collector run
+// beside the program.
+const minMaxProcs = 2
+
+// Settings are the runtime settings in effect after Apply, and where each
+// came from: an environment variable of the runtime, the configuration,
+// the cgroup or a default.
+type Settings struct {
+	MaxProcsSource    string
+	GCPercentSource   string
+	MemoryLimitSource string
+	// CPULimit and CgroupMemory are the cgroup limits found, 0 for none.
+	CPULimit     float64
+	CgroupMemory int64
+	MemoryLimit  int64
+	MaxProcs     int
+	GCPercent    int
+}
+
+// Apply sets GOGC and the soft memory limit from cfg, unless GOGC or
+// GOMEMLIMIT are set, and lowers GOMAXPROCS to the cgroup CPU limit,
+// unless GOMAXPROCS is set. It returns the settings in effect.
+func Apply(cfg *config.RuntimeConfig) Settings {
+	return apply(cfg, cgroupRoot)
+}
+
+func apply(cfg *config.RuntimeConfig, root string) Settings {
+	var s Settings
+	s.CPULimit, _ = cpuLimit(root)
+	s.CgroupMemory, _ = memoryLimit(root)
+	applyMaxProcs(&s)
+	applyGCPercent(&s, cfg)
+	applyMemoryLimit(&s, cfg)
+	return s
+}
+
+// applyMaxProcs is the automaxprocs behavior: GOMAXPROCS is the CPU limit
+// rounded up. Since Go 1.25 the runtime does the same, and keeps following
+// the limit as it changes, so this only steps in when the runtime's own
+// value is higher, as it is with GODEBUG=containermaxprocs=0 or on an older
+// toolchain.
+func applyMaxProcs(s *Settings) {
+	s.MaxProcs, s.MaxProcsSource = runtime.GOMAXPROCS(0), "runtime"
+	if os.Getenv("GOMAXPROCS") != "" {
+		s.MaxProcsSource = "GOMAXPROCS"
+		return
+	}
+	if s.CPULimit == 0 {
+		return
+	}
+	limit := max(int(math.Ceil(s.CPULimit)), minMaxProcs)
+	if s.MaxProcs > limit {
+		runtime.GOMAXPROCS(limit)
+		s.MaxProcs, s.MaxProcsSource = limit, "cgroup"
+	}
+}
+
+func applyGCPercent(s *Settings, cfg *config.RuntimeConfig) {
+	if os.Getenv("GOGC") != "" {
+		// SetGCPercent is the only way to read it back.
+		s.GCPercent, s.GCPercentSource = debug.SetGCPercent(100), "GOGC"
+		debug.SetGCPercent(s.GCPercent)
+		return
+	}
+	debug.SetGCPercent(cfg.GCPercent)
+	s.GCPercent, s.GCPercentSource = cfg.GCPercent, "config"
+}
+
+func applyMemoryLimit(s *Settings, cfg *config.RuntimeConfig) {
+	switch {
+	case os.Getenv("GOMEMLIMIT") != "":
+		s.MemoryLimit, s.MemoryLimitSource = debug.SetMemoryLimit(-1), "GOMEMLIMIT"
+		return
+	case cfg.MemoryLimit > 0:
+		s.MemoryLimit, s.MemoryLimitSource = int64(cfg.MemoryLimit), "config"
+	case s.CgroupMemory > 0:
+		s.MemoryLimit = int64(float64(s.CgroupMemory) * float64(cfg.MemoryLimitPercent) / 100)
+		s.MemoryLimitSource = "cgroup"
+	default:
+		s.MemoryLimit, s.MemoryLimitSource = defaultMemoryLimit, "default"
+	}
+	debug.SetMemoryLimit(s.MemoryLimit)
+}
@@ -0,0 +1,118 @@
+package tuning
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+)
+
+// cgroupFiles writes files, by path relative to a new cgroup root, and
+// returns the root.
+func cgroupFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return root
+}
+
+func TestCgroupLimits(t *testing.T) {
+	for _, tt := range []struct {
+		files   map[string]string
+		name    string
+		wantCPU float64
+		wantMem int64
+	}{
+		{
+			name:    "v2",
+			files:   map[string]string{"cpu.max": "150000 100000", "memory.max": "536870912"},
+			wantCPU: 1.5,
+			wantMem: 512 << 20,
+		},
+		{
+			name:  "v2 unlimited",
+			files: map[string]string{"cpu.max": "max 100000", "memory.max": "max"},
+		},
+		{
+			name: "v1",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "300000",
+				"cpu/cpu.cfs_period_us":        "100000",
+				"memory/memory.limit_in_bytes": "1073741824",
+			},
+			wantCPU: 3,
+			wantMem: 1 << 30,
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1",
+				"cpu/cpu.cfs_period_us":        "100000",
+				"memory/memory.limit_in_bytes": "9223372036854771712",
+			},
+		},
+		{name: "no cgroup"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cgroupFiles(t, tt.files)
+			if got, _ := cpuLimit(root); got != tt.wantCPU {
+				t.Errorf("cpuLimit() = %v; want %v", got, tt.wantCPU)
+			}
+			if got, _ := memoryLimit(root); got != tt.wantMem {
+				t.Errorf("memoryLimit() = %d; want %d", got, tt.wantMem)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, env := range []string{"GOMAXPROCS", "GOGC", "GOMEMLIMIT"} {
+		t.Setenv(env, "")
+	}
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+	defer debug.SetGCPercent(debug.SetGCPercent(100))
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	root := cgroupFiles(t, map[string]string{"cpu.max": "50000 100000", "memory.max": "1073741824"})
+	cfg := config.RuntimeConfig{GCPercent: 150, MemoryLimitPercent: 50}
+	s := apply(&cfg, root)
+
+	if want := min(procs, minMaxProcs); s.CPULimit != 0.5 || s.MaxProcs != want || runtime.GOMAXPROCS(0) != want {
+		t.Errorf("CPULimit/MaxProcs = %v/%d, GOMAXPROCS %d; want 0.5/%d", s.CPULimit, s.MaxProcs,
+			runtime.GOMAXPROCS(0), want)
+	}
+	if s.GCPercent != 150 || s.GCPercentSource != "config" {
+		t.Errorf("GCPercent = %d (%s); want 150 (config)", s.GCPercent, s.GCPercentSource)
+	}
+	if s.MemoryLimit != 512<<20 || s.MemoryLimitSource != "cgroup" || debug.SetMemoryLimit(-1) != 512<<20 {
+		t.Errorf("MemoryLimit = %d (%s); want %d (cgroup)", s.MemoryLimit, s.MemoryLimitSource, 512<<20)
+	}
+
+	cfg.MemoryLimit = 256 << 20
+	if s = apply(&cfg, t.TempDir()); s.MemoryLimit != 256<<20 || s.MemoryLimitSource != "config" {
+		t.Errorf("MemoryLimit = %d (%s); want %d (config)", s.MemoryLimit, s.MemoryLimitSource, 256<<20)
+	}
+
+	cfg.MemoryLimit = 0
+	if s = apply(&cfg, t.TempDir()); s.MemoryLimit != defaultMemoryLimit || s.MemoryLimitSource != "default" {
+		t.Errorf("MemoryLimit = %d (%s); want %d (default)", s.MemoryLimit, s.MemoryLimitSource, defaultMemoryLimit)
+	}
+
+	t.Setenv("GOMEMLIMIT", "1GiB")
+	t.Setenv("GOGC", "300")
+	if s = apply(&cfg, root); s.MemoryLimitSource != "GOMEMLIMIT" || s.GCPercentSource != "GOGC" {
+		t.Errorf("sources = %s/%s; want GOMEMLIMIT/GOGC", s.MemoryLimitSource, s.GCPercentSource)
+	}
+}
@@ -0,0 +1,179 @@
+// Package ackclient implements the ACK protocol of the syslog consumer for
+// the Go services that receive its batches: Decode reads a published
+// payload, and Marshal, Answer and Sender build and send the ACKs that
+// finalize its entries or leave them pending, signed when the consumer
+// requires it. The consumer parses ACKs with this package, so both ends
+// share one definition of the protocol.
+package ackclient
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+)
+
+// Version is the version of the ACK protocol this package speaks. Marshal
+// writes it in "v", and Parse rejects payloads of a later version; a
+// payload without "v" is version 1.
+const Version = 1
+
+// MaxRetryAfter bounds RetryAfter, so a bogus value cannot overflow the
+// duration or park entries for good.
+const MaxRetryAfter = 24 * time.Hour
+
+// ErrVersion reports an ACK of a protocol version after Version.
+var ErrVersion = errors.New("ack: unsupported protocol version")
+
+// Ack is the answer to some entries of one stream: Ack true finalizes
+// them, false leaves them pending to be delivered again, under the policy
+// the consumer maps Reason to and not before RetryAfter when it is set.
+type Ack struct {
+	Stream     string
+	Reason     string
+	IDs        []string
+	RetryAfter time.Duration
+	Ack        bool
+}
+
+// Accept returns the ACK finalizing ids of stream.
+func Accept(stream string, ids ...string) Ack {
+	return Ack{Stream: stream, IDs: ids, Ack: true}
+}
+
+// Reject returns the NACK of ids of stream, with an optional reason and
+// retry hint.
+func Reject(stream, reason string, retryAfter time.Duration, ids ...string) Ack {
+	return Ack{Stream: stream, Reason: reason, IDs: ids, RetryAfter: retryAfter}
+}
+
+// Validate reports why the consumer would drop a, if it would.
+func (a *Ack) Validate() error {
+	if len(a.IDs) == 0 {
+		return errors.New("ack missing required field: ids")
+	}
+	if a.Stream == "" {
+		return errors.New("ack missing required field: stream")
+	}
+	return nil
+}
+
+// Marshal renders a as the payload {"v":1,"ids":[...],"stream":"…",
+// "ack":bool}, with "reason" and "retry_after" (seconds) on a NACK that
+// has them, and signs it with key unless key is empty (see Sign).
+func Marshal(a *Ack, key []byte) ([]byte, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	b := jsonfast.New(64 + 24*len(a.IDs))
+	b.BeginObject()
+	b.AddIntField("v", Version)
+	b.AddStringArrayField("ids", a.IDs)
+	b.AddStringField("stream", a.Stream)
+	b.AddBoolField("ack", a.Ack)
+	if !a.Ack && a.Reason != "" {
+		b.AddStringField("reason", a.Reason)
+	}
+	if !a.Ack && a.RetryAfter > 0 {
+		b.AddFloat64Field("retry_after", min(a.RetryAfter, MaxRetryAfter).Seconds())
+	}
+	b.EndObject()
+	return Sign(b.Bytes(), key), nil
+}
+
+// Parse reads an ACK payload. With a key, the payload must carry a valid
+// signature under it (ErrSignature otherwise); without one, a signature is
+// ignored. IDs, Stream and Reason are copied out of payload.
+// A Control payload returns ErrControl.
+func Parse(payload, key []byte) (Ack, error) {
+	p, err := parse(payload, key)
+	if err != nil {
+		return Ack{}, err
+	}
+	if p.found&8 != 0 {
+		return Ack{}, ErrControl
+	}
+	if p.found&1 == 0 {
+		return Ack{}, errors.New("ack missing required field: ids")
+	}
+	if p.found&2 == 0 {
+		return Ack{}, errors.New("ack missing required field: stream")
+	}
+	if err := p.ack.Validate(); err != nil {
+		return Ack{}, err
+	}
+	return p.ack, nil
+}
+
+// parse checks the signature and the version of payload and reads its
+// fields.
+func parse(payload, key []byte) (parser, error) {
+	var p parser
+	if len(key) > 0 && !Verify(payload, key) {
+		return p, ErrSignature
+	}
+	if !jsonfast.IterateFields(payload, p.handleField) {
+		return p, errors.New("ack: malformed JSON")
+	}
+	if p.version > Version {
+		return p, ErrVersion
+	}
+	return p, nil
+}
+
+type parser struct {
+	ack     Ack
+	control Control
+	version float64
+	found   int // bitmask: 1=ids, 2=stream, 4=ack, 8=control
+}
+
+func (p *parser) handleField(key, value []byte) bool {
+	switch string(key) {
+	case `"v"`:
+		if v, ok := jsonfast.DecodeFloat64(value); ok {
+			p.version = v
+		}
+	case `"ids"`:
+		jsonfast.IterateStringArray(value, func(id string) bool {
+			p.ack.IDs = append(p.ack.IDs, strings.Clone(id))
+			return true
+		})
+		p.found |= 1
+	case `"stream"`:
+		if s, ok := jsonfast.DecodeString(value); ok {
+			p.ack.Stream = s
+		}
+		p.found |= 2
+	case `"ack"`:
+		if v, ok := jsonfast.DecodeBool(value); ok {
+			p.ack.Ack = v
+		}
+		p.found |= 4
+	case `"reason"`:
+		if s, ok := jsonfast.DecodeString(value); ok {
+			p.ack.Reason = s
+		}
+	case `"retry_after"`:
+		if v, ok := jsonfast.DecodeFloat64(value); ok {
+			p.ack.RetryAfter = retryAfter(v)
+		}
+	default:
+		p.handleControlField(key, value)
+	}
+	return true
+}
+
+// retryAfter converts retry_after seconds to a duration; values that are
+// not positive numbers mean "no hint".
+func retryAfter(seconds float64) time.Duration {
+	if math.IsNaN(seconds) || seconds <= 0 {
+		return 0
+	}
+	if seconds >= MaxRetryAfter.Seconds() {
+		return MaxRetryAfter
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
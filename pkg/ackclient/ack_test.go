@@ -0,0 +1,80 @@
+package ackclient
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	for _, a := range []Ack{
+		Accept("s1", "1-0"),
+		Accept("s1", "1-0", "2-0", "3-0"),
+		Reject("s1", "overloaded", 1500*time.Millisecond, "1-0"),
+		Reject("s\"2", "", 0, "1-0", "2-0"),
+	} {
+		payload, err := Marshal(&a, nil)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) error = %v", a, err)
+		}
+		got, err := Parse(payload, nil)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", payload, err)
+		}
+		if got.Stream != a.Stream || got.Reason != a.Reason || got.RetryAfter != a.RetryAfter ||
+			got.Ack != a.Ack || !slices.Equal(got.IDs, a.IDs) {
+			t.Errorf("Parse(Marshal(%+v)) = %+v", a, got)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	a := Reject("s1", "overloaded", 48*time.Hour, "1-0")
+	payload, err := Marshal(&a, nil)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"v":1,"ids":["1-0"],"stream":"s1","ack":false,"reason":"overloaded","retry_after":86400}`
+	if string(payload) != want {
+		t.Errorf("Marshal() = %s; want %s", payload, want)
+	}
+
+	// An ACK drops the NACK fields.
+	a.Ack = true
+	if payload, _ = Marshal(&a, nil); string(payload) != `{"v":1,"ids":["1-0"],"stream":"s1","ack":true}` {
+		t.Errorf("Marshal(ack) = %s", payload)
+	}
+
+	for _, bad := range []Ack{Accept("s1"), Accept("", "1-0")} {
+		if _, err := Marshal(&bad, nil); err == nil {
+			t.Errorf("Marshal(%+v) error = nil; want error", bad)
+		}
+	}
+}
+
+func TestParse_Version(t *testing.T) {
+	for payload, want := range map[string]error{
+		`{"ids":["1-0"],"stream":"s1","ack":true}`:         nil,
+		`{"v":1,"ids":["1-0"],"stream":"s1","ack":true}`:   nil,
+		`{"v":2,"ids":["1-0"],"stream":"s1","ack":true}`:   ErrVersion,
+		`{"v":"x","ids":["1-0"],"stream":"s1","ack":true}`: nil,
+	} {
+		if _, err := Parse([]byte(payload), nil); !errors.Is(err, want) {
+			t.Errorf("Parse(%s) error = %v; want %v", payload, err, want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for seconds, want := range map[float64]time.Duration{
+		1.5:  1500 * time.Millisecond,
+		0:    0,
+		-3:   0,
+		1e12: MaxRetryAfter,
+	} {
+		if got := retryAfter(seconds); got != want {
+			t.Errorf("retryAfter(%v) = %v; want %v", seconds, got, want)
+		}
+	}
+}
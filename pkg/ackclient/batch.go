@@ -0,0 +1,110 @@
+package ackclient
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+)
+
+// maxBatchBytes bounds a decompressed batch, like the consumer's default
+// MAX_DECOMPRESS_BYTES.
+const maxBatchBytes = 256 * 1024 * 1024
+
+// Entry is one "id\tstream\t{json}" line of a published batch. Object is
+// the entry's JSON object, or the envelope of a truncated or split record
+// as the README describes; the chunks of a split record are entries with
+// the same ID and Stream, to be answered once all of them arrived.
+type Entry struct {
+	ID     string
+	Stream string
+	Object []byte
+}
+
+// Batch is a decoded publish payload.
+type Batch struct {
+	// Origin names the consumer that published the payload, when it
+	// started with an origin frame (MQTT_ORIGIN_MARKER).
+	Origin  string
+	Entries []Entry
+	// Malformed counts the lines skipped for lacking an ID or a stream.
+	Malformed int
+}
+
+// decoder decompresses batches; DecodeAll is safe for concurrent use.
+var decoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxBatchBytes))
+})
+
+// Decode reads a payload the consumer published on its publish topic,
+// zstd-compressed or not. The entries' strings and objects are copies, so
+// payload may be reused afterwards.
+func Decode(payload []byte) (Batch, error) {
+	var batch Batch
+	if origin, ok := compress.Origin(payload); ok {
+		batch.Origin = string(origin)
+	}
+	if compress.IsCompressed(payload) {
+		dec, err := decoder()
+		if err != nil {
+			return Batch{}, fmt.Errorf("ack: zstd decoder: %w", err)
+		}
+		if payload, err = dec.DecodeAll(payload, nil); err != nil {
+			return Batch{}, fmt.Errorf("ack: zstd decompress failed: %w", err)
+		}
+	}
+	for line := range bytes.SplitSeq(payload, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		id, rest, ok := bytes.Cut(line, []byte{'\t'})
+		if !ok {
+			batch.Malformed++
+			continue
+		}
+		stream, object, ok := bytes.Cut(rest, []byte{'\t'})
+		if !ok || len(id) == 0 || len(stream) == 0 {
+			batch.Malformed++
+			continue
+		}
+		batch.Entries = append(batch.Entries, Entry{
+			ID: string(id), Stream: string(stream), Object: bytes.Clone(object),
+		})
+	}
+	return batch, nil
+}
+
+// Answer builds the ACKs of entries, one per stream and verdict: verdict
+// returns the Ack for an entry, of which only Ack, Reason and RetryAfter
+// are used. An ID is answered once, by the verdict on its first entry, and
+// the ACKs come in the order of their first entries.
+func Answer(entries []Entry, verdict func(Entry) Ack) []Ack {
+	type group struct {
+		stream, reason string
+		retryAfter     time.Duration
+		ack            bool
+	}
+	var acks []Ack
+	index := make(map[group]int)
+	seen := make(map[[2]string]bool, len(entries))
+	for _, e := range entries {
+		if seen[[2]string{e.Stream, e.ID}] {
+			continue
+		}
+		seen[[2]string{e.Stream, e.ID}] = true
+		v := verdict(e)
+		g := group{stream: e.Stream, reason: v.Reason, retryAfter: v.RetryAfter, ack: v.Ack}
+		i, ok := index[g]
+		if !ok {
+			i = len(acks)
+			index[g] = i
+			acks = append(acks, Ack{Stream: e.Stream, Reason: v.Reason, RetryAfter: v.RetryAfter, Ack: v.Ack})
+		}
+		acks[i].IDs = append(acks[i].IDs, e.ID)
+	}
+	return acks
+}
@@ -0,0 +1,65 @@
+package ackclient
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+)
+
+const testBatch = "1-0\ts1\t{\"a\":1}\n2-0\ts2\t{}\nbroken\n\n3-0\t\t{}\n"
+
+func TestDecode(t *testing.T) {
+	want := []Entry{{ID: "1-0", Stream: "s1", Object: []byte(`{"a":1}`)}, {ID: "2-0", Stream: "s2", Object: []byte(`{}`)}}
+	enc := compress.NewEncoder()
+	for name, tc := range map[string]struct {
+		origin  string
+		payload []byte
+	}{
+		"plain":      {"", []byte(testBatch)},
+		"compressed": {"", compress.EncodeWith(enc, nil, []byte(testBatch))},
+		"marked":     {"consumer-1", compress.EncodeMarked(enc, nil, []byte(testBatch), []byte("consumer-1"))},
+	} {
+		batch, err := Decode(tc.payload)
+		if err != nil {
+			t.Fatalf("Decode(%s) error = %v", name, err)
+		}
+		if !slices.EqualFunc(batch.Entries, want, func(a, b Entry) bool {
+			return a.ID == b.ID && a.Stream == b.Stream && string(a.Object) == string(b.Object)
+		}) {
+			t.Errorf("Decode(%s) entries = %+v; want %+v", name, batch.Entries, want)
+		}
+		if batch.Malformed != 2 || batch.Origin != tc.origin {
+			t.Errorf("Decode(%s) malformed, origin = %d, %q; want 2, %q", name, batch.Malformed, batch.Origin, tc.origin)
+		}
+	}
+
+	if _, err := Decode([]byte{0x28, 0xB5, 0x2F, 0xFD, 0xff}); err == nil {
+		t.Error("Decode() of a corrupt frame error = nil")
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	entries := []Entry{
+		{ID: "1-0", Stream: "s1"}, {ID: "2-0", Stream: "s1"}, {ID: "3-0", Stream: "s2"},
+		{ID: "4-0", Stream: "s1"}, {ID: "1-0", Stream: "s1"}, // the second chunk of 1-0
+	}
+	acks := Answer(entries, func(e Entry) Ack {
+		if e.ID == "2-0" || e.ID == "4-0" {
+			return Reject("", "overloaded", time.Minute)
+		}
+		return Accept("")
+	})
+	want := []Ack{
+		Accept("s1", "1-0"),
+		Reject("s1", "overloaded", time.Minute, "2-0", "4-0"),
+		Accept("s2", "3-0"),
+	}
+	if !slices.EqualFunc(acks, want, func(a, b Ack) bool {
+		return a.Stream == b.Stream && a.Reason == b.Reason && a.RetryAfter == b.RetryAfter &&
+			a.Ack == b.Ack && slices.Equal(a.IDs, b.IDs)
+	}) {
+		t.Errorf("Answer() = %+v; want %+v", acks, want)
+	}
+}
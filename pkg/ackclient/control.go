@@ -0,0 +1,133 @@
+package ackclient
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ubyte-source/go-jsonfast"
+)
+
+// Control kinds.
+const (
+	ControlPause  = "pause"
+	ControlResume = "resume"
+	ControlRate   = "rate"
+)
+
+// MaxControlDuration bounds Control.Duration, so a bogus value cannot stop
+// the consumer for good.
+const MaxControlDuration = time.Hour
+
+// ErrControl reports that Parse was given a Control payload, which
+// ParseControl reads.
+var ErrControl = errors.New("ack: control message")
+
+// Control is a flow-control message a receiver publishes on the ACK topic
+// to slow the consumer down, honored under MQTT_ACK_FLOW_CONTROL: "pause"
+// holds its publishes for Duration, "resume" ends a pause early, and
+// "rate" caps them at Limit entries per second, for Duration when it is
+// set and until the next "rate" otherwise; a Limit of zero lifts the cap.
+type Control struct {
+	Kind     string
+	Duration time.Duration
+	Limit    int
+}
+
+// Pause returns the Control holding the publishes for d.
+func Pause(d time.Duration) Control {
+	return Control{Kind: ControlPause, Duration: d}
+}
+
+// Resume returns the Control ending a pause.
+func Resume() Control {
+	return Control{Kind: ControlResume}
+}
+
+// Rate returns the Control capping the publishes at limit entries per
+// second, for d unless d is zero.
+func Rate(limit int, d time.Duration) Control {
+	return Control{Kind: ControlRate, Limit: limit, Duration: d}
+}
+
+// Validate reports why the consumer would drop c, if it would.
+func (c *Control) Validate() error {
+	switch {
+	case c.Kind == ControlPause && c.Duration <= 0:
+		return errors.New("control pause needs a positive duration")
+	case c.Kind == ControlRate && c.Limit < 0:
+		return errors.New("control rate needs a limit that is not negative")
+	case c.Kind != ControlPause && c.Kind != ControlRate && c.Kind != ControlResume:
+		return errors.New("control must be pause, resume or rate")
+	}
+	return nil
+}
+
+// MarshalControl renders c as the payload {"v":1,"control":"…"}, with
+// "duration" (a Go duration) and "limit" when they apply, and signs it
+// with key unless key is empty (see Sign).
+func MarshalControl(c *Control, key []byte) ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	b := jsonfast.New(80)
+	b.BeginObject()
+	b.AddIntField("v", Version)
+	b.AddStringField("control", c.Kind)
+	if c.Kind != ControlResume && c.Duration > 0 {
+		b.AddStringField("duration", min(c.Duration, MaxControlDuration).String())
+	}
+	if c.Kind == ControlRate {
+		b.AddIntField("limit", c.Limit)
+	}
+	b.EndObject()
+	return Sign(b.Bytes(), key), nil
+}
+
+// ParseControl reads a Control payload, signed like an ACK (see Parse).
+// "duration" is a Go duration such as "30s" or a number of seconds.
+func ParseControl(payload, key []byte) (Control, error) {
+	p, err := parse(payload, key)
+	if err != nil {
+		return Control{}, err
+	}
+	if p.found&8 == 0 {
+		return Control{}, errors.New("control missing required field: control")
+	}
+	if err := p.control.Validate(); err != nil {
+		return Control{}, err
+	}
+	return p.control, nil
+}
+
+func (p *parser) handleControlField(key, value []byte) {
+	switch string(key) {
+	case `"control"`:
+		if s, ok := jsonfast.DecodeString(value); ok {
+			p.control.Kind = s
+		}
+		p.found |= 8
+	case `"duration"`:
+		p.control.Duration = controlDuration(value)
+	case `"limit"`:
+		if v, ok := jsonfast.DecodeFloat64(value); ok && !math.IsNaN(v) {
+			p.control.Limit = int(max(min(v, math.MaxInt32), -1))
+		}
+	}
+}
+
+// controlDuration reads a duration given as a Go duration string or a
+// number of seconds; anything else, or a value that is not positive,
+// means none.
+func controlDuration(value []byte) time.Duration {
+	var d time.Duration
+	if s, ok := jsonfast.DecodeString(value); ok {
+		d, _ = time.ParseDuration(s)
+	} else if v, ok := jsonfast.DecodeFloat64(value); ok && !math.IsNaN(v) {
+		d = time.Duration(min(v, MaxControlDuration.Seconds()) * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0
+	}
+	return min(d, MaxControlDuration)
+}
@@ -0,0 +1,77 @@
+package ackclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarshalControl_RoundTrip(t *testing.T) {
+	key := []byte("secret")
+	for _, c := range []Control{
+		Pause(30 * time.Second),
+		Resume(),
+		Rate(5000, 0),
+		Rate(0, 2*time.Minute),
+	} {
+		payload, err := MarshalControl(&c, key)
+		if err != nil {
+			t.Fatalf("MarshalControl(%+v) error = %v", c, err)
+		}
+		got, err := ParseControl(payload, key)
+		if err != nil {
+			t.Fatalf("ParseControl(%s) error = %v", payload, err)
+		}
+		if got != c {
+			t.Errorf("ParseControl(MarshalControl(%+v)) = %+v", c, got)
+		}
+		if _, err := Parse(payload, key); !errors.Is(err, ErrControl) {
+			t.Errorf("Parse(%s) error = %v; want ErrControl", payload, err)
+		}
+	}
+
+	pause := Pause(2 * MaxControlDuration)
+	if payload, _ := MarshalControl(&pause, nil); string(payload) != `{"v":1,"control":"pause","duration":"1h0m0s"}` {
+		t.Errorf("MarshalControl(pause) = %s", payload)
+	}
+	for _, bad := range []Control{Pause(0), Rate(-1, 0), {Kind: "stop"}} {
+		if _, err := MarshalControl(&bad, nil); err == nil {
+			t.Errorf("MarshalControl(%+v) error = nil; want error", bad)
+		}
+	}
+}
+
+func TestParseControl(t *testing.T) {
+	for payload, want := range map[string]Control{
+		`{"control":"pause","duration":"30s"}`:  Pause(30 * time.Second),
+		`{"control":"pause","duration":1.5}`:    Pause(1500 * time.Millisecond),
+		`{"control":"pause","duration":"48h"}`:  Pause(MaxControlDuration),
+		`{"control":"rate","limit":5000}`:       Rate(5000, 0),
+		`{"control":"rate","limit":10.7}`:       Rate(10, 0),
+		`{"v":1,"control":"resume","limit":3}`:  {Kind: ControlResume, Limit: 3},
+		`{"control":"rate","limit":0,"x":true}`: Rate(0, 0),
+	} {
+		got, err := ParseControl([]byte(payload), nil)
+		if err != nil || got != want {
+			t.Errorf("ParseControl(%s) = %+v, %v; want %+v", payload, got, err, want)
+		}
+	}
+
+	for _, payload := range []string{
+		`{"control":"pause"}`,
+		`{"control":"pause","duration":"-5s"}`,
+		`{"control":"rate","limit":-3}`,
+		`{"control":"slow"}`,
+		`{"ids":["1-0"],"stream":"s1","ack":true}`,
+		`{"v":2,"control":"resume"}`,
+		`{"control":`,
+	} {
+		if _, err := ParseControl([]byte(payload), nil); err == nil {
+			t.Errorf("ParseControl(%s) error = nil; want error", payload)
+		}
+	}
+
+	if _, err := ParseControl([]byte(`{"control":"resume"}`), []byte("secret")); !errors.Is(err, ErrSignature) {
+		t.Errorf("ParseControl(unsigned) error = %v; want ErrSignature", err)
+	}
+}
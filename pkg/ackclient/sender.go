@@ -0,0 +1,33 @@
+package ackclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sender sends ACKs on the consumer's ACK topic through the caller's MQTT
+// client, which this package leaves to the caller.
+type Sender struct {
+	// Publish sends payload on topic, e.g. through a paho client's Publish.
+	Publish func(topic string, payload []byte) error
+	// Topic is the consumer's MQTT_ACK_TOPIC, with its CN prefix if any.
+	Topic string
+	// Key signs every ACK when set; it must match MQTT_ACK_HMAC_KEY.
+	Key []byte
+}
+
+// Send publishes each of acks as its own payload. It goes on past a
+// failure, and returns the failures joined.
+func (s *Sender) Send(acks ...Ack) error {
+	var errs []error
+	for i := range acks {
+		payload, err := Marshal(&acks[i], s.Key)
+		if err == nil {
+			err = s.Publish(s.Topic, payload)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ack of %d entries of %s: %w", len(acks[i].IDs), acks[i].Stream, err))
+		}
+	}
+	return errors.Join(errs...)
+}
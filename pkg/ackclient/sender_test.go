@@ -0,0 +1,35 @@
+package ackclient
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSender_Send(t *testing.T) {
+	var sent []string
+	s := Sender{
+		Publish: func(topic string, payload []byte) error {
+			if topic != "acks" {
+				t.Errorf("Publish() topic = %s; want acks", topic)
+			}
+			if !Verify(payload, testKey) {
+				t.Errorf("Publish() payload %s is not signed", payload)
+			}
+			sent = append(sent, string(payload))
+			if len(sent) == 1 {
+				return errors.New("broker down")
+			}
+			return nil
+		},
+		Topic: "acks",
+		Key:   testKey,
+	}
+
+	err := s.Send(Accept("s1", "1-0"), Accept("s1"), Accept("s2", "2-0"))
+	if len(sent) != 2 {
+		t.Errorf("Send() published %d payloads; want 2, past the failure and the invalid ACK", len(sent))
+	}
+	if err == nil {
+		t.Fatal("Send() error = nil; want the publish and validation errors")
+	}
+}
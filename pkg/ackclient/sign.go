@@ -0,0 +1,60 @@
+package ackclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrSignature reports an ACK without a valid signature under the key the
+// consumer was given in MQTT_ACK_HMAC_KEY.
+var ErrSignature = errors.New("ack: missing or invalid signature")
+
+// sigField starts the signature, the last member of a signed payload.
+const sigField = `,"sig":"`
+
+// Sign returns payload, a JSON object with at least one member, with the
+// lowercase hex HMAC-SHA256 of payload under key added as its last member
+// "sig". Removing that member gives back the signed bytes, so any language
+// can sign the compact object it built the same way. An empty key, or a
+// payload that is not an object, returns payload unchanged.
+func Sign(payload, key []byte) []byte {
+	payload = bytes.TrimRight(payload, " \t\r\n")
+	if len(key) == 0 || !bytes.HasSuffix(payload, []byte("}")) {
+		return payload
+	}
+	sum := mac(payload, key)
+	out := make([]byte, 0, len(payload)+len(sigField)+hex.EncodedLen(len(sum))+1)
+	out = append(out, payload[:len(payload)-1]...)
+	out = append(out, sigField...)
+	out = hex.AppendEncode(out, sum)
+	return append(out, `"}`...)
+}
+
+// Verify reports whether payload ends with a "sig" member holding the
+// HMAC-SHA256 under key of the payload without it.
+func Verify(payload, key []byte) bool {
+	payload = bytes.TrimRight(payload, " \t\r\n")
+	i := bytes.LastIndex(payload, []byte(sigField))
+	if i < 0 {
+		return false
+	}
+	sig, ok := bytes.CutSuffix(payload[i+len(sigField):], []byte(`"}`))
+	if !ok {
+		return false
+	}
+	got := make([]byte, hex.DecodedLen(len(sig)))
+	if _, err := hex.Decode(got, sig); err != nil {
+		return false
+	}
+	signed := append(payload[:i:i], '}')
+	return hmac.Equal(got, mac(signed, key))
+}
+
+func mac(payload, key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(payload)
+	return h.Sum(nil)
+}
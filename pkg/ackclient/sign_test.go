@@ -0,0 +1,70 @@
+package ackclient
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testKey = []byte("secret")
+
+func TestSign_Verify(t *testing.T) {
+	a := Accept("s1", "1-0", "2-0")
+	payload, err := Marshal(&a, testKey)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	unsigned, _ := Marshal(&a, nil)
+	if !bytes.HasPrefix(payload, unsigned[:len(unsigned)-1]) || !bytes.Contains(payload, []byte(`,"sig":"`)) {
+		t.Fatalf("Marshal() with a key = %s; want %s with a sig member", payload, unsigned)
+	}
+	if !Verify(payload, testKey) {
+		t.Error("Verify() of a signed payload = false")
+	}
+	if !Verify(append(payload, '\n'), testKey) {
+		t.Error("Verify() of a signed payload with a trailing newline = false")
+	}
+	if Verify(payload, []byte("other")) {
+		t.Error("Verify() under another key = true")
+	}
+	tampered := bytes.Replace(payload, []byte(`"2-0"`), []byte(`"3-0"`), 1)
+	if Verify(tampered, testKey) {
+		t.Error("Verify() of a tampered payload = true")
+	}
+	for _, bad := range []string{string(unsigned), `{"sig":"zz"}`, `,"sig":"`, ``} {
+		if Verify([]byte(bad), testKey) {
+			t.Errorf("Verify(%s) = true", bad)
+		}
+	}
+}
+
+func TestSign_Unchanged(t *testing.T) {
+	for _, tc := range []struct {
+		payload string
+		key     []byte
+	}{
+		{`{"ids":["1-0"]}`, nil},
+		{`not an object`, testKey},
+		{``, testKey},
+	} {
+		if got := Sign([]byte(tc.payload), tc.key); string(got) != tc.payload {
+			t.Errorf("Sign(%q) = %q; want it unchanged", tc.payload, got)
+		}
+	}
+}
+
+func TestParse_Signature(t *testing.T) {
+	a := Reject("s1", "overloaded", 0, "1-0")
+	signed, _ := Marshal(&a, testKey)
+	unsigned, _ := Marshal(&a, nil)
+
+	if got, err := Parse(signed, testKey); err != nil || got.Reason != "overloaded" {
+		t.Errorf("Parse(signed, key) = %+v, %v", got, err)
+	}
+	if _, err := Parse(signed, nil); err != nil {
+		t.Errorf("Parse(signed) without a key error = %v; want the signature ignored", err)
+	}
+	if _, err := Parse(unsigned, testKey); !errors.Is(err, ErrSignature) {
+		t.Errorf("Parse(unsigned, key) error = %v; want ErrSignature", err)
+	}
+}
@@ -0,0 +1,260 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// deadLetterReplayer is the part of *redis.Client the replay endpoint
+// drives.
+type deadLetterReplayer interface {
+	ReplayDeadLetters(ctx context.Context, opts *redis.ReplayOptions) (redis.ReplayResult, error)
+}
+
+// dlqReplayHandler serves POST /admin/dlq/replay. The query parameters
+// dry_run, reason, target, limit and rate map to redis.ReplayOptions; the
+// response is the redis.ReplayResult as JSON, with an "error" field when
+// the replay stopped early.
+func dlqReplayHandler(replayer deadLetterReplayer, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseReplayOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := replayer.ReplayDeadLetters(r.Context(), &opts)
+		resp := struct {
+			Error string `json:"error,omitempty"`
+			redis.ReplayResult
+		}{ReplayResult: result}
+		status := http.StatusOK
+		if err != nil {
+			resp.Error = err.Error()
+			status = http.StatusInternalServerError
+			logger.Errorf(r.Context(), "DLQ replay failed after %d entries: %v", result.Replayed, err)
+		} else {
+			logger.Infof(r.Context(), "DLQ replay: %d replayed, %d skipped (dry run %t)",
+				result.Replayed, result.Skipped, opts.DryRun)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Errorf(r.Context(), "DLQ replay: write response: %v", err)
+		}
+	})
+}
+
+func parseReplayOptions(query url.Values) (redis.ReplayOptions, error) {
+	opts := redis.ReplayOptions{Reason: query.Get("reason"), Target: query.Get("target")}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("dry_run %q is not a boolean", v)
+		}
+		opts.DryRun = dryRun
+	}
+	var err error
+	if opts.Limit, err = parseCount(query, "limit"); err != nil {
+		return opts, err
+	}
+	opts.Rate, err = parseCount(query, "rate")
+	return opts, err
+}
+
+// parseCount returns the non-negative integer parameter name, 0 when absent.
+func parseCount(query url.Values, name string) (int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %q must be a non-negative integer", name, v)
+	}
+	return n, nil
+}
+
+// groupLagReader is the part of *redis.Client the lag status endpoint
+// reads.
+type groupLagReader interface {
+	GroupLags(ctx context.Context) ([]redis.GroupLag, error)
+}
+
+// lagStatusHandler serves GET /status/lag: the consumer group's lag on
+// every stream, read on request, with the totals over all of them.
+func lagStatusHandler(reader groupLagReader, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lags, err := reader.GroupLags(r.Context())
+		if err != nil {
+			logger.Errorf(r.Context(), "Lag status: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := struct {
+			Streams []redis.GroupLag `json:"streams"`
+			Lag     int64            `json:"lag"`
+			Pending int64            `json:"pending"`
+		}{Streams: lags}
+		for i := range lags {
+			resp.Lag += lags[i].Lag
+			resp.Pending += lags[i].Pending
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Errorf(r.Context(), "Lag status: write response: %v", err)
+		}
+	})
+}
+
+// captureSource is the part of *hotpath.HotPath the capture endpoint
+// reads.
+type captureSource interface {
+	Capture() (hotpath.CaptureDump, bool)
+}
+
+// captureHandler serves GET /debug/capture: the published payloads and
+// ACKs kept under Pipeline.CaptureSize, oldest first, as JSON.
+func captureHandler(source captureSource, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dump, ok := source.Capture()
+		if !ok {
+			http.Error(w, "capture disabled: PIPELINE_CAPTURE_SIZE is 0", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(dump); err != nil {
+			logger.Errorf(r.Context(), "Capture: write response: %v", err)
+		}
+	})
+}
+
+// defaultDetachWait bounds how long POST /admin/streams/detach waits for
+// the stream to drain when the request sets no wait.
+const defaultDetachWait = 30 * time.Second
+
+// streamAdmin is the part of *redis.Client the stream endpoints drive.
+type streamAdmin interface {
+	AttachStream(ctx context.Context, stream string) (bool, error)
+	DetachStream(ctx context.Context, stream string) (redis.StreamDetach, error)
+}
+
+// streamAttachHandler serves POST /admin/streams/attach?stream=: the
+// stream is read from the next fetch on, its consumer group created if
+// needed. The response says whether it was read before.
+func streamAttachHandler(admin streamAdmin, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := r.URL.Query().Get("stream")
+		if stream == "" {
+			http.Error(w, "stream is required", http.StatusBadRequest)
+			return
+		}
+		added, err := admin.AttachStream(r.Context(), stream)
+		if err != nil {
+			logger.Errorf(r.Context(), "Stream attach %s: %v", stream, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := struct {
+			Stream string `json:"stream"`
+			Added  bool   `json:"added"`
+		}{Stream: stream, Added: added}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Errorf(r.Context(), "Stream attach: write response: %v", err)
+		}
+	})
+}
+
+// streamDetachHandler serves POST /admin/streams/detach?stream=&wait=: the
+// stream is no longer read, and the response, a redis.StreamDetach, comes
+// once the entries this instance holds from it are acknowledged, with 200,
+// or after wait (30s by default) with 202 while they are still draining.
+func streamDetachHandler(admin streamAdmin, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		stream := query.Get("stream")
+		if stream == "" {
+			http.Error(w, "stream is required", http.StatusBadRequest)
+			return
+		}
+		wait := defaultDetachWait
+		if v := query.Get("wait"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, fmt.Sprintf("wait %q must be a positive duration", v), http.StatusBadRequest)
+				return
+			}
+			wait = d
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		detach, err := admin.DetachStream(ctx, stream)
+		cancel()
+		status := http.StatusOK
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusAccepted
+			logger.Warnf(r.Context(), "Stream %s detached with %d entries still pending", stream, detach.Pending)
+		case err != nil:
+			logger.Errorf(r.Context(), "Stream detach %s: %v", stream, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		default:
+			logger.Infof(r.Context(), "Stream %s detached and drained", stream)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(detach); err != nil {
+			logger.Errorf(r.Context(), "Stream detach: write response: %v", err)
+		}
+	})
+}
+
+// ackBufferResizer is the part of *hotpath.HotPath the buffer endpoint
+// drives.
+type ackBufferResizer interface {
+	ResizeAckBuffer(capacity int) error
+}
+
+// ackBufferHandler serves POST /admin/buffer?capacity=: the ACK queues are
+// replaced by ones holding capacity ACKs in all, and the old ones drained,
+// so a sustained backlog can be absorbed without a restart. It answers 503
+// while the hot path is not running.
+func ackBufferHandler(resizer ackBufferResizer, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capacity, err := parseCount(r.URL.Query(), "capacity")
+		if err == nil && capacity == 0 {
+			err = errors.New("capacity must be a positive integer")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := resizer.ResizeAckBuffer(capacity); err != nil {
+			logger.Errorf(r.Context(), "ACK buffer resize to %d: %v", capacity, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		logger.Infof(r.Context(), "ACK buffer resized to %d", capacity)
+
+		resp := struct {
+			Capacity int `json:"capacity"`
+		}{Capacity: capacity}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Errorf(r.Context(), "ACK buffer resize: write response: %v", err)
+		}
+	})
+}
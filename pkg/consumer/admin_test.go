@@ -0,0 +1,277 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+type stubReplayer struct {
+	err    error
+	opts   *redis.ReplayOptions
+	result redis.ReplayResult
+}
+
+func (s *stubReplayer) ReplayDeadLetters(_ context.Context, opts *redis.ReplayOptions) (redis.ReplayResult, error) {
+	s.opts = opts
+	return s.result, s.err
+}
+
+func serveReplay(t *testing.T, replayer *stubReplayer, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/admin/dlq/replay?"+query, http.NoBody)
+	rec := httptest.NewRecorder()
+	dlqReplayHandler(replayer, log.New()).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDLQReplayHandler_PassesOptions(t *testing.T) {
+	replayer := &stubReplayer{result: redis.ReplayResult{Replayed: 3, Skipped: 1}}
+	rec := serveReplay(t, replayer, "dry_run=true&reason=schema_error&target=replayed&limit=10&rate=50")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	want := redis.ReplayOptions{Reason: "schema_error", Target: "replayed", Limit: 10, Rate: 50, DryRun: true}
+	if *replayer.opts != want {
+		t.Errorf("options = %+v; want %+v", *replayer.opts, want)
+	}
+	var got redis.ReplayResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got != replayer.result {
+		t.Errorf("response = %+v; want %+v", got, replayer.result)
+	}
+}
+
+func TestDLQReplayHandler_BadParameters(t *testing.T) {
+	for _, query := range []string{"dry_run=maybe", "limit=-1", "rate=fast"} {
+		replayer := &stubReplayer{}
+		if rec := serveReplay(t, replayer, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d; want 400", query, rec.Code)
+		}
+		if replayer.opts != nil {
+			t.Errorf("%s: replay ran; want it rejected first", query)
+		}
+	}
+}
+
+func TestDLQReplayHandler_ReplayError(t *testing.T) {
+	replayer := &stubReplayer{result: redis.ReplayResult{Replayed: 2}, err: errors.New("xadd failed")}
+	rec := serveReplay(t, replayer, "")
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d; want 500", rec.Code)
+	}
+	var got struct {
+		Error    string `json:"error"`
+		Replayed int    `json:"replayed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Replayed != 2 || got.Error != "xadd failed" {
+		t.Errorf("response = %+v; want 2 replayed and the error", got)
+	}
+}
+
+type stubLagReader struct {
+	err  error
+	lags []redis.GroupLag
+}
+
+func (s *stubLagReader) GroupLags(context.Context) ([]redis.GroupLag, error) {
+	return s.lags, s.err
+}
+
+func TestLagStatusHandler(t *testing.T) {
+	reader := &stubLagReader{lags: []redis.GroupLag{
+		{Stream: "syslog:a", Lag: 10, Pending: 2, EntriesRead: 40, Exact: true},
+		{Stream: "syslog:b", Lag: 5, Pending: 1, EntriesRead: -1},
+	}}
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/status/lag", http.NoBody)
+	rec := httptest.NewRecorder()
+	lagStatusHandler(reader, log.New()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	var got struct {
+		Streams []redis.GroupLag `json:"streams"`
+		Lag     int64            `json:"lag"`
+		Pending int64            `json:"pending"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Lag != 15 || got.Pending != 3 || len(got.Streams) != 2 || got.Streams[1] != reader.lags[1] {
+		t.Errorf("response = %+v; want both streams and lag 15, pending 3", got)
+	}
+
+	reader.err = errors.New("connection refused")
+	rec = httptest.NewRecorder()
+	lagStatusHandler(reader, log.New()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d; want 500 when the lag cannot be read", rec.Code)
+	}
+}
+
+type stubCapture struct {
+	dump hotpath.CaptureDump
+	ok   bool
+}
+
+func (s *stubCapture) Capture() (hotpath.CaptureDump, bool) {
+	return s.dump, s.ok
+}
+
+func TestCaptureHandler(t *testing.T) {
+	source := &stubCapture{}
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/debug/capture", http.NoBody)
+	rec := httptest.NewRecorder()
+	captureHandler(source, log.New()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want 404 with the capture disabled", rec.Code)
+	}
+
+	source.ok = true
+	source.dump.Published = []hotpath.CapturedPublish{{Payload: `{"a":1}`, Messages: 1, Bytes: 20}}
+	source.dump.Acks = []hotpath.CapturedAck{{Stream: "syslog:a", IDs: []string{"1-0"}, IDCount: 1, Ack: true}}
+	rec = httptest.NewRecorder()
+	captureHandler(source, log.New()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	var got hotpath.CaptureDump
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got.Published) != 1 || got.Published[0].Payload != `{"a":1}` || len(got.Acks) != 1 || !got.Acks[0].Ack {
+		t.Errorf("response = %+v; want the captured payload and ACK", got)
+	}
+}
+
+type stubStreamAdmin struct {
+	err      error
+	attached []string
+	detach   redis.StreamDetach
+	deadline bool
+}
+
+func (s *stubStreamAdmin) AttachStream(_ context.Context, stream string) (bool, error) {
+	s.attached = append(s.attached, stream)
+	return true, s.err
+}
+
+func (s *stubStreamAdmin) DetachStream(ctx context.Context, stream string) (redis.StreamDetach, error) {
+	_, s.deadline = ctx.Deadline()
+	s.detach.Stream = stream
+	return s.detach, s.err
+}
+
+func serveStreams(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStreamAttachHandler(t *testing.T) {
+	admin := &stubStreamAdmin{}
+	rec := serveStreams(t, streamAttachHandler(admin, log.New()), "/admin/streams/attach?stream=syslog:fw")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"stream":"syslog:fw","added":true}`+"\n" {
+		t.Errorf("response = %d %q; want 200 and the stream added", rec.Code, rec.Body.String())
+	}
+
+	rec = serveStreams(t, streamAttachHandler(admin, log.New()), "/admin/streams/attach")
+	if rec.Code != http.StatusBadRequest || len(admin.attached) != 1 {
+		t.Errorf("status = %d, attached %v; want 400 without a stream", rec.Code, admin.attached)
+	}
+}
+
+func TestStreamDetachHandler(t *testing.T) {
+	tests := []struct {
+		err    error
+		name   string
+		query  string
+		detach redis.StreamDetach
+		want   int
+	}{
+		{name: "drained", query: "stream=fw", detach: redis.StreamDetach{Drained: true}, want: http.StatusOK},
+		{
+			name: "still draining", query: "stream=fw&wait=1s", err: context.DeadlineExceeded,
+			detach: redis.StreamDetach{Pending: 4}, want: http.StatusAccepted,
+		},
+		{name: "redis error", query: "stream=fw", err: errors.New("xpending failed"), want: http.StatusInternalServerError},
+		{name: "no stream", query: "wait=1s", want: http.StatusBadRequest},
+		{name: "bad wait", query: "stream=fw&wait=soon", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin := &stubStreamAdmin{err: tt.err, detach: tt.detach}
+			rec := serveStreams(t, streamDetachHandler(admin, log.New()), "/admin/streams/detach?"+tt.query)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d; want %d (body %q)", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code >= http.StatusBadRequest {
+				return
+			}
+			if !admin.deadline {
+				t.Error("DetachStream ran without a deadline")
+			}
+			var got redis.StreamDetach
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if want := (redis.StreamDetach{Stream: "fw", Pending: tt.detach.Pending, Drained: tt.detach.Drained}); got != want {
+				t.Errorf("response = %+v; want %+v", got, want)
+			}
+		})
+	}
+}
+
+type stubResizer struct {
+	err      error
+	capacity int
+}
+
+func (s *stubResizer) ResizeAckBuffer(capacity int) error {
+	s.capacity = capacity
+	return s.err
+}
+
+func TestAckBufferHandler(t *testing.T) {
+	tests := []struct {
+		err   error
+		name  string
+		query string
+		want  int
+	}{
+		{name: "resized", query: "capacity=50000", want: http.StatusOK},
+		{name: "not running", query: "capacity=50000", err: errors.New("not running"), want: http.StatusServiceUnavailable},
+		{name: "no capacity", want: http.StatusBadRequest},
+		{name: "zero", query: "capacity=0", want: http.StatusBadRequest},
+		{name: "not a number", query: "capacity=lots", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resizer := &stubResizer{err: tt.err}
+			rec := serveStreams(t, ackBufferHandler(resizer, log.New()), "/admin/buffer?"+tt.query)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d; want %d (body %q)", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && (resizer.capacity != 50000 || rec.Body.String() != `{"capacity":50000}`+"\n") {
+				t.Errorf("capacity = %d, response %q; want 50000", resizer.capacity, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package consumer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// MQTT 3.1.1 control packet types.
+const (
+	packetConnect     = 1
+	packetPublish     = 3
+	packetPubrel      = 6
+	packetSubscribe   = 8
+	packetUnsubscribe = 10
+	packetPingreq     = 12
+	packetDisconnect  = 14
+)
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker for the end-to-end
+// tests. It accepts CONNECT, SUBSCRIBE, UNSUBSCRIBE, PUBLISH at QoS 0 to 2,
+// PINGREQ and DISCONNECT, and routes each PUBLISH to the matching
+// subscriptions at the lower of the two QoS levels, granting QoS 1 at
+// most. A QoS 2 PUBLISH is held until its PUBREL and routed once then. It
+// keeps no sessions and no retained messages, so a client that reconnects
+// must subscribe again, and the QoS 2 publishes it had not released are
+// lost.
+type testBroker struct {
+	ln       net.Listener
+	conns    map[*brokerConn]struct{}
+	cutTopic string // see cutAfterPubrec
+	wg       sync.WaitGroup
+	mu       sync.Mutex // guards conns, closed, the cuts and the subscriptions
+	cuts     int
+	closed   bool
+}
+
+// brokerConn is one client connection and its subscriptions.
+type brokerConn struct {
+	conn    net.Conn
+	subs    map[string]byte        // topic filter to granted QoS
+	held    map[uint16]heldPublish // QoS 2 publishes awaiting PUBREL; serve only
+	writeMu sync.Mutex
+	nextID  uint16
+}
+
+// heldPublish is a QoS 2 PUBLISH received but not yet released.
+type heldPublish struct {
+	topic   string
+	payload []byte
+}
+
+var errMalformedPacket = errors.New("broker: malformed packet")
+
+// newTestBroker starts a broker on a loopback port; it is closed when the
+// test ends.
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	b := &testBroker{ln: ln, conns: make(map[*brokerConn]struct{})}
+	b.wg.Add(1)
+	go b.accept()
+	t.Cleanup(b.close)
+	return b
+}
+
+// URL returns the broker address in the form MQTT_BROKER expects.
+func (b *testBroker) URL() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// cutAfterPubrec makes the next n QoS 2 publishes to topic lose their
+// connection right after the broker sends PUBREC, before the client can
+// release them, as a network failure in the middle of the handshake does.
+func (b *testBroker) cutAfterPubrec(topic string, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cutTopic, b.cuts = topic, n
+}
+
+// cutPubrec reports whether a QoS 2 publish to topic must lose its
+// connection, counting it against cutAfterPubrec.
+func (b *testBroker) cutPubrec(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cuts == 0 || topic != b.cutTopic {
+		return false
+	}
+	b.cuts--
+	return true
+}
+
+// dropClients closes every client connection, as a broker restart does,
+// and returns how many there were. The broker keeps accepting.
+func (b *testBroker) dropClients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.conns {
+		_ = c.conn.Close()
+	}
+	return len(b.conns)
+}
+
+func (b *testBroker) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	_ = b.ln.Close()
+	b.dropClients()
+	b.wg.Wait()
+}
+
+func (b *testBroker) accept() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &brokerConn{conn: conn, subs: make(map[string]byte), held: make(map[uint16]heldPublish)}
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		b.conns[c] = struct{}{}
+		b.wg.Add(1)
+		b.mu.Unlock()
+		go b.serve(c)
+	}
+}
+
+func (b *testBroker) serve(c *brokerConn) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, c)
+		b.mu.Unlock()
+		_ = c.conn.Close()
+	}()
+	r := bufio.NewReader(c.conn)
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		if err := b.handle(c, header, body); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers one packet; an error closes the connection.
+func (b *testBroker) handle(c *brokerConn, header byte, body []byte) error {
+	switch header >> 4 {
+	case packetConnect:
+		return c.write(0x20, []byte{0, 0}) // CONNACK, session not present, accepted
+	case packetPublish:
+		return b.publish(c, header, body)
+	case packetPubrel:
+		if len(body) < 2 {
+			return errMalformedPacket
+		}
+		id := binary.BigEndian.Uint16(body)
+		if h, ok := c.held[id]; ok {
+			delete(c.held, id)
+			b.route(h.topic, 2, h.payload)
+		}
+		return c.write(0x70, body[:2]) // PUBCOMP
+	case packetSubscribe:
+		return b.subscribe(c, body)
+	case packetUnsubscribe:
+		return b.unsubscribe(c, body)
+	case packetPingreq:
+		return c.write(0xd0, nil) // PINGRESP
+	case packetDisconnect:
+		return io.EOF
+	default:
+		return nil // PUBACK, PUBREC and PUBCOMP for the broker's own deliveries
+	}
+}
+
+func (b *testBroker) publish(c *brokerConn, header byte, body []byte) error {
+	qos := header >> 1 & 3
+	topic, rest, ok := cutString(body)
+	if !ok || qos > 2 {
+		return errMalformedPacket
+	}
+	if qos == 0 {
+		b.route(topic, qos, rest)
+		return nil
+	}
+	if len(rest) < 2 {
+		return errMalformedPacket
+	}
+	id, payload := rest[:2], rest[2:]
+	if qos == 1 {
+		if err := c.write(0x40, id); err != nil { // PUBACK
+			return err
+		}
+		b.route(topic, qos, payload)
+		return nil
+	}
+	// A resent PUBLISH of a held ID replaces it, so it is still routed once.
+	c.held[binary.BigEndian.Uint16(id)] = heldPublish{topic: topic, payload: payload}
+	if err := c.write(0x50, id); err != nil { // PUBREC
+		return err
+	}
+	if b.cutPubrec(topic) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// route delivers payload to every connection with a matching subscription.
+func (b *testBroker) route(topic string, qos byte, payload []byte) {
+	type delivery struct {
+		c   *brokerConn
+		qos byte
+	}
+	var deliveries []delivery
+	b.mu.Lock()
+	for c := range b.conns {
+		granted, matched := byte(0), false
+		for filter, q := range c.subs {
+			if topicMatches(filter, topic) {
+				granted, matched = max(granted, q), true
+			}
+		}
+		if matched {
+			deliveries = append(deliveries, delivery{c: c, qos: min(qos, granted)})
+		}
+	}
+	b.mu.Unlock()
+	for _, d := range deliveries {
+		_ = d.c.deliver(topic, d.qos, payload) // a failed write ends that connection's serve
+	}
+}
+
+func (b *testBroker) subscribe(c *brokerConn, body []byte) error {
+	if len(body) < 2 {
+		return errMalformedPacket
+	}
+	ack := []byte{body[0], body[1]}
+	rest := body[2:]
+	b.mu.Lock()
+	for len(rest) > 0 {
+		filter, after, ok := cutString(rest)
+		if !ok || len(after) < 1 {
+			b.mu.Unlock()
+			return errMalformedPacket
+		}
+		granted := min(after[0]&3, 1)
+		c.subs[filter] = granted
+		ack = append(ack, granted)
+		rest = after[1:]
+	}
+	b.mu.Unlock()
+	return c.write(0x90, ack) // SUBACK
+}
+
+func (b *testBroker) unsubscribe(c *brokerConn, body []byte) error {
+	if len(body) < 2 {
+		return errMalformedPacket
+	}
+	id := body[:2]
+	rest := body[2:]
+	b.mu.Lock()
+	for len(rest) > 0 {
+		filter, after, ok := cutString(rest)
+		if !ok {
+			b.mu.Unlock()
+			return errMalformedPacket
+		}
+		delete(c.subs, filter)
+		rest = after
+	}
+	b.mu.Unlock()
+	return c.write(0xb0, id) // UNSUBACK
+}
+
+// deliver sends a PUBLISH to the client.
+func (c *brokerConn) deliver(topic string, qos byte, payload []byte) error {
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(topic))) //nolint:gosec // topics are short
+	body = append(body, topic...)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if qos > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		body = binary.BigEndian.AppendUint16(body, c.nextID)
+	}
+	body = append(body, payload...)
+	return c.writeLocked(0x30|qos<<1, body)
+}
+
+func (c *brokerConn) write(header byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeLocked(header, body)
+}
+
+func (c *brokerConn) writeLocked(header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n & 0x7f)
+		n >>= 7
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+// readPacket reads one control packet: its fixed header byte and its body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return 0, nil, errMalformedPacket
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// cutString splits a length-prefixed UTF-8 string off b.
+func cutString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+// topicMatches reports whether topic matches filter, with the + and #
+// wildcards.
+func topicMatches(filter, topic string) bool {
+	levels := strings.Split(topic, "/")
+	for i, f := range strings.Split(filter, "/") {
+		switch {
+		case f == "#":
+			return true
+		case i >= len(levels):
+			return false
+		case f != "+" && f != levels[i]:
+			return false
+		}
+	}
+	return len(levels) == len(strings.Split(filter, "/"))
+}
@@ -0,0 +1,264 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+)
+
+// The conformance tests run the whole consumer, through Start, against
+// miniredis and testBroker: entries are fetched from the stream, published
+// to the broker, acknowledged by a receiver and deleted, without any
+// external service.
+
+// pipeline is a consumer running against an in-process Redis and broker,
+// with a receiver acknowledging every entry it is published.
+type pipeline struct {
+	redis    *miniredis.Miniredis
+	broker   *testBroker
+	cfg      *Config
+	received map[string]int // entry ID to the times it was received
+	mu       sync.Mutex
+}
+
+func startPipeline(t *testing.T, qos byte) *pipeline {
+	t.Helper()
+	p := &pipeline{
+		redis:    miniredis.RunT(t),
+		broker:   newTestBroker(t),
+		received: make(map[string]int),
+	}
+	p.cfg = pipelineCfg(t, p.redis.Addr(), p.broker.URL(), qos)
+	p.startReceiver(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- Start(ctx, p.cfg, Hooks{OnStarted: func(context.Context) { close(started) }})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v; want nil after a graceful shutdown", err)
+		}
+	})
+	select {
+	case <-started:
+	case err := <-done:
+		t.Fatalf("Start() error = %v before the pipeline started", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pipeline did not start within 5s")
+	}
+	return p
+}
+
+// pipelineCfg loads the configuration like the binary, then points it at
+// the test services and shrinks the pools, batches and intervals.
+func pipelineCfg(t *testing.T, redisAddr, brokerURL string, qos byte) *Config {
+	t.Helper()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.Log.Level = "error"
+	cfg.Redis.Address = redisAddr
+	cfg.Redis.BatchSize = 10
+	cfg.Redis.BlockTimeout = 20 * time.Millisecond
+	cfg.Redis.ClaimIdle = 300 * time.Millisecond
+	cfg.Redis.PoolSize = 10
+	cfg.Redis.MinIdleConns = 1
+	cfg.Redis.ClaimConcurrency = 1
+	cfg.MQTT.Broker = brokerURL
+	cfg.MQTT.ClientID = "conformance-" + t.Name()
+	cfg.MQTT.QoS = qos
+	cfg.MQTT.PoolSize = 2
+	cfg.MQTT.WriteTimeout = time.Second
+	cfg.MQTT.ConnectRetryDelay = 20 * time.Millisecond
+	cfg.MQTT.MaxReconnectInterval = 50 * time.Millisecond
+	cfg.Pipeline.PublishWorkers = 2
+	cfg.Pipeline.AckWorkers = 2
+	cfg.Pipeline.ErrorBackoff = 20 * time.Millisecond
+	cfg.Pipeline.HealthAddr = "127.0.0.1:0"
+	return cfg
+}
+
+// startReceiver connects the downstream receiver: it subscribes to the
+// publish topic again on every connect, since the broker keeps no
+// sessions, and answers each batch with an ACK per stream.
+func (p *pipeline) startReceiver(t *testing.T) {
+	t.Helper()
+	opts := paho.NewClientOptions().
+		AddBroker(p.broker.URL()).
+		SetClientID("receiver-" + t.Name()).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(50 * time.Millisecond).
+		SetOrderMatters(false)
+	opts.SetOnConnectHandler(func(c paho.Client) {
+		c.Subscribe(p.cfg.MQTT.PublishTopic, p.cfg.MQTT.QoS, func(c paho.Client, m paho.Message) {
+			for _, ack := range p.receive(t, m.Payload()) {
+				c.Publish(p.cfg.MQTT.AckTopic, p.cfg.MQTT.QoS, false, ack)
+			}
+		})
+	})
+	receiver := paho.NewClient(opts)
+	if tok := receiver.Connect(); !tok.WaitTimeout(5*time.Second) || tok.Error() != nil {
+		t.Fatalf("receiver Connect() error = %v", tok.Error())
+	}
+	t.Cleanup(func() { receiver.Disconnect(0) })
+}
+
+// receive records the entries of a batch and returns the ACKs for them.
+func (p *pipeline) receive(t *testing.T, payload []byte) [][]byte {
+	if compress.IsCompressed(payload) {
+		out, err := compress.Decompress(nil, payload)
+		if err != nil {
+			t.Errorf("Decompress() error = %v", err)
+			return nil
+		}
+		payload = out
+	}
+	ids := make(map[string][]string)
+	p.mu.Lock()
+	for line := range bytes.SplitSeq(payload, []byte{'\n'}) {
+		id, rest, _ := bytes.Cut(line, []byte{'\t'})
+		stream, _, ok := bytes.Cut(rest, []byte{'\t'})
+		if !ok {
+			continue
+		}
+		ids[string(stream)] = append(ids[string(stream)], string(id))
+		p.received[string(id)]++
+	}
+	p.mu.Unlock()
+	acks := make([][]byte, 0, len(ids))
+	for stream, streamIDs := range ids {
+		ack, err := json.Marshal(message.AckMessage{Stream: stream, IDs: streamIDs, Ack: true})
+		if err != nil {
+			t.Errorf("json.Marshal() error = %v", err)
+			continue
+		}
+		acks = append(acks, ack)
+	}
+	return acks
+}
+
+// add appends n entries to the stream and returns their IDs.
+func (p *pipeline) add(t *testing.T, n int) []string {
+	t.Helper()
+	ids := make([]string, 0, n)
+	for i := range n {
+		id, err := p.redis.XAdd(p.cfg.Redis.Stream, "*",
+			[]string{"object", `{"n":` + strconv.Itoa(i) + `}`, "raw", "entry " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatalf("XAdd() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// waitDrained waits until every entry was received and deleted from the
+// stream.
+func (p *pipeline) waitDrained(t *testing.T, ids []string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		entries, err := p.redis.Stream(p.cfg.Redis.Stream)
+		missing := p.missing(ids)
+		if err == nil && len(entries) == 0 && len(missing) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stream holds %d entries (err %v) and %d entries were never received; want all drained",
+				len(entries), err, len(missing))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (p *pipeline) missing(ids []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var missing []string
+	for _, id := range ids {
+		if p.received[id] == 0 {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+func TestConformance_FetchPublishAckDelete(t *testing.T) {
+	for _, qos := range []byte{0, 1, 2} {
+		t.Run("qos "+strconv.Itoa(int(qos)), func(t *testing.T) {
+			p := startPipeline(t, qos)
+			p.waitDrained(t, p.add(t, 50))
+		})
+	}
+}
+
+func TestConformance_BrokerDisconnect(t *testing.T) {
+	p := startPipeline(t, 1)
+	p.waitDrained(t, p.add(t, 20))
+
+	if n := p.broker.dropClients(); n == 0 {
+		t.Fatal("dropClients() = 0; want the consumer and receiver connections dropped")
+	}
+	// Entries published while the connections are down stay pending
+	// until the claim loop delivers them again.
+	p.waitDrained(t, p.add(t, 20))
+}
+
+// A QoS 2 publish counts only once its PUBCOMP arrives. One whose
+// connection is lost after PUBREC was never released, so the broker drops
+// it and the claim loop publishes its entries again: each still reaches
+// the receiver exactly once.
+func TestConformance_QoS2ReconnectExactlyOnce(t *testing.T) {
+	p := startPipeline(t, 2)
+	p.waitDrained(t, p.add(t, 20))
+
+	p.broker.cutAfterPubrec(p.cfg.MQTT.PublishTopic, 2)
+	ids := p.add(t, 50)
+	p.waitDrained(t, ids)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if n := p.received[id]; n != 1 {
+			t.Errorf("entry %s received %d times; want exactly once", id, n)
+		}
+	}
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	if p.broker.cuts != 0 {
+		t.Errorf("%d connections left to cut; want every cut made mid-stream", p.broker.cuts)
+	}
+}
+
+func TestConformance_RedisRestart(t *testing.T) {
+	p := startPipeline(t, 1)
+	p.waitDrained(t, p.add(t, 20))
+
+	// A restarted Redis without persistence comes back empty: the client
+	// must recreate the consumer group on NOGROUP. miniredis' own Restart
+	// is not used, since it leaves blocking reads hanging.
+	addr := p.redis.Addr()
+	p.redis.Close()
+	time.Sleep(100 * time.Millisecond) // let the fetch loop fail against the stopped server
+	p.redis = miniredis.NewMiniRedis()
+	if err := p.redis.StartAddr(addr); err != nil {
+		t.Fatalf("StartAddr(%s) error = %v", addr, err)
+	}
+	t.Cleanup(p.redis.Close)
+	p.waitDrained(t, p.add(t, 20))
+}
@@ -0,0 +1,385 @@
+// Package consumer runs the syslog consumer inside another Go program: it
+// connects to Redis and the MQTT broker, serves the health endpoint and
+// runs the hot path until its context is canceled.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/archive"
+	"github.com/ibs-source/syslog-consumer/internal/certs"
+	"github.com/ibs-source/syslog-consumer/internal/compress"
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/health"
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+	"github.com/ibs-source/syslog-consumer/internal/systemd"
+)
+
+// Config is the consumer configuration. LoadConfig builds one; embedders
+// may adjust its fields before calling Start.
+type Config = config.Config
+
+// Observer receives the lifecycle events of the entries going through the
+// pipeline, off the pipeline's goroutines; see Hooks.Observer.
+type Observer = hotpath.Observer
+
+// Event is one pipeline event passed to an Observer.
+type Event = hotpath.Event
+
+// NopObserver ignores every event; embed it to implement only some of the
+// Observer methods.
+type NopObserver = hotpath.NopObserver
+
+// ErrShutdownTimeout is returned by Start when the pipeline has not stopped
+// within Pipeline.ShutdownTimeout of ctx being canceled.
+var ErrShutdownTimeout = errors.New("consumer: shutdown timeout exceeded")
+
+// LoadConfig resolves the configuration exactly like the binary does —
+// defaults < environment < command-line flags — and validates it.
+func LoadConfig() (*Config, error) {
+	return config.Load()
+}
+
+// Hooks are optional lifecycle callbacks. Start calls them synchronously
+// from its own goroutine, so a hook that blocks delays the lifecycle; nil
+// hooks are skipped.
+type Hooks struct {
+	// OnConnected runs once Redis and the MQTT pool are connected, before
+	// the pipeline starts.
+	OnConnected func(ctx context.Context)
+	// OnStarted runs once the hot path and the health server are running.
+	OnStarted func(ctx context.Context)
+	// OnStopping runs when ctx is canceled, before the graceful shutdown.
+	OnStopping func(ctx context.Context)
+	// OnStopped runs last, after every connection is closed, with the
+	// error Start is about to return.
+	OnStopped func(err error)
+	// CaptureDump, when set, writes the published payloads and ACKs kept
+	// under Pipeline.CaptureSize to the log each time it receives; the
+	// binary feeds it SIGUSR1.
+	CaptureDump <-chan os.Signal
+	// Observer, when set, receives the fetch, publish, ACK, drop and error
+	// events of the pipeline. Events it cannot keep up with are dropped
+	// and counted in consumer.events_dropped.
+	Observer Observer
+}
+
+// Start validates cfg and runs the consumer until ctx is canceled or the
+// pipeline fails. It returns nil after a graceful shutdown,
+// ErrShutdownTimeout when the shutdown overruns Pipeline.ShutdownTimeout,
+// a *StartupError when the consumer could not start, and the failure
+// otherwise.
+//
+// Start installs no signal handlers; cancel ctx to stop the consumer.
+// compress.Init is process-wide, so only one consumer should run per
+// process.
+func Start(ctx context.Context, cfg *Config, hooks Hooks) error {
+	err := start(ctx, cfg, &hooks)
+	if hooks.OnStopped != nil {
+		hooks.OnStopped(err)
+	}
+	return err
+}
+
+func start(ctx context.Context, cfg *Config, hooks *Hooks) error {
+	if cfg == nil {
+		return &StartupError{Err: errors.New("config must not be nil"), Failure: StartupConfig}
+	}
+	if err := config.Validate(cfg); err != nil {
+		return &StartupError{Err: err, Failure: StartupConfig}
+	}
+
+	logger := newLogger(&cfg.Log)
+	// Deferred first so it runs last, after the shutdown has been logged.
+	defer logger.Close()
+	compress.Init(&cfg.Compress)
+	// Teardown runs after ctx is canceled, so it gets a context that is not.
+	cleanupCtx := context.WithoutCancel(ctx)
+	// Deferred before closeServices so the last save has their final counts.
+	defer startCounterState(cleanupCtx, &cfg.Pipeline, logger)()
+
+	redisClient, pub, hp, err := initializeServices(ctx, cfg, logger, hooks.Observer)
+	if err != nil {
+		return err
+	}
+	defer closeServices(cleanupCtx, redisClient, pub, hp, logger)
+	if hooks.OnConnected != nil {
+		hooks.OnConnected(ctx)
+	}
+
+	healthSrv, err := newHealthServer(cfg, redisClient, pub, hp, logger)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to configure health server TLS: %v", err)
+		return &StartupError{Err: err, Failure: StartupTLS}
+	}
+	go func() {
+		if err := healthSrv.ListenAndServe(cleanupCtx); err != nil {
+			logger.Infof(ctx, "Health server stopped: %v", err)
+		}
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(cleanupCtx, cfg.Pipeline.ShutdownTimeout)
+		defer cancel()
+		if err := healthSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf(ctx, "Health server shutdown error: %v", err)
+		}
+	}()
+	logger.Infof(ctx, "Health server listening on %s", cfg.Pipeline.HealthAddr)
+
+	stopStatsD, err := startStatsD(cleanupCtx, &cfg.Pipeline, logger)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to start the StatsD exporter: %v", err)
+		return &StartupError{Err: err, Failure: StartupConfig}
+	}
+	defer stopStatsD()
+
+	stopLifecycle, err := startLifecycle(cleanupCtx, cfg, redisClient.Streams(), pub, hp, logger, hooks)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to start recording lifecycle events: %v", err)
+		return &StartupError{Err: err, Failure: StartupConfig}
+	}
+	err = runHotPath(ctx, hp, cfg, logger, hooks)
+	stopLifecycle(err)
+	return err
+}
+
+func newLogger(cfg *config.LogConfig) *log.Logger {
+	var logger *log.Logger
+	if cfg.Async {
+		logger = log.NewAsync(cfg.Level, cfg.QueueSize)
+	} else {
+		logger = log.NewWithLevel(cfg.Level)
+	}
+	logger.SetPayloadPreview(cfg.PayloadPreviewBytes)
+	return logger
+}
+
+// startStatsD starts sending the metrics to Pipeline.StatsdAddr, when it
+// is set. The returned stop sends them one last time and closes the
+// exporter.
+func startStatsD(ctx context.Context, cfg *config.PipelineConfig, logger *log.Logger) (func(), error) {
+	if cfg.StatsdAddr == "" {
+		return func() {}, nil
+	}
+	exporter, err := metrics.NewStatsD(cfg.StatsdAddr, cfg.StatsdPrefix, cfg.StatsdTags)
+	if err != nil {
+		return nil, err
+	}
+	exportCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exportStatsD(exportCtx, exporter, cfg.StatsdInterval, logger)
+	}()
+	logger.Infof(ctx, "Sending metrics to StatsD at %s every %s", cfg.StatsdAddr, cfg.StatsdInterval)
+	return func() {
+		cancel()
+		<-done
+		if err := exporter.Close(); err != nil {
+			logger.Errorf(ctx, "Error closing the StatsD exporter: %v", err)
+		}
+	}, nil
+}
+
+// newHealthServer builds the health server, with the consumer group lag
+// status and, when Pipeline.CaptureSize, Pipeline.AdminDLQReplay,
+// Pipeline.AdminStreams and Pipeline.AdminBuffer are set, the capture, DLQ
+// replay, stream attach/detach and ACK buffer endpoints, served over TLS
+// when Pipeline.HealthTLSCert is. The MQTT connections are checked when
+// pub is the broker's pool, and the ack latency objective of hp when
+// Pipeline.AckSLOLatency is set.
+func newHealthServer(
+	cfg *config.Config, redisClient *redis.Client, pub mqtt.Publisher, hp *hotpath.HotPath, logger *log.Logger,
+) (*health.Server, error) {
+	checker, _ := pub.(health.ConnectionChecker)
+	healthSrv := health.NewServer(
+		cfg.Pipeline.HealthAddr,
+		redisClient,
+		checker,
+		cfg.Pipeline.HealthPingTimeout,
+		cfg.Pipeline.HealthReadHeaderTimeout,
+	)
+	healthSrv.SetDegrader(hp)
+	healthSrv.Handle("GET /status/lag", lagStatusHandler(redisClient, logger))
+	if cfg.Pipeline.CaptureSize > 0 {
+		healthSrv.Handle("GET /debug/capture", captureHandler(hp, logger))
+	}
+	if cfg.Pipeline.AdminDLQReplay {
+		healthSrv.Handle("POST /admin/dlq/replay", dlqReplayHandler(redisClient, logger))
+	}
+	if cfg.Pipeline.AdminStreams {
+		healthSrv.Handle("POST /admin/streams/attach", streamAttachHandler(redisClient, logger))
+		healthSrv.Handle("POST /admin/streams/detach", streamDetachHandler(redisClient, logger))
+	}
+	if cfg.Pipeline.AdminBuffer {
+		healthSrv.Handle("POST /admin/buffer", ackBufferHandler(hp, logger))
+	}
+	if cfg.Pipeline.HealthTLSCert != "" {
+		tlsConfig, err := certs.Server(cfg.Pipeline.HealthTLSCert, cfg.Pipeline.HealthTLSKey, cfg.Pipeline.HealthClientCA)
+		if err != nil {
+			return nil, err
+		}
+		healthSrv.SetTLSConfig(tlsConfig)
+	}
+	return healthSrv, nil
+}
+
+// newPublisher connects to the MQTT broker or, under ARCHIVE_MODE=only,
+// opens the archive that takes its place.
+func newPublisher(ctx context.Context, cfg *config.Config, logger *log.Logger) (mqtt.Publisher, error) {
+	if cfg.Archive.Mode == config.ArchiveOnly {
+		sink, err := archive.New(&cfg.Archive, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof(ctx, "Archiving to %s instead of publishing to MQTT", cfg.Archive.Dir)
+		return sink, nil
+	}
+	mqttPool, err := mqtt.NewPool(ctx, &cfg.MQTT, cfg.MQTT.PoolSize, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Connected to MQTT broker with %d connections", cfg.MQTT.PoolSize)
+	return mqttPool, nil
+}
+
+func initializeServices(
+	ctx context.Context, cfg *config.Config, logger *log.Logger, observer Observer,
+) (*redis.Client, mqtt.Publisher, *hotpath.HotPath, error) {
+	redisClient, err := redis.NewClient(ctx, &cfg.Redis, logger)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create Redis client: %v", err)
+		return nil, nil, nil, startupError(StartupRedis, err)
+	}
+	logger.Infof(ctx, "Connected to Redis")
+
+	pub, err := newPublisher(ctx, cfg, logger)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create the publisher: %v", err)
+		if cerr := redisClient.Close(); cerr != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", cerr)
+		}
+		return nil, nil, nil, startupError(publisherFailure(cfg), err)
+	}
+
+	var observers []Observer
+	if observer != nil {
+		observers = append(observers, observer)
+	}
+	hp, err := hotpath.New(redisClient, pub, cfg, logger, observers...)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create hot path: %v", err)
+		if cerr := pub.Close(); cerr != nil {
+			logger.Errorf(ctx, "Error closing the publisher: %v", cerr)
+		}
+		if cerr := redisClient.Close(); cerr != nil {
+			logger.Errorf(ctx, "Error closing Redis client: %v", cerr)
+		}
+		return nil, nil, nil, &StartupError{Err: err, Failure: StartupConfig}
+	}
+	return redisClient, pub, hp, nil
+}
+
+// publisherFailure is what a failure of newPublisher means: the archive
+// that stands in for the broker under ARCHIVE_MODE=only is configured,
+// not reached.
+func publisherFailure(cfg *config.Config) StartupFailure {
+	if cfg.Archive.Mode == config.ArchiveOnly {
+		return StartupConfig
+	}
+	return StartupMQTT
+}
+
+func closeServices(
+	ctx context.Context, redisClient *redis.Client, pub mqtt.Publisher, hp *hotpath.HotPath, logger *log.Logger,
+) {
+	if err := hp.Close(); err != nil {
+		logger.Errorf(ctx, "Error closing hot path: %v", err)
+	}
+	if err := pub.Close(); err != nil {
+		logger.Errorf(ctx, "Error closing the publisher: %v", err)
+	}
+	if err := redisClient.Close(); err != nil {
+		logger.Errorf(ctx, "Error closing Redis client: %v", err)
+	}
+}
+
+// dumpCaptures logs the capture of hp on every value of dumps until ctx
+// is canceled. A nil dumps waits for ctx only.
+func dumpCaptures(ctx context.Context, dumps <-chan os.Signal, hp *hotpath.HotPath) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dumps:
+			hp.LogCapture(ctx)
+		}
+	}
+}
+
+// runHotPath runs hp until ctx is canceled, then gives it
+// Pipeline.ShutdownTimeout to stop. hp runs on a context of its own so the
+// shutdown starts only after OnStopping has returned. Under systemd it
+// reports readiness, watchdog heartbeats and the shutdown to the service
+// manager.
+func runHotPath(ctx context.Context, hp *hotpath.HotPath, cfg *config.Config, logger *log.Logger, hooks *Hooks) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- hp.Run(runCtx)
+	}()
+
+	logger.Infof(ctx, "Hot path orchestrator started")
+	notifier := systemd.FromEnv()
+	notifyCtx, stopNotify := context.WithCancel(ctx)
+	defer stopNotify()
+	go notifySystemd(notifyCtx, notifier, hp, logger)
+	go dumpCaptures(notifyCtx, hooks.CaptureDump, hp)
+	if hooks.OnStarted != nil {
+		hooks.OnStarted(ctx)
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Infof(runCtx, "Stop requested, initiating graceful shutdown")
+		if err := notifier.Notify(runCtx, systemd.Stopping); err != nil {
+			logger.Warnf(runCtx, "Failed to notify systemd of the shutdown: %v", err)
+		}
+		if hooks.OnStopping != nil {
+			hooks.OnStopping(runCtx)
+		}
+		cancel()
+
+		timer := time.NewTimer(cfg.Pipeline.ShutdownTimeout)
+		defer timer.Stop()
+
+		select {
+		case err := <-doneCh:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logger.Errorf(runCtx, "Hot path shutdown error: %v", err)
+				return err
+			}
+			logger.Infof(runCtx, "Graceful shutdown completed")
+			return nil
+		case <-timer.C:
+			logger.Errorf(runCtx, "Shutdown timeout exceeded")
+			return ErrShutdownTimeout
+		}
+
+	case err := <-doneCh:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Errorf(ctx, "Hot path error: %v", err)
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,232 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/message"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+	"github.com/ibs-source/syslog-consumer/internal/redis"
+)
+
+// --- lightweight mocks for hotpath.New dependencies ---
+
+type stubRedis struct{}
+
+func (s *stubRedis) ReadBatch(_ context.Context) (message.Batch, error) {
+	return message.Batch{}, nil
+}
+func (s *stubRedis) ClaimIdle(_ context.Context) (message.Batch, error) {
+	return message.Batch{}, nil
+}
+func (s *stubRedis) AckAndDeleteBatch(_ context.Context, _ []string, _ string) error {
+	return nil
+}
+func (s *stubRedis) CleanupDeadConsumers(_ context.Context, _ time.Duration) error { return nil }
+func (s *stubRedis) RefreshStreams(_ context.Context) (int, error)                 { return 0, nil }
+func (s *stubRedis) Close() error                                                  { return nil }
+
+// stubRedisBlocking blocks reads until the context is canceled.
+type stubRedisBlocking struct {
+	stubRedis
+}
+
+func (s *stubRedisBlocking) ReadBatch(ctx context.Context) (message.Batch, error) {
+	<-ctx.Done()
+	return message.Batch{}, ctx.Err()
+}
+func (s *stubRedisBlocking) ClaimIdle(ctx context.Context) (message.Batch, error) {
+	<-ctx.Done()
+	return message.Batch{}, ctx.Err()
+}
+
+type stubRedisCloseFail struct {
+	stubRedis
+}
+
+func (s *stubRedisCloseFail) Close() error { return errors.New("redis close error") }
+
+type stubPublisher struct {
+	subErr error
+}
+
+func (s *stubPublisher) Publish(_ context.Context, _ message.Payload) error {
+	return nil
+}
+func (s *stubPublisher) SubscribeAck(_ context.Context, _ func(message.AckMessage)) error {
+	return s.subErr
+}
+func (s *stubPublisher) Close() error { return nil }
+
+// stubPublisherHang never returns from SubscribeAck, so the hot path
+// cannot finish its shutdown.
+type stubPublisherHang struct {
+	stubPublisher
+	release chan struct{}
+}
+
+func (s *stubPublisherHang) SubscribeAck(_ context.Context, _ func(message.AckMessage)) error {
+	<-s.release
+	return nil
+}
+
+func testCfg() *config.Config {
+	return &config.Config{
+		Redis: config.RedisConfig{
+			Stream:              "test",
+			ClaimIdle:           30 * time.Second,
+			CleanupInterval:     1 * time.Minute,
+			ConsumerIdleTimeout: 5 * time.Minute,
+		},
+		Pipeline: config.PipelineConfig{
+			BufferCapacity:       100,
+			MessageQueueCapacity: 4,
+			PublishWorkers:       2,
+			AckWorkers:           2,
+			RefreshInterval:      1 * time.Minute,
+			ErrorBackoff:         100 * time.Millisecond,
+			AckTimeout:           5 * time.Second,
+			ShutdownTimeout:      5 * time.Second,
+		},
+	}
+}
+
+func newHotPath(t *testing.T, redisClient redis.StreamClient, pub mqtt.Publisher, cfg *config.Config) *hotpath.HotPath {
+	t.Helper()
+	hp, err := hotpath.New(redisClient, pub, cfg, log.New())
+	if err != nil {
+		t.Fatalf("hotpath.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := hp.Close(); err != nil {
+			t.Errorf("hp.Close(): %v", err)
+		}
+	})
+	return hp
+}
+
+// recordingHooks returns Hooks that append their name to calls.
+func recordingHooks(calls *[]string, stopErr *error) Hooks {
+	return Hooks{
+		OnConnected: func(context.Context) { *calls = append(*calls, "connected") },
+		OnStarted:   func(context.Context) { *calls = append(*calls, "started") },
+		OnStopping:  func(context.Context) { *calls = append(*calls, "stopping") },
+		OnStopped: func(err error) {
+			*calls = append(*calls, "stopped")
+			*stopErr = err
+		},
+	}
+}
+
+func TestStart_NilConfig(t *testing.T) {
+	var calls []string
+	var stopErr error
+	err := Start(t.Context(), nil, recordingHooks(&calls, &stopErr))
+	if err == nil {
+		t.Fatal("Start() error = nil; want error for a nil config")
+	}
+	if len(calls) != 1 || calls[0] != "stopped" || !errors.Is(stopErr, err) {
+		t.Errorf("hooks = %v (OnStopped err %v); want only OnStopped with %v", calls, stopErr, err)
+	}
+}
+
+func TestStart_InvalidConfig(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.Pipeline.BufferCapacity = -1
+	var startupErr *StartupError
+	if err := Start(t.Context(), cfg, Hooks{}); !errors.As(err, &startupErr) || startupErr.Failure != StartupConfig {
+		t.Errorf("Start() error = %v; want a StartupConfig StartupError", err)
+	}
+}
+
+func TestStart_RedisConnectionFailure(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.Redis.Address = "localhost:1" // unroutable port → immediate failure
+
+	var calls []string
+	var stopErr error
+	err = Start(t.Context(), cfg, recordingHooks(&calls, &stopErr))
+	var startupErr *StartupError
+	if !errors.As(err, &startupErr) || startupErr.Failure != StartupRedis {
+		t.Fatalf("Start() error = %v; want a StartupRedis StartupError", err)
+	}
+	if len(calls) != 1 || calls[0] != "stopped" || stopErr == nil {
+		t.Errorf("hooks = %v; want only OnStopped, with the error", calls)
+	}
+}
+
+func TestRunHotPath_GracefulStop(t *testing.T) {
+	cfg := testCfg()
+	hp := newHotPath(t, &stubRedisBlocking{}, &stubPublisher{}, cfg)
+
+	var calls []string
+	var stopErr error
+	hooks := recordingHooks(&calls, &stopErr)
+	ctx, cancel := context.WithCancel(t.Context())
+	hooks.OnStarted = func(context.Context) {
+		calls = append(calls, "started")
+		cancel()
+	}
+
+	if err := runHotPath(ctx, hp, cfg, log.New(), &hooks); err != nil {
+		t.Errorf("runHotPath() = %v; want nil for a graceful stop", err)
+	}
+	if len(calls) != 2 || calls[0] != "started" || calls[1] != "stopping" {
+		t.Errorf("hooks = %v; want [started stopping]", calls)
+	}
+}
+
+func TestRunHotPath_HotPathError(t *testing.T) {
+	cfg := testCfg()
+	hp := newHotPath(t, &stubRedis{}, &stubPublisher{subErr: errors.New("subscribe failed")}, cfg)
+
+	if err := runHotPath(t.Context(), hp, cfg, log.New(), &Hooks{}); err == nil {
+		t.Error("runHotPath() = nil; want the hot path error")
+	}
+}
+
+func TestRunHotPath_ShutdownTimeout(t *testing.T) {
+	cfg := testCfg()
+	cfg.Pipeline.ShutdownTimeout = 50 * time.Millisecond
+	pub := &stubPublisherHang{release: make(chan struct{})}
+	hp := newHotPath(t, &stubRedisBlocking{}, pub, cfg)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err := runHotPath(ctx, hp, cfg, log.New(), &Hooks{})
+	close(pub.release)
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("runHotPath() = %v; want ErrShutdownTimeout", err)
+	}
+}
+
+// TestCloseServices_NilSafe verifies that closeServices does not panic
+// when called with properly created but unconnected services.
+func TestCloseServices_NilSafe(t *testing.T) {
+	hp, err := hotpath.New(&stubRedis{}, &stubPublisher{}, testCfg(), log.New())
+	if err != nil {
+		t.Fatalf("hotpath.New() error = %v", err)
+	}
+	// redis.Client with nil rdb and mqtt.Pool with no clients close cleanly.
+	closeServices(t.Context(), &redis.Client{}, &mqtt.Pool{}, hp, log.New())
+}
+
+// TestCloseServices_ErrorPaths verifies closeServices logs errors without panicking.
+func TestCloseServices_ErrorPaths(t *testing.T) {
+	hp, err := hotpath.New(&stubRedisCloseFail{}, &stubPublisher{}, testCfg(), log.New())
+	if err != nil {
+		t.Fatalf("hotpath.New() error = %v", err)
+	}
+	closeServices(t.Context(), &redis.Client{}, &mqtt.Pool{}, hp, log.New())
+}
@@ -0,0 +1,70 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// startCounterState adds the counters saved at Pipeline.MetricsStatePath,
+// when it is set, and saves them there every Pipeline.MetricsStateInterval.
+// The returned stop saves them one last time; it must run after the
+// services are closed, so that the counts of their final flushes are kept.
+func startCounterState(ctx context.Context, cfg *config.PipelineConfig, logger *log.Logger) func() {
+	if cfg.MetricsStatePath == "" {
+		return func() {}
+	}
+	savedAt, err := metrics.RestoreCounters(cfg.MetricsStatePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		logger.Infof(ctx, "No saved counters at %s; counting from zero", cfg.MetricsStatePath)
+	case err != nil:
+		logger.Warnf(ctx, "Failed to restore the counters from %s; counting from zero: %v", cfg.MetricsStatePath, err)
+	default:
+		logger.Infof(ctx, "Restored the counters saved at %s from %s",
+			savedAt.Format(time.RFC3339), cfg.MetricsStatePath)
+	}
+
+	saveCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		saveCounters(saveCtx, cfg.MetricsStatePath, cfg.MetricsStateInterval, logger)
+	}()
+	return func() {
+		cancel()
+		<-done
+		if err := metrics.SaveCounters(cfg.MetricsStatePath); err != nil {
+			logger.Errorf(ctx, "Failed to save the counters to %s: %v", cfg.MetricsStatePath, err)
+		}
+	}
+}
+
+// saveCounters saves the counters to path every interval until ctx is
+// canceled. Like exportStatsD, it logs when the saves start to fail and
+// when they recover, not at every save.
+func saveCounters(ctx context.Context, path string, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := metrics.SaveCounters(path)
+		switch {
+		case err != nil && !failing:
+			logger.Warnf(ctx, "Failed to save the counters to %s: %v", path, err)
+		case err == nil && failing:
+			logger.Infof(ctx, "Saving the counters to %s again", path)
+		}
+		failing = err != nil
+	}
+}
@@ -0,0 +1,26 @@
+package consumer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func TestStartCounterState_SavesOnStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.json")
+	stop := startCounterState(t.Context(), &config.PipelineConfig{
+		MetricsStatePath: path, MetricsStateInterval: time.Hour,
+	}, log.New())
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("state file before stop: %v; want none", err)
+	}
+	stop()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("state file after stop: %v; want it saved", err)
+	}
+}
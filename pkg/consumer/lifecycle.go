@@ -0,0 +1,91 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/ibs-source/syslog-consumer/internal/config"
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// startLifecycle records the lifecycle events of the consumer when
+// Pipeline.LifecycleLog or MQTT.LifecycleTopic is set: the streams found
+// at startup and the broker connection first, then, through hp and the
+// pool, pauses, new streams and quarantined connections, and the start and
+// the beginning of the shutdown ahead of hooks' OnStarted and OnStopping.
+// The returned stop records the end of the shutdown with the error Start
+// returns and writes out the events within Pipeline.ShutdownTimeout; it
+// must run before the services are closed, so the last events are still
+// published.
+func startLifecycle(
+	ctx context.Context, cfg *config.Config, streams []string, pub mqtt.Publisher,
+	hp *hotpath.HotPath, logger *log.Logger, hooks *Hooks,
+) (func(error), error) {
+	if !lifecycle.Enabled(cfg) {
+		return func(error) {}, nil
+	}
+	transitions, err := lifecycle.New(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	transitions.Record(lifecycle.StreamsDiscovered, map[string]any{"streams": len(streams)})
+	attachLifecycle(ctx, cfg, transitions, pub, logger)
+	hp.SetLifecycle(transitions)
+	chainLifecycleHooks(transitions, hooks)
+	logger.Infof(ctx, "Recording lifecycle events")
+	return func(err error) {
+		transitions.Record(lifecycle.ShutdownComplete, shutdownDetail(err))
+		closeCtx, cancel := context.WithTimeout(ctx, cfg.Pipeline.ShutdownTimeout)
+		defer cancel()
+		if err := transitions.Close(closeCtx); err != nil {
+			logger.Errorf(ctx, "Error closing the lifecycle log: %v", err)
+		}
+	}, nil
+}
+
+// attachLifecycle records the broker connection and has the events
+// published through the pool, when pub is the broker's pool; under
+// ARCHIVE_MODE=only there is no broker to publish them to.
+func attachLifecycle(
+	ctx context.Context, cfg *config.Config, transitions *lifecycle.Recorder, pub mqtt.Publisher, logger *log.Logger,
+) {
+	pool, ok := pub.(*mqtt.Pool)
+	if !ok {
+		if cfg.MQTT.LifecycleTopic != "" {
+			logger.Warnf(ctx, "Not publishing lifecycle events to %s: there is no MQTT broker", cfg.MQTT.LifecycleTopic)
+		}
+		return
+	}
+	pool.SetLifecycle(transitions)
+	transitions.Attach(pool)
+	transitions.Record(lifecycle.BrokerConnected, map[string]any{"connections": cfg.MQTT.PoolSize})
+}
+
+// chainLifecycleHooks records the start and the beginning of the shutdown
+// before hooks' own OnStarted and OnStopping run.
+func chainLifecycleHooks(transitions *lifecycle.Recorder, hooks *Hooks) {
+	onStarted, onStopping := hooks.OnStarted, hooks.OnStopping
+	hooks.OnStarted = func(ctx context.Context) {
+		transitions.Record(lifecycle.Started, nil)
+		if onStarted != nil {
+			onStarted(ctx)
+		}
+	}
+	hooks.OnStopping = func(ctx context.Context) {
+		transitions.Record(lifecycle.ShutdownBegin, nil)
+		if onStopping != nil {
+			onStopping(ctx)
+		}
+	}
+}
+
+// shutdownDetail is the detail of the shutdown-complete event: the error
+// the consumer stops with, if any.
+func shutdownDetail(err error) map[string]any {
+	if err == nil {
+		return nil
+	}
+	return map[string]any{"error": err.Error()}
+}
@@ -0,0 +1,77 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/lifecycle"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+)
+
+func TestStartLifecycle_RecordsTheRunAndChainsHooks(t *testing.T) {
+	cfg := testCfg()
+	cfg.Pipeline.LifecycleLog = filepath.Join(t.TempDir(), "lifecycle.jsonl")
+	hp := newHotPath(t, &stubRedis{}, &stubPublisher{}, cfg)
+	var calls []string
+	var stopErr error
+	hooks := recordingHooks(&calls, &stopErr)
+
+	stop, err := startLifecycle(t.Context(), cfg, []string{"a", "b"}, &stubPublisher{}, hp, log.New(), &hooks)
+	if err != nil {
+		t.Fatalf("startLifecycle() error = %v", err)
+	}
+	hooks.OnStarted(t.Context())
+	hooks.OnStopping(t.Context())
+	stop(errors.New("hot path failed"))
+
+	if len(calls) != 2 || calls[0] != "started" || calls[1] != "stopping" {
+		t.Errorf("hooks = %v; want the original OnStarted and OnStopping still called", calls)
+	}
+	data, err := os.ReadFile(cfg.Pipeline.LifecycleLog)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var kinds []lifecycle.Kind
+	var last lifecycle.Event
+	for line := range strings.Lines(string(data)) {
+		if err := json.Unmarshal([]byte(line), &last); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		kinds = append(kinds, last.Kind)
+	}
+	want := []lifecycle.Kind{
+		lifecycle.StreamsDiscovered, lifecycle.Started, lifecycle.ShutdownBegin, lifecycle.ShutdownComplete,
+	}
+	if !slices.Equal(kinds, want) {
+		t.Errorf("events = %v; want %v, without a broker to connect to", kinds, want)
+	}
+	if last.Detail["error"] != "hot path failed" {
+		t.Errorf("shutdown-complete detail = %v; want the error", last.Detail)
+	}
+}
+
+func TestStartLifecycle_Off(t *testing.T) {
+	cfg := testCfg()
+	hp := newHotPath(t, &stubRedis{}, &stubPublisher{}, cfg)
+	var hooks Hooks
+	stop, err := startLifecycle(t.Context(), cfg, nil, &stubPublisher{}, hp, log.New(), &hooks)
+	if err != nil {
+		t.Fatalf("startLifecycle() error = %v", err)
+	}
+	stop(nil)
+	if hooks.OnStarted != nil || hooks.OnStopping != nil {
+		t.Error("hooks were chained; want them left alone without lifecycle events")
+	}
+}
+
+func TestChainLifecycleHooks_NilHooks(t *testing.T) {
+	var hooks Hooks
+	chainLifecycleHooks(nil, &hooks)
+	hooks.OnStarted(t.Context())
+	hooks.OnStopping(t.Context())
+}
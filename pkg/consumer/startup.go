@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+// StartupFailure says what kept the consumer from starting.
+type StartupFailure string
+
+// Startup failures. StartupRedis and StartupMQTT are worth retrying with
+// backoff, since the dependency may come back; StartupConfig and
+// StartupTLS need an operator.
+const (
+	StartupConfig StartupFailure = "invalid configuration"
+	StartupRedis  StartupFailure = "redis unreachable"
+	StartupMQTT   StartupFailure = "mqtt unreachable"
+	StartupTLS    StartupFailure = "tls error"
+)
+
+// StartupError is returned by Start when the consumer fails before the
+// pipeline runs. Start makes no attempt to recover from it: the caller, or
+// the orchestrator restarting the process, decides whether to try again.
+// Any other error of Start is a failure of the running pipeline.
+type StartupError struct {
+	Err     error
+	Failure StartupFailure
+}
+
+func (e *StartupError) Error() string {
+	return "consumer: " + string(e.Failure) + ": " + e.Err.Error()
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// startupError returns err as a StartupError of failure, or of StartupTLS
+// when a certificate failed to load or the peer's was rejected.
+func startupError(failure StartupFailure, err error) error {
+	if isTLSError(err) {
+		failure = StartupTLS
+	}
+	return &StartupError{Err: err, Failure: failure}
+}
+
+func isTLSError(err error) bool {
+	var (
+		verify    *tls.CertificateVerificationError
+		alert     tls.AlertError
+		record    tls.RecordHeaderError
+		authority x509.UnknownAuthorityError
+		hostname  x509.HostnameError
+		invalid   x509.CertificateInvalidError
+	)
+	return errors.Is(err, mqtt.ErrTLSConfig) || errors.As(err, &verify) || errors.As(err, &alert) ||
+		errors.As(err, &record) || errors.As(err, &authority) || errors.As(err, &hostname) ||
+		errors.As(err, &invalid)
+}
@@ -0,0 +1,31 @@
+package consumer
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ibs-source/syslog-consumer/internal/mqtt"
+)
+
+func TestStartupError(t *testing.T) {
+	refused := errors.New("connection refused")
+	for _, tt := range []struct {
+		err  error
+		want StartupFailure
+	}{
+		{err: refused, want: StartupMQTT},
+		{err: fmt.Errorf("failed to create client 0: %w", mqtt.ErrTLSConfig), want: StartupTLS},
+		{err: fmt.Errorf("mqtt connect failed: %w", x509.UnknownAuthorityError{}), want: StartupTLS},
+	} {
+		err := startupError(StartupMQTT, tt.err)
+		var startupErr *StartupError
+		if !errors.As(err, &startupErr) || startupErr.Failure != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("startupError(%v) = %v; want a %q StartupError wrapping it", tt.err, err, tt.want)
+		}
+	}
+	if got := startupError(StartupRedis, refused).Error(); got != "consumer: redis unreachable: connection refused" {
+		t.Errorf("Error() = %q", got)
+	}
+}
@@ -0,0 +1,34 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+// exportStatsD flushes the metrics to exporter every interval, and once
+// more when ctx is canceled so the last counts of the run are sent too.
+// A failing server is logged when the flushes start to fail and when they
+// recover, not at every flush.
+func exportStatsD(ctx context.Context, exporter *metrics.StatsD, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := false
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+		}
+		err := exporter.Flush()
+		switch {
+		case err != nil && !failing:
+			logger.Warnf(ctx, "Failed to send metrics to StatsD: %v", err)
+		case err == nil && failing:
+			logger.Infof(ctx, "Sending metrics to StatsD again")
+		}
+		failing = err != nil
+	}
+}
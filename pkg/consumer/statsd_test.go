@@ -0,0 +1,41 @@
+package consumer
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/metrics"
+)
+
+func TestExportStatsD_FlushesOnCancel(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	exporter, err := metrics.NewStatsD(conn.LocalAddr().String(), "", nil)
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer func() { _ = exporter.Close() }()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	exportStatsD(ctx, exporter, time.Hour, log.New())
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), "consumer.ack_queue_depth:") {
+		t.Errorf("final flush = %q; want the consumer.* gauges", buf[:n])
+	}
+}
@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/hotpath"
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/systemd"
+)
+
+// notifySystemd supervises hp for systemd when it runs the consumer as a
+// Type=notify service: READY=1 once hp has read from Redis, then, with
+// WatchdogSec= set, WATCHDOG=1 every half of it while the fetch loop keeps
+// going round. A fetch loop stuck for the whole timeout misses the
+// heartbeats and systemd restarts the service. It returns when ctx is
+// canceled.
+func notifySystemd(ctx context.Context, notifier *systemd.Notifier, hp *hotpath.HotPath, logger *log.Logger) {
+	if notifier == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	case <-hp.Ready():
+	}
+	if err := notifier.Notify(ctx, systemd.Ready); err != nil {
+		logger.Warnf(ctx, "Failed to notify systemd of readiness: %v", err)
+	}
+
+	timeout := notifier.WatchdogTimeout()
+	if timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !hp.Alive(timeout) {
+			logger.Warnf(ctx, "Fetch loop stuck for %s, withholding the systemd watchdog heartbeat", timeout)
+			continue
+		}
+		if err := notifier.Notify(ctx, systemd.Watchdog); err != nil {
+			logger.Warnf(ctx, "Failed to send systemd watchdog heartbeat: %v", err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibs-source/syslog-consumer/internal/log"
+	"github.com/ibs-source/syslog-consumer/internal/systemd"
+)
+
+func TestRunHotPath_NotifiesSystemd(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	cfg := testCfg()
+	hp := newHotPath(t, &stubRedis{}, &stubPublisher{}, cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- runHotPath(ctx, hp, cfg, log.New(), &Hooks{}) }()
+
+	var states []string
+	buf := make([]byte, 64)
+	for len(states) == 0 || states[len(states)-1] != systemd.Stopping {
+		if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("SetReadDeadline() error = %v", err)
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v after %v", err, states)
+		}
+		states = append(states, string(buf[:n]))
+		if states[len(states)-1] == systemd.Watchdog {
+			cancel()
+		}
+	}
+	if err := <-done; err != nil {
+		t.Errorf("runHotPath() = %v; want nil for a graceful stop", err)
+	}
+	if states[0] != systemd.Ready || states[1] != systemd.Watchdog {
+		t.Errorf("states = %v; want READY=1, then WATCHDOG=1, then STOPPING=1", states)
+	}
+}